@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/internal/hooks"
+	"public-ip-monitor/internal/ip"
+	"public-ip-monitor/internal/logger"
+	"public-ip-monitor/internal/notify"
+)
+
+// acmeDNSInterval is how often the domains are resolved while waiting for
+// them to point at the new IP
+const acmeDNSInterval = 15 * time.Second
+
+// acmeRenewer renews the certificates of acme.domains after IP changes
+type acmeRenewer struct {
+	runner *hooks.Runner
+	cfg    *config.Config
+	mu     sync.Mutex // Renewals run one at a time, e.g. when both families change
+	log    *logger.Logger
+
+	latestMu sync.Mutex
+	latest   map[ip.Family]string // Newest IP of each family, to drop renewals for replaced ones
+}
+
+// newACMERenewer creates the renewer of the domains in cfg.ACME
+func newACMERenewer(runner *hooks.Runner, cfg *config.Config, log *logger.Logger) *acmeRenewer {
+	return &acmeRenewer{runner: runner, cfg: cfg, log: log, latest: make(map[ip.Family]string)}
+}
+
+// Renew waits for the domains to resolve to newIP and runs the ACME client,
+// reporting a skipped or failed renewal like a failed hook. A renewal is
+// dropped once the IP changed again, as the next one takes over.
+func (r *acmeRenewer) Renew(family ip.Family, oldIP, newIP string, queueNotification func(notify.Event)) {
+	r.latestMu.Lock()
+	r.latest[family] = newIP
+	r.latestMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start := time.Now()
+	report := func(failure config.HookFailure) {
+		failure.Name = "acme"
+		failure.Duration = time.Since(start)
+		queueNotification(notify.NewHookFailureEvent([]config.HookFailure{failure}, time.Now()))
+	}
+
+	timeout := time.Duration(r.cfg.ACME.DNSTimeoutSeconds) * time.Second
+	err := r.waitForDNS(family, newIP, timeout)
+	if r.replaced(family, newIP) {
+		r.log.Debugf("Certificate renewal for %s dropped, the IP changed again", newIP)
+		return
+	}
+	if err != nil {
+		r.log.Errorf("Certificate renewal skipped: %v", err)
+		report(config.HookFailure{Error: "renewal skipped: " + err.Error()})
+		return
+	}
+	r.log.Infof("DNS of %s points at %s, renewing certificates", strings.Join(r.cfg.ACME.Domains, ", "), newIP)
+
+	result := r.runner.Run(context.Background(), hooks.Command{
+		Name: "acme",
+		Path: r.cfg.ACME.Command,
+		Args: r.cfg.ACME.Args,
+		Env: []string{
+			"OLD_IP=" + oldIP,
+			"NEW_IP=" + newIP,
+			"IP_FAMILY=" + string(family),
+			"ACME_DOMAINS=" + strings.Join(r.cfg.ACME.Domains, " "),
+		},
+		Timeout: time.Duration(r.cfg.ACME.TimeoutSeconds) * time.Second,
+		User:    r.cfg.ACME.User,
+	})
+	if !result.Failed() {
+		r.log.Infof("Certificate renewal completed in %v", result.Duration.Round(time.Millisecond))
+		return
+	}
+
+	r.log.Errorf("Certificate renewal failed: %v", result.Err)
+	if output := result.Output(); output != "" {
+		r.log.Errorf("Certificate renewal output:\n%s", output)
+	}
+	report(config.HookFailure{Error: result.Err.Error(), Output: result.Output()})
+}
+
+// waitForDNS resolves the domains until all of them point at newIP, or the
+// timeout expires. The lookups bypass dns_cache, which would keep serving
+// the old address until its TTL runs out, but go through ip.doh if set.
+func (r *acmeRenewer) waitForDNS(family ip.Family, newIP string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resolver := ip.Resolver()
+	if resolver == net.DefaultResolver {
+		resolver = &net.Resolver{}
+	}
+	pending := slices.Clone(r.cfg.ACME.Domains)
+	for {
+		var stale []string
+		var lastErr error
+		for _, domain := range pending {
+			ips, err := ip.ResolveHost(ctx, resolver, domain, family)
+			if err != nil {
+				lastErr = err
+			}
+			if !slices.Contains(ips, newIP) {
+				stale = append(stale, domain)
+			}
+		}
+		if len(stale) == 0 || r.replaced(family, newIP) {
+			return nil
+		}
+		pending = stale
+		r.log.Debugf("Waiting for %s to resolve to %s", strings.Join(pending, ", "), newIP)
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("%s did not resolve to %s within %v: %w", strings.Join(pending, ", "), newIP, timeout, lastErr)
+			}
+			return fmt.Errorf("%s did not resolve to %s within %v", strings.Join(pending, ", "), newIP, timeout)
+		case <-time.After(acmeDNSInterval):
+		}
+	}
+}
+
+// replaced reports whether the IP of the family changed again after newIP
+func (r *acmeRenewer) replaced(family ip.Family, newIP string) bool {
+	r.latestMu.Lock()
+	defer r.latestMu.Unlock()
+	return r.latest[family] != newIP
+}
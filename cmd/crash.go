@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/internal/ip"
+	"public-ip-monitor/internal/logger"
+	"public-ip-monitor/internal/notify"
+)
+
+// crashFile is written to the data directory when the monitor exits on a
+// fatal error, or to the temporary directory if that is not writable
+const crashFile = "crash_report.json"
+
+// fatalNoticeTimeout bounds the stop notice sent on a fatal error. Unlike
+// regular notifications it is not retried: the monitor is going down.
+const fatalNoticeTimeout = 10 * time.Second
+
+// crashReport is the state of the monitor when it exited on a fatal error,
+// for a postmortem
+type crashReport struct {
+	Time      time.Time `json:"time"`
+	Reason    string    `json:"reason"`
+	Stack     string    `json:"stack,omitempty"` // Of a panic
+	Version   string    `json:"version"`
+	GoVersion string    `json:"go_version"`
+	Platform  string    `json:"platform"`
+	PID       int       `json:"pid"`
+	Site      string    `json:"site,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Uptime    string    `json:"uptime"`
+	PausedAt  time.Time `json:"paused_at,omitzero"`
+
+	Addresses            []crashAddress    `json:"addresses,omitempty"`
+	PendingNotifications int               `json:"pending_notifications"`
+	Notified             []string          `json:"notified,omitempty"`      // Channels the stop notice went through
+	NotifyErrors         map[string]string `json:"notify_errors,omitempty"` // Channels it failed on, by name
+}
+
+// crashAddress is the last known IP of a monitored target
+type crashAddress struct {
+	Target string `json:"target"`
+	IP     string `json:"ip"`
+}
+
+// fatalState is what the running monitor adds to a crash report
+type fatalState struct {
+	Status  config.LifecycleStatus // Addresses and paused state
+	Pending int                    // Notifications left in the spool
+}
+
+// fatalReporter ends the monitor on a fatal error. Before exiting it sends
+// a stop notice with the reason through the channels that still work and
+// writes a crash report, both best effort, so that a monitor that died is
+// not mistaken for one that sees no changes.
+type fatalReporter struct {
+	dataDir   string
+	site      string
+	startedAt time.Time
+	daemon    bool // Commands and -check only log the error
+	log       *logger.Logger
+
+	mu        sync.Mutex
+	exiting   bool
+	notifiers []notify.Notifier // Set once the channels are set up
+	settings  *runtimeSettings
+	state     func() fatalState // Set once the monitors are set up
+	onExit    func()            // E.g. flushes deferred writes
+}
+
+func newFatalReporter(cfg *config.Config, startedAt time.Time, daemon bool, log *logger.Logger) *fatalReporter {
+	return &fatalReporter{dataDir: cfg.IP.DataDir, site: cfg.Site, startedAt: startedAt, daemon: daemon, log: log}
+}
+
+// SetChannels sets the channels the stop notice goes through
+func (f *fatalReporter) SetChannels(notifiers []notify.Notifier, settings *runtimeSettings) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.notifiers, f.settings = notifiers, settings
+}
+
+// SetState sets how to read the state of the running monitor
+func (f *fatalReporter) SetState(state func() fatalState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state = state
+}
+
+// OnExit sets a function run before exiting
+func (f *fatalReporter) OnExit(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onExit = fn
+}
+
+// Exitf logs the fatal error, reports it and exits
+func (f *fatalReporter) Exitf(format string, args ...any) {
+	f.log.Errorf(format, args...)
+	f.exit(fmt.Sprintf(format, args...), "")
+}
+
+// Recover reports a panic of the calling goroutine and exits, when
+// deferred. The stack goes to the crash report rather than the log.
+func (f *fatalReporter) Recover() {
+	if r := recover(); r != nil {
+		reason := fmt.Sprintf("panic: %v", r)
+		f.log.Errorf("Monitor crashed: %s", reason)
+		f.exit(reason, string(debug.Stack()))
+	}
+}
+
+func (f *fatalReporter) exit(reason, stack string) {
+	if !f.daemon {
+		os.Exit(1)
+	}
+
+	f.mu.Lock()
+	if f.exiting {
+		// Another goroutine is reporting already and exits when done
+		f.mu.Unlock()
+		select {}
+	}
+	f.exiting = true
+	notifiers, settings, state, onExit := f.notifiers, f.settings, f.state, f.onExit
+	f.mu.Unlock()
+
+	now := time.Now()
+	report := crashReport{
+		Time:      now,
+		Reason:    reason,
+		Stack:     stack,
+		Version:   version,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		PID:       os.Getpid(),
+		Site:      f.site,
+		StartedAt: f.startedAt,
+		Uptime:    now.Sub(f.startedAt).Round(time.Second).String(),
+	}
+	status := config.LifecycleStatus{Kind: config.LifecycleStopped, Site: f.site, Version: version, Uptime: now.Sub(f.startedAt), Reason: reason}
+	if state != nil {
+		current := state()
+		status.PausedAt, status.Addresses = current.Status.PausedAt, current.Status.Addresses
+		report.PausedAt, report.PendingNotifications = current.Status.PausedAt, current.Pending
+		for _, address := range current.Status.Addresses {
+			report.Addresses = append(report.Addresses, crashAddress{Target: address.Label(), IP: address.Value()})
+		}
+	}
+
+	if len(notifiers) > 0 {
+		report.Notified, report.NotifyErrors = f.sendNotice(notify.NewLifecycleEvent(status, now), notifiers, settings)
+	}
+	f.writeReport(report)
+	if onExit != nil {
+		onExit()
+	}
+	os.Exit(1)
+}
+
+// sendNotice sends the stop notice through every channel taking it at
+// once, without retries. Channels whose circuit breaker is open fail right
+// away, so only those that worked lately are waited for.
+func (f *fatalReporter) sendNotice(event notify.Event, notifiers []notify.Notifier, settings *runtimeSettings) ([]string, map[string]string) {
+	event.Site = f.site
+	ctx, cancel := context.WithTimeout(context.Background(), fatalNoticeTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var notified []string
+	errs := make(map[string]string)
+	for _, notifier := range notifiers {
+		if !notify.Accepts(notifier, event) {
+			continue
+		}
+		if settings != nil && settings.Muted(notifier.Name()) {
+			continue
+		}
+		if settings != nil && settings.DryRun() {
+			f.log.Infof("Dry run, not sending %s notification%s: %s", notifier.Name(), eventRef(event), describeEvent(event))
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := notifier.Notify(ctx, event)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[notifier.Name()] = err.Error()
+				return
+			}
+			notified = append(notified, notifier.Name())
+		}()
+	}
+
+	// Channels still sending when the timeout passes are left behind
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(notified) > 0 {
+		f.log.Infof("Sent the stop notice via %v", notified)
+	}
+	for name, err := range errs {
+		f.log.Warnf("Failed to send the stop notice via %s: %s", name, err)
+	}
+	return slices.Clone(notified), maps.Clone(errs)
+}
+
+// writeReport writes the crash report to the data directory, or to the
+// temporary directory when the data directory is what failed
+func (f *fatalReporter) writeReport(report crashReport) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return
+	}
+	for _, dir := range []string{f.dataDir, os.TempDir()} {
+		path := filepath.Join(dir, crashFile)
+		if err := os.WriteFile(path, data, ip.DataFilePerm); err != nil {
+			f.log.Warnf("Failed to write crash report: %v", err)
+			continue
+		}
+		f.log.Infof("Crash report written to %s", path)
+		return
+	}
+}
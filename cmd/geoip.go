@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/internal/geo"
+	"public-ip-monitor/internal/logger"
+	"public-ip-monitor/internal/scheduler"
+)
+
+// geoIPDatabase is a MaxMind database file and the edition it is updated from
+type geoIPDatabase struct {
+	edition string
+	path    string
+}
+
+// openGeoIP opens the MaxMind databases of the "maxmind" geolocation
+// provider. With a license key, missing databases are downloaded first and
+// all of them are updated on the geoip schedule.
+func openGeoIP(cfg *config.Config, taskScheduler *scheduler.Scheduler, log *logger.Logger) (*geo.DB, error) {
+	maxmind := cfg.Enrichment.Geolocation.MaxMind
+	databases := []geoIPDatabase{
+		{edition: geo.EditionCity, path: dataPath(cfg, maxmind.CityDatabase)},
+		{edition: geo.EditionASN, path: dataPath(cfg, maxmind.ASNDatabase)},
+	}
+
+	var updater *geo.Updater
+	if maxmind.LicenseKey != "" {
+		updater = geo.NewUpdater(maxmind.AccountID, maxmind.LicenseKey, maxmind.DownloadURL, 0)
+		for _, database := range databases {
+			if _, err := os.Stat(database.path); !errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			log.Infof("Downloading the %s database to %s", database.edition, database.path)
+			if _, err := updater.Update(context.Background(), database.edition, database.path); err != nil {
+				if database.edition == geo.EditionCity {
+					return nil, fmt.Errorf("failed to download the %s database: %w", database.edition, err)
+				}
+				log.Warnf("Failed to download the %s database, looking up the ASN through the network: %v", database.edition, err)
+			}
+		}
+	}
+
+	db, err := geo.OpenDB(databases[0].path, databases[1].path)
+	if err != nil {
+		return nil, err
+	}
+	for _, meta := range db.Databases() {
+		log.Infof("Using the %s database built %s", meta.DatabaseType, meta.BuildTime.Format("2006-01-02"))
+	}
+
+	if updater != nil {
+		err = taskScheduler.Add(config.ScheduleGeoIP, config.GetSchedule(cfg, config.ScheduleGeoIP), func(ctx context.Context) {
+			updateGeoIP(ctx, updater, db, databases, log)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to schedule database updates: %w", err)
+		}
+	}
+	return db, nil
+}
+
+// updateGeoIP downloads the databases published since the last update and
+// switches lookups over to them
+func updateGeoIP(ctx context.Context, updater *geo.Updater, db *geo.DB, databases []geoIPDatabase, log *logger.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	updated := false
+	for _, database := range databases {
+		replaced, err := updater.Update(ctx, database.edition, database.path)
+		if err != nil {
+			log.Warnf("Update of the %s database failed: %v", database.edition, err)
+			continue
+		}
+		if replaced {
+			log.Infof("%s database updated", database.edition)
+			updated = true
+		} else {
+			log.Debugf("%s database is up to date", database.edition)
+		}
+	}
+	if !updated {
+		return
+	}
+	if err := db.Reload(); err != nil {
+		log.Warnf("Failed to load the updated databases, keeping the previous ones: %v", err)
+	}
+}
+
+// dataPath resolves a file name against ip.data_dir, unless it is absolute
+func dataPath(cfg *config.Config, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(cfg.IP.DataDir, name)
+}
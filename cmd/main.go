@@ -2,41 +2,192 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"maps"
+	"math"
+	"net"
+	"net/url"
 	"os"
 	"os/signal"
-	"runtime"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
+	"public-ip-monitor/internal/api"
+	"public-ip-monitor/internal/chaos"
 	"public-ip-monitor/internal/config"
+	"public-ip-monitor/internal/debughttp"
+	"public-ip-monitor/internal/dnscache"
+	"public-ip-monitor/internal/doh"
+	"public-ip-monitor/internal/enrich"
+	"public-ip-monitor/internal/gateway"
+	"public-ip-monitor/internal/hooks"
 	"public-ip-monitor/internal/ip"
 	"public-ip-monitor/internal/logger"
+	"public-ip-monitor/internal/notify"
+	"public-ip-monitor/internal/privacy"
+	"public-ip-monitor/internal/proxy"
+	"public-ip-monitor/internal/resources"
+	"public-ip-monitor/internal/scheduler"
+	"public-ip-monitor/internal/update"
+	"public-ip-monitor/pkg/clock"
+	"public-ip-monitor/pkg/dingtalk"
+	"public-ip-monitor/pkg/discord"
 	"public-ip-monitor/pkg/email"
+	"public-ip-monitor/pkg/file"
+	"public-ip-monitor/pkg/line"
+	"public-ip-monitor/pkg/matrix"
+	"public-ip-monitor/pkg/mqtt"
+	"public-ip-monitor/pkg/ntfy"
+	"public-ip-monitor/pkg/openpgp"
+	"public-ip-monitor/pkg/pagerduty"
+	"public-ip-monitor/pkg/ping"
+	"public-ip-monitor/pkg/sheets"
+	"public-ip-monitor/pkg/shortlink"
+	"public-ip-monitor/pkg/slack"
+	"public-ip-monitor/pkg/sns"
+	"public-ip-monitor/pkg/syslog"
+	"public-ip-monitor/pkg/teams"
+	"public-ip-monitor/pkg/webhook"
+	"public-ip-monitor/pkg/wecom"
 	"public-ip-monitor/pkg/whatsapp"
 )
 
 // version is set at build time using -ldflags
 var version string
 
+// appClock paces the checks, scheduled tasks and retries. Simulations
+// replace it with a fake clock to run them fast-forwarded.
+var appClock = clock.System
+
 func main() {
 	// Parse command line flags
 	var (
 		configPath  = flag.String("config", "config.json", "Path to configuration file")
 		showHistory = flag.Bool("history", false, "Show IP change history and exit")
 		checkOnce   = flag.Bool("check", false, "Check IP once and exit")
+		debugHTTP   = flag.Bool("debug-http", false, "Log sanitized summaries of outbound HTTP requests")
+		debugDir    = flag.String("debug-http-dir", "", "Also write full HTTP requests and responses to this directory (implies -debug-http)")
+		chaosSpec   = flag.String("chaos", "", "Inject faults, e.g. \"fetch=0.3,notify=0.5,slow=0.1,delay=5s\" or \"on\" (testing only)")
+		simulate    = flag.String("simulate", "", "Run against built-in stubs of an IP service, the WhatsApp API and an SMTP server listening on this address, or \"on\" for a free port (testing only)")
+		envOnly     = flag.Bool("env", false, "Read the configuration from "+config.EnvPrefix+"* environment variables only, without a config file")
+		ipv4Only    = flag.Bool("4", false, "Check the IPv4 address only, overriding ip.families")
+		ipv6Only    = flag.Bool("6", false, "Check the IPv6 address only, overriding ip.families")
 	)
+	flag.Usage = usage
 	flag.Parse()
 
 	// Load configuration
 	configManager := config.NewManager(*configPath)
+	if *envOnly {
+		configManager = config.NewEnvManager()
+	}
+
+	// Configuration commands print to stdout without logging
+	if flag.NArg() > 0 && flag.Arg(0) == "config" {
+		if err := runConfigCommand(flag.Args()[1:], configManager); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	cfg, err := configManager.Load()
 	if err != nil {
 		fmt.Printf("Error loading configuration: %v\n", err)
 		os.Exit(1)
 	}
+	config.SetBranding(cfg.Branding)
+	config.SetMessageLimits(cfg.MessageLimits)
+	if err := config.SetDigestTemplates(cfg.Digest); err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Pin every check to one family, so a dual-stack host does not report
+	// whichever the OS prefers
+	switch {
+	case *ipv4Only && *ipv6Only:
+		fmt.Println("Error: -4 and -6 cannot be combined; set ip.families to monitor both")
+		os.Exit(1)
+	case *ipv4Only:
+		cfg.IP.Families = []string{"ipv4"}
+	case *ipv6Only:
+		cfg.IP.Families = []string{"ipv6"}
+	}
+
+	// Hide IPs in logs and shared outputs; notifications get full values
+	redactor, err := privacy.New(cfg.Privacy.Mode, cfg.Privacy.Salt)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// History exports go to stdout without logging too
+	if flag.NArg() > 0 && flag.Arg(0) == "history" {
+		storage := ip.NewStorage(cfg.IP.DataDir, cfg.IP.RecordsFile, cfg.IP.LastIPFile)
+		location, err := time.LoadLocation(cfg.Logging.Timezone)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := runHistoryCommand(flag.Args()[1:], storage, cfg, redactor, location); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Failed notifications are listed without logging too
+	if flag.NArg() > 0 && flag.Arg(0) == "notifications" && flag.Arg(1) != "resend" {
+		location, err := time.LoadLocation(cfg.Logging.Timezone)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if flag.Arg(1) == "quota" {
+			err = runQuotaCommand(cfg, location)
+		} else {
+			err = runListFailed(flag.Args(), filepath.Join(cfg.IP.DataDir, deadLetterFile), location)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Events are listed and acknowledged without logging too; "events
+	// replay" needs the channels
+	if flag.NArg() > 0 && (flag.Arg(0) == "ack" || (flag.Arg(0) == "events" && flag.Arg(1) != "replay")) {
+		location, err := time.LoadLocation(cfg.Logging.Timezone)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := runEventsCommand(flag.Args(), cfg.IP.DataDir, location); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Container health checks print a single line without logging
+	if flag.NArg() > 0 && flag.Arg(0) == "healthcheck" {
+		if err := runHealthcheck(filepath.Join(config.GetRuntimeDir(cfg), healthFile), config.GetCheckInterval(cfg)); err != nil {
+			fmt.Printf("unhealthy: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Initialize logger
 	log, err := logger.New(cfg.Logging)
@@ -44,58 +195,413 @@ func main() {
 		fmt.Printf("Error initializing logger: %v\n", err)
 		os.Exit(1)
 	}
+	if redactor != nil {
+		log.SetRedact(redactor.Text)
+	}
 
 	if version == "" {
 		version = "dev" // Fallback for non-built binaries
 	}
 
+	startedAt := time.Now()
 	log.Info("Starting program...")
 	log.Infof("Version: %s", version)
 
+	// Report fatal errors of the daemon through the channels and a crash file
+	fatal := newFatalReporter(cfg, startedAt, flag.NArg() == 0 && !*checkOnce && !*showHistory, log)
+
+	// Log outbound requests of the fetcher and the notification clients
+	if *debugHTTP || *debugDir != "" {
+		if err := debughttp.Enable(log.Debugf, *debugDir); err != nil {
+			fatal.Exitf("Failed to enable HTTP debugging: %v", err)
+		}
+		if *debugDir != "" {
+			log.Infof("HTTP debugging enabled, capturing exchanges in %s", *debugDir)
+		} else {
+			log.Info("HTTP debugging enabled")
+		}
+	}
+
+	// Inject faults to exercise failure handling (testing only)
+	var chaosSettings *chaos.Settings
+	if *chaosSpec != "" {
+		settings, err := chaos.Parse(*chaosSpec)
+		if err != nil {
+			fatal.Exitf("Invalid chaos settings: %v", err)
+		}
+		chaos.Enable(settings)
+		chaosSettings = &settings
+		log.Warnf("Chaos mode enabled: %s", settings)
+	}
+
+	// Exercise the whole pipeline against local stub servers (testing only)
+	var simulated *simulation
+	if *simulate != "" {
+		simulated, err = startSimulation(*simulate, cfg, log)
+		if err != nil {
+			fatal.Exitf("Failed to start simulation: %v", err)
+		}
+		defer simulated.Close()
+	}
+
+	// Cache DNS answers of every client (IP services, SMTP, notification APIs)
+	var dnsCache *dnscache.Cache
+	if cfg.DNSCache.Enabled {
+		dnsCache = dnscache.New(dnscache.Settings{
+			MaxTTL:      time.Duration(cfg.DNSCache.MaxTTLSeconds) * time.Second,
+			NegativeTTL: time.Duration(cfg.DNSCache.NegativeTTLSeconds) * time.Second,
+			StaleTTL:    time.Duration(cfg.DNSCache.StaleTTLSeconds) * time.Second,
+		})
+		dnsCache.Install()
+		log.Info("DNS cache enabled")
+	}
+
+	// Reach the IP services and notification APIs through a proxy
+	proxyFunc, err := proxy.New(cfg.Proxy.URL, cfg.Proxy.NoProxy)
+	if err != nil {
+		fatal.Exitf("Invalid proxy: %v", err)
+	}
+	proxy.Install(proxyFunc)
+	switch cfg.Proxy.URL {
+	case "":
+	case proxy.Direct:
+		log.Info("Connecting without a proxy, ignoring HTTP_PROXY and HTTPS_PROXY")
+	default:
+		u, _ := proxy.Parse(cfg.Proxy.URL)
+		log.Infof("Using proxy %s", u.Redacted())
+	}
+
+	// Install releases, printing to stdout
+	if flag.NArg() > 0 && flag.Arg(0) == "self-update" {
+		if err := runSelfUpdate(flag.Args()[1:], cfg); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Size the Go runtime to the container instead of the host
+	applied := resources.Apply(resources.Settings{
+		GOMAXPROCS:       cfg.Resources.GOMAXPROCS,
+		MemoryLimitBytes: int64(cfg.Resources.MemoryLimitMB) << 20,
+		BallastBytes:     cfg.Resources.BallastMB << 20,
+	})
+	logResources(applied, log)
+
 	// Initialize IP storage
 	storage := ip.NewStorage(cfg.IP.DataDir, cfg.IP.RecordsFile, cfg.IP.LastIPFile)
 	if err := storage.Initialize(); err != nil {
-		log.Errorf("Failed to initialize storage: %v", err)
-		os.Exit(1)
+		fatal.Exitf("Failed to initialize storage: %v", err)
+	}
+
+	// Spare SD cards: last IPs and checks are written periodically, and the
+	// health file goes to a tmpfs
+	lowWrite := cfg.LowWrite.Enabled && flag.NArg() == 0 && !*checkOnce && !*showHistory
+	if lowWrite {
+		storage.DeferWrites()
+		if err := os.MkdirAll(config.GetRuntimeDir(cfg), 0755); err != nil {
+			fatal.Exitf("Failed to create runtime directory: %v", err)
+		}
+		log.Infof("Low-write mode enabled, flushing %s; health file in %s", config.GetSchedule(cfg, config.ScheduleFlush), config.GetRuntimeDir(cfg))
+	}
+
+	// Trust a private CA and present a client certificate to the services
+	if cfg.IP.TLS.CAFile != "" || cfg.IP.TLS.CertFile != "" {
+		tlsConfig, err := ip.LoadTLSConfig(cfg.IP.TLS.CAFile, cfg.IP.TLS.CertFile, cfg.IP.TLS.KeyFile)
+		if err != nil {
+			fatal.Exitf("Invalid ip.tls: %v", err)
+		}
+		ip.SetTLSConfig(tlsConfig)
+	}
+
+	// Look the services up through DNS over HTTPS, past a broken resolver
+	if cfg.IP.DoH.URL != "" {
+		client, err := doh.New(cfg.IP.DoH.URL, cfg.IP.DoH.BootstrapIPs, time.Duration(cfg.IP.TimeoutSeconds)*time.Second)
+		if err != nil {
+			fatal.Exitf("Invalid ip.doh: %v", err)
+		}
+		ip.SetResolver(client.Resolver())
+		log.Infof("Resolving service hostnames through DNS over HTTPS (%s)", cfg.IP.DoH.URL)
 	}
 
 	// Initialize IP fetcher
 	fetcher := ip.NewFetcher(cfg.IP.Services, cfg.IP.TimeoutSeconds)
+	fetcher.SetRequestHeaders(ipUserAgent(cfg), cfg.IP.ServiceHeaders)
+	if len(cfg.IP.Sources) > 0 {
+		specs := make([]ip.SourceSpec, 0, len(cfg.IP.Sources))
+		for _, source := range cfg.IP.Sources {
+			specs = append(specs, ip.SourceSpec(source))
+		}
+		if err := fetcher.SetSources(specs); err != nil {
+			fatal.Exitf("Invalid IP source: %v", err)
+		}
+		log.Infof("Using %d additional IP sources", len(specs))
+	}
+	if cfg.IP.FetchMode == "race" {
+		fetcher.SetRace(true)
+		log.Info("Racing IP services and sources, taking the first answer")
+	}
+	if cfg.IP.Quorum > 1 {
+		fetcher.SetQuorum(cfg.IP.Quorum)
+		log.Infof("Accepting an IP once %d services or sources agree", cfg.IP.Quorum)
+	}
+
+	// Track how every service answers, for "services status" and to try
+	// flaky ones last
+	serviceHealth := ip.NewHealthTracker(filepath.Join(cfg.IP.DataDir, serviceHealthFile))
+	if err := serviceHealth.Load(); err != nil {
+		log.Warnf("Starting service health over: %v", err)
+	}
+	fetcher.SetHealthTracker(serviceHealth, cfg.IP.AdaptiveOrder)
+	if cfg.IP.AdaptiveOrder {
+		log.Info("Trying flaky IP services and sources last")
+	}
+
+	// Resolve the address families to monitor
+	families := []ip.Family{ip.FamilyAny}
+	if len(cfg.IP.Families) > 0 {
+		families = families[:0]
+		for _, name := range cfg.IP.Families {
+			family, err := ip.ParseFamily(name)
+			if err != nil {
+				fatal.Exitf("Invalid IP family: %v", err)
+			}
+			families = append(families, family)
+		}
+	}
+
+	// Benchmark the IP services, printing to stdout
+	if flag.NArg() > 0 && flag.Arg(0) == "services" {
+		if err := runServicesCommand(flag.Args()[1:], fetcher, families, cfg, configManager); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Handle history command
 	if *showHistory {
-		monitor := ip.NewMonitor(fetcher, storage, nil)
+		monitor := ip.NewMonitor(fetcher, storage)
 		if err := monitor.PrintHistory(); err != nil {
-			log.Errorf("Failed to print history: %v", err)
+			fatal.Exitf("Failed to print history: %v", err)
+		}
+		for _, wan := range cfg.IP.WANs {
+			fmt.Printf("\nWAN %s:", wan.Name)
+			monitor := ip.NewMonitor(fetcher, storage.ForWAN(wan.Name))
+			if err := monitor.PrintHistory(); err != nil {
+				fatal.Exitf("Failed to print history for WAN %s: %v", wan.Name, err)
+			}
+		}
+		return
+	}
+
+	// Auxiliary tasks run on cron-like schedules from the config
+	taskScheduler := scheduler.New(log.Location())
+	taskScheduler.SetClock(appClock)
+
+	// Use the remote services index when configured
+	var indexLoader *ip.IndexLoader
+	if cfg.IP.ServicesIndex.URL != "" {
+		indexLoader, err = ip.NewIndexLoader(
+			cfg.IP.ServicesIndex.URL,
+			cfg.IP.ServicesIndex.PublicKey,
+			filepath.Join(cfg.IP.DataDir, cfg.IP.ServicesIndex.CacheFile),
+			cfg.IP.TimeoutSeconds,
+		)
+		if err != nil {
+			fatal.Exitf("Failed to configure services index: %v", err)
+		}
+
+		if index, err := indexLoader.LoadCached(); err == nil {
+			fetcher.SetServices(index.Services)
+			log.Infof("Using %d services from cached services index", len(index.Services))
+		}
+
+		err = taskScheduler.Add(config.ScheduleServicesIndex, config.GetSchedule(cfg, config.ScheduleServicesIndex), func(ctx context.Context) {
+			refreshServicesIndex(ctx, indexLoader, fetcher, log)
+		})
+		if err != nil {
+			fatal.Exitf("Failed to schedule services index refresh: %v", err)
+		}
+	}
+
+	// Look for new releases, installing them with update.auto_apply; the
+	// restart goes through the shutdown set up below
+	var restartForUpdate func()
+	if cfg.Update.ManifestURL != "" {
+		updater, err := update.New(cfg.Update.ManifestURL, cfg.Update.PublicKey, cfg.Update.TimeoutSeconds)
+		if err != nil {
+			fatal.Exitf("Failed to configure updates: %v", err)
+		}
+		checker := &updateChecker{updater: updater, autoApply: cfg.Update.AutoApply, log: log}
+		err = taskScheduler.Add(config.ScheduleUpdate, config.GetSchedule(cfg, config.ScheduleUpdate), func(ctx context.Context) {
+			if checker.Check(ctx) && restartForUpdate != nil {
+				restartForUpdate()
+			}
+		})
+		if err != nil {
+			fatal.Exitf("Failed to schedule update checks: %v", err)
+		}
+	}
+
+	err = taskScheduler.Add(config.ScheduleResourceUsage, config.GetSchedule(cfg, config.ScheduleResourceUsage), func(ctx context.Context) {
+		logResourceUsage(log)
+		if dnsCache != nil {
+			hits, misses, stale := dnsCache.Stats()
+			log.Infof("DNS cache: %d hits, %d misses, %d expired answers served while DNS failed", hits, misses, stale)
+		}
+	})
+	if err != nil {
+		fatal.Exitf("Failed to schedule resource usage logging: %v", err)
+	}
+
+	// Data past its retention is pruned with the notification stores set
+	// up below, before the scheduler runs
+	var pruner *dataPruner
+	err = taskScheduler.Add(config.ScheduleRetention, config.GetSchedule(cfg, config.ScheduleRetention), func(ctx context.Context) {
+		if pruned, err := pruner.Prune(ctx); err != nil {
+			log.Errorf("Failed to prune data: %v", err)
+		} else {
+			logPruned(pruned, log)
+		}
+	})
+	if err != nil {
+		fatal.Exitf("Failed to schedule data pruning: %v", err)
+	}
+
+	// The heartbeat goes through the notification queue set up below
+	var heartbeat func()
+	if cfg.Lifecycle.Heartbeat {
+		err = taskScheduler.Add(config.ScheduleHeartbeat, config.GetSchedule(cfg, config.ScheduleHeartbeat), func(ctx context.Context) {
+			if heartbeat != nil {
+				heartbeat()
+			}
+		})
+		if err != nil {
+			fatal.Exitf("Failed to schedule heartbeat: %v", err)
+		}
+	}
+
+	// Low-write mode flushes the last IPs and the check log set up below
+	var flushWrites func()
+	if cfg.LowWrite.Enabled {
+		err = taskScheduler.Add(config.ScheduleFlush, config.GetSchedule(cfg, config.ScheduleFlush), func(ctx context.Context) {
+			if flushWrites != nil {
+				flushWrites()
+			}
+		})
+		if err != nil {
+			fatal.Exitf("Failed to schedule flushing: %v", err)
+		}
+	}
+
+	// Unacknowledged events are escalated by the notification worker, within
+	// seconds of being due by default
+	var escalate <-chan time.Time
+	if cfg.Escalation.Enabled {
+		escalate, err = scheduleTicks(taskScheduler, config.ScheduleEscalation, config.GetSchedule(cfg, config.ScheduleEscalation))
+		if err != nil {
+			fatal.Exitf("Failed to schedule escalation: %v", err)
+		}
+	}
+
+	// Notifications held for quiet hours or quotas are sent by the
+	// notification worker, within a minute of the hours ending or the quotas
+	// resetting by default
+	var release <-chan time.Time
+	if cfg.QuietHours.Enabled || len(cfg.Quotas.Channels) > 0 {
+		release, err = scheduleTicks(taskScheduler, config.ScheduleRelease, config.GetSchedule(cfg, config.ScheduleRelease))
+		if err != nil {
+			fatal.Exitf("Failed to schedule held notifications: %v", err)
+		}
+	}
+
+	// Events left in the webhook outboxes set up below are sent again
+	var replayWebhooks func(ctx context.Context)
+	if cfg.Webhook.Enabled && cfg.Webhook.Outbox.Enabled {
+		err = taskScheduler.Add(config.ScheduleWebhookOutbox, config.GetSchedule(cfg, config.ScheduleWebhookOutbox), func(ctx context.Context) {
+			if replayWebhooks != nil {
+				replayWebhooks(ctx)
+			}
+		})
+		if err != nil {
+			fatal.Exitf("Failed to schedule webhook outbox replays: %v", err)
+		}
+	}
+
+	// Handle subcommands; "notify test", "notifications resend" and "events
+	// replay" need the channels set up below
+	if flag.NArg() > 0 && flag.Arg(0) != "notify" && flag.Arg(0) != "notifications" && flag.Arg(0) != "events" {
+		if err := runCommand(flag.Args(), taskScheduler, log.Location()); err != nil {
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
 		return
 	}
 
+	if indexLoader != nil {
+		refreshServicesIndex(context.Background(), indexLoader, fetcher, log)
+	}
+
+	// Channels every notification event is delivered through
+	var notifiers []notify.Notifier
+
 	// Initialize email client (independent)
-	var emailClient email.Client
 	if cfg.Email.Enabled {
-		emailFactory := email.NewSMTPFactory()
+		var emailFactory email.Factory = email.NewSMTPFactory()
+		if cfg.Email.Backend == "gmail" {
+			emailFactory = email.NewGmailFactory()
+		}
 		emailConfig := email.Config{
 			From:     cfg.Email.From,
+			FromName: cfg.Email.FromName,
 			Password: cfg.Email.Password,
 			SMTPHost: cfg.Email.SMTPHost,
 			SMTPPort: cfg.Email.SMTPPort,
 			Timeout:  cfg.Email.Timeout,
+			Logf:     log.Warnf,
+			Gmail: email.GmailAuth{
+				ClientID:     cfg.Email.Gmail.ClientID,
+				ClientSecret: cfg.Email.Gmail.ClientSecret,
+				RefreshToken: cfg.Email.Gmail.RefreshToken,
+			},
 		}
-		emailClient, err = emailFactory.NewClient(emailConfig)
+		if simulated != nil {
+			emailConfig.RootCAs = simulated.server.SMTP.CertPool()
+		}
+		for _, relay := range cfg.Email.Relays {
+			emailConfig.Relays = append(emailConfig.Relays, email.Relay{
+				Host:     relay.SMTPHost,
+				Port:     relay.SMTPPort,
+				Username: relay.Username,
+				Password: relay.Password,
+			})
+		}
+		emailClient, err := emailFactory.NewClient(emailConfig)
 		if err != nil {
-			log.Errorf("Failed to create email client: %v", err)
-			os.Exit(1)
+			fatal.Exitf("Failed to create email client: %v", err)
 		}
 		defer emailClient.Close()
-		log.Info("Email notifications enabled")
+
+		var emailKey *openpgp.PublicKey
+		if cfg.Email.PGPPublicKeyFile != "" {
+			armored, err := os.ReadFile(cfg.Email.PGPPublicKeyFile)
+			if err != nil {
+				fatal.Exitf("Failed to read email encryption key: %v", err)
+			}
+			emailKey, err = openpgp.ReadPublicKey(armored)
+			if err != nil {
+				fatal.Exitf("Invalid email encryption key %s: %v", cfg.Email.PGPPublicKeyFile, err)
+			}
+			log.Infof("Email bodies encrypted to OpenPGP key %s", emailKey)
+		}
+		notifiers = append(notifiers, notify.Route(notify.NewEmailNotifier(emailClient, cfg.Email.To, emailKey), cfg.Email.Events))
+		log.Infof("Email notifications enabled (%s)", cfg.Email.Backend)
 	} else {
 		log.Info("Email notifications disabled")
 	}
 
 	// Initialize WhatsApp client (independent)
-	var whatsappClient whatsapp.Client
 	if cfg.WhatsApp.Enabled {
 		whatsappFactory := whatsapp.NewMetaFactory()
 		whatsappConfig := whatsapp.Config{
@@ -104,264 +610,3638 @@ func main() {
 			APIVersion:     cfg.WhatsApp.APIVersion,
 			TimeoutSeconds: cfg.WhatsApp.TimeoutSeconds,
 		}
-		whatsappClient, err = whatsappFactory.NewClient(whatsappConfig)
+		if simulated != nil {
+			whatsappConfig.BaseURL = simulated.server.URL()
+		}
+		whatsappClient, err := whatsappFactory.NewClient(whatsappConfig)
 		if err != nil {
-			log.Errorf("Failed to create WhatsApp client: %v", err)
-			os.Exit(1)
+			fatal.Exitf("Failed to create WhatsApp client: %v", err)
 		}
 		defer whatsappClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewWhatsAppNotifier(whatsappClient, cfg.WhatsApp.RecipientNumber, cfg.WhatsApp.RecentChanges), cfg.WhatsApp.Events))
 		log.Info("WhatsApp notifications enabled")
 	} else {
 		log.Info("WhatsApp notifications disabled")
 	}
 
-	// Pre-allocate channels for notifications to avoid blocking
-	notificationChan := make(chan notificationRequest, 10) // Buffered channel
+	// Initialize Slack client (independent)
+	if cfg.Slack.Enabled {
+		slackFactory := slack.NewAPIFactory()
+		slackConfig := slack.Config{
+			WebhookURL:     cfg.Slack.WebhookURL,
+			Token:          cfg.Slack.Token,
+			Channel:        cfg.Slack.Channel,
+			TimeoutSeconds: cfg.Slack.TimeoutSeconds,
+		}
+		slackClient, err := slackFactory.NewClient(slackConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create Slack client: %v", err)
+		}
+		defer slackClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewSlackNotifier(slackClient), cfg.Slack.Events))
+		log.Info("Slack notifications enabled")
+	} else {
+		log.Info("Slack notifications disabled")
+	}
 
-	// Start notification worker goroutine
-	go notificationWorker(notificationChan, emailClient, whatsappClient, cfg, log)
+	// Initialize Discord client (independent)
+	if cfg.Discord.Enabled {
+		discordFactory := discord.NewWebhookFactory()
+		discordConfig := discord.Config{
+			WebhookURL:     cfg.Discord.WebhookURL,
+			Username:       cfg.Discord.Username,
+			TimeoutSeconds: cfg.Discord.TimeoutSeconds,
+		}
+		discordClient, err := discordFactory.NewClient(discordConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create Discord client: %v", err)
+		}
+		defer discordClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewDiscordNotifier(discordClient), cfg.Discord.Events))
+		log.Info("Discord notifications enabled")
+	} else {
+		log.Info("Discord notifications disabled")
+	}
 
-	// Create IP change handler with async notifications
-	changeHandler := func(oldIP, newIP string) error {
-		if oldIP == "" {
-			oldIP = "Unknown"
+	// Initialize Teams client (independent)
+	if cfg.Teams.Enabled {
+		teamsFactory := teams.NewWebhookFactory()
+		teamsConfig := teams.Config{
+			WebhookURL:     cfg.Teams.WebhookURL,
+			TimeoutSeconds: cfg.Teams.TimeoutSeconds,
 		}
+		teamsClient, err := teamsFactory.NewClient(teamsConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create Teams client: %v", err)
+		}
+		defer teamsClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewTeamsNotifier(teamsClient), cfg.Teams.Events))
+		log.Info("Teams notifications enabled")
+	} else {
+		log.Info("Teams notifications disabled")
+	}
 
-		log.Infof("IP changed from %s to %s", oldIP, newIP)
+	// Initialize Google Sheets client (independent)
+	if cfg.Sheets.Enabled {
+		sheetsFactory := sheets.NewAPIFactory()
+		sheetsConfig := sheets.Config{
+			CredentialsFile: cfg.Sheets.CredentialsFile,
+			SpreadsheetID:   cfg.Sheets.SpreadsheetID,
+			SheetName:       cfg.Sheets.SheetName,
+			TimeoutSeconds:  cfg.Sheets.TimeoutSeconds,
+		}
+		sheetsClient, err := sheetsFactory.NewClient(sheetsConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create Google Sheets client: %v", err)
+		}
+		defer sheetsClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewSheetsNotifier(sheetsClient), cfg.Sheets.Events))
+		log.Info("Google Sheets export enabled")
+	} else {
+		log.Info("Google Sheets export disabled")
+	}
 
-		// Send notification request asynchronously
-		select {
-		case notificationChan <- notificationRequest{
-			OldIP:     oldIP,
-			NewIP:     newIP,
-			Timestamp: time.Now(),
-		}:
-			// Notification queued successfully
-		default:
-			// Channel full, log warning but don't block
-			log.Warn("Notification channel full, dropping notification")
+	// Initialize file output (independent)
+	if cfg.File.Enabled {
+		fileFactory := file.NewLocalFactory()
+		fileClient, err := fileFactory.NewClient(file.Config{Path: cfg.File.Path})
+		if err != nil {
+			fatal.Exitf("Failed to create file client: %v", err)
 		}
+		defer fileClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewFileNotifier(fileClient), cfg.File.Events))
+		log.Infof("File notifications enabled (%s)", cfg.File.Path)
+	} else {
+		log.Info("File notifications disabled")
+	}
 
-		return nil
+	// Initialize Matrix client (independent)
+	if cfg.Matrix.Enabled {
+		matrixFactory := matrix.NewAPIFactory()
+		matrixConfig := matrix.Config{
+			HomeserverURL:  cfg.Matrix.HomeserverURL,
+			AccessToken:    cfg.Matrix.AccessToken,
+			RoomID:         cfg.Matrix.RoomID,
+			TimeoutSeconds: cfg.Matrix.TimeoutSeconds,
+		}
+		matrixClient, err := matrixFactory.NewClient(matrixConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create Matrix client: %v", err)
+		}
+		defer matrixClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewMatrixNotifier(matrixClient), cfg.Matrix.Events))
+		log.Info("Matrix notifications enabled")
+	} else {
+		log.Info("Matrix notifications disabled")
 	}
 
-	// Initialize IP monitor
-	monitor := ip.NewMonitor(fetcher, storage, changeHandler)
+	// Initialize ntfy client (independent)
+	if cfg.Ntfy.Enabled {
+		ntfyFactory := ntfy.NewHTTPFactory()
+		ntfyConfig := ntfy.Config{
+			Server:         cfg.Ntfy.Server,
+			Topic:          cfg.Ntfy.Topic,
+			Priority:       cfg.Ntfy.Priority,
+			Token:          cfg.Ntfy.Token,
+			TimeoutSeconds: cfg.Ntfy.TimeoutSeconds,
+		}
+		ntfyClient, err := ntfyFactory.NewClient(ntfyConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create ntfy client: %v", err)
+		}
+		defer ntfyClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewNtfyNotifier(ntfyClient), cfg.Ntfy.Events))
+		log.Info("ntfy notifications enabled")
+	} else {
+		log.Info("ntfy notifications disabled")
+	}
 
-	// Handle check-once command
-	if *checkOnce {
-		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-		defer cancel()
+	// Initialize LINE client (independent)
+	if cfg.Line.Enabled {
+		lineFactory := line.NewMessagingFactory()
+		lineConfig := line.Config{
+			Token:          cfg.Line.Token,
+			To:             cfg.Line.To,
+			TimeoutSeconds: cfg.Line.TimeoutSeconds,
+		}
+		lineClient, err := lineFactory.NewClient(lineConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create LINE client: %v", err)
+		}
+		defer lineClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewLineNotifier(lineClient, cfg.Line.RecentChanges), cfg.Line.Events))
+		log.Info("LINE notifications enabled")
+	} else {
+		log.Info("LINE notifications disabled")
+	}
 
-		result := monitor.CheckOnce(ctx)
-		if result.Error != nil {
-			log.Errorf("Check failed: %v", result.Error)
-			os.Exit(1)
+	// Initialize DingTalk client (independent)
+	if cfg.DingTalk.Enabled {
+		dingtalkFactory := dingtalk.NewWebhookFactory()
+		dingtalkConfig := dingtalk.Config{
+			WebhookURL:     cfg.DingTalk.WebhookURL,
+			Secret:         cfg.DingTalk.Secret,
+			TimeoutSeconds: cfg.DingTalk.TimeoutSeconds,
+		}
+		dingtalkClient, err := dingtalkFactory.NewClient(dingtalkConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create DingTalk client: %v", err)
 		}
+		defer dingtalkClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewDingTalkNotifier(dingtalkClient), cfg.DingTalk.Events))
+		log.Info("DingTalk notifications enabled")
+	} else {
+		log.Info("DingTalk notifications disabled")
+	}
 
-		if result.Changed {
-			log.Infof("IP changed from %s to %s", result.LastIP, result.CurrentIP)
-		} else {
-			log.Infof("IP unchanged: %s", result.CurrentIP)
+	// Initialize WeCom client (independent)
+	if cfg.WeCom.Enabled {
+		wecomFactory := wecom.NewWebhookFactory()
+		wecomConfig := wecom.Config{
+			WebhookURL:     cfg.WeCom.WebhookURL,
+			TimeoutSeconds: cfg.WeCom.TimeoutSeconds,
+		}
+		wecomClient, err := wecomFactory.NewClient(wecomConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create WeCom client: %v", err)
 		}
+		defer wecomClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewWeComNotifier(wecomClient), cfg.WeCom.Events))
+		log.Info("WeCom notifications enabled")
+	} else {
+		log.Info("WeCom notifications disabled")
+	}
 
-		// Wait for any pending notifications before exit
-		close(notificationChan)
-		time.Sleep(100 * time.Millisecond)
-		return
+	// Initialize SNS client (independent)
+	if cfg.SNS.Enabled {
+		snsFactory := sns.NewPublishFactory()
+		snsConfig := sns.Config{
+			TopicARN:        cfg.SNS.TopicARN,
+			Region:          cfg.SNS.Region,
+			AccessKeyID:     cfg.SNS.AccessKeyID,
+			SecretAccessKey: cfg.SNS.SecretAccessKey,
+			SessionToken:    cfg.SNS.SessionToken,
+			RoleARN:         cfg.SNS.RoleARN,
+			ExternalID:      cfg.SNS.ExternalID,
+			TimeoutSeconds:  cfg.SNS.TimeoutSeconds,
+		}
+		snsClient, err := snsFactory.NewClient(snsConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create SNS client: %v", err)
+		}
+		defer snsClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewSNSNotifier(snsClient), cfg.SNS.Events))
+		log.Info("SNS notifications enabled")
+	} else {
+		log.Info("SNS notifications disabled")
 	}
 
-	// Get last known IP for logging
-	lastIP, err := storage.ReadLastIP()
-	if err != nil {
-		log.Errorf("Failed to read last IP: %v", err)
-	} else if lastIP == "" {
-		log.Info("No last IP found - this appears to be the first run")
+	// Initialize MQTT client (independent)
+	if cfg.MQTT.Enabled {
+		mqttFactory := mqtt.NewBrokerFactory()
+		mqttConfig := mqtt.Config{
+			Broker:             cfg.MQTT.Broker,
+			ClientID:           cfg.MQTT.ClientID,
+			Username:           cfg.MQTT.Username,
+			Password:           cfg.MQTT.Password,
+			QoS:                cfg.MQTT.QoS,
+			CAFile:             cfg.MQTT.CAFile,
+			InsecureSkipVerify: cfg.MQTT.InsecureSkipVerify,
+			TimeoutSeconds:     cfg.MQTT.TimeoutSeconds,
+		}
+		mqttClient, err := mqttFactory.NewClient(mqttConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create MQTT client: %v", err)
+		}
+		defer mqttClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewMQTTNotifier(mqttClient, cfg.MQTT.Topic, cfg.MQTT.EventTopic), cfg.MQTT.Events))
+		log.Infof("MQTT publishing enabled (%s)", cfg.MQTT.Topic)
 	} else {
-		log.Infof("Last known IP: %s", lastIP)
+		log.Info("MQTT publishing disabled")
 	}
 
-	// Start monitoring
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Initialize syslog client (independent)
+	if cfg.Syslog.Enabled {
+		syslogFactory := syslog.NewTLSFactory()
+		syslogConfig := syslog.Config{
+			Address:            cfg.Syslog.Address,
+			Facility:           cfg.Syslog.Facility,
+			AppName:            cfg.Syslog.AppName,
+			CAFile:             cfg.Syslog.CAFile,
+			CertFile:           cfg.Syslog.CertFile,
+			KeyFile:            cfg.Syslog.KeyFile,
+			InsecureSkipVerify: cfg.Syslog.InsecureSkipVerify,
+			TimeoutSeconds:     cfg.Syslog.TimeoutSeconds,
+		}
+		syslogClient, err := syslogFactory.NewClient(syslogConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create syslog client: %v", err)
+		}
+		defer syslogClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewSyslogNotifier(syslogClient, cfg.Syslog.Hostname), cfg.Syslog.Events))
+		log.Infof("Syslog forwarding enabled (%s)", cfg.Syslog.Address)
+	} else {
+		log.Info("Syslog forwarding disabled")
+	}
 
-	log.Infof("Starting IP monitoring every %d seconds...", cfg.CheckIntervalSeconds)
-	resultChan := monitor.StartMonitoring(ctx, config.GetCheckInterval(cfg))
+	// Initialize PagerDuty client (independent)
+	if cfg.PagerDuty.Enabled {
+		pagerdutyFactory := pagerduty.NewEventsFactory()
+		pagerdutyConfig := pagerduty.Config{
+			RoutingKey:     cfg.PagerDuty.RoutingKey,
+			EventsURL:      cfg.PagerDuty.EventsURL,
+			TimeoutSeconds: cfg.PagerDuty.TimeoutSeconds,
+		}
+		pagerdutyClient, err := pagerdutyFactory.NewClient(pagerdutyConfig)
+		if err != nil {
+			fatal.Exitf("Failed to create PagerDuty client: %v", err)
+		}
+		defer pagerdutyClient.Close()
+		notifiers = append(notifiers, notify.Route(notify.NewPagerDutyNotifier(pagerdutyClient, cfg.PagerDuty.SeverityMap, cfg.PagerDuty.AutoResolve), cfg.PagerDuty.Events))
+		log.Info("PagerDuty notifications enabled")
+	} else {
+		log.Info("PagerDuty notifications disabled")
+	}
 
-	// Set up signal handling for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	// Initialize generic webhook clients, one per URL so retries stay per endpoint (independent)
+	var outboxes []*notify.OutboxNotifier
+	if cfg.Webhook.Enabled {
+		webhookFactory := webhook.NewHTTPFactory()
+		for _, url := range cfg.Webhook.URLs {
+			webhookClient, err := webhookFactory.NewClient(webhook.Config{
+				URL:            url,
+				Method:         cfg.Webhook.Method,
+				Headers:        cfg.Webhook.Headers,
+				Template:       cfg.Webhook.PayloadTemplate,
+				TimeoutSeconds: cfg.Webhook.TimeoutSeconds,
+			})
+			if err != nil {
+				fatal.Exitf("Failed to create webhook client: %v", err)
+			}
+			defer webhookClient.Close()
+			var webhookNotifier notify.Notifier = notify.NewWebhookNotifier(webhookClient)
+			// Deliver at least once and in order, replaying events after
+			// outages; commands like "notify test" reach the endpoint directly
+			if cfg.Webhook.Outbox.Enabled && flag.NArg() == 0 {
+				outbox, err := openWebhookOutbox(webhookNotifier, url, cfg, log)
+				if err != nil {
+					fatal.Exitf("Failed to open webhook outbox: %v", err)
+				}
+				outboxes = append(outboxes, outbox)
+				webhookNotifier = outbox
+			}
+			notifiers = append(notifiers, notify.Route(webhookNotifier, cfg.Webhook.Events))
+		}
+		log.Infof("Webhook notifications enabled (%d URLs)", len(cfg.Webhook.URLs))
+	} else {
+		log.Info("Webhook notifications disabled")
+	}
+
+	// Initialize notification plugins, one notifier per command so retries stay per plugin
+	if len(cfg.Plugins.Commands) > 0 {
+		pluginRunner := hooks.NewRunner(cfg.Hooks.OutputLimitBytes)
+		for _, plugin := range cfg.Plugins.Commands {
+			user := plugin.User
+			if user == "" {
+				user = cfg.Plugins.User
+			}
+			pluginNotifier := notify.NewPluginNotifier(pluginRunner, hooks.Command{
+				Name:    plugin.Name,
+				Path:    plugin.Command,
+				Args:    plugin.Args,
+				Timeout: config.GetPluginTimeout(cfg, plugin),
+				User:    user,
+			})
+			notifiers = append(notifiers, notify.Route(pluginNotifier, plugin.Events))
+		}
+		log.Infof("Notification plugins enabled (%d commands)", len(cfg.Plugins.Commands))
+	}
+
+	if simulated != nil {
+		notifiers = simulated.Notifiers(notifiers)
+	}
+	if chaosSettings != nil {
+		notifiers = chaos.WrapNotifiers(notifiers, *chaosSettings)
+	}
+
+	// Skip channels that keep failing rather than retrying them on every event
+	if cfg.CircuitBreaker.FailureThreshold > 0 {
+		cooldown := time.Duration(cfg.CircuitBreaker.CooldownSeconds) * time.Second
+		for i, notifier := range notifiers {
+			notifiers[i] = notify.Guard(notifier, cfg.CircuitBreaker.FailureThreshold, cooldown, log.Infof, log.Warnf)
+		}
+	}
+
+	// Count the sends of channels with a daily quota, however they are sent
+	quotas, err := newQuotaPolicy(cfg, log)
+	if err != nil {
+		fatal.Exitf("Failed to open notification quotas: %v", err)
+	}
+	for i, notifier := range notifiers {
+		notifiers[i] = notify.Meter(notifier, quotas.Quotas(), log.Warnf)
+	}
+	for channel, target := range cfg.Quotas.Reroute {
+		for _, name := range []string{channel, target} {
+			if !slices.ContainsFunc(notifiers, func(notifier notify.Notifier) bool { return strings.EqualFold(notifier.Name(), name) }) {
+				log.Warnf("Channel %q in quotas.reroute is not enabled", name)
+			}
+		}
+	}
+
+	// Notifications that failed on every attempt, for "notifications resend"
+	deadLetters, err := notify.OpenDeadLetters(filepath.Join(cfg.IP.DataDir, deadLetterFile))
+	if err != nil {
+		fatal.Exitf("Failed to open failed notifications: %v", err)
+	}
+
+	// Send a sample notification through the channels to verify credentials,
+	// failed notifications or past events again
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case "notifications":
+			err = runResend(flag.Args(), notifiers, deadLetters, log)
+		case "events":
+			err = runReplay(flag.Args(), notifiers, cfg.IP.DataDir, log)
+		default:
+			lastIP, _ := storage.ForFamily(families[0]).ReadLastIP()
+			err = runNotifyTest(flag.Args(), notifiers, sampleEvent(families[0], lastIP, cfg.Site), log)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Events notified, to look them up when acknowledged
+	journal, err := openEventJournal(cfg.IP.DataDir)
+	if err != nil {
+		fatal.Exitf("Failed to open event history: %v", err)
+	}
+
+	// Notify the escalation channels of events nobody acknowledged in time
+	var escalation *escalationPolicy
+	if cfg.Escalation.Enabled {
+		escalation, err = newEscalationPolicy(cfg, notifiers, journal, log)
+		if err != nil {
+			fatal.Exitf("Failed to set up escalation: %v", err)
+		}
+	}
+
+	// Settings the admin API can change while running
+	settings := newRuntimeSettings(cfg)
+	for _, name := range cfg.MutedChannels {
+		if !slices.ContainsFunc(notifiers, func(notifier notify.Notifier) bool { return strings.EqualFold(notifier.Name(), name) }) {
+			log.Warnf("Muted channel %q is not enabled", name)
+		}
+	}
+	if cfg.DryRun {
+		log.Warn("Dry run: notifications are logged, not sent")
+	}
+	pruner = &dataPruner{cfg: cfg, storage: storage, deadLetters: deadLetters, journal: journal}
+	fatal.SetChannels(notifiers, settings)
+
+	// Hold notifications back during quiet hours
+	var quiet *quietQueue
+	if cfg.QuietHours.Enabled {
+		hours, err := notify.NewQuietHours(cfg.QuietHours.Start, cfg.QuietHours.End, log.Location(), cfg.QuietHours.UrgentChannels)
+		if err != nil {
+			fatal.Exitf("Invalid quiet hours: %v", err)
+		}
+		quiet = newQuietQueue(hours)
+		log.Infof("Quiet hours enabled (%s-%s)", cfg.QuietHours.Start, cfg.QuietHours.End)
+	}
+
+	// Queue notifications on disk so that none are lost to restarts or outages
+	spool, err := notify.OpenSpool(filepath.Join(cfg.IP.DataDir, spoolFile))
+	if err != nil {
+		fatal.Exitf("Failed to open notification spool: %v", err)
+	}
+	if pending := spool.Len(); pending > 0 {
+		log.Infof("Resending %d notifications left from the last run", pending)
+	}
+
+	go notificationWorker(spool, deadLetters, len(families), notifiers, quiet, quotas, release, journal, escalation, escalate, settings, cfg, log)
+	if len(outboxes) > 0 {
+		timeout := time.Duration(cfg.Webhook.Outbox.ReplaySeconds) * time.Second
+		replayWebhooks = func(ctx context.Context) {
+			replayOutboxes(ctx, outboxes, timeout)
+		}
+		// Events left by the last run are sent right away
+		go replayWebhooks(context.Background())
+	}
+
+	// Track the default gateway so router swaps and WAN failovers show up in notifications
+	var gatewayTracker *gateway.Tracker
+	if cfg.IP.DetectGateway {
+		gatewayTracker = gateway.NewTracker(config.GetCheckInterval(cfg))
+		if _, err := gatewayTracker.Observe(); err != nil {
+			log.Warnf("Gateway detection unavailable: %v", err)
+			gatewayTracker = nil
+		}
+	}
+
+	// Supervised runner for on-change hook commands
+	hookRunner := hooks.NewRunner(cfg.Hooks.OutputLimitBytes)
+
+	// Renew certificates validated through the public IP once DNS follows
+	// a change
+	var renewer *acmeRenewer
+	if len(cfg.ACME.Domains) > 0 {
+		renewer = newACMERenewer(hookRunner, cfg, log)
+		log.Infof("Certificate renewal after IP changes enabled for %s", strings.Join(cfg.ACME.Domains, ", "))
+	}
+
+	// Keep a short link pointing at the current IP of the default route
+	var shortlinkUpdater *shortlink.Updater
+	shortlinkTarget := monitorTarget{Family: families[0]}
+	if cfg.Shortlink.Enabled {
+		if cfg.Shortlink.Family != "" {
+			shortlinkTarget.Family, _ = ip.ParseFamily(cfg.Shortlink.Family)
+			if !slices.Contains(families, shortlinkTarget.Family) {
+				fatal.Exitf("shortlink.family: %s is not monitored (see ip.families)", cfg.Shortlink.Family)
+			}
+		}
+		client, err := shortlink.NewClient(shortlink.Config{
+			Provider:       cfg.Shortlink.Provider,
+			APIURL:         cfg.Shortlink.APIURL,
+			APIKey:         cfg.Shortlink.APIKey,
+			ID:             cfg.Shortlink.ID,
+			Method:         cfg.Shortlink.Method,
+			Headers:        cfg.Shortlink.Headers,
+			Body:           cfg.Shortlink.Body,
+			TimeoutSeconds: cfg.Shortlink.TimeoutSeconds,
+		})
+		if err != nil {
+			fatal.Exitf("Failed to create short link client: %v", err)
+		}
+		shortlinkUpdater = shortlink.NewUpdater(client, time.Duration(cfg.Shortlink.MinIntervalSeconds)*time.Second, log.Infof, log.Errorf)
+		log.Infof("Short link enabled (%s %s)", cfg.Shortlink.Provider, cfg.Shortlink.ID)
+	}
+
+	// Tell a dead man's switch service after every check cycle that the
+	// monitor is still running
+	var pinger *cyclePinger
+	if cfg.Ping.Enabled {
+		client, err := ping.NewClient(ping.Config{
+			URL:            cfg.Ping.URL,
+			FailURL:        cfg.Ping.FailURL,
+			TimeoutSeconds: cfg.Ping.TimeoutSeconds,
+		})
+		if err != nil {
+			fatal.Exitf("Failed to create ping client: %v", err)
+		}
+		pinger = newCyclePinger(client, log)
+		log.Info("Pings after every check cycle enabled")
+	}
+
+	// Look up the network of new IPs to flag VPN and hosting exits
+	var enricher *enrich.Enricher
+	if cfg.Enrichment.Enabled {
+		enricher, err = enrich.New(cfg.Enrichment.HostingASNs, cfg.Enrichment.HostingListFile, time.Duration(cfg.Enrichment.TimeoutSeconds)*time.Second)
+		if err != nil {
+			fatal.Exitf("Failed to set up enrichment: %v", err)
+		}
+		log.Info("Network lookup of new IPs enabled")
+
+		switch geolocation := cfg.Enrichment.Geolocation; geolocation.Provider {
+		case "":
+		case "maxmind":
+			db, err := openGeoIP(cfg, taskScheduler, log)
+			if err != nil {
+				fatal.Exitf("Failed to open MaxMind databases: %v", err)
+			}
+			enricher.SetLocator(db)
+			log.Info("Geolocation of new IPs through local MaxMind databases enabled")
+		default:
+			locator, err := enrich.NewLocator(geolocation.Provider, geolocation.URL, geolocation.Token)
+			if err != nil {
+				fatal.Exitf("Invalid enrichment.geolocation: %v", err)
+			}
+			enricher.SetLocator(locator)
+			log.Infof("Geolocation of new IPs through %s enabled", geolocation.Provider)
+		}
+	}
+
+	expectedRanges, err := ip.ParseRanges(cfg.IP.ExpectedRanges)
+	if err != nil {
+		fatal.Exitf("Invalid ip.expected_ranges: %v", err)
+	}
+
+	// Send notification requests asynchronously
+	queueNotification := func(event notify.Event) {
+		event.Site = cfg.Site
+		if err := spool.Push(event); err != nil {
+			log.Errorf("Failed to spool notification, it is lost if not sent before shutdown: %v", err)
+		}
+	}
+
+	// Targets whose startup catch-up has to wait for the network
+	pending := newPendingCatchUps()
+
+	// Create IP change handler with async notifications
+	newChangeHandler := func(target monitorTarget) ip.ChangeHandler {
+		return func(oldIP, newIP string) error {
+			if oldIP == "" {
+				oldIP = "Unknown"
+			}
+
+			log.Infof("%s changed from %s to %s", target.Label(), oldIP, newIP)
+
+			change := config.IPChange{
+				Family: target.Family.Label(),
+				OldIP:  oldIP,
+				NewIP:  newIP,
+				WAN:    target.WAN,
+			}
+			if target.WAN == "" {
+				detectFailover(&change, target.Family, cfg.IP.WANs, storage)
+				if event := change.WANEvent(); event != "" {
+					log.Warn(event)
+				}
+			}
+			details := enrichChange(enricher, &change, log)
+			detectGeofence(&change, details, cfg.Enrichment.ExpectedCountries, log)
+			history := storage
+			if target.WAN != "" {
+				history = storage.ForWAN(target.WAN)
+			}
+			detectNetworkChange(&change, history.ForFamily(target.Family), log)
+			detectUnusualActivity(&change, history.ForFamily(target.Family), cfg.Anomaly, log)
+			annotateRecord(history.ForFamily(target.Family), newIP, details, log)
+			detectUnexpectedIP(&change, expectedRanges, history.ForFamily(target.Family), log)
+			if n := config.GetRecentChanges(cfg); n > 0 {
+				change.Recent = recentIPs(history.ForFamily(target.Family), n, log)
+			}
+
+			if !pending.Hold(target, change) {
+				event := notify.NewChangeEvent([]config.IPChange{change}, observeGateway(gatewayTracker, log), time.Now())
+				event.Enrichment = details
+				queueNotification(event)
+			}
+
+			return nil
+		}
+	}
+
+	// Hooks follow the default route only, e.g. DDNS updates must not point
+	// at a WAN that is not carrying traffic. Certificates are renewed after
+	// them, once the DNS records they updated point at the new IP.
+	newHooksHandler := func(target monitorTarget) ip.ChangeHandler {
+		return func(oldIP, newIP string) error {
+			if oldIP == "" {
+				oldIP = "Unknown"
+			}
+			go func() {
+				runHooks(hookRunner, cfg, target.Family, oldIP, newIP, queueNotification, log)
+				// The first IP seen is not a change that could break validation
+				if renewer != nil && oldIP != "Unknown" {
+					renewer.Renew(target.Family, oldIP, newIP, queueNotification)
+				}
+			}()
+			return nil
+		}
+	}
+
+	// Initialize one IP monitor per monitored address family, on the default
+	// route and on every WAN profile
+	var targets []monitorTarget
+	monitors := make(map[monitorTarget]*ip.Monitor)
+	apiState := api.NewState()
+	for _, family := range families {
+		target := monitorTarget{Family: family}
+		targets = append(targets, target)
+		monitors[target] = ip.NewMonitor(fetcher.ForFamily(family), storage.ForFamily(family))
+		monitors[target].SetClock(appClock)
+		monitors[target].Subscribe(newChangeHandler(target))
+		if len(cfg.Hooks.Commands) > 0 || renewer != nil {
+			monitors[target].SubscribeAction(newHooksHandler(target))
+		}
+	}
+	for _, wan := range cfg.IP.WANs {
+		wanFetcher := fetcher
+		if len(wan.Services) > 0 {
+			wanFetcher = ip.NewFetcher(wan.Services, cfg.IP.TimeoutSeconds)
+			wanFetcher.SetQuorum(cfg.IP.Quorum)
+			wanFetcher.SetRace(cfg.IP.FetchMode == "race")
+			wanFetcher.SetRequestHeaders(ipUserAgent(cfg), cfg.IP.ServiceHeaders)
+		}
+		if wan.Interface != "" {
+			wanFetcher = wanFetcher.ForInterface(wan.Interface)
+		}
+
+		for _, family := range families {
+			target := monitorTarget{Family: family, WAN: wan.Name}
+			targets = append(targets, target)
+			monitors[target] = ip.NewMonitor(wanFetcher.ForFamily(family), storage.ForWAN(wan.Name).ForFamily(family))
+			monitors[target].SetClock(appClock)
+			monitors[target].Subscribe(newChangeHandler(target))
+		}
+	}
+
+	// Checking stays paused across restarts until resumed
+	pauses := newPauseControl(cfg.IP.DataDir, filepath.Join(config.GetRuntimeDir(cfg), healthFile), monitors, apiState, settings, log)
+
+	// Startup, shutdown and heartbeat notices carry the last IP of every target
+	lifecycleStatus := func(kind string) config.LifecycleStatus {
+		status := config.LifecycleStatus{Kind: kind, Site: cfg.Site, Version: version, PausedAt: pauses.PausedAt(), Quotas: quotas.Usage()}
+		if kind != config.LifecycleStarted {
+			status.Uptime = time.Since(startedAt)
+		}
+		for _, target := range targets {
+			lastIP, err := monitors[target].GetLastIP()
+			if err != nil {
+				log.Warnf("Failed to read last %s: %v", target.Label(), err)
+			}
+			status.Addresses = append(status.Addresses, config.CurrentIP{Family: target.Family.Label(), WAN: target.WAN, IP: lastIP})
+		}
+		return status
+	}
+	queueLifecycle := func(kind string) {
+		queueNotification(notify.NewLifecycleEvent(lifecycleStatus(kind), time.Now()))
+	}
+	fatal.SetState(func() fatalState {
+		return fatalState{Status: lifecycleStatus(config.LifecycleStopped), Pending: spool.Len()}
+	})
+	heartbeat = func() {
+		log.Info("Sending heartbeat notification")
+		queueLifecycle(config.LifecycleHeartbeat)
+	}
+
+	// Record every check for uptime statistics
+	var checkLog *ip.CheckLog
+	if cfg.CheckLog.Enabled {
+		checkLog, err = ip.OpenCheckLog(filepath.Join(cfg.IP.DataDir, cfg.CheckLog.File), cfg.CheckLog.MaxEntries, time.Duration(cfg.CheckLog.MaxAgeHours)*time.Hour)
+		if err != nil {
+			fatal.Exitf("Failed to open check log: %v", err)
+		}
+		if lowWrite {
+			checkLog.DeferWrites()
+		}
+		log.Infof("Check log enabled (%s)", cfg.CheckLog.File)
+	}
+	if lowWrite {
+		flushWrites = func() {
+			if err := storage.Flush(); err != nil {
+				log.Errorf("Failed to flush last IPs: %v", err)
+			}
+			if err := serviceHealth.Save(); err != nil {
+				log.Errorf("Failed to flush service health: %v", err)
+			}
+			if checkLog != nil {
+				if err := checkLog.Flush(); err != nil {
+					log.Errorf("Failed to flush check log: %v", err)
+				}
+			}
+		}
+		fatal.OnExit(flushWrites)
+	}
+
+	// Handle check-once command
+	if *checkOnce {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		failed := false
+		for _, target := range targets {
+			result := monitors[target].CheckOnce(ctx)
+			recordCheck(checkLog, target, result, log)
+			if result.Error != nil {
+				log.Errorf("%s check failed: %v", target.Label(), result.Error)
+				failed = true
+				continue
+			}
+			for _, stage := range result.StageErrors() {
+				log.Errorf("%s change %s stage failed: %v", target.Label(), stage.Stage, stage.Err)
+			}
+
+			if result.Changed {
+				log.Infof("%s changed from %s to %s", target.Label(), result.LastIP, result.CurrentIP)
+			} else {
+				log.Infof("%s unchanged: %s", target.Label(), result.CurrentIP)
+			}
+			log.Infof("%s checked %s", target.Label(), describeRoute(checkRoute(result.Source)))
+			logDissent(target, result.Source, log)
+		}
+
+		if err := serviceHealth.Save(); err != nil {
+			log.Warnf("%v", err)
+		}
+
+		// Wait for any pending notifications before exit
+		spool.Close()
+		time.Sleep(100 * time.Millisecond)
+
+		if failed {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Get last known IP for logging
+	for _, target := range targets {
+		lastIP, err := monitors[target].GetLastIP()
+		if err != nil {
+			log.Errorf("Failed to read last %s: %v", target.Label(), err)
+		} else if lastIP == "" {
+			log.Infof("No last %s found - this appears to be the first run", target.Label())
+		} else {
+			log.Infof("Last known %s: %s", target.Label(), lastIP)
+			apiState.Seed(target.Family.Label(), target.WAN, lastIP, monitors[target].GetLastChangeTime())
+		}
+	}
+
+	// Serve the current IP to other applications on the network
+	if cfg.API.Enabled {
+		apiOptions := api.Options{
+			Listen:  cfg.API.Listen,
+			Token:   cfg.API.Token,
+			MaxWait: time.Duration(cfg.API.MaxWaitSeconds) * time.Second,
+			Logf:    log.Errorf,
+			History: func(query api.HistoryQuery) (api.HistoryPage, error) {
+				histories, err := readHistories(storage, cfg.IP.WANs)
+				if err != nil {
+					return api.HistoryPage{}, err
+				}
+				// Searched as exported, so that redacted IPs cannot be probed
+				exported := redactExport(ip.BuildExportRecords(histories), redactor)
+				filters := historyFilters{Since: query.Since, Until: query.Until, IP: query.IP, Site: query.Site, Limit: query.Limit, Cursor: query.Cursor}
+				page, err := filters.apply(ip.SearchHistory(exported, ip.ParseHistoryQuery(query.Search)), cfg.Site, log.Location())
+				if err != nil {
+					return api.HistoryPage{}, fmt.Errorf("%w: %w", api.ErrInvalidQuery, err)
+				}
+				result := api.HistoryPage{NextCursor: page.NextCursor}
+				for _, record := range page.Records {
+					result.Records = append(result.Records, api.HistoryRecord{
+						Family:     record.Family.Label(),
+						WAN:        record.WAN,
+						IP:         record.IP,
+						PreviousIP: record.PreviousIP,
+						Timestamp:  record.Timestamp,
+						Enrichment: record.Enrichment,
+					})
+				}
+				return result, nil
+			},
+			Checks: checksFunc(checkLog),
+			Events: journal.Records,
+			Quotas: quotasFunc(quotas),
+			Resources: func() api.Resources {
+				return resourceStatus(applied, resources.ReadUsage())
+			},
+			Admin: adminOptions(cfg, configManager, settings, monitors, notifiers, pruner, pauses, log),
+			Ack: func(eventID, by string) (api.EventRecord, bool, error) {
+				record, pending, err := journal.Acknowledge(eventID, by)
+				if err == nil {
+					log.Infof("Event %s acknowledged by %s", eventID, record.AckedBy)
+				}
+				return record, pending, err
+			},
+		}
+		apiServer := api.NewServer(apiState, apiOptions)
+		if err := apiServer.Start(); err != nil {
+			fatal.Exitf("Failed to start API: %v", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			apiServer.Shutdown(ctx)
+		}()
+		log.Infof("API listening on %s", cfg.API.Listen)
+	}
+
+	// On boot the service often starts before DHCP or the WAN link is up
+	if grace := config.GetStartupGrace(cfg); grace > 0 {
+		graceCtx, stopGrace := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		waitForNetwork(graceCtx, fetcher.ForFamily(families[0]), grace, log)
+		interrupted := graceCtx.Err() != nil
+		stopGrace()
+
+		if interrupted {
+			log.Info("Received signal while waiting for the network, exiting")
+			spool.Close()
+			return
+		}
+	}
+
+	// Catch up on anything that happened while the monitor was not running
+	reconcileCtx, reconcileCancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	var catchUps []config.IPChange
+	var catchUpDetails map[string]string
+	for _, target := range targets {
+		// Paused checks pick up a change as usual once resumed
+		if !pauses.PausedAt().IsZero() {
+			break
+		}
+
+		// The DNS record follows the default route, not individual WANs
+		dnsRecord := cfg.IP.DNSRecord
+		if target.WAN != "" {
+			dnsRecord = ""
+		}
+
+		report, err := monitors[target].Reconcile(reconcileCtx, dnsRecord)
+		if err != nil {
+			log.Warnf("Startup reconciliation for %s failed: %v", target.Label(), err)
+			// Report a change found once the network is back as a catch-up
+			if lastIP, _ := monitors[target].GetLastIP(); lastIP != "" {
+				pending.Add(target, monitors[target].GetLastChangeTime())
+			}
+			continue
+		}
+		if report.DNSError != nil {
+			log.Warnf("Startup DNS check for %s failed: %v", target.Label(), report.DNSError)
+		}
+		if report.Consistent() {
+			continue
+		}
+
+		if report.MissedChange() {
+			log.Infof("%s changed from %s to %s while the monitor was not running", target.Label(), report.LastIP, report.CurrentIP)
+		}
+		if report.DNSStale() {
+			log.Warnf("DNS record %s does not point at current %s %s", report.DNSRecord, target.Label(), report.CurrentIP)
+		}
+
+		change := config.IPChange{
+			Family:     target.Family.Label(),
+			WAN:        target.WAN,
+			OldIP:      report.LastIP,
+			NewIP:      report.CurrentIP,
+			Missed:     report.MissedChange(),
+			LastChange: report.LastChange,
+			DNSRecord:  report.DNSRecord,
+			DNSIPs:     report.DNSIPs,
+		}
+		if report.DNSError != nil {
+			change.DNSError = report.DNSError.Error()
+		}
+		if target.WAN == "" && change.Missed {
+			detectFailover(&change, target.Family, cfg.IP.WANs, storage)
+			if event := change.WANEvent(); event != "" {
+				log.Warn(event)
+			}
+		}
+		if change.Missed {
+			history := storage
+			if target.WAN != "" {
+				history = storage.ForWAN(target.WAN)
+			}
+			if details := enrichChange(enricher, &change, log); details != nil {
+				catchUpDetails = details
+				detectGeofence(&change, details, cfg.Enrichment.ExpectedCountries, log)
+				detectNetworkChange(&change, history.ForFamily(target.Family), log)
+				annotateRecord(history.ForFamily(target.Family), change.NewIP, details, log)
+			}
+			detectUnexpectedIP(&change, expectedRanges, history.ForFamily(target.Family), log)
+		}
+		catchUps = append(catchUps, change)
+	}
+	reconcileCancel()
+
+	if len(catchUps) > 0 {
+		event := notify.NewCatchUpEvent(catchUps, observeGateway(gatewayTracker, log), time.Now())
+		event.Enrichment = catchUpDetails
+		queueNotification(event)
+	}
+
+	// Start monitoring
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go taskScheduler.Run(ctx)
+
+	log.Infof("Starting IP monitoring every %d seconds...", cfg.CheckIntervalSeconds)
+	resultChan := startMonitors(ctx, monitors, settings.Settings().CheckInterval)
+
+	failures := newFailureTracker(cfg.IP.FailureThreshold, cfg.IP.RecoveryThreshold)
 
-	// Main monitoring loop
+	// The startup notice waits for the first check of every target, so
+	// that it has the current IPs, unless checking is paused
+	unchecked := make(map[monitorTarget]bool)
+	if cfg.Lifecycle.Startup && !pauses.PausedAt().IsZero() {
+		queueLifecycle(config.LifecycleStarted)
+	} else if cfg.Lifecycle.Startup {
+		for _, target := range targets {
+			unchecked[target] = true
+		}
+	}
+
+	// Set up signal handling for graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	restartForUpdate = func() {
+		select {
+		case sigChan <- syscall.SIGTERM:
+		default: // Already shutting down
+		}
+	}
+
+	// And for pausing and resuming checks
+	pauseChan := make(chan os.Signal, 1)
+	if pauseSignal != nil {
+		signal.Notify(pauseChan, pauseSignal, resumeSignal)
+	}
+
+	// Main monitoring loop; a crash in it is reported like a fatal error
+	defer fatal.Recover()
 	for {
 		select {
 		case result, ok := <-resultChan:
 			if !ok {
 				log.Info("Monitoring stopped")
-				close(notificationChan) // Close notification channel
+				if flushWrites != nil {
+					flushWrites()
+				}
+				spool.Close()
 				return
 			}
 
-			if result.Error != nil {
-				log.Errorf("IP check failed: %v", result.Error)
-				continue
-			}
+			recordCheck(checkLog, result.Target, result.CheckResult, log)
+			if !lowWrite {
+				if err := serviceHealth.SaveEvery(serviceHealthSaveInterval); err != nil {
+					log.Warnf("%v", err)
+				}
+			}
+			writeHealth(filepath.Join(config.GetRuntimeDir(cfg), healthFile), result.CheckResult, settings.Settings().CheckInterval, pauses.PausedAt(), log)
+			if pinger != nil {
+				pinger.Observe(result.Target, result.Error, len(targets))
+			}
+
+			if unchecked[result.Target] {
+				delete(unchecked, result.Target)
+				if len(unchecked) == 0 {
+					queueLifecycle(config.LifecycleStarted)
+				}
+			}
+
+			if result.Error != nil {
+				log.Errorf("%s check failed: %v", result.Target.Label(), result.Error)
+				if failure := failures.Failed(result.Target, result.Error); failure != nil {
+					log.Warnf("%s checks failed %d times in a row", failure.Label(), failure.Count)
+					queueNotification(notify.NewFetchFailureEvent([]config.CheckFailure{*failure}, time.Now()))
+				}
+				continue
+			}
+
+			for _, stage := range result.StageErrors() {
+				log.Errorf("%s change %s stage failed: %v", result.Target.Label(), stage.Stage, stage.Err)
+			}
+
+			failure := failures.Succeeded(result.Target)
+			if failure != nil {
+				log.Infof("%s checks work again after %d failures", failure.Label(), failure.Count)
+			}
+
+			// A change held back for the startup catch-up is reported together
+			// with the outage, in one notification per channel
+			if change, ok := pending.Release(result.Target); ok {
+				change.Outage = failure
+				queueNotification(notify.NewCatchUpEvent([]config.IPChange{change}, observeGateway(gatewayTracker, log), time.Now()))
+			} else if failure != nil {
+				queueNotification(notify.NewFetchRecoveredEvent([]config.CheckFailure{*failure}, time.Now()))
+			}
+
+			apiState.Observe(result.Target.Family.Label(), result.Target.WAN, result.CurrentIP, time.Now())
+			apiState.SetRoute(result.Target.Family.Label(), result.Target.WAN, checkRoute(result.Source))
+			logDissent(result.Target, result.Source, log)
+
+			// The first check after starting points the link at the IP in
+			// case it changed meanwhile; later ones only when it changes
+			if shortlinkUpdater != nil && result.Target == shortlinkTarget {
+				shortlinkUpdater.Set(shortlink.TargetURL(cfg.Shortlink.Target, result.CurrentIP))
+			}
+
+			// Notice gateway changes even when the IP stays the same
+			observeGateway(gatewayTracker, log)
+
+			if result.Changed {
+				log.Infof("%s changed from %s to %s", result.Target.Label(), result.LastIP, result.CurrentIP)
+			} else {
+				log.Infof("%s unchanged: %s", result.Target.Label(), result.CurrentIP)
+			}
+
+		case sig := <-pauseChan:
+			if err := pauses.Set(sig == pauseSignal, signalName(sig)); err != nil {
+				log.Errorf("Failed to apply %s: %v", signalName(sig), err)
+			}
+
+		case sig := <-sigChan:
+			log.Infof("Received signal %v, shutting down gracefully...", sig)
+			cancel()
+			if flushWrites != nil {
+				flushWrites()
+			} else if err := serviceHealth.Save(); err != nil {
+				log.Warnf("%v", err)
+			}
+
+			if cfg.Lifecycle.Shutdown {
+				queueLifecycle(config.LifecycleStopped)
+				awaitSpool(spool, shutdownNoticeTimeout)
+			}
+
+			// Stop the worker; notifications not sent by then stay spooled
+			spool.Close()
+			time.Sleep(2 * time.Second) // Give time for pending notifications
+			if pending := spool.Len(); pending > 0 {
+				log.Infof("%d notifications left in the spool for the next start", pending)
+			}
+
+			log.Info("Shutdown complete")
+			return
+		}
+	}
+}
+
+// shutdownNoticeTimeout is how long shutdown waits for the shutdown notice,
+// well within the 10 seconds "docker stop" allows
+const shutdownNoticeTimeout = 5 * time.Second
+
+// awaitSpool waits until the spool is empty, e.g. for the shutdown notice
+// to be sent, or the timeout passed
+func awaitSpool(spool *notify.Spool, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for spool.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// hiddenFlags are accepted but left out of the usage message
+var hiddenFlags = map[string]bool{"chaos": true}
+
+// usage prints the command line flags, except hidden ones
+func usage() {
+	visible := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	visible.SetOutput(flag.CommandLine.Output())
+	flag.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			visible.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", os.Args[0])
+	visible.PrintDefaults()
+}
+
+// checkRoute describes how a check went out: the local address its answer
+// came in on, the interface holding that address and the interface's
+// gateway, so policy routing can be verified
+func checkRoute(meta ip.Meta) api.Route {
+	route := api.Route{Source: meta.Source, Detail: meta.Detail, SourceAddress: meta.LocalAddr, NAT: meta.NAT}
+	if local := net.ParseIP(meta.LocalAddr); local != nil {
+		// The interface is known even when the gateway is not
+		info, _ := gateway.LookupFrom(local)
+		route.Interface, route.Gateway = info.Interface, info.IP
+	}
+	return route
+}
+
+// recentIPs returns the latest n+1 addresses of a history, newest first,
+// with how long each was kept
+func recentIPs(storage *ip.Storage, n int, log *logger.Logger) []config.HeldIP {
+	records, err := storage.Recent(n + 1)
+	if err != nil {
+		log.Warnf("Failed to read recent IP changes: %v", err)
+		return nil
+	}
+	recent := make([]config.HeldIP, len(records))
+	for i, record := range records {
+		recent[i] = config.HeldIP{IP: record.IP, Since: record.Timestamp}
+		if i > 0 {
+			recent[i].Held = records[i-1].Timestamp.Sub(record.Timestamp)
+		}
+	}
+	return recent
+}
+
+// logDissent warns about the sources that reported another address than
+// the quorum agreed on, which may be compromised or misbehaving
+func logDissent(target monitorTarget, meta ip.Meta, log *logger.Logger) {
+	for _, dissent := range meta.Dissent {
+		log.Warnf("%s quorum of %d overruled %s", target.Label(), len(meta.Agreed), dissent)
+	}
+}
+
+// describeRoute returns the route as text, e.g. "via http://... from
+// 192.168.1.10 on eth0, gateway 192.168.1.1"
+func describeRoute(route api.Route) string {
+	text := "via " + route.Detail
+	if route.SourceAddress != "" {
+		text += " from " + route.SourceAddress
+	}
+	if route.Interface != "" {
+		text += " on " + route.Interface
+	}
+	if route.Gateway != "" {
+		text += ", gateway " + route.Gateway
+	}
+	if route.NAT != "" {
+		text += ", " + route.NAT
+	}
+	return text
+}
+
+// observeGateway looks up the default gateway, logging changes, and returns
+// it as notification context (nil when gateway detection is disabled)
+func observeGateway(tracker *gateway.Tracker, log *logger.Logger) *config.GatewayContext {
+	if tracker == nil {
+		return nil
+	}
+
+	change, err := tracker.Observe()
+	if err != nil {
+		log.Warnf("Failed to look up default gateway: %v", err)
+		return nil
+	}
+
+	if change.Detected {
+		log.Warnf("Default gateway changed from %s (%s) to %s (%s)",
+			change.Previous.IP, change.Previous.MAC, change.Current.IP, change.Current.MAC)
+	}
+
+	return &config.GatewayContext{
+		Interface:   change.Current.Interface,
+		IP:          change.Current.IP,
+		MAC:         change.Current.MAC,
+		Changed:     change.Changed,
+		PreviousIP:  change.Previous.IP,
+		PreviousMAC: change.Previous.MAC,
+	}
+}
+
+// enrichChange looks up the network of the new IP, marking the change when
+// it belongs to a hosting or VPN provider, and returns the details for the
+// notification event. It returns nil when enrichment is disabled.
+func enrichChange(enricher *enrich.Enricher, change *config.IPChange, log *logger.Logger) map[string]string {
+	if enricher == nil {
+		return nil
+	}
+	info, err := enricher.Lookup(context.Background(), change.NewIP)
+	if err != nil {
+		log.Warnf("Network lookup of %s failed: %v", change.NewIP, err)
+	}
+	if info.ASN != 0 {
+		change.ASN = fmt.Sprintf("AS%d", info.ASN)
+		change.ASName = info.ASName
+		log.Infof("%s is announced by %s %s", change.NewIP, change.ASN, change.ASName)
+	}
+	if info.Hosted {
+		change.HostedExit = info.Reason
+		log.Warn(change.ExitNote())
+	}
+	if enricher.Locates() {
+		change.Country, change.Region, change.City = info.Country, info.Region, info.City
+		if location := change.Location(); location != "" {
+			log.Infof("%s is located in %s", change.NewIP, location)
+		}
+	}
+	return info.Details()
+}
+
+// detectNetworkChange marks the change when the new IP is announced by
+// another network than the old one, as recorded in the history, which
+// usually means a failover to another line or someone redirecting traffic.
+// A rename of the ISP under the same ASN counts as well.
+func detectNetworkChange(change *config.IPChange, history *ip.Storage, log *logger.Logger) {
+	if change.ASN == "" {
+		return
+	}
+	records, err := history.Recent(2)
+	if err != nil {
+		log.Warnf("Failed to read the network of %s: %v", change.OldIP, err)
+		return
+	}
+	for _, record := range records {
+		if record.IP != change.OldIP || record.ASN == "" {
+			continue
+		}
+		name := record.Enrichment["as_name"]
+		if record.ASN == change.ASN && (name == "" || change.ASName == "" || strings.EqualFold(name, change.ASName)) {
+			return
+		}
+		change.PreviousASN, change.PreviousASName = record.ASN, name
+		log.Warn(change.NetworkNote())
+		return
+	}
+}
+
+// detectGeofence marks the change when the new IP is located outside the
+// expected countries. An IP whose country could not be looked up is not
+// flagged, as nothing says it left them.
+func detectGeofence(change *config.IPChange, details map[string]string, expected []string, log *logger.Logger) {
+	if len(expected) == 0 {
+		return
+	}
+	country := details["country"]
+	if country == "" {
+		log.Warnf("Country of %s unknown, not checked against the expected countries", change.NewIP)
+		return
+	}
+	if slices.Contains(expected, strings.ToUpper(country)) {
+		return
+	}
+	change.OutsideCountry = fmt.Sprintf("%s is located in %s, expected %s", change.NewIP, country, strings.Join(expected, " or "))
+	log.Error(change.GeofenceNote())
+}
+
+// detectUnusualActivity marks the change when the IP changed far more
+// often in the anomaly window than the history says it usually does. The
+// usual frequency is only trusted once the history covers three windows.
+func detectUnusualActivity(change *config.IPChange, history *ip.Storage, anomaly config.AnomalyConfig, log *logger.Logger) {
+	if !anomaly.Enabled {
+		return
+	}
+	window := time.Duration(anomaly.WindowHours) * time.Hour
+	learn := time.Duration(anomaly.LearnDays) * 24 * time.Hour
+	frequency, learned, err := history.Frequency(time.Now(), window, learn, 3*window)
+	if err != nil {
+		log.Warnf("Failed to read how often %s changes: %v", change.Label(), err)
+		return
+	}
+	if !learned {
+		log.Debugf("History too short to tell how often %s usually changes", change.Label())
+		return
+	}
+	if frequency.Recent < anomaly.MinChanges || float64(frequency.Recent) <= float64(anomaly.Factor)*frequency.Usual {
+		return
+	}
+	change.UnusualActivity = fmt.Sprintf("%s changed %d times in %dh, usually %.1f times",
+		change.Label(), frequency.Recent, anomaly.WindowHours, frequency.Usual)
+	log.Warn(change.ActivityNote())
+}
+
+// annotateRecord stores the enrichment details of a change with its record
+// in the history, so that history, search and exports show them
+func annotateRecord(history *ip.Storage, newIP string, details map[string]string, log *logger.Logger) {
+	if details == nil {
+		return
+	}
+	err := history.AnnotateRecord(newIP, func(record *ip.Record) {
+		record.Country, record.Region, record.City, record.ASN = details["country"], details["region"], details["city"], details["asn"]
+		record.Enrichment = nil
+		for name, value := range details {
+			switch name {
+			case "country", "region", "city", "asn":
+			default:
+				if record.Enrichment == nil {
+					record.Enrichment = make(map[string]string)
+				}
+				record.Enrichment[name] = value
+			}
+		}
+	})
+	if err != nil {
+		log.Warnf("Failed to store the network details of %s: %v", newIP, err)
+	}
+}
+
+// detectUnexpectedIP marks the change when the new IP is outside the
+// expected ranges of its family, and its record in the history as anomalous
+func detectUnexpectedIP(change *config.IPChange, ranges ip.Ranges, history *ip.Storage, log *logger.Logger) {
+	if ranges.Expects(change.NewIP) {
+		return
+	}
+	change.UnexpectedIP = fmt.Sprintf("%s is not in %s", change.NewIP, strings.Join(ranges.Of(change.NewIP), ", "))
+	log.Warn(change.RangeNote())
+	err := history.AnnotateRecord(change.NewIP, func(record *ip.Record) {
+		record.Anomaly = "outside the expected ranges"
+	})
+	if err != nil {
+		log.Warnf("Failed to mark %s as anomalous in the history: %v", change.NewIP, err)
+	}
+}
+
+// detectFailover marks a change of the default route's IP as a failover to a
+// backup WAN, or back to a primary one, by comparing the old and new IP with
+// the last IPs seen through each WAN profile
+func detectFailover(change *config.IPChange, family ip.Family, wans []config.WANConfig, storage *ip.Storage) {
+	var from, to *config.WANConfig
+	for i, wan := range wans {
+		lastIP, err := storage.ForWAN(wan.Name).ForFamily(family).ReadLastIP()
+		if err != nil || lastIP == "" {
+			continue
+		}
+		if lastIP == change.OldIP {
+			from = &wans[i]
+		}
+		if lastIP == change.NewIP {
+			to = &wans[i]
+		}
+	}
+
+	switch {
+	case to == nil:
+		return
+	case to.Backup && (from == nil || !from.Backup):
+		change.FailoverTo = to.Name
+	case !to.Backup && from != nil && from.Backup:
+		change.RestoredTo = to.Name
+	}
+}
+
+// waitForNetwork retries fetching the IP with backoff until it succeeds or
+// the grace period ends, so that a monitor started before the network is up
+// neither misses its startup catch-up nor begins with failed checks
+// ipUserAgent returns the User-Agent of the requests to the IP services
+func ipUserAgent(cfg *config.Config) string {
+	if cfg.IP.UserAgent != "" {
+		return cfg.IP.UserAgent
+	}
+	return "public-ip-monitor/" + version
+}
+
+func waitForNetwork(ctx context.Context, fetcher *ip.Fetcher, grace time.Duration, log *logger.Logger) {
+	deadline := appClock.Now().Add(grace)
+	delay := time.Second
+
+	for attempt := 1; ; attempt++ {
+		checkCtx, cancel := context.WithTimeout(ctx, clock.Until(appClock, deadline))
+		_, err := fetcher.GetCurrentIP(checkCtx)
+		cancel()
+
+		if err == nil {
+			if attempt > 1 {
+				log.Infof("Network available after %d attempts", attempt)
+			}
+			return
+		}
+
+		remaining := clock.Until(appClock, deadline)
+		if remaining <= 0 || ctx.Err() != nil {
+			log.Warnf("Network still unavailable after %v startup grace period: %v", grace, err)
+			return
+		}
+
+		delay = min(delay, remaining)
+		log.Infof("Network not available yet, retrying in %v", delay.Round(time.Second))
+
+		if clock.Sleep(ctx, appClock, delay) != nil {
+			return
+		}
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+// logResources logs the resource settings in effect
+func logResources(applied resources.Applied, log *logger.Logger) {
+	cpus := "unlimited"
+	if applied.CPUQuota > 0 {
+		cpus = fmt.Sprintf("%.2f CPUs", applied.CPUQuota)
+	}
+	memory := "unlimited"
+	if applied.ContainerMemory > 0 {
+		memory = fmt.Sprintf("%d MiB", applied.ContainerMemory>>20)
+	}
+	log.Infof("Resources: GOMAXPROCS=%d (CPU quota: %s), container memory: %s", applied.GOMAXPROCS, cpus, memory)
+
+	if applied.MemoryLimitBytes > 0 {
+		log.Infof("Go memory limit: %d MiB", applied.MemoryLimitBytes>>20)
+	}
+	if applied.BallastBytes > 0 {
+		log.Infof("Heap ballast: %d MiB", applied.BallastBytes>>20)
+	}
+}
+
+// logResourceUsage logs the current resource usage
+func logResourceUsage(log *logger.Logger) {
+	usage := resources.ReadUsage()
+	log.Infof("Resource usage: %d goroutines, heap %d KiB in use / %d KiB reserved, %d KiB from OS, %d GC cycles",
+		usage.Goroutines, usage.HeapAllocBytes>>10, usage.HeapSysBytes>>10, usage.SysBytes>>10, usage.NumGC)
+}
+
+// resourceStatus combines the resource settings in effect and the usage for
+// the API
+func resourceStatus(applied resources.Applied, usage resources.Usage) api.Resources {
+	return api.Resources{
+		GOMAXPROCS:       applied.GOMAXPROCS,
+		CPUQuota:         applied.CPUQuota,
+		MemoryLimitBytes: applied.MemoryLimitBytes,
+		ContainerMemory:  applied.ContainerMemory,
+		Goroutines:       usage.Goroutines,
+		HeapAllocBytes:   usage.HeapAllocBytes,
+		HeapSysBytes:     usage.HeapSysBytes,
+		SysBytes:         usage.SysBytes,
+		NumGC:            usage.NumGC,
+	}
+}
+
+// runCommand runs a subcommand given on the command line
+func runCommand(args []string, taskScheduler *scheduler.Scheduler, location *time.Location) error {
+	switch {
+	case len(args) == 2 && args[0] == "schedule" && args[1] == "list":
+		printSchedule(taskScheduler, location)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q (available: schedule list, notify test [channel...], notifications list-failed, notifications resend [id...], notifications quota, events list, events replay --channel <name> [--since time], ack <event-id> [--by name], history export, services bench, services status, config show [--effective], config defaults, healthcheck)", strings.Join(args, " "))
+	}
+}
+
+// sampleEvent is the change sent by "notify test". The new IP is the last
+// known one, so that channels keeping the current IP (e.g. MQTT retained
+// messages) stay correct.
+func sampleEvent(family ip.Family, lastIP, site string) notify.Event {
+	if lastIP == "" {
+		lastIP = "198.51.100.2"
+	}
+	event := notify.NewChangeEvent([]config.IPChange{{
+		Family: family.Label(),
+		OldIP:  "203.0.113.1",
+		NewIP:  lastIP,
+	}}, nil, time.Now())
+	event.Site = site
+	return event
+}
+
+// runNotifyTest sends the event through every channel, or those named, once
+// and without retries, and reports which ones failed
+func runNotifyTest(args []string, notifiers []notify.Notifier, event notify.Event, log *logger.Logger) error {
+	if len(args) < 2 || args[0] != "notify" || args[1] != "test" {
+		return fmt.Errorf("unknown command %q (available: notify test [channel...])", strings.Join(args, " "))
+	}
+	names := args[2:]
+
+	var results []string
+	failed := 0
+	for _, notifier := range notifiers {
+		if len(names) > 0 && !slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, notifier.Name()) }) {
+			continue
+		}
+
+		log.Infof("Sending test notification through %s...", notifier.Name())
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := notifier.Notify(ctx, event)
+		cancel()
+
+		if err != nil {
+			failed++
+			results = append(results, fmt.Sprintf("  FAIL  %s: %v", notifier.Name(), err))
+		} else {
+			results = append(results, fmt.Sprintf("  OK    %s", notifier.Name()))
+		}
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("no enabled channel matches %s", strings.Join(names, ", "))
+	}
+	fmt.Println("Test notification results:")
+	for _, result := range results {
+		fmt.Println(result)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d channels failed", failed, len(results))
+	}
+	return nil
+}
+
+// runListFailed prints the notifications that failed on every attempt
+func runListFailed(args []string, path string, location *time.Location) error {
+	if len(args) != 2 || args[1] != "list-failed" {
+		return fmt.Errorf("unknown command %q (available: notifications list-failed, notifications resend [id...], notifications quota)", strings.Join(args, " "))
+	}
+
+	deadLetters, err := notify.OpenDeadLetters(path)
+	if err != nil {
+		return err
+	}
+	letters, err := deadLetters.List()
+	if err != nil {
+		return err
+	}
+	if len(letters) == 0 {
+		fmt.Println("No failed notifications")
+		return nil
+	}
+
+	fmt.Println("Failed notifications:")
+	for _, letter := range letters {
+		fmt.Printf("  #%-4d %s  %-16s %s\n", letter.ID, letter.FailedAt.In(location).Format("2006-01-02 15:04:05"), letter.Channel, describeEvent(letter.Event))
+		details := fmt.Sprintf("%d attempts", letter.Attempts)
+		if letter.Event.ID != "" {
+			details += ", event " + letter.Event.ID
+		}
+		fmt.Printf("        %s (%s)\n", letter.Error, details)
+	}
+	fmt.Printf("Send them again with \"notifications resend [id...]\"\n")
+	return nil
+}
+
+// runEventsCommand runs "ack <event-id> [--by name]", acknowledging an
+// event by the ID in its notifications so that it is not escalated, or
+// "events list", printing the recent events and who acknowledged them
+func runEventsCommand(args []string, dataDir string, location *time.Location) error {
+	journal, err := openEventJournal(dataDir)
+	if err != nil {
+		return err
+	}
+	if args[0] == "events" {
+		if len(args) != 2 || args[1] != "list" {
+			return fmt.Errorf("unknown command %q (available: events list, events replay --channel <name> [--since time], ack <event-id> [--by name])", strings.Join(args, " "))
+		}
+		return listEvents(journal, location)
+	}
+
+	flags := flag.NewFlagSet("ack", flag.ContinueOnError)
+	by := flags.String("by", "", "who acknowledges the event (default: the current user)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	// The ID may come before the flags as well
+	ids := flags.Args()
+	if len(ids) > 1 {
+		if err := flags.Parse(ids[1:]); err != nil {
+			return err
+		}
+		ids = append([]string{ids[0]}, flags.Args()...)
+	}
+	if len(ids) != 1 || ids[0] == "" {
+		return fmt.Errorf("usage: ack <event-id> [--by name]")
+	}
+	if *by == "" {
+		*by = os.Getenv("USER")
+	}
+	if *by == "" {
+		*by = "cli"
+	}
+
+	record, pending, err := journal.Acknowledge(ids[0], *by)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Event %s (%s) acknowledged by %s at %s", record.ID, record.Summary, record.AckedBy, record.AckedAt.In(location).Format("2006-01-02 15:04:05"))
+	if pending {
+		fmt.Print(", its escalation is cancelled")
+	}
+	fmt.Println()
+	return nil
+}
+
+// listEvents prints the recent events, oldest first
+func listEvents(journal *eventJournal, location *time.Location) error {
+	records, err := journal.Records()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("No events notified yet")
+		return nil
+	}
+	fmt.Println("Recent events:")
+	for _, record := range records {
+		acked := "not acknowledged"
+		if !record.AckedAt.IsZero() {
+			acked = fmt.Sprintf("acknowledged by %s at %s", record.AckedBy, record.AckedAt.In(location).Format("2006-01-02 15:04:05"))
+		}
+		fmt.Printf("  %s  %s  %s\n        %s\n", record.ID, record.Timestamp.In(location).Format("2006-01-02 15:04:05"), record.Summary, acked)
+	}
+	return nil
+}
+
+// runReplay runs "events replay --channel <name> [--since time]", sending
+// the change events of the event history again through the named channels,
+// marked as replays, e.g. so that a consumer added since can backfill its
+// state. Each channel gets the events oldest first and none after one it
+// failed, so that it never sees them out of order.
+func runReplay(args []string, notifiers []notify.Notifier, dataDir string, log *logger.Logger) error {
+	usage := fmt.Errorf("usage: events replay --channel <name> [--channel <name>...] [--since time]")
+	if len(args) < 2 || args[1] != "replay" {
+		return fmt.Errorf("unknown command %q (available: events list, events replay --channel <name> [--since time], ack <event-id> [--by name])", strings.Join(args, " "))
+	}
+	flags := flag.NewFlagSet("events replay", flag.ContinueOnError)
+	since := flags.String("since", "", "Only events at or after this time, e.g. 2025-06-01, 2025-06-01T08:00:00Z or 72h (ago)")
+	var names []string
+	flags.Func("channel", "Channel to send the events through, by name as in the logs; repeat for several", func(name string) error {
+		names = append(names, name)
+		return nil
+	})
+	if err := flags.Parse(args[2:]); err != nil {
+		return err
+	}
+	if len(names) == 0 || flags.NArg() > 0 {
+		return usage
+	}
+
+	var from time.Time
+	if *since != "" {
+		var err error
+		if from, err = ip.ParseHistoryTime(*since, time.Now(), log.Location()); err != nil {
+			return fmt.Errorf("since: %w", err)
+		}
+	}
+	var selected []notify.Notifier
+	for _, name := range names {
+		i := slices.IndexFunc(notifiers, func(n notify.Notifier) bool { return strings.EqualFold(n.Name(), name) })
+		if i < 0 {
+			return fmt.Errorf("channel %q is not enabled", name)
+		}
+		selected = append(selected, notifiers[i])
+	}
+
+	journal, err := openEventJournal(dataDir)
+	if err != nil {
+		return err
+	}
+	var events []notify.Event
+	for _, event := range journal.history.List() {
+		if event.IsChange() && !event.Timestamp.Before(from) {
+			event.Replay = true
+			event.Escalated = false
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		fmt.Println("No change events to replay")
+		return nil
+	}
+
+	var results []string
+	failed := 0
+	for _, notifier := range selected {
+		sent, skipped := 0, 0
+		var failure error
+		for _, event := range events {
+			if !notify.Accepts(notifier, event) {
+				skipped++
+				continue
+			}
+			log.Infof("Replaying event %s through %s...", event.ID, notifier.Name())
+			failure = sendWithRetry(context.Background(), notifier.Name(), event, log, func(ctx context.Context) error {
+				return notifier.Notify(ctx, event)
+			})
+			if failure != nil {
+				failure = fmt.Errorf("event %s: %w", event.ID, failure)
+				break
+			}
+			sent++
+		}
+
+		result := fmt.Sprintf("%s: %d of %d events sent", notifier.Name(), sent, len(events))
+		if skipped > 0 {
+			result += fmt.Sprintf(", %d not among its events", skipped)
+		}
+		if failure != nil {
+			failed++
+			results = append(results, fmt.Sprintf("  FAIL  %s, then %v", result, failure))
+		} else {
+			results = append(results, "  OK    "+result)
+		}
+	}
+
+	fmt.Println("Replay results:")
+	for _, result := range results {
+		fmt.Println(result)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d channels failed", failed, len(results))
+	}
+	return nil
+}
+
+// describeEvent summarizes an event on one line, e.g.
+// "ip_changed: IPv4 203.0.113.1 -> 198.51.100.2"
+func describeEvent(event notify.Event) string {
+	var parts []string
+	for _, change := range event.Changes {
+		parts = append(parts, fmt.Sprintf("%s %s -> %s", change.Label(), change.OldIP, change.NewIP))
+	}
+	if len(event.Changes) == 0 {
+		for _, failure := range event.Failures {
+			parts = append(parts, fmt.Sprintf("%s (%d checks)", failure.Label(), failure.Count))
+		}
+	}
+	for _, failure := range event.HookFailures {
+		parts = append(parts, "hook "+failure.Name)
+	}
+	if event.Lifecycle != nil {
+		for _, address := range event.Lifecycle.Addresses {
+			parts = append(parts, fmt.Sprintf("%s %s", address.Label(), address.Value()))
+		}
+	}
+	return fmt.Sprintf("%s: %s", event.Type, strings.Join(parts, ", "))
+}
+
+// runResend sends the failed notifications, or those with the given IDs,
+// again with retries. Delivered ones are removed from the dead letters.
+func runResend(args []string, notifiers []notify.Notifier, deadLetters *notify.DeadLetters, log *logger.Logger) error {
+	if len(args) < 2 || args[1] != "resend" {
+		return fmt.Errorf("unknown command %q (available: notifications list-failed, notifications resend [id...], notifications quota)", strings.Join(args, " "))
+	}
+	var ids []int64
+	for _, arg := range args[2:] {
+		id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid notification ID %q", arg)
+		}
+		ids = append(ids, id)
+	}
+
+	letters, err := deadLetters.List()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(letters, func(letter notify.DeadLetter) bool { return letter.ID == id }) {
+			return fmt.Errorf("no failed notification #%d", id)
+		}
+	}
+
+	var results []string
+	var changed []notify.DeadLetter
+	var removed []int64
+	for _, letter := range letters {
+		if len(ids) > 0 && !slices.Contains(ids, letter.ID) {
+			continue
+		}
+
+		i := slices.IndexFunc(notifiers, func(n notify.Notifier) bool { return strings.EqualFold(n.Name(), letter.Channel) })
+		if i < 0 {
+			results = append(results, fmt.Sprintf("  FAIL  #%d %s: channel is not enabled", letter.ID, letter.Channel))
+			continue
+		}
+
+		log.Infof("Resending notification #%d through %s...", letter.ID, letter.Channel)
+		err := sendWithRetry(context.Background(), letter.Channel, letter.Event, log, func(ctx context.Context) error {
+			return notifiers[i].Notify(ctx, letter.Event)
+		})
+		if err != nil {
+			letter.Error = err.Error()
+			letter.FailedAt = time.Now()
+			letter.Attempts += notifyAttempts
+			changed = append(changed, letter)
+			results = append(results, fmt.Sprintf("  FAIL  #%d %s: %v", letter.ID, letter.Channel, err))
+			continue
+		}
+		removed = append(removed, letter.ID)
+		results = append(results, fmt.Sprintf("  OK    #%d %s", letter.ID, letter.Channel))
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No failed notifications")
+		return nil
+	}
+	if err := deadLetters.Update(changed, removed); err != nil {
+		return err
+	}
+	fmt.Println("Resend results:")
+	for _, result := range results {
+		fmt.Println(result)
+	}
+	if failed := len(results) - len(removed); failed > 0 {
+		return fmt.Errorf("%d of %d notifications failed", failed, len(results))
+	}
+	return nil
+}
+
+// runConfigCommand prints the configuration: "show" as written in the file,
+// "show --effective" with all defaults applied, both with secrets redacted,
+// and "defaults" as a commented default file
+func runConfigCommand(args []string, manager *config.Manager) error {
+	var cfg *config.Config
+	var err error
+	options := config.RenderOptions{Redact: true}
+
+	switch {
+	case len(args) == 1 && args[0] == "defaults":
+		cfg = manager.Defaults()
+		options = config.RenderOptions{Comments: true}
+	case len(args) == 1 && args[0] == "show":
+		cfg, err = manager.Read()
+	case len(args) == 2 && args[0] == "show" && (args[1] == "--effective" || args[1] == "-effective"):
+		cfg, err = manager.Load()
+	default:
+		return fmt.Errorf("unknown command %q (available: config show [--effective], config defaults)", strings.Join(append([]string{"config"}, args...), " "))
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := config.Render(cfg, options)
+	if err != nil {
+		return fmt.Errorf("failed to render configuration: %w", err)
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// runHistoryCommand exports the history of the default route and all WANs
+// for analysis, e.g. "history export --format parquet --output history.parquet",
+// or searches it, e.g. "history search vodafone"
+func runHistoryCommand(args []string, storage *ip.Storage, cfg *config.Config, redactor *privacy.Redactor, location *time.Location) error {
+	if len(args) > 0 && args[0] == "search" {
+		return searchHistory(args[1:], storage, cfg, redactor, location)
+	}
+	if len(args) == 0 || args[0] != "export" {
+		return fmt.Errorf("unknown command %q (available: history export [--format csv|json|parquet] [--output file] [filters], history search [filters] <words>)", strings.Join(append([]string{"history"}, args...), " "))
+	}
+
+	flags := flag.NewFlagSet("history export", flag.ContinueOnError)
+	format := flags.String("format", "json", "Export format: "+strings.Join(ip.ExportFormats, ", "))
+	output := flags.String("output", "", "File to write to instead of standard output")
+	var filters historyFilters
+	filters.addFlags(flags)
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if !slices.Contains(ip.ExportFormats, *format) {
+		return fmt.Errorf("unknown export format %q (available: %s)", *format, strings.Join(ip.ExportFormats, ", "))
+	}
+
+	histories, err := readHistories(storage, cfg.IP.WANs)
+	if err != nil {
+		return err
+	}
+
+	page, err := filters.apply(redactExport(ip.BuildExportRecords(histories), redactor), cfg.Site, location)
+	if err != nil {
+		return err
+	}
+	exported := page.Records
+	if *output == "" {
+		return ip.WriteExport(os.Stdout, *format, exported)
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := ip.WriteExport(file, *format, exported); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to export history: %w", err)
+	}
+	return file.Close()
+}
+
+// searchHistory prints the records of all histories matching the words,
+// e.g. "vodafone" or "country:de ipv6", or as JSON with --json
+func searchHistory(args []string, storage *ip.Storage, cfg *config.Config, redactor *privacy.Redactor, location *time.Location) error {
+	flags := flag.NewFlagSet("history search", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "Print the matching records as JSON")
+	var filters historyFilters
+	filters.addFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	query := ip.ParseHistoryQuery(strings.Join(flags.Args(), " "))
+	if query.Empty() {
+		return fmt.Errorf("usage: history search [--json] [filters] <words>, e.g. history search vodafone, history search --since 720h country:de ipv6")
+	}
+
+	histories, err := readHistories(storage, cfg.IP.WANs)
+	if err != nil {
+		return err
+	}
+	records := redactExport(ip.BuildExportRecords(histories), redactor)
+	page, err := filters.apply(ip.SearchHistory(records, query), cfg.Site, location)
+	if err != nil {
+		return err
+	}
+	matched := page.Records
+	if *asJSON {
+		return ip.WriteExport(os.Stdout, "json", matched)
+	}
+
+	if len(matched) == 0 {
+		fmt.Println("No matching records")
+		return nil
+	}
+	for _, record := range matched {
+		label := config.IPChange{Family: record.Family.Label(), WAN: record.WAN}.Label()
+		fmt.Printf("%s  %-16s %s\n", record.Timestamp.Format("2006-01-02 15:04:05"), label, record.IP)
+		if details := formatEnrichment(record.Enrichment); details != "" {
+			fmt.Printf("    %s\n", details)
+		}
+	}
+	if page.NextCursor != "" {
+		fmt.Printf("First %d matching records of %d, raise --limit for more\n", len(matched), len(records))
+		return nil
+	}
+	fmt.Printf("%d of %d records match\n", len(matched), len(records))
+	return nil
+}
+
+// historyFilters narrow the history in "history export", "history search"
+// and GET /history alike
+type historyFilters struct {
+	Since, Until string // Time, date or duration ago
+	IP           string
+	Site         string
+	Limit        int
+	Cursor       string // API only: the commands print everything
+}
+
+// addFlags registers the filters as flags of a history command
+func (f *historyFilters) addFlags(flags *flag.FlagSet) {
+	flags.StringVar(&f.Since, "since", "", "Only records at or after this time, e.g. 2025-06-01, 2025-06-01T08:00:00Z or 72h (ago)")
+	flags.StringVar(&f.Until, "until", "", "Only records before this time")
+	flags.StringVar(&f.IP, "ip", "", "Only records changing to or from this IP")
+	flags.StringVar(&f.Site, "site", "", "Only records of this site")
+	flags.IntVar(&f.Limit, "limit", 0, "At most this many records, oldest first")
+}
+
+// apply returns the page of records matching the filters. Every record is
+// of this monitor's site, so naming another site matches none.
+func (f historyFilters) apply(records []ip.ExportRecord, site string, location *time.Location) (ip.HistoryPage, error) {
+	if f.Limit < 0 {
+		return ip.HistoryPage{}, fmt.Errorf("limit must not be negative")
+	}
+	filter := ip.HistoryFilter{IP: f.IP, Limit: f.Limit, Cursor: f.Cursor}
+	now := time.Now()
+	var err error
+	if f.Since != "" {
+		if filter.Since, err = ip.ParseHistoryTime(f.Since, now, location); err != nil {
+			return ip.HistoryPage{}, fmt.Errorf("since: %w", err)
+		}
+	}
+	if f.Until != "" {
+		if filter.Until, err = ip.ParseHistoryTime(f.Until, now, location); err != nil {
+			return ip.HistoryPage{}, fmt.Errorf("until: %w", err)
+		}
+	}
+	if f.Site != "" && !strings.EqualFold(f.Site, site) {
+		records = nil
+	}
+	return ip.FilterHistory(records, filter)
+}
+
+// formatEnrichment lists enrichment details by name, e.g.
+// "as_name=VODAFONE, asn=AS3209, country=DE"
+func formatEnrichment(details map[string]string) string {
+	names := slices.Sorted(maps.Keys(details))
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, name+"="+details[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// redactExport hides the IPs of exported records, including any in their
+// enrichment details
+func redactExport(records []ip.ExportRecord, redactor *privacy.Redactor) []ip.ExportRecord {
+	if redactor == nil {
+		return records
+	}
+	for i, record := range records {
+		records[i].IP = redactor.IP(record.IP)
+		records[i].PreviousIP = redactor.IP(record.PreviousIP)
+		if len(record.Enrichment) > 0 {
+			enrichment := make(map[string]string, len(record.Enrichment))
+			for name, value := range record.Enrichment {
+				enrichment[name] = redactor.Text(value)
+			}
+			records[i].Enrichment = enrichment
+		}
+	}
+	return records
+}
+
+// healthFile is written after every check, for the healthcheck command, in
+// ip.data_dir or low_write.runtime_dir (see config.GetRuntimeDir)
+const healthFile = "health.json"
+
+// serviceHealthFile keeps the track record of every IP service in
+// ip.data_dir across restarts
+const serviceHealthFile = "service_health.json"
+
+// serviceHealthSaveInterval is how often the service health is saved at
+// most, rather than after every check
+const serviceHealthSaveInterval = 10 * time.Minute
+
+// pauseFile records in ip.data_dir that checking is paused, so that it
+// stays paused across restarts
+const pauseFile = "paused.json"
+
+// deadLetterFile keeps the notifications in ip.data_dir that failed on
+// every attempt
+const deadLetterFile = "failed_notifications.jsonl"
+
+// spoolFile queues the notifications in ip.data_dir until every channel
+// got them
+const spoolFile = "notification_spool.jsonl"
+
+// ackFile keeps the acknowledged events in ip.data_dir
+const ackFile = "acknowledged_events.jsonl"
+
+// escalationFile keeps the events in ip.data_dir waiting to be escalated
+const escalationFile = "escalations.json"
+
+// eventHistoryFile keeps the recent events in ip.data_dir, to look them up
+// when acknowledged
+const eventHistoryFile = "event_history.jsonl"
+
+// healthStatus is the outcome of the last check, as seen by healthcheck
+type healthStatus struct {
+	CheckedAt       time.Time `json:"checked_at"`
+	OK              bool      `json:"ok"`
+	Error           string    `json:"error,omitempty"`
+	IntervalSeconds int       `json:"interval_seconds,omitempty"` // Check interval in effect, which the admin API may have changed
+	PausedAt        time.Time `json:"paused_at,omitzero"`         // When checking was paused, if it is
+}
+
+// writeHealth records that the monitoring loop is alive
+func writeHealth(path string, result ip.CheckResult, interval time.Duration, pausedAt time.Time, log *logger.Logger) {
+	status := healthStatus{CheckedAt: time.Now(), OK: result.Error == nil, IntervalSeconds: int(interval / time.Second), PausedAt: pausedAt}
+	if result.Error != nil {
+		status.Error = result.Error.Error()
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(path, data, ip.DataFilePerm); err != nil {
+		log.Warnf("Failed to write health status: %v", err)
+	}
+}
+
+// runHealthcheck checks that a running monitor completed a check recently,
+// for Docker's HEALTHCHECK. Failing checks (e.g. the Internet is down)
+// still count as healthy: restarting the container would not help. Nor
+// would it resume paused checks, so a paused monitor is healthy too.
+func runHealthcheck(path string, interval time.Duration) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no check recorded yet")
+	}
+	if err != nil {
+		return err
+	}
+	var status healthStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("invalid health status: %w", err)
+	}
+
+	if !status.PausedAt.IsZero() {
+		fmt.Printf("healthy: checks paused since %s\n", status.PausedAt.Local().Format("2006-01-02 15:04:05"))
+		return nil
+	}
+
+	if status.IntervalSeconds > 0 {
+		interval = time.Duration(status.IntervalSeconds) * time.Second
+	}
+
+	// A check may take up to the fetch timeout on top of the interval
+	age := time.Since(status.CheckedAt)
+	if age > 2*interval+time.Minute {
+		return fmt.Errorf("last check %v ago (interval %v)", age.Round(time.Second), interval)
+	}
+
+	if status.OK {
+		fmt.Printf("healthy: last check %v ago\n", age.Round(time.Second))
+	} else {
+		fmt.Printf("healthy: last check %v ago failed: %s\n", age.Round(time.Second), status.Error)
+	}
+	return nil
+}
+
+// recordCheck adds the outcome of a check to the check log, if enabled
+func recordCheck(checkLog *ip.CheckLog, target monitorTarget, result ip.CheckResult, log *logger.Logger) {
+	if checkLog == nil {
+		return
+	}
+
+	entry := ip.CheckEntry{
+		Time:      time.Now(),
+		Family:    target.Family,
+		WAN:       target.WAN,
+		OK:        result.Error == nil,
+		IP:        result.CurrentIP,
+		Source:    result.Source.Detail,
+		LatencyMS: result.Latency.Milliseconds(),
+	}
+	if entry.Source == "" {
+		entry.Source = result.Source.Source
+	}
+	if result.Error != nil {
+		entry.Error = result.Error.Error()
+	}
+
+	if err := checkLog.Add(entry); err != nil {
+		log.Warnf("Failed to record check: %v", err)
+	}
+}
+
+// checksFunc exposes the check log to the API; nil leaves /checks unserved
+func checksFunc(checkLog *ip.CheckLog) func(since time.Time) []api.CheckRecord {
+	if checkLog == nil {
+		return nil
+	}
+	return func(since time.Time) []api.CheckRecord {
+		entries := checkLog.Entries(since)
+		records := make([]api.CheckRecord, 0, len(entries))
+		for _, entry := range entries {
+			records = append(records, api.CheckRecord{
+				Family:  entry.Family.Label(),
+				WAN:     entry.WAN,
+				Time:    entry.Time,
+				OK:      entry.OK,
+				Source:  entry.Source,
+				Latency: time.Duration(entry.LatencyMS) * time.Millisecond,
+			})
+		}
+		return records
+	}
+}
+
+// quotasFunc exposes the quota usage to the API; nil leaves it out of /status
+func quotasFunc(quotas *quotaPolicy) func() []api.QuotaUsage {
+	if quotas == nil {
+		return nil
+	}
+	return func() []api.QuotaUsage {
+		usage := quotas.Usage()
+		records := make([]api.QuotaUsage, len(usage))
+		for i, quota := range usage {
+			records[i] = api.QuotaUsage(quota)
+		}
+		return records
+	}
+}
+
+// readHistories reads the history of the default route ("") and of every WAN
+func readHistories(storage *ip.Storage, wans []config.WANConfig) (map[string][]ip.Record, error) {
+	histories := map[string][]ip.Record{}
+	records, err := storage.GetHistory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IP history: %w", err)
+	}
+	histories[""] = records
+	for _, wan := range wans {
+		records, err := storage.ForWAN(wan.Name).GetHistory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get IP history for WAN %s: %w", wan.Name, err)
+		}
+		histories[wan.Name] = records
+	}
+	return histories, nil
+}
+
+// printSchedule prints the scheduled tasks and when they run next
+func printSchedule(taskScheduler *scheduler.Scheduler, location *time.Location) {
+	entries := taskScheduler.Entries()
+
+	fmt.Println("\n=== Scheduled Tasks ===")
+	if len(entries) == 0 {
+		fmt.Println("No tasks scheduled.")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TASK\tSCHEDULE\tNEXT RUN")
+		for _, entry := range entries {
+			next := "never"
+			if !entry.Next.IsZero() {
+				next = entry.Next.In(location).Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Name, entry.Spec, next)
+		}
+		w.Flush()
+	}
+	fmt.Println("=======================")
+}
+
+// runServicesCommand handles "services bench", which queries every
+// configured IP service for each monitored family, reports its
+// availability, agreement with the other services and latency, and
+// suggests an order; --write saves the suggestion to the config file.
+// "services status" prints the health the monitor tracked instead.
+func runServicesCommand(args []string, fetcher *ip.Fetcher, families []ip.Family, cfg *config.Config, manager *config.Manager) error {
+	if len(args) > 0 && args[0] == "status" {
+		return printServiceHealth(filepath.Join(cfg.IP.DataDir, serviceHealthFile))
+	}
+	if len(args) == 0 || args[0] != "bench" {
+		return fmt.Errorf("unknown command %q (available: services bench [--samples n] [--write], services status)", strings.Join(append([]string{"services"}, args...), " "))
+	}
+
+	flags := flag.NewFlagSet("services bench", flag.ContinueOnError)
+	samples := flags.Int("samples", 5, "Queries per service and family")
+	pause := flags.Duration("pause", time.Second, "Time between rounds of queries")
+	write := flags.Bool("write", false, "Save the suggested order and timeout to the config file")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *samples < 1 {
+		return fmt.Errorf("--samples must be at least 1")
+	}
+	if len(cfg.IP.Services) == 0 {
+		return fmt.Errorf("no IP services configured (ip.services)")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	merged := make(map[string]ip.ServiceStats)
+	for _, family := range families {
+		fmt.Printf("Benchmarking %d services, %d samples each (%s)...\n", len(cfg.IP.Services), *samples, family.Label())
+		stats := fetcher.ForFamily(family).BenchServices(ctx, cfg.IP.Services, *samples, *pause)
+		if ctx.Err() != nil {
+			return fmt.Errorf("interrupted")
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVICE\tANSWERED\tAGREED\tMEDIAN\tP95\tLAST ERROR")
+		for _, s := range stats {
+			median, p95 := "-", "-"
+			if s.Successes > 0 {
+				median = s.Percentile(0.5).Round(time.Millisecond).String()
+				p95 = s.Percentile(0.95).Round(time.Millisecond).String()
+			}
+			fmt.Fprintf(w, "%s\t%d/%d\t%.0f%%\t%s\t%s\t%s\n",
+				s.URL, s.Successes, s.Samples, 100*s.Agreement(), median, p95, s.LastError)
+			if previous, ok := merged[s.URL]; ok {
+				s = previous.Merge(s)
+			}
+			merged[s.URL] = s
+		}
+		w.Flush()
+		fmt.Println()
+	}
+
+	stats := make([]ip.ServiceStats, 0, len(cfg.IP.Services))
+	for _, service := range cfg.IP.Services {
+		stats = append(stats, merged[service])
+	}
+	suggested := ip.SuggestServiceOrder(stats)
+	if len(suggested) == 0 {
+		return fmt.Errorf("no service answered reliably; check the network and the services")
+	}
+
+	order := make([]string, 0, len(suggested))
+	var slowest time.Duration
+	for _, s := range suggested {
+		order = append(order, s.URL)
+		slowest = max(slowest, s.Percentile(0.95))
+	}
+	// Leave room for slow moments, without waiting long on a service that is down
+	timeout := min(max(int(math.Ceil((3*slowest).Seconds())), 2), 30)
+
+	fmt.Println("Suggested ip.services, the most reliable first and the fastest first among equals:")
+	for i, service := range order {
+		fmt.Printf("  %d. %s\n", i+1, service)
+	}
+	for _, s := range stats {
+		if !s.Usable() {
+			fmt.Printf("Left out, answered %d/%d and agreed %.0f%%: %s\n", s.Successes, s.Samples, 100*s.Agreement(), s.URL)
+		}
+	}
+	fmt.Printf("Suggested ip.timeout_seconds: %d (now %d, slowest p95 %v)\n", timeout, cfg.IP.TimeoutSeconds, slowest.Round(time.Millisecond))
+	if cfg.IP.ServicesIndex.URL != "" {
+		fmt.Println("Note: ip.services_index.url is set, so the services of the index replace ip.services while it is available")
+	}
+
+	if !*write {
+		fmt.Println("Run with --write to save the suggestion to the config file.")
+		return nil
+	}
+	err := manager.Update(func(c *config.Config) {
+		c.IP.Services = order
+		c.IP.TimeoutSeconds = timeout
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save the suggestion: %w", err)
+	}
+	fmt.Println("Saved to the config file.")
+	return nil
+}
+
+// printServiceHealth prints the track record of every service and source
+// the monitor asked, flaky ones marked
+func printServiceHealth(path string) error {
+	health := ip.NewHealthTracker(path)
+	if err := health.Load(); err != nil {
+		return err
+	}
+	records := health.Records()
+	if len(records) == 0 {
+		fmt.Println("No service health recorded yet; it is tracked while the monitor runs.")
+		return nil
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FAMILY	SERVICE	ANSWERED	SCORE	LATENCY	STATUS	LAST ERROR")
+	for _, h := range records {
+		latency, status := "-", "ok"
+		if h.Successes > 0 {
+			latency = time.Duration(h.LatencyMS * float64(time.Millisecond)).Round(time.Millisecond).String()
+		}
+		if h.Flaky(now) {
+			status = fmt.Sprintf("flaky, %d failures in a row", h.ConsecutiveFailures)
+		}
+		lastError := h.LastError
+		if !h.LastFailure.IsZero() {
+			lastError = fmt.Sprintf("%s ago: %s", now.Sub(h.LastFailure).Round(time.Minute), h.LastError)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%.0f%%\t%s\t%s\t%s\n",
+			h.Family.Label(), h.Source, h.Successes, h.Attempts, 100*h.Score, latency, status, lastError)
+	}
+	return w.Flush()
+}
+
+// refreshServicesIndex replaces the fetcher's services with those from the
+// remote index, keeping the current ones if the index is unavailable
+func refreshServicesIndex(ctx context.Context, loader *ip.IndexLoader, fetcher *ip.Fetcher, log *logger.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	index, err := loader.Fetch(ctx)
+	if index == nil {
+		log.Warnf("Services index unavailable, keeping %d current services: %v", len(fetcher.Services()), err)
+		return
+	}
+	if err != nil {
+		log.Warnf("Services index fetched but not cached: %v", err)
+	}
+
+	if !slices.Equal(index.Services, fetcher.Services()) {
+		log.Infof("Services index updated: using %d services", len(index.Services))
+	}
+	fetcher.SetServices(index.Services)
+}
+
+// monitorTarget identifies what a monitor watches: an address family on the
+// default route or through a specific WAN profile
+type monitorTarget struct {
+	Family ip.Family
+	WAN    string // Empty for the default route
+}
+
+// Label returns a human-readable name for the target, e.g. "IPv4 (backup)"
+func (t monitorTarget) Label() string {
+	if t.WAN == "" {
+		return t.Family.Label()
+	}
+	return fmt.Sprintf("%s (%s)", t.Family.Label(), t.WAN)
+}
+
+// targetResult is a check result tagged with the target it was performed for
+type targetResult struct {
+	ip.CheckResult
+	Target monitorTarget
+}
+
+// failureTracker counts consecutive failed checks per target, and the
+// successful ones after a reported failure
+type failureTracker struct {
+	threshold         int
+	recoveryThreshold int
+	failures          map[monitorTarget]*config.CheckFailure
+	successes         map[monitorTarget]int
+}
+
+func newFailureTracker(threshold, recoveryThreshold int) *failureTracker {
+	return &failureTracker{
+		threshold:         threshold,
+		recoveryThreshold: recoveryThreshold,
+		failures:          make(map[monitorTarget]*config.CheckFailure),
+		successes:         make(map[monitorTarget]int),
+	}
+}
+
+// Failed records a failed check and returns the failure when it reaches the threshold
+func (t *failureTracker) Failed(target monitorTarget, err error) *config.CheckFailure {
+	failure, ok := t.failures[target]
+	if !ok {
+		failure = &config.CheckFailure{Family: target.Family.Label(), WAN: target.WAN, Since: time.Now()}
+		t.failures[target] = failure
+	}
+	failure.Count++
+	failure.Error = err.Error()
+	// A recovery has to start over
+	delete(t.successes, target)
+
+	if failure.Count != t.threshold {
+		return nil
+	}
+	return failure
+}
+
+// Succeeded resets the target and returns its failure if it had been
+// reported. A reported failure is only over after the recovery threshold
+// of successful checks in a row.
+func (t *failureTracker) Succeeded(target monitorTarget) *config.CheckFailure {
+	failure, ok := t.failures[target]
+	if !ok {
+		return nil
+	}
+	if failure.Count < t.threshold {
+		delete(t.failures, target)
+		return nil
+	}
+
+	t.successes[target]++
+	if t.successes[target] < t.recoveryThreshold {
+		return nil
+	}
+	delete(t.failures, target)
+	delete(t.successes, target)
+	return failure
+}
+
+// cyclePinger pings a dead man's switch service once every target was
+// checked: the check URL when all checks succeeded, the fail URL with the
+// errors otherwise. Pings are sent in the background, so a slow service
+// does not hold up the checks.
+type cyclePinger struct {
+	client  ping.Client
+	log     *logger.Logger
+	checked map[monitorTarget]error // Targets checked in the current cycle
+}
+
+func newCyclePinger(client ping.Client, log *logger.Logger) *cyclePinger {
+	return &cyclePinger{client: client, log: log, checked: make(map[monitorTarget]error)}
+}
+
+// Observe records the check of a target and pings once the cycle of all
+// targets is complete
+func (p *cyclePinger) Observe(target monitorTarget, err error, targets int) {
+	p.checked[target] = err
+	if len(p.checked) < targets {
+		return
+	}
+
+	var failures []string
+	for target, err := range p.checked {
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", target.Label(), err))
+		}
+	}
+	clear(p.checked)
+	slices.Sort(failures)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if len(failures) == 0 {
+			if err := p.client.Success(ctx); err != nil {
+				p.log.Warnf("Failed to ping: %v", err)
+			}
+			return
+		}
+		if err := p.client.Fail(ctx, strings.Join(failures, "\n")); err != nil {
+			p.log.Warnf("Failed to ping the failure: %v", err)
+		}
+	}()
+}
+
+// pendingCatchUps tracks targets whose startup catch-up could not run, e.g.
+// because the network was still down. The first change of such a target is
+// held back until the check that found it is processed, so that it is
+// reported as a catch-up along with the outage rather than as a change
+// followed by a separate recovery notification.
+type pendingCatchUps struct {
+	mu      sync.Mutex
+	waiting map[monitorTarget]time.Time // When the last known IP was recorded
+	held    map[monitorTarget]config.IPChange
+}
+
+func newPendingCatchUps() *pendingCatchUps {
+	return &pendingCatchUps{
+		waiting: make(map[monitorTarget]time.Time),
+		held:    make(map[monitorTarget]config.IPChange),
+	}
+}
+
+// Add marks the target as waiting for its catch-up
+func (p *pendingCatchUps) Add(target monitorTarget, lastChange time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.waiting[target] = lastChange
+}
+
+// Hold keeps the change back if its target is waiting for its catch-up,
+// and reports whether it did
+func (p *pendingCatchUps) Hold(target monitorTarget, change config.IPChange) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lastChange, ok := p.waiting[target]
+	if !ok {
+		return false
+	}
+	change.Missed = true
+	change.LastChange = lastChange
+	p.held[target] = change
+	return true
+}
+
+// Release ends the wait of the target after a successful check and returns
+// the change held back for it, if any
+func (p *pendingCatchUps) Release(target monitorTarget) (config.IPChange, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	change, ok := p.held[target]
+	delete(p.waiting, target)
+	delete(p.held, target)
+	return change, ok
+}
+
+// startMonitors starts every monitor and merges their results into one channel
+func startMonitors(ctx context.Context, monitors map[monitorTarget]*ip.Monitor, interval time.Duration) <-chan targetResult {
+	resultChan := make(chan targetResult, len(monitors))
+
+	var wg sync.WaitGroup
+	for target, monitor := range monitors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range monitor.StartMonitoring(ctx, interval) {
+				resultChan <- targetResult{CheckResult: result, Target: target}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	return resultChan
+}
+
+// collectChanges gathers changes arriving within the merge window so that
+// several address families changing together produce a single notification.
+// Entries that cannot be merged (e.g., hook failures, or events left from
+// the last run for some channels) are returned after the merged entry, in
+// arrival order. Merged entries are replaced in the spool.
+func collectChanges(
+	entries <-chan notify.SpoolEntry,
+	first notify.SpoolEntry,
+	families int,
+	window time.Duration,
+	merge func(ids []int64, event notify.Event) notify.SpoolEntry,
+) []notify.SpoolEntry {
+	mergeable := func(entry notify.SpoolEntry) bool {
+		return entry.Event.IsChange() && len(entry.Channels) == 0
+	}
+	if !mergeable(first) {
+		return []notify.SpoolEntry{first}
+	}
+
+	var (
+		changes    []config.IPChange
+		catchUp    bool
+		gw         *config.GatewayContext
+		enrichment map[string]string
+		last       time.Time
+		ids        []int64
+		deferred   []notify.SpoolEntry
+	)
+	index := make(map[string]int)
+
+	add := func(entry notify.SpoolEntry) {
+		event := entry.Event
+		ids = append(ids, entry.ID)
+		catchUp = catchUp || event.Type == notify.TypeCatchUp
+		if event.Gateway != nil {
+			gw = event.Gateway
+		}
+		if event.Enrichment != nil {
+			enrichment = event.Enrichment
+		}
+		for _, change := range event.Changes {
+			if i, ok := index[change.Label()]; ok {
+				changes[i] = changes[i].Merge(change)
+				continue
+			}
+			index[change.Label()] = len(changes)
+			changes = append(changes, change)
+		}
+		last = event.Timestamp
+	}
+
+	merged := func() []notify.SpoolEntry {
+		if len(ids) == 1 {
+			return append([]notify.SpoolEntry{first}, deferred...)
+		}
+		event := notify.NewChangeEvent(changes, gw, last)
+		if catchUp {
+			event = notify.NewCatchUpEvent(changes, gw, last)
+		}
+		event.Site = first.Event.Site
+		event.Enrichment = enrichment
+		return append([]notify.SpoolEntry{merge(ids, event)}, deferred...)
+	}
+
+	add(first)
+	if families <= 1 {
+		return merged()
+	}
+
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+
+	for len(index) < families {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				return merged()
+			}
+			if !mergeable(entry) {
+				deferred = append(deferred, entry)
+				continue
+			}
+			add(entry)
+		case <-timer.C:
+			return merged()
+		}
+	}
+
+	return merged()
+}
+
+// runHooks runs the configured on-change commands in order and reports failures
+func runHooks(
+	runner *hooks.Runner,
+	cfg *config.Config,
+	family ip.Family,
+	oldIP, newIP string,
+	queueNotification func(notify.Event),
+	log *logger.Logger,
+) {
+	var failures []config.HookFailure
+
+	for _, hook := range cfg.Hooks.Commands {
+		user := hook.User
+		if user == "" {
+			user = cfg.Hooks.User
+		}
+
+		result := runner.Run(context.Background(), hooks.Command{
+			Name: hook.Name,
+			Path: hook.Command,
+			Args: hook.Args,
+			Env: []string{
+				"OLD_IP=" + oldIP,
+				"NEW_IP=" + newIP,
+				"IP_FAMILY=" + string(family),
+			},
+			Timeout: config.GetHookTimeout(cfg, hook),
+			User:    user,
+		})
+
+		if !result.Failed() {
+			log.Infof("Hook %s completed with exit status %d in %v", result.Name, result.ExitCode, result.Duration.Round(time.Millisecond))
+			continue
+		}
+
+		log.Errorf("Hook %s failed: %v", result.Name, result.Err)
+		if output := result.Output(); output != "" {
+			log.Errorf("Hook %s output:\n%s", result.Name, output)
+		}
+
+		failures = append(failures, config.HookFailure{
+			Name:     result.Name,
+			Error:    result.Err.Error(),
+			Output:   result.Output(),
+			Duration: result.Duration,
+		})
+	}
+
+	if len(failures) > 0 && cfg.Hooks.NotifyOnFailure {
+		queueNotification(notify.NewHookFailureEvent(failures, time.Now()))
+	}
+}
+
+// notificationWorker processes notifications asynchronously. Notifications
+// held for quiet hours or quotas are released at the times received from
+// release, and pending escalations checked at those from escalate.
+func notificationWorker(
+	spool *notify.Spool,
+	deadLetters *notify.DeadLetters,
+	families int,
+	notifiers []notify.Notifier,
+	quiet *quietQueue,
+	quotas *quotaPolicy,
+	release <-chan time.Time,
+	journal *eventJournal,
+	escalation *escalationPolicy,
+	escalate <-chan time.Time,
+	settings *runtimeSettings,
+	cfg *config.Config,
+	log *logger.Logger,
+) {
+	merge := func(ids []int64, event notify.Event) notify.SpoolEntry {
+		entry, err := spool.Replace(ids, event)
+		if err != nil {
+			log.Errorf("Failed to update notification spool: %v", err)
+		}
+		return entry
+	}
+	settle := func(id int64, channels []string) {
+		if err := spool.Settle(id, channels); err != nil {
+			log.Errorf("Failed to update notification spool: %v", err)
+		}
+	}
+	// Failed deliveries are kept for "notifications resend"
+	bury := func(event notify.Event, failed map[string]error) {
+		for channel, err := range failed {
+			id, addErr := deadLetters.Add(notify.DeadLetter{
+				Channel:  channel,
+				Event:    event,
+				Error:    err.Error(),
+				FailedAt: time.Now(),
+				Attempts: notifyAttempts,
+			})
+			if addErr != nil {
+				log.Errorf("Failed to save failed %s notification: %v", channel, addErr)
+				continue
+			}
+			log.Warnf("Saved failed %s notification as #%d, send it again with \"notifications resend\"", channel, id)
+		}
+	}
+
+	// Held entries are sent as a summary, and settled once it went out
+	sendHeld := func(i int, entries []notify.SpoolEntry, reason string, now time.Time) {
+		events := make([]notify.Event, len(entries))
+		for j, entry := range entries {
+			events[j] = entry.Event
+		}
+		summary := notify.Summarize(events, now)
+		name := notifiers[i].Name()
+		log.Infof("%s, sending %d notifications held for %s as %d", reason, len(events), name, len(summary))
+
+		pending := false
+		for _, event := range summary {
+			result := dispatchNotification(notify.SpoolEntry{Event: event}, notifiers[i:i+1], nil, nil, settings, log)
+			bury(event, result.Failed)
+			pending = pending || len(result.Pending) > 0
+		}
+		if pending {
+			log.Warnf("Notifications held for %s are kept for the next start", name)
+			return
+		}
+		for _, entry := range entries {
+			if err := spool.Delivered(entry.ID, name); err != nil {
+				log.Errorf("Failed to update notification spool: %v", err)
+			}
+		}
+	}
+
+	for {
+		select {
+		case first, ok := <-spool.Entries():
+			if !ok {
+				if held := quiet.Held(); held > 0 {
+					log.Infof("%d notifications held for quiet hours are kept for the next start", held)
+				}
+				if held := quotas.Held(); held > 0 {
+					log.Infof("%d notifications held for quotas are kept for the next start", held)
+				}
+				return
+			}
+			for _, entry := range collectChanges(spool.Entries(), first, families, config.GetFamilyMergeWindow(cfg), merge) {
+				result := dispatchNotification(entry, notifiers, quiet, quotas, settings, log)
+				bury(entry.Event, result.Failed)
+				settle(entry.ID, result.Pending)
+				// Entries for some channels only are retries or escalations
+				if len(entry.Channels) == 0 {
+					journal.Record(entry.Event, log)
+					escalation.Track(entry.Event, spool, log)
+				}
+			}
+		case now := <-escalate:
+			escalation.Escalate(now, spool, log)
+		case now := <-release:
+			for i, entries := range quiet.Release(now) {
+				sendHeld(i, entries, "Quiet hours ended", now)
+			}
+			for i, entries := range quotas.Release(now, notifiers) {
+				sendHeld(i, entries, notifiers[i].Name()+" quota reset", now)
+			}
+		}
+	}
+}
+
+// scheduleTicks registers a task handing the time it runs at to the loop
+// reading the returned channel, for work that must stay on that loop, such
+// as the notification worker's. A run lasts until the loop takes its time,
+// so runs due while the loop is busy are skipped.
+func scheduleTicks(taskScheduler *scheduler.Scheduler, name, spec string) (<-chan time.Time, error) {
+	ticks := make(chan time.Time)
+	err := taskScheduler.Add(name, spec, func(ctx context.Context) {
+		select {
+		case ticks <- appClock.Now():
+		case <-ctx.Done():
+		}
+	})
+	return ticks, err
+}
 
-			if result.Changed {
-				log.Infof("IP changed from %s to %s", result.LastIP, result.CurrentIP)
-			} else {
-				log.Infof("IP unchanged: %s", result.CurrentIP)
+// quietQueue holds the entries of each channel during quiet hours
+type quietQueue struct {
+	hours *notify.QuietHours
+	mu    sync.Mutex
+	held  map[int][]notify.SpoolEntry // By index of the notifier
+}
+
+func newQuietQueue(hours *notify.QuietHours) *quietQueue {
+	return &quietQueue{hours: hours, held: make(map[int][]notify.SpoolEntry)}
+}
+
+// Hold keeps the entry for the notifier at index i if quiet hours are on
+func (q *quietQueue) Hold(i int, notifier notify.Notifier, entry notify.SpoolEntry, now time.Time) bool {
+	if q == nil || !q.hours.Holds(notifier.Name(), now) {
+		return false
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.held[i] = append(q.held[i], entry)
+	return true
+}
+
+// Release returns and forgets the held entries once quiet hours are over
+func (q *quietQueue) Release(now time.Time) map[int][]notify.SpoolEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.held) == 0 || q.hours.Active(now) {
+		return nil
+	}
+	held := q.held
+	q.held = make(map[int][]notify.SpoolEntry)
+	return held
+}
+
+// Held returns the number of entries held
+func (q *quietQueue) Held() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	count := 0
+	for _, entries := range q.held {
+		count += len(entries)
+	}
+	return count
+}
+
+// replayOutboxes sends the webhook events left in the outboxes, giving
+// each endpoint up to timeout
+func replayOutboxes(ctx context.Context, outboxes []*notify.OutboxNotifier, timeout time.Duration) {
+	for _, outbox := range outboxes {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		outbox.Replay(ctx)
+		cancel()
+	}
+}
+
+// openWebhookOutbox wraps the notifier of a webhook URL in its outbox
+func openWebhookOutbox(notifier notify.Notifier, url string, cfg *config.Config, log *logger.Logger) (*notify.OutboxNotifier, error) {
+	maxAge := time.Duration(max(cfg.Webhook.Outbox.MaxAgeHours, 0)) * time.Hour
+	outbox, err := notify.OpenOutbox(filepath.Join(cfg.IP.DataDir, webhookOutboxFile(url)), maxAge)
+	if err != nil {
+		return nil, err
+	}
+	if pending := outbox.Len(); pending > 0 {
+		log.Infof("Replaying %d webhook events left for %s", pending, webhookLabel(url))
+	}
+	return notify.NewOutboxNotifier(notifier, outbox, webhookLabel(url), log.Infof, log.Warnf), nil
+}
+
+// webhookOutboxFile names the outbox of a webhook URL in ip.data_dir after
+// a hash of it, so that reordering the URLs keeps each one's events
+func webhookOutboxFile(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return fmt.Sprintf("webhook_outbox_%x.jsonl", sum[:4])
+}
+
+// webhookLabel names a webhook URL in log lines by its host, leaving out
+// credentials and tokens in the path or query
+func webhookLabel(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return "Webhook"
+	}
+	return "Webhook " + parsed.Host
+}
+
+// runtimeSettings are the settings the admin API changes while running
+type runtimeSettings struct {
+	mu       sync.Mutex
+	interval time.Duration
+	muted    []string
+	dryRun   bool
+}
+
+func newRuntimeSettings(cfg *config.Config) *runtimeSettings {
+	return &runtimeSettings{
+		interval: config.GetCheckInterval(cfg),
+		muted:    slices.Clone(cfg.MutedChannels),
+		dryRun:   cfg.DryRun,
+	}
+}
+
+// Settings returns the current settings
+func (s *runtimeSettings) Settings() api.Settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return api.Settings{CheckInterval: s.interval, MutedChannels: slices.Clone(s.muted), DryRun: s.dryRun}
+}
+
+// Muted reports whether notifications skip the channel
+func (s *runtimeSettings) Muted(channel string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.ContainsFunc(s.muted, func(name string) bool { return strings.EqualFold(name, channel) })
+}
+
+// DryRun reports whether notifications are logged instead of sent
+func (s *runtimeSettings) DryRun() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dryRun
+}
+
+// Apply checks an update against the enabled channels and applies it
+func (s *runtimeSettings) Apply(update api.SettingsUpdate, channels []string) (api.Settings, error) {
+	if update.CheckIntervalSeconds != nil && *update.CheckIntervalSeconds <= 0 {
+		return api.Settings{}, fmt.Errorf("%w: check_interval_seconds must be positive", api.ErrInvalidSettings)
+	}
+	if update.MutedChannels != nil {
+		for _, name := range *update.MutedChannels {
+			if !slices.ContainsFunc(channels, func(channel string) bool { return strings.EqualFold(channel, name) }) {
+				return api.Settings{}, fmt.Errorf("%w: unknown channel %q (enabled: %s)", api.ErrInvalidSettings, name, strings.Join(channels, ", "))
 			}
+		}
+	}
 
-		case sig := <-sigChan:
-			log.Infof("Received signal %v, shutting down gracefully...", sig)
-			cancel()
+	s.mu.Lock()
+	if update.CheckIntervalSeconds != nil {
+		s.interval = time.Duration(*update.CheckIntervalSeconds) * time.Second
+	}
+	if update.MutedChannels != nil {
+		s.muted = slices.Clone(*update.MutedChannels)
+	}
+	if update.DryRun != nil {
+		s.dryRun = *update.DryRun
+	}
+	s.mu.Unlock()
+	return s.Settings(), nil
+}
 
-			// Close notification channel and wait for worker to finish
-			close(notificationChan)
-			time.Sleep(2 * time.Second) // Give time for pending notifications
+// pauseState is what pauseFile holds
+type pauseState struct {
+	PausedAt time.Time `json:"paused_at"`
+	By       string    `json:"by"` // e.g. "SIGUSR1" or "admin API"
+}
 
-			log.Info("Shutdown complete")
-			return
+// pauseControl pauses and resumes the checks of every monitor, on a signal
+// or through the admin API. The monitor keeps running meanwhile: the API,
+// scheduled tasks and heartbeats go on, reporting that checking is paused.
+type pauseControl struct {
+	mu       sync.Mutex
+	path     string
+	pausedAt time.Time // Zero while checking
+	monitors map[monitorTarget]*ip.Monitor
+	state    *api.State
+	health   string
+	settings *runtimeSettings
+	log      *logger.Logger
+}
+
+// newPauseControl restores the state saved by the last run, pausing the
+// monitors if they were
+func newPauseControl(dataDir, healthPath string, monitors map[monitorTarget]*ip.Monitor, state *api.State, settings *runtimeSettings, log *logger.Logger) *pauseControl {
+	p := &pauseControl{
+		path:     filepath.Join(dataDir, pauseFile),
+		monitors: monitors,
+		state:    state,
+		health:   healthPath,
+		settings: settings,
+		log:      log,
+	}
+
+	data, err := os.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return p
+	}
+	var saved pauseState
+	if err == nil {
+		err = json.Unmarshal(data, &saved)
+	}
+	if err != nil || saved.PausedAt.IsZero() {
+		log.Warnf("Ignoring unreadable pause state %s: %v", p.path, err)
+		return p
+	}
+
+	p.pause(saved.PausedAt)
+	resume := "POST /admin/resume"
+	if resumeSignal != nil {
+		resume = signalName(resumeSignal) + " or " + resume
+	}
+	log.Warnf("Checks are paused since %s (by %s); resume them with %s",
+		saved.PausedAt.Local().Format("2006-01-02 15:04:05"), saved.By, resume)
+	return p
+}
+
+// signalName returns the conventional name of the pause and resume signals
+func signalName(sig os.Signal) string {
+	switch sig {
+	case pauseSignal:
+		return "SIGUSR1"
+	case resumeSignal:
+		return "SIGUSR2"
+	}
+	return sig.String()
+}
+
+// PausedAt returns when checking was paused, or a zero time while checking
+func (p *pauseControl) PausedAt() time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pausedAt
+}
+
+// Set pauses or resumes checking, saving the state for the next start.
+// Pausing paused checks keeps the time they were paused at.
+func (p *pauseControl) Set(paused bool, by string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if paused == !p.pausedAt.IsZero() {
+		return nil
+	}
+
+	if !paused {
+		if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear pause state: %w", err)
+		}
+		p.pausedAt = time.Time{}
+		p.state.SetPaused(time.Time{})
+		for _, monitor := range p.monitors {
+			monitor.Resume()
 		}
+		p.log.Infof("Checks resumed by %s", by)
+		return nil
+	}
+
+	saved := pauseState{PausedAt: time.Now(), By: by}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
 	}
+	if err := os.WriteFile(p.path, data, ip.DataFilePerm); err != nil {
+		return fmt.Errorf("failed to save pause state: %w", err)
+	}
+	p.pause(saved.PausedAt)
+	p.log.Infof("Checks paused by %s", by)
+	return nil
 }
 
-// notificationRequest represents a notification to be sent
-type notificationRequest struct {
-	OldIP     string
-	NewIP     string
-	Timestamp time.Time
+// pause stops the monitors and records the pause for the API and the
+// healthcheck, which would otherwise find the last check too old
+func (p *pauseControl) pause(at time.Time) {
+	p.pausedAt = at
+	p.state.SetPaused(at)
+	for _, monitor := range p.monitors {
+		monitor.Pause()
+	}
+	writeHealth(p.health, ip.CheckResult{}, p.settings.Settings().CheckInterval, at, p.log)
 }
 
-// notificationWorker processes notifications asynchronously
-func notificationWorker(
-	notificationChan <-chan notificationRequest,
-	emailClient email.Client,
-	whatsappClient whatsapp.Client,
+// adminOptions sets up the admin API, if it has a token: changes to the
+// check interval reach the running monitors, and persisted changes the
+// config file
+func adminOptions(
 	cfg *config.Config,
+	manager *config.Manager,
+	settings *runtimeSettings,
+	monitors map[monitorTarget]*ip.Monitor,
+	notifiers []notify.Notifier,
+	pruner *dataPruner,
+	pauses *pauseControl,
 	log *logger.Logger,
-) {
-	// Set GOMAXPROCS for better CPU utilization in containers
-	if runtime.GOMAXPROCS(0) == 1 {
-		runtime.GOMAXPROCS(2) // Minimum 2 for concurrent notifications
+) *api.Admin {
+	if cfg.API.AdminToken == "" {
+		return nil
+	}
+	var channels []string
+	for _, notifier := range notifiers {
+		if !slices.Contains(channels, notifier.Name()) {
+			channels = append(channels, notifier.Name())
+		}
 	}
 
-	for req := range notificationChan {
-		// Process notifications concurrently
-		var wg sync.WaitGroup
+	return &api.Admin{
+		Token:    cfg.API.AdminToken,
+		Settings: settings.Settings,
+		UpdateSettings: func(update api.SettingsUpdate) (api.Settings, error) {
+			before := settings.Settings()
+			after, err := settings.Apply(update, channels)
+			if err != nil {
+				return api.Settings{}, err
+			}
+			if after.CheckInterval != before.CheckInterval {
+				for _, monitor := range monitors {
+					monitor.SetInterval(after.CheckInterval)
+				}
+			}
+			log.Infof("Settings changed through the admin API: checking every %v, muted channels [%s], dry run %t",
+				after.CheckInterval, strings.Join(after.MutedChannels, ", "), after.DryRun)
 
-		// Send email notification (if enabled)
-		if cfg.Email.Enabled && emailClient != nil {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				sendEmailNotification(emailClient, cfg, req, log)
-			}()
+			if !update.Persist {
+				return after, nil
+			}
+			err = manager.Update(func(file *config.Config) {
+				if update.CheckIntervalSeconds != nil {
+					file.CheckIntervalSeconds = *update.CheckIntervalSeconds
+				}
+				if update.MutedChannels != nil {
+					file.MutedChannels = *update.MutedChannels
+				}
+				if update.DryRun != nil {
+					file.DryRun = *update.DryRun
+				}
+			})
+			if err != nil {
+				return after, fmt.Errorf("settings changed but not saved: %w", err)
+			}
+			log.Info("Saved the changed settings to the config file")
+			return after, nil
+		},
+		Prune: func(ctx context.Context) (map[string]int, error) {
+			pruned, err := pruner.Prune(ctx)
+			if err == nil {
+				logPruned(pruned, log)
+			}
+			return pruned, err
+		},
+		SetPaused: func(paused bool) error {
+			return pauses.Set(paused, "admin API")
+		},
+	}
+}
+
+// dataPruner drops data past its retention, on schedule or through the
+// admin API
+type dataPruner struct {
+	cfg         *config.Config
+	storage     *ip.Storage
+	deadLetters *notify.DeadLetters
+	journal     *eventJournal
+}
+
+// Prune drops the IP history and failed notifications past their
+// retention, and the acknowledgments of events no longer in the event
+// history. It returns how many entries it dropped, by data store.
+func (p *dataPruner) Prune(ctx context.Context) (map[string]int, error) {
+	if p == nil {
+		return nil, nil
+	}
+	pruned := map[string]int{"history": 0, "failed_notifications": 0, "acknowledgments": 0}
+	now := time.Now()
+
+	if days := p.cfg.Retention.HistoryDays; days > 0 {
+		before := now.AddDate(0, 0, -days)
+		storages := []*ip.Storage{p.storage}
+		for _, wan := range p.cfg.IP.WANs {
+			storages = append(storages, p.storage.ForWAN(wan.Name))
+		}
+		for _, storage := range storages {
+			removed, err := storage.PruneHistory(before)
+			if err != nil {
+				return pruned, err
+			}
+			pruned["history"] += removed
 		}
+	}
 
-		// Send WhatsApp notification (if enabled)
-		if cfg.WhatsApp.Enabled && whatsappClient != nil {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				sendWhatsAppNotification(whatsappClient, cfg, req, log)
-			}()
+	if days := p.cfg.Retention.FailedNotificationsDays; days > 0 {
+		before := now.AddDate(0, 0, -days)
+		letters, err := p.deadLetters.List()
+		if err != nil {
+			return pruned, err
+		}
+		var removed []int64
+		for _, letter := range letters {
+			if letter.FailedAt.Before(before) {
+				removed = append(removed, letter.ID)
+			}
+		}
+		if len(removed) > 0 {
+			if err := p.deadLetters.Update(nil, removed); err != nil {
+				return pruned, err
+			}
+			pruned["failed_notifications"] = len(removed)
 		}
+	}
 
-		// Wait for all notifications to complete (with timeout)
-		done := make(chan struct{})
-		go func() {
-			wg.Wait()
-			close(done)
-		}()
+	pending := p.journal.escalations.Pending()
+	removed, err := p.journal.acks.Prune(func(ack notify.Ack) bool {
+		if _, found := p.journal.history.Find(ack.EventID); found {
+			return true
+		}
+		return slices.ContainsFunc(pending, func(escalation notify.Escalation) bool { return escalation.Event.ID == ack.EventID })
+	})
+	if err != nil {
+		return pruned, err
+	}
+	pruned["acknowledgments"] = removed
+	return pruned, ctx.Err()
+}
 
-		select {
-		case <-done:
-			// All notifications completed
-		case <-time.After(30 * time.Second):
-			// Timeout waiting for notifications
-			log.Warn("Notification timeout - some notifications may not have completed")
+// logPruned reports what pruning dropped
+func logPruned(pruned map[string]int, log *logger.Logger) {
+	var parts []string
+	for _, store := range []struct{ name, label string }{
+		{"history", "history records"},
+		{"failed_notifications", "failed notifications"},
+		{"acknowledgments", "acknowledgments"},
+	} {
+		if pruned[store.name] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", pruned[store.name], store.label))
 		}
 	}
+	if len(parts) == 0 {
+		log.Debug("Pruning found nothing past its retention")
+		return
+	}
+	log.Infof("Pruned %s", strings.Join(parts, ", "))
 }
 
-// sendEmailNotification sends email notification with retry logic
-func sendEmailNotification(
-	client email.Client,
-	cfg *config.Config,
-	req notificationRequest,
-	log *logger.Logger,
-) {
-	emailSubject := config.BuildEmailSubject()
-	emailBody := config.BuildEmailBody(req.OldIP, req.NewIP, req.Timestamp)
+// escalationPolicy sends the events nobody acknowledged in time to the
+// secondary channels
+type escalationPolicy struct {
+	escalations *notify.Escalations
+	channels    []string // Names of the secondary notifiers
+	after       time.Duration
+	events      []string // Event types escalated; empty escalates all
+}
 
-	// Retry logic with exponential backoff
-	maxRetries := 3
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// newEscalationPolicy opens the pending escalations and turns the
+// configured channels into secondary ones
+func newEscalationPolicy(cfg *config.Config, notifiers []notify.Notifier, journal *eventJournal, log *logger.Logger) (*escalationPolicy, error) {
+	escalations := journal.escalations
+	policy := &escalationPolicy{
+		escalations: escalations,
+		after:       time.Duration(cfg.Escalation.AfterMinutes) * time.Minute,
+		events:      cfg.Escalation.Events,
+	}
 
-		emailMsg := email.Message{
-			To:      cfg.Email.To,
-			Subject: emailSubject,
-			Body:    emailBody,
+	for _, name := range cfg.Escalation.Channels {
+		found := false
+		for i, notifier := range notifiers {
+			if strings.EqualFold(notifier.Name(), name) {
+				notifiers[i] = notify.Secondary(notifier)
+				policy.channels = append(policy.channels, notifier.Name())
+				found = true
+			}
+		}
+		if !found {
+			log.Warnf("Escalation channel %q is not enabled, ignoring it", name)
 		}
+	}
+	if len(policy.channels) == 0 {
+		return nil, fmt.Errorf("none of the escalation channels is enabled")
+	}
 
-		if err := client.Send(ctx, emailMsg); err != nil {
-			cancel()
-			if attempt == maxRetries {
-				log.Errorf("Failed to send email notification after %d attempts: %v", maxRetries, err)
-				return
+	log.Infof("Escalation enabled: %s after %v without acknowledgment", strings.Join(policy.channels, ", "), policy.after)
+	if pending := len(escalations.Pending()); pending > 0 {
+		log.Infof("%d events from the last run are waiting to be acknowledged", pending)
+	}
+	return policy, nil
+}
+
+// Escalates reports whether the event goes to the secondary channels when
+// nobody acknowledges it. Recoveries are only escalated after their failure.
+func (p *escalationPolicy) Escalates(event notify.Event) bool {
+	if event.Escalated || event.Type == notify.TypeFetchRecovered || event.IsLifecycle() {
+		return false
+	}
+	return len(p.events) == 0 || slices.Contains(p.events, string(event.Type))
+}
+
+// Track starts the acknowledgment period of an event the primary channels
+// got. A recovery cancels the escalation of its failure, or is escalated
+// itself if the failure was.
+func (p *escalationPolicy) Track(event notify.Event, spool *notify.Spool, log *logger.Logger) {
+	if p == nil {
+		return
+	}
+	if event.Type == notify.TypeFetchRecovered {
+		dropped, escalated, err := p.escalations.Recovered()
+		if err != nil {
+			log.Errorf("Failed to update escalations: %v", err)
+			return
+		}
+		if dropped > 0 {
+			log.Infof("Checks recovered, cancelled %d pending escalations", dropped)
+		}
+		if escalated {
+			p.send(event, spool, log)
+		}
+		return
+	}
+	if !p.Escalates(event) {
+		return
+	}
+	if err := p.escalations.Add(event, time.Now().Add(p.after)); err != nil {
+		log.Errorf("Failed to record escalation%s: %v", eventRef(event), err)
+		return
+	}
+	log.Debugf("Escalating %s to %s unless acknowledged within %v%s", event.Type, strings.Join(p.channels, ", "), p.after, eventRef(event))
+}
+
+// Escalate sends the events due at now that nobody acknowledged
+func (p *escalationPolicy) Escalate(now time.Time, spool *notify.Spool, log *logger.Logger) {
+	escalate, acked, err := p.escalations.Due(now)
+	if err != nil {
+		log.Errorf("Failed to update escalations: %v", err)
+		return
+	}
+	for _, escalation := range acked {
+		log.Debugf("Event %s was acknowledged, not escalating it", escalation.Event.ID)
+	}
+	for _, escalation := range escalate {
+		log.Warnf("Event %s was not acknowledged within %v, escalating it to %s", escalation.Event.ID, p.after, strings.Join(p.channels, ", "))
+		p.send(escalation.Event, spool, log)
+	}
+}
+
+// send queues the event for the secondary channels
+func (p *escalationPolicy) send(event notify.Event, spool *notify.Spool, log *logger.Logger) {
+	event.Escalated = true
+	if err := spool.PushTo(event, p.channels); err != nil {
+		log.Errorf("Failed to queue escalation%s: %v", eventRef(event), err)
+	}
+}
+
+// eventHistorySize is how many recent events can be acknowledged
+const eventHistorySize = 500
+
+// eventJournal keeps the events notified and who acknowledged them, for
+// the monitor, the ack command and the API alike
+type eventJournal struct {
+	history     *notify.EventHistory
+	acks        *notify.Acks
+	escalations *notify.Escalations
+}
+
+func openEventJournal(dataDir string) (*eventJournal, error) {
+	history, err := notify.OpenEventHistory(filepath.Join(dataDir, eventHistoryFile), eventHistorySize)
+	if err != nil {
+		return nil, err
+	}
+	acks := notify.OpenAcks(filepath.Join(dataDir, ackFile))
+	escalations, err := notify.OpenEscalations(filepath.Join(dataDir, escalationFile), acks)
+	if err != nil {
+		return nil, err
+	}
+	return &eventJournal{history: history, acks: acks, escalations: escalations}, nil
+}
+
+// Record adds an event the channels were notified of to the history
+func (j *eventJournal) Record(event notify.Event, log *logger.Logger) {
+	if err := j.history.Add(event); err != nil {
+		log.Errorf("Failed to record event%s: %v", eventRef(event), err)
+	}
+}
+
+// Acknowledge records that by took care of the event and reports whether
+// its escalation was still pending. An event acknowledged before keeps
+// its first acknowledgment.
+func (j *eventJournal) Acknowledge(eventID, by string) (api.EventRecord, bool, error) {
+	pending := slices.ContainsFunc(j.escalations.Pending(), func(escalation notify.Escalation) bool {
+		return escalation.Event.ID == eventID
+	})
+	event, found := j.history.Find(eventID)
+	if !found && !pending {
+		return api.EventRecord{}, false, fmt.Errorf("%w %q (not among the last %d events)", api.ErrUnknownEvent, eventID, eventHistorySize)
+	}
+
+	ack, acked, err := j.acks.Find(eventID)
+	if err != nil {
+		return api.EventRecord{}, false, err
+	}
+	if !acked {
+		ack = notify.Ack{EventID: eventID, AckedAt: time.Now(), By: by}
+		if err := j.acks.Add(ack); err != nil {
+			return api.EventRecord{}, false, err
+		}
+	}
+	if !found {
+		for _, escalation := range j.escalations.Pending() {
+			if escalation.Event.ID == eventID {
+				event = escalation.Event
 			}
+		}
+	}
+	return eventRecord(event, ack), pending && !acked, nil
+}
 
-			// Exponential backoff: 1s, 2s, 4s
-			backoff := time.Duration(1<<(attempt-1)) * time.Second
-			log.Warnf("Email notification attempt %d failed, retrying in %v: %v", attempt, backoff, err)
-			time.Sleep(backoff)
+// Records returns the recent events and their acknowledgments, oldest
+// first
+func (j *eventJournal) Records() ([]api.EventRecord, error) {
+	acks, err := j.acks.List()
+	if err != nil {
+		return nil, err
+	}
+	byEvent := make(map[string]notify.Ack, len(acks))
+	for _, ack := range acks {
+		if _, ok := byEvent[ack.EventID]; !ok {
+			byEvent[ack.EventID] = ack
+		}
+	}
+
+	events := j.history.List()
+	records := make([]api.EventRecord, 0, len(events))
+	for _, event := range events {
+		records = append(records, eventRecord(event, byEvent[event.ID]))
+	}
+	return records, nil
+}
+
+// eventRecord describes an event and its acknowledgment, if any, for the
+// API and the events command
+func eventRecord(event notify.Event, ack notify.Ack) api.EventRecord {
+	return api.EventRecord{
+		ID:        event.ID,
+		Type:      string(event.Type),
+		Severity:  string(event.Severity),
+		Timestamp: event.Timestamp,
+		Summary:   describeEvent(event),
+		AckedBy:   ack.By,
+		AckedAt:   ack.AckedAt,
+	}
+}
+
+// dispatchResult is what became of an entry on the channels it had to reach
+type dispatchResult struct {
+	Pending []string         // Channels holding it for quiet hours or their quota
+	Failed  map[string]error // Channels that failed after all attempts
+}
+
+// dispatchTimeout bounds the attempts of all channels at sending an entry,
+// backoff included; a channel not done by then has failed
+const dispatchTimeout = 30 * time.Second
+
+// dispatchNotification sends an entry through all enabled channels it has
+// to reach concurrently, except those holding it for quiet hours. Muted
+// channels and dry runs count as delivered. Every channel sending it has
+// either delivered or failed on return, so the entry is never sent again
+// by a send still running.
+func dispatchNotification(entry notify.SpoolEntry, notifiers []notify.Notifier, quiet *quietQueue, quotas *quotaPolicy, settings *runtimeSettings, log *logger.Logger) dispatchResult {
+	ctx, cancel := context.WithTimeout(context.Background(), dispatchTimeout)
+	defer cancel()
+
+	// Process notifications concurrently
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var pending, dispatched []string
+	var rerouted []notify.Notifier
+	errs := make(map[string]error)
+	send := func(notifier notify.Notifier) {
+		dispatched = append(dispatched, notifier.Name())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := sendWithRetry(ctx, notifier.Name(), entry.Event, log, func(ctx context.Context) error {
+				return notifier.Notify(ctx, entry.Event)
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			errs[notifier.Name()] = err
+		}()
+	}
+
+	now := time.Now()
+	for i, notifier := range notifiers {
+		if !notify.Accepts(notifier, entry.Event) {
+			continue
+		}
+		if len(entry.Channels) > 0 && !slices.Contains(entry.Channels, notifier.Name()) {
+			continue
+		}
+		if settings.Muted(notifier.Name()) {
+			log.Debugf("%s is muted, not sending the notification%s", notifier.Name(), eventRef(entry.Event))
+			continue
+		}
+		if settings.DryRun() {
+			log.Infof("Dry run, not sending %s notification%s: %s", notifier.Name(), eventRef(entry.Event), describeEvent(entry.Event))
+			continue
+		}
+		if quiet.Hold(i, notifier, entry, now) {
+			log.Infof("Holding %s notification until quiet hours end%s", notifier.Name(), eventRef(entry.Event))
+			pending = append(pending, notifier.Name())
+			continue
+		}
+		if !quotas.Allows(notifier.Name(), entry.Event, now) {
+			if target := quotas.Reroute(notifier.Name(), entry.Event, notifiers, settings, now); target != nil {
+				log.Infof("Sending %s notification through %s instead, it is short of its daily quota%s", notifier.Name(), target.Name(), eventRef(entry.Event))
+				rerouted = append(rerouted, target)
+				continue
+			}
+			log.Infof("Holding %s notification until its quota resets%s", notifier.Name(), eventRef(entry.Event))
+			quotas.Hold(i, entry)
+			pending = append(pending, notifier.Name())
 			continue
 		}
 
-		cancel()
-		log.Info("Email notification sent successfully")
-		return
+		send(notifier)
+	}
+
+	// Channels taking over for others send the event once, even when they
+	// filter it out themselves
+	for _, target := range rerouted {
+		if !slices.Contains(dispatched, target.Name()) && !slices.Contains(pending, target.Name()) {
+			send(target)
+		}
+	}
+
+	// The sends give up at the deadline, so this wait is bounded too
+	wg.Wait()
+
+	result := dispatchResult{Pending: pending, Failed: make(map[string]error)}
+	for _, name := range dispatched {
+		if err := errs[name]; err != nil {
+			result.Failed[name] = err
+		}
 	}
+	return result
 }
 
-// sendWhatsAppNotification sends WhatsApp notification with retry logic
-func sendWhatsAppNotification(
-	client whatsapp.Client,
-	cfg *config.Config,
-	req notificationRequest,
-	log *logger.Logger,
-) {
-	whatsappMessage := config.BuildWhatsAppMessage(req.OldIP, req.NewIP, req.Timestamp)
+// notifyAttempts is how often a notification is tried on each channel
+const notifyAttempts = 3
+
+// eventRef identifies the event in log lines, e.g. " (event 3f9a1c07b2e4)",
+// to trace its delivery across channels
+func eventRef(event notify.Event) string {
+	if event.ID == "" {
+		return ""
+	}
+	return " (event " + event.ID + ")"
+}
 
-	// Retry logic with exponential backoff
-	maxRetries := 3
+// sendWithRetry calls send with exponential backoff until it succeeds, the
+// attempts are exhausted or ctx is done, returning the last error
+func sendWithRetry(ctx context.Context, channel string, event notify.Event, log *logger.Logger, send func(ctx context.Context) error) error {
+	ref := eventRef(event)
+	maxRetries := notifyAttempts
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		attemptCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err := send(attemptCtx)
+		cancel()
 
-		whatsappMsg := whatsapp.Message{
-			To:   cfg.WhatsApp.RecipientNumber,
-			Text: whatsappMessage,
+		if errors.Is(err, notify.ErrCircuitOpen) {
+			log.Warnf("%s notification%s %v", channel, ref, err)
+			return err
 		}
-
-		if err := client.Send(ctx, whatsappMsg); err != nil {
-			cancel()
+		if err != nil {
 			if attempt == maxRetries {
-				log.Errorf("Failed to send WhatsApp notification after %d attempts: %v", maxRetries, err)
-				return
+				log.Errorf("Failed to send %s notification after %d attempts%s: %v", channel, maxRetries, ref, err)
+				return err
 			}
 
 			// Exponential backoff: 1s, 2s, 4s
 			backoff := time.Duration(1<<(attempt-1)) * time.Second
-			log.Warnf("WhatsApp notification attempt %d failed, retrying in %v: %v", attempt, backoff, err)
-			time.Sleep(backoff)
+			log.Warnf("%s notification attempt %d failed, retrying in %v%s: %v", channel, attempt, backoff, ref, err)
+			if clock.Sleep(ctx, appClock, backoff) != nil {
+				log.Errorf("Failed to send %s notification, out of time after %d attempts%s: %v", channel, attempt, ref, err)
+				return err
+			}
 			continue
 		}
 
-		cancel()
-		log.Info("WhatsApp notification sent successfully")
-		return
+		log.Infof("%s notification sent successfully%s", channel, ref)
+		return nil
 	}
+	return nil
 }
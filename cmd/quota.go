@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/internal/logger"
+	"public-ip-monitor/internal/notify"
+)
+
+// quotaFile counts today's sends of the channels with a daily quota, in
+// the data directory
+const quotaFile = "notification_quota.json"
+
+// quotaPolicy keeps channels within their daily quota: a channel about to
+// reach it sends only IP changes and warnings, and what it cannot send goes
+// through its reroute channel or is held until the quota resets
+type quotaPolicy struct {
+	quotas  *notify.Quotas
+	reroute map[string]string // Channel taking over, by channel name in lower case
+	log     *logger.Logger
+
+	mu       sync.Mutex
+	held     map[int][]notify.SpoolEntry  // By index of the notifier
+	reported map[string]notify.QuotaState // Last state logged, by channel name in lower case
+}
+
+// newQuotaPolicy opens the counts of today; nil without quotas
+func newQuotaPolicy(cfg *config.Config, log *logger.Logger) (*quotaPolicy, error) {
+	if len(cfg.Quotas.Channels) == 0 {
+		return nil, nil
+	}
+	quotas, err := notify.OpenQuotas(filepath.Join(cfg.IP.DataDir, quotaFile), cfg.Quotas.Channels, cfg.Quotas.ReservePercent, log.Location())
+	if err != nil {
+		return nil, err
+	}
+	policy := &quotaPolicy{
+		quotas:   quotas,
+		reroute:  make(map[string]string),
+		log:      log,
+		held:     make(map[int][]notify.SpoolEntry),
+		reported: make(map[string]notify.QuotaState),
+	}
+	for channel, target := range cfg.Quotas.Reroute {
+		policy.reroute[strings.ToLower(channel)] = target
+	}
+	return policy, nil
+}
+
+// Quotas returns the counts, or nil without quotas
+func (p *quotaPolicy) Quotas() *notify.Quotas {
+	if p == nil {
+		return nil
+	}
+	return p.quotas
+}
+
+// Usage returns today's sends of every channel with a quota
+func (p *quotaPolicy) Usage() []config.QuotaUsage {
+	return p.Quotas().Usage(time.Now())
+}
+
+// Allows reports whether the channel may send the event at now, logging
+// when its quota runs low, runs out or resets
+func (p *quotaPolicy) Allows(channel string, event notify.Event, now time.Time) bool {
+	if p == nil {
+		return true
+	}
+	p.report(channel, p.quotas.State(channel, now), now)
+	return p.quotas.Allows(channel, event, now)
+}
+
+func (p *quotaPolicy) report(channel string, state notify.QuotaState, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := strings.ToLower(channel)
+	if p.reported[key] == state {
+		return
+	}
+	p.reported[key] = state
+
+	resets := p.quotas.ResetsAt(now).Format("2006-01-02 15:04")
+	switch state {
+	case notify.QuotaLow:
+		p.log.Warnf("%s quota nearly used up (%s), sending only IP changes and warnings through it until %s", channel, p.describe(channel, now), resets)
+	case notify.QuotaExhausted:
+		p.log.Warnf("%s quota used up (%s), sending nothing through it until %s", channel, p.describe(channel, now), resets)
+	default:
+		p.log.Infof("%s quota reset", channel)
+	}
+}
+
+// describe returns the channel's usage, e.g. "230 of 250 today"
+func (p *quotaPolicy) describe(channel string, now time.Time) string {
+	for _, usage := range p.quotas.Usage(now) {
+		if strings.EqualFold(usage.Channel, channel) {
+			return fmt.Sprintf("%d of %d today", usage.Used, usage.Limit)
+		}
+	}
+	return ""
+}
+
+// Reroute returns the channel taking over an event the named channel
+// cannot send, or nil when it has none or that one cannot send it either
+func (p *quotaPolicy) Reroute(channel string, event notify.Event, notifiers []notify.Notifier, settings *runtimeSettings, now time.Time) notify.Notifier {
+	if p == nil {
+		return nil
+	}
+	target, ok := p.reroute[strings.ToLower(channel)]
+	if !ok {
+		return nil
+	}
+	for _, notifier := range notifiers {
+		if strings.EqualFold(notifier.Name(), target) {
+			if settings.Muted(notifier.Name()) || !p.Allows(notifier.Name(), event, now) {
+				return nil
+			}
+			return notifier
+		}
+	}
+	return nil
+}
+
+// Hold keeps the entry for the notifier at index i until its quota resets
+func (p *quotaPolicy) Hold(i int, entry notify.SpoolEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.held[i] = append(p.held[i], entry)
+}
+
+// Release returns and forgets the entries held for notifiers whose quota
+// reset
+func (p *quotaPolicy) Release(now time.Time, notifiers []notify.Notifier) map[int][]notify.SpoolEntry {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	released := make(map[int][]notify.SpoolEntry)
+	for i, entries := range p.held {
+		if p.quotas.State(notifiers[i].Name(), now) == notify.QuotaOK {
+			released[i] = entries
+			delete(p.held, i)
+		}
+	}
+	return released
+}
+
+// Held returns the number of entries held
+func (p *quotaPolicy) Held() int {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	count := 0
+	for _, entries := range p.held {
+		count += len(entries)
+	}
+	return count
+}
+
+// runQuotaCommand prints today's sends of the channels with a quota, for
+// "notifications quota"
+func runQuotaCommand(cfg *config.Config, location *time.Location) error {
+	if len(cfg.Quotas.Channels) == 0 {
+		fmt.Println("No channel has a quota (quotas.channels)")
+		return nil
+	}
+	quotas, err := notify.OpenQuotas(filepath.Join(cfg.IP.DataDir, quotaFile), cfg.Quotas.Channels, cfg.Quotas.ReservePercent, location)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANNEL\tSENT TODAY\tLIMIT\tSTATE\tREROUTE")
+	for _, usage := range quotas.Usage(now) {
+		state := "ok"
+		switch quotas.State(usage.Channel, now) {
+		case notify.QuotaLow:
+			state = "low, important only"
+		case notify.QuotaExhausted:
+			state = "used up"
+		}
+		reroute := "-"
+		for channel, target := range cfg.Quotas.Reroute {
+			if strings.EqualFold(channel, usage.Channel) {
+				reroute = target
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", usage.Channel, usage.Used, usage.Limit, state, reroute)
+	}
+	w.Flush()
+	fmt.Printf("Quotas reset at %s\n", quotas.ResetsAt(now).Format("2006-01-02 15:04"))
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/internal/logger"
+	"public-ip-monitor/internal/notify"
+	"public-ip-monitor/pkg/clock"
+)
+
+// useFakeClock replaces the clock of the retries for the test
+func useFakeClock(t *testing.T) *clock.Fake {
+	t.Helper()
+	fake := clock.NewFake(time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC))
+	previous := appClock
+	appClock = fake
+	t.Cleanup(func() { appClock = previous })
+	return fake
+}
+
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+	log, err := logger.New(config.LoggingConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return log
+}
+
+func TestSendWithRetryBacksOffOnFakeClock(t *testing.T) {
+	fake := useFakeClock(t)
+	log := newTestLogger(t)
+
+	var attempts []time.Time
+	result := make(chan error, 1)
+	go func() {
+		result <- sendWithRetry(context.Background(), "Test", notify.Event{ID: "3f9a1c07b2e4"}, log, func(context.Context) error {
+			attempts = append(attempts, fake.Now())
+			if len(attempts) < notifyAttempts {
+				return errors.New("service unavailable")
+			}
+			return nil
+		})
+	}()
+
+	// Backoff of 1s after the first attempt, 2s after the second
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second} {
+		fake.BlockUntil(1)
+		fake.Advance(backoff)
+	}
+	if err := <-result; err != nil {
+		t.Fatalf("sendWithRetry = %v, want success on the last attempt", err)
+	}
+
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	want := []time.Time{start, start.Add(time.Second), start.Add(3 * time.Second)}
+	if len(attempts) != len(want) {
+		t.Fatalf("%d attempts, want %d", len(attempts), len(want))
+	}
+	for i := range want {
+		if !attempts[i].Equal(want[i]) {
+			t.Errorf("attempt %d at %s, want %s", i+1, attempts[i].Format(time.TimeOnly), want[i].Format(time.TimeOnly))
+		}
+	}
+}
+
+func TestSendWithRetryReturnsLastError(t *testing.T) {
+	fake := useFakeClock(t)
+	log := newTestLogger(t)
+
+	failure := errors.New("service unavailable")
+	attempts := 0
+	result := make(chan error, 1)
+	go func() {
+		result <- sendWithRetry(context.Background(), "Test", notify.Event{}, log, func(context.Context) error {
+			attempts++
+			return failure
+		})
+	}()
+	for range notifyAttempts - 1 {
+		fake.BlockUntil(1)
+		fake.Advance(time.Minute)
+	}
+
+	if err := <-result; !errors.Is(err, failure) {
+		t.Fatalf("sendWithRetry = %v, want %v", err, failure)
+	}
+	if attempts != notifyAttempts {
+		t.Errorf("%d attempts, want %d", attempts, notifyAttempts)
+	}
+}
+
+// TestSendWithRetryStopsAtDeadline checks that a send gives up when its
+// context ends during the backoff, so that no retry outlives the dispatch
+func TestSendWithRetryStopsAtDeadline(t *testing.T) {
+	fake := useFakeClock(t)
+	log := newTestLogger(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	result := make(chan error, 1)
+	go func() {
+		result <- sendWithRetry(ctx, "Test", notify.Event{}, log, func(context.Context) error {
+			attempts++
+			return errors.New("service unavailable")
+		})
+	}()
+
+	// Waiting out the first backoff
+	fake.BlockUntil(1)
+	cancel()
+
+	if err := <-result; err == nil {
+		t.Fatal("sendWithRetry succeeded after its context ended")
+	}
+	if attempts != 1 {
+		t.Errorf("%d attempts, want 1", attempts)
+	}
+}
+
+func TestSendWithRetryStopsAtOpenCircuit(t *testing.T) {
+	useFakeClock(t)
+	log := newTestLogger(t)
+
+	attempts := 0
+	err := sendWithRetry(context.Background(), "Test", notify.Event{}, log, func(context.Context) error {
+		attempts++
+		return notify.ErrCircuitOpen
+	})
+	if !errors.Is(err, notify.ErrCircuitOpen) || attempts != 1 {
+		t.Errorf("sendWithRetry = %v after %d attempts, want %v after 1", err, attempts, notify.ErrCircuitOpen)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/internal/notify"
+	"public-ip-monitor/internal/scheduler"
+)
+
+// runTicks runs a scheduler on the fake application clock with a task
+// registered by scheduleTicks, until the test ends
+func runTicks(t *testing.T, spec string) <-chan time.Time {
+	t.Helper()
+	taskScheduler := scheduler.New(time.UTC)
+	taskScheduler.SetClock(appClock)
+	ticks, err := scheduleTicks(taskScheduler, "test", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		taskScheduler.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return ticks
+}
+
+// TestScheduleTicksOnFakeClock checks that a loop reading scheduled ticks,
+// like the notification worker, gets them at the times of the schedule on
+// the application clock
+func TestScheduleTicksOnFakeClock(t *testing.T) {
+	fake := useFakeClock(t)
+	start := fake.Now()
+	ticks := runTicks(t, "@every 15s")
+
+	for i := 1; i <= 3; i++ {
+		fake.BlockUntil(1)
+		fake.Advance(15 * time.Second)
+		want := start.Add(time.Duration(i) * 15 * time.Second)
+		if got := <-ticks; !got.Equal(want) {
+			t.Errorf("tick %d at %s, want %s", i, got.Format(time.TimeOnly), want.Format(time.TimeOnly))
+		}
+	}
+}
+
+// namedNotifier is a notifier that only has a name, for holding entries
+type namedNotifier string
+
+func (n namedNotifier) Name() string {
+	return string(n)
+}
+
+func (n namedNotifier) Notify(ctx context.Context, event notify.Event) error {
+	return nil
+}
+
+// TestQuietHoursReleaseOnFakeClock checks that notifications held during
+// quiet hours are released by the first scheduled release after they end
+func TestQuietHoursReleaseOnFakeClock(t *testing.T) {
+	fake := useFakeClock(t)
+	fake.Set(time.Date(2026, 1, 5, 6, 57, 30, 0, time.UTC))
+	hours, err := notify.NewQuietHours("23:00", "07:00", time.UTC, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	quiet := newQuietQueue(hours)
+	if !quiet.Hold(0, namedNotifier("Email"), notify.SpoolEntry{ID: 1}, fake.Now()) {
+		t.Fatal("entry not held during quiet hours")
+	}
+	ticks := runTicks(t, "@every 1m")
+
+	// Releases run every minute from 06:57:30; the first after 07:00
+	// sends the held entry
+	for _, want := range []string{"06:58:30", "06:59:30", "07:00:30"} {
+		fake.BlockUntil(1)
+		fake.Advance(time.Minute)
+		now := <-ticks
+		if at := now.Format(time.TimeOnly); at != want {
+			t.Fatalf("release at %s, want %s", at, want)
+		}
+		released := quiet.Release(now)
+		if now.Hour() < 7 {
+			if len(released) > 0 {
+				t.Errorf("released %v at %s, during quiet hours", released, want)
+			}
+			continue
+		}
+		if len(released[0]) != 1 || released[0][0].ID != 1 {
+			t.Errorf("released %v at %s, want entry 1", released, want)
+		}
+	}
+}
+
+// endpointNotifier stands in for a webhook endpoint that is down until up
+// is set, reporting when events arrive
+type endpointNotifier struct {
+	up        atomic.Bool
+	delivered chan time.Time
+}
+
+func (n *endpointNotifier) Name() string {
+	return "Webhook"
+}
+
+func (n *endpointNotifier) Notify(ctx context.Context, event notify.Event) error {
+	if !n.up.Load() {
+		return errors.New("connection refused")
+	}
+	n.delivered <- appClock.Now()
+	return nil
+}
+
+// TestWebhookOutboxReplayOnFakeClock checks that an event queued while the
+// endpoint was down is sent by the webhook_outbox task, on its default
+// schedule of every replay_seconds
+func TestWebhookOutboxReplayOnFakeClock(t *testing.T) {
+	fake := useFakeClock(t)
+	start := fake.Now()
+	log := newTestLogger(t)
+
+	var cfg config.Config
+	cfg.Webhook.Outbox.ReplaySeconds = 60
+	outbox, err := notify.OpenOutbox(filepath.Join(t.TempDir(), "webhook_outbox.jsonl"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoint := &endpointNotifier{delivered: make(chan time.Time, 1)}
+	outboxes := []*notify.OutboxNotifier{notify.NewOutboxNotifier(endpoint, outbox, "webhook", log.Infof, log.Warnf)}
+	if err := outboxes[0].Notify(context.Background(), notify.Event{ID: "3f9a1c07b2e4"}); err != nil {
+		t.Fatal(err)
+	}
+
+	taskScheduler := scheduler.New(time.UTC)
+	taskScheduler.SetClock(appClock)
+	err = taskScheduler.Add(config.ScheduleWebhookOutbox, config.GetSchedule(&cfg, config.ScheduleWebhookOutbox), func(ctx context.Context) {
+		replayOutboxes(ctx, outboxes, time.Minute)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		taskScheduler.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	endpoint.up.Store(true)
+	fake.BlockUntil(1)
+	fake.Advance(59 * time.Second)
+	select {
+	case at := <-endpoint.delivered:
+		t.Fatalf("replayed at %s, before replay_seconds", at.Format(time.TimeOnly))
+	case <-time.After(20 * time.Millisecond):
+	}
+	fake.Advance(time.Second)
+	if at := <-endpoint.delivered; !at.Equal(start.Add(time.Minute)) {
+		t.Errorf("replayed at %s, want %s", at.Format(time.TimeOnly), start.Add(time.Minute).Format(time.TimeOnly))
+	}
+	if outbox.Len() != 0 {
+		t.Errorf("%d events left in the outbox, want 0", outbox.Len())
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// Other platforms have no user signals: checking is paused and resumed
+// through the admin API only
+var (
+	pauseSignal  os.Signal
+	resumeSignal os.Signal
+)
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"slices"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/internal/logger"
+	"public-ip-monitor/internal/notify"
+	"public-ip-monitor/internal/testserver"
+)
+
+// simulatedChannels are the channels delivered to the stub servers; the
+// others are left out of a simulation
+var simulatedChannels = []string{"Email", "WhatsApp"}
+
+// simulation runs the monitor against the stub servers of the testserver
+// package instead of the real IP services and notification APIs
+type simulation struct {
+	server  *testserver.Server
+	dataDir string // Temporary, so that simulated IPs stay out of the real history
+	log     *logger.Logger
+}
+
+// startSimulation starts the stub servers on addr, or a free loopback port
+// for "on", and points cfg at them: the IP echo is the only IP service, and
+// WhatsApp and email are sent to the Graph API stub and the SMTP sink.
+// Anything else that would act on a simulated IP, such as hooks, ACME
+// renewals, pings and updates, is disabled.
+func startSimulation(addr string, cfg *config.Config, log *logger.Logger) (*simulation, error) {
+	if addr == "on" {
+		addr = "127.0.0.1:0"
+	}
+	dataDir, err := os.MkdirTemp("", "public-ip-monitor-simulation-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create simulation data directory: %w", err)
+	}
+	server, err := testserver.Start(addr, func(format string, args ...any) {
+		log.Infof("Simulation: "+format, args...)
+	})
+	if err != nil {
+		os.RemoveAll(dataDir)
+		return nil, err
+	}
+	smtpHost, smtpPort, _ := net.SplitHostPort(server.SMTP.Addr())
+
+	cfg.DryRun = false
+	cfg.IP.Services = []string{server.URL() + "/ip"}
+	cfg.IP.Sources = nil
+	cfg.IP.Quorum = 0
+	cfg.IP.Families = nil
+	cfg.IP.WANs = nil
+	cfg.IP.DNSRecord = ""
+	cfg.IP.ServicesIndex.URL = ""
+	cfg.IP.DataDir = dataDir
+	cfg.IP.RecordsFile = "ip_records.json"
+	cfg.IP.LastIPFile = "last_ip.txt"
+	cfg.LowWrite.Enabled = false
+	cfg.Proxy.URL = "direct"
+
+	cfg.WhatsApp.Enabled = true
+	cfg.WhatsApp.Token = "simulation"
+	cfg.WhatsApp.PhoneID = "100000000000000"
+	cfg.WhatsApp.RecipientNumber = "15555550100"
+	cfg.Email.Enabled = true
+	cfg.Email.Backend = "smtp"
+	cfg.Email.From = "monitor@simulation.test"
+	cfg.Email.Password = "simulation"
+	cfg.Email.To = "admin@simulation.test"
+	cfg.Email.SMTPHost = smtpHost
+	cfg.Email.SMTPPort = smtpPort
+	cfg.Email.Relays = nil
+	cfg.Email.PGPPublicKeyFile = ""
+
+	cfg.Hooks.Commands = nil
+	cfg.OnChangeCommands = nil
+	cfg.ACME.Domains = nil
+	cfg.Enrichment.Enabled = false
+	cfg.Shortlink.Enabled = false
+	cfg.Ping.Enabled = false
+	cfg.Escalation.Enabled = false
+	cfg.Update.ManifestURL = ""
+
+	log.Warnf("Simulation mode: IP echo at %s, Graph API stub at %s, SMTP sink at %s", cfg.IP.Services[0], server.URL(), server.SMTP.Addr())
+	log.Infof("Simulation: change the IP with \"curl -d 203.0.113.20 %s/ip\", list the messages sent at %s/messages", server.URL(), server.URL())
+	return &simulation{server: server, dataDir: dataDir, log: log}, nil
+}
+
+// Notifiers returns the notifiers of the simulated channels
+func (s *simulation) Notifiers(notifiers []notify.Notifier) []notify.Notifier {
+	return slices.DeleteFunc(notifiers, func(notifier notify.Notifier) bool {
+		if slices.Contains(simulatedChannels, notifier.Name()) {
+			return false
+		}
+		s.log.Infof("Simulation: %s notifications disabled", notifier.Name())
+		return true
+	})
+}
+
+// Close stops the stub servers and removes the simulation's data
+func (s *simulation) Close() {
+	s.server.Close()
+	os.RemoveAll(s.dataDir)
+}
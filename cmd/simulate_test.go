@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/internal/ip"
+	"public-ip-monitor/internal/notify"
+	"public-ip-monitor/internal/testserver"
+	"public-ip-monitor/pkg/email"
+	"public-ip-monitor/pkg/whatsapp"
+)
+
+// TestSimulationDeliversChange runs a check against the simulation's IP
+// echo, changes the IP and sends the change through the email and WhatsApp
+// channels, configured the way main configures them, to the stub servers
+func TestSimulationDeliversChange(t *testing.T) {
+	log := newTestLogger(t)
+	manager := config.NewManager(filepath.Join(t.TempDir(), "config.json"))
+	if err := manager.Save(manager.Defaults()); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := manager.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	simulated, err := startSimulation("on", cfg, log)
+	if err != nil {
+		t.Fatalf("startSimulation: %v", err)
+	}
+	t.Cleanup(simulated.Close)
+	server := simulated.server
+
+	emailClient, err := email.NewSMTPFactory().NewClient(email.Config{
+		From:     cfg.Email.From,
+		Password: cfg.Email.Password,
+		SMTPHost: cfg.Email.SMTPHost,
+		SMTPPort: cfg.Email.SMTPPort,
+		Timeout:  cfg.Email.Timeout,
+		RootCAs:  server.SMTP.CertPool(),
+	})
+	if err != nil {
+		t.Fatalf("email client: %v", err)
+	}
+	defer emailClient.Close()
+	whatsappClient, err := whatsapp.NewMetaFactory().NewClient(whatsapp.Config{
+		Token:          cfg.WhatsApp.Token,
+		PhoneID:        cfg.WhatsApp.PhoneID,
+		APIVersion:     cfg.WhatsApp.APIVersion,
+		TimeoutSeconds: cfg.WhatsApp.TimeoutSeconds,
+		BaseURL:        server.URL(),
+	})
+	if err != nil {
+		t.Fatalf("WhatsApp client: %v", err)
+	}
+	defer whatsappClient.Close()
+	notifiers := simulated.Notifiers([]notify.Notifier{
+		notify.NewEmailNotifier(emailClient, cfg.Email.To, nil),
+		notify.NewWhatsAppNotifier(whatsappClient, cfg.WhatsApp.RecipientNumber, 0),
+	})
+
+	storage := ip.NewStorage(cfg.IP.DataDir, cfg.IP.RecordsFile, cfg.IP.LastIPFile)
+	monitor := ip.NewMonitor(ip.NewFetcher(cfg.IP.Services, 5), storage)
+	monitor.Subscribe(func(oldIP, newIP string) error {
+		if oldIP == "" {
+			return nil
+		}
+		event := notify.NewChangeEvent([]config.IPChange{{Family: "IP", OldIP: oldIP, NewIP: newIP}}, nil, time.Now())
+		for _, notifier := range notifiers {
+			if err := sendWithRetry(context.Background(), notifier.Name(), event, log, func(ctx context.Context) error {
+				return notifier.Notify(ctx, event)
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	ctx := context.Background()
+	if result := monitor.CheckOnce(ctx); result.Error != nil || result.CurrentIP != testserver.InitialIP {
+		t.Fatalf("first check = %+v, want %s", result, testserver.InitialIP)
+	}
+	server.Echo.SetIP("203.0.113.20")
+	result := monitor.CheckOnce(ctx)
+	if result.Error != nil || !result.Changed || result.CurrentIP != "203.0.113.20" {
+		t.Fatalf("check after the change = %+v, want a change to 203.0.113.20", result)
+	}
+
+	emails := server.SMTP.Emails()
+	if len(emails) != 1 {
+		t.Fatalf("%d emails delivered, want 1", len(emails))
+	}
+	if got := emails[0]; got.Username != cfg.Email.From || len(got.To) != 1 || got.To[0] != cfg.Email.To || got.Subject != config.BuildEmailSubject() {
+		t.Errorf("email = %+v, want %q from %s to %s", got, config.BuildEmailSubject(), cfg.Email.From, cfg.Email.To)
+	}
+	if body := emails[0].Body; !strings.Contains(body, testserver.InitialIP) || !strings.Contains(body, "203.0.113.20") {
+		t.Errorf("email body does not mention both IPs:\n%s", body)
+	}
+
+	messages := server.Graph.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("%d WhatsApp messages sent, want 1", len(messages))
+	}
+	if got := messages[0]; got.To != cfg.WhatsApp.RecipientNumber || got.PhoneID != cfg.WhatsApp.PhoneID {
+		t.Errorf("WhatsApp message to %s through %s, want to %s through %s", got.To, got.PhoneID, cfg.WhatsApp.RecipientNumber, cfg.WhatsApp.PhoneID)
+	}
+	if text := messages[0].Text; !strings.Contains(text, testserver.InitialIP) || !strings.Contains(text, "203.0.113.20") {
+		t.Errorf("WhatsApp message does not mention both IPs:\n%s", text)
+	}
+}
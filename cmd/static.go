@@ -0,0 +1,7 @@
+//go:build static
+
+package main
+
+// Static builds run from scratch or distroless images without a zoneinfo
+// database, so the timezone data is embedded for logging.timezone
+import _ "time/tzdata"
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/internal/logger"
+	"public-ip-monitor/internal/update"
+)
+
+// updateChecker looks for new releases on a schedule and, with autoApply,
+// installs them
+type updateChecker struct {
+	updater   *update.Updater
+	autoApply bool
+	installed string // Version installed but not running yet
+	log       *logger.Logger
+}
+
+// Check logs a newer release or installs it, and reports whether the
+// monitor should restart into it: only under systemd, which starts it
+// again, as nothing would otherwise
+func (c *updateChecker) Check(ctx context.Context) bool {
+	release, err := c.updater.Latest(ctx)
+	if err != nil {
+		c.log.Warnf("Update check failed: %v", err)
+		return false
+	}
+	if !release.Newer(version) || release.Version == c.installed {
+		c.log.Debugf("Version %s is up to date (latest release: %s)", version, release.Version)
+		return false
+	}
+	if !c.autoApply {
+		c.log.Infof("Version %s is available (running %s), install it with self-update", release.Version, version)
+		return false
+	}
+
+	executable, err := currentExecutable()
+	if err != nil {
+		c.log.Errorf("Failed to install version %s: %v", release.Version, err)
+		return false
+	}
+	if err := c.updater.Install(ctx, release, executable); err != nil {
+		c.log.Errorf("Failed to install version %s: %v", release.Version, err)
+		return false
+	}
+	c.installed = release.Version
+	if !underSystemd() {
+		c.log.Infof("Installed version %s in %s, it runs after the next restart", release.Version, executable)
+		return false
+	}
+	c.log.Infof("Installed version %s in %s, restarting into it", release.Version, executable)
+	return true
+}
+
+// runSelfUpdate installs the latest release in place of this binary, for
+// "self-update"
+func runSelfUpdate(args []string, cfg *config.Config) error {
+	flags := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	checkOnly := flags.Bool("check", false, "Only report whether a newer version is available")
+	force := flags.Bool("force", false, "Install the latest release even if it is not newer, e.g. over a dev build")
+	restart := flags.Bool("restart", false, "Restart update.systemd_unit after installing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if cfg.Update.ManifestURL == "" {
+		return fmt.Errorf("no release manifest configured (update.manifest_url)")
+	}
+
+	updater, err := update.New(cfg.Update.ManifestURL, cfg.Update.PublicKey, cfg.Update.TimeoutSeconds)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	release, err := updater.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	if !release.Newer(version) && !*force {
+		fmt.Printf("Version %s is up to date (latest release: %s)\n", version, release.Version)
+		return nil
+	}
+	if *checkOnly {
+		fmt.Printf("Version %s is available (running %s)\n", release.Version, version)
+		return nil
+	}
+
+	executable, err := currentExecutable()
+	if err != nil {
+		return err
+	}
+	if err := updater.Install(ctx, release, executable); err != nil {
+		return err
+	}
+	fmt.Printf("Installed version %s in %s, the previous binary is kept as %s.old\n", release.Version, executable, executable)
+
+	if !*restart {
+		fmt.Printf("Restart the monitor to run it, e.g. sudo systemctl restart %s\n", cfg.Update.SystemdUnit)
+		return nil
+	}
+	output, err := exec.CommandContext(ctx, "systemctl", "restart", cfg.Update.SystemdUnit).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to restart %s: %w: %s", cfg.Update.SystemdUnit, err, strings.TrimSpace(string(output)))
+	}
+	fmt.Printf("Restarted %s\n", cfg.Update.SystemdUnit)
+	return nil
+}
+
+// currentExecutable returns the path of the running binary, with symbolic
+// links resolved so that the link itself is not replaced
+func currentExecutable() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the running binary: %w", err)
+	}
+	return filepath.EvalSymlinks(executable)
+}
+
+// underSystemd reports whether the monitor runs as a systemd service,
+// which restarts it after it exits
+func underSystemd() bool {
+	return os.Getenv("INVOCATION_ID") != ""
+}
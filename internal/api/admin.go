@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSettings is returned by Admin.UpdateSettings for values it
+// rejects, e.g. an unknown channel
+var ErrInvalidSettings = errors.New("invalid settings")
+
+// Settings are the settings the admin endpoints change while running
+type Settings struct {
+	CheckInterval time.Duration
+	MutedChannels []string // Names of the channels not notified
+	DryRun        bool     // Notifications are logged instead of sent
+}
+
+// SettingsUpdate changes the settings that are set. With Persist, the
+// changes are written back to the config file as well.
+type SettingsUpdate struct {
+	CheckIntervalSeconds *int      `json:"check_interval_seconds"`
+	MutedChannels        *[]string `json:"muted_channels"`
+	DryRun               *bool     `json:"dry_run"`
+	Persist              bool      `json:"persist"`
+}
+
+// Admin is what the admin endpoints act on
+type Admin struct {
+	Token string // Required from admin clients; the endpoints are only served when set
+
+	// Settings returns the current settings
+	Settings func() Settings
+
+	// UpdateSettings applies an update and returns the settings that
+	// result; values it rejects are reported as ErrInvalidSettings
+	UpdateSettings func(update SettingsUpdate) (Settings, error)
+
+	// Prune drops data past its retention and returns how many entries it
+	// dropped, by data store
+	Prune func(ctx context.Context) (map[string]int, error)
+
+	// SetPaused pauses or resumes checking, reflected in the state
+	SetPaused func(paused bool) error
+}
+
+// registerAdmin registers the admin endpoints
+func (s *Server) registerAdmin() {
+	s.mux.HandleFunc("GET /admin/settings", s.adminAuthorized(s.handleSettings))
+	s.mux.HandleFunc("PATCH /admin/settings", s.adminAuthorized(s.handleUpdateSettings))
+	s.mux.HandleFunc("POST /admin/prune", s.adminAuthorized(s.handlePrune))
+	s.mux.HandleFunc("POST /admin/pause", s.adminAuthorized(s.handlePause(true)))
+	s.mux.HandleFunc("POST /admin/resume", s.adminAuthorized(s.handlePause(false)))
+}
+
+// adminAuthorized requires the admin token, which the read-only token does
+// not replace
+func (s *Server) adminAuthorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == r.Header.Get("Authorization") {
+			token = ""
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.options.Admin.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid admin token")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+type settingsPayload struct {
+	CheckIntervalSeconds int      `json:"check_interval_seconds"`
+	MutedChannels        []string `json:"muted_channels"`
+	DryRun               bool     `json:"dry_run"`
+}
+
+func newSettingsPayload(settings Settings) settingsPayload {
+	payload := settingsPayload{
+		CheckIntervalSeconds: int(settings.CheckInterval / time.Second),
+		MutedChannels:        settings.MutedChannels,
+		DryRun:               settings.DryRun,
+	}
+	if payload.MutedChannels == nil {
+		payload.MutedChannels = []string{}
+	}
+	return payload
+}
+
+// handleSettings returns the current settings
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, newSettingsPayload(s.options.Admin.Settings()))
+}
+
+// handleUpdateSettings changes the settings present in the body
+func (s *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var update SettingsUpdate
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+
+	settings, err := s.options.Admin.UpdateSettings(update)
+	if errors.Is(err, ErrInvalidSettings) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, newSettingsPayload(settings))
+}
+
+// handlePrune drops data past its retention right away
+func (s *Server) handlePrune(w http.ResponseWriter, r *http.Request) {
+	pruned, err := s.options.Admin.Prune(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pruned": pruned})
+}
+
+// handlePause returns a handler pausing or resuming checking
+func (s *Server) handlePause(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.options.Admin.SetPaused(paused); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		pausedAt := s.state.PausedAt()
+		payload := map[string]any{"paused": !pausedAt.IsZero()}
+		if !pausedAt.IsZero() {
+			payload["paused_at"] = formatTime(pausedAt)
+		}
+		writeJSON(w, http.StatusOK, payload)
+	}
+}
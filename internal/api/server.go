@@ -0,0 +1,741 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options configures the API server
+type Options struct {
+	Listen  string        // e.g. ":8787"
+	Token   string        // Required as a bearer token or ?token= when set
+	MaxWait time.Duration // Upper bound for long-poll requests
+	Logf    func(format string, args ...any)
+
+	// History returns a page of the IP change history in chronological
+	// order, with the records matching the query; the /history endpoint is
+	// only served when set. Returns ErrInvalidQuery for filters it cannot
+	// apply.
+	History func(query HistoryQuery) (HistoryPage, error)
+
+	// Checks returns the checks since the given time, oldest first; the
+	// /checks endpoint is only served when set
+	Checks func(since time.Time) []CheckRecord
+
+	// Events returns the recent notification events, oldest first; the
+	// /events endpoint is only served when set
+	Events func() ([]EventRecord, error)
+
+	// Ack acknowledges a notification event by ID on behalf of by and
+	// reports whether its escalation was still pending. An event
+	// acknowledged before keeps its first acknowledgment. Returns
+	// ErrUnknownEvent for IDs not in the event history; the
+	// /events/{id}/ack endpoint is only served when set.
+	Ack func(eventID, by string) (record EventRecord, pending bool, err error)
+
+	// Quotas returns today's sends of the notification channels with a
+	// daily quota, listed by /status when set
+	Quotas func() []QuotaUsage
+
+	// Resources returns the resource settings in effect and the current
+	// usage, listed by /status when set
+	Resources func() Resources
+
+	// Admin serves the /admin endpoints changing settings at runtime when
+	// set with a token
+	Admin *Admin
+}
+
+// ErrUnknownEvent is returned by Options.Ack for events it does not know
+var ErrUnknownEvent = errors.New("unknown event")
+
+// ErrInvalidQuery is returned by Options.History for invalid filters, e.g.
+// an unparsable time or a cursor no page returned
+var ErrInvalidQuery = errors.New("invalid query")
+
+// EventRecord is a notification event and who acknowledged it
+type EventRecord struct {
+	ID        string
+	Type      string // e.g. "ip_changed"
+	Severity  string
+	Timestamp time.Time
+	Summary   string // e.g. "ip_changed: IPv4 203.0.113.1 -> 198.51.100.2"
+	AckedBy   string
+	AckedAt   time.Time // Zero while not acknowledged
+}
+
+// QuotaUsage is how much of its daily quota a notification channel used
+type QuotaUsage struct {
+	Channel string
+	Used    int
+	Limit   int
+}
+
+// Resources are the resource settings of the process and its usage
+type Resources struct {
+	GOMAXPROCS       int
+	CPUQuota         float64 // Container CPU quota in CPUs, 0 if unlimited
+	MemoryLimitBytes int64   // Go runtime soft memory limit, 0 if unlimited
+	ContainerMemory  int64   // Container memory limit in bytes, 0 if unlimited
+	Goroutines       int
+	HeapAllocBytes   uint64
+	HeapSysBytes     uint64
+	SysBytes         uint64 // Obtained from the OS in total
+	NumGC            uint32
+}
+
+// HistoryQuery selects the records of the IP change history, with the
+// same filters as the history commands
+type HistoryQuery struct {
+	Search string // Words every record must match, e.g. "country:de ipv6"
+	Since  string // Time, date or duration ago, as given
+	Until  string
+	IP     string // New or previous IP
+	Site   string // Monitor the records are from
+	Limit  int    // Records per page; 0 for all
+	Cursor string // NextCursor of the previous page
+}
+
+// HistoryRecord is an entry of the IP change history
+type HistoryRecord struct {
+	Family     string // e.g. "IPv4"
+	WAN        string // Empty for the default route
+	IP         string
+	PreviousIP string
+	Timestamp  time.Time
+	Enrichment map[string]string // Network details, e.g. "as_name" or "country"
+}
+
+// HistoryPage is a page of the IP change history
+type HistoryPage struct {
+	Records    []HistoryRecord
+	NextCursor string // Empty on the last page
+}
+
+// CheckRecord is the outcome of a single check
+type CheckRecord struct {
+	Family  string // e.g. "IPv4"
+	WAN     string // Empty for the default route
+	Time    time.Time
+	OK      bool
+	Source  string
+	Latency time.Duration
+}
+
+// Server serves the monitor's state to other applications on the network
+type Server struct {
+	state   *State
+	options Options
+	mux     *http.ServeMux
+	server  *http.Server
+	closing chan struct{} // Closed on shutdown to release long-poll requests
+}
+
+// NewServer creates an API server for the given state
+func NewServer(state *State, options Options) *Server {
+	s := &Server{
+		state:   state,
+		options: options,
+		mux:     http.NewServeMux(),
+		closing: make(chan struct{}),
+	}
+
+	s.Handle("GET /ip", s.handleIP)
+	s.Handle("GET /ip/wait", s.handleWait)
+	s.Handle("GET /status", s.handleStatus)
+	if options.History != nil {
+		s.Handle("GET /history", s.handleHistory)
+	}
+	if options.Checks != nil {
+		s.Handle("GET /checks", s.handleChecks)
+	}
+	if options.Events != nil {
+		s.Handle("GET /events", s.handleEvents)
+	}
+	if options.Ack != nil {
+		s.Handle("POST /events/{id}/ack", s.handleAck)
+	}
+	if options.Admin != nil && options.Admin.Token != "" {
+		s.registerAdmin()
+	}
+
+	s.server = &http.Server{
+		Addr:              options.Listen,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Handle registers an additional endpoint behind the same authorization
+func (s *Server) Handle(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, s.authorized(handler))
+}
+
+// Start listens on the configured address and serves requests in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.options.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.options.Listen, err)
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) && s.options.Logf != nil {
+			s.options.Logf("API server stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the server, ending pending long-poll requests
+func (s *Server) Shutdown(ctx context.Context) error {
+	close(s.closing)
+	return s.server.Shutdown(ctx)
+}
+
+// authorized rejects requests without the configured token
+func (s *Server) authorized(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.options.Token != "" {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || token == r.Header.Get("Authorization") {
+				token = r.URL.Query().Get("token")
+			}
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.options.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, "missing or invalid token")
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+// handleIP returns the current addresses
+func (s *Server) handleIP(w http.ResponseWriter, r *http.Request) {
+	s.writeSnapshot(w, r, s.state.Snapshot())
+}
+
+// handleWait returns as soon as an address changes after ?since= (Unix
+// seconds or RFC 3339), immediately if that already happened. Without
+// since it waits for the next change. When nothing changes within
+// ?timeout= seconds (capped at the configured maximum) it returns 304.
+func (s *Server) handleWait(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	wait := s.options.MaxWait
+	if value := query.Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid timeout %q", value))
+			return
+		}
+		wait = min(wait, time.Duration(seconds)*time.Second)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+	go func() {
+		select {
+		case <-s.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// 304 already means that nothing changed in time
+	r.Header.Del("If-None-Match")
+	r.Header.Del("If-Modified-Since")
+
+	var snapshot Snapshot
+	var ok bool
+	if value := query.Get("since"); value != "" {
+		since, err := parseSince(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		snapshot, ok = s.state.Wait(ctx, since)
+	} else {
+		snapshot, ok = s.state.Next(ctx)
+	}
+
+	if !ok {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.writeSnapshot(w, r, snapshot)
+}
+
+// parseSince parses Unix seconds or an RFC 3339 timestamp
+func parseSince(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Unix(int64(seconds), 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q (expected Unix seconds or RFC 3339)", value)
+}
+
+// addressPayload is the JSON form of an address
+type addressPayload struct {
+	Family    string `json:"family"`
+	WAN       string `json:"wan,omitempty"`
+	IP        string `json:"ip"`
+	ChangedAt string `json:"changed_at,omitempty"`
+	CheckedAt string `json:"checked_at,omitempty"`
+}
+
+// snapshotPayload is the JSON form of a snapshot
+type snapshotPayload struct {
+	IP            string           `json:"ip"` // First address of the default route
+	Addresses     []addressPayload `json:"addresses"`
+	ChangedAt     string           `json:"changed_at,omitempty"`
+	ChangedAtUnix int64            `json:"changed_at_unix"` // Pass back as ?since= to wait for the next change
+}
+
+// writeSnapshot writes the snapshot as JSON, or just the default route's
+// address with ?format=text
+func (s *Server) writeSnapshot(w http.ResponseWriter, r *http.Request, snapshot Snapshot) {
+	payload := snapshotPayload{Addresses: []addressPayload{}}
+	for _, address := range snapshot.Addresses {
+		if payload.IP == "" && address.WAN == "" {
+			payload.IP = address.IP
+		}
+		payload.Addresses = append(payload.Addresses, addressPayload{
+			Family:    address.Family,
+			WAN:       address.WAN,
+			IP:        address.IP,
+			ChangedAt: formatTime(address.ChangedAt),
+			CheckedAt: formatTime(address.CheckedAt),
+		})
+	}
+	if !snapshot.ChangedAt.IsZero() {
+		payload.ChangedAt = formatTime(snapshot.ChangedAt)
+		payload.ChangedAtUnix = snapshot.ChangedAt.Unix()
+	}
+
+	// Clients polling /ip get 304 until an address changes or is checked again
+	modified := snapshot.ChangedAt
+	for _, address := range snapshot.Addresses {
+		if address.CheckedAt.After(modified) {
+			modified = address.CheckedAt
+		}
+	}
+
+	if r.URL.Query().Get("format") == "text" {
+		writeConditional(w, r, "text/plain; charset=utf-8", []byte(payload.IP+"\n"), modified)
+		return
+	}
+	writeConditionalJSON(w, r, payload, modified)
+}
+
+// routePayload is the JSON form of a route
+type routePayload struct {
+	Source        string `json:"source,omitempty"`
+	Detail        string `json:"detail,omitempty"`
+	SourceAddress string `json:"source_address,omitempty"`
+	Interface     string `json:"interface,omitempty"`
+	Gateway       string `json:"gateway,omitempty"`
+	NAT           string `json:"nat,omitempty"`
+}
+
+// statusPayload is the JSON form of an address with the route of its last
+// successful check
+type statusPayload struct {
+	Family    string        `json:"family"`
+	WAN       string        `json:"wan,omitempty"`
+	IP        string        `json:"ip"`
+	CheckedAt string        `json:"checked_at,omitempty"`
+	Route     *routePayload `json:"route,omitempty"`
+}
+
+// quotaPayload is the JSON form of a channel's quota usage
+type quotaPayload struct {
+	Channel string `json:"channel"`
+	Used    int    `json:"used"`
+	Limit   int    `json:"limit"`
+}
+
+// resourcesPayload is the JSON form of the resource settings and usage
+type resourcesPayload struct {
+	GOMAXPROCS       int     `json:"gomaxprocs"`
+	CPUQuota         float64 `json:"cpu_quota,omitempty"`
+	MemoryLimitBytes int64   `json:"memory_limit_bytes,omitempty"`
+	ContainerMemory  int64   `json:"container_memory_bytes,omitempty"`
+	Goroutines       int     `json:"goroutines"`
+	HeapAllocBytes   uint64  `json:"heap_alloc_bytes"`
+	HeapSysBytes     uint64  `json:"heap_sys_bytes"`
+	SysBytes         uint64  `json:"sys_bytes"`
+	NumGC            uint32  `json:"num_gc"`
+}
+
+// handleStatus returns whether checking is paused, every address with
+// the source address, interface and gateway its last successful check went
+// out through, the quota usage of the notification channels and the
+// process's resources
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.state.Snapshot()
+	pausedAt := s.state.PausedAt()
+	payload := struct {
+		Paused    bool              `json:"paused"`
+		PausedAt  string            `json:"paused_at,omitempty"`
+		Addresses []statusPayload   `json:"addresses"`
+		Quotas    []quotaPayload    `json:"quotas,omitempty"`
+		Resources *resourcesPayload `json:"resources,omitempty"`
+	}{Paused: !pausedAt.IsZero(), PausedAt: formatTime(pausedAt), Addresses: make([]statusPayload, 0, len(snapshot.Addresses))}
+	for _, address := range snapshot.Addresses {
+		status := statusPayload{
+			Family:    address.Family,
+			WAN:       address.WAN,
+			IP:        address.IP,
+			CheckedAt: formatTime(address.CheckedAt),
+		}
+		if route := address.Route; route != (Route{}) {
+			status.Route = &routePayload{
+				Source:        route.Source,
+				Detail:        route.Detail,
+				SourceAddress: route.SourceAddress,
+				Interface:     route.Interface,
+				Gateway:       route.Gateway,
+				NAT:           route.NAT,
+			}
+		}
+		payload.Addresses = append(payload.Addresses, status)
+	}
+	if s.options.Quotas != nil {
+		for _, quota := range s.options.Quotas() {
+			payload.Quotas = append(payload.Quotas, quotaPayload(quota))
+		}
+	}
+	if s.options.Resources != nil {
+		resources := resourcesPayload(s.options.Resources())
+		payload.Resources = &resources
+	}
+	writeJSON(w, http.StatusOK, payload)
+}
+
+// maxHistoryLimit caps the records per page of /history
+const maxHistoryLimit = 1000
+
+// historyPayload is the JSON form of an entry of the history
+type historyPayload struct {
+	Family     string            `json:"family"`
+	WAN        string            `json:"wan,omitempty"`
+	IP         string            `json:"ip"`
+	PreviousIP string            `json:"previous_ip,omitempty"`
+	Timestamp  string            `json:"timestamp"`
+	Enrichment map[string]string `json:"enrichment,omitempty"`
+}
+
+// handleHistory returns the IP change history, narrowed by ?q=, ?since=,
+// ?until=, ?ip= and ?site=, a page of ?limit= records at a time from
+// ?cursor=. It only grows, so dashboards polling it mostly get 304
+// responses.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := HistoryQuery{
+		Search: params.Get("q"),
+		Since:  params.Get("since"),
+		Until:  params.Get("until"),
+		IP:     params.Get("ip"),
+		Site:   params.Get("site"),
+		Cursor: params.Get("cursor"),
+	}
+	if value := params.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxHistoryLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxHistoryLimit))
+			return
+		}
+		query.Limit = n
+	}
+
+	page, err := s.options.History(query)
+	if errors.Is(err, ErrInvalidQuery) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	payload := struct {
+		Records    []historyPayload `json:"records"`
+		NextCursor string           `json:"next_cursor,omitempty"`
+	}{Records: make([]historyPayload, 0, len(page.Records)), NextCursor: page.NextCursor}
+	var modified time.Time
+	for _, record := range page.Records {
+		payload.Records = append(payload.Records, historyPayload{
+			Family:     record.Family,
+			WAN:        record.WAN,
+			IP:         record.IP,
+			PreviousIP: record.PreviousIP,
+			Timestamp:  formatTime(record.Timestamp),
+			Enrichment: record.Enrichment,
+		})
+		if record.Timestamp.After(modified) {
+			modified = record.Timestamp
+		}
+	}
+
+	writeConditionalJSON(w, r, payload, modified)
+}
+
+type eventPayload struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Severity  string `json:"severity"`
+	Timestamp string `json:"timestamp"`
+	Summary   string `json:"summary"`
+	AckedBy   string `json:"acked_by,omitempty"`
+	AckedAt   string `json:"acked_at,omitempty"`
+}
+
+func newEventPayload(record EventRecord) eventPayload {
+	return eventPayload{
+		ID:        record.ID,
+		Type:      record.Type,
+		Severity:  record.Severity,
+		Timestamp: formatTime(record.Timestamp),
+		Summary:   record.Summary,
+		AckedBy:   record.AckedBy,
+		AckedAt:   formatTime(record.AckedAt),
+	}
+}
+
+// handleEvents returns the recent notification events and their
+// acknowledgments
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	records, err := s.options.Events()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	payload := struct {
+		Events []eventPayload `json:"events"`
+	}{Events: make([]eventPayload, 0, len(records))}
+	for _, record := range records {
+		payload.Events = append(payload.Events, newEventPayload(record))
+	}
+	writeJSON(w, http.StatusOK, payload)
+}
+
+type ackPayload struct {
+	EventID           string       `json:"event_id"`
+	Acknowledged      bool         `json:"acknowledged"`
+	EscalationPending bool         `json:"escalation_pending"` // The ack stopped an escalation
+	Event             eventPayload `json:"event"`
+}
+
+// handleAck acknowledges the event, stopping its escalation. Who
+// acknowledged it is taken from the optional {"by": "..."} body, or the
+// client address.
+func (s *Server) handleAck(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var body struct {
+		By string `json:"by"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+			return
+		}
+	}
+	by := strings.TrimSpace(body.By)
+	if by == "" {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		by = "api " + host
+	}
+
+	record, pending, err := s.options.Ack(id, by)
+	if errors.Is(err, ErrUnknownEvent) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ackPayload{EventID: id, Acknowledged: true, EscalationPending: pending, Event: newEventPayload(record)})
+}
+
+// maxCheckHours bounds the period /checks reports on
+const maxCheckHours = 24 * 31
+
+type checkBucket struct {
+	Start        string  `json:"start"`
+	Total        int     `json:"total"`
+	Failed       int     `json:"failed"`
+	Uptime       float64 `json:"uptime"`
+	AvgLatencyMS int64   `json:"avg_latency_ms"`
+	okLatency    time.Duration
+}
+
+type checksPayload struct {
+	Hours        int            `json:"hours"`
+	Total        int            `json:"total"`
+	Failed       int            `json:"failed"`
+	Uptime       float64        `json:"uptime"` // Share of successful checks, 0-1
+	AvgLatencyMS int64          `json:"avg_latency_ms"`
+	Sources      map[string]int `json:"sources"` // Successful checks per source
+	Buckets      []*checkBucket `json:"buckets"` // One per hour, oldest first
+}
+
+// handleChecks returns uptime statistics over the last ?hours=N (24 by
+// default), with hourly buckets for sparklines. ?family= and ?wan= narrow
+// it to one target.
+func (s *Server) handleChecks(w http.ResponseWriter, r *http.Request) {
+	hours := 24
+	if value := r.URL.Query().Get("hours"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxCheckHours {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("hours must be between 1 and %d", maxCheckHours))
+			return
+		}
+		hours = n
+	}
+	family := r.URL.Query().Get("family")
+	wan := r.URL.Query().Get("wan")
+
+	start := time.Now().Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour)
+	payload := checksPayload{
+		Hours:   hours,
+		Sources: map[string]int{},
+		Buckets: make([]*checkBucket, hours),
+	}
+	for i := range payload.Buckets {
+		payload.Buckets[i] = &checkBucket{Start: formatTime(start.Add(time.Duration(i) * time.Hour))}
+	}
+
+	var okLatency time.Duration
+	var modified time.Time
+	for _, record := range s.options.Checks(start) {
+		if (family != "" && !strings.EqualFold(record.Family, family)) || (wan != "" && record.WAN != wan) {
+			continue
+		}
+		index := int(record.Time.Sub(start) / time.Hour)
+		if index < 0 || index >= hours {
+			continue
+		}
+		bucket := payload.Buckets[index]
+		bucket.Total++
+		payload.Total++
+		if record.OK {
+			bucket.okLatency += record.Latency
+			okLatency += record.Latency
+			if record.Source != "" {
+				payload.Sources[record.Source]++
+			}
+		} else {
+			bucket.Failed++
+			payload.Failed++
+		}
+		if record.Time.After(modified) {
+			modified = record.Time
+		}
+	}
+
+	payload.Uptime, payload.AvgLatencyMS = uptime(payload.Total, payload.Failed, okLatency)
+	for _, bucket := range payload.Buckets {
+		bucket.Uptime, bucket.AvgLatencyMS = uptime(bucket.Total, bucket.Failed, bucket.okLatency)
+	}
+
+	writeConditionalJSON(w, r, payload, modified)
+}
+
+// uptime returns the share of successful checks and their average latency
+func uptime(total, failed int, okLatency time.Duration) (float64, int64) {
+	ok := total - failed
+	if ok == 0 {
+		return 0, 0
+	}
+	return float64(ok) / float64(total), (okLatency / time.Duration(ok)).Milliseconds()
+}
+
+// formatTime formats a timestamp for responses; zero times are omitted
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// writeConditionalJSON writes a JSON response like writeConditional
+func writeConditionalJSON(w http.ResponseWriter, r *http.Request, payload any, modified time.Time) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeConditional(w, r, "application/json", append(body, '\n'), modified)
+}
+
+// writeConditional writes a response with an ETag derived from the body and
+// a Last-Modified time, or 304 Not Modified when the request's
+// If-None-Match (or, without it, If-Modified-Since) shows the client has it
+func writeConditional(w http.ResponseWriter, r *http.Request, contentType string, body []byte, modified time.Time) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache") // Cache, but revalidate every time
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
+
+// notModified evaluates the conditional request headers
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := r.Header.Get("If-Modified-Since"); since != "" && !modified.IsZero() {
+		t, err := http.ParseTime(since)
+		// Last-Modified has a resolution of whole seconds
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatusListsResources(t *testing.T) {
+	server := NewServer(NewState(), Options{
+		Resources: func() Resources {
+			return Resources{GOMAXPROCS: 2, CPUQuota: 1.5, MemoryLimitBytes: 230 << 20, Goroutines: 12, HeapAllocBytes: 4 << 20, SysBytes: 16 << 20, NumGC: 7}
+		},
+	})
+
+	recorder := httptest.NewRecorder()
+	server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET /status = %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var status struct {
+		Resources map[string]any `json:"resources"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"gomaxprocs":         2.0,
+		"cpu_quota":          1.5,
+		"memory_limit_bytes": float64(230 << 20),
+		"goroutines":         12.0,
+		"heap_alloc_bytes":   float64(4 << 20),
+		"heap_sys_bytes":     0.0,
+		"sys_bytes":          float64(16 << 20),
+		"num_gc":             7.0,
+	}
+	if len(status.Resources) != len(want) {
+		t.Errorf("resources = %v, want %v", status.Resources, want)
+	}
+	for key, value := range want {
+		if status.Resources[key] != value {
+			t.Errorf("resources.%s = %v, want %v", key, status.Resources[key], value)
+		}
+	}
+}
+
+func TestStatusWithoutResources(t *testing.T) {
+	server := NewServer(NewState(), Options{})
+	recorder := httptest.NewRecorder()
+	server.mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	var status map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := status["resources"]; ok {
+		t.Errorf("status lists resources without Options.Resources: %s", recorder.Body)
+	}
+}
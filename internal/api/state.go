@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Address is the last address seen for a family on the default route or a WAN
+type Address struct {
+	Family    string // e.g. "IPv4"
+	WAN       string // Empty for the default route
+	IP        string
+	ChangedAt time.Time // When the address was first seen
+	CheckedAt time.Time // When the address was last confirmed
+	Route     Route     // Of the last successful check; zero before the first
+}
+
+// Route describes how the last successful check of an address reached the
+// service that answered, for diagnosing policy routing
+type Route struct {
+	Source        string // How the address was detected, e.g. "http"
+	Detail        string // What was asked, e.g. the service URL
+	SourceAddress string // Local address of the connection
+	Interface     string // Interface holding the source address
+	Gateway       string // Gateway of the default route on that interface
+	NAT           string // NAT behavior seen by a stun source, e.g. "port-preserving NAT"
+}
+
+// Snapshot is the state at one point in time
+type Snapshot struct {
+	Addresses []Address
+	ChangedAt time.Time // Latest change of any address
+}
+
+// State tracks the current addresses and wakes up waiting clients on changes
+type State struct {
+	mu        sync.Mutex
+	addresses []Address
+	changedAt time.Time
+	changed   chan struct{} // Closed and replaced on every change
+	pausedAt  time.Time     // When checking was paused; zero while running
+}
+
+// NewState creates an empty state
+func NewState() *State {
+	return &State{changed: make(chan struct{})}
+}
+
+// Seed sets an address known from storage before the first check, without
+// waking up clients
+func (s *State) Seed(family, wan, ip string, changedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(Address{Family: family, WAN: wan, IP: ip, ChangedAt: changedAt})
+	if changedAt.After(s.changedAt) {
+		s.changedAt = changedAt
+	}
+}
+
+// Observe records the result of a successful check
+func (s *State) Observe(family, wan, ip string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.addresses {
+		address := &s.addresses[i]
+		if address.Family != family || address.WAN != wan {
+			continue
+		}
+		address.CheckedAt = at
+		if address.IP == ip {
+			return
+		}
+		address.IP = ip
+		address.ChangedAt = at
+		s.notify(at)
+		return
+	}
+
+	s.set(Address{Family: family, WAN: wan, IP: ip, ChangedAt: at, CheckedAt: at})
+	s.notify(at)
+}
+
+// SetRoute records how the last successful check of an address was made.
+// It does not count as a change.
+func (s *State) SetRoute(family, wan string, route Route) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.addresses {
+		if s.addresses[i].Family == family && s.addresses[i].WAN == wan {
+			s.addresses[i].Route = route
+			return
+		}
+	}
+}
+
+// SetPaused records when checking was paused, or a zero time once resumed
+func (s *State) SetPaused(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pausedAt = at
+}
+
+// PausedAt returns when checking was paused, or a zero time while running
+func (s *State) PausedAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pausedAt
+}
+
+// set adds or replaces an address
+func (s *State) set(address Address) {
+	for i := range s.addresses {
+		if s.addresses[i].Family == address.Family && s.addresses[i].WAN == address.WAN {
+			s.addresses[i] = address
+			return
+		}
+	}
+	s.addresses = append(s.addresses, address)
+}
+
+// notify wakes up waiting clients
+func (s *State) notify(at time.Time) {
+	s.changedAt = at
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Snapshot returns the current state
+func (s *State) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot()
+}
+
+func (s *State) snapshot() Snapshot {
+	return Snapshot{
+		Addresses: append([]Address(nil), s.addresses...),
+		ChangedAt: s.changedAt,
+	}
+}
+
+// Wait returns as soon as an address changed after since (compared in whole
+// seconds, the resolution clients pass it back in), or reports false when
+// the context ends first
+func (s *State) Wait(ctx context.Context, since time.Time) (Snapshot, bool) {
+	for {
+		s.mu.Lock()
+		if s.changedAt.Unix() > since.Unix() {
+			snapshot := s.snapshot()
+			s.mu.Unlock()
+			return snapshot, true
+		}
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return Snapshot{}, false
+		}
+	}
+}
+
+// Next waits for the next change
+func (s *State) Next(ctx context.Context) (Snapshot, bool) {
+	s.mu.Lock()
+	changed := s.changed
+	s.mu.Unlock()
+
+	select {
+	case <-changed:
+		return s.Snapshot(), true
+	case <-ctx.Done():
+		return Snapshot{}, false
+	}
+}
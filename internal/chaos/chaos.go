@@ -0,0 +1,161 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"public-ip-monitor/internal/ip"
+	"public-ip-monitor/internal/notify"
+)
+
+// Injected errors, recognizable in logs
+var (
+	ErrFetch  = errors.New("chaos: injected fetch failure")
+	ErrNotify = errors.New("chaos: injected notification failure")
+)
+
+// Settings holds the rates of faults injected into IP detection and
+// notifications, each between 0 and 1
+type Settings struct {
+	FetchFailureRate  float64       // Share of source fetches that fail
+	NotifyFailureRate float64       // Share of notifications that fail
+	SlowRate          float64       // Share of fetches and notifications that are delayed
+	SlowDelay         time.Duration // How long delayed calls wait (bounded by their timeout)
+}
+
+// DefaultSettings are used for keys not given to Parse
+var DefaultSettings = Settings{
+	FetchFailureRate:  0.2,
+	NotifyFailureRate: 0.2,
+	SlowRate:          0.1,
+	SlowDelay:         10 * time.Second,
+}
+
+// Parse reads settings from a comma-separated list such as
+// "fetch=0.3,notify=0.5,slow=0.1,delay=5s"; "on" uses the defaults
+func Parse(spec string) (Settings, error) {
+	settings := DefaultSettings
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == "on" {
+		return settings, nil
+	}
+
+	for _, item := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return settings, fmt.Errorf("invalid chaos setting %q (expected key=value)", item)
+		}
+
+		if key == "delay" {
+			delay, err := time.ParseDuration(value)
+			if err != nil || delay < 0 {
+				return settings, fmt.Errorf("invalid chaos delay %q", value)
+			}
+			settings.SlowDelay = delay
+			continue
+		}
+
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return settings, fmt.Errorf("invalid chaos rate %s=%q (expected 0 to 1)", key, value)
+		}
+		switch key {
+		case "fetch":
+			settings.FetchFailureRate = rate
+		case "notify":
+			settings.NotifyFailureRate = rate
+		case "slow":
+			settings.SlowRate = rate
+		default:
+			return settings, fmt.Errorf("unknown chaos setting %q (expected fetch, notify, slow or delay)", key)
+		}
+	}
+	return settings, nil
+}
+
+// String describes the settings for logs
+func (s Settings) String() string {
+	return fmt.Sprintf("fetch failures %.0f%%, notification failures %.0f%%, %.0f%% delayed by %v",
+		s.FetchFailureRate*100, s.NotifyFailureRate*100, s.SlowRate*100, s.SlowDelay)
+}
+
+// Enable injects faults into all IP sources created from now on
+func Enable(settings Settings) {
+	ip.WrapSources(func(source ip.Source) ip.Source {
+		return &faultySource{Source: source, settings: settings}
+	})
+}
+
+// WrapNotifiers injects faults into the given notifiers
+func WrapNotifiers(notifiers []notify.Notifier, settings Settings) []notify.Notifier {
+	wrapped := make([]notify.Notifier, len(notifiers))
+	for i, notifier := range notifiers {
+		wrapped[i] = &faultyNotifier{Notifier: notifier, settings: settings}
+	}
+	return wrapped
+}
+
+// faultySource fails or delays some fetches of a source
+type faultySource struct {
+	ip.Source
+	settings Settings
+}
+
+func (s *faultySource) Fetch(ctx context.Context) (net.IP, ip.Meta, error) {
+	if err := delay(ctx, s.settings); err != nil {
+		return nil, ip.Meta{}, err
+	}
+	if hit(s.settings.FetchFailureRate) {
+		return nil, ip.Meta{}, fmt.Errorf("%s: %w", s.Name(), ErrFetch)
+	}
+	return s.Source.Fetch(ctx)
+}
+
+// faultyNotifier fails or delays some notifications
+type faultyNotifier struct {
+	notify.Notifier
+	settings Settings
+}
+
+func (n *faultyNotifier) Notify(ctx context.Context, event notify.Event) error {
+	if err := delay(ctx, n.settings); err != nil {
+		return err
+	}
+	if hit(n.settings.NotifyFailureRate) {
+		return ErrNotify
+	}
+	return n.Notifier.Notify(ctx, event)
+}
+
+// Accepts keeps the event filtering of the wrapped notifier
+func (n *faultyNotifier) Accepts(event notify.Event) bool {
+	return notify.Accepts(n.Notifier, event)
+}
+
+// hit reports whether a fault with the given rate occurs
+func hit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+// delay waits for the slow delay at the slow rate, returning the context's
+// error if it ends first
+func delay(ctx context.Context, settings Settings) error {
+	if !hit(settings.SlowRate) {
+		return nil
+	}
+
+	timer := time.NewTimer(settings.SlowDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultProductName is the name built-in templates sign messages with
+const DefaultProductName = "Public IP Monitor"
+
+var (
+	brandingMu sync.RWMutex
+	branding   = BrandingConfig{ProductName: DefaultProductName, Signature: DefaultProductName}
+)
+
+// SetBranding sets the product name, signature and emoji usage of all
+// built-in templates
+func SetBranding(b BrandingConfig) {
+	if b.ProductName == "" {
+		b.ProductName = DefaultProductName
+	}
+	if b.Signature == "" {
+		b.Signature = b.ProductName
+	}
+	b.NoEmojiChannels = append([]string(nil), b.NoEmojiChannels...)
+
+	brandingMu.Lock()
+	defer brandingMu.Unlock()
+	branding = b
+}
+
+// currentBranding returns the branding in effect
+func currentBranding() BrandingConfig {
+	brandingMu.RLock()
+	defer brandingMu.RUnlock()
+	return branding
+}
+
+// ProductName returns the configured product name
+func ProductName() string {
+	return currentBranding().ProductName
+}
+
+// ReferenceEnabled reports whether messages show the ID of their event
+func ReferenceEnabled() bool {
+	return !currentBranding().NoReference
+}
+
+// signature returns the line messages are signed with
+func signature() string {
+	return currentBranding().Signature
+}
+
+// subjectSuffix returns the product name as appended to subject lines
+func subjectSuffix() string {
+	return " - " + ProductName()
+}
+
+// EmojiEnabled reports whether messages for the named channel may contain
+// emoji, which needs the channel to display them and the branding to allow
+// them
+func EmojiEnabled(channel string) bool {
+	b := currentBranding()
+	if b.NoEmoji || !Capabilities(channel).Emoji {
+		return false
+	}
+	for _, name := range b.NoEmojiChannels {
+		if strings.EqualFold(name, channel) {
+			return false
+		}
+	}
+	return true
+}
+
+// ChannelText returns text as it should be sent on the named channel, with
+// emoji removed when they are disabled for it and shortened to its limit
+func ChannelText(channel, text string) string {
+	if !EmojiEnabled(channel) {
+		text = StripEmoji(text)
+	}
+	return fitText(text, Capabilities(channel))
+}
+
+// ChannelHTML returns the HTML body of a message as it should be sent on
+// the named channel, or "" when the channel renders no HTML or the body is
+// over its limit, since markup cannot be cut safely; the plain text is then
+// sent alone
+func ChannelHTML(channel, html string) string {
+	capabilities := Capabilities(channel)
+	if !capabilities.HTML || (capabilities.MaxLength > 0 && len(html) > capabilities.MaxLength) {
+		return ""
+	}
+	if !EmojiEnabled(channel) {
+		html = StripEmoji(html)
+	}
+	return html
+}
+
+// ChannelCard returns card as it should be sent on the named channel
+func ChannelCard(channel string, card Card) Card {
+	if EmojiEnabled(channel) {
+		return card
+	}
+
+	plain := Card{Title: StripEmoji(card.Title), Color: card.Color, Footer: StripEmoji(card.Footer)}
+	for _, field := range card.Fields {
+		field.Name = StripEmoji(field.Name)
+		field.Value = StripEmoji(field.Value)
+		plain.Fields = append(plain.Fields, field)
+	}
+	return plain
+}
+
+// slackEmoji matches the Slack shortcodes used by the built-in templates
+var slackEmoji = regexp.MustCompile(`:(rotating_light|warning|white_check_mark|large_green_circle|red_circle|heartpulse):[ \t]?`)
+
+// StripEmoji removes emoji, and the space following each, from text
+func StripEmoji(text string) string {
+	text = slackEmoji.ReplaceAllString(text, "")
+
+	var out strings.Builder
+	skipSpace := false
+	for _, r := range text {
+		if isEmoji(r) {
+			skipSpace = true
+			continue
+		}
+		if skipSpace && r == ' ' {
+			skipSpace = false
+			continue
+		}
+		skipSpace = false
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// isEmoji reports whether r is an emoji or an emoji modifier
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // Pictographs, emoticons, transport, flags
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // Miscellaneous symbols and dingbats
+		return true
+	case r == 0x2B50 || r == 0x2B55: // Star and circle
+		return true
+	case r == 0xFE0F || r == 0x200D || r == 0x20E3: // Variation selector, joiner, keycap
+		return true
+	}
+	return false
+}
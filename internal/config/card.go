@@ -0,0 +1,48 @@
+package config
+
+// Card is a structured notification for channels that render titled
+// messages with labelled fields (embeds, cards) instead of plain text
+type Card struct {
+	Title  string
+	Color  int // RGB accent color
+	Fields []CardField
+	Footer string
+}
+
+// CardField is a labelled value shown on a card
+type CardField struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+const (
+	CardColorChange  = 0xE74C3C // Red
+	CardColorCatchUp = 0xE67E22 // Orange
+	CardColorWarning = 0xF1C40F // Yellow
+	CardColorHealthy = 0x2ECC71 // Green
+	CardColorStopped = 0x95A5A6 // Gray
+)
+
+// buildGatewayCardFields describes the default gateway, if known
+func buildGatewayCardFields(gateway *GatewayContext) []CardField {
+	if gateway == nil {
+		return nil
+	}
+
+	fields := []CardField{{Name: "Gateway", Value: gateway.Current()}}
+	if gateway.Changed {
+		fields = append(fields, CardField{Name: "⚠️ Gateway Changed From", Value: gateway.Previous()})
+	}
+	return fields
+}
+
+// truncateText shortens text to at most limit runes, keeping the end since
+// command output usually has the relevant error last
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return "…" + string(runes[len(runes)-limit+1:])
+}
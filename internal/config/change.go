@@ -0,0 +1,297 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// IPChange describes an address change for a single IP family
+type IPChange struct {
+	Family string // e.g., "IPv4", "IPv6" or "IP" when not family specific
+	OldIP  string
+	NewIP  string
+
+	// Catch-up details, set when the change is reported on startup
+	Missed     bool          // The change happened while the monitor was not running
+	LastChange time.Time     // When OldIP was recorded
+	DNSRecord  string        // Hostname expected to point at the public IP
+	DNSIPs     []string      // What DNSRecord resolved to at startup
+	DNSError   string        // Why DNSRecord could not be resolved; empty when it was
+	Outage     *CheckFailure // Failed checks before the network came back, when it was down on startup
+
+	// WAN details, set when WAN profiles are configured
+	WAN        string // WAN profile the change was observed on; empty for the default route
+	FailoverTo string // Backup WAN the default route now leaves through
+	RestoredTo string // Primary WAN the default route is back on after a failover
+
+	// Network details, set when enrichment is enabled
+	ASN        string // e.g. "AS16509"
+	ASName     string
+	HostedExit string // Why NewIP looks like a hosting or VPN exit rather than the ISP; empty otherwise
+	ExitIP     string // Address HostedExit is about when it is not NewIP, see Merge
+
+	// Network OldIP was announced by, set when it differs from ASN and ASName
+	PreviousASN    string
+	PreviousASName string
+
+	// How the change is part of unusually frequent changes, e.g. "IPv4
+	// changed 9 times in 24h, usually 0.5 times", set when anomaly is enabled
+	UnusualActivity string
+
+	// Where NewIP is when outside the expected countries, e.g. "198.51.100.7
+	// is located in FR, expected DE", set when enrichment.expected_countries
+	// is configured
+	OutsideCountry string
+
+	// Why NewIP is unexpected, e.g. "198.51.100.7 is not in 203.0.113.0/24",
+	// set when ip.expected_ranges is configured
+	UnexpectedIP string
+
+	// Geolocation of NewIP, set when enrichment.geolocation is enabled
+	Country string // ISO 3166 code, e.g. "DE"
+	Region  string
+	City    string
+
+	// Latest addresses of the history, newest (NewIP) first, set when a
+	// channel lists them
+	Recent []HeldIP
+}
+
+// HeldIP is an address of the history and how long it was kept
+type HeldIP struct {
+	IP    string
+	Since time.Time
+	Held  time.Duration // Until the next address; zero for the newest
+}
+
+// WithRecent returns the change listing at most n addresses besides NewIP
+// of its history, or none for n <= 0
+func (c IPChange) WithRecent(n int) IPChange {
+	c.Recent = c.Recent[:min(max(n+1, 0), len(c.Recent))]
+	if len(c.Recent) < 2 {
+		c.Recent = nil
+	}
+	return c
+}
+
+// Label returns the family, qualified by the WAN profile when set
+func (c IPChange) Label() string {
+	if c.WAN == "" {
+		return c.Family
+	}
+	return fmt.Sprintf("%s (%s)", c.Family, c.WAN)
+}
+
+// Plain reports whether the change carries nothing beyond the old and new IP,
+// so the single-change message templates can describe it
+func (c IPChange) Plain() bool {
+	return c.WAN == "" && len(c.Warnings()) == 0 && c.Location() == ""
+}
+
+// Merge combines the change with a later change of the same family, e.g.
+// when several are sent as one notification. The result goes from OldIP to
+// the later NewIP, with the later network, location and history, and the
+// catch-up details of c. No warning is lost: one raised by either change
+// is kept, the later one when both raised it, so an address that looked
+// wrong stays reported even when the next one is fine. The network note
+// compares the original OldIP with the later NewIP.
+func (c IPChange) Merge(later IPChange) IPChange {
+	merged := c
+	merged.NewIP = later.NewIP
+	merged.ASN, merged.ASName = later.ASN, later.ASName
+	merged.Country, merged.Region, merged.City = later.Country, later.Region, later.City
+	merged.Recent = later.Recent
+
+	if later.WANEvent() != "" {
+		merged.FailoverTo, merged.RestoredTo = later.FailoverTo, later.RestoredTo
+	}
+	merged.HostedExit, merged.ExitIP = later.HostedExit, later.ExitIP
+	if later.HostedExit == "" && c.HostedExit != "" {
+		merged.ExitIP, merged.HostedExit = c.exit()
+	}
+	if merged.PreviousASN == "" {
+		merged.PreviousASN, merged.PreviousASName = later.PreviousASN, later.PreviousASName
+	}
+	if merged.PreviousASN == merged.ASN && strings.EqualFold(merged.PreviousASName, merged.ASName) {
+		merged.PreviousASN, merged.PreviousASName = "", ""
+	}
+	if later.UnusualActivity != "" {
+		merged.UnusualActivity = later.UnusualActivity
+	}
+	if later.OutsideCountry != "" {
+		merged.OutsideCountry = later.OutsideCountry
+	}
+	if later.UnexpectedIP != "" {
+		merged.UnexpectedIP = later.UnexpectedIP
+	}
+	return merged
+}
+
+// WANEvent describes a failover to or from a backup WAN, if any
+func (c IPChange) WANEvent() string {
+	switch {
+	case c.FailoverTo != "":
+		return fmt.Sprintf("Failover to backup WAN %s detected", c.FailoverTo)
+	case c.RestoredTo != "":
+		return fmt.Sprintf("Back on primary WAN %s", c.RestoredTo)
+	}
+	return ""
+}
+
+// ExitNote warns that the new IP belongs to a hosting or VPN provider, if so
+func (c IPChange) ExitNote() string {
+	if c.HostedExit == "" {
+		return ""
+	}
+	address, reason := c.exit()
+	return fmt.Sprintf("%s belongs to a hosting or VPN provider (%s), traffic may leave through a VPN or proxy",
+		address, reason)
+}
+
+// exit returns the address that looks like a hosting or VPN exit and why,
+// qualified by its network
+func (c IPChange) exit() (address, reason string) {
+	if c.ExitIP != "" {
+		return c.ExitIP, c.HostedExit
+	}
+	if c.ASName != "" {
+		return c.NewIP, fmt.Sprintf("%s %s: %s", c.ASN, c.ASName, c.HostedExit)
+	}
+	return c.NewIP, c.HostedExit
+}
+
+// NetworkNote warns that NewIP belongs to another network than OldIP, if so
+func (c IPChange) NetworkNote() string {
+	if c.PreviousASN == "" {
+		return ""
+	}
+	return fmt.Sprintf("Network changed from %s to %s, the connection may have failed over to another line or be redirected",
+		strings.TrimSpace(c.PreviousASN+" "+c.PreviousASName), strings.TrimSpace(c.ASN+" "+c.ASName))
+}
+
+// ActivityNote warns that the IP changes far more often than usual, if so
+func (c IPChange) ActivityNote() string {
+	if c.UnusualActivity == "" {
+		return ""
+	}
+	return fmt.Sprintf("Unusual activity: %s, possibly a line fault or an upstream issue", c.UnusualActivity)
+}
+
+// GeofenceNote warns that NewIP is located outside the expected countries,
+// if so
+func (c IPChange) GeofenceNote() string {
+	if c.OutsideCountry == "" {
+		return ""
+	}
+	return fmt.Sprintf("Outside the expected countries: %s, a VPN may be misconfigured or traffic hijacked", c.OutsideCountry)
+}
+
+// RangeNote warns that NewIP is outside the expected ranges, if so
+func (c IPChange) RangeNote() string {
+	if c.UnexpectedIP == "" {
+		return ""
+	}
+	return fmt.Sprintf("Unexpected IP: %s, the ISP may have renumbered the line or traffic is redirected", c.UnexpectedIP)
+}
+
+// Warnings returns the WAN event, network, exit, activity, geofence and
+// range notes of the change, if any
+func (c IPChange) Warnings() []string {
+	var warnings []string
+	for _, warning := range []string{c.WANEvent(), c.NetworkNote(), c.ExitNote(), c.ActivityNote(), c.GeofenceNote(), c.RangeNote()} {
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
+}
+
+// Location describes where NewIP is geolocated and the network announcing
+// it, e.g. "Berlin, DE (AS3209 Vodafone GmbH)", or "" when not geolocated
+func (c IPChange) Location() string {
+	var parts []string
+	for _, part := range []string{c.City, c.Region, c.Country} {
+		if part != "" && !slices.Contains(parts, part) {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	location := strings.Join(parts, ", ")
+	if c.ASN != "" {
+		location += " (" + strings.TrimSpace(c.ASN+" "+c.ASName) + ")"
+	}
+	return location
+}
+
+// OutageNote describes the failed checks the catch-up had to wait out, if any
+func (c IPChange) OutageNote() string {
+	if c.Outage == nil {
+		return ""
+	}
+	return fmt.Sprintf("Network restored after %d failed checks since %s",
+		c.Outage.Count, c.Outage.Since.Format("2006-01-02 15:04:05"))
+}
+
+// DNSStale reports whether the DNS record did not point at the new IP. A
+// record that could not be resolved is not known to be stale.
+func (c IPChange) DNSStale() bool {
+	if c.DNSRecord == "" || c.DNSError != "" {
+		return false
+	}
+	for _, ip := range c.DNSIPs {
+		if ip == c.NewIP {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckFailure describes consecutive failed checks of a single IP family
+type CheckFailure struct {
+	Family string // e.g., "IPv4", "IPv6" or "IP" when not family specific
+	WAN    string // Empty for the default route
+	Count  int    // Consecutive failed checks
+	Since  time.Time
+	Error  string // Most recent error
+}
+
+// Label returns the family, qualified by the WAN profile when set
+func (f CheckFailure) Label() string {
+	return IPChange{Family: f.Family, WAN: f.WAN}.Label()
+}
+
+// GatewayContext describes the default gateway when a notification was sent
+type GatewayContext struct {
+	Interface   string
+	IP          string
+	MAC         string
+	Changed     bool // The gateway changed around the time of the notification
+	PreviousIP  string
+	PreviousMAC string
+}
+
+// Current renders the current gateway, e.g. "192.168.1.1 (aa:bb:cc:dd:ee:ff) on eth0"
+func (g *GatewayContext) Current() string {
+	return formatGateway(g.IP, g.MAC, g.Interface)
+}
+
+// Previous renders the gateway that was in use before the change
+func (g *GatewayContext) Previous() string {
+	return formatGateway(g.PreviousIP, g.PreviousMAC, "")
+}
+
+// formatGateway renders a gateway address with its MAC and interface, when known
+func formatGateway(ip, mac, iface string) string {
+	text := ip
+	if mac != "" {
+		text += " (" + mac + ")"
+	}
+	if iface != "" {
+		text += " on " + iface
+	}
+	return text
+}
@@ -0,0 +1,105 @@
+package config
+
+import "testing"
+
+func TestIPChangeDNSStale(t *testing.T) {
+	tests := []struct {
+		name   string
+		change IPChange
+		want   bool
+	}{
+		{"no record", IPChange{NewIP: "198.51.100.7"}, false},
+		{"up to date", IPChange{NewIP: "198.51.100.7", DNSRecord: "home.example.com", DNSIPs: []string{"198.51.100.7"}}, false},
+		{"stale", IPChange{NewIP: "198.51.100.7", DNSRecord: "home.example.com", DNSIPs: []string{"203.0.113.1"}}, true},
+		{"no addresses", IPChange{NewIP: "198.51.100.7", DNSRecord: "home.example.com"}, true},
+		{"lookup failed", IPChange{NewIP: "198.51.100.7", DNSRecord: "home.example.com", DNSError: "no such host"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.change.DNSStale(); got != tt.want {
+			t.Errorf("%s: DNSStale() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIPChangeMergeKeepsWarnings(t *testing.T) {
+	first := IPChange{
+		Family:         "IPv4",
+		OldIP:          "203.0.113.1",
+		NewIP:          "198.51.100.7",
+		ASN:            "AS64500",
+		ASName:         "Example Hosting",
+		HostedExit:     "hosting network",
+		PreviousASN:    "AS3209",
+		PreviousASName: "Vodafone GmbH",
+		OutsideCountry: "198.51.100.7 is located in FR, expected DE",
+		UnexpectedIP:   "198.51.100.7 is not in 203.0.113.0/24",
+		Country:        "FR",
+	}
+	later := IPChange{
+		Family:          "IPv4",
+		OldIP:           "198.51.100.7",
+		NewIP:           "203.0.113.9",
+		ASN:             "AS3209",
+		ASName:          "Vodafone GmbH",
+		PreviousASN:     "AS64500",
+		PreviousASName:  "Example Hosting",
+		UnusualActivity: "IPv4 changed 9 times in 24h, usually 0.5 times",
+		Country:         "DE",
+	}
+
+	merged := first.Merge(later)
+	if merged.OldIP != "203.0.113.1" || merged.NewIP != "203.0.113.9" {
+		t.Errorf("merged %s -> %s, want 203.0.113.1 -> 203.0.113.9", merged.OldIP, merged.NewIP)
+	}
+	if merged.ASN != "AS3209" || merged.Country != "DE" {
+		t.Errorf("merged network %s in %s, want the later AS3209 in DE", merged.ASN, merged.Country)
+	}
+	// Back on the original network: nothing changed from OldIP's
+	if merged.NetworkNote() != "" {
+		t.Errorf("NetworkNote() = %q, want none", merged.NetworkNote())
+	}
+	want := []string{
+		"198.51.100.7 belongs to a hosting or VPN provider (AS64500 Example Hosting: hosting network), traffic may leave through a VPN or proxy",
+		"Unusual activity: IPv4 changed 9 times in 24h, usually 0.5 times, possibly a line fault or an upstream issue",
+		"Outside the expected countries: 198.51.100.7 is located in FR, expected DE, a VPN may be misconfigured or traffic hijacked",
+		"Unexpected IP: 198.51.100.7 is not in 203.0.113.0/24, the ISP may have renumbered the line or traffic is redirected",
+	}
+	got := merged.Warnings()
+	if len(got) != len(want) {
+		t.Fatalf("Warnings() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("warning %d = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+	if merged.Plain() {
+		t.Error("Plain() = true for a change with warnings")
+	}
+
+	// Merged again with a plain change, the warnings are still kept
+	again := merged.Merge(IPChange{Family: "IPv4", OldIP: "203.0.113.9", NewIP: "203.0.113.10"})
+	if len(again.Warnings()) != len(want) || again.Warnings()[0] != want[0] {
+		t.Errorf("Warnings() after another merge = %q, want %q", again.Warnings(), want)
+	}
+}
+
+func TestIPChangePlain(t *testing.T) {
+	if change := (IPChange{Family: "IPv4", OldIP: "203.0.113.1", NewIP: "198.51.100.7"}); !change.Plain() {
+		t.Error("Plain() = false for a change of IP only")
+	}
+	for _, change := range []IPChange{
+		{NewIP: "198.51.100.7", WAN: "lte"},
+		{NewIP: "198.51.100.7", FailoverTo: "lte"},
+		{NewIP: "198.51.100.7", HostedExit: "hosting network"},
+		{NewIP: "198.51.100.7", PreviousASN: "AS3209"},
+		{NewIP: "198.51.100.7", UnusualActivity: "IPv4 changed 9 times in 24h"},
+		{NewIP: "198.51.100.7", OutsideCountry: "198.51.100.7 is located in FR"},
+		{NewIP: "198.51.100.7", UnexpectedIP: "198.51.100.7 is not in 203.0.113.0/24"},
+		{NewIP: "198.51.100.7", Country: "DE"},
+	} {
+		if change.Plain() {
+			t.Errorf("Plain() = true for %+v", change)
+		}
+	}
+}
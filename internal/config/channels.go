@@ -0,0 +1,134 @@
+package config
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ChannelCapabilities describes what the messages of a channel can hold
+type ChannelCapabilities struct {
+	MaxLength int  // Longest message in bytes; 0 for no limit
+	Markdown  bool // Renders markdown, e.g. *bold* and ``` code blocks
+	HTML      bool // Renders an HTML body besides the plain text
+	Emoji     bool // Displays emoji rather than mangling them
+}
+
+// channelCapabilities are the built-in capabilities by channel name in
+// lower case, as documented by each service. SMS is the text SNS sends to
+// phone numbers, a single segment unless message_limits allows more.
+var channelCapabilities = map[string]ChannelCapabilities{
+	"email":     {Emoji: true},
+	"slack":     {MaxLength: 40000, Markdown: true, Emoji: true},
+	"matrix":    {MaxLength: 30000, HTML: true, Emoji: true},
+	"whatsapp":  {MaxLength: 4096, Markdown: true, Emoji: true},
+	"line":      {MaxLength: 5000, Emoji: true},
+	"dingtalk":  {MaxLength: 20000, Markdown: true, Emoji: true},
+	"wecom":     {MaxLength: 2048, Emoji: true},
+	"ntfy":      {MaxLength: 4096, Emoji: true},
+	"pagerduty": {MaxLength: 1024, Emoji: true},
+	"sns":       {Emoji: true},
+	"sms":       {MaxLength: 160},
+	"webhook":   {Emoji: true},
+}
+
+// truncationMarker replaces what was cut from a message; plain ASCII, as
+// "…" would switch SMS to a costlier encoding
+const truncationMarker = "..."
+
+var (
+	limitsMu      sync.RWMutex
+	messageLimits map[string]int // By channel name in lower case
+)
+
+// SetMessageLimits sets the longest message of channels by name,
+// replacing their built-in limit; 0 removes the limit
+func SetMessageLimits(limits map[string]int) {
+	lower := make(map[string]int, len(limits))
+	for name, limit := range limits {
+		lower[strings.ToLower(name)] = limit
+	}
+
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	messageLimits = lower
+}
+
+// Capabilities returns what messages of the named channel can hold.
+// Channels without built-in capabilities, such as plugins, are assumed to
+// take any plain text.
+func Capabilities(channel string) ChannelCapabilities {
+	name := strings.ToLower(channel)
+	capabilities, ok := channelCapabilities[name]
+	if !ok {
+		capabilities = ChannelCapabilities{Emoji: true}
+	}
+
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	if limit, ok := messageLimits[name]; ok {
+		capabilities.MaxLength = limit
+	}
+	return capabilities
+}
+
+// fitText shortens text to the channel's limit. Whole lines are dropped
+// from the end, except for the last one, which holds the event reference or
+// the signature, and a "..." line marks the cut. Text that is a single line,
+// or too long to spare room for the last line, is cut short instead. An
+// open markdown code block is closed again.
+func fitText(text string, capabilities ChannelCapabilities) string {
+	limit := capabilities.MaxLength
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+
+	fence := ""
+	if capabilities.Markdown {
+		fence = "\n```"
+	}
+	lines := strings.Split(text, "\n")
+	last := lines[len(lines)-1]
+	budget := limit - len(last) - len(truncationMarker) - len(fence) - 2
+	if len(lines) == 1 || budget < limit/2 {
+		return closeFence(cutText(text, limit-len(truncationMarker)-len(fence))+truncationMarker, fence)
+	}
+
+	kept := lines[:0:0]
+	size := 0
+	for _, line := range lines[:len(lines)-1] {
+		if size+len(line)+1 > budget {
+			break
+		}
+		kept = append(kept, line)
+		size += len(line) + 1
+	}
+	if len(kept) == 0 {
+		kept = append(kept, cutText(lines[0], budget-1))
+	}
+	body := strings.TrimRight(strings.Join(kept, "\n"), "\n")
+	return closeFence(body, fence) + "\n" + truncationMarker + "\n" + last
+}
+
+// cutText returns the longest start of text within size bytes that does
+// not split a character
+func cutText(text string, size int) string {
+	if size <= 0 {
+		return ""
+	}
+	if len(text) <= size {
+		return text
+	}
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	return text[:size]
+}
+
+// closeFence appends fence when text leaves a markdown code block open
+func closeFence(text, fence string) string {
+	if fence != "" && strings.Count(text, "```")%2 == 1 {
+		return text + fence
+	}
+	return text
+}
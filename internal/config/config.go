@@ -3,9 +3,16 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
+
+	"public-ip-monitor/internal/proxy"
+	"public-ip-monitor/internal/scheduler"
 )
 
 const (
@@ -13,9 +20,38 @@ const (
 	ConfigFilePerm    = 0644
 )
 
+// Names of the scheduled tasks that can be configured in "schedules"
+const (
+	ScheduleServicesIndex = "services_index"
+	ScheduleResourceUsage = "resource_usage"
+	ScheduleRetention     = "retention"
+	ScheduleHeartbeat     = "heartbeat"
+	ScheduleFlush         = "flush"
+	ScheduleUpdate        = "update"
+	ScheduleGeoIP         = "geoip"
+	ScheduleEscalation    = "escalation"
+	ScheduleRelease       = "release"
+	ScheduleWebhookOutbox = "webhook_outbox"
+)
+
+// scheduleNames lists every configurable scheduled task
+var scheduleNames = []string{
+	ScheduleServicesIndex,
+	ScheduleResourceUsage,
+	ScheduleRetention,
+	ScheduleHeartbeat,
+	ScheduleFlush,
+	ScheduleUpdate,
+	ScheduleGeoIP,
+	ScheduleEscalation,
+	ScheduleRelease,
+	ScheduleWebhookOutbox,
+}
+
 // Manager handles configuration loading and saving
 type Manager struct {
 	configPath string
+	envOnly    bool // Configured by PIM_* environment variables alone, without a file
 }
 
 // NewManager creates a new configuration manager
@@ -28,8 +64,26 @@ func NewManager(configPath string) *Manager {
 	}
 }
 
-// Load loads configuration from a file
+// NewEnvManager creates a configuration manager reading the PIM_*
+// environment variables only, for containers run without a config file
+func NewEnvManager() *Manager {
+	return &Manager{envOnly: true}
+}
+
+// Load loads configuration from a file. PIM_* environment variables
+// override the values of the file.
 func (m *Manager) Load() (*Config, error) {
+	if m.envOnly {
+		config, err := m.Read()
+		if err != nil {
+			return nil, err
+		}
+		if err := validateConfig(config); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+		return config, nil
+	}
+
 	// Check if the config file exists
 	if _, err := os.Stat(m.configPath); os.IsNotExist(err) {
 		// Create default config
@@ -41,24 +95,51 @@ func (m *Manager) Load() (*Config, error) {
 	}
 
 	// Read existing config
+	config, err := m.Read()
+	if err != nil {
+		return nil, err
+	}
+	if err := applyEnv(config, os.Environ()); err != nil {
+		return nil, err
+	}
+
+	// Validate and set defaults
+	if err := validateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// Read parses the configuration file as written, without validation and
+// defaults. Comments (// and /* */) are allowed.
+func (m *Manager) Read() (*Config, error) {
+	if m.envOnly {
+		var config Config
+		if err := applyEnv(&config, os.Environ()); err != nil {
+			return nil, err
+		}
+		return &config, nil
+	}
+
 	data, err := os.ReadFile(m.configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
 	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	if err := json.Unmarshal(stripComments(data), &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	// Validate and set defaults
-	if err := validateConfig(&config); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
-	}
-
 	return &config, nil
 }
 
+// Defaults returns the configuration written on first run
+func (m *Manager) Defaults() *Config {
+	return m.createDefaultConfig()
+}
+
 // Save saves configuration to file
 func (m *Manager) Save(config *Config) error {
 	// Ensure directory exists
@@ -79,17 +160,146 @@ func (m *Manager) Save(config *Config) error {
 	return nil
 }
 
+// Update changes the configuration file as written, e.g. settings changed
+// at runtime, leaving defaults and environment overrides out of it.
+// Comments in the file are not kept.
+func (m *Manager) Update(change func(config *Config)) error {
+	if m.envOnly {
+		return fmt.Errorf("configured by environment variables, there is no file to update")
+	}
+	config, err := m.Read()
+	if err != nil {
+		return err
+	}
+	change(config)
+	return m.Save(config)
+}
+
 // GetCheckInterval returns the check interval as a duration
 func GetCheckInterval(config *Config) time.Duration {
 	return time.Duration(config.CheckIntervalSeconds) * time.Second
 }
 
+// GetFamilyMergeWindow returns the window in which changes of different
+// address families are combined into a single notification
+func GetFamilyMergeWindow(config *Config) time.Duration {
+	return time.Duration(config.IP.FamilyMergeWindowSeconds) * time.Second
+}
+
+// MaxRecentChanges caps recent_changes, keeping messages short enough to
+// read on a phone
+const MaxRecentChanges = 10
+
+// GetRecentChanges returns the most earlier addresses an enabled channel
+// lists in change messages, so that the history is only read when needed
+func GetRecentChanges(config *Config) int {
+	n := 0
+	if config.WhatsApp.Enabled {
+		n = config.WhatsApp.RecentChanges
+	}
+	if config.Line.Enabled {
+		n = max(n, config.Line.RecentChanges)
+	}
+	return n
+}
+
+// GetStartupGrace returns how long to wait for the network on startup
+func GetStartupGrace(config *Config) time.Duration {
+	if config.IP.StartupGraceSeconds < 0 {
+		return 0
+	}
+	return time.Duration(config.IP.StartupGraceSeconds) * time.Second
+}
+
+// GetSchedule returns the schedule specification for a scheduled task,
+// falling back to the task's own interval setting
+func GetSchedule(config *Config, name string) string {
+	if spec := config.Schedules[name]; spec != "" {
+		return spec
+	}
+
+	switch name {
+	case ScheduleServicesIndex:
+		return fmt.Sprintf("@every %dm", config.IP.ServicesIndex.RefreshIntervalMinutes)
+	case ScheduleResourceUsage:
+		return "@hourly"
+	case ScheduleRetention:
+		return "@daily"
+	case ScheduleHeartbeat:
+		return "0 9 * * *"
+	case ScheduleFlush:
+		return fmt.Sprintf("@every %dm", config.LowWrite.FlushIntervalMinutes)
+	case ScheduleUpdate:
+		return "@daily"
+	case ScheduleGeoIP:
+		// The GeoLite2 databases are published on Tuesdays and Fridays
+		return "0 6 * * 3,6"
+	case ScheduleEscalation:
+		return "@every 15s"
+	case ScheduleRelease:
+		return "@every 1m"
+	case ScheduleWebhookOutbox:
+		return fmt.Sprintf("@every %ds", config.Webhook.Outbox.ReplaySeconds)
+	}
+	return ""
+}
+
+// GetRuntimeDir returns the directory of files written on every check:
+// the data directory, or a tmpfs-friendly one in low-write mode
+func GetRuntimeDir(config *Config) string {
+	switch {
+	case !config.LowWrite.Enabled:
+		return config.IP.DataDir
+	case config.LowWrite.RuntimeDir != "":
+		return config.LowWrite.RuntimeDir
+	}
+	return os.TempDir()
+}
+
+// GetHookTimeout returns the timeout for a hook command
+func GetHookTimeout(config *Config, hook HookCommand) time.Duration {
+	if hook.TimeoutSeconds > 0 {
+		return time.Duration(hook.TimeoutSeconds) * time.Second
+	}
+	return time.Duration(config.Hooks.TimeoutSeconds) * time.Second
+}
+
+// GetPluginTimeout returns the timeout for a notification plugin
+func GetPluginTimeout(config *Config, plugin PluginCommand) time.Duration {
+	if plugin.TimeoutSeconds > 0 {
+		return time.Duration(plugin.TimeoutSeconds) * time.Second
+	}
+	return time.Duration(config.Plugins.TimeoutSeconds) * time.Second
+}
+
 // validateConfig validates the configuration and sets defaults
 func validateConfig(c *Config) error {
+	if err := applyNotifyURLs(c); err != nil {
+		return err
+	}
+
 	if c.CheckIntervalSeconds <= 0 {
 		c.CheckIntervalSeconds = 300 // Default 5 minutes
 	}
 
+	if c.Site == "" {
+		c.Site, _ = os.Hostname()
+	}
+
+	if c.Branding.ProductName == "" {
+		c.Branding.ProductName = DefaultProductName
+	}
+
+	if c.Branding.Signature == "" {
+		c.Branding.Signature = c.Branding.ProductName
+	}
+
+	for channel, limit := range c.MessageLimits {
+		if limit < 0 {
+			return fmt.Errorf("message_limits.%s: limit must not be negative", channel)
+		}
+	}
+
 	if c.Logging.Timezone == "" {
 		c.Logging.Timezone = "UTC"
 	}
@@ -102,6 +312,16 @@ func validateConfig(c *Config) error {
 		c.Logging.Identifier = "PUBLIC-IP-MONITOR"
 	}
 
+	switch c.Privacy.Mode {
+	case "", "mask":
+	case "hash":
+		if c.Privacy.Salt == "" {
+			return fmt.Errorf("privacy.salt is required when privacy.mode is \"hash\"")
+		}
+	default:
+		return fmt.Errorf("privacy.mode: unknown mode %q (expected \"mask\" or \"hash\")", c.Privacy.Mode)
+	}
+
 	if c.WhatsApp.APIVersion == "" {
 		c.WhatsApp.APIVersion = "v17.0"
 	}
@@ -110,6 +330,14 @@ func validateConfig(c *Config) error {
 		c.WhatsApp.TimeoutSeconds = 30
 	}
 
+	if c.WhatsApp.RecentChanges < 0 || c.WhatsApp.RecentChanges > MaxRecentChanges {
+		return fmt.Errorf("whatsapp.recent_changes must be between 0 and %d", MaxRecentChanges)
+	}
+
+	if c.Email.FromName == "" {
+		c.Email.FromName = c.Branding.ProductName
+	}
+
 	if c.Email.SMTPPort == "" {
 		c.Email.SMTPPort = "587"
 	}
@@ -118,6 +346,266 @@ func validateConfig(c *Config) error {
 		c.Email.Timeout = 30
 	}
 
+	for i, relay := range c.Email.Relays {
+		if relay.SMTPHost == "" {
+			return fmt.Errorf("email.relays[%d]: smtp_host is required", i)
+		}
+		if relay.SMTPPort == "" {
+			c.Email.Relays[i].SMTPPort = "587"
+		}
+	}
+
+	switch c.Email.Backend {
+	case "":
+		c.Email.Backend = "smtp"
+	case "smtp":
+	case "gmail":
+		gmail := c.Email.Gmail
+		if c.Email.Enabled && (gmail.ClientID == "" || gmail.ClientSecret == "" || gmail.RefreshToken == "") {
+			return fmt.Errorf("email.gmail: client_id, client_secret and refresh_token are required with the gmail backend")
+		}
+	default:
+		return fmt.Errorf("email.backend must be smtp or gmail, got %q", c.Email.Backend)
+	}
+
+	if c.Slack.Enabled {
+		if c.Slack.WebhookURL == "" && c.Slack.Token == "" {
+			return fmt.Errorf("slack.webhook_url or slack.token is required when Slack is enabled")
+		}
+		if c.Slack.Token != "" && c.Slack.Channel == "" {
+			return fmt.Errorf("slack.channel is required when using slack.token")
+		}
+	}
+
+	if c.Slack.TimeoutSeconds <= 0 {
+		c.Slack.TimeoutSeconds = 30
+	}
+
+	if c.Discord.Enabled && c.Discord.WebhookURL == "" {
+		return fmt.Errorf("discord.webhook_url is required when Discord is enabled")
+	}
+
+	if c.Discord.TimeoutSeconds <= 0 {
+		c.Discord.TimeoutSeconds = 30
+	}
+
+	if c.Teams.Enabled && c.Teams.WebhookURL == "" {
+		return fmt.Errorf("teams.webhook_url is required when Teams is enabled")
+	}
+
+	if c.Teams.TimeoutSeconds <= 0 {
+		c.Teams.TimeoutSeconds = 30
+	}
+
+	if c.Sheets.Enabled && (c.Sheets.CredentialsFile == "" || c.Sheets.SpreadsheetID == "") {
+		return fmt.Errorf("google_sheets.credentials_file and google_sheets.spreadsheet_id are required when Google Sheets is enabled")
+	}
+
+	if c.Sheets.SheetName == "" {
+		c.Sheets.SheetName = "Sheet1"
+	}
+
+	if c.Sheets.TimeoutSeconds <= 0 {
+		c.Sheets.TimeoutSeconds = 30
+	}
+
+	if c.File.Enabled && c.File.Path == "" {
+		return fmt.Errorf("file.path is required when file output is enabled")
+	}
+
+	if c.Matrix.Enabled && (c.Matrix.HomeserverURL == "" || c.Matrix.AccessToken == "" || c.Matrix.RoomID == "") {
+		return fmt.Errorf("matrix.homeserver_url, matrix.access_token and matrix.room_id are required when Matrix is enabled")
+	}
+
+	if c.Matrix.TimeoutSeconds <= 0 {
+		c.Matrix.TimeoutSeconds = 30
+	}
+
+	if c.Ntfy.Enabled && c.Ntfy.Topic == "" {
+		return fmt.Errorf("ntfy.topic is required when ntfy is enabled")
+	}
+
+	if c.Ntfy.Server == "" {
+		c.Ntfy.Server = "https://ntfy.sh"
+	}
+
+	if c.Ntfy.Priority == "" {
+		c.Ntfy.Priority = "default"
+	}
+
+	if c.Ntfy.TimeoutSeconds <= 0 {
+		c.Ntfy.TimeoutSeconds = 30
+	}
+
+	if c.Line.Enabled && (c.Line.Token == "" || c.Line.To == "") {
+		return fmt.Errorf("line.token and line.to are required when LINE is enabled")
+	}
+
+	if c.Line.TimeoutSeconds <= 0 {
+		c.Line.TimeoutSeconds = 30
+	}
+
+	if c.Line.RecentChanges < 0 || c.Line.RecentChanges > MaxRecentChanges {
+		return fmt.Errorf("line.recent_changes must be between 0 and %d", MaxRecentChanges)
+	}
+
+	if c.DingTalk.Enabled && c.DingTalk.WebhookURL == "" {
+		return fmt.Errorf("dingtalk.webhook_url is required when DingTalk is enabled")
+	}
+
+	if c.DingTalk.TimeoutSeconds <= 0 {
+		c.DingTalk.TimeoutSeconds = 30
+	}
+
+	if c.WeCom.Enabled && c.WeCom.WebhookURL == "" {
+		return fmt.Errorf("wecom.webhook_url is required when WeCom is enabled")
+	}
+
+	if c.WeCom.TimeoutSeconds <= 0 {
+		c.WeCom.TimeoutSeconds = 30
+	}
+
+	if c.SNS.Enabled && c.SNS.TopicARN == "" {
+		return fmt.Errorf("sns.topic_arn is required when SNS is enabled")
+	}
+
+	if c.SNS.Enabled && c.SNS.AccessKeyID != "" && c.SNS.SecretAccessKey == "" {
+		return fmt.Errorf("sns.secret_access_key is required when sns.access_key_id is set")
+	}
+
+	if c.SNS.TimeoutSeconds <= 0 {
+		c.SNS.TimeoutSeconds = 30
+	}
+
+	if c.MQTT.Enabled && c.MQTT.Broker == "" {
+		return fmt.Errorf("mqtt.broker is required when MQTT is enabled")
+	}
+
+	if c.MQTT.QoS < 0 || c.MQTT.QoS > 2 {
+		return fmt.Errorf("mqtt.qos must be 0, 1 or 2")
+	}
+
+	if c.MQTT.ClientID == "" {
+		c.MQTT.ClientID = "public-ip-monitor-" + c.Site
+	}
+
+	if c.MQTT.Topic == "" {
+		c.MQTT.Topic = "public-ip-monitor/ip"
+	}
+
+	if c.MQTT.TimeoutSeconds <= 0 {
+		c.MQTT.TimeoutSeconds = 30
+	}
+
+	if c.Syslog.Enabled && c.Syslog.Address == "" {
+		return fmt.Errorf("syslog.address is required when syslog is enabled")
+	}
+
+	switch c.Syslog.Facility {
+	case "":
+		c.Syslog.Facility = "local0"
+	case "kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron", "authpriv", "ftp",
+		"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7":
+	default:
+		return fmt.Errorf("syslog.facility: unknown facility %q (e.g. local0, user or daemon)", c.Syslog.Facility)
+	}
+
+	if (c.Syslog.CertFile == "") != (c.Syslog.KeyFile == "") {
+		return fmt.Errorf("syslog.cert_file and syslog.key_file must be set together")
+	}
+
+	if c.Syslog.AppName == "" {
+		c.Syslog.AppName = "public-ip-monitor"
+	}
+
+	if c.Syslog.TimeoutSeconds <= 0 {
+		c.Syslog.TimeoutSeconds = 30
+	}
+
+	if c.PagerDuty.Enabled && c.PagerDuty.RoutingKey == "" {
+		return fmt.Errorf("pagerduty.routing_key is required when PagerDuty is enabled")
+	}
+
+	if c.PagerDuty.EventsURL == "" {
+		c.PagerDuty.EventsURL = "https://events.pagerduty.com/v2/enqueue"
+	}
+
+	severities := map[string]string{"info": "info", "warning": "warning", "critical": "critical"}
+	for severity, mapped := range c.PagerDuty.SeverityMap {
+		if _, ok := severities[severity]; !ok {
+			return fmt.Errorf("pagerduty.severity_map: unknown event severity %q (expected info, warning or critical)", severity)
+		}
+		if !slices.Contains([]string{"critical", "error", "warning", "info"}, mapped) {
+			return fmt.Errorf("pagerduty.severity_map.%s: invalid PagerDuty severity %q (expected critical, error, warning or info)", severity, mapped)
+		}
+		severities[severity] = mapped
+	}
+	c.PagerDuty.SeverityMap = severities
+
+	if c.PagerDuty.TimeoutSeconds <= 0 {
+		c.PagerDuty.TimeoutSeconds = 30
+	}
+
+	if c.Webhook.Enabled && len(c.Webhook.URLs) == 0 {
+		return fmt.Errorf("webhook.urls is required when webhooks are enabled")
+	}
+
+	if c.Webhook.Method == "" {
+		c.Webhook.Method = "POST"
+	}
+
+	if c.Webhook.TimeoutSeconds <= 0 {
+		c.Webhook.TimeoutSeconds = 30
+	}
+
+	if c.Webhook.Outbox.MaxAgeHours == 0 {
+		c.Webhook.Outbox.MaxAgeHours = 24
+	}
+
+	if c.Webhook.Outbox.ReplaySeconds <= 0 {
+		c.Webhook.Outbox.ReplaySeconds = 60
+	}
+
+	if c.Shortlink.Enabled {
+		if !slices.Contains([]string{"shlink", "kutt", "http"}, c.Shortlink.Provider) {
+			return fmt.Errorf("shortlink.provider: unknown provider %q (expected shlink, kutt or http)", c.Shortlink.Provider)
+		}
+		if c.Shortlink.APIURL == "" {
+			return fmt.Errorf("shortlink.api_url is required when the short link is enabled")
+		}
+		if c.Shortlink.ID == "" && c.Shortlink.Provider != "http" {
+			return fmt.Errorf("shortlink.id is required for %s", c.Shortlink.Provider)
+		}
+		if !strings.Contains(c.Shortlink.Target, "{ip}") {
+			return fmt.Errorf("shortlink.target must contain {ip}, e.g. \"http://{ip}:8080/\"")
+		}
+		family := strings.ToLower(strings.TrimSpace(c.Shortlink.Family))
+		if family != "" && family != "ipv4" && family != "ipv6" {
+			return fmt.Errorf("shortlink.family: unknown family %q (expected \"ipv4\" or \"ipv6\")", c.Shortlink.Family)
+		}
+		c.Shortlink.Family = family
+	}
+
+	if c.Shortlink.Method == "" {
+		c.Shortlink.Method = "PUT"
+	}
+
+	if c.Shortlink.MinIntervalSeconds <= 0 {
+		c.Shortlink.MinIntervalSeconds = 60
+	}
+
+	if c.Shortlink.TimeoutSeconds <= 0 {
+		c.Shortlink.TimeoutSeconds = 30
+	}
+
+	if c.Ping.Enabled && c.Ping.URL == "" {
+		return fmt.Errorf("ping.url is required when pings are enabled")
+	}
+
+	if c.Ping.TimeoutSeconds <= 0 {
+		c.Ping.TimeoutSeconds = 10
+	}
+
 	if c.IP.TimeoutSeconds <= 0 {
 		c.IP.TimeoutSeconds = 30
 	}
@@ -134,7 +622,351 @@ func validateConfig(c *Config) error {
 		c.IP.LastIPFile = "last_ip.txt"
 	}
 
-	if len(c.IP.Services) == 0 {
+	seenFamilies := make(map[string]bool)
+	for i, family := range c.IP.Families {
+		family = strings.ToLower(strings.TrimSpace(family))
+		if family != "ipv4" && family != "ipv6" {
+			return fmt.Errorf("ip.families: unknown family %q (expected \"ipv4\" or \"ipv6\")", c.IP.Families[i])
+		}
+		if seenFamilies[family] {
+			return fmt.Errorf("ip.families: %q listed more than once", family)
+		}
+		seenFamilies[family] = true
+		c.IP.Families[i] = family
+	}
+
+	for _, cidr := range c.IP.ExpectedRanges {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+			return fmt.Errorf("ip.expected_ranges: invalid range %q (expected e.g. 203.0.113.0/24)", cidr)
+		}
+	}
+
+	seenWANs := make(map[string]bool)
+	for _, wan := range c.IP.WANs {
+		if wan.Name == "" || wan.Name == "." || wan.Name == ".." || strings.ContainsAny(wan.Name, `/\`) {
+			return fmt.Errorf("ip.wans: invalid name %q", wan.Name)
+		}
+		if seenWANs[wan.Name] {
+			return fmt.Errorf("ip.wans: %q listed more than once", wan.Name)
+		}
+		seenWANs[wan.Name] = true
+		if wan.Interface == "" && len(wan.Services) == 0 {
+			return fmt.Errorf("ip.wans: %s needs an interface or services", wan.Name)
+		}
+	}
+
+	if c.IP.FamilyMergeWindowSeconds <= 0 {
+		c.IP.FamilyMergeWindowSeconds = 15
+	}
+
+	if c.IP.FailureThreshold <= 0 {
+		c.IP.FailureThreshold = 3
+	}
+
+	if c.IP.RecoveryThreshold <= 0 {
+		c.IP.RecoveryThreshold = 1
+	}
+
+	if c.IP.StartupGraceSeconds == 0 {
+		c.IP.StartupGraceSeconds = 120
+	}
+
+	if c.Resources.GOMAXPROCS < 0 || c.Resources.MemoryLimitMB < 0 || c.Resources.BallastMB < 0 {
+		return fmt.Errorf("resources: values must not be negative")
+	}
+
+	if c.Update.ManifestURL != "" && c.Update.PublicKey == "" {
+		return fmt.Errorf("update.public_key is required when update.manifest_url is set")
+	}
+
+	if c.Update.SystemdUnit == "" {
+		c.Update.SystemdUnit = "public-ip-monitor"
+	}
+
+	if c.Update.TimeoutSeconds <= 0 {
+		c.Update.TimeoutSeconds = 300
+	}
+
+	if c.LowWrite.FlushIntervalMinutes <= 0 {
+		c.LowWrite.FlushIntervalMinutes = 60
+	}
+
+	if c.API.Listen == "" {
+		c.API.Listen = ":8787"
+	}
+
+	if c.API.MaxWaitSeconds <= 0 {
+		c.API.MaxWaitSeconds = 300
+	}
+
+	if c.DNSCache.MaxTTLSeconds <= 0 {
+		c.DNSCache.MaxTTLSeconds = 3600
+	}
+
+	if c.DNSCache.NegativeTTLSeconds <= 0 {
+		c.DNSCache.NegativeTTLSeconds = 30
+	}
+
+	if c.DNSCache.StaleTTLSeconds <= 0 {
+		c.DNSCache.StaleTTLSeconds = 86400
+	}
+
+	if _, err := proxy.New(c.Proxy.URL, c.Proxy.NoProxy); err != nil {
+		return fmt.Errorf("proxy: %w", err)
+	}
+
+	for name, spec := range c.Schedules {
+		if !slices.Contains(scheduleNames, name) {
+			return fmt.Errorf("schedules: unknown task %q (expected one of %s)", name, strings.Join(scheduleNames, ", "))
+		}
+		if _, err := scheduler.Parse(spec); err != nil {
+			return fmt.Errorf("schedules.%s: %w", name, err)
+		}
+	}
+
+	if c.Hooks.TimeoutSeconds <= 0 {
+		c.Hooks.TimeoutSeconds = 30
+	}
+
+	if c.Hooks.OutputLimitBytes <= 0 {
+		c.Hooks.OutputLimitBytes = 4096
+	}
+
+	if err := validateChannelEvents(c); err != nil {
+		return err
+	}
+
+	// Moved to hooks.commands, so that the effective configuration can be
+	// loaded again without running them twice
+	c.Hooks.Commands = append(c.Hooks.Commands, c.OnChangeCommands...)
+	c.OnChangeCommands = nil
+
+	for i, hook := range c.Hooks.Commands {
+		if hook.Command == "" {
+			return fmt.Errorf("hooks.commands[%d]: command is required", i)
+		}
+		if hook.Name == "" {
+			c.Hooks.Commands[i].Name = filepath.Base(hook.Command)
+		}
+	}
+
+	for i, domain := range c.ACME.Domains {
+		if domain == "" || strings.ContainsAny(domain, " /:") {
+			return fmt.Errorf("acme.domains[%d]: invalid domain %q", i, domain)
+		}
+	}
+
+	if c.ACME.Command == "" {
+		c.ACME.Command = "certbot"
+		if len(c.ACME.Args) == 0 {
+			c.ACME.Args = []string{"renew", "--non-interactive"}
+		}
+	}
+
+	if c.ACME.DNSTimeoutSeconds <= 0 {
+		c.ACME.DNSTimeoutSeconds = 600
+	}
+
+	if c.ACME.TimeoutSeconds <= 0 {
+		c.ACME.TimeoutSeconds = 300
+	}
+
+	if c.Plugins.TimeoutSeconds <= 0 {
+		c.Plugins.TimeoutSeconds = 30
+	}
+
+	for i, plugin := range c.Plugins.Commands {
+		if plugin.Command == "" {
+			return fmt.Errorf("plugins.commands[%d]: command is required", i)
+		}
+		if plugin.Name == "" {
+			c.Plugins.Commands[i].Name = filepath.Base(plugin.Command)
+		}
+	}
+
+	if c.QuietHours.Enabled {
+		if _, err := time.Parse("15:04", c.QuietHours.Start); err != nil {
+			return fmt.Errorf("quiet_hours.start must be a time like 23:00, got %q", c.QuietHours.Start)
+		}
+		if _, err := time.Parse("15:04", c.QuietHours.End); err != nil {
+			return fmt.Errorf("quiet_hours.end must be a time like 07:00, got %q", c.QuietHours.End)
+		}
+		if c.QuietHours.Start == c.QuietHours.End {
+			return fmt.Errorf("quiet_hours.start and quiet_hours.end must differ")
+		}
+	}
+
+	if _, err := parseDigestTemplates(c.Digest); err != nil {
+		return err
+	}
+
+	if c.CircuitBreaker.FailureThreshold == 0 {
+		c.CircuitBreaker.FailureThreshold = 5
+	}
+
+	if c.CircuitBreaker.CooldownSeconds <= 0 {
+		c.CircuitBreaker.CooldownSeconds = 300
+	}
+
+	for channel, limit := range c.Quotas.Channels {
+		if limit <= 0 {
+			return fmt.Errorf("quotas.channels.%s must be positive", channel)
+		}
+	}
+
+	if c.Quotas.ReservePercent == 0 {
+		c.Quotas.ReservePercent = 10
+	}
+	if c.Quotas.ReservePercent >= 100 {
+		return fmt.Errorf("quotas.reserve_percent must be below 100")
+	}
+
+	for channel, target := range c.Quotas.Reroute {
+		if _, ok := c.Quotas.Channels[channel]; !ok {
+			return fmt.Errorf("quotas.reroute.%s: channel has no quota in quotas.channels", channel)
+		}
+		if strings.EqualFold(channel, target) {
+			return fmt.Errorf("quotas.reroute.%s must name another channel", channel)
+		}
+	}
+
+	if c.Escalation.Enabled && len(c.Escalation.Channels) == 0 {
+		return fmt.Errorf("escalation.channels is required when escalation is enabled")
+	}
+
+	for _, event := range c.Escalation.Events {
+		if !slices.Contains(EventTypes, event) {
+			return fmt.Errorf("escalation.events: unknown event type %q (available: %s)", event, strings.Join(EventTypes, ", "))
+		}
+	}
+
+	if c.Escalation.AfterMinutes <= 0 {
+		c.Escalation.AfterMinutes = 15
+	}
+
+	if c.IP.ServicesIndex.URL != "" && c.IP.ServicesIndex.PublicKey == "" {
+		return fmt.Errorf("ip.services_index.public_key is required when a services index URL is set")
+	}
+
+	if c.IP.ServicesIndex.RefreshIntervalMinutes <= 0 {
+		c.IP.ServicesIndex.RefreshIntervalMinutes = 360
+	}
+
+	if c.IP.ServicesIndex.CacheFile == "" {
+		c.IP.ServicesIndex.CacheFile = "services_index.json"
+	}
+
+	if c.CheckLog.File == "" {
+		c.CheckLog.File = "check_log.jsonl"
+	}
+
+	if c.CheckLog.MaxEntries <= 0 {
+		c.CheckLog.MaxEntries = 10000
+	}
+
+	if c.CheckLog.MaxAgeHours <= 0 {
+		c.CheckLog.MaxAgeHours = 168
+	}
+
+	if c.Retention.HistoryDays < 0 || c.Retention.FailedNotificationsDays < 0 {
+		return fmt.Errorf("retention days must not be negative")
+	}
+
+	for _, asn := range c.Enrichment.HostingASNs {
+		if asn <= 0 {
+			return fmt.Errorf("enrichment.hosting_asns: invalid ASN %d", asn)
+		}
+	}
+
+	if c.Enrichment.TimeoutSeconds <= 0 {
+		c.Enrichment.TimeoutSeconds = 5
+	}
+
+	for i, country := range c.Enrichment.ExpectedCountries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) != 2 || strings.Trim(country, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return fmt.Errorf("enrichment.expected_countries: invalid country code %q (expected e.g. DE)", c.Enrichment.ExpectedCountries[i])
+		}
+		c.Enrichment.ExpectedCountries[i] = country
+	}
+	if len(c.Enrichment.ExpectedCountries) > 0 && !c.Enrichment.Enabled {
+		return fmt.Errorf("enrichment.expected_countries needs enrichment.enabled")
+	}
+
+	if c.Anomaly.WindowHours <= 0 {
+		c.Anomaly.WindowHours = 24
+	}
+	if c.Anomaly.Factor <= 0 {
+		c.Anomaly.Factor = 4
+	}
+	if c.Anomaly.MinChanges <= 0 {
+		c.Anomaly.MinChanges = 3
+	}
+	if c.Anomaly.LearnDays <= 0 {
+		c.Anomaly.LearnDays = 30
+	}
+
+	switch geo := &c.Enrichment.Geolocation; geo.Provider {
+	case "", "ipinfo", "ip-api":
+	case "maxmind":
+		if geo.MaxMind.CityDatabase == "" {
+			geo.MaxMind.CityDatabase = "GeoLite2-City.mmdb"
+		}
+		if geo.MaxMind.ASNDatabase == "" {
+			geo.MaxMind.ASNDatabase = "GeoLite2-ASN.mmdb"
+		}
+		if (geo.MaxMind.AccountID == "") != (geo.MaxMind.LicenseKey == "") {
+			return fmt.Errorf("enrichment.geolocation.maxmind: account_id and license_key are required together")
+		}
+	default:
+		return fmt.Errorf("enrichment.geolocation.provider: unknown provider %q (expected ipinfo, ip-api or maxmind)", geo.Provider)
+	}
+
+	for i, source := range c.IP.Sources {
+		if source.Type == "" {
+			return fmt.Errorf("ip.sources[%d]: type is required", i)
+		}
+		if source.Proxy != "" && source.Type != "http" && source.Type != "router" {
+			return fmt.Errorf("ip.sources[%d]: proxy only applies to http and router sources", i)
+		}
+		if len(source.Headers) > 0 && source.Type != "http" && source.Type != "router" {
+			return fmt.Errorf("ip.sources[%d]: headers only apply to http and router sources", i)
+		}
+		for name := range source.Headers {
+			if !validHeaderName(name) {
+				return fmt.Errorf("ip.sources[%d]: invalid header name %q", i, name)
+			}
+		}
+	}
+
+	if strings.ContainsAny(c.IP.UserAgent, "\r\n") {
+		return fmt.Errorf("ip.user_agent must be a single line")
+	}
+	if (c.IP.TLS.CertFile == "") != (c.IP.TLS.KeyFile == "") {
+		return fmt.Errorf("ip.tls.cert_file and ip.tls.key_file must be set together")
+	}
+
+	if c.IP.DoH.URL != "" {
+		if u, err := url.Parse(c.IP.DoH.URL); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("ip.doh.url: expected an https:// URL, got %q", c.IP.DoH.URL)
+		}
+	}
+	for _, address := range c.IP.DoH.BootstrapIPs {
+		if net.ParseIP(address) == nil {
+			return fmt.Errorf("ip.doh.bootstrap_ips: invalid IP address %q", address)
+		}
+	}
+
+	for service, headers := range c.IP.ServiceHeaders {
+		for name := range headers {
+			if !validHeaderName(name) {
+				return fmt.Errorf("ip.service_headers[%s]: invalid header name %q", service, name)
+			}
+		}
+	}
+
+	// Sources may replace the services entirely
+	if len(c.IP.Services) == 0 && len(c.IP.Sources) == 0 {
 		c.IP.Services = []string{
 			"https://api.ipify.org",
 			"https://icanhazip.com",
@@ -142,6 +974,27 @@ func validateConfig(c *Config) error {
 		}
 	}
 
+	switch c.IP.FetchMode {
+	case "":
+		c.IP.FetchMode = "sequential"
+	case "sequential", "race":
+	default:
+		return fmt.Errorf("ip.fetch_mode must be sequential or race, got %q", c.IP.FetchMode)
+	}
+
+	if c.IP.Quorum < 0 {
+		return fmt.Errorf("ip.quorum must not be negative")
+	}
+	if sources := len(c.IP.Services) + len(c.IP.Sources); c.IP.Quorum > sources {
+		return fmt.Errorf("ip.quorum: %d exceeds the %d services and sources", c.IP.Quorum, sources)
+	}
+	for _, wan := range c.IP.WANs {
+		// WANs with their own services do not use the sources
+		if len(wan.Services) > 0 && c.IP.Quorum > len(wan.Services) {
+			return fmt.Errorf("ip.quorum: %d exceeds the %d services of WAN %s", c.IP.Quorum, len(wan.Services), wan.Name)
+		}
+	}
+
 	return nil
 }
 
@@ -149,11 +1002,23 @@ func validateConfig(c *Config) error {
 func (m *Manager) createDefaultConfig() *Config {
 	return &Config{
 		CheckIntervalSeconds: 300, // 5 minutes
+		Site:                 "",
+		DryRun:               false,
+		MutedChannels:        []string{},
+		MessageLimits:        map[string]int{},
+		Branding: BrandingConfig{
+			ProductName: DefaultProductName,
+			Signature:   DefaultProductName,
+		},
 		Logging: LoggingConfig{
 			Timezone:   "UTC",
 			Format:     "2006-01-02 15:04:05",
 			Identifier: "PUBLIC-IP-MONITOR",
 		},
+		Privacy: PrivacyConfig{
+			Mode: "",
+			Salt: "",
+		},
 		WhatsApp: WhatsAppConfig{
 			Enabled:         false,
 			Token:           "YOUR_WHATSAPP_TOKEN",
@@ -165,11 +1030,110 @@ func (m *Manager) createDefaultConfig() *Config {
 		Email: EmailConfig{
 			Enabled:  true,
 			From:     "your-email@gmail.com",
+			FromName: DefaultProductName,
 			Password: "your-app-password",
 			To:       "recipient@gmail.com",
 			SMTPHost: "smtp.gmail.com",
 			SMTPPort: "587",
 			Timeout:  30,
+			Backend:  "smtp",
+		},
+		Slack: SlackConfig{
+			Enabled:        false,
+			WebhookURL:     "YOUR_SLACK_WEBHOOK_URL",
+			TimeoutSeconds: 30,
+		},
+		Discord: DiscordConfig{
+			Enabled:        false,
+			WebhookURL:     "YOUR_DISCORD_WEBHOOK_URL",
+			TimeoutSeconds: 30,
+		},
+		Teams: TeamsConfig{
+			Enabled:        false,
+			WebhookURL:     "YOUR_TEAMS_WEBHOOK_URL",
+			TimeoutSeconds: 30,
+		},
+		Sheets: SheetsConfig{
+			Enabled:         false,
+			CredentialsFile: "service-account.json",
+			SpreadsheetID:   "YOUR_SPREADSHEET_ID",
+			SheetName:       "Sheet1",
+			TimeoutSeconds:  30,
+		},
+		File: FileConfig{
+			Enabled: false,
+			Path:    "data/notifications.log",
+		},
+		Matrix: MatrixConfig{
+			Enabled:        false,
+			HomeserverURL:  "https://matrix.example.org",
+			AccessToken:    "YOUR_MATRIX_ACCESS_TOKEN",
+			RoomID:         "YOUR_ROOM_ID",
+			TimeoutSeconds: 30,
+		},
+		Ntfy: NtfyConfig{
+			Enabled:        false,
+			Server:         "https://ntfy.sh",
+			Topic:          "YOUR_NTFY_TOPIC",
+			Priority:       "default",
+			TimeoutSeconds: 30,
+		},
+		Line: LineConfig{
+			Enabled:        false,
+			Token:          "YOUR_LINE_CHANNEL_ACCESS_TOKEN",
+			To:             "YOUR_LINE_USER_OR_GROUP_ID",
+			TimeoutSeconds: 30,
+		},
+		DingTalk: DingTalkConfig{
+			Enabled:        false,
+			WebhookURL:     "YOUR_DINGTALK_WEBHOOK_URL",
+			Secret:         "",
+			TimeoutSeconds: 30,
+		},
+		WeCom: WeComConfig{
+			Enabled:        false,
+			WebhookURL:     "YOUR_WECOM_WEBHOOK_URL",
+			TimeoutSeconds: 30,
+		},
+		SNS: SNSConfig{
+			Enabled:        false,
+			TopicARN:       "arn:aws:sns:us-east-1:123456789012:public-ip-monitor",
+			TimeoutSeconds: 30,
+		},
+		MQTT: MQTTConfig{
+			Enabled:        false,
+			Broker:         "mqtt://localhost:1883",
+			Topic:          "public-ip-monitor/ip",
+			EventTopic:     "public-ip-monitor/events",
+			QoS:            0,
+			TimeoutSeconds: 30,
+		},
+		Syslog: SyslogConfig{
+			Enabled:        false,
+			Address:        "syslog.example.com:6514",
+			Facility:       "local0",
+			AppName:        "public-ip-monitor",
+			TimeoutSeconds: 30,
+		},
+		PagerDuty: PagerDutyConfig{
+			Enabled:        false,
+			RoutingKey:     "YOUR_PAGERDUTY_ROUTING_KEY",
+			EventsURL:      "https://events.pagerduty.com/v2/enqueue",
+			SeverityMap:    map[string]string{"info": "info", "warning": "warning", "critical": "critical"},
+			AutoResolve:    false,
+			TimeoutSeconds: 30,
+		},
+		Webhook: WebhookConfig{
+			Enabled:        false,
+			URLs:           []string{},
+			Method:         "POST",
+			Headers:        map[string]string{},
+			TimeoutSeconds: 30,
+			Outbox: WebhookOutboxConfig{
+				Enabled:       false,
+				MaxAgeHours:   24,
+				ReplaySeconds: 60,
+			},
 		},
 		IP: IPConfig{
 			Services: []string{
@@ -178,9 +1142,167 @@ func (m *Manager) createDefaultConfig() *Config {
 				"https://ipecho.net/plain",
 			},
 			TimeoutSeconds: 30,
+			FetchMode:      "sequential",
+			AdaptiveOrder:  false,
+			UserAgent:      "",
+			ServiceHeaders: map[string]map[string]string{},
+			TLS:            FetchTLSConfig{},
+			DoH:            DoHConfig{BootstrapIPs: []string{}},
 			DataDir:        "data",
 			RecordsFile:    "ip_records.json",
 			LastIPFile:     "last_ip.txt",
+			Families:       []string{},
+			ExpectedRanges: []string{},
+
+			FamilyMergeWindowSeconds: 15,
+			FailureThreshold:         3,
+			RecoveryThreshold:        1,
+			StartupGraceSeconds:      120,
+
+			ServicesIndex: ServicesIndexConfig{
+				RefreshIntervalMinutes: 360,
+				CacheFile:              "services_index.json",
+			},
+
+			WANs: []WANConfig{},
+		},
+		CheckLog: CheckLogConfig{
+			Enabled:     false,
+			File:        "check_log.jsonl",
+			MaxEntries:  10000,
+			MaxAgeHours: 168,
 		},
+		Retention: RetentionConfig{
+			HistoryDays:             0,
+			FailedNotificationsDays: 0,
+		},
+		Enrichment: EnrichmentConfig{
+			Enabled:           false,
+			HostingASNs:       []int{},
+			HostingListFile:   "",
+			TimeoutSeconds:    5,
+			ExpectedCountries: []string{},
+			Geolocation:       GeolocationConfig{},
+		},
+		Anomaly: AnomalyConfig{
+			Enabled:     false,
+			WindowHours: 24,
+			Factor:      4,
+			MinChanges:  3,
+			LearnDays:   30,
+		},
+		API: APIConfig{
+			Enabled:        false,
+			Listen:         ":8787",
+			Token:          "",
+			AdminToken:     "",
+			MaxWaitSeconds: 300,
+		},
+		Hooks: HooksConfig{
+			Commands:         []HookCommand{},
+			TimeoutSeconds:   30,
+			OutputLimitBytes: 4096,
+			NotifyOnFailure:  false,
+		},
+		OnChangeCommands: []HookCommand{},
+		ACME: ACMEConfig{
+			Domains:           []string{},
+			Command:           "certbot",
+			Args:              []string{"renew", "--non-interactive"},
+			DNSTimeoutSeconds: 600,
+			TimeoutSeconds:    300,
+		},
+		Shortlink: ShortlinkConfig{
+			Enabled:            false,
+			Provider:           "shlink",
+			APIURL:             "https://s.example.com",
+			APIKey:             "YOUR_SHORTLINK_API_KEY",
+			ID:                 "home",
+			Target:             "http://{ip}:8080/",
+			Method:             "PUT",
+			Headers:            map[string]string{},
+			MinIntervalSeconds: 60,
+			TimeoutSeconds:     30,
+		},
+		Plugins: PluginsConfig{
+			Commands:       []PluginCommand{},
+			TimeoutSeconds: 30,
+		},
+		QuietHours: QuietHoursConfig{
+			Enabled:        false,
+			Start:          "23:00",
+			End:            "07:00",
+			UrgentChannels: []string{},
+		},
+		Digest: DigestConfig{
+			Channels: map[string]string{},
+		},
+		CircuitBreaker: CircuitBreakerConfig{
+			FailureThreshold: 5,
+			CooldownSeconds:  300,
+		},
+		Escalation: EscalationConfig{
+			Enabled:      false,
+			Channels:     []string{},
+			AfterMinutes: 15,
+			Events:       []string{},
+		},
+		Lifecycle: LifecycleConfig{
+			Startup:   false,
+			Shutdown:  false,
+			Heartbeat: false,
+		},
+		Quotas: QuotaConfig{
+			Channels:       map[string]int{},
+			ReservePercent: 10,
+			Reroute:        map[string]string{},
+		},
+		Ping: PingConfig{
+			Enabled:        false,
+			URL:            "https://hc-ping.com/YOUR_CHECK_UUID",
+			FailURL:        "",
+			TimeoutSeconds: 10,
+		},
+		Resources: ResourcesConfig{
+			GOMAXPROCS:    0,
+			MemoryLimitMB: 0,
+			BallastMB:     0,
+		},
+		LowWrite: LowWriteConfig{
+			Enabled:              false,
+			FlushIntervalMinutes: 60,
+			RuntimeDir:           "",
+		},
+		DNSCache: DNSCacheConfig{
+			Enabled:            false,
+			MaxTTLSeconds:      3600,
+			NegativeTTLSeconds: 30,
+			StaleTTLSeconds:    86400,
+		},
+		Proxy: ProxyConfig{
+			URL:     "",
+			NoProxy: []string{},
+		},
+		Update: UpdateConfig{
+			ManifestURL:    "",
+			PublicKey:      "",
+			AutoApply:      false,
+			SystemdUnit:    "public-ip-monitor",
+			TimeoutSeconds: 300,
+		},
+		Schedules: map[string]string{},
+	}
+}
+
+// validHeaderName reports whether name can be sent as an HTTP header name
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
 	}
+	return true
 }
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// DigestChange is an IP change listed in a digest
+type DigestChange struct {
+	IPChange
+	ChangedAt time.Time
+	Held      time.Duration // How long NewIP was kept, until the next change or the digest
+	Current   bool          // NewIP is still the address
+}
+
+// Digest lists the IP changes of a period, e.g. quiet hours, in the order
+// they happened. Unlike a change notification, which reports the first old
+// and the last new IP, it shows every address in between and how long each
+// was kept.
+type Digest struct {
+	Site    string
+	Since   time.Time // First change
+	Until   time.Time // When the digest was made
+	Changes []DigestChange
+}
+
+// DefaultDigestTemplate renders a digest unless digest.template or a
+// channel's template replaces it
+const DefaultDigestTemplate = `{{len .Changes}} IP changes since {{time .Since}}{{if .Site}} at {{.Site}}{{end}}:{{range .Changes}}
+• {{.Label}}: {{or .OldIP "unknown"}} → {{.NewIP}} at {{time .ChangedAt}}, {{if .Current}}current for{{else}}kept{{end}} {{duration .Held}}{{end}}`
+
+// digestFuncs are the functions digest templates can use besides the
+// built-in ones
+var digestFuncs = template.FuncMap{
+	"time":     func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+	"duration": formatUptime,
+}
+
+// digestTemplates are the parsed digest templates in effect
+type digestTemplates struct {
+	base     *template.Template
+	channels map[string]*template.Template // By channel name in lower case
+}
+
+// defaultDigest is DefaultDigestTemplate parsed, also used when a
+// configured template fails on a digest
+var defaultDigest = template.Must(template.New("digest").Funcs(digestFuncs).Parse(DefaultDigestTemplate))
+
+var (
+	digestMu  sync.RWMutex
+	digestSet = &digestTemplates{base: defaultDigest}
+)
+
+// parseDigestTemplates parses the templates of the digest settings and
+// tries them on a sample digest, so that mistakes surface on startup
+func parseDigestTemplates(c DigestConfig) (*digestTemplates, error) {
+	parse := func(name, text string) (*template.Template, error) {
+		tmpl, err := template.New(name).Funcs(digestFuncs).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		if err := tmpl.Execute(&strings.Builder{}, sampleDigest()); err != nil {
+			return nil, err
+		}
+		return tmpl, nil
+	}
+
+	set := &digestTemplates{base: defaultDigest, channels: make(map[string]*template.Template)}
+	var err error
+	if c.Template != "" {
+		if set.base, err = parse("digest", c.Template); err != nil {
+			return nil, fmt.Errorf("digest.template: %w", err)
+		}
+	}
+	for channel, text := range c.Channels {
+		if set.channels[strings.ToLower(channel)], err = parse("digest."+channel, text); err != nil {
+			return nil, fmt.Errorf("digest.channels.%s: %w", channel, err)
+		}
+	}
+	return set, nil
+}
+
+// sampleDigest is a digest with every field set, to try templates on
+func sampleDigest() Digest {
+	at := time.Date(2025, 6, 8, 2, 0, 0, 0, time.UTC)
+	return Digest{
+		Site:  "home",
+		Since: at,
+		Until: at.Add(5 * time.Hour),
+		Changes: []DigestChange{
+			{IPChange: IPChange{Family: "IPv4", OldIP: "203.0.113.2", NewIP: "203.0.113.7"}, ChangedAt: at, Held: 3 * time.Hour},
+			{IPChange: IPChange{Family: "IPv4", OldIP: "203.0.113.7", NewIP: "203.0.113.9"}, ChangedAt: at.Add(3 * time.Hour), Held: 2 * time.Hour, Current: true},
+		},
+	}
+}
+
+// SetDigestTemplates sets the templates digests are rendered with
+func SetDigestTemplates(c DigestConfig) error {
+	set, err := parseDigestTemplates(c)
+	if err != nil {
+		return err
+	}
+	digestMu.Lock()
+	defer digestMu.Unlock()
+	digestSet = set
+	return nil
+}
+
+// BuildDigestText renders the digest with the template of the named
+// channel, falling back to the built-in template if it fails
+func BuildDigestText(channel string, digest Digest) string {
+	digestMu.RLock()
+	set := digestSet
+	digestMu.RUnlock()
+
+	tmpl, ok := set.channels[strings.ToLower(channel)]
+	if !ok {
+		tmpl = set.base
+	}
+	var text strings.Builder
+	if err := tmpl.Execute(&text, digest); err == nil {
+		return text.String()
+	}
+	text.Reset()
+	defaultDigest.Execute(&text, digest)
+	return text.String()
+}
+
+// BuildDigestTitle returns the headline of digest messages
+func BuildDigestTitle() string {
+	return "📋 IP Change Digest"
+}
+
+// BuildDigestEmailSubject creates the subject line of digest emails
+func BuildDigestEmailSubject() string {
+	return BuildDigestTitle() + subjectSuffix()
+}
+
+// BuildDigestEmailBody creates the email body of a digest
+func BuildDigestEmailBody(channel string, digest Digest) string {
+	return fmt.Sprintf(`IP Change Digest
+
+%s
+
+This notification was sent automatically by your IP monitoring service.
+
+Best regards,
+%s`, BuildDigestText(channel, digest), signature())
+}
+
+// BuildDigestMessage creates a plain text message of a digest, for chat
+// channels
+func BuildDigestMessage(channel string, digest Digest) string {
+	return fmt.Sprintf("%s\n\n%s\n\n%s", BuildDigestTitle(), BuildDigestText(channel, digest), signature())
+}
+
+// BuildDigestCard creates the card of a digest, for channels rendering cards
+func BuildDigestCard(channel string, digest Digest) Card {
+	return Card{
+		Title:  BuildDigestTitle(),
+		Color:  CardColorChange,
+		Footer: signature(),
+		Fields: []CardField{{Name: "Changes", Value: BuildDigestText(channel, digest)}},
+	}
+}
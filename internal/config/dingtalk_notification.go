@@ -0,0 +1,54 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// BuildDingTalkMessage creates the DingTalk title and markdown for an IP
+// change, with the same content as the ntfy notification
+func BuildDingTalkMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) (string, string) {
+	title, body := BuildNtfyMessage(changes, timestamp, gateway)
+	return title, buildChatMarkdown(title, body)
+}
+
+// BuildCatchUpDingTalkMessage describes what happened while the monitor was not running
+func BuildCatchUpDingTalkMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) (string, string) {
+	title, body := BuildCatchUpNtfyMessage(changes, timestamp, gateway)
+	return title, buildChatMarkdown(title, body)
+}
+
+// BuildHookFailureDingTalkMessage creates the DingTalk title and markdown for failed hooks
+func BuildHookFailureDingTalkMessage(failures []HookFailure, timestamp time.Time) (string, string) {
+	title, body := BuildHookFailureNtfyMessage(failures, timestamp)
+	return title, buildChatMarkdown(title, body)
+}
+
+// BuildLifecycleDingTalkMessage creates the DingTalk title and markdown
+// when the monitor starts, stops or sends its heartbeat
+func BuildLifecycleDingTalkMessage(status LifecycleStatus, timestamp time.Time) (string, string) {
+	title, body := BuildLifecycleNtfyMessage(status, timestamp)
+	return title, buildChatMarkdown(status.emoji()+" "+title, body)
+}
+
+// BuildFetchFailureDingTalkMessage creates the DingTalk title and markdown
+// for checks that keep failing, or work again when recovered
+func BuildFetchFailureDingTalkMessage(failures []CheckFailure, recovered bool, timestamp time.Time) (string, string) {
+	title, body := BuildFetchFailureNtfyMessage(failures, recovered, timestamp)
+	return title, buildChatMarkdown(fetchFailureEmoji(recovered)+" "+title, body)
+}
+
+// buildChatMarkdown formats a title and plain-text lines as markdown for the
+// corporate chat bots, which only break lines at paragraph boundaries. The
+// footer also carries "IP" for robots secured with that keyword.
+func buildChatMarkdown(title, body string) string {
+	var text strings.Builder
+	text.WriteString("#### " + title + "\n\n")
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			text.WriteString(line + "\n\n")
+		}
+	}
+	text.WriteString("> " + signature())
+	return text.String()
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// BuildDiscordCard creates the Discord embed content for an IP change
+func BuildDiscordCard(changes []IPChange, timestamp time.Time, gateway *GatewayContext) Card {
+	card := Card{
+		Title:  "🚨 IP Address Changed",
+		Color:  CardColorChange,
+		Footer: signature(),
+	}
+
+	for _, change := range changes {
+		prefix := ""
+		if len(changes) > 1 || change.WAN != "" {
+			prefix = change.Label() + " "
+		}
+		card.Fields = append(card.Fields,
+			CardField{Name: prefix + "Old IP", Value: change.OldIP, Inline: true},
+			CardField{Name: prefix + "New IP", Value: change.NewIP, Inline: true},
+		)
+		for _, event := range change.Warnings() {
+			card.Title = "⚠️ " + event
+			card.Color = CardColorWarning
+		}
+		if location := change.Location(); location != "" {
+			card.Fields = append(card.Fields, CardField{Name: prefix + "Location", Value: location})
+		}
+	}
+	card.Fields = append(card.Fields, CardField{Name: "Time", Value: timestamp.Format("2006-01-02 15:04:05")})
+	card.Fields = append(card.Fields, buildGatewayCardFields(gateway)...)
+
+	return card
+}
+
+// BuildCatchUpDiscordCard describes what happened while the monitor was not running
+func BuildCatchUpDiscordCard(changes []IPChange, timestamp time.Time, gateway *GatewayContext) Card {
+	card := Card{
+		Title:  "🚨 Changed While Offline",
+		Color:  CardColorCatchUp,
+		Footer: signature(),
+	}
+
+	for _, change := range changes {
+		value := fmt.Sprintf("%s (unchanged)", change.NewIP)
+		if change.Missed {
+			value = fmt.Sprintf("%s → %s%s", change.OldIP, change.NewIP, formatRecordedAt(change.LastChange))
+			for _, event := range change.Warnings() {
+				value += "\n⚠️ " + event
+			}
+			if location := change.Location(); location != "" {
+				value += "\n📍 " + location
+			}
+		}
+		card.Fields = append(card.Fields, CardField{Name: change.Label(), Value: value})
+
+		if change.DNSRecord != "" {
+			card.Fields = append(card.Fields, CardField{
+				Name:  "DNS " + change.DNSRecord,
+				Value: formatDNSIPs(change.DNSIPs) + formatDNSState(change),
+			})
+		}
+		if note := change.OutageNote(); note != "" {
+			card.Fields = append(card.Fields, CardField{Name: "Network", Value: note})
+		}
+	}
+	card.Fields = append(card.Fields, CardField{Name: "Checked", Value: timestamp.Format("2006-01-02 15:04:05")})
+	card.Fields = append(card.Fields, buildGatewayCardFields(gateway)...)
+
+	return card
+}
+
+// BuildHookFailureDiscordCard creates the Discord embed content for failed hooks
+func BuildHookFailureDiscordCard(failures []HookFailure, timestamp time.Time) Card {
+	card := Card{
+		Title:  "⚠️ IP Change Hook Failed",
+		Color:  CardColorWarning,
+		Footer: signature(),
+	}
+
+	for _, failure := range failures {
+		value := failure.Error
+		if failure.Output != "" {
+			// Discord limits field values to 1024 characters
+			value += "\n```" + truncateText(failure.Output, 900) + "```"
+		}
+		card.Fields = append(card.Fields, CardField{Name: failure.Name, Value: value})
+	}
+	card.Fields = append(card.Fields, CardField{Name: "Time", Value: timestamp.Format("2006-01-02 15:04:05")})
+
+	return card
+}
+
+// BuildLifecycleDiscordCard creates the Discord embed content when the
+// monitor starts, stops or sends its heartbeat
+func BuildLifecycleDiscordCard(status LifecycleStatus, timestamp time.Time) Card {
+	return buildLifecycleCard(status, timestamp)
+}
+
+// BuildFetchFailureDiscordCard creates the Discord embed content for checks
+// that keep failing, or work again when recovered
+func BuildFetchFailureDiscordCard(failures []CheckFailure, recovered bool, timestamp time.Time) Card {
+	return buildFetchFailureCard(failures, recovered, timestamp)
+}
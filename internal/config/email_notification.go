@@ -2,16 +2,17 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
 // BuildEmailSubject creates the email subject line
 func BuildEmailSubject() string {
-	return "🚨 IP Address Changed - Public IP Monitor"
+	return "🚨 IP Address Changed" + subjectSuffix()
 }
 
 // BuildEmailBody creates the email body content
-func BuildEmailBody(oldIP, newIP string, timestamp time.Time) string {
+func BuildEmailBody(oldIP, newIP string, timestamp time.Time, gateway *GatewayContext) string {
 	return fmt.Sprintf(`IP Address Change Notification
 
 Your public IP address has changed:
@@ -19,9 +20,186 @@ Your public IP address has changed:
 Previous IP: %s
 New IP: %s
 Change Time: %s
+%s
+This notification was sent automatically by your IP monitoring service.
+
+Best regards,
+%s`, oldIP, newIP, timestamp.Format("2006-01-02 15:04:05"), buildEmailGatewaySection(gateway), signature())
+}
+
+// BuildCombinedEmailBody creates the email body for changes of several address families
+func BuildCombinedEmailBody(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	var details strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&details, "%s\n  Previous: %s\n  New: %s\n", change.Label(), change.OldIP, change.NewIP)
+		for _, event := range change.Warnings() {
+			fmt.Fprintf(&details, "  %s\n", event)
+		}
+		if location := change.Location(); location != "" {
+			fmt.Fprintf(&details, "  Location: %s\n", location)
+		}
+		details.WriteString("\n")
+	}
+
+	return fmt.Sprintf(`IP Address Change Notification
+
+Your public IP addresses have changed:
+
+%sChange Time: %s
+%s
+This notification was sent automatically by your IP monitoring service.
+
+Best regards,
+%s`, details.String(), timestamp.Format("2006-01-02 15:04:05"), buildEmailGatewaySection(gateway), signature())
+}
+
+// BuildFailoverEmailSubject creates the subject line when the default route fails over to a backup WAN
+func BuildFailoverEmailSubject() string {
+	return "⚠️ Failover to Backup WAN Detected" + subjectSuffix()
+}
+
+// BuildHostedExitEmailSubject creates the subject line when the new IP
+// belongs to a hosting or VPN provider
+func BuildHostedExitEmailSubject() string {
+	return "⚠️ Public IP Now Leaves Through a VPN or Hosting Provider" + subjectSuffix()
+}
+
+// BuildNetworkChangedEmailSubject creates the subject line when the new IP
+// belongs to another network than the old one
+func BuildNetworkChangedEmailSubject() string {
+	return "⚠️ Public IP Moved to Another Network" + subjectSuffix()
+}
+
+// BuildUnusualActivityEmailSubject creates the subject line when the IP
+// changes far more often than usual
+func BuildUnusualActivityEmailSubject() string {
+	return "⚠️ Unusual IP Change Activity" + subjectSuffix()
+}
+
+// BuildUnexpectedIPEmailSubject creates the subject line when the new IP
+// is outside the expected ranges
+func BuildUnexpectedIPEmailSubject() string {
+	return "⚠️ Public IP Outside Expected Ranges" + subjectSuffix()
+}
+
+// BuildGeofenceEmailSubject creates the subject line when the new IP is
+// located outside the expected countries
+func BuildGeofenceEmailSubject() string {
+	return "🚨 SECURITY ALERT: Public IP Outside Expected Countries" + subjectSuffix()
+}
+
+// BuildGeofenceEmailBody describes new IPs located outside the expected
+// countries and what to check, as this may be a misconfigured VPN or
+// hijacked traffic rather than a routine change
+func BuildGeofenceEmailBody(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	var details strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&details, "%s\n  Previous: %s\n  New: %s\n", change.Label(), change.OldIP, change.NewIP)
+		for _, event := range change.Warnings() {
+			fmt.Fprintf(&details, "  %s\n", event)
+		}
+		if location := change.Location(); location != "" {
+			fmt.Fprintf(&details, "  Location: %s\n", location)
+		}
+		details.WriteString("\n")
+	}
+
+	return fmt.Sprintf(`SECURITY ALERT: Public IP Outside Expected Countries
+
+Your public IP address is now located in a country it is not expected in:
+
+%sChange Time: %s
+%s
+Please check right away:
+  - whether a VPN client or proxy was installed or reconfigured
+  - the WAN and DNS settings of the router
+  - whether traffic is being redirected by someone else
+
+This notification was sent automatically by your IP monitoring service.
+
+Best regards,
+%s`, details.String(), timestamp.Format("2006-01-02 15:04:05"), buildEmailGatewaySection(gateway), signature())
+}
+
+// BuildCatchUpEmailSubject creates the subject line for startup catch-up emails
+func BuildCatchUpEmailSubject() string {
+	return "🚨 IP Address Changed While Offline" + subjectSuffix()
+}
 
+// BuildCatchUpEmailBody describes what happened while the monitor was not running
+func BuildCatchUpEmailBody(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	var details strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&details, "%s\n", change.Label())
+		if change.Missed {
+			fmt.Fprintf(&details, "  Last known: %s%s\n", change.OldIP, formatRecordedAt(change.LastChange))
+			fmt.Fprintf(&details, "  Current: %s\n", change.NewIP)
+			for _, event := range change.Warnings() {
+				fmt.Fprintf(&details, "  %s\n", event)
+			}
+			if location := change.Location(); location != "" {
+				fmt.Fprintf(&details, "  Location: %s\n", location)
+			}
+		} else {
+			fmt.Fprintf(&details, "  Current: %s (unchanged)\n", change.NewIP)
+		}
+		if change.DNSRecord != "" {
+			fmt.Fprintf(&details, "  DNS %s: %s%s\n", change.DNSRecord, formatDNSIPs(change.DNSIPs), formatDNSState(change))
+		}
+		if note := change.OutageNote(); note != "" {
+			fmt.Fprintf(&details, "  %s (last error: %s)\n", note, change.Outage.Error)
+		}
+		details.WriteString("\n")
+	}
+
+	return fmt.Sprintf(`IP Address Catch-Up Notification
+
+The monitor was not running when the following happened:
+
+%sChecked At: %s
+%s
 This notification was sent automatically by your IP monitoring service.
 
 Best regards,
-Public IP Monitor`, oldIP, newIP, timestamp.Format("2006-01-02 15:04:05"))
+%s`, details.String(), timestamp.Format("2006-01-02 15:04:05"), buildEmailGatewaySection(gateway), signature())
+}
+
+// formatRecordedAt renders when an IP was recorded, if known
+func formatRecordedAt(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return " (recorded " + t.Format("2006-01-02 15:04:05") + ")"
+}
+
+// formatDNSIPs renders the addresses a DNS record resolved to
+func formatDNSIPs(ips []string) string {
+	if len(ips) == 0 {
+		return "no addresses"
+	}
+	return strings.Join(ips, ", ")
+}
+
+// formatDNSState annotates whether a DNS record is up to date
+func formatDNSState(change IPChange) string {
+	if change.DNSError != "" {
+		return " (lookup failed: " + change.DNSError + ")"
+	}
+	if change.DNSStale() {
+		return " (out of date)"
+	}
+	return " (up to date)"
+}
+
+// buildEmailGatewaySection describes the default gateway, if known
+func buildEmailGatewaySection(gateway *GatewayContext) string {
+	if gateway == nil {
+		return ""
+	}
+
+	section := fmt.Sprintf("Gateway: %s\n", gateway.Current())
+	if gateway.Changed {
+		section += fmt.Sprintf("Gateway changed from %s - the router or uplink was likely replaced or failed over\n", gateway.Previous())
+	}
+	return section
 }
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the environment variables that set configuration fields,
+// named after the JSON path of the field: PIM_EMAIL_SMTP_HOST sets
+// email.smtp_host
+const EnvPrefix = "PIM_"
+
+// EnvName returns the environment variable setting the field at a JSON path
+func EnvName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// envField is a configuration field settable from the environment
+type envField struct {
+	path  string
+	index []int // Field index path from Config
+}
+
+// envFields maps the environment variable names to configuration fields
+func envFields() map[string]envField {
+	fields := map[string]envField{}
+	var walk func(t reflect.Type, prefix string, index []int)
+	walk = func(t reflect.Type, prefix string, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			path := prefix + name
+			fieldIndex := append(append([]int(nil), index...), i)
+			if field.Type.Kind() == reflect.Struct {
+				walk(field.Type, path+".", fieldIndex)
+				continue
+			}
+			fields[EnvName(path)] = envField{path: path, index: fieldIndex}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "", nil)
+	return fields
+}
+
+// applyEnv sets the fields named by PIM_* environment variables. Strings,
+// numbers and booleans are given as is; lists may be comma-separated
+// strings or JSON, objects and lists of objects JSON.
+func applyEnv(c *Config, environ []string) error {
+	fields := envFields()
+	var unknown []string
+
+	for _, entry := range environ {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		field, ok := fields[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if err := setEnvValue(reflect.ValueOf(c).Elem().FieldByIndex(field.index), value); err != nil {
+			return fmt.Errorf("%s (%s): %w", name, field.path, err)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown configuration variables: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// setEnvValue parses value into a field
+func setEnvValue(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", value)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		trimmed := strings.TrimSpace(value)
+		if v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(trimmed, "[") {
+			var items []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			v.Set(reflect.ValueOf(items).Convert(v.Type()))
+			return nil
+		}
+		fallthrough
+	default:
+		target := reflect.New(v.Type())
+		if err := json.Unmarshal([]byte(value), target.Interface()); err != nil {
+			return fmt.Errorf("invalid JSON: %w", err)
+		}
+		v.Set(target.Elem())
+	}
+	return nil
+}
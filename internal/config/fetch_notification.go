@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// fetchFailureTitle returns the headline of check failure and recovery
+// notifications
+func fetchFailureTitle(recovered bool) string {
+	if recovered {
+		return "IP Checks Recovered"
+	}
+	return "IP Checks Failing"
+}
+
+// fetchFailureEmoji returns the symbol leading the title
+func fetchFailureEmoji(recovered bool) string {
+	if recovered {
+		return "✅"
+	}
+	return "🚨"
+}
+
+// fetchFailureFacts describes each failure, or its end when recovered
+func fetchFailureFacts(failures []CheckFailure, recovered bool, timestamp time.Time) []CardField {
+	fields := make([]CardField, 0, len(failures)+1)
+	for _, failure := range failures {
+		since := failure.Since.Format("2006-01-02 15:04:05")
+		value := fmt.Sprintf("%d checks failed in a row since %s: %s", failure.Count, since, failure.Error)
+		if recovered {
+			value = fmt.Sprintf("Working again after %d failed checks since %s (down %v)",
+				failure.Count, since, timestamp.Sub(failure.Since).Round(time.Second))
+		}
+		fields = append(fields, CardField{Name: failure.Label(), Value: value})
+	}
+	return append(fields, CardField{Name: "Time", Value: timestamp.Format("2006-01-02 15:04:05")})
+}
+
+// buildFetchFailureLines lists the facts as "Name: value" lines
+func buildFetchFailureLines(failures []CheckFailure, recovered bool, timestamp time.Time) string {
+	var lines strings.Builder
+	for _, fact := range fetchFailureFacts(failures, recovered, timestamp) {
+		fmt.Fprintf(&lines, "%s: %s\n", fact.Name, fact.Value)
+	}
+	return lines.String()
+}
+
+// buildFetchFailureCard creates the card for checks that keep failing or
+// work again
+func buildFetchFailureCard(failures []CheckFailure, recovered bool, timestamp time.Time) Card {
+	card := Card{
+		Title:  fetchFailureEmoji(recovered) + " " + fetchFailureTitle(recovered),
+		Color:  CardColorChange,
+		Footer: signature(),
+		Fields: fetchFailureFacts(failures, recovered, timestamp),
+	}
+	if recovered {
+		card.Color = CardColorHealthy
+	}
+	return card
+}
+
+// BuildFetchFailureEmailSubject creates the subject line for check failure
+// and recovery emails
+func BuildFetchFailureEmailSubject(recovered bool) string {
+	return fetchFailureEmoji(recovered) + " " + fetchFailureTitle(recovered) + subjectSuffix()
+}
+
+// BuildFetchFailureEmailBody creates the email body for checks that keep
+// failing, or work again when recovered
+func BuildFetchFailureEmailBody(failures []CheckFailure, recovered bool, timestamp time.Time) string {
+	intro := "The public IP could not be determined: every check failed. Changes are not noticed until the checks work again."
+	if recovered {
+		intro = "The public IP checks work again."
+	}
+
+	return fmt.Sprintf(`%s
+
+%s
+
+%s
+This notification was sent automatically by your IP monitoring service.
+
+Best regards,
+%s`, fetchFailureTitle(recovered), intro, buildFetchFailureLines(failures, recovered, timestamp), signature())
+}
+
+// BuildFetchFailureWhatsAppMessage creates the WhatsApp message for checks
+// that keep failing, or work again when recovered
+func BuildFetchFailureWhatsAppMessage(failures []CheckFailure, recovered bool, timestamp time.Time) string {
+	return fmt.Sprintf("%s %s\n\n%s\n%s",
+		fetchFailureEmoji(recovered), fetchFailureTitle(recovered), buildFetchFailureLines(failures, recovered, timestamp), signature())
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BuildFileMessage creates a single line describing the change, suitable for
+// tailing from scripts and desktop widgets
+func BuildFileMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	parts := make([]string, 0, len(changes))
+	for _, change := range changes {
+		part := fmt.Sprintf("%s %s -> %s", change.Label(), change.OldIP, change.NewIP)
+		for _, event := range change.Warnings() {
+			part += fmt.Sprintf(" (%s)", strings.ToLower(event[:1])+event[1:])
+		}
+		if location := change.Location(); location != "" {
+			part += fmt.Sprintf(" (%s)", location)
+		}
+		parts = append(parts, part)
+	}
+
+	return fmt.Sprintf("%s changed %s%s", timestamp.Format("2006-01-02 15:04:05"), strings.Join(parts, ", "), buildFileGatewaySuffix(gateway))
+}
+
+// BuildHookFailureFileMessage creates a single line describing failed hooks
+func BuildHookFailureFileMessage(failures []HookFailure, timestamp time.Time) string {
+	parts := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		parts = append(parts, fmt.Sprintf("%s: %s", failure.Name, failure.Error))
+	}
+
+	return fmt.Sprintf("%s hook-failed %s", timestamp.Format("2006-01-02 15:04:05"), strings.Join(parts, ", "))
+}
+
+// BuildLifecycleFileMessage creates a single line when the monitor starts,
+// stops or sends its heartbeat
+func BuildLifecycleFileMessage(status LifecycleStatus, timestamp time.Time) string {
+	parts := make([]string, 0, len(status.Addresses)+3)
+	if status.Reason != "" {
+		parts = append(parts, "reason: "+status.Reason)
+	}
+	for _, address := range status.Addresses {
+		parts = append(parts, fmt.Sprintf("%s: %s", address.Label(), address.Value()))
+	}
+	if status.Uptime > 0 {
+		parts = append(parts, "uptime "+formatUptime(status.Uptime))
+	}
+	if !status.PausedAt.IsZero() {
+		parts = append(parts, "paused since "+status.PausedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	return fmt.Sprintf("%s %s %s", timestamp.Format("2006-01-02 15:04:05"), status.Kind, strings.Join(parts, ", "))
+}
+
+// BuildFetchFailureFileMessage creates a single line for checks that keep
+// failing, or work again when recovered
+func BuildFetchFailureFileMessage(failures []CheckFailure, recovered bool, timestamp time.Time) string {
+	parts := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		parts = append(parts, fmt.Sprintf("%s: %d failed checks since %s (%s)",
+			failure.Label(), failure.Count, failure.Since.Format("2006-01-02 15:04:05"), failure.Error))
+	}
+
+	kind := "fetch-failed"
+	if recovered {
+		kind = "fetch-recovered"
+	}
+	return fmt.Sprintf("%s %s %s", timestamp.Format("2006-01-02 15:04:05"), kind, strings.Join(parts, ", "))
+}
+
+// BuildCatchUpFileMessage creates a single line describing what happened while the monitor was not running
+func BuildCatchUpFileMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	parts := make([]string, 0, len(changes))
+	for _, change := range changes {
+		part := fmt.Sprintf("%s %s -> %s", change.Label(), change.OldIP, change.NewIP)
+		for _, event := range change.Warnings() {
+			part += fmt.Sprintf(" (%s)", strings.ToLower(event[:1])+event[1:])
+		}
+		if location := change.Location(); location != "" {
+			part += fmt.Sprintf(" (%s)", location)
+		}
+		if change.DNSStale() {
+			part += fmt.Sprintf(" (DNS %s stale)", change.DNSRecord)
+		}
+		if change.Outage != nil {
+			part += fmt.Sprintf(" (after %d failed checks)", change.Outage.Count)
+		}
+		parts = append(parts, part)
+	}
+
+	return fmt.Sprintf("%s caught-up %s%s", timestamp.Format("2006-01-02 15:04:05"), strings.Join(parts, ", "), buildFileGatewaySuffix(gateway))
+}
+
+// buildFileGatewaySuffix describes the default gateway, if known
+func buildFileGatewaySuffix(gateway *GatewayContext) string {
+	if gateway == nil {
+		return ""
+	}
+
+	suffix := fmt.Sprintf(" [gateway %s", gateway.Current())
+	if gateway.Changed {
+		suffix += fmt.Sprintf(", changed from %s", gateway.Previous())
+	}
+	return suffix + "]"
+}
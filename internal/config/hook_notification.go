@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HookFailure describes an on-change hook that did not complete successfully
+type HookFailure struct {
+	Name     string
+	Error    string
+	Output   string // Truncated stdout/stderr
+	Duration time.Duration
+}
+
+// BuildHookFailureEmailSubject creates the subject line for hook failure emails
+func BuildHookFailureEmailSubject() string {
+	return "⚠️ IP Change Hook Failed" + subjectSuffix()
+}
+
+// BuildHookFailureEmailBody creates the email body for failed hooks
+func BuildHookFailureEmailBody(failures []HookFailure, timestamp time.Time) string {
+	var details strings.Builder
+	for _, failure := range failures {
+		fmt.Fprintf(&details, "%s: %s (after %v)\n", failure.Name, failure.Error, failure.Duration.Round(time.Millisecond))
+		if failure.Output != "" {
+			fmt.Fprintf(&details, "%s\n", failure.Output)
+		}
+		details.WriteString("\n")
+	}
+
+	return fmt.Sprintf(`IP Change Hook Failure
+
+The following commands failed while handling an IP change:
+
+%sTime: %s
+
+This notification was sent automatically by your IP monitoring service.
+
+Best regards,
+%s`, details.String(), timestamp.Format("2006-01-02 15:04:05"), signature())
+}
+
+// BuildHookFailureWhatsAppMessage creates the WhatsApp message for failed hooks
+func BuildHookFailureWhatsAppMessage(failures []HookFailure, timestamp time.Time) string {
+	var details strings.Builder
+	for _, failure := range failures {
+		fmt.Fprintf(&details, "%s: %s\n", failure.Name, failure.Error)
+	}
+
+	return fmt.Sprintf("⚠️ IP Change Hook Failed!\n\n%s\nTime: %s\n\n%s",
+		details.String(), timestamp.Format("2006-01-02 15:04:05"), signature())
+}
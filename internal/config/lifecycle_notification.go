@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lifecycle notification kinds, the same as their event types
+const (
+	LifecycleStarted   = "started"
+	LifecycleStopped   = "stopped"
+	LifecycleHeartbeat = "heartbeat"
+)
+
+// LifecycleStatus describes the monitor itself when it starts, stops or
+// sends its heartbeat
+type LifecycleStatus struct {
+	Kind      string // LifecycleStarted, LifecycleStopped or LifecycleHeartbeat
+	Site      string
+	Version   string
+	Uptime    time.Duration // Zero on startup
+	PausedAt  time.Time     // When checking was paused; zero while checking
+	Reason    string        // Fatal error the monitor stopped on; empty for a regular shutdown
+	Addresses []CurrentIP
+	Quotas    []QuotaUsage // Of the channels with a daily quota
+}
+
+// CurrentIP is the address the monitor holds for one family / WAN
+type CurrentIP struct {
+	Family string
+	WAN    string
+	IP     string // Empty when not known, e.g. the first check failed
+}
+
+// QuotaUsage is how much of its daily quota a notification channel used
+type QuotaUsage struct {
+	Channel string
+	Used    int // Sends today
+	Limit   int
+}
+
+// Label returns the family, qualified by the WAN profile when set
+func (c CurrentIP) Label() string {
+	return IPChange{Family: c.Family, WAN: c.WAN}.Label()
+}
+
+// Value returns the IP, or "unknown"
+func (c CurrentIP) Value() string {
+	if c.IP == "" {
+		return "unknown"
+	}
+	return c.IP
+}
+
+// Title returns the headline of the notification, e.g. "Monitor Started"
+func (s LifecycleStatus) Title() string {
+	switch s.Kind {
+	case LifecycleStopped:
+		return "Monitor Stopped"
+	case LifecycleHeartbeat:
+		return "Monitor Heartbeat"
+	default:
+		return "Monitor Started"
+	}
+}
+
+// emoji returns the symbol leading the title
+func (s LifecycleStatus) emoji() string {
+	switch s.Kind {
+	case LifecycleStopped:
+		return "🔴"
+	case LifecycleHeartbeat:
+		return "💓"
+	default:
+		return "🟢"
+	}
+}
+
+// facts returns the labelled values every channel shows, in order
+func (s LifecycleStatus) facts(timestamp time.Time) []CardField {
+	fields := []CardField{{Name: "Site", Value: s.Site}}
+	if s.Reason != "" {
+		fields = append(fields, CardField{Name: "Reason", Value: s.Reason})
+	}
+	for _, address := range s.Addresses {
+		fields = append(fields, CardField{Name: address.Label(), Value: address.Value()})
+	}
+	if s.Uptime > 0 {
+		fields = append(fields, CardField{Name: "Uptime", Value: formatUptime(s.Uptime)})
+	}
+	if !s.PausedAt.IsZero() {
+		fields = append(fields, CardField{Name: "Checks", Value: "Paused since " + s.PausedAt.Format("2006-01-02 15:04:05")})
+	}
+	if len(s.Quotas) > 0 {
+		usage := make([]string, len(s.Quotas))
+		for i, quota := range s.Quotas {
+			usage[i] = fmt.Sprintf("%s %d/%d", quota.Channel, quota.Used, quota.Limit)
+		}
+		fields = append(fields, CardField{Name: "Quota today", Value: strings.Join(usage, ", ")})
+	}
+	if s.Version != "" {
+		fields = append(fields, CardField{Name: "Version", Value: s.Version})
+	}
+	return append(fields, CardField{Name: "Time", Value: timestamp.Format("2006-01-02 15:04:05")})
+}
+
+// formatUptime renders a duration in days, hours and minutes, e.g. "3d 4h 12m"
+func formatUptime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := d / (24 * time.Hour)
+	hours := (d % (24 * time.Hour)) / time.Hour
+	minutes := (d % time.Hour) / time.Minute
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// buildLifecycleLines lists the facts as "Name: value" lines
+func buildLifecycleLines(status LifecycleStatus, timestamp time.Time) string {
+	var lines strings.Builder
+	for _, fact := range status.facts(timestamp) {
+		fmt.Fprintf(&lines, "%s: %s\n", fact.Name, fact.Value)
+	}
+	return lines.String()
+}
+
+// buildLifecycleCard creates the card shown when the monitor starts, stops
+// or sends its heartbeat
+func buildLifecycleCard(status LifecycleStatus, timestamp time.Time) Card {
+	card := Card{
+		Title:  status.emoji() + " " + status.Title(),
+		Color:  CardColorHealthy,
+		Footer: signature(),
+		Fields: status.facts(timestamp),
+	}
+	if status.Kind == LifecycleStopped || !status.PausedAt.IsZero() {
+		card.Color = CardColorStopped
+	}
+	return card
+}
+
+// BuildLifecycleEmailSubject creates the subject line for lifecycle emails
+func BuildLifecycleEmailSubject(status LifecycleStatus) string {
+	return status.emoji() + " " + status.Title() + subjectSuffix()
+}
+
+// BuildLifecycleEmailBody creates the email body when the monitor starts,
+// stops or sends its heartbeat
+func BuildLifecycleEmailBody(status LifecycleStatus, timestamp time.Time) string {
+	intro := "The IP monitor started."
+	switch status.Kind {
+	case LifecycleStopped:
+		intro = "The IP monitor is shutting down. No changes are reported until it starts again."
+		if status.Reason != "" {
+			intro = "The IP monitor stopped on a fatal error. No changes are reported until it is fixed and started again."
+		}
+	case LifecycleHeartbeat:
+		intro = "The IP monitor is running."
+	}
+	if status.Kind != LifecycleStopped && !status.PausedAt.IsZero() {
+		intro += " Its checks are paused: no changes are reported until they are resumed."
+	}
+
+	return fmt.Sprintf(`%s
+
+%s
+
+%s
+This notification was sent automatically by your IP monitoring service.
+
+Best regards,
+%s`, status.Title(), intro, buildLifecycleLines(status, timestamp), signature())
+}
+
+// BuildLifecycleWhatsAppMessage creates the WhatsApp message when the
+// monitor starts, stops or sends its heartbeat
+func BuildLifecycleWhatsAppMessage(status LifecycleStatus, timestamp time.Time) string {
+	return fmt.Sprintf("%s %s\n\n%s\n%s",
+		status.emoji(), status.Title(), buildLifecycleLines(status, timestamp), signature())
+}
@@ -0,0 +1,34 @@
+package config
+
+import "time"
+
+// BuildLineMessage creates the LINE message for an IP change. LINE shows
+// plain text with emoji like WhatsApp, so the WhatsApp texts are reused.
+func BuildLineMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	if len(changes) == 1 && changes[0].Plain() {
+		return BuildWhatsAppMessage(changes[0].OldIP, changes[0].NewIP, timestamp, gateway)
+	}
+	return BuildCombinedWhatsAppMessage(changes, timestamp, gateway)
+}
+
+// BuildCatchUpLineMessage describes what happened while the monitor was not running
+func BuildCatchUpLineMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	return BuildCatchUpWhatsAppMessage(changes, timestamp, gateway)
+}
+
+// BuildHookFailureLineMessage creates the LINE message for failed hooks
+func BuildHookFailureLineMessage(failures []HookFailure, timestamp time.Time) string {
+	return BuildHookFailureWhatsAppMessage(failures, timestamp)
+}
+
+// BuildLifecycleLineMessage creates the LINE message when the monitor
+// starts, stops or sends its heartbeat
+func BuildLifecycleLineMessage(status LifecycleStatus, timestamp time.Time) string {
+	return BuildLifecycleWhatsAppMessage(status, timestamp)
+}
+
+// BuildFetchFailureLineMessage creates the LINE message for checks that
+// keep failing, or work again when recovered
+func BuildFetchFailureLineMessage(failures []CheckFailure, recovered bool, timestamp time.Time) string {
+	return BuildFetchFailureWhatsAppMessage(failures, recovered, timestamp)
+}
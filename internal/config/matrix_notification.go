@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// BuildMatrixMessage creates the Matrix message (plain text and HTML) for an IP change
+func BuildMatrixMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) (string, string) {
+	var text, formatted strings.Builder
+	text.WriteString("🚨 IP Address Changed\n")
+	formatted.WriteString("<h4>🚨 IP Address Changed</h4><ul>")
+
+	for _, change := range changes {
+		fmt.Fprintf(&text, "%s: %s → %s\n", change.Label(), change.OldIP, change.NewIP)
+		fmt.Fprintf(&formatted, "<li><b>%s:</b> <code>%s</code> → <code>%s</code></li>",
+			html.EscapeString(change.Label()), html.EscapeString(change.OldIP), html.EscapeString(change.NewIP))
+		for _, event := range change.Warnings() {
+			fmt.Fprintf(&text, "⚠️ %s\n", event)
+			fmt.Fprintf(&formatted, "<li>⚠️ %s</li>", html.EscapeString(event))
+		}
+		if location := change.Location(); location != "" {
+			fmt.Fprintf(&text, "Location: %s\n", location)
+			fmt.Fprintf(&formatted, "<li><b>Location:</b> %s</li>", html.EscapeString(location))
+		}
+	}
+
+	writeMatrixFooter(&text, &formatted, "Time", timestamp, gateway)
+	return text.String(), formatted.String()
+}
+
+// BuildCatchUpMatrixMessage describes what happened while the monitor was not running
+func BuildCatchUpMatrixMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) (string, string) {
+	var text, formatted strings.Builder
+	text.WriteString("🚨 Changed While Offline\n")
+	formatted.WriteString("<h4>🚨 Changed While Offline</h4><ul>")
+
+	for _, change := range changes {
+		line := fmt.Sprintf("%s (unchanged)", change.NewIP)
+		if change.Missed {
+			line = fmt.Sprintf("%s → %s", change.OldIP, change.NewIP)
+		}
+		fmt.Fprintf(&text, "%s: %s\n", change.Label(), line)
+		fmt.Fprintf(&formatted, "<li><b>%s:</b> %s</li>", html.EscapeString(change.Label()), html.EscapeString(line))
+
+		for _, event := range change.Warnings() {
+			fmt.Fprintf(&text, "⚠️ %s\n", event)
+			fmt.Fprintf(&formatted, "<li>⚠️ %s</li>", html.EscapeString(event))
+		}
+		if location := change.Location(); location != "" {
+			fmt.Fprintf(&text, "Location: %s\n", location)
+			fmt.Fprintf(&formatted, "<li><b>Location:</b> %s</li>", html.EscapeString(location))
+		}
+		if change.DNSRecord != "" {
+			dns := formatDNSIPs(change.DNSIPs) + formatDNSState(change)
+			fmt.Fprintf(&text, "DNS %s: %s\n", change.DNSRecord, dns)
+			fmt.Fprintf(&formatted, "<li><b>DNS %s:</b> %s</li>", html.EscapeString(change.DNSRecord), html.EscapeString(dns))
+		}
+		if note := change.OutageNote(); note != "" {
+			fmt.Fprintf(&text, "%s\n", note)
+			fmt.Fprintf(&formatted, "<li>%s</li>", html.EscapeString(note))
+		}
+	}
+
+	writeMatrixFooter(&text, &formatted, "Checked", timestamp, gateway)
+	return text.String(), formatted.String()
+}
+
+// BuildHookFailureMatrixMessage creates the Matrix message for failed hooks
+func BuildHookFailureMatrixMessage(failures []HookFailure, timestamp time.Time) (string, string) {
+	var text, formatted strings.Builder
+	text.WriteString("⚠️ IP Change Hook Failed\n")
+	formatted.WriteString("<h4>⚠️ IP Change Hook Failed</h4><ul>")
+
+	for _, failure := range failures {
+		fmt.Fprintf(&text, "%s: %s\n", failure.Name, failure.Error)
+		fmt.Fprintf(&formatted, "<li><b>%s:</b> %s", html.EscapeString(failure.Name), html.EscapeString(failure.Error))
+		if failure.Output != "" {
+			fmt.Fprintf(&formatted, "<pre>%s</pre>", html.EscapeString(truncateText(failure.Output, 2000)))
+		}
+		formatted.WriteString("</li>")
+	}
+
+	writeMatrixFooter(&text, &formatted, "Time", timestamp, nil)
+	return text.String(), formatted.String()
+}
+
+// BuildLifecycleMatrixMessage creates the Matrix message when the monitor
+// starts, stops or sends its heartbeat
+func BuildLifecycleMatrixMessage(status LifecycleStatus, timestamp time.Time) (string, string) {
+	var text, formatted strings.Builder
+	title := status.emoji() + " " + status.Title()
+	text.WriteString(title + "\n")
+	fmt.Fprintf(&formatted, "<h4>%s</h4><ul>", title)
+
+	for _, fact := range status.facts(timestamp) {
+		fmt.Fprintf(&text, "%s: %s\n", fact.Name, fact.Value)
+		fmt.Fprintf(&formatted, "<li><b>%s:</b> %s</li>", html.EscapeString(fact.Name), html.EscapeString(fact.Value))
+	}
+	formatted.WriteString("</ul>")
+
+	return text.String(), formatted.String()
+}
+
+// BuildFetchFailureMatrixMessage creates the Matrix message for checks that
+// keep failing, or work again when recovered
+func BuildFetchFailureMatrixMessage(failures []CheckFailure, recovered bool, timestamp time.Time) (string, string) {
+	var text, formatted strings.Builder
+	title := fetchFailureEmoji(recovered) + " " + fetchFailureTitle(recovered)
+	text.WriteString(title + "\n")
+	fmt.Fprintf(&formatted, "<h4>%s</h4><ul>", title)
+
+	for _, fact := range fetchFailureFacts(failures, recovered, timestamp) {
+		fmt.Fprintf(&text, "%s: %s\n", fact.Name, fact.Value)
+		fmt.Fprintf(&formatted, "<li><b>%s:</b> %s</li>", html.EscapeString(fact.Name), html.EscapeString(fact.Value))
+	}
+	formatted.WriteString("</ul>")
+
+	return text.String(), formatted.String()
+}
+
+// writeMatrixFooter closes the list with the time and default gateway, if known
+func writeMatrixFooter(text, formatted *strings.Builder, label string, timestamp time.Time, gateway *GatewayContext) {
+	stamp := timestamp.Format("2006-01-02 15:04:05")
+	fmt.Fprintf(text, "%s: %s\n", label, stamp)
+	fmt.Fprintf(formatted, "<li><b>%s:</b> %s</li>", label, stamp)
+
+	if gateway != nil {
+		fmt.Fprintf(text, "Gateway: %s\n", gateway.Current())
+		fmt.Fprintf(formatted, "<li><b>Gateway:</b> %s</li>", html.EscapeString(gateway.Current()))
+		if gateway.Changed {
+			fmt.Fprintf(text, "⚠️ Gateway changed from %s\n", gateway.Previous())
+			fmt.Fprintf(formatted, "<li>⚠️ Gateway changed from %s</li>", html.EscapeString(gateway.Previous()))
+		}
+	}
+
+	formatted.WriteString("</ul>")
+}
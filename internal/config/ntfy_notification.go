@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BuildNtfyMessage creates the ntfy title and body for an IP change
+func BuildNtfyMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) (string, string) {
+	title := "IP Address Changed"
+
+	var body strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&body, "%s: %s → %s\n", change.Label(), change.OldIP, change.NewIP)
+		for _, event := range change.Warnings() {
+			title = event
+			fmt.Fprintf(&body, "%s\n", event)
+		}
+		if location := change.Location(); location != "" {
+			fmt.Fprintf(&body, "Location: %s\n", location)
+		}
+	}
+	fmt.Fprintf(&body, "Time: %s\n%s", timestamp.Format("2006-01-02 15:04:05"), buildWhatsAppGatewayLines(gateway))
+
+	return title, strings.TrimSpace(body.String())
+}
+
+// BuildCatchUpNtfyMessage describes what happened while the monitor was not running
+func BuildCatchUpNtfyMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) (string, string) {
+	var body strings.Builder
+	for _, change := range changes {
+		if change.Missed {
+			fmt.Fprintf(&body, "%s: %s → %s\n", change.Label(), change.OldIP, change.NewIP)
+			for _, event := range change.Warnings() {
+				fmt.Fprintf(&body, "%s\n", event)
+			}
+			if location := change.Location(); location != "" {
+				fmt.Fprintf(&body, "Location: %s\n", location)
+			}
+		} else {
+			fmt.Fprintf(&body, "%s: %s (unchanged)\n", change.Label(), change.NewIP)
+		}
+		if change.DNSRecord != "" {
+			fmt.Fprintf(&body, "DNS %s: %s%s\n", change.DNSRecord, formatDNSIPs(change.DNSIPs), formatDNSState(change))
+		}
+		if note := change.OutageNote(); note != "" {
+			fmt.Fprintf(&body, "%s\n", note)
+		}
+	}
+	fmt.Fprintf(&body, "Checked: %s\n%s", timestamp.Format("2006-01-02 15:04:05"), buildWhatsAppGatewayLines(gateway))
+
+	return "Changed While Offline", strings.TrimSpace(body.String())
+}
+
+// BuildHookFailureNtfyMessage creates the ntfy title and body for failed hooks
+func BuildHookFailureNtfyMessage(failures []HookFailure, timestamp time.Time) (string, string) {
+	var body strings.Builder
+	for _, failure := range failures {
+		fmt.Fprintf(&body, "%s: %s\n", failure.Name, failure.Error)
+	}
+	fmt.Fprintf(&body, "Time: %s", timestamp.Format("2006-01-02 15:04:05"))
+
+	return "IP Change Hook Failed", body.String()
+}
+
+// BuildLifecycleNtfyMessage creates the ntfy title and body when the
+// monitor starts, stops or sends its heartbeat
+func BuildLifecycleNtfyMessage(status LifecycleStatus, timestamp time.Time) (string, string) {
+	return status.Title(), strings.TrimSpace(buildLifecycleLines(status, timestamp))
+}
+
+// BuildFetchFailureNtfyMessage creates the ntfy title and body for checks
+// that keep failing, or work again when recovered
+func BuildFetchFailureNtfyMessage(failures []CheckFailure, recovered bool, timestamp time.Time) (string, string) {
+	return fetchFailureTitle(recovered), strings.TrimSpace(buildFetchFailureLines(failures, recovered, timestamp))
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BuildPagerDutySummary creates the incident summary for an IP change
+func BuildPagerDutySummary(changes []IPChange, site string) string {
+	parts := make([]string, 0, len(changes))
+	for _, change := range changes {
+		part := fmt.Sprintf("%s %s → %s", change.Label(), change.OldIP, change.NewIP)
+		for _, event := range change.Warnings() {
+			part += " (" + event + ")"
+		}
+		parts = append(parts, part)
+	}
+	return fmt.Sprintf("Public IP changed on %s: %s", site, strings.Join(parts, ", "))
+}
+
+// BuildCatchUpPagerDutySummary creates the incident summary for changes missed while not running
+func BuildCatchUpPagerDutySummary(changes []IPChange, site string) string {
+	var parts []string
+	for _, change := range changes {
+		switch {
+		case change.Missed:
+			parts = append(parts, fmt.Sprintf("%s %s → %s", change.Label(), change.OldIP, change.NewIP))
+		case change.DNSStale():
+			parts = append(parts, fmt.Sprintf("DNS %s does not point at %s", change.DNSRecord, change.NewIP))
+		}
+	}
+	return fmt.Sprintf("Public IP changed on %s while the monitor was not running: %s", site, strings.Join(parts, ", "))
+}
+
+// BuildHookFailurePagerDutySummary creates the incident summary for failed hooks
+func BuildHookFailurePagerDutySummary(failures []HookFailure, site string) string {
+	parts := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		parts = append(parts, fmt.Sprintf("%s: %s", failure.Name, failure.Error))
+	}
+	return fmt.Sprintf("IP change hook failed on %s: %s", site, strings.Join(parts, ", "))
+}
+
+// BuildFetchFailurePagerDutySummary creates the incident summary for checks that keep failing
+func BuildFetchFailurePagerDutySummary(failures []CheckFailure, site string) string {
+	parts := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		parts = append(parts, fmt.Sprintf("%s failed %d times in a row since %s (%s)",
+			failure.Label(), failure.Count, failure.Since.Format("2006-01-02 15:04:05"), failure.Error))
+	}
+	return fmt.Sprintf("Public IP checks failing on %s: %s", site, strings.Join(parts, ", "))
+}
+
+// BuildPagerDutyDetails creates the custom details shown on an incident
+func BuildPagerDutyDetails(changes []IPChange, failures []CheckFailure, timestamp time.Time, gateway *GatewayContext) map[string]string {
+	details := map[string]string{
+		"time": timestamp.Format("2006-01-02 15:04:05"),
+	}
+	for _, change := range changes {
+		details[change.Label()+" old IP"] = change.OldIP
+		details[change.Label()+" new IP"] = change.NewIP
+		if location := change.Location(); location != "" {
+			details[change.Label()+" location"] = location
+		}
+	}
+	for _, failure := range failures {
+		details[failure.Label()+" last error"] = failure.Error
+	}
+	if gateway != nil {
+		details["gateway"] = gateway.Current()
+		if gateway.Changed {
+			details["previous gateway"] = gateway.Previous()
+		}
+	}
+	return details
+}
@@ -0,0 +1,271 @@
+package config
+
+import "strings"
+
+// fieldDocs describes the configuration fields by their JSON path, for the
+// comments of "config defaults"; keep in sync with the README
+var fieldDocs = map[string]string{
+	"check_interval_seconds":                       "How often to check IP (in seconds)",
+	"site":                                         "Name of the monitored location, included in notification events",
+	"branding.product_name":                        "Product name used in subject lines and as the email sender name",
+	"branding.signature":                           "Line closing every message; defaults to the product name",
+	"branding.no_emoji":                            "Send all messages without emoji",
+	"branding.no_emoji_channels":                   "Channels sent without emoji, by name (e.g. whatsapp, line)",
+	"branding.no_reference":                        `Leave the "Ref:" event ID out of messages; JSON payloads keep it`,
+	"notify_urls":                                  "Apprise-style notification URLs (e.g. slack://TokenA/TokenB/TokenC) enabling the matching channels",
+	"dry_run":                                      "Log notifications instead of sending them",
+	"muted_channels":                               "Channels not notified, by name (e.g. Email, Webhook)",
+	"message_limits":                               "Longest message in bytes by channel name (e.g. sms, webhook), replacing the built-in limits; 0 removes a limit",
+	"logging.timezone":                             "Timezone for log timestamps",
+	"logging.format":                               "Go time format for logs",
+	"logging.identifier":                           "Log identifier prefix",
+	"email.enabled":                                "Enable email notifications",
+	"email.from":                                   "Sender email address",
+	"email.from_name":                              "Sender display name",
+	"email.password":                               "App password (not regular password)",
+	"email.to":                                     "Recipient email address",
+	"email.smtp_host":                              "SMTP server hostname",
+	"email.smtp_port":                              "SMTP server port",
+	"email.timeout_seconds":                        "SMTP timeout in seconds",
+	"email.relays":                                 "Backup SMTP servers ({smtp_host, smtp_port, username, password}) tried in order when smtp_host fails",
+	"email.pgp_public_key_file":                    "OpenPGP public key (gpg --armor --export) the email bodies are encrypted to",
+	"email.backend":                                `"smtp", or "gmail" to send through the Gmail API with OAuth2 instead of an app password`,
+	"email.gmail.client_id":                        "OAuth2 client ID of the gmail backend",
+	"email.gmail.client_secret":                    "OAuth2 client secret of the gmail backend",
+	"email.gmail.refresh_token":                    "OAuth2 refresh token granted for the gmail.send scope",
+	"slack.enabled":                                "Enable Slack notifications",
+	"slack.webhook_url":                            "Incoming webhook URL",
+	"slack.token":                                  "Bot token; posts via chat.postMessage instead of the webhook",
+	"slack.channel":                                "Channel ID or name for chat.postMessage",
+	"slack.timeout_seconds":                        "Slack API timeout in seconds",
+	"discord.enabled":                              "Enable Discord notifications",
+	"discord.webhook_url":                          "Discord channel webhook URL",
+	"discord.username":                             "Overrides the webhook's display name",
+	"discord.timeout_seconds":                      "Discord webhook timeout in seconds",
+	"teams.enabled":                                "Enable Microsoft Teams notifications",
+	"teams.webhook_url":                            `Teams incoming webhook or Workflows ("Post to a channel when a webhook request is received") URL`,
+	"teams.timeout_seconds":                        "Teams webhook timeout in seconds",
+	"google_sheets.enabled":                        "Append every IP change as a row to a Google Sheet",
+	"google_sheets.credentials_file":               "Service account key file (JSON)",
+	"google_sheets.spreadsheet_id":                 "ID from the spreadsheet URL",
+	"google_sheets.sheet_name":                     "Tab the rows are appended to",
+	"google_sheets.timeout_seconds":                "Google API timeout in seconds",
+	"file.enabled":                                 "Write a one-line message per event to a file or named pipe",
+	"file.path":                                    "File to append to, or named pipe (FIFO) to write to",
+	"matrix.enabled":                               "Enable Matrix notifications",
+	"matrix.homeserver_url":                        "Homeserver base URL",
+	"matrix.access_token":                          "Access token of the account posting the messages",
+	"matrix.room_id":                               "Room to post to, e.g. !abcdef:example.org (the account must have joined it)",
+	"matrix.timeout_seconds":                       "Matrix request timeout in seconds",
+	"ntfy.enabled":                                 "Enable ntfy push notifications",
+	"ntfy.server":                                  "ntfy server URL (self-hosted or public)",
+	"ntfy.topic":                                   "Topic to publish to; pick a hard-to-guess name on the public server",
+	"ntfy.priority":                                "min, low, default, high, max or 1-5",
+	"ntfy.token":                                   "Access token for protected topics",
+	"ntfy.timeout_seconds":                         "ntfy request timeout in seconds",
+	"line.enabled":                                 "Push notifications to a LINE chat through a Messaging API bot",
+	"line.token":                                   "Channel access token of the bot's Messaging API channel",
+	"line.to":                                      "User, group or room ID to push to",
+	"line.timeout_seconds":                         "LINE API timeout in seconds",
+	"line.recent_changes":                          "Earlier addresses listed in change messages with how long each was kept (verbose level), up to 10; 0 lists none",
+	"dingtalk.enabled":                             "Post notifications to a DingTalk group through a custom robot",
+	"dingtalk.webhook_url":                         "Robot webhook URL including its access_token",
+	"dingtalk.secret":                              "Signing secret (SEC...) when the robot is secured with signatures",
+	"dingtalk.timeout_seconds":                     "DingTalk webhook timeout in seconds",
+	"wecom.enabled":                                "Post notifications to a WeChat Work (WeCom) group through a group bot",
+	"wecom.webhook_url":                            "Group bot webhook URL including its key",
+	"wecom.timeout_seconds":                        "WeCom webhook timeout in seconds",
+	"sns.enabled":                                  "Publish notifications to an AWS SNS topic, for SMS, email and Lambda subscribers",
+	"sns.region":                                   "AWS region of the topic; defaults to the region in the topic ARN",
+	"sns.topic_arn":                                "ARN of the topic to publish to",
+	"sns.access_key_id":                            "Access key ID; empty uses the AWS_* environment variables, the ECS task role or the EC2 instance role",
+	"sns.secret_access_key":                        "Secret access key of the access key ID",
+	"sns.session_token":                            "Session token of temporary credentials",
+	"sns.role_arn":                                 "Role assumed through STS before publishing",
+	"sns.external_id":                              "External ID required by the role's trust policy",
+	"sns.timeout_seconds":                          "SNS API timeout in seconds",
+	"mqtt.enabled":                                 "Publish the current IP and change events to an MQTT broker",
+	"mqtt.broker":                                  "Broker URL, mqtt://host:1883 or mqtts://host:8883 for TLS",
+	"mqtt.client_id":                               "MQTT client identifier",
+	"mqtt.username":                                "Broker user name",
+	"mqtt.password":                                "Broker password",
+	"mqtt.topic":                                   "Topic receiving the current IP as a retained message",
+	"mqtt.event_topic":                             "Topic receiving each event as JSON; empty disables",
+	"mqtt.qos":                                     "Quality of service: 0 (at most once), 1 (at least once) or 2 (exactly once)",
+	"mqtt.ca_file":                                 "PEM bundle used to verify the broker instead of the system roots",
+	"mqtt.insecure_skip_verify":                    "Accept any broker certificate (test brokers only)",
+	"mqtt.timeout_seconds":                         "Broker session timeout in seconds",
+	"syslog.enabled":                               "Forward IP changes and failures as RFC 5424 messages to a syslog collector over TLS",
+	"syslog.address":                               "Collector host:port; port 6514 when omitted",
+	"syslog.facility":                              "Syslog facility, e.g. local0, user or daemon",
+	"syslog.app_name":                              "APP-NAME of the messages",
+	"syslog.hostname":                              "HOSTNAME of the messages; the site when empty",
+	"syslog.ca_file":                               "PEM bundle used to verify the collector instead of the system roots",
+	"syslog.cert_file":                             "Client certificate, for collectors requiring mutual TLS",
+	"syslog.key_file":                              "Key of the client certificate",
+	"syslog.insecure_skip_verify":                  "Accept any collector certificate (test collectors only)",
+	"syslog.timeout_seconds":                       "Collector connection timeout in seconds",
+	"pagerduty.enabled":                            "Trigger PagerDuty incidents on IP changes, hook failures and sustained check failures",
+	"pagerduty.routing_key":                        "Integration key of an Events API v2 integration",
+	"pagerduty.events_url":                         "Events API v2 endpoint",
+	"pagerduty.severity_map":                       "Maps event severities (info, warning for failovers, hosting exits and hook failures, critical for check failures) to PagerDuty severities (critical, error, warning, info)",
+	"pagerduty.auto_resolve":                       "Resolve check failure incidents when checks work again, and IP change incidents right after triggering them",
+	"pagerduty.timeout_seconds":                    "PagerDuty API timeout in seconds",
+	"webhook.enabled":                              "Enable generic webhook notifications",
+	"webhook.urls":                                 "URLs the payload is sent to",
+	"webhook.method":                               "HTTP method",
+	"webhook.headers":                              "Extra request headers, e.g. Authorization",
+	"webhook.payload_template":                     "Go text/template for the request body",
+	"webhook.timeout_seconds":                      "Webhook request timeout in seconds",
+	"webhook.outbox.enabled":                       "Keep events until each URL accepted them and replay them in order after outages",
+	"webhook.outbox.max_age_hours":                 "Events not delivered within this are discarded; negative keeps them",
+	"webhook.outbox.replay_seconds":                "How often events left in the outbox are sent again, unless schedules.webhook_outbox is set, and how long a replay may take",
+	"whatsapp.enabled":                             "Enable WhatsApp notifications",
+	"whatsapp.token":                               "WhatsApp Business API token",
+	"whatsapp.phone_id":                            "Phone number ID from Meta",
+	"whatsapp.recipient_number":                    "Recipient's WhatsApp number",
+	"whatsapp.api_version":                         "WhatsApp API version",
+	"whatsapp.timeout_seconds":                     "WhatsApp API timeout in seconds",
+	"whatsapp.recent_changes":                      "Earlier addresses listed in change messages with how long each was kept (verbose level), up to 10; 0 lists none",
+	"ip.services":                                  "List of IP detection services",
+	"ip.sources":                                   "Other detection methods tried after the services, in order",
+	"ip.quorum":                                    "Services and sources that must report the same address before it is accepted; 0 or 1 takes the first answer",
+	"ip.adaptive_order":                            "In sequential mode, try services and sources that failed repeatedly lately last",
+	"ip.user_agent":                                "User-Agent of the requests to the services and http/router sources; empty sends public-ip-monitor/<version>",
+	"ip.service_headers":                           "Extra request headers by service URL, e.g. the API key of a paid service",
+	"ip.tls.ca_file":                               "PEM bundle trusted in addition to the system roots, e.g. the private CA of a self-hosted service",
+	"ip.tls.cert_file":                             "Client certificate presented to services requiring mutual TLS",
+	"ip.tls.key_file":                              "Key of the client certificate",
+	"ip.doh.url":                                   "DNS-over-HTTPS server resolving the hostnames of the services and ip.dns_record instead of the system resolver",
+	"ip.doh.bootstrap_ips":                         "Addresses of the DoH server, so that its own hostname needs no lookup",
+	"ip.fetch_mode":                                `"sequential" tries the services and sources in order, "race" asks them all at once and takes the first answer`,
+	"ip.timeout_seconds":                           "Timeout for IP service requests",
+	"ip.data_dir":                                  "Directory for storing data files",
+	"ip.records_file":                              "Filename for IP change records",
+	"ip.last_ip_file":                              "Filename for last known IP",
+	"ip.families":                                  `Address families to monitor separately ("ipv4", "ipv6"); empty uses the OS preference`,
+	"ip.family_merge_window_seconds":               "Changes of different families within this window are sent as one notification",
+	"ip.failure_threshold":                         "Consecutive failed checks after which a check failure alert is sent to all channels",
+	"ip.recovery_threshold":                        "Consecutive successful checks after an alerted failure before the recovery is sent",
+	"ip.startup_grace_seconds":                     "On startup, retry with backoff (1s, 2s, 4s, ... up to 30s) until the network is up before the first check; negative disables",
+	"ip.expected_ranges":                           "CIDR ranges the public IP is expected in, e.g. the ISP's known blocks; an IP outside them escalates the notification",
+	"ip.dns_record":                                "Hostname (e.g., your DDNS name) expected to resolve to the public IP; checked on startup",
+	"ip.detect_gateway":                            "Include the default gateway (router IP/MAC) in notifications and log when it changes (Linux)",
+	"ip.services_index.url":                        "URL of a signed services index that replaces ip.services",
+	"ip.services_index.public_key":                 "Base64 Ed25519 public key the index must be signed with",
+	"ip.services_index.refresh_interval_minutes":   "How often the index is re-fetched, unless schedules.services_index is set",
+	"ip.services_index.cache_file":                 "Last verified index, used when the URL is unreachable",
+	"ip.wans":                                      "WAN links monitored separately, with their own history",
+	"check_log.enabled":                            "Record every check (outcome, latency, source) for uptime statistics and GET /checks",
+	"check_log.file":                               "File in ip.data_dir the checks are appended to",
+	"check_log.max_entries":                        "Checks kept; older ones are dropped",
+	"check_log.max_age_hours":                      "Checks older than this are dropped",
+	"retention.history_days":                       "IP change records older than this are pruned, except the current IP; 0 keeps them",
+	"retention.failed_notifications_days":          "Failed notifications older than this are pruned; 0 keeps them",
+	"enrichment.enabled":                           "Look up the network (ASN) of new IPs and warn when it belongs to a hosting or VPN provider",
+	"enrichment.hosting_asns":                      "ASNs flagged in addition to the built-in hosting and VPN providers",
+	"enrichment.hosting_list_file":                 "File of ASNs (AS64500) and CIDR ranges flagged as hosting, one per line",
+	"enrichment.expected_countries":                "Countries (ISO codes, e.g. DE) new IPs are expected in; an IP located elsewhere raises an urgent alert",
+	"enrichment.timeout_seconds":                   "Network lookup timeout in seconds",
+	"enrichment.geolocation.provider":              "Service geolocating new IPs: ipinfo, ip-api or maxmind (local databases); empty disables geolocation",
+	"enrichment.geolocation.token":                 "ipinfo access token or ip-api Pro key; the free tiers need none",
+	"enrichment.geolocation.url":                   "Replaces the provider's base URL, e.g. for a mirror",
+	"enrichment.geolocation.maxmind.city_database": "GeoLite2 City database, relative to ip.data_dir unless absolute",
+	"enrichment.geolocation.maxmind.asn_database":  "GeoLite2 ASN database, used when present",
+	"enrichment.geolocation.maxmind.account_id":    "MaxMind account ID; with license_key, the databases are downloaded and kept up to date",
+	"enrichment.geolocation.maxmind.license_key":   "MaxMind license key",
+	"enrichment.geolocation.maxmind.download_url":  "Replaces MaxMind's download URL, {edition} being e.g. GeoLite2-City",
+	"anomaly.enabled":                              "Alert when the IP changes far more often than usual, e.g. on a line fault",
+	"anomaly.window_hours":                         "Period the latest changes are counted in",
+	"anomaly.factor":                               "Sensitivity: how many times the usual number of changes is unusual; lower alerts sooner",
+	"anomaly.min_changes":                          "Fewer changes in the window are never unusual",
+	"anomaly.learn_days":                           "History the usual frequency is learned from",
+	"privacy.mode":                                 "Hide public IPs in logs and shared outputs: mask (keep the /24 or /48) or hash",
+	"privacy.salt":                                 "Secret mixed into hashes; required for hash mode",
+	"api.enabled":                                  "Serve the current IP over HTTP",
+	"api.listen":                                   "Address the API listens on; 127.0.0.1:8787 limits it to local clients",
+	"api.token":                                    "Token clients must send as Authorization: Bearer <token> or ?token=; empty allows anyone",
+	"api.admin_token":                              "Enables the /admin endpoints, which require it as Authorization: Bearer <token>",
+	"api.max_wait_seconds":                         "Longest time an /ip/wait request is held open",
+	"hooks.commands":                               "Commands run on every IP change",
+	"hooks.timeout_seconds":                        "Default timeout for each hook command",
+	"hooks.user":                                   "Default user to run hook commands as (Unix only)",
+	"hooks.output_limit_bytes":                     "Bytes of stdout/stderr kept per hook for logs and notifications",
+	"hooks.notify_on_failure":                      "Send failed hook output through the notification channels",
+	"on_change_commands":                           "Commands run on every IP change, added to hooks.commands",
+	"shortlink.enabled":                            "Point a short link at the current IP after changes",
+	"shortlink.provider":                           "Short link service: shlink, kutt or http",
+	"shortlink.api_url":                            "Base URL of the service; for http, the URL requested",
+	"shortlink.api_key":                            "Shlink/Kutt API key; for http, a bearer token",
+	"shortlink.id":                                 "Short code or link ID, e.g. home",
+	"shortlink.target":                             "URL the link points at, {ip} being the current IP",
+	"shortlink.family":                             "Family whose IP is used; the first monitored by default",
+	"shortlink.method":                             "http: request method",
+	"shortlink.headers":                            "http: extra request headers",
+	"shortlink.body":                               "http: request body; {url}, {ip} and {id} are replaced",
+	"shortlink.min_interval_seconds":               "Minimum time between updates, for rate-limited APIs",
+	"shortlink.timeout_seconds":                    "Short link API request timeout in seconds",
+	"acme.domains":                                 "Domains whose certificates are validated through the public IP; empty disables renewal",
+	"acme.command":                                 "ACME client run to renew the certificates",
+	"acme.args":                                    "Arguments of the ACME client",
+	"acme.dns_timeout_seconds":                     "How long to wait for the domains to resolve to the new IP before giving up",
+	"acme.timeout_seconds":                         "Timeout of the renewal command in seconds",
+	"acme.user":                                    "User to run the renewal command as (Unix only)",
+	"plugins.commands":                             "Notification plugins receiving every event as JSON on stdin",
+	"plugins.timeout_seconds":                      "Default timeout for each plugin run",
+	"plugins.user":                                 "Default user to run plugins as (Unix only)",
+	"quiet_hours.enabled":                          "Hold notifications during a daily window and send a summary when it ends",
+	"quiet_hours.start":                            "Start of the window (HH:MM, logging timezone)",
+	"quiet_hours.end":                              "End of the window (HH:MM); may be on the next day",
+	"quiet_hours.urgent_channels":                  "Channels notified right away, by name (e.g. pagerduty)",
+	"digest.template":                              "Go text/template of digests listing the changes held during quiet hours; empty uses the built-in one",
+	"digest.channels":                              "Digest templates by channel name (e.g. slack), replacing digest.template",
+	"circuit_breaker.failure_threshold":            "Consecutive failed attempts before a channel is skipped; -1 disables",
+	"circuit_breaker.cooldown_seconds":             "How long a failing channel is skipped before it is probed again",
+	"escalation.enabled":                           "Notify the escalation channels of events not acknowledged in time",
+	"escalation.channels":                          "Secondary channels, by name (e.g. pagerduty, SNS); only notified of escalated events",
+	"escalation.after_minutes":                     "Time to acknowledge an event before it is escalated",
+	"escalation.events":                            "Event types escalated; empty escalates all",
+	"lifecycle.startup":                            "Notify when the monitor starts, with the current IPs",
+	"lifecycle.shutdown":                           "Notify when the monitor shuts down",
+	"lifecycle.heartbeat":                          "Notify daily (schedules.heartbeat, default 09:00) that the monitor is alive",
+	"quotas.channels":                              `Sends per day by channel name, e.g. {"WhatsApp": 250, "Email": 100}`,
+	"quotas.reserve_percent":                       "Share of each quota kept for IP changes and warnings; -1 keeps none",
+	"quotas.reroute":                               `Channel taking over what a channel has no quota left for, e.g. {"WhatsApp": "Slack"}`,
+	"ping.enabled":                                 "Ping a dead man's switch service (healthchecks.io, Dead Man's Snitch) after every check cycle",
+	"ping.url":                                     "URL pinged when every check of the cycle succeeded",
+	"ping.fail_url":                                "URL pinged when a check failed; url + /fail by default",
+	"ping.timeout_seconds":                         "Ping request timeout in seconds",
+	"resources.gomaxprocs":                         "OS threads running Go code; 0 derives it from the container CPU quota unless GOMAXPROCS is set",
+	"resources.memory_limit_mb":                    "Go soft memory limit; 0 uses 90% of the container memory limit, if any, unless GOMEMLIMIT is set",
+	"resources.ballast_mb":                         "Heap ballast that makes the GC run less often on small heaps",
+	"low_write.enabled":                            "Keep the last IPs and the check log in memory, flushing them periodically, to spare SD cards",
+	"low_write.flush_interval_minutes":             "How often the last IPs and the check log are written, unless schedules.flush is set",
+	"low_write.runtime_dir":                        "Directory of the health file written on every check, ideally a tmpfs; the system temporary directory when empty",
+	"dns_cache.enabled":                            "Cache DNS answers of all outbound connections (IP services, SMTP, notification APIs)",
+	"dns_cache.max_ttl_seconds":                    "Answers are cached for their TTL, but at most this long",
+	"dns_cache.negative_ttl_seconds":               `Upper bound for caching "no such host" answers`,
+	"dns_cache.stale_ttl_seconds":                  "How long expired answers are still used when the DNS servers fail or time out",
+	"proxy.url":                                    `Proxy of the IP services and notification APIs: http://, https://, socks5:// or socks5h://, or "direct" for none; HTTP_PROXY/HTTPS_PROXY/NO_PROXY apply when empty`,
+	"proxy.no_proxy":                               `Hosts, domains (".lan" or "example.com" with subdomains) and CIDR ranges reached without the proxy`,
+	"update.manifest_url":                          "Signed release manifest listing the binary of every platform; empty disables self-update",
+	"update.public_key":                            "Base64 Ed25519 public key the manifest must be signed with",
+	"update.auto_apply":                            "Scheduled checks install new versions and restart under systemd, instead of only logging them",
+	"update.systemd_unit":                          "Unit restarted by self-update -restart",
+	"update.timeout_seconds":                       "Timeout of the manifest and binary downloads in seconds",
+	"schedules":                                    `Schedules of auxiliary tasks by task name, e.g. {"services_index": "0 */6 * * *"}`,
+}
+
+// channelEventsDoc describes the "events" field of every notification channel
+const channelEventsDoc = `Event types sent to the channel, e.g. ["ip_changed", "fetch_failed"]; empty sends all it supports`
+
+// fieldDoc returns the description of the field at a JSON path
+func fieldDoc(path string) string {
+	if doc, ok := fieldDocs[path]; ok {
+		return doc
+	}
+	if strings.HasSuffix(path, ".events") {
+		return channelEventsDoc
+	}
+	return ""
+}
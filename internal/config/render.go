@@ -0,0 +1,205 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"public-ip-monitor/internal/debughttp"
+)
+
+// RenderOptions controls how Render writes a configuration
+type RenderOptions struct {
+	Comments bool // Describe each field in a comment above it
+	Redact   bool // Hide credentials and the secret parts of URLs
+}
+
+// secretField matches the names of fields and header keys whose values are redacted
+var secretField = regexp.MustCompile(`(?i)(password|passwd|token|secret|routing_key|api[_-]?key|authorization|cookie|notify_urls|salt)`)
+
+// redacted replaces secret values in rendered configurations
+const redacted = "REDACTED"
+
+// Render writes the configuration as indented JSON with the fields in
+// declaration order. With comments, the output is JSON with // comments,
+// which Load accepts.
+func Render(config *Config, options RenderOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := renderValue(&buf, reflect.ValueOf(*config), "", "", 0, options); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// renderValue writes a value; path is its JSON path and name its field or map key
+func renderValue(buf *bytes.Buffer, v reflect.Value, path, name string, depth int, options RenderOptions) error {
+	indent := strings.Repeat("    ", depth+1)
+	closing := strings.Repeat("    ", depth)
+
+	switch v.Kind() {
+	case reflect.Struct:
+		type field struct {
+			name  string
+			value reflect.Value
+		}
+		var fields []field
+		for i := 0; i < v.NumField(); i++ {
+			tag := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+			if tag == "" || tag == "-" || !v.Type().Field(i).IsExported() {
+				continue
+			}
+			fields = append(fields, field{name: tag, value: v.Field(i)})
+		}
+
+		buf.WriteString("{\n")
+		for i, f := range fields {
+			fieldPath := joinPath(path, f.name)
+			if doc := fieldDoc(fieldPath); options.Comments && doc != "" {
+				fmt.Fprintf(buf, "%s// %s\n", indent, doc)
+			}
+			fmt.Fprintf(buf, "%s%q: ", indent, f.name)
+			if err := renderValue(buf, f.value, fieldPath, f.name, depth+1, options); err != nil {
+				return err
+			}
+			if i < len(fields)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(closing + "}")
+
+	case reflect.Map:
+		if v.Len() == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+
+		buf.WriteString("{\n")
+		for i, key := range keys {
+			fmt.Fprintf(buf, "%s%q: ", indent, key)
+			if err := renderValue(buf, v.MapIndex(reflect.ValueOf(key)), path, key, depth+1, options); err != nil {
+				return err
+			}
+			if i < len(keys)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(closing + "}")
+
+	case reflect.Slice:
+		if v.Len() == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		buf.WriteString("[\n")
+		for i := 0; i < v.Len(); i++ {
+			buf.WriteString(indent)
+			if err := renderValue(buf, v.Index(i), path, name, depth+1, options); err != nil {
+				return err
+			}
+			if i < v.Len()-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(closing + "]")
+
+	case reflect.String:
+		value := v.String()
+		if options.Redact {
+			value = redactValue(name, value)
+		}
+		return writeJSON(buf, value)
+
+	default:
+		return writeJSON(buf, v.Interface())
+	}
+	return nil
+}
+
+// redactValue hides secret values and the credentials in URLs
+func redactValue(name, value string) string {
+	if value == "" {
+		return value
+	}
+	if secretField.MatchString(name) {
+		return redacted
+	}
+	if strings.Contains(value, "://") {
+		if u, err := url.Parse(value); err == nil && u.Host != "" {
+			return debughttp.Sanitize(u)
+		}
+	}
+	return value
+}
+
+// writeJSON writes a scalar value as JSON without escaping HTML characters,
+// which are common in payload templates
+func writeJSON(buf *bytes.Buffer, value any) error {
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimRight(encoded.Bytes(), "\n"))
+	return nil
+}
+
+// joinPath appends a field name to a JSON path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// stripComments blanks out // and /* */ comments outside of strings, keeping
+// line breaks so that parse errors point at the right place
+func stripComments(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+
+	inString := false
+	for i := 0; i < len(out); i++ {
+		switch {
+		case inString:
+			if out[i] == '\\' {
+				i++
+			} else if out[i] == '"' {
+				inString = false
+			}
+		case out[i] == '"':
+			inString = true
+		case out[i] == '/' && i+1 < len(out) && out[i+1] == '/':
+			for ; i < len(out) && out[i] != '\n'; i++ {
+				out[i] = ' '
+			}
+		case out[i] == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := bytes.Index(out[i+2:], []byte("*/"))
+			stop := len(out)
+			if end >= 0 {
+				stop = i + 2 + end + 2
+			}
+			for ; i < stop; i++ {
+				if out[i] != '\n' {
+					out[i] = ' '
+				}
+			}
+			i--
+		}
+	}
+	return out
+}
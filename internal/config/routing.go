@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// EventTypes lists the event types channels can be limited to with their
+// "events" setting; they are the types of notification events
+var EventTypes = []string{
+	"ip_changed",
+	"failover",
+	"hosted_exit",
+	"network_changed",
+	"unusual_activity",
+	"outside_geofence",
+	"unexpected_ip",
+	"catch_up",
+	"hook_failed",
+	"fetch_failed",
+	"fetch_recovered",
+	LifecycleStarted,
+	LifecycleStopped,
+	LifecycleHeartbeat,
+}
+
+// validateChannelEvents checks the "events" setting of every channel
+func validateChannelEvents(c *Config) error {
+	channels := map[string][]string{
+		"whatsapp":      c.WhatsApp.Events,
+		"email":         c.Email.Events,
+		"slack":         c.Slack.Events,
+		"discord":       c.Discord.Events,
+		"teams":         c.Teams.Events,
+		"google_sheets": c.Sheets.Events,
+		"file":          c.File.Events,
+		"matrix":        c.Matrix.Events,
+		"ntfy":          c.Ntfy.Events,
+		"line":          c.Line.Events,
+		"dingtalk":      c.DingTalk.Events,
+		"wecom":         c.WeCom.Events,
+		"sns":           c.SNS.Events,
+		"mqtt":          c.MQTT.Events,
+		"syslog":        c.Syslog.Events,
+		"pagerduty":     c.PagerDuty.Events,
+		"webhook":       c.Webhook.Events,
+	}
+	for i, plugin := range c.Plugins.Commands {
+		channels[fmt.Sprintf("plugins.commands[%d]", i)] = plugin.Events
+	}
+
+	for channel, events := range channels {
+		for _, event := range events {
+			if !slices.Contains(EventTypes, event) {
+				return fmt.Errorf("%s.events: unknown event type %q (available: %s)", channel, event, strings.Join(EventTypes, ", "))
+			}
+		}
+	}
+	return nil
+}
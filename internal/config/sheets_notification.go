@@ -0,0 +1,33 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+// BuildSheetsRows creates the spreadsheet rows (time, family, old IP, new IP, note) for IP changes
+func BuildSheetsRows(changes []IPChange, catchUp bool, timestamp time.Time) [][]string {
+	rows := make([][]string, 0, len(changes))
+	for _, change := range changes {
+		note := "Changed"
+		if warnings := change.Warnings(); len(warnings) > 0 {
+			note = strings.Join(warnings, "; ")
+		}
+		if catchUp {
+			if !change.Missed {
+				// Only the DNS record was out of date; the IP itself did not change
+				continue
+			}
+			note = "Changed while monitor was offline"
+		}
+
+		rows = append(rows, []string{
+			timestamp.Format("2006-01-02 15:04:05"),
+			change.Label(),
+			change.OldIP,
+			change.NewIP,
+			note,
+		})
+	}
+	return rows
+}
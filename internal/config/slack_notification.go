@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BuildSlackMessage creates the Slack message content (mrkdwn)
+func BuildSlackMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	var details strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&details, "• *%s:* `%s` → `%s`\n", change.Label(), change.OldIP, change.NewIP)
+		for _, event := range change.Warnings() {
+			fmt.Fprintf(&details, "• :warning: %s\n", event)
+		}
+		if location := change.Location(); location != "" {
+			fmt.Fprintf(&details, "• *Location:* %s\n", location)
+		}
+	}
+
+	return fmt.Sprintf(":rotating_light: *IP Address Changed*\n%s*Time:* %s\n%s_%s_",
+		details.String(), timestamp.Format("2006-01-02 15:04:05"), buildSlackGatewayLines(gateway), signature())
+}
+
+// BuildCatchUpSlackMessage describes what happened while the monitor was not running
+func BuildCatchUpSlackMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	var details strings.Builder
+	for _, change := range changes {
+		if change.Missed {
+			fmt.Fprintf(&details, "• *%s:* `%s` → `%s`\n", change.Label(), change.OldIP, change.NewIP)
+			for _, event := range change.Warnings() {
+				fmt.Fprintf(&details, "• :warning: %s\n", event)
+			}
+			if location := change.Location(); location != "" {
+				fmt.Fprintf(&details, "• *Location:* %s\n", location)
+			}
+		} else {
+			fmt.Fprintf(&details, "• *%s:* `%s` (unchanged)\n", change.Label(), change.NewIP)
+		}
+		if change.DNSRecord != "" {
+			fmt.Fprintf(&details, "• *DNS %s:* %s%s\n", change.DNSRecord, formatDNSIPs(change.DNSIPs), formatDNSState(change))
+		}
+		if note := change.OutageNote(); note != "" {
+			fmt.Fprintf(&details, "• %s\n", note)
+		}
+	}
+
+	return fmt.Sprintf(":rotating_light: *Changed While Offline*\n%s*Checked:* %s\n%s_%s_",
+		details.String(), timestamp.Format("2006-01-02 15:04:05"), buildSlackGatewayLines(gateway), signature())
+}
+
+// BuildHookFailureSlackMessage creates the Slack message for failed hooks
+func BuildHookFailureSlackMessage(failures []HookFailure, timestamp time.Time) string {
+	var details strings.Builder
+	for _, failure := range failures {
+		fmt.Fprintf(&details, "• *%s:* %s\n", failure.Name, failure.Error)
+		if failure.Output != "" {
+			fmt.Fprintf(&details, "```%s```\n", failure.Output)
+		}
+	}
+
+	return fmt.Sprintf(":warning: *IP Change Hook Failed*\n%s*Time:* %s\n_%s_",
+		details.String(), timestamp.Format("2006-01-02 15:04:05"), signature())
+}
+
+// BuildLifecycleSlackMessage creates the Slack message when the monitor
+// starts, stops or sends its heartbeat
+func BuildLifecycleSlackMessage(status LifecycleStatus, timestamp time.Time) string {
+	emoji := ":large_green_circle:"
+	switch status.Kind {
+	case LifecycleStopped:
+		emoji = ":red_circle:"
+	case LifecycleHeartbeat:
+		emoji = ":heartpulse:"
+	}
+
+	var details strings.Builder
+	for _, fact := range status.facts(timestamp) {
+		fmt.Fprintf(&details, "*%s:* %s\n", fact.Name, fact.Value)
+	}
+
+	return fmt.Sprintf("%s *%s*\n%s_%s_", emoji, status.Title(), details.String(), signature())
+}
+
+// BuildFetchFailureSlackMessage creates the Slack message for checks that
+// keep failing, or work again when recovered
+func BuildFetchFailureSlackMessage(failures []CheckFailure, recovered bool, timestamp time.Time) string {
+	emoji := ":rotating_light:"
+	if recovered {
+		emoji = ":white_check_mark:"
+	}
+
+	var details strings.Builder
+	for _, fact := range fetchFailureFacts(failures, recovered, timestamp) {
+		fmt.Fprintf(&details, "*%s:* %s\n", fact.Name, fact.Value)
+	}
+
+	return fmt.Sprintf("%s *%s*\n%s_%s_", emoji, fetchFailureTitle(recovered), details.String(), signature())
+}
+
+// buildSlackGatewayLines describes the default gateway, if known
+func buildSlackGatewayLines(gateway *GatewayContext) string {
+	if gateway == nil {
+		return ""
+	}
+
+	lines := fmt.Sprintf("*Gateway:* %s\n", gateway.Current())
+	if gateway.Changed {
+		lines += fmt.Sprintf(":warning: *Gateway changed from* %s\n", gateway.Previous())
+	}
+	return lines
+}
@@ -0,0 +1,77 @@
+package config
+
+import "time"
+
+// SNSMessage holds the texts published to an SNS topic, one for each kind
+// of subscriber
+type SNSMessage struct {
+	Subject string // Email subject
+	Default string // Text for protocols without a specific text
+	Email   string // Email body
+	SMS     string // Single line for SMS
+}
+
+// BuildSNSMessage creates the SNS message for an IP change. Email
+// subscribers get the email texts, SMS subscribers the one-line file message
+// and everyone else the WhatsApp text.
+func BuildSNSMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) SNSMessage {
+	message := SNSMessage{
+		Subject: BuildEmailSubject(),
+		Default: BuildCombinedWhatsAppMessage(changes, timestamp, gateway),
+		Email:   BuildCombinedEmailBody(changes, timestamp, gateway),
+		SMS:     buildSMSText(BuildFileMessage(changes, timestamp, gateway)),
+	}
+	if len(changes) == 1 && changes[0].Plain() {
+		message.Default = BuildWhatsAppMessage(changes[0].OldIP, changes[0].NewIP, timestamp, gateway)
+		message.Email = BuildEmailBody(changes[0].OldIP, changes[0].NewIP, timestamp, gateway)
+	}
+	return message
+}
+
+// BuildCatchUpSNSMessage describes what happened while the monitor was not running
+func BuildCatchUpSNSMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) SNSMessage {
+	return SNSMessage{
+		Subject: BuildCatchUpEmailSubject(),
+		Default: BuildCatchUpWhatsAppMessage(changes, timestamp, gateway),
+		Email:   BuildCatchUpEmailBody(changes, timestamp, gateway),
+		SMS:     buildSMSText(BuildCatchUpFileMessage(changes, timestamp, gateway)),
+	}
+}
+
+// BuildHookFailureSNSMessage creates the SNS message for failed hooks
+func BuildHookFailureSNSMessage(failures []HookFailure, timestamp time.Time) SNSMessage {
+	return SNSMessage{
+		Subject: BuildHookFailureEmailSubject(),
+		Default: BuildHookFailureWhatsAppMessage(failures, timestamp),
+		Email:   BuildHookFailureEmailBody(failures, timestamp),
+		SMS:     buildSMSText(BuildHookFailureFileMessage(failures, timestamp)),
+	}
+}
+
+// BuildLifecycleSNSMessage creates the SNS message when the monitor starts,
+// stops or sends its heartbeat
+func BuildLifecycleSNSMessage(status LifecycleStatus, timestamp time.Time) SNSMessage {
+	return SNSMessage{
+		Subject: BuildLifecycleEmailSubject(status),
+		Default: BuildLifecycleWhatsAppMessage(status, timestamp),
+		Email:   BuildLifecycleEmailBody(status, timestamp),
+		SMS:     buildSMSText(BuildLifecycleFileMessage(status, timestamp)),
+	}
+}
+
+// BuildFetchFailureSNSMessage creates the SNS message for checks that keep
+// failing, or work again when recovered
+func BuildFetchFailureSNSMessage(failures []CheckFailure, recovered bool, timestamp time.Time) SNSMessage {
+	return SNSMessage{
+		Subject: BuildFetchFailureEmailSubject(recovered),
+		Default: BuildFetchFailureWhatsAppMessage(failures, recovered, timestamp),
+		Email:   BuildFetchFailureEmailBody(failures, recovered, timestamp),
+		SMS:     buildSMSText(BuildFetchFailureFileMessage(failures, recovered, timestamp)),
+	}
+}
+
+// buildSMSText prefixes a one-line message with the product name, since SMS
+// recipients see no sender name
+func buildSMSText(line string) string {
+	return ProductName() + ": " + line
+}
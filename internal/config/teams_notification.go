@@ -0,0 +1,47 @@
+package config
+
+import "time"
+
+// BuildTeamsCard creates the Adaptive Card content for an IP change. Teams
+// shows the same title and fields as the Discord embed.
+func BuildTeamsCard(changes []IPChange, timestamp time.Time, gateway *GatewayContext) Card {
+	return BuildDiscordCard(changes, timestamp, gateway)
+}
+
+// BuildCatchUpTeamsCard describes what happened while the monitor was not running
+func BuildCatchUpTeamsCard(changes []IPChange, timestamp time.Time, gateway *GatewayContext) Card {
+	return BuildCatchUpDiscordCard(changes, timestamp, gateway)
+}
+
+// BuildHookFailureTeamsCard creates the Adaptive Card content for failed hooks
+func BuildHookFailureTeamsCard(failures []HookFailure, timestamp time.Time) Card {
+	card := Card{
+		Title:  "⚠️ IP Change Hook Failed",
+		Color:  CardColorWarning,
+		Footer: signature(),
+	}
+
+	for _, failure := range failures {
+		value := failure.Error
+		if failure.Output != "" {
+			// Fact values render as plain text without code blocks
+			value += "\n\n" + truncateText(failure.Output, 900)
+		}
+		card.Fields = append(card.Fields, CardField{Name: failure.Name, Value: value})
+	}
+	card.Fields = append(card.Fields, CardField{Name: "Time", Value: timestamp.Format("2006-01-02 15:04:05")})
+
+	return card
+}
+
+// BuildLifecycleTeamsCard creates the Adaptive Card content when the
+// monitor starts, stops or sends its heartbeat
+func BuildLifecycleTeamsCard(status LifecycleStatus, timestamp time.Time) Card {
+	return buildLifecycleCard(status, timestamp)
+}
+
+// BuildFetchFailureTeamsCard creates the Adaptive Card content for checks
+// that keep failing, or work again when recovered
+func BuildFetchFailureTeamsCard(failures []CheckFailure, recovered bool, timestamp time.Time) Card {
+	return buildFetchFailureCard(failures, recovered, timestamp)
+}
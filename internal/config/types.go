@@ -4,17 +4,153 @@ package config
 type Config struct {
 	CheckIntervalSeconds int `json:"check_interval_seconds"`
 
+	// Name of the monitored location included in notification events; defaults to the hostname
+	Site string `json:"site"`
+
+	// Product name, signature and emoji usage of the built-in templates
+	Branding BrandingConfig `json:"branding"`
+
+	// Apprise-style notification URLs, translated to the built-in channels
+	NotifyURLs []string `json:"notify_urls"`
+
+	// Notifications are logged instead of sent, e.g. while trying out a setup
+	DryRun bool `json:"dry_run"`
+
+	// Channels not notified, by name as in the logs (e.g. "Email", "Webhook")
+	MutedChannels []string `json:"muted_channels"`
+
+	// Longest message in bytes by channel name (e.g. "sms", "webhook"),
+	// replacing the built-in limits; 0 removes a limit
+	MessageLimits map[string]int `json:"message_limits"`
+
 	// Logging configuration
 	Logging LoggingConfig `json:"logging"`
 
+	// Redaction of IPs in logs and shared outputs
+	Privacy PrivacyConfig `json:"privacy"`
+
 	// WhatsApp configuration
 	WhatsApp WhatsAppConfig `json:"whatsapp"`
 
 	// Email configuration
 	Email EmailConfig `json:"email"`
 
+	// Slack configuration
+	Slack SlackConfig `json:"slack"`
+
+	// Discord configuration
+	Discord DiscordConfig `json:"discord"`
+
+	// Microsoft Teams configuration
+	Teams TeamsConfig `json:"teams"`
+
+	// Google Sheets history export configuration
+	Sheets SheetsConfig `json:"google_sheets"`
+
+	// Local file / named pipe output configuration
+	File FileConfig `json:"file"`
+
+	// Matrix room configuration
+	Matrix MatrixConfig `json:"matrix"`
+
+	// ntfy push notification configuration
+	Ntfy NtfyConfig `json:"ntfy"`
+
+	// LINE Messaging API configuration
+	Line LineConfig `json:"line"`
+
+	// DingTalk custom robot configuration
+	DingTalk DingTalkConfig `json:"dingtalk"`
+
+	// WeChat Work (WeCom) group bot configuration
+	WeCom WeComConfig `json:"wecom"`
+
+	// AWS SNS topic configuration
+	SNS SNSConfig `json:"sns"`
+
+	// MQTT publishing configuration (Home Assistant, Node-RED)
+	MQTT MQTTConfig `json:"mqtt"`
+
+	// Remote syslog forwarding over TLS, for audit trails
+	Syslog SyslogConfig `json:"syslog"`
+
+	// PagerDuty Events API v2 configuration
+	PagerDuty PagerDutyConfig `json:"pagerduty"`
+
+	// Generic outbound webhook configuration
+	Webhook WebhookConfig `json:"webhook"`
+
 	// IP monitoring configuration
 	IP IPConfig `json:"ip"`
+
+	// Log of every check, for uptime statistics
+	CheckLog CheckLogConfig `json:"check_log"`
+
+	// How long the IP history and failed notifications are kept
+	Retention RetentionConfig `json:"retention"`
+
+	// Network lookup of new IPs, flagging hosting and VPN exits
+	Enrichment EnrichmentConfig `json:"enrichment"`
+
+	// Alerts on IP changes far more frequent than the history has them
+	Anomaly AnomalyConfig `json:"anomaly"`
+
+	// HTTP API serving the current IP to other applications
+	API APIConfig `json:"api"`
+
+	// Commands run when the IP changes
+	Hooks HooksConfig `json:"hooks"`
+
+	// Commands run when the IP changes, in addition to hooks.commands
+	OnChangeCommands []HookCommand `json:"on_change_commands"`
+
+	// Short link kept pointing at the current IP, so bookmarks survive changes
+	Shortlink ShortlinkConfig `json:"shortlink"`
+
+	// Certificate renewal once the DNS of the domains points at a new IP
+	ACME ACMEConfig `json:"acme"`
+
+	// External commands acting as notification channels
+	Plugins PluginsConfig `json:"plugins"`
+
+	// Daily window during which notifications are held and sent as a summary
+	QuietHours QuietHoursConfig `json:"quiet_hours"`
+
+	// Templates of the digest listing the IP changes held during quiet hours
+	Digest DigestConfig `json:"digest"`
+
+	// Skipping of channels that keep failing
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"`
+
+	// Channels notified only of events nobody acknowledged in time
+	Escalation EscalationConfig `json:"escalation"`
+
+	// Notifications about the monitor itself
+	Lifecycle LifecycleConfig `json:"lifecycle"`
+
+	// Daily send limits of channels, e.g. a WhatsApp tier or an SMTP provider's cap
+	Quotas QuotaConfig `json:"quotas"`
+
+	// Pings to a dead man's switch service after every check cycle
+	Ping PingConfig `json:"ping"`
+
+	// Go runtime resource settings
+	Resources ResourcesConfig `json:"resources"`
+
+	// Fewer writes to the data directory, e.g. on a Raspberry Pi SD card
+	LowWrite LowWriteConfig `json:"low_write"`
+
+	// Caching of DNS answers for all outbound connections
+	DNSCache DNSCacheConfig `json:"dns_cache"`
+
+	// Proxy of the IP services and the notification APIs
+	Proxy ProxyConfig `json:"proxy"`
+
+	// Updates of the binary from signed releases
+	Update UpdateConfig `json:"update"`
+
+	// Cron-like schedules of auxiliary tasks, by task name
+	Schedules map[string]string `json:"schedules"`
 }
 
 // LoggingConfig holds logging configuration
@@ -24,25 +160,279 @@ type LoggingConfig struct {
 	Identifier string `json:"identifier"` // e.g., "public-ip-monitor"
 }
 
+// PrivacyConfig holds how IPs are hidden in logs, exports and the API
+// history. Notifications, hooks and DNS updates always get full values.
+type PrivacyConfig struct {
+	Mode string `json:"mode"` // "" (off), "mask" (keep the /24 or /48) or "hash"
+	Salt string `json:"salt"` // Secret mixed into hashes, so they cannot be reversed by trying every IP
+}
+
+// BrandingConfig holds how notifications name and sign themselves
+type BrandingConfig struct {
+	ProductName     string   `json:"product_name"`      // Used in subject lines and as the email sender name
+	Signature       string   `json:"signature"`         // Line closing every message; defaults to the product name
+	NoEmoji         bool     `json:"no_emoji"`          // Send all messages without emoji
+	NoEmojiChannels []string `json:"no_emoji_channels"` // Channels sent without emoji, e.g. ["whatsapp", "line"]
+	NoReference     bool     `json:"no_reference"`      // Leave the "Ref:" event ID out of messages; JSON payloads keep it
+}
+
 // WhatsAppConfig holds WhatsApp configuration
 type WhatsAppConfig struct {
-	Enabled         bool   `json:"enabled"`
-	Token           string `json:"token"`
-	PhoneID         string `json:"phone_id"`
-	RecipientNumber string `json:"recipient_number"`
-	APIVersion      string `json:"api_version"`
-	TimeoutSeconds  int    `json:"timeout_seconds"`
+	Enabled         bool     `json:"enabled"`
+	Token           string   `json:"token"`
+	PhoneID         string   `json:"phone_id"`
+	RecipientNumber string   `json:"recipient_number"`
+	APIVersion      string   `json:"api_version"`
+	TimeoutSeconds  int      `json:"timeout_seconds"`
+	Events          []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+
+	// Earlier addresses listed in change messages, with how long each was
+	// kept (verbose level); 0 lists none
+	RecentChanges int `json:"recent_changes"`
 }
 
 // EmailConfig holds email configuration
 type EmailConfig struct {
-	Enabled  bool   `json:"enabled"`
-	From     string `json:"from"`
-	Password string `json:"password"`
-	To       string `json:"to"`
+	Enabled  bool     `json:"enabled"`
+	From     string   `json:"from"`
+	FromName string   `json:"from_name"` // Sender display name; defaults to the product name
+	Password string   `json:"password"`
+	To       string   `json:"to"`
+	SMTPHost string   `json:"smtp_host"`
+	SMTPPort string   `json:"smtp_port"`
+	Timeout  int      `json:"timeout_seconds"`
+	Events   []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+
+	// Backup SMTP servers, tried in order when smtp_host fails
+	Relays []EmailRelay `json:"relays"`
+
+	// ASCII-armored OpenPGP public key the bodies are encrypted to, optional
+	PGPPublicKeyFile string `json:"pgp_public_key_file"`
+
+	// "smtp" or "gmail" to send through the Gmail API with OAuth2 instead,
+	// which needs no app password
+	Backend string     `json:"backend"`
+	Gmail   EmailGmail `json:"gmail"`
+}
+
+// EmailGmail holds the OAuth2 credentials of the gmail backend
+type EmailGmail struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"` // Granted for the gmail.send scope
+}
+
+// EmailRelay holds a backup SMTP server
+type EmailRelay struct {
 	SMTPHost string `json:"smtp_host"`
-	SMTPPort string `json:"smtp_port"`
-	Timeout  int    `json:"timeout_seconds"`
+	SMTPPort string `json:"smtp_port"` // Defaults to "587"
+	Username string `json:"username"`  // Defaults to email.from
+	Password string `json:"password"`  // Defaults to email.password
+}
+
+// SlackConfig holds Slack configuration
+type SlackConfig struct {
+	Enabled        bool     `json:"enabled"`
+	WebhookURL     string   `json:"webhook_url"`
+	Token          string   `json:"token"`   // Bot token; uses chat.postMessage instead of the webhook
+	Channel        string   `json:"channel"` // Required with token
+	TimeoutSeconds int      `json:"timeout_seconds"`
+	Events         []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// DiscordConfig holds Discord configuration
+type DiscordConfig struct {
+	Enabled        bool     `json:"enabled"`
+	WebhookURL     string   `json:"webhook_url"`
+	Username       string   `json:"username"` // Overrides the webhook's default name
+	TimeoutSeconds int      `json:"timeout_seconds"`
+	Events         []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// TeamsConfig holds Microsoft Teams configuration
+type TeamsConfig struct {
+	Enabled        bool     `json:"enabled"`
+	WebhookURL     string   `json:"webhook_url"` // Incoming webhook or Workflows URL
+	TimeoutSeconds int      `json:"timeout_seconds"`
+	Events         []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// SheetsConfig holds Google Sheets configuration
+type SheetsConfig struct {
+	Enabled         bool     `json:"enabled"`
+	CredentialsFile string   `json:"credentials_file"` // Service account key (JSON)
+	SpreadsheetID   string   `json:"spreadsheet_id"`
+	SheetName       string   `json:"sheet_name"`
+	TimeoutSeconds  int      `json:"timeout_seconds"`
+	Events          []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// FileConfig holds local file / named pipe output configuration
+type FileConfig struct {
+	Enabled bool     `json:"enabled"`
+	Path    string   `json:"path"`   // Appended to if a regular file, written to if a named pipe
+	Events  []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// MatrixConfig holds Matrix configuration
+type MatrixConfig struct {
+	Enabled        bool     `json:"enabled"`
+	HomeserverURL  string   `json:"homeserver_url"`
+	AccessToken    string   `json:"access_token"`
+	RoomID         string   `json:"room_id"` // e.g., "!abcdef:example.org"
+	TimeoutSeconds int      `json:"timeout_seconds"`
+	Events         []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// NtfyConfig holds ntfy configuration
+type NtfyConfig struct {
+	Enabled        bool     `json:"enabled"`
+	Server         string   `json:"server"` // Self-hosted server URL; defaults to https://ntfy.sh
+	Topic          string   `json:"topic"`
+	Priority       string   `json:"priority"` // min, low, default, high, max or 1-5
+	Token          string   `json:"token"`    // Access token for protected topics
+	TimeoutSeconds int      `json:"timeout_seconds"`
+	Events         []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// LineConfig holds LINE configuration
+type LineConfig struct {
+	Enabled        bool     `json:"enabled"`
+	Token          string   `json:"token"` // Channel access token of a Messaging API channel
+	To             string   `json:"to"`    // User, group or room ID the bot pushes to
+	TimeoutSeconds int      `json:"timeout_seconds"`
+	Events         []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+
+	// Earlier addresses listed in change messages, with how long each was
+	// kept (verbose level); 0 lists none
+	RecentChanges int `json:"recent_changes"`
+}
+
+// DingTalkConfig holds DingTalk configuration
+type DingTalkConfig struct {
+	Enabled        bool     `json:"enabled"`
+	WebhookURL     string   `json:"webhook_url"` // https://oapi.dingtalk.com/robot/send?access_token=...
+	Secret         string   `json:"secret"`      // Signing secret ("SEC..."), if the robot uses signatures
+	TimeoutSeconds int      `json:"timeout_seconds"`
+	Events         []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// WeComConfig holds WeChat Work configuration
+type WeComConfig struct {
+	Enabled        bool     `json:"enabled"`
+	WebhookURL     string   `json:"webhook_url"` // https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=...
+	TimeoutSeconds int      `json:"timeout_seconds"`
+	Events         []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// SNSConfig holds AWS SNS configuration
+type SNSConfig struct {
+	Enabled         bool     `json:"enabled"`
+	Region          string   `json:"region"`    // Defaults to the region of the topic ARN
+	TopicARN        string   `json:"topic_arn"` // arn:aws:sns:<region>:<account>:<topic>
+	AccessKeyID     string   `json:"access_key_id"`
+	SecretAccessKey string   `json:"secret_access_key"`
+	SessionToken    string   `json:"session_token"`
+	RoleARN         string   `json:"role_arn"`    // Role assumed before publishing, optional
+	ExternalID      string   `json:"external_id"` // External ID of the role's trust policy, optional
+	TimeoutSeconds  int      `json:"timeout_seconds"`
+	Events          []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// MQTTConfig holds MQTT configuration
+type MQTTConfig struct {
+	Enabled            bool     `json:"enabled"`
+	Broker             string   `json:"broker"` // e.g., "mqtt://broker:1883", or "mqtts://broker:8883" for TLS
+	ClientID           string   `json:"client_id"`
+	Username           string   `json:"username"`
+	Password           string   `json:"password"`
+	Topic              string   `json:"topic"`       // Current IP, retained; families and WANs get subtopics
+	EventTopic         string   `json:"event_topic"` // JSON event per change; empty disables
+	QoS                int      `json:"qos"`
+	CAFile             string   `json:"ca_file"`              // PEM bundle verifying the broker instead of the system roots
+	InsecureSkipVerify bool     `json:"insecure_skip_verify"` // Accept any broker certificate
+	TimeoutSeconds     int      `json:"timeout_seconds"`
+	Events             []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// SyslogConfig holds configuration for forwarding events as RFC 5424
+// messages to a remote collector over TLS
+type SyslogConfig struct {
+	Enabled            bool     `json:"enabled"`
+	Address            string   `json:"address"`  // Collector host:port; port 6514 when omitted
+	Facility           string   `json:"facility"` // e.g. "local0", "user" or "daemon"
+	AppName            string   `json:"app_name"`
+	Hostname           string   `json:"hostname"`             // Sent as the message hostname; the site when empty
+	CAFile             string   `json:"ca_file"`              // PEM bundle verifying the collector instead of the system roots
+	CertFile           string   `json:"cert_file"`            // Client certificate, for collectors requiring mutual TLS
+	KeyFile            string   `json:"key_file"`             // Key of the client certificate
+	InsecureSkipVerify bool     `json:"insecure_skip_verify"` // Accept any collector certificate
+	TimeoutSeconds     int      `json:"timeout_seconds"`
+	Events             []string `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// PagerDutyConfig holds PagerDuty configuration
+type PagerDutyConfig struct {
+	Enabled        bool              `json:"enabled"`
+	RoutingKey     string            `json:"routing_key"` // Integration key of an Events API v2 integration
+	EventsURL      string            `json:"events_url"`
+	SeverityMap    map[string]string `json:"severity_map"` // Event severity (info, warning, critical) to PagerDuty severity
+	AutoResolve    bool              `json:"auto_resolve"` // Resolve fetch failure incidents on recovery and change incidents right away
+	TimeoutSeconds int               `json:"timeout_seconds"`
+	Events         []string          `json:"events"` // Event types sent to the channel; empty sends all it supports
+}
+
+// WebhookConfig holds generic webhook configuration
+type WebhookConfig struct {
+	Enabled         bool                `json:"enabled"`
+	URLs            []string            `json:"urls"`
+	Method          string              `json:"method"`
+	Headers         map[string]string   `json:"headers"`
+	PayloadTemplate string              `json:"payload_template"` // Go text/template; defaults to a JSON object
+	TimeoutSeconds  int                 `json:"timeout_seconds"`
+	Events          []string            `json:"events"` // Event types sent to the channel; empty sends all it supports
+	Outbox          WebhookOutboxConfig `json:"outbox"`
+}
+
+// WebhookOutboxConfig holds the at-least-once delivery of webhooks: events
+// are kept per URL until the endpoint accepted them and replayed in order
+// after outages
+type WebhookOutboxConfig struct {
+	Enabled       bool `json:"enabled"`
+	MaxAgeHours   int  `json:"max_age_hours"`  // Events not delivered within this are discarded; negative keeps them
+	ReplaySeconds int  `json:"replay_seconds"` // How often events left are sent again
+}
+
+// ShortlinkConfig holds the redirect or short link service entry updated
+// to point at the new IP after a change
+type ShortlinkConfig struct {
+	Enabled            bool              `json:"enabled"`
+	Provider           string            `json:"provider"`             // "shlink", "kutt" or "http"
+	APIURL             string            `json:"api_url"`              // Base URL of the service; for "http", the URL requested
+	APIKey             string            `json:"api_key"`              // Shlink/Kutt API key; for "http", a bearer token
+	ID                 string            `json:"id"`                   // Short code or link ID, e.g. "home"
+	Target             string            `json:"target"`               // URL the link points at, {ip} being the current IP, e.g. "http://{ip}:8080/"
+	Family             string            `json:"family"`               // Family whose IP is used when both are monitored; the first by default
+	Method             string            `json:"method"`               // http: request method
+	Headers            map[string]string `json:"headers"`              // http: extra request headers
+	Body               string            `json:"body"`                 // http: request body; {url}, {ip} and {id} are replaced
+	MinIntervalSeconds int               `json:"min_interval_seconds"` // Minimum time between updates, for rate-limited APIs
+	TimeoutSeconds     int               `json:"timeout_seconds"`
+}
+
+// ACMEConfig holds the certificate renewal run after an IP change, for
+// certificates whose validation reaches this host through its public IP
+// (HTTP-01 or TLS-ALPN-01). The renewal waits until the domains resolve to
+// the new IP, e.g. once a DDNS hook updated them, since validation would
+// otherwise fail and count against the CA's rate limits. A failed renewal is
+// reported like a failed hook.
+type ACMEConfig struct {
+	Domains           []string `json:"domains"`             // Domains that must resolve to the new IP; empty disables renewal
+	Command           string   `json:"command"`             // ACME client run to renew, "certbot" by default
+	Args              []string `json:"args"`                // Arguments of command; "renew --non-interactive" for certbot by default
+	DNSTimeoutSeconds int      `json:"dns_timeout_seconds"` // How long to wait for the domains to resolve to the new IP
+	TimeoutSeconds    int      `json:"timeout_seconds"`     // Timeout of the renewal command
+	User              string   `json:"user"`                // User to run the command as
 }
 
 // IPConfig holds IP monitoring configuration
@@ -52,4 +442,329 @@ type IPConfig struct {
 	DataDir        string   `json:"data_dir"`
 	RecordsFile    string   `json:"records_file"`
 	LastIPFile     string   `json:"last_ip_file"`
+
+	// Detection methods tried after the services (dns, upnp, router, ...)
+	Sources []SourceConfig `json:"sources"`
+
+	// Services and sources that must report the same address for it to be
+	// accepted; 0 or 1 takes the first answer
+	Quorum int `json:"quorum"`
+
+	// "sequential" tries the services and sources in order, "race" asks
+	// them all at once and takes the first answer
+	FetchMode string `json:"fetch_mode"`
+
+	// In sequential mode, try services and sources that failed repeatedly
+	// lately last, until an hour after their last failure
+	AdaptiveOrder bool `json:"adaptive_order"`
+
+	// User-Agent of the requests to the services and http/router sources;
+	// "public-ip-monitor/<version>" when empty, as some services block Go's
+	UserAgent string `json:"user_agent"`
+
+	// Extra request headers by service URL, e.g. the API key of a paid service
+	ServiceHeaders map[string]map[string]string `json:"service_headers"`
+
+	// TLS of the services and http/router sources, e.g. for a self-hosted
+	// endpoint with a private CA
+	TLS FetchTLSConfig `json:"tls"`
+
+	// DNS-over-HTTPS server resolving the hostnames of the services and of
+	// dns_record, for networks with broken or hijacked DNS
+	DoH DoHConfig `json:"doh"`
+
+	// Address families to monitor separately, e.g. ["ipv4", "ipv6"].
+	// Empty means a single check using whatever family the OS prefers.
+	Families []string `json:"families"`
+
+	// Changes of different families within this window are merged into one notification
+	FamilyMergeWindowSeconds int `json:"family_merge_window_seconds"`
+
+	// Consecutive failed checks after which a fetch failure event is sent
+	FailureThreshold int `json:"failure_threshold"`
+
+	// Consecutive successful checks after a reported failure before the
+	// recovery is sent, so that a flapping link does not alert on every check
+	RecoveryThreshold int `json:"recovery_threshold"`
+
+	// How long to retry with backoff on startup until the network is up
+	// (e.g., DHCP or the WAN link on boot); negative disables the wait
+	StartupGraceSeconds int `json:"startup_grace_seconds"`
+
+	// Hostname expected to resolve to the public IP (e.g., a DDNS name),
+	// compared with the stored and current IP on startup
+	DNSRecord string `json:"dns_record"`
+
+	// CIDR ranges the public IP is expected in, e.g. the ISP's known
+	// blocks. A new IP outside the ranges of its family escalates the
+	// notification and is marked anomalous in the history.
+	ExpectedRanges []string `json:"expected_ranges"`
+
+	// Include the default gateway (router IP/MAC) in notifications and log when it changes
+	DetectGateway bool `json:"detect_gateway"`
+
+	// Signed remote list of services that replaces Services when available
+	ServicesIndex ServicesIndexConfig `json:"services_index"`
+
+	// WAN links monitored separately (dual-WAN routers); the default egress
+	// IP is compared with them to detect failover to a backup link
+	WANs []WANConfig `json:"wans"`
+}
+
+// FetchTLSConfig holds the TLS settings of the IP services
+type FetchTLSConfig struct {
+	CAFile   string `json:"ca_file"`   // PEM bundle trusted in addition to the system roots
+	CertFile string `json:"cert_file"` // Client certificate, for services requiring mutual TLS
+	KeyFile  string `json:"key_file"`  // Key of the client certificate
+}
+
+// DoHConfig holds the DNS-over-HTTPS server of the IP services
+type DoHConfig struct {
+	URL          string   `json:"url"`           // e.g. "https://cloudflare-dns.com/dns-query"; empty uses the system resolver
+	BootstrapIPs []string `json:"bootstrap_ips"` // Addresses of the server, so that its hostname needs no lookup
+}
+
+// WANConfig describes a single WAN link
+type WANConfig struct {
+	Name      string   `json:"name"`
+	Interface string   `json:"interface"` // Local interface requests for this WAN are sent from
+	Services  []string `json:"services"`  // Overrides ip.services, e.g. a router status page reporting this WAN's IP
+	Backup    bool     `json:"backup"`    // Default traffic leaving through this WAN is reported as a failover
+}
+
+// EnrichmentConfig holds configuration for looking up the network of new
+// IPs, to notice traffic leaving through a VPN or proxy instead of the ISP
+type EnrichmentConfig struct {
+	Enabled         bool   `json:"enabled"`
+	HostingASNs     []int  `json:"hosting_asns"`      // Flagged in addition to the built-in hosting providers
+	HostingListFile string `json:"hosting_list_file"` // ASNs ("AS64500") and CIDR ranges, one per line
+	TimeoutSeconds  int    `json:"timeout_seconds"`
+
+	// ISO 3166 codes of the countries new IPs are expected in, e.g. "DE";
+	// an IP located anywhere else raises an urgent alert. Empty expects any.
+	ExpectedCountries []string `json:"expected_countries"`
+
+	// Country, region and city of new IPs, shown in notifications and
+	// stored with the history
+	Geolocation GeolocationConfig `json:"geolocation"`
+}
+
+// GeolocationConfig holds the service geolocating new IPs
+type GeolocationConfig struct {
+	Provider string `json:"provider"` // "ipinfo", "ip-api" or "maxmind"; empty disables geolocation
+	Token    string `json:"token"`    // ipinfo access token or ip-api Pro key; the free tiers need none
+	URL      string `json:"url"`      // Replaces the provider's base URL, e.g. for a mirror
+
+	// Local MaxMind databases of the "maxmind" provider
+	MaxMind MaxMindConfig `json:"maxmind"`
+}
+
+// MaxMindConfig holds the GeoLite2 databases looked up locally and the
+// account keeping them up to date
+type MaxMindConfig struct {
+	CityDatabase string `json:"city_database"` // Relative to ip.data_dir unless absolute
+	ASNDatabase  string `json:"asn_database"`  // Optional; relative to ip.data_dir unless absolute
+	AccountID    string `json:"account_id"`
+	LicenseKey   string `json:"license_key"`  // With account_id, the databases are downloaded and updated on schedule
+	DownloadURL  string `json:"download_url"` // {edition} is replaced, e.g. by "GeoLite2-City"; MaxMind's when empty
+}
+
+// AnomalyConfig holds when IP changes count as unusually frequent. The
+// usual number of changes in a window is learned from the history of the
+// learn_days before it; a window with factor times as many, and at least
+// min_changes, is unusual.
+type AnomalyConfig struct {
+	Enabled     bool `json:"enabled"`
+	WindowHours int  `json:"window_hours"` // Period the latest changes are counted in
+	Factor      int  `json:"factor"`       // Sensitivity: how many times the usual changes are unusual; lower alerts sooner
+	MinChanges  int  `json:"min_changes"`  // Fewer changes in a window are never unusual, e.g. on a line that never changed
+	LearnDays   int  `json:"learn_days"`   // History the usual frequency is learned from
+}
+
+// CheckLogConfig holds configuration for the log of every check
+type CheckLogConfig struct {
+	Enabled     bool   `json:"enabled"`
+	File        string `json:"file"`          // In ip.data_dir
+	MaxEntries  int    `json:"max_entries"`   // Older checks are dropped beyond this count
+	MaxAgeHours int    `json:"max_age_hours"` // Checks older than this are dropped
+}
+
+// RetentionConfig holds how long data is kept before the retention task
+// prunes it; 0 keeps it forever
+type RetentionConfig struct {
+	HistoryDays             int `json:"history_days"`              // IP change records; the current IP of each family is always kept
+	FailedNotificationsDays int `json:"failed_notifications_days"` // Notifications that failed on every attempt
+}
+
+// SourceConfig describes an IP detection source; which fields apply depends on the type
+type SourceConfig struct {
+	Type     string            `json:"type"`     // "http", "dns", "stun", "upnp" or "router"
+	URL      string            `json:"url"`      // http/router page, or upnp device description (discovered when empty)
+	Server   string            `json:"server"`   // dns or stun server, e.g. "resolver1.opendns.com"
+	Hostname string            `json:"hostname"` // dns name resolving to the asking address, e.g. "myip.opendns.com"
+	Record   string            `json:"record"`   // dns record type: A, AAAA or TXT; A/AAAA by family when empty
+	Pattern  string            `json:"pattern"`  // router regular expression; its first group is the IP
+	Username string            `json:"username"` // router basic auth
+	Password string            `json:"password"`
+	Format   string            `json:"format"`  // http answer: "text" (default) or "json"
+	Field    string            `json:"field"`   // http json: dotted path of the IP, e.g. "ip" (default) or "data.addresses.0"
+	Proxy    string            `json:"proxy"`   // http/router proxy URL, or "direct"; the global proxy when empty
+	Headers  map[string]string `json:"headers"` // http/router extra request headers, e.g. an API key
+}
+
+// ServicesIndexConfig holds configuration for the remote services index
+type ServicesIndexConfig struct {
+	URL                    string `json:"url"`
+	PublicKey              string `json:"public_key"` // Base64-encoded Ed25519 public key
+	RefreshIntervalMinutes int    `json:"refresh_interval_minutes"`
+	CacheFile              string `json:"cache_file"` // Last verified index, relative to the data directory
+}
+
+// UpdateConfig holds the self-update: a signed release manifest lists the
+// binaries of every version, which "self-update" and the scheduled check
+// install in place of the running binary
+type UpdateConfig struct {
+	ManifestURL    string `json:"manifest_url"` // Empty disables updates
+	PublicKey      string `json:"public_key"`   // Base64-encoded Ed25519 key the manifest is signed with
+	AutoApply      bool   `json:"auto_apply"`   // Scheduled checks install new versions and restart under systemd, instead of logging them
+	SystemdUnit    string `json:"systemd_unit"` // Restarted by "self-update -restart"
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// LowWriteConfig holds the low-write mode, which keeps the last IPs and the
+// check log in memory and writes them to the data directory periodically,
+// sparing SD cards a write on every check. What changed since the last
+// flush is lost on a crash or power cut, not on a regular shutdown.
+type LowWriteConfig struct {
+	Enabled              bool   `json:"enabled"`
+	FlushIntervalMinutes int    `json:"flush_interval_minutes"` // Unless schedules.flush is set
+	RuntimeDir           string `json:"runtime_dir"`            // Health file written on every check, ideally a tmpfs; the system temporary directory when empty
+}
+
+// ResourcesConfig holds Go runtime resource settings
+type ResourcesConfig struct {
+	GOMAXPROCS    int `json:"gomaxprocs"`      // 0 derives it from the container CPU quota
+	MemoryLimitMB int `json:"memory_limit_mb"` // Go soft memory limit; 0 uses 90% of the container limit, if any
+	BallastMB     int `json:"ballast_mb"`      // Heap ballast; reduces GC frequency on small heaps
+}
+
+// DNSCacheConfig holds DNS cache configuration
+type DNSCacheConfig struct {
+	Enabled            bool `json:"enabled"`
+	MaxTTLSeconds      int  `json:"max_ttl_seconds"`      // Upper bound for the TTL of answers
+	NegativeTTLSeconds int  `json:"negative_ttl_seconds"` // Upper bound for caching "no such host"
+	StaleTTLSeconds    int  `json:"stale_ttl_seconds"`    // How long expired answers are used while DNS fails
+}
+
+// QuotaConfig holds the daily send limits of notification channels. A
+// channel about to reach its limit sends only IP changes and warnings;
+// what it cannot send goes through its reroute channel, or waits until the
+// limit resets at midnight.
+type QuotaConfig struct {
+	Channels       map[string]int    `json:"channels"`        // Sends per day by channel name, e.g. {"WhatsApp": 250, "Email": 100}
+	ReservePercent int               `json:"reserve_percent"` // Share of each limit kept for IP changes and warnings; negative keeps none
+	Reroute        map[string]string `json:"reroute"`         // Channel taking over by channel name, e.g. {"WhatsApp": "Slack"}
+}
+
+// ProxyConfig holds the proxy of outbound HTTP requests
+type ProxyConfig struct {
+	URL     string   `json:"url"`      // http://, https://, socks5:// or socks5h://, or "direct"; HTTP_PROXY and HTTPS_PROXY apply when empty
+	NoProxy []string `json:"no_proxy"` // Hosts, domains (".lan") or CIDR ranges reached directly
+}
+
+// APIConfig holds HTTP API configuration
+type APIConfig struct {
+	Enabled        bool   `json:"enabled"`
+	Listen         string `json:"listen"`           // e.g. ":8787", or "127.0.0.1:8787" for local clients only
+	Token          string `json:"token"`            // Required from clients when set
+	AdminToken     string `json:"admin_token"`      // Enables the /admin endpoints, which require it
+	MaxWaitSeconds int    `json:"max_wait_seconds"` // Upper bound for /ip/wait long-poll requests
+}
+
+// HooksConfig holds configuration for commands run on IP changes
+type HooksConfig struct {
+	Commands         []HookCommand `json:"commands"`
+	TimeoutSeconds   int           `json:"timeout_seconds"`    // Default per-command timeout
+	User             string        `json:"user"`               // Default user to run commands as
+	OutputLimitBytes int           `json:"output_limit_bytes"` // Captured output kept per stream
+	NotifyOnFailure  bool          `json:"notify_on_failure"`  // Send failed hook output through notifications
+}
+
+// HookCommand describes a single command run on IP changes
+type HookCommand struct {
+	Name           string   `json:"name"`
+	Command        string   `json:"command"`
+	Args           []string `json:"args"`
+	TimeoutSeconds int      `json:"timeout_seconds"` // Overrides hooks.timeout_seconds
+	User           string   `json:"user"`            // Overrides hooks.user
+}
+
+// PluginsConfig holds configuration for notification plugins, commands that
+// receive every notification event as JSON on standard input
+type PluginsConfig struct {
+	Commands       []PluginCommand `json:"commands"`
+	TimeoutSeconds int             `json:"timeout_seconds"` // Default per-command timeout
+	User           string          `json:"user"`            // Default user to run plugins as
+}
+
+// PluginCommand describes a single notification plugin
+type PluginCommand struct {
+	Name           string   `json:"name"`
+	Command        string   `json:"command"`
+	Args           []string `json:"args"`
+	TimeoutSeconds int      `json:"timeout_seconds"` // Overrides plugins.timeout_seconds
+	User           string   `json:"user"`            // Overrides plugins.user
+	Events         []string `json:"events"`          // Event types sent to the channel; empty sends all it supports
+}
+
+// QuietHoursConfig holds the do-not-disturb window. Notifications of other
+// than the urgent channels are held during it and sent as one summary per
+// channel once it ends.
+type QuietHoursConfig struct {
+	Enabled        bool     `json:"enabled"`
+	Start          string   `json:"start"`           // e.g. "23:00", in the logging timezone
+	End            string   `json:"end"`             // e.g. "07:00"
+	UrgentChannels []string `json:"urgent_channels"` // Channels notified right away, by name (e.g. pagerduty)
+}
+
+// DigestConfig holds the templates of digests, which list every IP change
+// held back, e.g. during quiet hours, with how long each IP was kept. The
+// templates are Go text/templates receiving a Digest.
+type DigestConfig struct {
+	Template string            `json:"template"` // Empty uses the built-in template
+	Channels map[string]string `json:"channels"` // Templates by channel name as in the logs (e.g. slack), replacing template
+}
+
+// EscalationConfig holds which channels are notified only when an event is
+// not acknowledged in time, e.g. SMS or PagerDuty after the family chat
+type EscalationConfig struct {
+	Enabled      bool     `json:"enabled"`
+	Channels     []string `json:"channels"`      // Secondary channels, by name as in the logs (e.g. pagerduty, SNS)
+	AfterMinutes int      `json:"after_minutes"` // Time to acknowledge an event before it is escalated
+	Events       []string `json:"events"`        // Event types escalated; empty escalates all
+}
+
+// LifecycleConfig holds which notifications the monitor sends about itself,
+// so that a silent monitor is told apart from a stable IP
+type LifecycleConfig struct {
+	Startup   bool `json:"startup"`   // Notify when the monitor starts, with the current IPs
+	Shutdown  bool `json:"shutdown"`  // Notify when the monitor shuts down
+	Heartbeat bool `json:"heartbeat"` // Notify on the heartbeat schedule that the monitor is alive
+}
+
+// PingConfig holds the check of a dead man's switch service (healthchecks.io,
+// Dead Man's Snitch, ...) pinged once every target was checked, so the
+// service alerts when the monitor stops running
+type PingConfig struct {
+	Enabled        bool   `json:"enabled"`
+	URL            string `json:"url"`      // Pinged when every check of the cycle succeeded
+	FailURL        string `json:"fail_url"` // Pinged when a check failed; url + "/fail" by default
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// CircuitBreakerConfig holds when a failing channel is skipped rather than
+// retried on every event, until a probe after the cooldown succeeds
+type CircuitBreakerConfig struct {
+	FailureThreshold int `json:"failure_threshold"` // Consecutive failed attempts before skipping; negative disables
+	CooldownSeconds  int `json:"cooldown_seconds"`  // How long the channel is skipped before the next probe
 }
@@ -0,0 +1,36 @@
+package config
+
+import "time"
+
+// BuildWeComMessage creates the WeCom markdown for an IP change; group bots
+// render the same markdown subset as DingTalk robots
+func BuildWeComMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	_, text := BuildDingTalkMessage(changes, timestamp, gateway)
+	return text
+}
+
+// BuildCatchUpWeComMessage describes what happened while the monitor was not running
+func BuildCatchUpWeComMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	_, text := BuildCatchUpDingTalkMessage(changes, timestamp, gateway)
+	return text
+}
+
+// BuildHookFailureWeComMessage creates the WeCom markdown for failed hooks
+func BuildHookFailureWeComMessage(failures []HookFailure, timestamp time.Time) string {
+	_, text := BuildHookFailureDingTalkMessage(failures, timestamp)
+	return text
+}
+
+// BuildLifecycleWeComMessage creates the WeCom markdown when the monitor
+// starts, stops or sends its heartbeat
+func BuildLifecycleWeComMessage(status LifecycleStatus, timestamp time.Time) string {
+	_, text := BuildLifecycleDingTalkMessage(status, timestamp)
+	return text
+}
+
+// BuildFetchFailureWeComMessage creates the WeCom markdown for checks that
+// keep failing, or work again when recovered
+func BuildFetchFailureWeComMessage(failures []CheckFailure, recovered bool, timestamp time.Time) string {
+	_, text := BuildFetchFailureDingTalkMessage(failures, recovered, timestamp)
+	return text
+}
@@ -2,11 +2,98 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
 // BuildWhatsAppMessage creates the WhatsApp message content
-func BuildWhatsAppMessage(oldIP, newIP string, timestamp time.Time) string {
-	return fmt.Sprintf("🚨 IP Address Changed!\n\nOld IP: %s\nNew IP: %s\nTime: %s\n\nPublic IP Monitor",
-		oldIP, newIP, timestamp.Format("2006-01-02 15:04:05"))
+func BuildWhatsAppMessage(oldIP, newIP string, timestamp time.Time, gateway *GatewayContext) string {
+	return fmt.Sprintf("🚨 IP Address Changed!\n\nOld IP: %s\nNew IP: %s\nTime: %s\n%s\n%s",
+		oldIP, newIP, timestamp.Format("2006-01-02 15:04:05"), buildWhatsAppGatewayLines(gateway), signature())
+}
+
+// AddRecentChanges lists the history of the changes above the signature
+// of a chat message, at most n addresses besides the new one per change
+func AddRecentChanges(text string, changes []IPChange, n int) string {
+	var lines strings.Builder
+	for _, change := range changes {
+		change = change.WithRecent(n)
+		if len(change.Recent) == 0 {
+			continue
+		}
+		fmt.Fprintf(&lines, "Recent %s addresses:\n", change.Label())
+		for i, held := range change.Recent {
+			if i == 0 {
+				fmt.Fprintf(&lines, "• %s (new)\n", held.IP)
+				continue
+			}
+			fmt.Fprintf(&lines, "• %s for %s, since %s\n", held.IP, formatUptime(held.Held), held.Since.Format("2006-01-02 15:04"))
+		}
+	}
+	if lines.Len() == 0 {
+		return text
+	}
+
+	body, ok := strings.CutSuffix(text, signature())
+	if !ok {
+		return text + "\n\n" + strings.TrimSuffix(lines.String(), "\n")
+	}
+	return body + lines.String() + "\n" + signature()
+}
+
+// BuildCombinedWhatsAppMessage creates the WhatsApp message for changes of several address families
+func BuildCombinedWhatsAppMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	var details strings.Builder
+	for _, change := range changes {
+		fmt.Fprintf(&details, "%s: %s → %s\n", change.Label(), change.OldIP, change.NewIP)
+		for _, event := range change.Warnings() {
+			fmt.Fprintf(&details, "⚠️ %s\n", event)
+		}
+		if location := change.Location(); location != "" {
+			fmt.Fprintf(&details, "Location: %s\n", location)
+		}
+	}
+
+	return fmt.Sprintf("🚨 IP Addresses Changed!\n\n%s\nTime: %s\n%s\n%s",
+		details.String(), timestamp.Format("2006-01-02 15:04:05"), buildWhatsAppGatewayLines(gateway), signature())
+}
+
+// BuildCatchUpWhatsAppMessage describes what happened while the monitor was not running
+func BuildCatchUpWhatsAppMessage(changes []IPChange, timestamp time.Time, gateway *GatewayContext) string {
+	var details strings.Builder
+	for _, change := range changes {
+		if change.Missed {
+			fmt.Fprintf(&details, "%s: %s → %s\n", change.Label(), change.OldIP, change.NewIP)
+			for _, event := range change.Warnings() {
+				fmt.Fprintf(&details, "⚠️ %s\n", event)
+			}
+			if location := change.Location(); location != "" {
+				fmt.Fprintf(&details, "Location: %s\n", location)
+			}
+		} else {
+			fmt.Fprintf(&details, "%s: %s (unchanged)\n", change.Label(), change.NewIP)
+		}
+		if change.DNSRecord != "" {
+			fmt.Fprintf(&details, "DNS %s: %s%s\n", change.DNSRecord, formatDNSIPs(change.DNSIPs), formatDNSState(change))
+		}
+		if note := change.OutageNote(); note != "" {
+			fmt.Fprintf(&details, "%s\n", note)
+		}
+	}
+
+	return fmt.Sprintf("🚨 Changed While Offline!\n\n%s\nChecked: %s\n%s\n%s",
+		details.String(), timestamp.Format("2006-01-02 15:04:05"), buildWhatsAppGatewayLines(gateway), signature())
+}
+
+// buildWhatsAppGatewayLines describes the default gateway, if known
+func buildWhatsAppGatewayLines(gateway *GatewayContext) string {
+	if gateway == nil {
+		return ""
+	}
+
+	lines := fmt.Sprintf("Gateway: %s\n", gateway.Current())
+	if gateway.Changed {
+		lines += fmt.Sprintf("⚠️ Gateway changed from %s\n", gateway.Previous())
+	}
+	return lines
 }
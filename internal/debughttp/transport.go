@@ -0,0 +1,209 @@
+package debughttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxLoggedError is how much of an error response body is included in the log
+const maxLoggedError = 512
+
+// base is the standard library's default transport, kept before Enable
+// replaces http.DefaultTransport
+var base = http.DefaultTransport.(*http.Transport)
+
+// secretParam matches query parameter names whose values are redacted
+var secretParam = regexp.MustCompile(`(?i)(token|key|secret|password|passwd|signature|sig|auth|code)`)
+
+// secretHeaders are never logged or captured
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// options holds the settings of enabled debugging
+type options struct {
+	logf func(format string, args ...interface{})
+	dir  string
+	seq  atomic.Uint64
+	mu   sync.Mutex // Serializes capture file writes
+}
+
+var enabled atomic.Pointer[options]
+
+// Enable logs a sanitized summary of every outbound HTTP request through
+// logf and, when dir is not empty, writes full requests and responses to
+// files in dir. It wraps http.DefaultTransport, so clients without their
+// own transport are covered; clients with their own transport use Wrap.
+func Enable(logf func(format string, args ...interface{}), dir string) error {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create HTTP debug directory: %w", err)
+		}
+	}
+
+	enabled.Store(&options{logf: logf, dir: dir})
+	http.DefaultTransport = &Transport{Base: base}
+	return nil
+}
+
+// BaseTransport returns the standard default transport, for cloning by
+// clients that need custom dialing
+func BaseTransport() *http.Transport {
+	return base
+}
+
+// Wrap returns the round tripper wrapped for debugging, or unchanged when
+// debugging is disabled
+func Wrap(rt http.RoundTripper) http.RoundTripper {
+	if enabled.Load() == nil {
+		return rt
+	}
+	return &Transport{Base: rt}
+}
+
+// Transport logs requests made through its base round tripper
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip performs the request and logs a summary of it
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	opts := enabled.Load()
+	if opts == nil {
+		return t.Base.RoundTrip(req)
+	}
+
+	seq := opts.seq.Add(1)
+	target := Sanitize(req.URL)
+
+	var reqBody []byte
+	if opts.dir != "" && req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		opts.logf("HTTP #%d %s %s failed after %v: %v", seq, req.Method, target, elapsed, err)
+		opts.capture(seq, req, reqBody, nil, nil)
+		return nil, err
+	}
+
+	// Error bodies are what make API failures actionable, so read them
+	// (and, when capturing, every body) and hand the caller a fresh reader
+	var respBody []byte
+	if opts.dir != "" || resp.StatusCode >= 400 {
+		respBody, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	}
+
+	summary := fmt.Sprintf("HTTP #%d %s %s -> %s in %v", seq, req.Method, target, resp.Status, elapsed)
+	if resp.StatusCode >= 400 {
+		snippet := strings.Join(strings.Fields(string(respBody)), " ")
+		if len(snippet) > maxLoggedError {
+			snippet = snippet[:maxLoggedError] + "..."
+		}
+		summary += ": " + snippet
+	}
+	opts.logf("%s", summary)
+	opts.capture(seq, req, reqBody, resp, respBody)
+
+	return resp, nil
+}
+
+// Sanitize returns the URL without credentials, secret query values and
+// long path segments, which usually are tokens (e.g., webhook URLs)
+func Sanitize(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+
+	if clean.RawQuery != "" {
+		query := clean.Query()
+		for name := range query {
+			if secretParam.MatchString(name) {
+				query.Set(name, "REDACTED")
+			}
+		}
+		clean.RawQuery = query.Encode()
+	}
+
+	segments := strings.Split(clean.Path, "/")
+	for i, segment := range segments {
+		if len(segment) >= 24 {
+			segments[i] = segment[:4] + "..."
+		}
+	}
+	clean.Path = strings.Join(segments, "/")
+	clean.RawPath = ""
+
+	return clean.String()
+}
+
+// capture writes the full request and response to the debug directory
+func (o *options) capture(seq uint64, req *http.Request, reqBody []byte, resp *http.Response, respBody []byte) {
+	if o.dir == "" {
+		return
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%s %s\n", req.Method, Sanitize(req.URL))
+	writeHeaders(&out, req.Header)
+	out.WriteString("\n")
+	out.Write(reqBody)
+
+	if resp != nil {
+		fmt.Fprintf(&out, "\n\n--- response ---\n%s %s\n", resp.Proto, resp.Status)
+		writeHeaders(&out, resp.Header)
+		out.WriteString("\n")
+		out.Write(respBody)
+	}
+	out.WriteString("\n")
+
+	name := fmt.Sprintf("%s-%04d-%s.txt", time.Now().Format("20060102-150405"), seq, req.URL.Hostname())
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := os.WriteFile(filepath.Join(o.dir, name), out.Bytes(), 0600); err != nil {
+		o.logf("Failed to write HTTP capture %s: %v", name, err)
+	}
+}
+
+// writeHeaders writes headers in a stable order, omitting secret ones
+func writeHeaders(out *bytes.Buffer, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if secretHeaders[http.CanonicalHeaderKey(name)] {
+			fmt.Fprintf(out, "%s: REDACTED\n", name)
+			continue
+		}
+		for _, value := range header[name] {
+			fmt.Fprintf(out, "%s: %s\n", name, value)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package dnscache
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// staleFallbackWait is how long the upstream server gets to answer before
+// an expired answer is served instead
+const staleFallbackWait = 2 * time.Second
+
+// Settings controls how long responses are cached
+type Settings struct {
+	MaxTTL      time.Duration // Upper bound for positive answers
+	NegativeTTL time.Duration // Upper bound for NXDOMAIN and empty answers
+	StaleTTL    time.Duration // How long expired answers are served when upstream DNS fails
+}
+
+// entry is a cached response
+type entry struct {
+	response []byte
+	expires  time.Time
+}
+
+// Cache is a caching stub between Go's resolver and the upstream DNS
+// servers. It caches responses for their TTL, caches negative answers and
+// serves expired answers when the upstream servers fail, so a flaky
+// resolver does not make every hostname lookup fail.
+type Cache struct {
+	settings Settings
+	dialer   net.Dialer
+
+	mu      sync.Mutex
+	entries map[question]entry
+	hits    uint64
+	misses  uint64
+	stale   uint64
+}
+
+// New creates a DNS cache
+func New(settings Settings) *Cache {
+	if settings.MaxTTL <= 0 {
+		settings.MaxTTL = time.Hour
+	}
+	if settings.NegativeTTL <= 0 {
+		settings.NegativeTTL = 30 * time.Second
+	}
+
+	return &Cache{
+		settings: settings,
+		entries:  make(map[question]entry),
+	}
+}
+
+// Install makes the default resolver, and thereby every HTTP and SMTP
+// client without its own resolver, look names up through the cache.
+// /etc/hosts, search domains and the servers of /etc/resolv.conf keep
+// working as before.
+func (c *Cache) Install() {
+	net.DefaultResolver.PreferGo = true
+	net.DefaultResolver.Dial = c.Dial
+}
+
+// Stats returns the number of cache hits, misses and stale answers served
+func (c *Cache) Stats() (hits, misses, stale uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses, c.stale
+}
+
+// Dial returns a connection answering the resolver's queries to the given
+// DNS server from the cache, forwarding misses to the server
+func (c *Cache) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	stub := &conn{ctx: ctx, cache: c, network: network, address: address}
+
+	// The resolver tells datagram and stream connections apart by whether
+	// they implement net.PacketConn
+	if !stub.stream() {
+		return &packetConn{stub}, nil
+	}
+	return stub, nil
+}
+
+// exchange answers a query from the cache or the upstream server
+func (c *Cache) exchange(ctx context.Context, network, address string, query []byte, deadline time.Time) ([]byte, error) {
+	id, q, err := parseQuestion(query)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	cached, found := c.entries[q]
+	if found && now.Before(cached.expires) {
+		c.hits++
+		c.mu.Unlock()
+		return withID(cached.response, id), nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// Leave the resolver time to receive the expired answer if the server
+	// does not respond
+	if found {
+		if wait := now.Add(staleFallbackWait); deadline.IsZero() || wait.Before(deadline) {
+			deadline = wait
+		}
+	}
+
+	response, err := c.forward(ctx, network, address, query, deadline)
+	if err == nil {
+		var info responseInfo
+		if info, err = inspect(response); err == nil && info.rcode != rcodeSuccess && info.rcode != rcodeNXDomain {
+			err = fmt.Errorf("DNS server %s answered with code %d", address, info.rcode)
+		}
+		if err == nil {
+			c.store(q, response, info, now)
+			return response, nil
+		}
+	}
+
+	// Serve an expired answer rather than fail while upstream DNS is flaky
+	if found && now.Before(cached.expires.Add(c.settings.StaleTTL)) {
+		c.mu.Lock()
+		c.stale++
+		c.mu.Unlock()
+		return withID(cached.response, id), nil
+	}
+
+	return nil, err
+}
+
+// store caches a response for its TTL
+func (c *Cache) store(q question, response []byte, info responseInfo, now time.Time) {
+	// Truncated responses are retried over TCP, which is cached instead
+	if info.truncated {
+		return
+	}
+
+	var ttl time.Duration
+	switch {
+	case info.rcode == rcodeNXDomain || info.answers == 0:
+		ttl = c.settings.NegativeTTL
+		if info.hasNegTTL {
+			ttl = min(info.negTTL, ttl)
+		}
+	case info.hasTTL:
+		ttl = min(info.ttl, c.settings.MaxTTL)
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries now and then so the map doesn't grow forever
+	if len(c.entries) >= 1024 {
+		for key, e := range c.entries {
+			if now.After(e.expires.Add(c.settings.StaleTTL)) {
+				delete(c.entries, key)
+			}
+		}
+	}
+
+	c.entries[q] = entry{response: append([]byte(nil), response...), expires: now.Add(ttl)}
+}
+
+// forward sends a query to the upstream server
+func (c *Cache) forward(ctx context.Context, network, address string, query []byte, deadline time.Time) ([]byte, error) {
+	upstream, err := c.dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	defer upstream.Close()
+
+	if !deadline.IsZero() {
+		upstream.SetDeadline(deadline)
+	}
+
+	if network == "udp" || network == "udp4" || network == "udp6" {
+		if _, err := upstream.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		for {
+			n, err := upstream.Read(buf)
+			if err != nil {
+				return nil, err
+			}
+			// Ignore stray packets that don't answer this query
+			if n >= 2 && binary.BigEndian.Uint16(buf) == binary.BigEndian.Uint16(query) {
+				return buf[:n], nil
+			}
+		}
+	}
+
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := upstream.Write(append(framed, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(upstream, length[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(upstream, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// withID returns a copy of a response carrying the query's ID
+func withID(response []byte, id uint16) []byte {
+	out := append([]byte(nil), response...)
+	binary.BigEndian.PutUint16(out, id)
+	return out
+}
+
+// conn is the connection handed to Go's resolver. Written queries are
+// answered on the next read; stream connections use the 2-byte length
+// framing of DNS over TCP.
+type conn struct {
+	ctx     context.Context
+	cache   *Cache
+	network string
+	address string
+
+	mu       sync.Mutex
+	deadline time.Time
+	query    []byte
+	pending  []byte
+	closed   bool
+}
+
+func (c *conn) stream() bool {
+	return c.network == "tcp" || c.network == "tcp4" || c.network == "tcp6"
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	c.query = append(c.query, b...)
+	return len(b), nil
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+
+	if len(c.pending) == 0 {
+		query := c.query
+		if c.stream() {
+			if len(query) < 2 {
+				return 0, errors.New("incomplete DNS query")
+			}
+			query = query[2:]
+		}
+		if len(query) == 0 {
+			return 0, errors.New("no DNS query written")
+		}
+		c.query = nil
+
+		response, err := c.cache.exchange(c.ctx, c.network, c.address, query, c.deadline)
+		if err != nil {
+			return 0, err
+		}
+		if c.stream() {
+			response = append(binary.BigEndian.AppendUint16(nil, uint16(len(response))), response...)
+		}
+		c.pending = response
+	}
+
+	if !c.stream() {
+		// A datagram is read whole; answers cached from TCP may not fit,
+		// which makes the resolver retry over TCP
+		response := c.pending
+		c.pending = nil
+		if len(response) > len(b) {
+			response = truncate(response)
+		}
+		return copy(b, response), nil
+	}
+
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *conn) LocalAddr() net.Addr  { return stubAddr{network: c.network, address: "dnscache"} }
+func (c *conn) RemoteAddr() net.Addr { return stubAddr{network: c.network, address: c.address} }
+
+func (c *conn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error  { return c.SetDeadline(t) }
+func (c *conn) SetWriteDeadline(t time.Time) error { return nil }
+
+// packetConn is a datagram connection handed to Go's resolver
+type packetConn struct {
+	*conn
+}
+
+func (c *packetConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, err := c.Read(b)
+	return n, c.RemoteAddr(), err
+}
+
+func (c *packetConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.Write(b)
+}
+
+// stubAddr is the address of a cache connection
+type stubAddr struct {
+	network, address string
+}
+
+func (a stubAddr) Network() string { return a.network }
+func (a stubAddr) String() string  { return a.address }
@@ -0,0 +1,189 @@
+package dnscache
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// DNS wire format constants (RFC 1035)
+const (
+	headerLen = 12
+
+	typeSOA = 6
+	typeOPT = 41
+
+	rcodeSuccess  = 0
+	rcodeNXDomain = 3
+
+	flagTruncated = 1 << 9
+)
+
+var errMalformed = errors.New("malformed DNS message")
+
+// question identifies a query; it is the cache key
+type question struct {
+	name  string // Lowercase
+	qtype uint16
+	class uint16
+}
+
+// parseQuestion returns the ID and first question of a message
+func parseQuestion(msg []byte) (uint16, question, error) {
+	if len(msg) < headerLen || binary.BigEndian.Uint16(msg[4:]) == 0 {
+		return 0, question{}, errMalformed
+	}
+
+	name, off, err := readName(msg, headerLen)
+	if err != nil {
+		return 0, question{}, err
+	}
+	if off+4 > len(msg) {
+		return 0, question{}, errMalformed
+	}
+
+	return binary.BigEndian.Uint16(msg), question{
+		name:  strings.ToLower(name),
+		qtype: binary.BigEndian.Uint16(msg[off:]),
+		class: binary.BigEndian.Uint16(msg[off+2:]),
+	}, nil
+}
+
+// responseInfo is what the cache needs to know about a response
+type responseInfo struct {
+	rcode     int
+	truncated bool
+	answers   int
+	ttl       time.Duration // Lowest TTL of the answer and authority records
+	hasTTL    bool
+	negTTL    time.Duration // From the SOA record of negative answers
+	hasNegTTL bool
+}
+
+// inspect reads the header and the TTLs of a response
+func inspect(msg []byte) (responseInfo, error) {
+	if len(msg) < headerLen {
+		return responseInfo{}, errMalformed
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:])
+	info := responseInfo{
+		rcode:     int(flags & 0xF),
+		truncated: flags&flagTruncated != 0,
+		answers:   int(binary.BigEndian.Uint16(msg[6:])),
+	}
+
+	off := headerLen
+	for range binary.BigEndian.Uint16(msg[4:]) {
+		var err error
+		if off, err = skipName(msg, off); err != nil {
+			return info, err
+		}
+		off += 4
+	}
+
+	// Answer and authority sections; the additional section only carries
+	// hints (and the EDNS OPT pseudo-record) and is ignored
+	records := info.answers + int(binary.BigEndian.Uint16(msg[8:]))
+	for range records {
+		var err error
+		if off, err = skipName(msg, off); err != nil {
+			return info, err
+		}
+		if off+10 > len(msg) {
+			return info, errMalformed
+		}
+
+		rtype := binary.BigEndian.Uint16(msg[off:])
+		ttl := time.Duration(binary.BigEndian.Uint32(msg[off+4:])) * time.Second
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		rdata := off + 10
+		if rdata+rdlen > len(msg) {
+			return info, errMalformed
+		}
+		off = rdata + rdlen
+
+		if rtype == typeOPT {
+			continue
+		}
+		if !info.hasTTL || ttl < info.ttl {
+			info.ttl, info.hasTTL = ttl, true
+		}
+
+		// Negative answers are cached for the lower of the SOA record's
+		// TTL and its MINIMUM field (RFC 2308)
+		if rtype == typeSOA {
+			pos, err := skipName(msg, rdata)
+			if err == nil {
+				pos, err = skipName(msg, pos)
+			}
+			if err == nil && pos+20 <= off {
+				negTTL := min(ttl, time.Duration(binary.BigEndian.Uint32(msg[pos+16:]))*time.Second)
+				info.negTTL, info.hasNegTTL = negTTL, true
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// truncate returns the header and question of a response with the
+// truncated flag set, telling the client to retry over TCP
+func truncate(msg []byte) []byte {
+	end := headerLen
+	for range binary.BigEndian.Uint16(msg[4:]) {
+		next, err := skipName(msg, end)
+		if err != nil || next+4 > len(msg) {
+			break
+		}
+		end = next + 4
+	}
+
+	out := append([]byte(nil), msg[:end]...)
+	binary.BigEndian.PutUint16(out[2:], binary.BigEndian.Uint16(out[2:])|flagTruncated)
+	clear(out[6:headerLen]) // No answer, authority or additional records
+	return out
+}
+
+// readName reads a possibly compressed domain name
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errMalformed
+		}
+
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case length&0xC0 == 0xC0:
+			if off+1 >= len(msg) || jumps > 16 {
+				return "", 0, errMalformed
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+			jumps++
+		default:
+			if off+1+length > len(msg) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(msg[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+}
+
+// skipName returns the offset after a domain name
+func skipName(msg []byte, off int) (int, error) {
+	_, end, err := readName(msg, off)
+	return end, err
+}
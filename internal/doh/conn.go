@@ -0,0 +1,94 @@
+package doh
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// conn is the connection handed to Go's resolver. It speaks DNS over TCP:
+// a written query, framed by its 2-byte length, is sent to the DoH server
+// and its response is returned, framed the same way, by the next reads.
+type conn struct {
+	ctx    context.Context
+	client *Client
+
+	mu       sync.Mutex
+	deadline time.Time
+	query    []byte
+	pending  []byte
+	closed   bool
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	c.query = append(c.query, b...)
+	return len(b), nil
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+
+	if len(c.pending) == 0 {
+		if len(c.query) < 2 || len(c.query)-2 < int(binary.BigEndian.Uint16(c.query)) {
+			return 0, errors.New("incomplete DNS query")
+		}
+		query := c.query[2:]
+		c.query = nil
+
+		ctx := c.ctx
+		if !c.deadline.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, c.deadline)
+			defer cancel()
+		}
+		response, err := c.client.Exchange(ctx, query)
+		if err != nil {
+			return 0, err
+		}
+		c.pending = append(binary.BigEndian.AppendUint16(nil, uint16(len(response))), response...)
+	}
+
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *conn) LocalAddr() net.Addr  { return stubAddr("doh") }
+func (c *conn) RemoteAddr() net.Addr { return stubAddr(c.client.url) }
+
+func (c *conn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return nil
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error  { return c.SetDeadline(t) }
+func (c *conn) SetWriteDeadline(t time.Time) error { return nil }
+
+// stubAddr is the address of a DoH connection
+type stubAddr string
+
+func (a stubAddr) Network() string { return "tcp" }
+func (a stubAddr) String() string  { return string(a) }
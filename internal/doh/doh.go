@@ -0,0 +1,136 @@
+package doh
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"public-ip-monitor/internal/debughttp"
+)
+
+// contentType is the media type of DNS messages sent over HTTPS (RFC 8484)
+const contentType = "application/dns-message"
+
+// maxMessageSize is the largest DNS message, bounded by its TCP framing
+const maxMessageSize = 65535
+
+// Client resolves names through a DNS-over-HTTPS server, for networks whose
+// DNS is broken or hijacked
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// New creates a client of the DoH server at serverURL, e.g.
+// "https://cloudflare-dns.com/dns-query". With bootstrap addresses, the
+// server's hostname is not looked up but connected to at those addresses in
+// turn, since the system resolver may be the broken one.
+func New(serverURL string, bootstrap []string, timeout time.Duration) (*Client, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid DoH server URL %q (expected https://host/path)", serverURL)
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	transport := debughttp.BaseTransport().Clone()
+	if len(bootstrap) > 0 {
+		transport.DialContext = bootstrapDial(u.Hostname(), bootstrap)
+	}
+
+	return &Client{
+		url: serverURL,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: debughttp.Wrap(transport),
+		},
+	}, nil
+}
+
+// Resolver returns a resolver sending its queries to the DoH server. Names
+// in the hosts file resolve as before.
+func (c *Client) Resolver() *net.Resolver {
+	return &net.Resolver{PreferGo: true, Dial: c.Dial}
+}
+
+// Dial returns a connection answering the resolver's queries through the
+// DoH server, whichever DNS server the resolver asked for
+func (c *Client) Dial(ctx context.Context, network, address string) (net.Conn, error) {
+	// Offered as a stream connection even for UDP, so that responses are
+	// never truncated to the size of a datagram
+	return &conn{ctx: ctx, client: c}, nil
+}
+
+// Exchange sends a DNS query to the server and returns its response
+func (c *Client) Exchange(ctx context.Context, query []byte) ([]byte, error) {
+	if len(query) < 2 {
+		return nil, errors.New("malformed DNS query")
+	}
+
+	// The ID is 0 on the wire so that HTTP caches can share responses
+	msg := bytes.Clone(query)
+	id := binary.BigEndian.Uint16(msg)
+	binary.BigEndian.PutUint16(msg, 0)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(msg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DoH request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned status %d", resp.StatusCode)
+	}
+	if media, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); media != contentType {
+		return nil, fmt.Errorf("DoH server returned %q instead of a DNS message", resp.Header.Get("Content-Type"))
+	}
+
+	response, err := io.ReadAll(io.LimitReader(resp.Body, maxMessageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DoH response: %w", err)
+	}
+	if len(response) < 12 || len(response) > maxMessageSize {
+		return nil, fmt.Errorf("DoH server returned a malformed DNS message")
+	}
+	binary.BigEndian.PutUint16(response, id)
+	return response, nil
+}
+
+// bootstrapDial returns a dial function connecting to the bootstrap
+// addresses instead of looking up host. Other hosts, e.g. a proxy, are
+// dialed as usual.
+func bootstrapDial(host string, bootstrap []string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		addrHost, port, err := net.SplitHostPort(addr)
+		if err != nil || addrHost != host {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		var lastErr error
+		for _, ip := range bootstrap {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
@@ -0,0 +1,249 @@
+package enrich
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Info describes the network a public IP belongs to
+type Info struct {
+	ASN     int    // Origin autonomous system; 0 when the lookup failed
+	ASName  string // e.g. "AMAZON-02 - Amazon.com, Inc., US"
+	Network string // Announced prefix, e.g. "203.0.113.0/24"
+	Country string // Country code the IP is geolocated in, or else the prefix is registered in
+	Region  string // Geolocated region, e.g. "Berlin"
+	City    string // Geolocated city
+	Hosted  bool   // The IP belongs to a hosting, cloud or VPN provider rather than an ISP
+	Reason  string // Why Hosted is set
+}
+
+// Details returns the info as enrichment details for notifications and
+// exports, or nil when nothing is known
+func (i Info) Details() map[string]string {
+	details := map[string]string{}
+	if i.ASN != 0 {
+		details["asn"] = fmt.Sprintf("AS%d", i.ASN)
+	}
+	if i.ASName != "" {
+		details["as_name"] = i.ASName
+	}
+	if i.Network != "" {
+		details["network"] = i.Network
+	}
+	if i.Country != "" {
+		details["country"] = i.Country
+	}
+	if i.Region != "" {
+		details["region"] = i.Region
+	}
+	if i.City != "" {
+		details["city"] = i.City
+	}
+	if i.Hosted {
+		details["hosted"] = i.Reason
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// Enricher looks up the origin network of public IPs through the Team
+// Cymru IP-to-ASN DNS service, optionally geolocates them, and flags the
+// ones of hosting and VPN providers
+type Enricher struct {
+	resolver *net.Resolver
+	locator  Locator // Nil unless geolocation is enabled
+	timeout  time.Duration
+	asns     map[int]bool // Hosting ASNs beyond the built-in ones
+	networks []*net.IPNet // Hosting ranges from the list file
+}
+
+// New creates an enricher flagging the built-in hosting providers, the
+// given ASNs and the ASNs and ranges listed in listFile, if set
+func New(asns []int, listFile string, timeout time.Duration) (*Enricher, error) {
+	e := &Enricher{resolver: net.DefaultResolver, timeout: timeout, asns: make(map[int]bool)}
+	for _, asn := range asns {
+		e.asns[asn] = true
+	}
+	if listFile != "" {
+		if err := e.loadList(listFile); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// SetLocator makes lookups geolocate IPs with the given locator
+func (e *Enricher) SetLocator(locator Locator) {
+	e.locator = locator
+}
+
+// Locates reports whether lookups geolocate IPs
+func (e *Enricher) Locates() bool {
+	return e.locator != nil
+}
+
+// loadList reads ASNs ("AS64500" or "64500") and CIDR ranges, one per
+// line; "#" starts a comment
+func (e *Enricher) loadList(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open hosting list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry, _, _ := strings.Cut(scanner.Text(), "#")
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return fmt.Errorf("%s:%d: invalid range %q", path, line, entry)
+			}
+			e.networks = append(e.networks, network)
+			continue
+		}
+		asn, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(entry), "AS"))
+		if err != nil || asn <= 0 {
+			return fmt.Errorf("%s:%d: invalid ASN %q", path, line, entry)
+		}
+		e.asns[asn] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read hosting list: %w", err)
+	}
+	return nil
+}
+
+// Lookup returns the network of ip. When a lookup fails, what is known
+// (e.g. a range listed in the hosting list) is returned with the error.
+func (e *Enricher) Lookup(ctx context.Context, ip string) (Info, error) {
+	var info Info
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return info, fmt.Errorf("invalid IP %q", ip)
+	}
+	for _, network := range e.networks {
+		if network.Contains(parsed) {
+			info.Hosted = true
+			info.Reason = "listed hosting range " + network.String()
+			break
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	err := e.lookupOrigin(ctx, parsed, &info)
+	if e.locator != nil {
+		err = errors.Join(err, e.locate(ctx, ip, &info))
+	}
+	if info.ASN == 0 || info.Hosted {
+		return info, err
+	}
+
+	switch {
+	case e.asns[info.ASN]:
+		info.Hosted, info.Reason = true, fmt.Sprintf("listed hosting network AS%d", info.ASN)
+	case knownHostingASNs[info.ASN] != "":
+		info.Hosted, info.Reason = true, fmt.Sprintf("AS%d is %s", info.ASN, knownHostingASNs[info.ASN])
+	default:
+		if keyword := hostingKeyword(info.ASName); keyword != "" {
+			info.Hosted, info.Reason = true, fmt.Sprintf("network name contains %q", keyword)
+		}
+	}
+	return info, err
+}
+
+// locate adds the geolocation of ip, and its network when the origin
+// lookup did not find it
+func (e *Enricher) locate(ctx context.Context, ip string, info *Info) error {
+	location, err := e.locator.Locate(ctx, ip)
+	if err != nil {
+		return err
+	}
+	if location.Country != "" {
+		info.Country = location.Country
+	}
+	info.Region = location.Region
+	info.City = location.City
+	if info.ASN == 0 {
+		info.ASN, info.ASName = location.ASN, location.ASName
+	}
+	return nil
+}
+
+// lookupOrigin queries the origin AS of ip, then its name
+func (e *Enricher) lookupOrigin(ctx context.Context, ip net.IP, info *Info) error {
+	records, err := e.resolver.LookupTXT(ctx, originName(ip))
+	if err != nil {
+		return fmt.Errorf("ASN lookup failed: %w", err)
+	}
+	// "16509 | 3.5.140.0/22 | US | arin | 2018-04-10"; a prefix announced
+	// by several ASes lists them all in the first field
+	fields := splitTXT(records)
+	if len(fields) < 3 {
+		return fmt.Errorf("ASN lookup failed: unexpected answer %q", strings.Join(records, " "))
+	}
+	asn, err := strconv.Atoi(strings.Fields(fields[0])[0])
+	if err != nil {
+		return fmt.Errorf("ASN lookup failed: unexpected answer %q", strings.Join(records, " "))
+	}
+	info.ASN = asn
+	info.Network = fields[1]
+	info.Country = fields[2]
+
+	// "16509 | US | arin | 2000-05-04 | AMAZON-02 - Amazon.com, Inc., US"
+	records, err = e.resolver.LookupTXT(ctx, fmt.Sprintf("AS%d.asn.cymru.com", asn))
+	if err != nil {
+		return fmt.Errorf("AS name lookup failed: %w", err)
+	}
+	if fields := splitTXT(records); len(fields) >= 5 {
+		info.ASName = fields[4]
+	}
+	return nil
+}
+
+// originName returns the name to query for the origin of ip, with the
+// octets (IPv4) or nibbles (IPv6) reversed
+func originName(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.origin.asn.cymru.com", v4[3], v4[2], v4[1], v4[0])
+	}
+	const hexDigits = "0123456789abcdef"
+	var name strings.Builder
+	for i := len(ip) - 1; i >= 0; i-- {
+		name.WriteByte(hexDigits[ip[i]&0x0f])
+		name.WriteByte('.')
+		name.WriteByte(hexDigits[ip[i]>>4])
+		name.WriteByte('.')
+	}
+	name.WriteString("origin6.asn.cymru.com")
+	return name.String()
+}
+
+// splitTXT splits the first answer into its "|" separated fields
+func splitTXT(records []string) []string {
+	if len(records) == 0 {
+		return nil
+	}
+	fields := strings.Split(records[0], "|")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	if fields[0] == "" {
+		return nil
+	}
+	return fields
+}
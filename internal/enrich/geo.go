@@ -0,0 +1,154 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Location is where a geolocation service places an IP
+type Location struct {
+	Country string // ISO 3166 code, e.g. "DE"
+	Region  string
+	City    string
+	ASN     int // 0 when the service does not tell
+	ASName  string
+}
+
+// Locator geolocates public IPs
+type Locator interface {
+	Locate(ctx context.Context, ip string) (Location, error)
+}
+
+// GeoProviders lists the geolocation services NewLocator supports
+var GeoProviders = []string{"ipinfo", "ip-api"}
+
+// NewLocator creates a locator using the given service. baseURL replaces
+// the service's own, e.g. for a self-hosted mirror; token is the ipinfo
+// access token or the ip-api Pro key, if any.
+func NewLocator(provider, baseURL, token string) (Locator, error) {
+	client := &http.Client{}
+	switch provider {
+	case "ipinfo":
+		if baseURL == "" {
+			baseURL = "https://ipinfo.io"
+		}
+		return &ipinfoLocator{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, client: client}, nil
+	case "ip-api":
+		if baseURL == "" {
+			// The free service is HTTP only; Pro keys get HTTPS
+			baseURL = "http://ip-api.com"
+			if token != "" {
+				baseURL = "https://pro.ip-api.com"
+			}
+		}
+		return &ipAPILocator{baseURL: strings.TrimSuffix(baseURL, "/"), key: token, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown geolocation provider %q (expected %s)", provider, strings.Join(GeoProviders, " or "))
+	}
+}
+
+// ipinfoLocator asks ipinfo.io
+type ipinfoLocator struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func (l *ipinfoLocator) Locate(ctx context.Context, ip string) (Location, error) {
+	var answer struct {
+		Country string `json:"country"`
+		Region  string `json:"region"`
+		City    string `json:"city"`
+		Org     string `json:"org"` // e.g. "AS3209 Vodafone GmbH"
+	}
+	header := http.Header{}
+	if l.token != "" {
+		header.Set("Authorization", "Bearer "+l.token)
+	}
+	if err := getJSON(ctx, l.client, l.baseURL+"/"+url.PathEscape(ip)+"/json", header, &answer); err != nil {
+		return Location{}, err
+	}
+
+	location := Location{Country: answer.Country, Region: answer.Region, City: answer.City}
+	location.ASN, location.ASName = parseOrg(answer.Org)
+	return location, nil
+}
+
+// ipAPILocator asks ip-api.com
+type ipAPILocator struct {
+	baseURL string
+	key     string
+	client  *http.Client
+}
+
+func (l *ipAPILocator) Locate(ctx context.Context, ip string) (Location, error) {
+	var answer struct {
+		Status      string `json:"status"`
+		Message     string `json:"message"`
+		CountryCode string `json:"countryCode"`
+		RegionName  string `json:"regionName"`
+		City        string `json:"city"`
+		AS          string `json:"as"` // e.g. "AS3209 Vodafone GmbH"
+	}
+	query := url.Values{"fields": {"status,message,countryCode,regionName,city,as"}}
+	if l.key != "" {
+		query.Set("key", l.key)
+	}
+	link := l.baseURL + "/json/" + url.PathEscape(ip) + "?" + query.Encode()
+	if err := getJSON(ctx, l.client, link, nil, &answer); err != nil {
+		return Location{}, err
+	}
+	if answer.Status != "success" {
+		return Location{}, fmt.Errorf("geolocation failed: %s", answer.Message)
+	}
+
+	location := Location{Country: answer.CountryCode, Region: answer.RegionName, City: answer.City}
+	location.ASN, location.ASName = parseOrg(answer.AS)
+	return location, nil
+}
+
+// getJSON decodes the JSON answer of a geolocation service
+func getJSON(ctx context.Context, client *http.Client, link string, header http.Header, answer any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create geolocation request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("geolocation failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return fmt.Errorf("failed to read geolocation: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("geolocation service returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, answer); err != nil {
+		return fmt.Errorf("failed to parse geolocation: %w", err)
+	}
+	return nil
+}
+
+// parseOrg splits an organization such as "AS3209 Vodafone GmbH" into its
+// ASN and name; anything else is a name only
+func parseOrg(org string) (int, string) {
+	prefix, name, _ := strings.Cut(strings.TrimSpace(org), " ")
+	if asn, err := strconv.Atoi(strings.TrimPrefix(prefix, "AS")); err == nil && strings.HasPrefix(prefix, "AS") {
+		return asn, strings.TrimSpace(name)
+	}
+	return 0, strings.TrimSpace(org)
+}
@@ -0,0 +1,47 @@
+package enrich
+
+import "strings"
+
+// knownHostingASNs are cloud, hosting and VPN networks whose addresses a
+// home or office connection does not get from its ISP
+var knownHostingASNs = map[int]string{
+	8075:   "Microsoft",
+	9009:   "M247",
+	12876:  "Scaleway",
+	13335:  "Cloudflare",
+	14061:  "DigitalOcean",
+	14618:  "Amazon",
+	16276:  "OVH",
+	16509:  "Amazon",
+	20473:  "Vultr",
+	24940:  "Hetzner",
+	31898:  "Oracle Cloud",
+	36352:  "ColoCrossing",
+	39351:  "31173 Services (Mullvad VPN)",
+	40676:  "Psychz Networks",
+	45102:  "Alibaba Cloud",
+	51167:  "Contabo",
+	60068:  "Datacamp (CDN77)",
+	62240:  "Clouvider",
+	63949:  "Akamai (Linode)",
+	132203: "Tencent Cloud",
+	136787: "TEFINCOM (NordVPN)",
+	212238: "Datacamp (CDN77)",
+	396982: "Google Cloud",
+}
+
+// hostingKeywords in the name of a network suggest a hosting or VPN
+// provider. The list is kept short: ISPs also run data centers, so a false
+// alarm is worse than a missed one.
+var hostingKeywords = []string{"HOSTING", "VPN", "DATACENTER", "DATA CENTER", "DEDICATED", "COLOCATION", "VPS"}
+
+// hostingKeyword returns the keyword found in an AS name, if any
+func hostingKeyword(name string) string {
+	upper := strings.ToUpper(name)
+	for _, keyword := range hostingKeywords {
+		if strings.Contains(upper, keyword) {
+			return keyword
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrUnsupported is returned on platforms where the gateway cannot be looked up
+var ErrUnsupported = errors.New("default gateway lookup is not supported on this platform")
+
+// Info identifies the default gateway (router) the host is using
+type Info struct {
+	Interface string
+	IP        string
+	MAC       string // Empty if the gateway is not in the neighbor table
+}
+
+// Same reports whether both describe the same router. MAC addresses are only
+// compared when both are known, so a missing neighbor entry is not a change.
+func (i Info) Same(other Info) bool {
+	if i.IP != other.IP || i.Interface != other.Interface {
+		return false
+	}
+	return i.MAC == "" || other.MAC == "" || i.MAC == other.MAC
+}
+
+// LookupFrom returns the interface holding the local address and the
+// gateway of the default route on that interface, i.e. the way out taken
+// by connections from that address. The interface is set even when the
+// gateway is not found, e.g. on a point-to-point link.
+func LookupFrom(local net.IP) (Info, error) {
+	name, err := interfaceOf(local)
+	if err != nil {
+		return Info{}, err
+	}
+	info := Info{Interface: name}
+	info.IP, err = interfaceGateway(name, local.To4() == nil)
+	return info, err
+}
+
+// interfaceOf returns the name of the interface holding the address
+func interfaceOf(local net.IP) (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(local) {
+				return iface.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no interface holds %s", local)
+}
+
+// Change describes the gateway at the time of an observation
+type Change struct {
+	Current   Info
+	Previous  Info      // Gateway before the most recent change
+	Changed   bool      // The gateway changed recently
+	ChangedAt time.Time // When the change was observed
+	Detected  bool      // This observation is the one that detected the change
+}
+
+// Tracker remembers the gateway between observations to detect changes
+type Tracker struct {
+	mu        sync.Mutex
+	window    time.Duration
+	lookup    func() (Info, error)
+	current   Info
+	known     bool
+	previous  Info
+	changedAt time.Time
+}
+
+// NewTracker creates a tracker that keeps reporting a change for window
+// after it was observed, so every notification within that time includes it
+func NewTracker(window time.Duration) *Tracker {
+	return &Tracker{
+		window: window,
+		lookup: Lookup,
+	}
+}
+
+// Observe looks up the current gateway and compares it with the last observation
+func (t *Tracker) Observe() (Change, error) {
+	info, err := t.lookup()
+	if err != nil {
+		return Change{}, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	detected := t.known && !t.current.Same(info)
+	if detected {
+		t.previous = t.current
+		t.changedAt = now
+	}
+	t.current = info
+	t.known = true
+
+	change := Change{Current: info, Detected: detected}
+	if !t.changedAt.IsZero() && now.Sub(t.changedAt) <= t.window {
+		change.Previous = t.previous
+		change.Changed = true
+		change.ChangedAt = t.changedAt
+	}
+	return change, nil
+}
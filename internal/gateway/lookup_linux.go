@@ -0,0 +1,148 @@
+//go:build linux
+
+package gateway
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	procRoute     = "/proc/net/route"
+	procIPv6Route = "/proc/net/ipv6_route"
+	procARP       = "/proc/net/arp"
+
+	routeFlagGateway = 0x2 // RTF_GATEWAY
+)
+
+// Lookup returns the IPv4 default gateway and its MAC address from the neighbor table
+func Lookup() (Info, error) {
+	info, err := defaultRoute("")
+	if err != nil {
+		return Info{}, err
+	}
+
+	info.MAC, err = neighborMAC(info.IP, info.Interface)
+	if err != nil {
+		return Info{}, err
+	}
+
+	return info, nil
+}
+
+// interfaceGateway returns the gateway of the default route on the named
+// interface
+func interfaceGateway(iface string, ipv6 bool) (string, error) {
+	if ipv6 {
+		return defaultIPv6Route(iface)
+	}
+	info, err := defaultRoute(iface)
+	return info.IP, err
+}
+
+// defaultRoute finds the IPv4 default route in the kernel routing table, on
+// the named interface if set
+func defaultRoute(iface string) (Info, error) {
+	f, err := os.Open(procRoute)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to read routing table: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Skip header
+	for scanner.Scan() {
+		// Iface Destination Gateway Flags RefCnt Use Metric Mask ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		if iface != "" && fields[0] != iface {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&routeFlagGateway == 0 {
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		// The kernel prints the address in host byte order
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.NativeEndian.Uint32(raw))
+
+		return Info{Interface: fields[0], IP: ip.String()}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return Info{}, fmt.Errorf("failed to read routing table: %w", err)
+	}
+
+	if iface != "" {
+		return Info{}, fmt.Errorf("no default route on %s", iface)
+	}
+	return Info{}, fmt.Errorf("no default route found")
+}
+
+// defaultIPv6Route finds the gateway of the IPv6 default route on the named
+// interface in the kernel routing table
+func defaultIPv6Route(iface string) (string, error) {
+	f, err := os.Open(procIPv6Route)
+	if err != nil {
+		return "", fmt.Errorf("failed to read IPv6 routing table: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Destination, prefix length, source, prefix length, next hop, metric, refcount, use, flags, iface
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[9] != iface || fields[1] != "00" || strings.Trim(fields[0], "0") != "" {
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[4])
+		if err != nil || len(raw) != net.IPv6len || net.IP(raw).IsUnspecified() {
+			continue
+		}
+		return net.IP(raw).String(), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read IPv6 routing table: %w", err)
+	}
+
+	return "", fmt.Errorf("no IPv6 default route on %s", iface)
+}
+
+// neighborMAC returns the MAC address of ip on iface from the ARP table
+func neighborMAC(ip, iface string) (string, error) {
+	f, err := os.Open(procARP)
+	if err != nil {
+		return "", fmt.Errorf("failed to read neighbor table: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Skip header
+	for scanner.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[0] != ip || fields[5] != iface {
+			continue
+		}
+		if fields[3] == "00:00:00:00:00:00" {
+			return "", nil // Incomplete entry
+		}
+		return strings.ToLower(fields[3]), nil
+	}
+
+	return "", scanner.Err()
+}
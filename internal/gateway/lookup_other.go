@@ -0,0 +1,13 @@
+//go:build !linux
+
+package gateway
+
+// Lookup is not implemented on this platform
+func Lookup() (Info, error) {
+	return Info{}, ErrUnsupported
+}
+
+// interfaceGateway is not implemented on this platform
+func interfaceGateway(iface string, ipv6 bool) (string, error) {
+	return "", ErrUnsupported
+}
@@ -0,0 +1,110 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+
+	"public-ip-monitor/internal/enrich"
+)
+
+// DB geolocates IPs locally with a City database and, if present, an ASN
+// database. It implements enrich.Locator.
+type DB struct {
+	cityPath string
+	asnPath  string
+
+	city atomic.Pointer[Reader]
+	asn  atomic.Pointer[Reader] // Nil without an ASN database
+}
+
+// OpenDB opens the databases; asnPath may be empty or name a file that
+// does not exist
+func OpenDB(cityPath, asnPath string) (*DB, error) {
+	db := &DB{cityPath: cityPath, asnPath: asnPath}
+	if err := db.Reload(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Reload reads the database files again, e.g. after an update. The
+// databases in use are kept if a file cannot be read.
+func (db *DB) Reload() error {
+	city, err := Open(db.cityPath)
+	if err != nil {
+		return fmt.Errorf("failed to open City database: %w", err)
+	}
+
+	var asn *Reader
+	if db.asnPath != "" {
+		asn, err = Open(db.asnPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to open ASN database: %w", err)
+		}
+	}
+
+	db.city.Store(city)
+	db.asn.Store(asn)
+	return nil
+}
+
+// Databases returns the metadata of the databases in use, the City one first
+func (db *DB) Databases() []Metadata {
+	databases := []Metadata{db.city.Load().Metadata}
+	if asn := db.asn.Load(); asn != nil {
+		databases = append(databases, asn.Metadata)
+	}
+	return databases
+}
+
+// Locate looks ip up in the databases
+func (db *DB) Locate(ctx context.Context, ip string) (enrich.Location, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return enrich.Location{}, fmt.Errorf("invalid IP %q", ip)
+	}
+
+	var location enrich.Location
+	fields, err := db.city.Load().Lookup(parsed)
+	if err != nil {
+		return location, fmt.Errorf("City lookup failed: %w", err)
+	}
+	if fields != nil {
+		location.Country = stringField(mapField(fields, "country"), "iso_code")
+		if location.Country == "" {
+			location.Country = stringField(mapField(fields, "registered_country"), "iso_code")
+		}
+		if subdivisions, _ := fields["subdivisions"].([]any); len(subdivisions) > 0 {
+			subdivision, _ := subdivisions[0].(map[string]any)
+			location.Region = englishName(subdivision)
+		}
+		location.City = englishName(mapField(fields, "city"))
+	}
+
+	if asn := db.asn.Load(); asn != nil {
+		fields, err := asn.Lookup(parsed)
+		if err != nil {
+			return location, fmt.Errorf("ASN lookup failed: %w", err)
+		}
+		if fields != nil {
+			location.ASN = int(uintField(fields, "autonomous_system_number"))
+			location.ASName = stringField(fields, "autonomous_system_organization")
+		}
+	}
+	return location, nil
+}
+
+// mapField returns a map of a map, or nil
+func mapField(fields map[string]any, name string) map[string]any {
+	m, _ := fields[name].(map[string]any)
+	return m
+}
+
+// englishName returns the English name of a place
+func englishName(place map[string]any) string {
+	return stringField(mapField(place, "names"), "en")
+}
@@ -0,0 +1,209 @@
+package geo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Types of the MaxMind DB data section
+const (
+	typeExtended = 0
+	typePointer  = 1
+	typeString   = 2
+	typeDouble   = 3
+	typeBytes    = 4
+	typeUint16   = 5
+	typeUint32   = 6
+	typeMap      = 7
+	typeInt32    = 8
+	typeUint64   = 9
+	typeUint128  = 10
+	typeArray    = 11
+	typeBool     = 14
+	typeFloat    = 15
+)
+
+// maxDepth bounds the nesting of maps and arrays, against corrupt files
+const maxDepth = 32
+
+// errCorrupt reports a data section that cannot be decoded
+var errCorrupt = errors.New("corrupt MaxMind DB data")
+
+// decoder reads values of a data section into maps, slices, strings,
+// uint64, int32, float64, bool, []byte and *big.Int (uint128)
+type decoder struct {
+	data []byte
+}
+
+// decode returns the value at offset and the offset following it
+func (d decoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, fmt.Errorf("%w: nested too deep", errCorrupt)
+	}
+	kind, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if kind == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(target, depth+1)
+		return value, next, err
+	}
+	return d.value(kind, size, offset, depth)
+}
+
+// control reads the control byte of a value, returning its type and size,
+// or the size bits of a pointer, and the offset of its payload
+func (d decoder) control(offset uint) (int, uint, uint, error) {
+	if offset >= uint(len(d.data)) {
+		return 0, 0, 0, fmt.Errorf("%w: offset %d beyond the data section", errCorrupt, offset)
+	}
+	ctrl := d.data[offset]
+	offset++
+
+	kind := int(ctrl >> 5)
+	if kind == typeExtended {
+		if offset >= uint(len(d.data)) {
+			return 0, 0, 0, errCorrupt
+		}
+		kind = 7 + int(d.data[offset])
+		offset++
+	}
+	if kind == typePointer {
+		return kind, uint(ctrl & 0x1f), offset, nil
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size < 29 {
+		return kind, size, offset, nil
+	}
+	n := size - 28
+	bytes, err := d.bytes(offset, n)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	switch size {
+	case 29:
+		size = 29 + uint(bytes[0])
+	case 30:
+		size = 285 + uint(binary.BigEndian.Uint16(bytes))
+	case 31:
+		size = 65821 + (uint(bytes[0])<<16 | uint(bytes[1])<<8 | uint(bytes[2]))
+	}
+	return kind, size, offset + n, nil
+}
+
+// pointer resolves a pointer to an offset in the data section
+func (d decoder) pointer(bits, offset uint) (uint, uint, error) {
+	n := (bits>>3)&3 + 1
+	b, err := d.bytes(offset, n)
+	if err != nil {
+		return 0, 0, err
+	}
+	high := bits & 7
+	var target uint
+	switch n {
+	case 1:
+		target = high<<8 | uint(b[0])
+	case 2:
+		target = (high<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 3:
+		target = (high<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	case 4:
+		target = uint(binary.BigEndian.Uint32(b))
+	}
+	return target, offset + n, nil
+}
+
+// value decodes the payload of a value of the given type and size
+func (d decoder) value(kind int, size, offset uint, depth int) (any, uint, error) {
+	switch kind {
+	case typeMap:
+		values := make(map[string]any, size)
+		for range size {
+			key, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("%w: map key is not a string", errCorrupt)
+			}
+			values[name], offset, err = d.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		return values, offset, nil
+	case typeArray:
+		values := make([]any, 0, min(size, 1024))
+		for range size {
+			value, next, err := d.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			values = append(values, value)
+			offset = next
+		}
+		return values, offset, nil
+	case typeBool:
+		if size > 1 {
+			return nil, 0, fmt.Errorf("%w: boolean of size %d", errCorrupt, size)
+		}
+		return size == 1, offset, nil
+	}
+
+	b, err := d.bytes(offset, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	next := offset + size
+	switch kind {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes:
+		return append([]byte(nil), b...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("%w: double of size %d", errCorrupt, size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("%w: float of size %d", errCorrupt, size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64, typeInt32:
+		if size > 8 || (kind == typeUint16 && size > 2) || ((kind == typeUint32 || kind == typeInt32) && size > 4) {
+			return nil, 0, fmt.Errorf("%w: integer of size %d", errCorrupt, size)
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		if kind == typeInt32 {
+			return int32(uint32(n)), next, nil
+		}
+		return n, next, nil
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, fmt.Errorf("%w: integer of size %d", errCorrupt, size)
+		}
+		return new(big.Int).SetBytes(b), next, nil
+	}
+	return nil, 0, fmt.Errorf("%w: unknown type %d", errCorrupt, kind)
+}
+
+// bytes returns n bytes at offset
+func (d decoder) bytes(offset, n uint) ([]byte, error) {
+	if offset+n > uint(len(d.data)) || offset+n < offset {
+		return nil, fmt.Errorf("%w: value beyond the data section", errCorrupt)
+	}
+	return d.data[offset : offset+n], nil
+}
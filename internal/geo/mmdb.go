@@ -0,0 +1,168 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// metadataMarker precedes the metadata at the end of a MaxMind DB file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSeparator is the size of the zeros between search tree and data
+const dataSeparator = 16
+
+// Metadata describes a database
+type Metadata struct {
+	DatabaseType string // e.g. "GeoLite2-City"
+	IPVersion    uint   // 4, or 6 for databases holding both families
+	RecordSize   uint   // Bits per search tree record: 24, 28 or 32
+	NodeCount    uint
+	BuildTime    time.Time
+}
+
+// Reader looks up IPs in a MaxMind DB file (.mmdb), such as the GeoLite2
+// City and ASN databases
+type Reader struct {
+	Metadata Metadata
+
+	tree      []byte
+	data      decoder
+	ipv4Start uint // Node IPv4 lookups start at in an IPv6 tree
+}
+
+// Open reads a database file
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := FromBytes(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// FromBytes reads a database held in memory
+func FromBytes(buf []byte) (*Reader, error) {
+	start := bytes.LastIndex(buf, metadataMarker)
+	if start < 0 {
+		return nil, errors.New("not a MaxMind DB file")
+	}
+	value, _, err := decoder{data: buf[start+len(metadataMarker):]}.decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("invalid metadata")
+	}
+
+	meta := Metadata{
+		DatabaseType: stringField(fields, "database_type"),
+		IPVersion:    uint(uintField(fields, "ip_version")),
+		RecordSize:   uint(uintField(fields, "record_size")),
+		NodeCount:    uint(uintField(fields, "node_count")),
+		BuildTime:    time.Unix(int64(uintField(fields, "build_epoch")), 0),
+	}
+	if major := uintField(fields, "binary_format_major_version"); major != 2 {
+		return nil, fmt.Errorf("unsupported format version %d", major)
+	}
+	if meta.RecordSize != 24 && meta.RecordSize != 28 && meta.RecordSize != 32 {
+		return nil, fmt.Errorf("unsupported record size %d", meta.RecordSize)
+	}
+	if meta.IPVersion != 4 && meta.IPVersion != 6 {
+		return nil, fmt.Errorf("unsupported IP version %d", meta.IPVersion)
+	}
+	treeSize := meta.NodeCount * meta.RecordSize / 4
+	if treeSize+dataSeparator > uint(start) {
+		return nil, errors.New("search tree larger than the file")
+	}
+
+	r := &Reader{
+		Metadata: meta,
+		tree:     buf[:treeSize],
+		data:     decoder{data: buf[treeSize+dataSeparator : start]},
+	}
+	if meta.IPVersion == 6 {
+		// IPv4 addresses are stored as ::a.b.c.d
+		for i := 0; i < 96 && r.ipv4Start < meta.NodeCount; i++ {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+	return r, nil
+}
+
+// Lookup returns the data of the network holding ip, or nil when the
+// database has none, e.g. for IPv6 addresses in an IPv4 database
+func (r *Reader) Lookup(ip net.IP) (map[string]any, error) {
+	node := uint(0)
+	address := ip.To4()
+	switch {
+	case address != nil && r.Metadata.IPVersion == 6:
+		node = r.ipv4Start
+	case address == nil:
+		address = ip.To16()
+		if address == nil {
+			return nil, fmt.Errorf("invalid IP %v", ip)
+		}
+		if r.Metadata.IPVersion == 4 {
+			return nil, nil
+		}
+	}
+
+	for i := 0; i < len(address)*8 && node < r.Metadata.NodeCount; i++ {
+		bit := uint(address[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+	switch {
+	case node == r.Metadata.NodeCount:
+		return nil, nil
+	case node < r.Metadata.NodeCount:
+		return nil, errors.New("corrupt MaxMind DB search tree")
+	}
+
+	value, _, err := r.data.decode(node-r.Metadata.NodeCount-dataSeparator, 0)
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: record is not a map", errCorrupt)
+	}
+	return fields, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a node
+func (r *Reader) record(node, bit uint) uint {
+	switch r.Metadata.RecordSize {
+	case 24:
+		b := r.tree[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.tree[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(r.tree[node*8+bit*4:]))
+	}
+}
+
+// stringField returns a string of a map, or ""
+func stringField(fields map[string]any, name string) string {
+	s, _ := fields[name].(string)
+	return s
+}
+
+// uintField returns an unsigned integer of a map, or 0
+func uintField(fields map[string]any, name string) uint64 {
+	n, _ := fields[name].(uint64)
+	return n
+}
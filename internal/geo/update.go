@@ -0,0 +1,176 @@
+package geo
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Editions of the free databases
+const (
+	EditionCity = "GeoLite2-City"
+	EditionASN  = "GeoLite2-ASN"
+)
+
+// DefaultDownloadURL is MaxMind's download endpoint; {edition} is replaced
+// by the edition, e.g. "GeoLite2-City"
+const DefaultDownloadURL = "https://download.maxmind.com/geoip/databases/{edition}/download?suffix=tar.gz"
+
+// maxArchiveSize caps downloads, so that a broken server cannot fill the
+// memory; the City database is about 60 MB
+const maxArchiveSize = 256 << 20
+
+// Updater downloads MaxMind databases with an account's license key
+type Updater struct {
+	accountID   string
+	licenseKey  string
+	downloadURL string
+	httpClient  *http.Client
+}
+
+// NewUpdater creates an updater for the MaxMind account; downloadURL
+// replaces DefaultDownloadURL, e.g. for a mirror
+func NewUpdater(accountID, licenseKey, downloadURL string, timeout time.Duration) *Updater {
+	if downloadURL == "" {
+		downloadURL = DefaultDownloadURL
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	return &Updater{
+		accountID:   accountID,
+		licenseKey:  licenseKey,
+		downloadURL: downloadURL,
+		httpClient:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Update downloads an edition to path unless the file is as recent as the
+// published one, and reports whether it was replaced. The archive is
+// checked against its published SHA-256 digest and the database has to
+// open before it replaces the file.
+func (u *Updater) Update(ctx context.Context, edition, path string) (bool, error) {
+	link := strings.ReplaceAll(u.downloadURL, "{edition}", edition)
+	var modified time.Time
+	if info, err := os.Stat(path); err == nil {
+		modified = info.ModTime()
+	}
+
+	archive, published, err := u.get(ctx, link, modified)
+	if err != nil || archive == nil {
+		return false, err
+	}
+	digest, _, err := u.get(ctx, checksumURL(link), time.Time{})
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch checksum: %w", err)
+	}
+	want, _, _ := strings.Cut(strings.TrimSpace(string(digest)), " ")
+	if sum := sha256.Sum256(archive); !strings.EqualFold(hex.EncodeToString(sum[:]), want) {
+		return false, fmt.Errorf("checksum mismatch for %s", edition)
+	}
+
+	database, err := extractDatabase(archive)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", edition, err)
+	}
+	if _, err := FromBytes(database); err != nil {
+		return false, fmt.Errorf("%s: %w", edition, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, database, 0644); err != nil {
+		return false, fmt.Errorf("failed to write database: %w", err)
+	}
+	if !published.IsZero() {
+		// Lets the next update ask for newer versions only
+		os.Chtimes(tmp, published, published)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return false, fmt.Errorf("failed to replace database: %w", err)
+	}
+	return true, nil
+}
+
+// get downloads link, returning nil when it was not modified since the
+// given time, with the time it was last modified if known
+func (u *Updater) get(ctx context.Context, link string, since time.Time) ([]byte, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.SetBasicAuth(u.accountID, u.licenseKey)
+	if !since.IsZero() {
+		req.Header.Set("If-Modified-Since", since.UTC().Format(http.TimeFormat))
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil, time.Time{}, nil
+	case http.StatusUnauthorized:
+		return nil, time.Time{}, errors.New("download refused: invalid account ID or license key")
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, time.Time{}, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveSize+1))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("download failed: %w", err)
+	}
+	if len(data) > maxArchiveSize {
+		return nil, time.Time{}, fmt.Errorf("download larger than %d MB", maxArchiveSize>>20)
+	}
+	published, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return data, published, nil
+}
+
+// checksumURL returns the URL of the SHA-256 digest of an archive
+func checksumURL(link string) string {
+	if strings.Contains(link, "suffix=tar.gz") {
+		return strings.Replace(link, "suffix=tar.gz", "suffix=tar.gz.sha256", 1)
+	}
+	return link + ".sha256"
+}
+
+// extractDatabase returns the .mmdb file of a .tar.gz archive
+func extractDatabase(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("archive holds no .mmdb file")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && strings.HasSuffix(header.Name, ".mmdb") {
+			return io.ReadAll(io.LimitReader(tr, maxArchiveSize))
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package hooks
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// configureProcess only supports running as the current user on this platform
+func configureProcess(cmd *exec.Cmd, username string) error {
+	if username != "" {
+		return fmt.Errorf("running hooks as user %s is not supported on this platform", username)
+	}
+	return nil
+}
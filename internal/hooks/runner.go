@@ -0,0 +1,160 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultOutputLimit = 4096
+)
+
+// Command describes an external command run by the runner
+type Command struct {
+	Name    string        // Display name used in logs and notifications
+	Path    string        // Executable to run
+	Args    []string      // Arguments passed to the executable
+	Env     []string      // Extra environment variables (KEY=value)
+	Stdin   []byte        // Data written to the command's standard input
+	Timeout time.Duration // Zero uses DefaultTimeout
+	User    string        // Run as this user instead of the current one
+}
+
+// Result holds the outcome of a supervised command
+type Result struct {
+	Name      string
+	ExitCode  int // -1 if the command did not exit normally
+	Stdout    string
+	Stderr    string
+	Truncated bool // Output exceeded the limit and only the tail was kept
+	TimedOut  bool
+	Duration  time.Duration
+	Err       error
+}
+
+// Failed reports whether the command did not complete successfully
+func (r Result) Failed() bool {
+	return r.Err != nil
+}
+
+// Output returns the captured stdout and stderr combined for display
+func (r Result) Output() string {
+	var parts []string
+	if out := strings.TrimSpace(r.Stdout); out != "" {
+		parts = append(parts, "stdout: "+out)
+	}
+	if out := strings.TrimSpace(r.Stderr); out != "" {
+		parts = append(parts, "stderr: "+out)
+	}
+	output := strings.Join(parts, "\n")
+	if r.Truncated {
+		output = "...(truncated)\n" + output
+	}
+	return output
+}
+
+// Runner executes commands with timeouts and bounded output capture
+type Runner struct {
+	outputLimit int
+}
+
+// NewRunner creates a runner keeping at most outputLimit bytes of each output stream
+func NewRunner(outputLimit int) *Runner {
+	if outputLimit <= 0 {
+		outputLimit = DefaultOutputLimit
+	}
+	return &Runner{outputLimit: outputLimit}
+}
+
+// Run executes the command and waits for it to finish or time out
+func (r *Runner) Run(ctx context.Context, command Command) Result {
+	result := Result{Name: command.Name, ExitCode: -1}
+	if result.Name == "" {
+		result.Name = command.Path
+	}
+
+	timeout := command.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command.Path, command.Args...)
+	cmd.Env = append(os.Environ(), command.Env...)
+	if command.Stdin != nil {
+		cmd.Stdin = bytes.NewReader(command.Stdin)
+	}
+
+	stdout := &tailBuffer{limit: r.outputLimit}
+	stderr := &tailBuffer{limit: r.outputLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := configureProcess(cmd, command.User); err != nil {
+		result.Err = err
+		return result
+	}
+	// Don't wait forever for children that inherited the output pipes
+	cmd.WaitDelay = 5 * time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	result.Truncated = stdout.truncated || stderr.truncated
+
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.TimedOut = true
+		result.Err = fmt.Errorf("timed out after %v", timeout)
+	case err != nil:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.Err = fmt.Errorf("exited with status %d", result.ExitCode)
+		} else {
+			result.Err = err
+		}
+	}
+
+	return result
+}
+
+// tailBuffer keeps the last limit bytes written to it
+type tailBuffer struct {
+	mu        sync.Mutex
+	data      []byte
+	limit     int
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	if len(b.data) > b.limit {
+		b.data = append(b.data[:0], b.data[len(b.data)-b.limit:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
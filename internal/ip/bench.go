@@ -0,0 +1,170 @@
+package ip
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ServiceStats is the outcome of benchmarking one service
+type ServiceStats struct {
+	URL        string
+	Samples    int             // Queries sent
+	Successes  int             // Queries answered with an address
+	Agreements int             // Answers matching the majority of their round
+	Latencies  []time.Duration // Of the successful queries, sorted
+	LastError  string
+}
+
+// Availability returns the share of queries answered
+func (s ServiceStats) Availability() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+	return float64(s.Successes) / float64(s.Samples)
+}
+
+// Agreement returns the share of answers matching the other services
+func (s ServiceStats) Agreement() float64 {
+	if s.Successes == 0 {
+		return 0
+	}
+	return float64(s.Agreements) / float64(s.Successes)
+}
+
+// Percentile returns the latency below which the given share of the
+// successful queries finished, or zero without any
+func (s ServiceStats) Percentile(p float64) time.Duration {
+	if len(s.Latencies) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(s.Latencies)) + 0.5)
+	return s.Latencies[min(max(i-1, 0), len(s.Latencies)-1)]
+}
+
+// Usable reports whether the service is worth keeping: it answered at
+// least half the time and mostly agreed with the others
+func (s ServiceStats) Usable() bool {
+	return s.Availability() >= 0.5 && s.Agreement() >= 0.5
+}
+
+// Merge adds the samples of another benchmark of the same service, e.g.
+// for another family
+func (s ServiceStats) Merge(other ServiceStats) ServiceStats {
+	s.Samples += other.Samples
+	s.Successes += other.Successes
+	s.Agreements += other.Agreements
+	s.Latencies = append(slices.Clone(s.Latencies), other.Latencies...)
+	slices.Sort(s.Latencies)
+	if other.LastError != "" {
+		s.LastError = other.LastError
+	}
+	return s
+}
+
+// BenchServices queries every service the given number of times, in
+// rounds of concurrent queries with pause in between, and compares each
+// answer with the address most services gave in the same round
+func (f *Fetcher) BenchServices(ctx context.Context, services []string, samples int, pause time.Duration) []ServiceStats {
+	env := f.sourceEnv()
+	stats := make([]ServiceStats, len(services))
+	sources := make([]Source, len(services))
+	for i, service := range services {
+		stats[i].URL = service
+		f.sources.mu.RLock()
+		spec := f.sources.serviceSpec(service)
+		f.sources.mu.RUnlock()
+		source, err := NewSource(spec, env)
+		if err != nil {
+			stats[i].LastError = err.Error()
+			continue
+		}
+		sources[i] = source
+	}
+
+	for round := 0; round < samples && ctx.Err() == nil; round++ {
+		if round > 0 {
+			select {
+			case <-time.After(pause):
+			case <-ctx.Done():
+			}
+		}
+
+		answers := make([]string, len(services))
+		var wg sync.WaitGroup
+		for i, source := range sources {
+			stats[i].Samples++
+			if source == nil {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				start := time.Now()
+				addr, _, err := source.Fetch(ctx)
+				latency := time.Since(start)
+				if err == nil && f.family != FamilyAny && (addr.To4() != nil) != (f.family == FamilyIPv4) {
+					err = fmt.Errorf("returned %s, which is not an %s address", addr, f.family.Label())
+				}
+				if err != nil {
+					stats[i].LastError = err.Error()
+					return
+				}
+				stats[i].Successes++
+				stats[i].Latencies = append(stats[i].Latencies, latency)
+				answers[i] = addr.String()
+			}()
+		}
+		wg.Wait()
+
+		majority := majorityAnswer(answers)
+		for i, answer := range answers {
+			if answer != "" && answer == majority {
+				stats[i].Agreements++
+			}
+		}
+	}
+
+	for i := range stats {
+		slices.Sort(stats[i].Latencies)
+	}
+	return stats
+}
+
+// majorityAnswer returns the address given most often, the first given on
+// ties, or "" without any
+func majorityAnswer(answers []string) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, answer := range answers {
+		if answer == "" {
+			continue
+		}
+		counts[answer]++
+		if counts[answer] > counts[best] {
+			best = answer
+		}
+	}
+	return best
+}
+
+// SuggestServiceOrder returns the usable services, the most reliable
+// first and the fastest first among equally reliable ones
+func SuggestServiceOrder(stats []ServiceStats) []ServiceStats {
+	var usable []ServiceStats
+	for _, s := range stats {
+		if s.Usable() {
+			usable = append(usable, s)
+		}
+	}
+	slices.SortStableFunc(usable, func(a, b ServiceStats) int {
+		if c := cmp.Compare(b.Availability()*b.Agreement(), a.Availability()*a.Agreement()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Percentile(0.5), b.Percentile(0.5))
+	})
+	return usable
+}
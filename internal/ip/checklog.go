@@ -0,0 +1,181 @@
+package ip
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CheckEntry is the outcome of a single check, successful or not
+type CheckEntry struct {
+	Time      time.Time `json:"t"`
+	Family    Family    `json:"f,omitempty"`
+	WAN       string    `json:"w,omitempty"`
+	OK        bool      `json:"ok"`
+	IP        string    `json:"ip,omitempty"`
+	Source    string    `json:"src,omitempty"` // Service or method that answered
+	LatencyMS int64     `json:"ms"`
+	Error     string    `json:"err,omitempty"`
+}
+
+// CheckLog keeps the most recent checks, capped by count and age. Entries
+// are appended to a JSON lines file, which is rewritten without the dropped
+// entries once it has grown a quarter beyond the cap.
+type CheckLog struct {
+	path       string
+	maxEntries int
+	maxAge     time.Duration
+
+	mu       sync.Mutex
+	entries  []CheckEntry // Oldest first
+	lines    int          // Entries in the file, including dropped ones
+	deferred bool         // Entries are written on Flush only
+	dirty    bool         // Entries were added since the last Flush
+}
+
+// OpenCheckLog loads the check log from path, creating it on the first Add
+func OpenCheckLog(path string, maxEntries int, maxAge time.Duration) (*CheckLog, error) {
+	l := &CheckLog{path: path, maxEntries: maxEntries, maxAge: maxAge}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read check log: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var entry CheckEntry
+		// A line cut short by a crash is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		l.entries = append(l.entries, entry)
+		l.lines++
+	}
+	l.trim(time.Now())
+
+	return l, nil
+}
+
+// Add records a check
+func (l *CheckLog) Add(entry CheckEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+	l.trim(entry.Time)
+
+	if l.deferred {
+		l.dirty = true
+		return nil
+	}
+	if l.lines+1 > l.maxEntries+l.maxEntries/4 {
+		return l.rewrite()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal check: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, DataFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open check log: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write check log: %w", err)
+	}
+	l.lines++
+	return nil
+}
+
+// DeferWrites keeps added checks in memory until Flush, which writes them
+// at once, sparing SD cards a write on every check
+func (l *CheckLog) DeferWrites() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.deferred = true
+}
+
+// Flush rewrites the file with the checks added since the last flush, with
+// deferred writes
+func (l *CheckLog) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.dirty {
+		return nil
+	}
+	if err := l.rewrite(); err != nil {
+		return err
+	}
+	l.dirty = false
+	return nil
+}
+
+// Entries returns the checks since the given time, oldest first
+func (l *CheckLog) Entries(since time.Time) []CheckEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, entry := range l.entries {
+		if !entry.Time.Before(since) {
+			return append([]CheckEntry(nil), l.entries[i:]...)
+		}
+	}
+	return nil
+}
+
+// trim drops the entries beyond the count and age caps
+func (l *CheckLog) trim(now time.Time) {
+	drop := 0
+	if l.maxEntries > 0 && len(l.entries) > l.maxEntries {
+		drop = len(l.entries) - l.maxEntries
+	}
+	if l.maxAge > 0 {
+		cutoff := now.Add(-l.maxAge)
+		for drop < len(l.entries) && l.entries[drop].Time.Before(cutoff) {
+			drop++
+		}
+	}
+	l.entries = append(l.entries[:0], l.entries[drop:]...)
+}
+
+// rewrite replaces the file with the kept entries
+func (l *CheckLog) rewrite() error {
+	var buf bytes.Buffer
+	for _, entry := range l.entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal check: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to rewrite check log: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to rewrite check log: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to rewrite check log: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), DataFilePerm); err != nil {
+		return fmt.Errorf("failed to rewrite check log: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return fmt.Errorf("failed to rewrite check log: %w", err)
+	}
+
+	l.lines = len(l.entries)
+	return nil
+}
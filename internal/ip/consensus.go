@@ -0,0 +1,95 @@
+package ip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// consensusAnswer is the outcome of asking one source for a quorum
+type consensusAnswer struct {
+	source Source
+	addr   net.IP
+	meta   Meta
+	err    error
+}
+
+// fetchConsensus asks all sources at once and returns the first address
+// reported by quorum of them. The sources still asking when the quorum is
+// reached are canceled, so a check takes as long as the quorum-th answer;
+// with a quorum of one, the fastest answer wins.
+func (f *Fetcher) fetchConsensus(ctx context.Context, sources []Source, quorum int) (net.IP, Meta, error) {
+	if quorum > len(sources) {
+		return nil, Meta{}, fmt.Errorf("quorum of %d exceeds the %d sources", quorum, len(sources))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	health, _ := f.sources.tracker()
+	answers := make(chan consensusAnswer, len(sources))
+	for _, source := range sources {
+		go func() {
+			sourceCtx, local := withLocalAddr(ctx)
+			start := time.Now()
+			addr, meta, err := source.Fetch(sourceCtx)
+			if err == nil {
+				err = f.checkAddr(source, addr)
+				meta.LocalAddr = local.IP()
+			}
+			health.record(ctx, f.family, source, time.Since(start), err)
+			answers <- consensusAnswer{source: source, addr: addr, meta: meta, err: err}
+		}()
+	}
+
+	votes := make(map[string][]consensusAnswer)
+	var order []string // Addresses in the order they were first reported
+	var failed int
+	var lastError error
+	for range sources {
+		answer := <-answers
+		if answer.err != nil {
+			failed++
+			lastError = answer.err
+			continue
+		}
+
+		key := answer.addr.String()
+		if votes[key] == nil {
+			order = append(order, key)
+		}
+		votes[key] = append(votes[key], answer)
+		if len(votes[key]) < quorum {
+			continue
+		}
+
+		// The meta of the first agreeing source describes the route
+		meta := votes[key][0].meta
+		for _, vote := range votes[key] {
+			meta.Agreed = append(meta.Agreed, vote.source.Name())
+		}
+		for _, other := range order {
+			if other == key {
+				continue
+			}
+			for _, vote := range votes[other] {
+				meta.Dissent = append(meta.Dissent, vote.source.Name()+": "+other)
+			}
+		}
+		return answer.addr, meta, nil
+	}
+
+	if quorum == 1 {
+		return nil, Meta{}, fmt.Errorf("failed to get IP from all sources, last error: %w", lastError)
+	}
+	tally := make([]string, 0, len(order)+1)
+	for _, addr := range order {
+		tally = append(tally, fmt.Sprintf("%s from %d", addr, len(votes[addr])))
+	}
+	if failed > 0 {
+		tally = append(tally, fmt.Sprintf("%d failed (last error: %v)", failed, lastError))
+	}
+	return nil, Meta{}, fmt.Errorf("no %d sources agree on the IP: %s", quorum, strings.Join(tally, ", "))
+}
@@ -0,0 +1,181 @@
+package ip
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"public-ip-monitor/pkg/parquet"
+)
+
+// ExportFormats lists the formats the history can be exported in
+var ExportFormats = []string{"csv", "json", "parquet"}
+
+// ExportRecord is a history record with the context needed to analyze it
+// without the rest of the history
+type ExportRecord struct {
+	Timestamp           time.Time         `json:"timestamp"`
+	WAN                 string            `json:"wan,omitempty"`
+	Family              Family            `json:"family,omitempty"`
+	IP                  string            `json:"ip"`
+	PreviousIP          string            `json:"previous_ip,omitempty"`
+	PreviousHeldSeconds int64             `json:"previous_held_seconds,omitempty"` // How long the previous IP was held
+	Enrichment          map[string]string `json:"enrichment,omitempty"`
+}
+
+// BuildExportRecords merges the histories of the default route ("") and each
+// WAN into one chronological list, linking every record to the previous one
+// of the same WAN and family
+func BuildExportRecords(histories map[string][]Record) []ExportRecord {
+	records := []ExportRecord{}
+	for wan, history := range histories {
+		for _, record := range history {
+			records = append(records, ExportRecord{
+				Timestamp:  record.Timestamp,
+				WAN:        wan,
+				Family:     record.Family,
+				IP:         record.IP,
+				Enrichment: record.Details(),
+			})
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return compareRecords(records[i], records[j]) < 0
+	})
+
+	type series struct {
+		wan    string
+		family Family
+	}
+	previous := map[series]ExportRecord{}
+	for i, record := range records {
+		key := series{wan: record.WAN, family: record.Family}
+		if last, ok := previous[key]; ok {
+			records[i].PreviousIP = last.IP
+			records[i].PreviousHeldSeconds = int64(record.Timestamp.Sub(last.Timestamp) / time.Second)
+		}
+		previous[key] = record
+	}
+
+	return records
+}
+
+// WriteExport writes the records in the given format. Enrichment details
+// become one "enrichment_<name>" column each in the tabular formats.
+func WriteExport(w io.Writer, format string, records []ExportRecord) error {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(records, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal records: %w", err)
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case "csv":
+		return writeCSVExport(w, records)
+	case "parquet":
+		return writeParquetExport(w, records)
+	default:
+		return fmt.Errorf("unknown export format %q (available: %v)", format, ExportFormats)
+	}
+}
+
+// enrichmentNames returns the enrichment details present in any record
+func enrichmentNames(records []ExportRecord) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, record := range records {
+		for name := range record.Enrichment {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeCSVExport(w io.Writer, records []ExportRecord) error {
+	names := enrichmentNames(records)
+	out := csv.NewWriter(w)
+
+	header := []string{"timestamp", "wan", "family", "ip", "previous_ip", "previous_held_seconds"}
+	for _, name := range names {
+		header = append(header, "enrichment_"+name)
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		held := ""
+		if record.PreviousIP != "" {
+			held = strconv.FormatInt(record.PreviousHeldSeconds, 10)
+		}
+		row := []string{
+			record.Timestamp.UTC().Format(time.RFC3339),
+			record.WAN,
+			string(record.Family),
+			record.IP,
+			record.PreviousIP,
+			held,
+		}
+		for _, name := range names {
+			row = append(row, record.Enrichment[name])
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+func writeParquetExport(w io.Writer, records []ExportRecord) error {
+	names := enrichmentNames(records)
+
+	columns := []parquet.Column{
+		{Name: "timestamp", Type: parquet.Timestamp},
+		{Name: "wan", Type: parquet.String, Optional: true},
+		{Name: "family", Type: parquet.String, Optional: true},
+		{Name: "ip", Type: parquet.String},
+		{Name: "previous_ip", Type: parquet.String, Optional: true},
+		{Name: "previous_held_seconds", Type: parquet.Int64, Optional: true},
+	}
+	for _, name := range names {
+		columns = append(columns, parquet.Column{Name: "enrichment_" + name, Type: parquet.String, Optional: true})
+	}
+
+	rows := make([][]any, 0, len(records))
+	for _, record := range records {
+		row := []any{record.Timestamp, optional(record.WAN), optional(string(record.Family)), record.IP, nil, nil}
+		if record.PreviousIP != "" {
+			row[4] = record.PreviousIP
+			row[5] = record.PreviousHeldSeconds
+		}
+		for _, name := range names {
+			if value, ok := record.Enrichment[name]; ok {
+				row = append(row, value)
+			} else {
+				row = append(row, nil)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return parquet.Write(w, columns, rows)
+}
+
+// optional returns nil for an empty string, a missing value in Parquet
+func optional(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
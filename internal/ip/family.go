@@ -0,0 +1,53 @@
+package ip
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Family identifies the IP address family a fetcher or storage is bound to
+type Family string
+
+const (
+	FamilyAny  Family = ""     // Whatever family the OS prefers
+	FamilyIPv4 Family = "ipv4" // IPv4 only
+	FamilyIPv6 Family = "ipv6" // IPv6 only
+)
+
+// ParseFamily converts a configuration value into a Family
+func ParseFamily(value string) (Family, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "any":
+		return FamilyAny, nil
+	case "ipv4", "4":
+		return FamilyIPv4, nil
+	case "ipv6", "6":
+		return FamilyIPv6, nil
+	default:
+		return FamilyAny, fmt.Errorf("unknown IP family %q", value)
+	}
+}
+
+// Label returns a human-readable name for the family
+func (f Family) Label() string {
+	switch f {
+	case FamilyIPv4:
+		return "IPv4"
+	case FamilyIPv6:
+		return "IPv6"
+	default:
+		return "IP"
+	}
+}
+
+// network returns the dial network matching the family
+func (f Family) network(network string) string {
+	switch f {
+	case FamilyIPv4:
+		return network + "4"
+	case FamilyIPv6:
+		return network + "6"
+	default:
+		return network
+	}
+}
@@ -3,17 +3,23 @@ package ip
 import (
 	"context"
 	"fmt"
-	"io"
+	"net"
 	"net/http"
-	"strings"
+	"sync"
 	"time"
 )
 
 // Fetcher handles fetching current public IP from external services
 type Fetcher struct {
-	services   []string
+	sources    *sourceList // Shared with fetchers derived via ForFamily/ForInterface
 	timeout    time.Duration
+	family     Family
+	iface      string // Network interface connections originate from
 	httpClient *http.Client
+
+	builtMu      sync.Mutex
+	built        []Source // Sources bound to this fetcher's family and interface
+	builtVersion int
 }
 
 // NewFetcher creates a new IP fetcher
@@ -24,63 +30,309 @@ func NewFetcher(services []string, timeoutSeconds int) *Fetcher {
 	}
 
 	return &Fetcher{
-		services: services,
-		timeout:  timeout,
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
+		sources:    &sourceList{services: services, version: 1},
+		timeout:    timeout,
+		httpClient: newHTTPClient(FamilyAny, "", timeout),
 	}
 }
 
-// GetCurrentIP fetches the current public IP from external services
-func (f *Fetcher) GetCurrentIP(ctx context.Context) (string, error) {
-	if len(f.services) == 0 {
-		return "", fmt.Errorf("no IP services configured")
+// sourceList holds the service URLs and additional sources, which may be
+// replaced at runtime
+type sourceList struct {
+	mu       sync.RWMutex
+	services []string     // Plain-text HTTP services, tried first
+	extra    []SourceSpec // Other sources, tried after the services
+	quorum   int          // Sources that must agree on an address; 0 or 1 takes the first
+	race     bool         // Ask all sources at once and take the first answer
+	health   *HealthTracker
+	reorder  bool // Try flaky sources last
+	version  int  // Incremented on every change
+
+	userAgent string                       // Sent by http and router sources
+	headers   map[string]map[string]string // Extra request headers, by service URL
+}
+
+// specs returns the effective list of sources and its version
+func (l *sourceList) specs() ([]SourceSpec, int) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	specs := make([]SourceSpec, 0, len(l.services)+len(l.extra))
+	for _, service := range l.services {
+		specs = append(specs, l.serviceSpec(service))
 	}
+	return append(specs, l.extra...), l.version
+}
 
-	// Try multiple services for reliability
-	var lastError error
-	for _, service := range f.services {
-		ip, err := f.fetchFromService(ctx, service)
+// serviceSpec returns the source asking a service; callers hold mu
+func (l *sourceList) serviceSpec(service string) SourceSpec {
+	return SourceSpec{Type: "http", URL: service, Headers: l.headers[service]}
+}
+
+// SetServices replaces the services used by this fetcher and all fetchers derived from it
+func (f *Fetcher) SetServices(services []string) {
+	f.sources.mu.Lock()
+	defer f.sources.mu.Unlock()
+	f.sources.services = append([]string(nil), services...)
+	f.sources.version++
+}
+
+// SetRequestHeaders sets the User-Agent of the http and router sources and
+// the extra headers sent to each service, e.g. the API key of a paid
+// service, for this fetcher and all fetchers derived from it. An empty
+// User-Agent keeps Go's default.
+func (f *Fetcher) SetRequestHeaders(userAgent string, byService map[string]map[string]string) {
+	f.sources.mu.Lock()
+	defer f.sources.mu.Unlock()
+	f.sources.userAgent = userAgent
+	f.sources.headers = byService
+	f.sources.version++
+}
+
+// SetQuorum sets how many sources must report the same address before it
+// is accepted, for this fetcher and all fetchers derived from it. With a
+// quorum above one, all sources are asked at once instead of in order, so
+// that a single misbehaving service cannot report a bogus address.
+func (f *Fetcher) SetQuorum(quorum int) {
+	f.sources.mu.Lock()
+	defer f.sources.mu.Unlock()
+	f.sources.quorum = quorum
+}
+
+// SetRace makes this fetcher and all fetchers derived from it ask all
+// sources at once and take the first answer, canceling the others, instead
+// of trying them in order
+func (f *Fetcher) SetRace(race bool) {
+	f.sources.mu.Lock()
+	defer f.sources.mu.Unlock()
+	f.sources.race = race
+}
+
+// SetHealthTracker records the outcome of every query of this fetcher and
+// all fetchers derived from it in the tracker. With reorder, sources that
+// failed repeatedly lately are tried last.
+func (f *Fetcher) SetHealthTracker(tracker *HealthTracker, reorder bool) {
+	f.sources.mu.Lock()
+	defer f.sources.mu.Unlock()
+	f.sources.health = tracker
+	f.sources.reorder = reorder
+}
+
+// tracker returns the health tracker and whether it reorders sources
+func (l *sourceList) tracker() (*HealthTracker, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.health, l.reorder
+}
+
+// strategy returns the number of sources that must agree and whether
+// sources race
+func (l *sourceList) strategy() (int, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.quorum, l.race
+}
+
+// Services returns the services currently in use
+func (f *Fetcher) Services() []string {
+	f.sources.mu.RLock()
+	defer f.sources.mu.RUnlock()
+	return append([]string(nil), f.sources.services...)
+}
+
+// SetSources sets the sources tried after the services, for this fetcher
+// and all fetchers derived from it
+func (f *Fetcher) SetSources(specs []SourceSpec) error {
+	env := f.sourceEnv()
+	for i, spec := range specs {
+		if _, err := NewSource(spec, env); err != nil {
+			return fmt.Errorf("source %d (%s): %w", i+1, spec.Type, err)
+		}
+	}
+
+	f.sources.mu.Lock()
+	defer f.sources.mu.Unlock()
+	f.sources.extra = append([]SourceSpec(nil), specs...)
+	f.sources.version++
+	return nil
+}
+
+// ForFamily returns a fetcher whose connections are pinned to the given family
+func (f *Fetcher) ForFamily(family Family) *Fetcher {
+	if family == f.family {
+		return f
+	}
+	return f.derive(family, f.iface)
+}
+
+// ForInterface returns a fetcher whose connections originate from the given
+// network interface, so that the IP seen through that WAN link is reported
+func (f *Fetcher) ForInterface(name string) *Fetcher {
+	if name == f.iface {
+		return f
+	}
+	return f.derive(f.family, name)
+}
+
+// derive creates a fetcher sharing this fetcher's sources with its own dialer
+func (f *Fetcher) derive(family Family, iface string) *Fetcher {
+	return &Fetcher{
+		sources:    f.sources,
+		timeout:    f.timeout,
+		family:     family,
+		iface:      iface,
+		httpClient: newHTTPClient(family, iface, f.timeout),
+	}
+}
+
+// sourceEnv returns what sources of this fetcher dial with
+func (f *Fetcher) sourceEnv() SourceEnv {
+	f.sources.mu.RLock()
+	userAgent := f.sources.userAgent
+	f.sources.mu.RUnlock()
+
+	return SourceEnv{
+		Family:     f.family,
+		Interface:  f.iface,
+		Timeout:    f.timeout,
+		HTTPClient: f.httpClient,
+		UserAgent:  userAgent,
+		Dial:       dialContext(f.family, f.iface),
+	}
+}
+
+// sourcesInUse returns the sources of this fetcher, rebuilding them when
+// the shared list changed
+func (f *Fetcher) sourcesInUse() ([]Source, error) {
+	specs, version := f.sources.specs()
+
+	f.builtMu.Lock()
+	defer f.builtMu.Unlock()
+	if version == f.builtVersion {
+		return f.built, nil
+	}
+
+	env := f.sourceEnv()
+	built := make([]Source, 0, len(specs))
+	for _, spec := range specs {
+		source, err := NewSource(spec, env)
 		if err != nil {
-			lastError = err
-			continue
+			return nil, fmt.Errorf("invalid %s source: %w", spec.Type, err)
 		}
-		return ip, nil
+		built = append(built, source)
 	}
 
-	return "", fmt.Errorf("failed to get IP from all services, last error: %w", lastError)
+	f.built = built
+	f.builtVersion = version
+	return built, nil
 }
 
-// fetchFromService fetches IP from a specific service
-func (f *Fetcher) fetchFromService(ctx context.Context, serviceURL string) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", serviceURL, nil)
+// interfaceAddr returns the first usable address of the named interface,
+// preferring IPv4 when the family is not pinned
+func interfaceAddr(name string, family Family) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request for %s: %w", serviceURL, err)
+		return nil, fmt.Errorf("failed to find interface %s: %w", name, err)
 	}
 
-	resp, err := f.httpClient.Do(req)
+	addrs, err := iface.Addrs()
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch from %s: %w", serviceURL, err)
+		return nil, fmt.Errorf("failed to list addresses of %s: %w", name, err)
+	}
+
+	var fallback net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		isIPv4 := ipNet.IP.To4() != nil
+		switch {
+		case family == FamilyIPv4 && isIPv4, family == FamilyIPv6 && !isIPv4, family == FamilyAny && isIPv4:
+			return ipNet.IP, nil
+		case family == FamilyAny && fallback == nil:
+			fallback = ipNet.IP
+		}
+	}
+
+	if fallback != nil {
+		return fallback, nil
 	}
-	defer resp.Body.Close()
+	return nil, fmt.Errorf("interface %s has no usable %s address", name, family.Label())
+}
+
+// Interface returns the network interface the fetcher is bound to, if any
+func (f *Fetcher) Interface() string {
+	return f.iface
+}
+
+// Family returns the address family the fetcher is pinned to
+func (f *Fetcher) Family() Family {
+	return f.family
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("service %s returned status %d", serviceURL, resp.StatusCode)
+// GetCurrentIP fetches the current public IP from external services
+func (f *Fetcher) GetCurrentIP(ctx context.Context) (string, error) {
+	addr, _, err := f.Fetch(ctx)
+	if err != nil {
+		return "", err
 	}
+	return addr.String(), nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// Fetch tries the sources in order and returns the first address found,
+// along with how it was detected. With a quorum set, the address must be
+// reported by that many sources instead; racing sources are asked at once.
+func (f *Fetcher) Fetch(ctx context.Context) (net.IP, Meta, error) {
+	sources, err := f.sourcesInUse()
 	if err != nil {
-		return "", fmt.Errorf("failed to read response from %s: %w", serviceURL, err)
+		return nil, Meta{}, err
+	}
+	if len(sources) == 0 {
+		return nil, Meta{}, fmt.Errorf("no IP services configured")
+	}
+	switch quorum, race := f.sources.strategy(); {
+	case quorum > 1:
+		return f.fetchConsensus(ctx, sources, quorum)
+	case race:
+		return f.fetchConsensus(ctx, sources, 1)
 	}
 
-	// Clean up response (remove newlines, whitespace, etc.)
-	ip := strings.TrimSpace(string(body))
+	health, reorder := f.sources.tracker()
+	if reorder {
+		sources = health.order(f.family, sources)
+	}
 
-	// Basic validation
-	if ip == "" {
-		return "", fmt.Errorf("empty response from %s", serviceURL)
+	// Try multiple sources for reliability
+	var lastError error
+	for _, source := range sources {
+		sourceCtx, local := withLocalAddr(ctx)
+		start := time.Now()
+		addr, meta, err := source.Fetch(sourceCtx)
+		if err == nil {
+			err = f.checkAddr(source, addr)
+		}
+		health.record(ctx, f.family, source, time.Since(start), err)
+		if err != nil {
+			lastError = err
+			continue
+		}
+		meta.LocalAddr = local.IP()
+		return addr, meta, nil
 	}
 
-	return ip, nil
+	return nil, Meta{}, fmt.Errorf("failed to get IP from all sources, last error: %w", lastError)
+}
+
+// checkAddr rejects answers that cannot be the public IP, such as the
+// private address of a captive portal, and makes sure a pinned fetcher
+// never reports an address of the other family
+func (f *Fetcher) checkAddr(source Source, addr net.IP) error {
+	if !isPublicAddr(addr) {
+		return fmt.Errorf("source %s returned %s, which is not a public address", source.Name(), addr)
+	}
+	if f.family != FamilyAny && (addr.To4() != nil) != (f.family == FamilyIPv4) {
+		return fmt.Errorf("source %s returned %s, which is not an %s address", source.Name(), addr, f.family.Label())
+	}
+	return nil
 }
@@ -0,0 +1,57 @@
+package ip
+
+import "time"
+
+// ChangeFrequency is how often the IP of a family changed lately, and how
+// often it usually does
+type ChangeFrequency struct {
+	Recent int     // Changes in the window ending now, the latest included
+	Usual  float64 // Changes a window of the same length held on average before it
+}
+
+// Frequency counts the changes of this view's family in the window ending
+// at now, and learns how many a window usually holds from the records of
+// the learn period before it. It reports false while the history before
+// the window covers less than minLearn, too little to tell what is usual.
+// The first record of the history is the IP found on the first run rather
+// than a change, so it only marks where the history starts.
+func (s *Storage) Frequency(now time.Time, window, learn, minLearn time.Duration) (ChangeFrequency, bool, error) {
+	records, err := s.GetHistory()
+	if err != nil {
+		return ChangeFrequency{}, false, err
+	}
+
+	windowStart := now.Add(-window)
+	learnStart := windowStart.Add(-learn)
+	var frequency ChangeFrequency
+	var first time.Time
+	learned := 0
+	for _, record := range records {
+		if record.Family != s.family {
+			continue
+		}
+		if first.IsZero() {
+			first = record.Timestamp
+			continue
+		}
+		switch {
+		case !record.Timestamp.Before(windowStart):
+			frequency.Recent++
+		case !record.Timestamp.Before(learnStart):
+			learned++
+		}
+	}
+
+	if first.IsZero() {
+		return frequency, false, nil
+	}
+	if first.After(learnStart) {
+		learnStart = first
+	}
+	span := windowStart.Sub(learnStart)
+	if span < minLearn {
+		return frequency, false, nil
+	}
+	frequency.Usual = float64(learned) * float64(window) / float64(span)
+	return frequency, true, nil
+}
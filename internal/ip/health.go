@@ -0,0 +1,211 @@
+package ip
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Thresholds of the service health scoring
+const (
+	healthWeight     = 0.2       // Weight of the latest query in Score and LatencyMS
+	flakyFailures    = 3         // Failures in a row that make a source flaky
+	flakyScore       = 0.5       // Score below which a source is flaky...
+	flakyMinAttempts = 5         // ...once it was asked this often
+	flakyCooldown    = time.Hour // Since the last failure, after which a flaky source gets another chance
+)
+
+// ServiceHealth is the track record of a service or source for one family
+type ServiceHealth struct {
+	Family              Family    `json:"family,omitempty"`
+	Source              string    `json:"source"`
+	Attempts            int       `json:"attempts"`
+	Successes           int       `json:"successes"`
+	Score               float64   `json:"score"`      // Success rate weighing recent queries more, from 0 to 1
+	LatencyMS           float64   `json:"latency_ms"` // Moving average of the successful queries
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastSuccess         time.Time `json:"last_success,omitzero"`
+	LastFailure         time.Time `json:"last_failure,omitzero"`
+}
+
+// Flaky reports whether the source failed repeatedly lately. It gets
+// another chance once an hour passed since its last failure; failing
+// again makes it flaky right away.
+func (h ServiceHealth) Flaky(now time.Time) bool {
+	if now.Sub(h.LastFailure) >= flakyCooldown {
+		return false
+	}
+	return h.ConsecutiveFailures >= flakyFailures || h.Attempts >= flakyMinAttempts && h.Score < flakyScore
+}
+
+// healthKey identifies a source's record: services often answer over one
+// family only
+type healthKey struct {
+	family Family
+	source string
+}
+
+// HealthTracker keeps the track record of every source a fetcher asks, so
+// that flaky ones can be tried last. The records are kept in a JSON file
+// across restarts.
+type HealthTracker struct {
+	path string
+
+	mu      sync.Mutex
+	records map[healthKey]*ServiceHealth
+	dirty   bool // Records changed since the last save
+	savedAt time.Time
+}
+
+// NewHealthTracker creates a tracker saving its records in path
+func NewHealthTracker(path string) *HealthTracker {
+	return &HealthTracker{path: path, records: make(map[healthKey]*ServiceHealth), savedAt: time.Now()}
+}
+
+// Load reads the records saved by the last run, if any
+func (t *HealthTracker) Load() error {
+	data, err := os.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read service health: %w", err)
+	}
+	var saved []ServiceHealth
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to parse service health %s: %w", t.path, err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, record := range saved {
+		t.records[healthKey{record.Family, record.Source}] = &record
+	}
+	return nil
+}
+
+// record adds the outcome of a query. Queries canceled because the check
+// ended, e.g. another source won a race, say nothing about the source and
+// are left out.
+func (t *HealthTracker) record(ctx context.Context, family Family, source Source, latency time.Duration, err error) {
+	if t == nil || err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := healthKey{family, source.Name()}
+	h, ok := t.records[key]
+	if !ok {
+		h = &ServiceHealth{Family: family, Source: source.Name(), Score: 1}
+		t.records[key] = h
+	}
+	h.Attempts++
+	now := time.Now()
+	if err != nil {
+		h.ConsecutiveFailures++
+		h.LastError = err.Error()
+		h.LastFailure = now
+		h.Score *= 1 - healthWeight
+	} else {
+		ms := float64(latency) / float64(time.Millisecond)
+		if h.Successes == 0 {
+			h.LatencyMS = ms
+		}
+		h.LatencyMS += healthWeight * (ms - h.LatencyMS)
+		h.Successes++
+		h.ConsecutiveFailures = 0
+		h.LastSuccess = now
+		h.Score += healthWeight * (1 - h.Score)
+	}
+	t.dirty = true
+}
+
+// order returns the sources with the flaky ones of the family moved to the
+// end, the most reliable of them first; the others keep their order
+func (t *HealthTracker) order(family Family, sources []Source) []Source {
+	if t == nil {
+		return sources
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	flaky := func(source Source) (bool, float64) {
+		h, ok := t.records[healthKey{family, source.Name()}]
+		if !ok || !h.Flaky(now) {
+			return false, 0
+		}
+		return true, h.Score
+	}
+	ordered := slices.Clone(sources)
+	slices.SortStableFunc(ordered, func(a, b Source) int {
+		aFlaky, aScore := flaky(a)
+		bFlaky, bScore := flaky(b)
+		switch {
+		case aFlaky != bFlaky && aFlaky:
+			return 1
+		case aFlaky != bFlaky:
+			return -1
+		case aFlaky:
+			return cmp.Compare(bScore, aScore)
+		}
+		return 0
+	})
+	return ordered
+}
+
+// Records returns the records by family and source
+func (t *HealthTracker) Records() []ServiceHealth {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	records := make([]ServiceHealth, 0, len(t.records))
+	for _, h := range t.records {
+		records = append(records, *h)
+	}
+	slices.SortFunc(records, func(a, b ServiceHealth) int {
+		return cmp.Or(cmp.Compare(a.Family, b.Family), cmp.Compare(a.Source, b.Source))
+	})
+	return records
+}
+
+// Save writes the records if they changed since the last save
+func (t *HealthTracker) Save() error {
+	records := t.Records()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal service health: %w", err)
+	}
+	if err := os.WriteFile(t.path, data, DataFilePerm); err != nil {
+		return fmt.Errorf("failed to save service health: %w", err)
+	}
+	t.dirty = false
+	t.savedAt = time.Now()
+	return nil
+}
+
+// SaveEvery saves the records if they changed and the interval passed
+// since the last save, so that they are not written on every check
+func (t *HealthTracker) SaveEvery(interval time.Duration) error {
+	t.mu.Lock()
+	due := time.Since(t.savedAt) >= interval
+	t.mu.Unlock()
+	if !due {
+		return nil
+	}
+	return t.Save()
+}
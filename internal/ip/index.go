@@ -0,0 +1,143 @@
+package ip
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ServicesIndex is the list of IP services published in a remote index
+type ServicesIndex struct {
+	Services []string  `json:"services"`
+	Updated  time.Time `json:"updated"`
+}
+
+// signedIndex is the envelope served at the index URL. The signature is an
+// Ed25519 signature over the base64-decoded payload, which holds a ServicesIndex.
+type signedIndex struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// IndexLoader fetches and verifies the remote services index, keeping a
+// local copy of the last verified index as a fallback
+type IndexLoader struct {
+	url        string
+	publicKey  ed25519.PublicKey
+	cacheFile  string
+	httpClient *http.Client
+}
+
+// NewIndexLoader creates a loader for the index at url, signed by the
+// base64-encoded Ed25519 publicKey
+func NewIndexLoader(url, publicKey, cacheFile string, timeoutSeconds int) (*IndexLoader, error) {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid services index public key: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid services index public key: expected %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &IndexLoader{
+		url:        url,
+		publicKey:  ed25519.PublicKey(key),
+		cacheFile:  cacheFile,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Fetch downloads and verifies the remote index, updating the local copy on
+// success. An index updated before the local copy is rejected.
+func (l *IndexLoader) Fetch(ctx context.Context) (*ServicesIndex, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", l.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", l.url, err)
+	}
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch services index: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("services index returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read services index: %w", err)
+	}
+
+	index, err := l.verify(data)
+	if err != nil {
+		return nil, err
+	}
+	// A validly signed but older index, e.g. replayed by whoever controls
+	// the network, must not bring back services since removed
+	if cached, err := l.LoadCached(); err == nil && index.Updated.Before(cached.Updated) {
+		return nil, fmt.Errorf("services index of %s is older than the cached one of %s", index.Updated.Format(time.RFC3339), cached.Updated.Format(time.RFC3339))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.cacheFile), 0755); err != nil {
+		return index, fmt.Errorf("failed to create services index cache directory: %w", err)
+	}
+	if err := os.WriteFile(l.cacheFile, data, DataFilePerm); err != nil {
+		return index, fmt.Errorf("failed to cache services index: %w", err)
+	}
+
+	return index, nil
+}
+
+// LoadCached returns the last verified index saved locally
+func (l *IndexLoader) LoadCached() (*ServicesIndex, error) {
+	data, err := os.ReadFile(l.cacheFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached services index: %w", err)
+	}
+	return l.verify(data)
+}
+
+// verify checks the envelope signature and decodes the index
+func (l *IndexLoader) verify(data []byte) (*ServicesIndex, error) {
+	var envelope signedIndex
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse services index: %w", err)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode services index payload: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(envelope.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode services index signature: %w", err)
+	}
+
+	if !ed25519.Verify(l.publicKey, payload, signature) {
+		return nil, fmt.Errorf("services index signature verification failed")
+	}
+
+	var index ServicesIndex
+	if err := json.Unmarshal(payload, &index); err != nil {
+		return nil, fmt.Errorf("failed to parse services index payload: %w", err)
+	}
+	if len(index.Services) == 0 {
+		return nil, fmt.Errorf("services index contains no services")
+	}
+
+	return &index, nil
+}
@@ -0,0 +1,77 @@
+package ip
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// signIndex returns the envelope of index signed with key
+func signIndex(t *testing.T, key ed25519.PrivateKey, index ServicesIndex) []byte {
+	t.Helper()
+	payload, err := json.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := json.Marshal(signedIndex{
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return envelope
+}
+
+func TestIndexLoaderRejectsOlderIndex(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var served atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(served.Load().([]byte))
+	}))
+	defer server.Close()
+
+	loader, err := NewIndexLoader(server.URL, base64.StdEncoding.EncodeToString(public), filepath.Join(t.TempDir(), "services_index.json"), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	june := time.Date(2025, 6, 8, 0, 0, 0, 0, time.UTC)
+	old := signIndex(t, private, ServicesIndex{Services: []string{"https://retired.example.com"}, Updated: june})
+	current := signIndex(t, private, ServicesIndex{Services: []string{"https://api.ipify.org"}, Updated: june.AddDate(0, 1, 0)})
+
+	served.Store(old)
+	if _, err := loader.Fetch(context.Background()); err != nil {
+		t.Fatalf("first Fetch: %v", err)
+	}
+	served.Store(current)
+	if index, err := loader.Fetch(context.Background()); err != nil || index.Services[0] != "https://api.ipify.org" {
+		t.Fatalf("Fetch of a newer index = %v, %v", index, err)
+	}
+
+	// The older index is validly signed, but replaying it must fail and
+	// keep the newer one cached
+	served.Store(old)
+	if index, err := loader.Fetch(context.Background()); err == nil {
+		t.Fatalf("Fetch of an older index = %v, want an error", index.Services)
+	}
+	cached, err := loader.LoadCached()
+	if err != nil || cached.Services[0] != "https://api.ipify.org" {
+		t.Errorf("cached index = %v, %v, want the newer one", cached, err)
+	}
+
+	// The same index again is not a replay of an older one
+	served.Store(current)
+	if _, err := loader.Fetch(context.Background()); err != nil {
+		t.Errorf("Fetch of the cached index: %v", err)
+	}
+}
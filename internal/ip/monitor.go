@@ -2,26 +2,106 @@ package ip
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"public-ip-monitor/pkg/clock"
 )
 
 // ChangeHandler is called when IP changes are detected
 type ChangeHandler func(oldIP, newIP string) error
 
+// Stage identifies a step of handling an IP change. Each stage runs even
+// when an earlier one failed, and reports its own status.
+type Stage string
+
+const (
+	StagePersist Stage = "persist" // Saving the last IP and the history record
+	StageActions Stage = "actions" // Handlers registered with SubscribeAction, e.g. hooks
+	StageNotify  Stage = "notify"  // Handlers registered with Subscribe, e.g. notifications
+)
+
+// StageResult is the outcome of one stage of handling a change
+type StageResult struct {
+	Stage Stage
+	Err   error // Errors of the stage's handlers joined, nil on success
+}
+
 // Monitor handles IP monitoring logic
 type Monitor struct {
 	fetcher *Fetcher
 	storage *Storage
+	clock   clock.Clock // Paces StartMonitoring and times checks
+
+	mu       sync.Mutex
+	handlers []subscription
+	nextID   int
+
+	interval chan time.Duration // New check intervals for StartMonitoring
+	paused   atomic.Bool        // StartMonitoring skips checks while set
+	resumed  chan struct{}      // Wakes StartMonitoring up to check right after a resume
+
+	// unsaved is a changed IP that could not be persisted. It is treated as
+	// the last IP, so the change is not reported again, and saving it is
+	// retried on the next check.
+	unsaved string
+}
+
+// subscription is a change handler registered with Subscribe or SubscribeAction
+type subscription struct {
+	id      int
+	stage   Stage
 	handler ChangeHandler
 }
 
 // NewMonitor creates a new IP monitor
-func NewMonitor(fetcher *Fetcher, storage *Storage, handler ChangeHandler) *Monitor {
+func NewMonitor(fetcher *Fetcher, storage *Storage) *Monitor {
 	return &Monitor{
-		fetcher: fetcher,
-		storage: storage,
-		handler: handler,
+		fetcher:  fetcher,
+		storage:  storage,
+		clock:    clock.System,
+		interval: make(chan time.Duration, 1),
+		resumed:  make(chan struct{}, 1),
+	}
+}
+
+// SetClock replaces the system clock, e.g. with a fake one to fast-forward
+// the checks. It must be called before StartMonitoring.
+func (m *Monitor) SetClock(c clock.Clock) {
+	m.clock = clock.OrSystem(c)
+}
+
+// Subscribe registers a handler called on every IP change, in registration
+// order, after the change was persisted and the actions ran. Handlers are
+// isolated from each other: an error or panic in one is reported in the
+// notify stage of the CheckResult and does not stop the others. The
+// returned function removes the handler.
+func (m *Monitor) Subscribe(handler ChangeHandler) (unsubscribe func()) {
+	return m.subscribe(StageNotify, handler)
+}
+
+// SubscribeAction registers a handler like Subscribe, for the actions stage
+// that runs before notifications
+func (m *Monitor) SubscribeAction(handler ChangeHandler) (unsubscribe func()) {
+	return m.subscribe(StageActions, handler)
+}
+
+func (m *Monitor) subscribe(stage Stage, handler ChangeHandler) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	id := m.nextID
+	m.handlers = append(m.handlers, subscription{id: id, stage: stage, handler: handler})
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.handlers = slices.DeleteFunc(m.handlers, func(s subscription) bool { return s.id == id })
 	}
 }
 
@@ -30,43 +110,101 @@ type CheckResult struct {
 	CurrentIP string
 	LastIP    string
 	Changed   bool
-	Error     error
+	Error     error         // The check itself failed; no stage ran
+	Stages    []StageResult // Status of each stage of handling a change, or of retrying to persist one
+	Source    Meta          // How the current IP was detected
+	Latency   time.Duration // Time taken to detect the current IP, or to fail
+}
+
+// StageErrors returns the failed stages
+func (r CheckResult) StageErrors() []StageResult {
+	var failed []StageResult
+	for _, stage := range r.Stages {
+		if stage.Err != nil {
+			failed = append(failed, stage)
+		}
+	}
+	return failed
 }
 
 // CheckOnce performs a single IP check
 func (m *Monitor) CheckOnce(ctx context.Context) CheckResult {
 	// Get current IP
-	currentIP, err := m.fetcher.GetCurrentIP(ctx)
+	start := m.clock.Now()
+	addr, meta, err := m.fetcher.Fetch(ctx)
+	latency := clock.Since(m.clock, start)
 	if err != nil {
-		return CheckResult{Error: fmt.Errorf("failed to get current IP: %w", err)}
+		return CheckResult{Error: fmt.Errorf("failed to get current IP: %w", err), Latency: latency}
 	}
+	currentIP := addr.String()
 
 	// Get last known IP
 	lastIP, err := m.storage.ReadLastIP()
 	if err != nil {
-		return CheckResult{Error: fmt.Errorf("failed to read last IP: %w", err)}
+		return CheckResult{Error: fmt.Errorf("failed to read last IP: %w", err), Latency: latency}
 	}
 
-	// Check if IP has changed
-	changed := currentIP != lastIP
+	result := CheckResult{Source: meta, Latency: latency}
 
-	result := CheckResult{
-		CurrentIP: currentIP,
-		LastIP:    lastIP,
-		Changed:   changed,
+	// Retry saving a change that was already reported
+	if m.unsaved != "" {
+		lastIP = m.unsaved
+		err := m.persistChange(m.unsaved)
+		result.Stages = append(result.Stages, StageResult{Stage: StagePersist, Err: err})
+		if err == nil {
+			m.unsaved = ""
+		}
 	}
 
-	if changed {
-		// Handle IP change
-		if err := m.handleIPChange(lastIP, currentIP); err != nil {
-			result.Error = fmt.Errorf("failed to handle IP change: %w", err)
-			return result
-		}
+	// Check if IP has changed
+	result.CurrentIP = currentIP
+	result.LastIP = lastIP
+	result.Changed = currentIP != lastIP
+
+	if result.Changed {
+		result.Stages = m.handleIPChange(lastIP, currentIP)
 	}
 
 	return result
 }
 
+// handleIPChange runs every stage of handling a change, each regardless of
+// the outcome of the others
+func (m *Monitor) handleIPChange(oldIP, newIP string) []StageResult {
+	err := m.persistChange(newIP)
+	if err != nil {
+		m.unsaved = newIP
+	} else {
+		m.unsaved = ""
+	}
+
+	return []StageResult{
+		{Stage: StagePersist, Err: err},
+		{Stage: StageActions, Err: m.runHandlers(StageActions, oldIP, newIP)},
+		{Stage: StageNotify, Err: m.runHandlers(StageNotify, oldIP, newIP)},
+	}
+}
+
+// runHandlers calls every handler of a stage and returns their errors joined
+func (m *Monitor) runHandlers(stage Stage, oldIP, newIP string) error {
+	m.mu.Lock()
+	handlers := slices.Clone(m.handlers)
+	m.mu.Unlock()
+
+	var errs []error
+	n := 0
+	for _, s := range handlers {
+		if s.stage != stage {
+			continue
+		}
+		n++
+		if err := callHandler(s.handler, oldIP, newIP); err != nil {
+			errs = append(errs, fmt.Errorf("handler %d failed: %w", n, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // StartMonitoring starts continuous IP monitoring
 func (m *Monitor) StartMonitoring(ctx context.Context, interval time.Duration) <-chan CheckResult {
 	resultChan := make(chan CheckResult, 1)
@@ -74,25 +212,41 @@ func (m *Monitor) StartMonitoring(ctx context.Context, interval time.Duration) <
 	go func() {
 		defer close(resultChan)
 
+		check := func() bool {
+			if m.paused.Load() {
+				return true
+			}
+			select {
+			case resultChan <- m.CheckOnce(ctx):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
 		// Check immediately on startup
-		select {
-		case resultChan <- m.CheckOnce(ctx):
-		case <-ctx.Done():
+		if !check() {
 			return
 		}
 
 		// Set up periodic checking
-		ticker := time.NewTicker(interval)
+		ticker := m.clock.NewTicker(interval)
 		defer ticker.Stop()
 
 		for {
 			select {
-			case <-ticker.C:
-				select {
-				case resultChan <- m.CheckOnce(ctx):
-				case <-ctx.Done():
+			case <-ticker.C():
+				if !check() {
+					return
+				}
+			case <-m.resumed:
+				// The next regular check is an interval after this one
+				ticker.Reset(interval)
+				if !check() {
 					return
 				}
+			case interval = <-m.interval:
+				ticker.Reset(interval)
 			case <-ctx.Done():
 				return
 			}
@@ -102,8 +256,53 @@ func (m *Monitor) StartMonitoring(ctx context.Context, interval time.Duration) <
 	return resultChan
 }
 
-// handleIPChange processes an IP change
-func (m *Monitor) handleIPChange(oldIP, newIP string) error {
+// SetInterval changes the interval of a running StartMonitoring; the next
+// check follows one new interval after the change
+func (m *Monitor) SetInterval(interval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Only the latest interval matters
+	select {
+	case <-m.interval:
+	default:
+	}
+	m.interval <- interval
+}
+
+// Pause stops the checks of StartMonitoring until Resume; a check running
+// already completes
+func (m *Monitor) Pause() {
+	m.paused.Store(true)
+}
+
+// Resume restarts the checks of StartMonitoring with one right away
+func (m *Monitor) Resume() {
+	if !m.paused.Swap(false) {
+		return
+	}
+	select {
+	case m.resumed <- struct{}{}:
+	default:
+	}
+}
+
+// Paused reports whether the checks are paused
+func (m *Monitor) Paused() bool {
+	return m.paused.Load()
+}
+
+// callHandler calls a handler, turning a panic into an error
+func callHandler(handler ChangeHandler, oldIP, newIP string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(oldIP, newIP)
+}
+
+// persistChange saves the new IP and appends it to the history
+func (m *Monitor) persistChange(newIP string) error {
 	// Save new IP
 	if err := m.storage.SaveLastIP(newIP); err != nil {
 		return fmt.Errorf("failed to save new IP: %w", err)
@@ -114,16 +313,19 @@ func (m *Monitor) handleIPChange(oldIP, newIP string) error {
 		return fmt.Errorf("failed to save IP record: %w", err)
 	}
 
-	// Call change handler if provided
-	if m.handler != nil {
-		if err := m.handler(oldIP, newIP); err != nil {
-			return fmt.Errorf("change handler failed: %w", err)
-		}
-	}
-
 	return nil
 }
 
+// GetLastIP returns the last known IP
+func (m *Monitor) GetLastIP() (string, error) {
+	return m.storage.ReadLastIP()
+}
+
+// GetLastChangeTime returns when the last known IP was first seen, or the zero time if unknown
+func (m *Monitor) GetLastChangeTime() time.Time {
+	return m.storage.LastIPTime()
+}
+
 // GetHistory returns IP change history
 func (m *Monitor) GetHistory() ([]Record, error) {
 	return m.storage.GetHistory()
@@ -145,8 +347,15 @@ func (m *Monitor) PrintHistory() error {
 
 	fmt.Println("\n=== IP Change History ===")
 	for i, record := range records {
-		fmt.Printf("%d. IP: %s - Time: %s\n",
-			i+1, record.IP, record.Timestamp.Format("2006-01-02 15:04:05"))
+		fmt.Printf("%d. %s: %s - Time: %s",
+			i+1, record.Family.Label(), record.IP, record.Timestamp.Format("2006-01-02 15:04:05"))
+		if location := record.Location(); location != "" {
+			fmt.Printf(" - %s", location)
+		}
+		if record.Anomaly != "" {
+			fmt.Printf(" - ANOMALY: %s", record.Anomaly)
+		}
+		fmt.Println()
 	}
 	fmt.Println("========================")
 
@@ -0,0 +1,79 @@
+package ip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"public-ip-monitor/pkg/clock"
+)
+
+// newTestMonitor returns a monitor on a fake clock, checking a service that
+// answers with the address in ip
+func newTestMonitor(t *testing.T, ip *atomic.Value) (*Monitor, *clock.Fake) {
+	t.Helper()
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ip.Load().(string)))
+	}))
+	t.Cleanup(service.Close)
+
+	fake := clock.NewFake(time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC))
+	monitor := NewMonitor(NewFetcher([]string{service.URL}, 5), NewStorage(t.TempDir(), "ip_records.json", "last_ip.txt"))
+	monitor.SetClock(fake)
+	return monitor, fake
+}
+
+func TestMonitorChecksOnFakeClock(t *testing.T) {
+	var ip atomic.Value
+	ip.Store("203.0.113.1")
+	monitor, fake := newTestMonitor(t, &ip)
+
+	var changes []string
+	monitor.Subscribe(func(oldIP, newIP string) error {
+		changes = append(changes, oldIP+" -> "+newIP)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := monitor.StartMonitoring(ctx, 5*time.Minute)
+
+	// The first check runs right away and records the first IP
+	if result := <-results; result.Error != nil || !result.Changed || result.CurrentIP != "203.0.113.1" {
+		t.Fatalf("first check = %+v, want a change to 203.0.113.1", result)
+	}
+
+	// Nothing is checked before the interval has passed
+	fake.BlockUntil(1)
+	fake.Advance(4 * time.Minute)
+	select {
+	case result := <-results:
+		t.Fatalf("checked after 4 of 5 minutes: %+v", result)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	ip.Store("198.51.100.7")
+	fake.Advance(time.Minute)
+	if result := <-results; !result.Changed || result.LastIP != "203.0.113.1" || result.CurrentIP != "198.51.100.7" {
+		t.Fatalf("check after 5 minutes = %+v, want a change to 198.51.100.7", result)
+	}
+
+	fake.Advance(5 * time.Minute)
+	if result := <-results; result.Changed {
+		t.Fatalf("check after 10 minutes = %+v, want no change", result)
+	}
+
+	// The first IP has no old one
+	want := []string{" -> 203.0.113.1", "203.0.113.1 -> 198.51.100.7"}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d = %q, want %q", i+1, changes[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package ip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Ranges are the networks the public IP is expected in, such as the ISP's
+// known blocks
+type Ranges []*net.IPNet
+
+// ParseRanges parses CIDR ranges, e.g. "203.0.113.0/24" or "2001:db8::/32"
+func ParseRanges(cidrs []string) (Ranges, error) {
+	ranges := make(Ranges, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", cidr)
+		}
+		ranges = append(ranges, network)
+	}
+	return ranges, nil
+}
+
+// Expects reports whether the address is in one of the ranges of its
+// family. Addresses of a family without any range, e.g. IPv6 when only the
+// ISP's IPv4 blocks are listed, are not checked and always expected.
+func (r Ranges) Expects(address string) bool {
+	addr := ParseAddr(address)
+	if addr == nil {
+		return true
+	}
+	checked := false
+	for _, network := range r {
+		if (network.IP.To4() != nil) != (addr.To4() != nil) {
+			continue
+		}
+		if network.Contains(addr) {
+			return true
+		}
+		checked = true
+	}
+	return !checked
+}
+
+// Of returns the ranges of the address's family, e.g. for messages
+func (r Ranges) Of(address string) []string {
+	addr := ParseAddr(address)
+	var cidrs []string
+	for _, network := range r {
+		if addr != nil && (network.IP.To4() != nil) == (addr.To4() != nil) {
+			cidrs = append(cidrs, network.String())
+		}
+	}
+	return cidrs
+}
@@ -0,0 +1,86 @@
+package ip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+)
+
+// StartupReport describes how the stored, published and current IPs relate at startup
+type StartupReport struct {
+	LastIP     string    // Last IP persisted by the previous run
+	LastChange time.Time // When LastIP was saved, zero if unknown
+	DNSRecord  string    // Hostname that is expected to point at the public IP
+	DNSIPs     []string  // Addresses DNSRecord resolved to
+	DNSError   error     // Set when DNSRecord could not be resolved
+	CurrentIP  string    // Freshly fetched IP
+}
+
+// MissedChange reports whether the IP changed while the monitor was not running
+func (r StartupReport) MissedChange() bool {
+	return r.LastIP != "" && r.CurrentIP != r.LastIP
+}
+
+// DNSStale reports whether the DNS record does not point at the current IP
+func (r StartupReport) DNSStale() bool {
+	return r.DNSRecord != "" && r.DNSError == nil && !slices.Contains(r.DNSIPs, r.CurrentIP)
+}
+
+// Consistent reports whether nothing needs to be caught up on
+func (r StartupReport) Consistent() bool {
+	return !r.MissedChange() && !r.DNSStale()
+}
+
+// Reconcile compares the stored last IP, the DNS record (if any) and the
+// current IP. A change that happened while the monitor was down is persisted
+// without invoking the change handler, so the caller can report it as a
+// catch-up and the first regular check does not report it again.
+// On the very first run there is nothing to reconcile and an empty report is returned.
+func (m *Monitor) Reconcile(ctx context.Context, dnsRecord string) (StartupReport, error) {
+	lastIP, err := m.storage.ReadLastIP()
+	if err != nil {
+		return StartupReport{}, fmt.Errorf("failed to read last IP: %w", err)
+	}
+	if lastIP == "" {
+		return StartupReport{}, nil
+	}
+
+	currentIP, err := m.fetcher.GetCurrentIP(ctx)
+	if err != nil {
+		return StartupReport{}, fmt.Errorf("failed to get current IP: %w", err)
+	}
+
+	report := StartupReport{
+		LastIP:     lastIP,
+		LastChange: m.storage.LastIPTime(),
+		DNSRecord:  dnsRecord,
+		CurrentIP:  currentIP,
+	}
+
+	if dnsRecord != "" {
+		report.DNSIPs, report.DNSError = ResolveHost(ctx, Resolver(), dnsRecord, m.fetcher.family)
+	}
+
+	if report.MissedChange() {
+		if err := m.persistChange(currentIP); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// ResolveHost returns the addresses of the given family that host resolves to
+func ResolveHost(ctx context.Context, resolver *net.Resolver, host string, family Family) ([]string, error) {
+	addrs, err := resolver.LookupIP(ctx, family.network("ip"), host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	ips := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.String())
+	}
+	return ips, nil
+}
@@ -0,0 +1,155 @@
+package ip
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// RecordVersion is the version of the record format written by this
+// binary. Readers accept any version: fields they do not know are kept as
+// they are, and known fields missing from older records stay empty, so
+// upgrading or downgrading never makes the records file unreadable.
+const RecordVersion = 1
+
+// recordFields are the JSON names of the fields Record knows
+var recordFields = jsonFieldNames(reflect.TypeFor[Record]())
+
+// jsonFieldNames returns the JSON names of the exported fields of a struct
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// plainRecord is Record without its JSON methods
+type plainRecord Record
+
+// UnmarshalJSON decodes a record of any version, keeping the fields this
+// version does not know
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var record plainRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for name, value := range fields {
+		if recordFields[name] {
+			continue
+		}
+		if record.extra == nil {
+			record.extra = make(map[string]json.RawMessage)
+		}
+		record.extra[name] = value
+	}
+	*r = Record(record)
+	return nil
+}
+
+// MarshalJSON encodes the record with the unknown fields it was read with
+func (r Record) MarshalJSON() ([]byte, error) {
+	if r.raw != nil {
+		return r.raw, nil
+	}
+	data, err := json.Marshal(plainRecord(r))
+	if err != nil || len(r.extra) == 0 {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range r.extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// Details returns the enrichment details of the record, including its
+// location, ASN and anomaly, by name
+func (r Record) Details() map[string]string {
+	if r.Country == "" && r.Region == "" && r.City == "" && r.ASN == "" && r.Anomaly == "" {
+		return r.Enrichment
+	}
+	details := make(map[string]string, len(r.Enrichment)+5)
+	for name, value := range r.Enrichment {
+		details[name] = value
+	}
+	for name, value := range map[string]string{"country": r.Country, "region": r.Region, "city": r.City, "asn": r.ASN, "anomaly": r.Anomaly} {
+		if value != "" {
+			details[name] = value
+		}
+	}
+	return details
+}
+
+// Location describes where the IP is geolocated, e.g. "Berlin, DE
+// (AS3209)", or "" when unknown
+func (r Record) Location() string {
+	var parts []string
+	for _, part := range []string{r.City, r.Region, r.Country} {
+		if part != "" && !slices.Contains(parts, part) {
+			parts = append(parts, part)
+		}
+	}
+	location := strings.Join(parts, ", ")
+	if r.ASN != "" {
+		location = strings.TrimSpace(location + " (" + r.ASN + ")")
+	}
+	return location
+}
+
+// undecodable reports whether the record could not be decoded, e.g. a
+// newer version changed the type of a field or moved the IP. Such records
+// are hidden from the history but kept in the file.
+func (r Record) undecodable() bool {
+	return r.raw != nil
+}
+
+// decodeRecords decodes a records file. Besides the list of records, it
+// accepts an object holding them under "records", in case a later version
+// adds file-level fields, and an empty file left by an interrupted write.
+func decodeRecords(data []byte) ([]Record, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		var wrapped struct {
+			Records []json.RawMessage `json:"records"`
+		}
+		if json.Unmarshal(data, &wrapped) != nil || wrapped.Records == nil {
+			return nil, err
+		}
+		items = wrapped.Records
+	}
+
+	records := make([]Record, 0, len(items))
+	for _, item := range items {
+		var record Record
+		if err := json.Unmarshal(item, &record); err != nil || record.IP == "" {
+			record = Record{raw: item}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,187 @@
+package ip
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HistoryQuery selects history records by words, e.g. "vodafone" or
+// "country:de ipv6". A record matches when every term does: a plain term
+// matches the IP, previous IP, WAN, family or any enrichment detail
+// (network name, ASN, country...), and "name:value" only the named field.
+// Terms match case-insensitively, anywhere in the value.
+type HistoryQuery struct {
+	terms []queryTerm
+}
+
+type queryTerm struct {
+	word  string
+	field string // Set for "name:value" terms
+	value string
+}
+
+// ParseHistoryQuery splits a query into its terms
+func ParseHistoryQuery(text string) HistoryQuery {
+	var query HistoryQuery
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		term := queryTerm{word: word}
+		// IPv6 addresses contain several colons, and match as plain terms
+		if field, value, ok := strings.Cut(word, ":"); ok && field != "" && value != "" && !strings.Contains(value, ":") {
+			term.field, term.value = field, value
+		}
+		query.terms = append(query.terms, term)
+	}
+	return query
+}
+
+// Empty reports whether the query has no terms, and matches everything
+func (q HistoryQuery) Empty() bool {
+	return len(q.terms) == 0
+}
+
+// Matches reports whether the record matches every term
+func (q HistoryQuery) Matches(record ExportRecord) bool {
+	fields := map[string]string{
+		"ip":          record.IP,
+		"previous_ip": record.PreviousIP,
+		"wan":         record.WAN,
+		"family":      record.Family.Label(),
+	}
+	for name, value := range record.Enrichment {
+		fields[strings.ToLower(name)] = value
+	}
+
+	for _, term := range q.terms {
+		if !term.matches(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether the term is found in its field, or in any field
+// if it names none the record has
+func (t queryTerm) matches(fields map[string]string) bool {
+	if value, ok := fields[t.field]; ok && t.field != "" {
+		return strings.Contains(strings.ToLower(value), t.value)
+	}
+	for _, value := range fields {
+		if strings.Contains(strings.ToLower(value), t.word) {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchHistory returns the records matching the query, in order
+func SearchHistory(records []ExportRecord, query HistoryQuery) []ExportRecord {
+	matched := []ExportRecord{}
+	for _, record := range records {
+		if query.Matches(record) {
+			matched = append(matched, record)
+		}
+	}
+	return matched
+}
+
+// HistoryFilter narrows the history to a time range and an address, and
+// pages through it. The zero value keeps every record.
+type HistoryFilter struct {
+	Since  time.Time // Records at or after; zero for no bound
+	Until  time.Time // Records before; zero for no bound
+	IP     string    // Records whose IP or previous IP is this address
+	Limit  int       // Records per page; 0 for all
+	Cursor string    // Where the previous page ended, from its NextCursor
+}
+
+// HistoryPage is a page of filtered records, in chronological order
+type HistoryPage struct {
+	Records    []ExportRecord
+	NextCursor string // Empty on the last page
+}
+
+// ErrInvalidCursor is returned for cursors that no page returned
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// compareRecords orders records by time, then WAN, family and IP, so that
+// every record has a fixed place a cursor can point at
+func compareRecords(a, b ExportRecord) int {
+	return cmp.Or(a.Timestamp.Compare(b.Timestamp), cmp.Compare(a.WAN, b.WAN), cmp.Compare(a.Family, b.Family), cmp.Compare(a.IP, b.IP))
+}
+
+// historyCursor is the position of the last record of a page
+type historyCursor struct {
+	Timestamp time.Time `json:"t"`
+	WAN       string    `json:"w,omitempty"`
+	Family    Family    `json:"f,omitempty"`
+	IP        string    `json:"ip"`
+}
+
+func (c historyCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeHistoryCursor(text string) (historyCursor, error) {
+	var c historyCursor
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil || json.Unmarshal(data, &c) != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// FilterHistory returns the page of records, ordered as BuildExportRecords
+// orders them, that match the filter. Cursors point at a record rather
+// than an offset, so pages stay in place as records are added or pruned.
+func FilterHistory(records []ExportRecord, filter HistoryFilter) (HistoryPage, error) {
+	var after *ExportRecord
+	if filter.Cursor != "" {
+		c, err := decodeHistoryCursor(filter.Cursor)
+		if err != nil {
+			return HistoryPage{}, err
+		}
+		after = &ExportRecord{Timestamp: c.Timestamp, WAN: c.WAN, Family: c.Family, IP: c.IP}
+	}
+
+	page := HistoryPage{Records: []ExportRecord{}}
+	for _, record := range records {
+		switch {
+		case after != nil && compareRecords(record, *after) <= 0:
+			continue
+		case !filter.Since.IsZero() && record.Timestamp.Before(filter.Since):
+			continue
+		case !filter.Until.IsZero() && !record.Timestamp.Before(filter.Until):
+			continue
+		case filter.IP != "" && record.IP != filter.IP && record.PreviousIP != filter.IP:
+			continue
+		}
+		if filter.Limit > 0 && len(page.Records) == filter.Limit {
+			last := page.Records[len(page.Records)-1]
+			page.NextCursor = historyCursor{Timestamp: last.Timestamp, WAN: last.WAN, Family: last.Family, IP: last.IP}.encode()
+			break
+		}
+		page.Records = append(page.Records, record)
+	}
+	return page, nil
+}
+
+// ParseHistoryTime parses a bound of a history filter: an RFC 3339 time, a
+// date (midnight in loc) or a duration before now, e.g. "72h"
+func ParseHistoryTime(text string, now time.Time, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", text, loc); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(text); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use e.g. 2025-06-01, 2025-06-01T08:00:00Z or 72h)", text)
+}
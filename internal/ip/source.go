@@ -0,0 +1,105 @@
+package ip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Meta describes how an address was detected
+type Meta struct {
+	Source    string // Source type, e.g. "http" or "dns"
+	Detail    string // What was asked, e.g. the service URL or DNS server
+	LocalAddr string // Local address the answer came in on; empty when unknown
+	NAT       string // stun: how the NAT in between translated the request, e.g. NATPortPreserving
+
+	// With a quorum: the sources that reported the address, and those that
+	// reported another one before the quorum was reached, as "name: address"
+	Agreed  []string
+	Dissent []string
+}
+
+// Source detects the public IP using a single method. New methods are
+// added by registering a SourceFactory, without changes to the Fetcher.
+type Source interface {
+	// Name identifies the source in logs and errors
+	Name() string
+	Fetch(ctx context.Context) (net.IP, Meta, error)
+}
+
+// SourceSpec configures a source; which fields apply depends on the type
+type SourceSpec struct {
+	Type     string // Registered source type, e.g. "http", "dns", "stun", "upnp" or "router"
+	URL      string // http and router: page to fetch; upnp: device description (discovered when empty)
+	Server   string // dns and stun: server to ask, host[:port]
+	Hostname string // dns: name resolving to the asking address
+	Record   string // dns: "A"/"AAAA" (by family when empty) or "TXT"
+	Pattern  string // router: regular expression matching the IP (first group if any)
+	Username string // router: HTTP basic auth
+	Password string
+	Format   string            // http: "text" (default) or "json"
+	Field    string            // http with json: path of the IP in the answer, e.g. "ip" (default) or "data.addresses.0"
+	Proxy    string            // http and router: proxy URL, or "direct"; the global proxy when empty
+	Headers  map[string]string // http and router: extra request headers, e.g. an API key
+}
+
+// SourceEnv is what a source gets from the fetcher it belongs to, so that it
+// honors the fetcher's family, interface and timeout
+type SourceEnv struct {
+	Family     Family
+	Interface  string
+	Timeout    time.Duration
+	HTTPClient *http.Client
+	UserAgent  string // Sent by http and router sources
+	Dial       func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// SourceFactory creates a source from its configuration
+type SourceFactory func(spec SourceSpec, env SourceEnv) (Source, error)
+
+// sourceFactories holds the registered source types
+var sourceFactories = make(map[string]SourceFactory)
+
+// sourceWrapper decorates every source created, e.g. for fault injection
+var sourceWrapper func(Source) Source
+
+// RegisterSource makes a source type available by name. It is meant to be
+// called from init functions.
+func RegisterSource(kind string, factory SourceFactory) {
+	if _, exists := sourceFactories[kind]; exists {
+		panic("ip: source type " + kind + " registered twice")
+	}
+	sourceFactories[kind] = factory
+}
+
+// SourceTypes returns the registered source types
+func SourceTypes() []string {
+	types := make([]string, 0, len(sourceFactories))
+	for kind := range sourceFactories {
+		types = append(types, kind)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewSource creates a source of a registered type
+func NewSource(spec SourceSpec, env SourceEnv) (Source, error) {
+	factory, ok := sourceFactories[spec.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown source type %q (expected one of %v)", spec.Type, SourceTypes())
+	}
+	source, err := factory(spec, env)
+	if err != nil || sourceWrapper == nil {
+		return source, err
+	}
+	return sourceWrapper(source), nil
+}
+
+// WrapSources decorates all sources created from now on. It is meant to be
+// called on startup, before the first fetch.
+func WrapSources(wrapper func(Source) Source) {
+	sourceWrapper = wrapper
+}
@@ -0,0 +1,255 @@
+package ip
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterSource("dns", newDNSSource)
+}
+
+// DNS record types understood by the dns source
+const (
+	dnsTypeA    = 1
+	dnsTypeTXT  = 16
+	dnsTypeAAAA = 28
+)
+
+// Defaults of the dns source: OpenDNS answers myip.opendns.com with the
+// address the query came from
+const (
+	defaultDNSServer   = "resolver1.opendns.com"
+	defaultDNSHostname = "myip.opendns.com"
+)
+
+// dnsSource asks a DNS server for a name that resolves to the asking
+// address, e.g. myip.opendns.com or, as TXT, o-o.myaddr.l.google.com on
+// ns1.google.com. The server must be asked directly, not through a
+// recursive resolver, so the query does not use the system resolver.
+type dnsSource struct {
+	server   string
+	hostname string
+	qtype    uint16
+	timeout  time.Duration
+	dial     func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+func newDNSSource(spec SourceSpec, env SourceEnv) (Source, error) {
+	source := &dnsSource{
+		server:   spec.Server,
+		hostname: strings.TrimSuffix(spec.Hostname, "."),
+		timeout:  env.Timeout,
+		dial:     env.Dial,
+	}
+	if source.server == "" {
+		source.server = defaultDNSServer
+	}
+	if _, _, err := net.SplitHostPort(source.server); err != nil {
+		source.server = net.JoinHostPort(source.server, "53")
+	}
+	if source.hostname == "" {
+		source.hostname = defaultDNSHostname
+	}
+
+	switch strings.ToUpper(spec.Record) {
+	case "":
+		source.qtype = dnsTypeA
+		if env.Family == FamilyIPv6 {
+			source.qtype = dnsTypeAAAA
+		}
+	case "A":
+		source.qtype = dnsTypeA
+	case "AAAA":
+		source.qtype = dnsTypeAAAA
+	case "TXT":
+		source.qtype = dnsTypeTXT
+	default:
+		return nil, fmt.Errorf("unsupported dns record type %q (expected A, AAAA or TXT)", spec.Record)
+	}
+	return source, nil
+}
+
+func (s *dnsSource) Name() string {
+	return fmt.Sprintf("dns://%s/%s", s.server, s.hostname)
+}
+
+func (s *dnsSource) Fetch(ctx context.Context) (net.IP, Meta, error) {
+	meta := Meta{Source: "dns", Detail: s.Name()}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	query, id, err := buildDNSQuery(s.hostname, s.qtype)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	response, err := s.exchange(ctx, "udp", query)
+	if err == nil && len(response) > 2 && response[2]&0x02 != 0 {
+		// Truncated: ask again over TCP
+		response, err = s.exchange(ctx, "tcp", query)
+	}
+	if err != nil {
+		return nil, meta, fmt.Errorf("failed to query %s: %w", s.Name(), err)
+	}
+
+	addr, err := parseDNSAnswer(response, id, s.qtype)
+	if err != nil {
+		return nil, meta, fmt.Errorf("invalid answer from %s: %w", s.Name(), err)
+	}
+	return addr, meta, nil
+}
+
+// exchange sends the query and reads the response over UDP or TCP
+func (s *dnsSource) exchange(ctx context.Context, network string, query []byte) ([]byte, error) {
+	conn, err := s.dial(ctx, network, s.server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 4096)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	// TCP messages are prefixed with their length
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(framed, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// buildDNSQuery encodes a single-question query with a random ID
+func buildDNSQuery(hostname string, qtype uint16) ([]byte, uint16, error) {
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, 0, fmt.Errorf("failed to generate query ID: %w", err)
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+
+	// Header: ID, recursion desired, one question
+	msg := []byte{idBytes[0], idBytes[1], 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	for _, label := range strings.Split(hostname, ".") {
+		if label == "" || len(label) > 63 {
+			return nil, 0, fmt.Errorf("invalid hostname %q", hostname)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // Class IN
+	return msg, id, nil
+}
+
+// parseDNSAnswer returns the first address of the requested type in the
+// answer section; TXT records must contain the address as text
+func parseDNSAnswer(msg []byte, id uint16, qtype uint16) (net.IP, error) {
+	if len(msg) < 12 {
+		return nil, errors.New("short message")
+	}
+	if binary.BigEndian.Uint16(msg[0:2]) != id {
+		return nil, errors.New("mismatched query ID")
+	}
+	if rcode := msg[3] & 0x0f; rcode != 0 {
+		return nil, fmt.Errorf("server returned rcode %d", rcode)
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:6]))
+	answers := int(binary.BigEndian.Uint16(msg[6:8]))
+
+	offset := 12
+	for range questions {
+		next, err := skipDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next + 4
+	}
+
+	for range answers {
+		next, err := skipDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(msg) {
+			return nil, errors.New("truncated record")
+		}
+		rtype := binary.BigEndian.Uint16(msg[next : next+2])
+		length := int(binary.BigEndian.Uint16(msg[next+8 : next+10]))
+		data := next + 10
+		if data+length > len(msg) {
+			return nil, errors.New("truncated record")
+		}
+		rdata := msg[data : data+length]
+		offset = data + length
+
+		if rtype != qtype {
+			continue // e.g. a CNAME leading to the answer
+		}
+		switch rtype {
+		case dnsTypeA, dnsTypeAAAA:
+			if len(rdata) == net.IPv4len || len(rdata) == net.IPv6len {
+				return net.IP(append([]byte(nil), rdata...)), nil
+			}
+		case dnsTypeTXT:
+			for len(rdata) > 0 {
+				size := int(rdata[0])
+				if 1+size > len(rdata) {
+					break
+				}
+				if addr := net.ParseIP(strings.TrimSpace(string(rdata[1 : 1+size]))); addr != nil {
+					return addr, nil
+				}
+				rdata = rdata[1+size:]
+			}
+		}
+	}
+
+	return nil, errors.New("no address in answer")
+}
+
+// skipDNSName returns the offset following the (possibly compressed) name at offset
+func skipDNSName(msg []byte, offset int) (int, error) {
+	for {
+		if offset >= len(msg) {
+			return 0, errors.New("truncated name")
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xc0 == 0xc0:
+			return offset + 2, nil
+		default:
+			offset += 1 + length
+		}
+	}
+}
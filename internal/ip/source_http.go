@@ -0,0 +1,181 @@
+package ip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	RegisterSource("http", newHTTPSource)
+}
+
+// defaultJSONField is where JSON services such as ifconfig.co/json and
+// ipinfo.io put the address
+const defaultJSONField = "ip"
+
+// httpSource asks an echo service that answers with the caller's address
+// as plain text (e.g., https://api.ipify.org), or in a field of a JSON
+// object (e.g., https://ipinfo.io/json)
+type httpSource struct {
+	url    string
+	field  string // Path of the address in a JSON answer; empty for plain text
+	header http.Header
+	client *http.Client
+}
+
+func newHTTPSource(spec SourceSpec, env SourceEnv) (Source, error) {
+	if spec.URL == "" {
+		return nil, fmt.Errorf("http source requires a url")
+	}
+	source := &httpSource{url: spec.URL, header: requestHeader(env.UserAgent, spec.Headers), client: env.HTTPClient}
+	if spec.Proxy != "" {
+		client, err := proxiedHTTPClient(env, spec.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("http source %s: %w", spec.URL, err)
+		}
+		source.client = client
+	}
+	switch spec.Format {
+	case "", "text":
+		if spec.Field != "" {
+			return nil, fmt.Errorf("http source %s: field requires format \"json\"", spec.URL)
+		}
+	case "json":
+		source.field = spec.Field
+		if source.field == "" {
+			source.field = defaultJSONField
+		}
+	default:
+		return nil, fmt.Errorf("http source %s: unknown format %q (use \"text\" or \"json\")", spec.URL, spec.Format)
+	}
+	return source, nil
+}
+
+func (s *httpSource) Name() string {
+	return s.url
+}
+
+func (s *httpSource) Fetch(ctx context.Context) (net.IP, Meta, error) {
+	meta := Meta{Source: "http", Detail: s.url}
+
+	body, err := fetchPage(ctx, s.client, s.url, s.header, "", "")
+	if err != nil {
+		return nil, meta, err
+	}
+
+	// Clean up response (remove newlines, whitespace, etc.)
+	text := strings.TrimSpace(string(body))
+	if text == "" {
+		return nil, meta, fmt.Errorf("empty response from %s", s.url)
+	}
+
+	// Captive portals and proxies answer with a login or error page
+	if strings.HasPrefix(text, "<") {
+		return nil, meta, fmt.Errorf("service %s returned an HTML page instead of an IP address, e.g. from a captive portal: %s", s.url, quoteAnswer(text))
+	}
+
+	// Plain-text services that answer in JSON, e.g. ifconfig.co/json listed
+	// in ip.services, are read like a json source with the default field
+	field := s.field
+	if field == "" && strings.HasPrefix(text, "{") {
+		field = defaultJSONField
+	}
+	if field != "" {
+		value, err := jsonField([]byte(text), field)
+		if err != nil {
+			return nil, meta, fmt.Errorf("service %s returned %s: %w", s.url, quoteAnswer(text), err)
+		}
+		text = value
+	}
+
+	addr := ParseAddr(text)
+	if addr == nil {
+		return nil, meta, fmt.Errorf("service %s returned %s, which is not an IP address", s.url, quoteAnswer(text))
+	}
+	return addr, meta, nil
+}
+
+// jsonField returns the string at a dotted path of a JSON document, where
+// numbers index arrays, e.g. "data.addresses.0"
+func jsonField(data []byte, path string) (string, error) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			var ok bool
+			if value, ok = node[key]; !ok {
+				return "", fmt.Errorf("no field %q", path)
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", fmt.Errorf("no field %q", path)
+			}
+			value = node[i]
+		default:
+			return "", fmt.Errorf("no field %q", path)
+		}
+	}
+	text, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("field %q is not a string", path)
+	}
+	return text, nil
+}
+
+// requestHeader returns the headers of the requests of a source: the
+// User-Agent, overridden by the configured headers
+func requestHeader(userAgent string, headers map[string]string) http.Header {
+	header := make(http.Header)
+	if userAgent != "" {
+		header.Set("User-Agent", userAgent)
+	}
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+	return header
+}
+
+// fetchPage performs a GET request with the given headers and returns the
+// decoded body
+func fetchPage(ctx context.Context, client *http.Client, url string, header http.Header, username, password string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", url, err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if host := header.Get("Host"); host != "" {
+		req.Host = host
+	}
+	// Set after the configured headers, as only these encodings are decoded
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("service %s returned status %d", url, resp.StatusCode)
+	}
+
+	body, err := readBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", url, err)
+	}
+	return body, nil
+}
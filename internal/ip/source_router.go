@@ -0,0 +1,106 @@
+package ip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+)
+
+func init() {
+	RegisterSource("router", newRouterSource)
+}
+
+// Candidate addresses on a router page; validated with net.ParseIP
+var (
+	ipv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6Pattern = regexp.MustCompile(`(?i)\b[0-9a-f]{1,4}(?::[0-9a-f]{0,4}){2,7}\b`)
+)
+
+// routerSource scrapes the WAN address from a router's status page
+type routerSource struct {
+	url      string
+	pattern  *regexp.Regexp
+	username string
+	password string
+	family   Family
+	header   http.Header
+	client   *http.Client
+}
+
+func newRouterSource(spec SourceSpec, env SourceEnv) (Source, error) {
+	if spec.URL == "" {
+		return nil, fmt.Errorf("router source requires a url")
+	}
+
+	source := &routerSource{
+		url:      spec.URL,
+		username: spec.Username,
+		password: spec.Password,
+		family:   env.Family,
+		header:   requestHeader(env.UserAgent, spec.Headers),
+		client:   env.HTTPClient,
+	}
+	if spec.Pattern != "" {
+		pattern, err := regexp.Compile(spec.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid router pattern: %w", err)
+		}
+		source.pattern = pattern
+	}
+	if spec.Proxy != "" {
+		client, err := proxiedHTTPClient(env, spec.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("router source %s: %w", spec.URL, err)
+		}
+		source.client = client
+	}
+	return source, nil
+}
+
+func (s *routerSource) Name() string {
+	return s.url
+}
+
+func (s *routerSource) Fetch(ctx context.Context) (net.IP, Meta, error) {
+	meta := Meta{Source: "router", Detail: s.url}
+
+	body, err := fetchPage(ctx, s.client, s.url, s.header, s.username, s.password)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	// With a pattern, the first match (or its first group) is the address
+	if s.pattern != nil {
+		match := s.pattern.FindSubmatch(body)
+		if match == nil {
+			return nil, meta, fmt.Errorf("pattern not found on %s", s.url)
+		}
+		text := match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+		addr := net.ParseIP(string(text))
+		if addr == nil {
+			return nil, meta, fmt.Errorf("pattern matched %q on %s, which is not an IP address", text, s.url)
+		}
+		return addr, meta, nil
+	}
+
+	// Otherwise take the first public address of the fetcher's family; the
+	// page usually also lists LAN, gateway and DNS server addresses
+	var candidates [][]byte
+	if s.family != FamilyIPv6 {
+		candidates = append(candidates, ipv4Pattern.FindAll(body, -1)...)
+	}
+	if s.family != FamilyIPv4 {
+		candidates = append(candidates, ipv6Pattern.FindAll(body, -1)...)
+	}
+	for _, candidate := range candidates {
+		if addr := net.ParseIP(string(candidate)); addr != nil && isPublicAddr(addr) {
+			return addr, meta, nil
+		}
+	}
+	return nil, meta, fmt.Errorf("no public %s address found on %s", s.family.Label(), s.url)
+}
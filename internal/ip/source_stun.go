@@ -0,0 +1,252 @@
+package ip
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+func init() {
+	RegisterSource("stun", newSTUNSource)
+}
+
+// defaultSTUNServer answers binding requests for free, over IPv4 and IPv6
+const defaultSTUNServer = "stun.l.google.com:19302"
+
+// STUN message format (RFC 5389)
+const (
+	stunHeaderSize     = 20
+	stunMagicCookie    = 0x2112A442
+	stunBindingRequest = 0x0001
+	stunBindingSuccess = 0x0101
+	stunBindingError   = 0x0111
+
+	stunAttrMappedAddress    = 0x0001 // RFC 3489 servers
+	stunAttrErrorCode        = 0x0009
+	stunAttrXORMappedAddress = 0x0020
+)
+
+// stunInitialRTO is the first retransmission timeout; it doubles on every
+// retransmission, as RFC 5389 recommends for UDP
+const stunInitialRTO = 500 * time.Millisecond
+
+// NAT behaviors reported by the stun source in Meta.NAT
+const (
+	NATNone           = "no NAT"              // The address is on a local interface
+	NATPortPreserving = "port-preserving NAT" // The NAT kept the local port
+	NATPortRemapping  = "port-remapping NAT"  // The NAT picked another port
+)
+
+// stunSource sends a STUN binding request over UDP and reads the address
+// the server saw it from. It works where HTTP is only allowed through a
+// proxy, and tells from the mapped port how the NAT in between behaves.
+type stunSource struct {
+	server  string
+	timeout time.Duration
+	dial    func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+func newSTUNSource(spec SourceSpec, env SourceEnv) (Source, error) {
+	source := &stunSource{server: spec.Server, timeout: env.Timeout, dial: env.Dial}
+	if source.server == "" {
+		source.server = defaultSTUNServer
+	}
+	if _, _, err := net.SplitHostPort(source.server); err != nil {
+		source.server = net.JoinHostPort(source.server, "3478")
+	}
+	return source, nil
+}
+
+func (s *stunSource) Name() string {
+	return "stun://" + s.server
+}
+
+func (s *stunSource) Fetch(ctx context.Context) (net.IP, Meta, error) {
+	meta := Meta{Source: "stun", Detail: s.Name()}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	conn, err := s.dial(ctx, "udp", s.server)
+	if err != nil {
+		return nil, meta, fmt.Errorf("failed to query %s: %w", s.Name(), err)
+	}
+	defer conn.Close()
+
+	request, txID, err := buildSTUNRequest()
+	if err != nil {
+		return nil, meta, err
+	}
+	mapped, err := stunExchange(ctx, conn, request, txID)
+	if err != nil {
+		return nil, meta, fmt.Errorf("failed to query %s: %w", s.Name(), err)
+	}
+
+	if local, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		meta.NAT = natBehavior(local, mapped)
+	}
+	return mapped.IP, meta, nil
+}
+
+// stunExchange sends the request until a response with its transaction ID
+// arrives, retransmitting with a doubling timeout until the context ends
+func stunExchange(ctx context.Context, conn net.Conn, request []byte, txID [12]byte) (*net.UDPAddr, error) {
+	buf := make([]byte, 1500)
+	rto := stunInitialRTO
+	deadline, hasDeadline := ctx.Deadline()
+	for {
+		if _, err := conn.Write(request); err != nil {
+			return nil, err
+		}
+
+		wait := time.Now().Add(rto)
+		if hasDeadline && deadline.Before(wait) {
+			wait = deadline
+		}
+		conn.SetReadDeadline(wait)
+
+		for {
+			n, err := conn.Read(buf)
+			var timeout net.Error
+			if errors.As(err, &timeout) && timeout.Timeout() {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			mapped, err := parseSTUNResponse(buf[:n], txID)
+			if errors.Is(err, errSTUNOtherTransaction) {
+				continue // Not an answer to this request
+			}
+			return mapped, err
+		}
+
+		if ctx.Err() != nil || hasDeadline && !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("no response: %w", context.DeadlineExceeded)
+		}
+		rto *= 2
+	}
+}
+
+// buildSTUNRequest encodes a binding request without attributes and with a
+// random transaction ID
+func buildSTUNRequest() ([]byte, [12]byte, error) {
+	var txID [12]byte
+	if _, err := rand.Read(txID[:]); err != nil {
+		return nil, txID, fmt.Errorf("failed to generate transaction ID: %w", err)
+	}
+	msg := binary.BigEndian.AppendUint16(nil, stunBindingRequest)
+	msg = binary.BigEndian.AppendUint16(msg, 0) // Attributes length
+	msg = binary.BigEndian.AppendUint32(msg, stunMagicCookie)
+	return append(msg, txID[:]...), txID, nil
+}
+
+// errSTUNOtherTransaction is returned for responses to another request
+var errSTUNOtherTransaction = errors.New("response to another transaction")
+
+// parseSTUNResponse returns the mapped address of a binding response,
+// preferring XOR-MAPPED-ADDRESS, which NATs rewriting addresses in
+// payloads leave alone
+func parseSTUNResponse(msg []byte, txID [12]byte) (*net.UDPAddr, error) {
+	if len(msg) < stunHeaderSize {
+		return nil, errors.New("short message")
+	}
+	if binary.BigEndian.Uint32(msg[4:8]) != stunMagicCookie || !bytes.Equal(msg[8:20], txID[:]) {
+		return nil, errSTUNOtherTransaction
+	}
+	length := int(binary.BigEndian.Uint16(msg[2:4]))
+	if stunHeaderSize+length > len(msg) {
+		return nil, errors.New("truncated message")
+	}
+	msgType := binary.BigEndian.Uint16(msg[0:2])
+	attrs := msg[stunHeaderSize : stunHeaderSize+length]
+
+	var mapped, xorMapped *net.UDPAddr
+	var errorCode string
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:2])
+		attrLength := int(binary.BigEndian.Uint16(attrs[2:4]))
+		if 4+attrLength > len(attrs) {
+			return nil, errors.New("truncated attribute")
+		}
+		value := attrs[4 : 4+attrLength]
+
+		switch attrType {
+		case stunAttrMappedAddress:
+			mapped = parseSTUNAddress(value, nil)
+		case stunAttrXORMappedAddress:
+			xorMapped = parseSTUNAddress(value, msg[4:20])
+		case stunAttrErrorCode:
+			if len(value) >= 4 {
+				errorCode = fmt.Sprintf("%d %s", int(value[2]&0x07)*100+int(value[3]), value[4:])
+			}
+		}
+
+		// Attributes are padded to a multiple of four bytes
+		next := 4 + (attrLength+3)&^3
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+
+	switch {
+	case msgType == stunBindingError:
+		return nil, fmt.Errorf("server returned error %s", errorCode)
+	case msgType != stunBindingSuccess:
+		return nil, fmt.Errorf("unexpected message type %#04x", msgType)
+	case xorMapped != nil:
+		return xorMapped, nil
+	case mapped != nil:
+		return mapped, nil
+	}
+	return nil, errors.New("no mapped address in response")
+}
+
+// parseSTUNAddress decodes a (XOR-)MAPPED-ADDRESS value. XORed addresses
+// are masked with the magic cookie and transaction ID, passed as mask.
+func parseSTUNAddress(value []byte, mask []byte) *net.UDPAddr {
+	if len(value) < 4 {
+		return nil
+	}
+	var size int
+	switch value[1] {
+	case 0x01:
+		size = net.IPv4len
+	case 0x02:
+		size = net.IPv6len
+	default:
+		return nil
+	}
+	if len(value) < 4+size {
+		return nil
+	}
+
+	port := binary.BigEndian.Uint16(value[2:4])
+	addr := net.IP(append([]byte(nil), value[4:4+size]...))
+	if mask != nil {
+		port ^= uint16(stunMagicCookie >> 16)
+		for i := range addr {
+			addr[i] ^= mask[i]
+		}
+	}
+	return &net.UDPAddr{IP: addr, Port: int(port)}
+}
+
+// natBehavior tells from the local and the mapped address of a request
+// whether and how a NAT translated it
+func natBehavior(local, mapped *net.UDPAddr) string {
+	switch {
+	case local.IP.Equal(mapped.IP):
+		return NATNone
+	case local.Port == mapped.Port:
+		return NATPortPreserving
+	default:
+		return NATPortRemapping
+	}
+}
@@ -0,0 +1,284 @@
+package ip
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterSource("upnp", newUPnPSource)
+}
+
+// ssdpAddr is the SSDP multicast group routers announce themselves on
+const ssdpAddr = "239.255.255.250:1900"
+
+// ssdpWait bounds how long discovery waits for routers to answer
+const ssdpWait = 3 * time.Second
+
+// wanServiceTypes are the UPnP services reporting the external address,
+// the second being used by PPPoE routers
+var wanServiceTypes = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:",
+	"urn:schemas-upnp-org:service:WANPPPConnection:",
+}
+
+// upnpSource asks the router for its external address through UPnP IGD.
+// It reports the router's WAN address, which is not the public IP behind
+// carrier-grade NAT, and only supports IPv4.
+type upnpSource struct {
+	location string // Device description URL; discovered when empty
+	iface    string
+	client   *http.Client
+
+	mu          sync.Mutex
+	controlURL  string // Cached after the first successful discovery
+	serviceType string
+}
+
+func newUPnPSource(spec SourceSpec, env SourceEnv) (Source, error) {
+	return &upnpSource{location: spec.URL, iface: env.Interface, client: env.HTTPClient}, nil
+}
+
+func (s *upnpSource) Name() string {
+	if s.location != "" {
+		return "upnp:" + s.location
+	}
+	return "upnp"
+}
+
+func (s *upnpSource) Fetch(ctx context.Context) (net.IP, Meta, error) {
+	meta := Meta{Source: "upnp", Detail: s.location}
+
+	controlURL, serviceType, err := s.service(ctx)
+	if err != nil {
+		return nil, meta, err
+	}
+	meta.Detail = controlURL
+
+	addr, err := s.externalAddress(ctx, controlURL, serviceType)
+	if err != nil {
+		// The router may have restarted with another control URL
+		s.mu.Lock()
+		s.controlURL = ""
+		s.mu.Unlock()
+		return nil, meta, err
+	}
+	if !isPublicAddr(addr) {
+		return nil, meta, fmt.Errorf("router reports WAN address %s, which is not public (carrier-grade NAT?)", addr)
+	}
+	return addr, meta, nil
+}
+
+// service returns the control URL and type of the router's WAN service
+func (s *upnpSource) service(ctx context.Context) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.controlURL != "" {
+		return s.controlURL, s.serviceType, nil
+	}
+
+	locations := []string{s.location}
+	if s.location == "" {
+		var err error
+		if locations, err = s.discover(ctx); err != nil {
+			return "", "", err
+		}
+	}
+
+	var lastError error
+	for _, location := range locations {
+		controlURL, serviceType, err := s.describe(ctx, location)
+		if err != nil {
+			lastError = err
+			continue
+		}
+		s.controlURL, s.serviceType = controlURL, serviceType
+		return controlURL, serviceType, nil
+	}
+	return "", "", lastError
+}
+
+// discover sends an SSDP search and returns the description URLs of the
+// gateways that answer
+func (s *upnpSource) discover(ctx context.Context) ([]string, error) {
+	// Answers come from the router's own address, so the socket must not
+	// be connected to the multicast group
+	local := &net.UDPAddr{}
+	if s.iface != "" {
+		addr, err := interfaceAddr(s.iface, FamilyIPv4)
+		if err != nil {
+			return nil, err
+		}
+		local.IP = addr
+	}
+	conn, err := net.ListenUDP("udp4", local)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSDP socket: %w", err)
+	}
+	defer conn.Close()
+
+	group, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, err
+	}
+	search := "M-SEARCH * HTTP/1.1\r\n" +
+		"HOST: " + ssdpAddr + "\r\n" +
+		"MAN: \"ssdp:discover\"\r\n" +
+		"MX: 2\r\n" +
+		"ST: urn:schemas-upnp-org:device:InternetGatewayDevice:1\r\n\r\n"
+	if _, err := conn.WriteTo([]byte(search), group); err != nil {
+		return nil, fmt.Errorf("failed to send SSDP search: %w", err)
+	}
+
+	deadline := time.Now().Add(ssdpWait)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetReadDeadline(deadline)
+
+	var locations []string
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break // Deadline reached
+		}
+		reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(buf[:n])))
+		if _, err := reader.ReadLine(); err != nil {
+			continue
+		}
+		header, err := reader.ReadMIMEHeader()
+		if err != nil && len(header) == 0 {
+			continue
+		}
+		if location := header.Get("Location"); location != "" && !containsString(locations, location) {
+			locations = append(locations, location)
+		}
+	}
+
+	if len(locations) == 0 {
+		return nil, errors.New("no UPnP gateway answered the SSDP search")
+	}
+	return locations, nil
+}
+
+// upnpDevice is the part of a UPnP device description used to find the WAN service
+type upnpDevice struct {
+	Services []struct {
+		ServiceType string `xml:"serviceType"`
+		ControlURL  string `xml:"controlURL"`
+	} `xml:"serviceList>service"`
+	Devices []upnpDevice `xml:"deviceList>device"`
+}
+
+// describe fetches a device description and returns its WAN service
+func (s *upnpSource) describe(ctx context.Context, location string) (string, string, error) {
+	body, err := fetchPage(ctx, s.client, location, nil, "", "")
+	if err != nil {
+		return "", "", err
+	}
+
+	var root struct {
+		URLBase string     `xml:"URLBase"`
+		Device  upnpDevice `xml:"device"`
+	}
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return "", "", fmt.Errorf("invalid device description at %s: %w", location, err)
+	}
+
+	base, err := url.Parse(location)
+	if err != nil {
+		return "", "", err
+	}
+	if root.URLBase != "" {
+		if parsed, err := url.Parse(root.URLBase); err == nil {
+			base = parsed
+		}
+	}
+
+	devices := []upnpDevice{root.Device}
+	for len(devices) > 0 {
+		device := devices[0]
+		devices = append(devices[1:], device.Devices...)
+		for _, service := range device.Services {
+			for _, wanType := range wanServiceTypes {
+				if strings.HasPrefix(service.ServiceType, wanType) {
+					control, err := base.Parse(strings.TrimSpace(service.ControlURL))
+					if err != nil {
+						return "", "", fmt.Errorf("invalid control URL %q: %w", service.ControlURL, err)
+					}
+					return control.String(), service.ServiceType, nil
+				}
+			}
+		}
+	}
+	return "", "", fmt.Errorf("device at %s has no WAN connection service", location)
+}
+
+// externalAddress calls GetExternalIPAddress on the WAN service
+func (s *upnpSource) externalAddress(ctx context.Context, controlURL, serviceType string) (net.IP, error) {
+	envelope := `<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body><u:GetExternalIPAddress xmlns:u="` + serviceType + `"></u:GetExternalIPAddress></s:Body>` +
+		`</s:Envelope>`
+
+	req, err := http.NewRequestWithContext(ctx, "POST", controlURL, strings.NewReader(envelope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", controlURL, err)
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"`+serviceType+`#GetExternalIPAddress"`)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", controlURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("router %s returned status %d", controlURL, resp.StatusCode)
+	}
+
+	decoder := xml.NewDecoder(io.LimitReader(resp.Body, maxResponseSize))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("no external address in response from %s", controlURL)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "NewExternalIPAddress" {
+			continue
+		}
+		var text string
+		if err := decoder.DecodeElement(&text, &start); err != nil {
+			return nil, fmt.Errorf("invalid response from %s: %w", controlURL, err)
+		}
+		addr := net.ParseIP(strings.TrimSpace(text))
+		if addr == nil {
+			return nil, fmt.Errorf("router %s returned %q, which is not an IP address", controlURL, text)
+		}
+		return addr, nil
+	}
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
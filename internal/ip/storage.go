@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -15,8 +17,30 @@ const (
 
 // Record represents an IP change record
 type Record struct {
-	IP        string    `json:"ip"`
-	Timestamp time.Time `json:"timestamp"`
+	Version    int               `json:"version,omitempty"` // RecordVersion when written; zero for records written before versioning
+	IP         string            `json:"ip"`
+	Family     Family            `json:"family,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Enrichment map[string]string `json:"enrichment,omitempty"` // Additional details about the IP, by name
+
+	// Where the IP is geolocated and the network announcing it, when
+	// enrichment looked them up
+	Country string `json:"country,omitempty"` // ISO 3166 code, e.g. "DE"
+	Region  string `json:"region,omitempty"`
+	City    string `json:"city,omitempty"`
+	ASN     string `json:"asn,omitempty"` // e.g. "AS3209"
+
+	// Why the IP was anomalous when recorded, e.g. "outside the expected
+	// ranges"; empty for an expected IP
+	Anomaly string `json:"anomaly,omitempty"`
+
+	// Fields this version does not know, written by a newer one. They are
+	// written back unchanged when the records file is rewritten.
+	extra map[string]json.RawMessage
+
+	// raw holds a record this version cannot decode at all. It is left out
+	// of the history but kept in the file.
+	raw json.RawMessage
 }
 
 // Storage handles IP data persistence
@@ -24,14 +48,82 @@ type Storage struct {
 	dataDir     string
 	recordsFile string
 	lastIPFile  string
+	family      Family
+	mu          *sync.Mutex     // Shared by every storage of the same records file, see recordsLock
+	deferred    *deferredWrites // Last IPs not written yet in low-write mode, shared by all views
+}
+
+// deferredWrites holds the last IPs saved since the last flush, by file
+type deferredWrites struct {
+	mu      sync.Mutex
+	lastIPs map[string]deferredIP
+}
+
+// deferredIP is a last IP and when it was saved, which becomes the file's
+// modification time when flushed
+type deferredIP struct {
+	ip      string
+	savedAt time.Time
+}
+
+// recordsLocks holds the lock of every records file by path
+var recordsLocks sync.Map
+
+// recordsLock returns the lock serializing the read-modify-write of a
+// records file. Storages are created for the same file in several places,
+// e.g. a WAN's monitors, its annotations and the pruner, so the lock
+// belongs to the file rather than to a storage.
+func recordsLock(path string) *sync.Mutex {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	mu, _ := recordsLocks.LoadOrStore(path, &sync.Mutex{})
+	return mu.(*sync.Mutex)
 }
 
 // NewStorage creates a new IP storage
 func NewStorage(dataDir, recordsFile, lastIPFile string) *Storage {
+	recordsFile = filepath.Join(dataDir, recordsFile)
 	return &Storage{
 		dataDir:     dataDir,
-		recordsFile: filepath.Join(dataDir, recordsFile),
+		recordsFile: recordsFile,
 		lastIPFile:  filepath.Join(dataDir, lastIPFile),
+		mu:          recordsLock(recordsFile),
+	}
+}
+
+// ForFamily returns a view of the storage that keeps a separate last IP
+// file for the given family while sharing the records file
+func (s *Storage) ForFamily(family Family) *Storage {
+	if family == FamilyAny {
+		return s
+	}
+
+	ext := filepath.Ext(s.lastIPFile)
+	lastIPFile := strings.TrimSuffix(s.lastIPFile, ext) + "_" + string(family) + ext
+
+	return &Storage{
+		dataDir:     s.dataDir,
+		recordsFile: s.recordsFile,
+		lastIPFile:  lastIPFile,
+		family:      family,
+		mu:          s.mu,
+		deferred:    s.deferred,
+	}
+}
+
+// ForWAN returns a storage keeping its own history and last IP files in a
+// subdirectory of the data directory for the named WAN profile
+func (s *Storage) ForWAN(name string) *Storage {
+	dataDir := filepath.Join(s.dataDir, "wans", name)
+	recordsFile := filepath.Join(dataDir, filepath.Base(s.recordsFile))
+	return &Storage{
+		dataDir:     dataDir,
+		recordsFile: recordsFile,
+		lastIPFile:  filepath.Join(dataDir, filepath.Base(s.lastIPFile)),
+		family:      s.family,
+		mu:          recordsLock(recordsFile),
+		deferred:    s.deferred,
 	}
 }
 
@@ -43,8 +135,51 @@ func (s *Storage) Initialize() error {
 	return nil
 }
 
+// DeferWrites keeps last IPs in memory until Flush, so that a change
+// costs no write to the SD card of a Raspberry Pi. Call it before creating
+// views of the storage.
+func (s *Storage) DeferWrites() {
+	s.deferred = &deferredWrites{lastIPs: make(map[string]deferredIP)}
+}
+
+// Flush writes the last IPs saved since the last flush, with deferred
+// writes. The file times are set to when they were saved.
+func (s *Storage) Flush() error {
+	if s.deferred == nil {
+		return nil
+	}
+	s.deferred.mu.Lock()
+	defer s.deferred.mu.Unlock()
+
+	for path, last := range s.deferred.lastIPs {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create data directory: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(last.ip), DataFilePerm); err != nil {
+			return fmt.Errorf("failed to save last IP: %w", err)
+		}
+		os.Chtimes(path, last.savedAt, last.savedAt)
+		delete(s.deferred.lastIPs, path)
+	}
+	return nil
+}
+
+// pendingLastIP returns the last IP saved but not written yet, if any
+func (s *Storage) pendingLastIP() (deferredIP, bool) {
+	if s.deferred == nil {
+		return deferredIP{}, false
+	}
+	s.deferred.mu.Lock()
+	defer s.deferred.mu.Unlock()
+	last, ok := s.deferred.lastIPs[s.lastIPFile]
+	return last, ok
+}
+
 // ReadLastIP reads the last known IP from file
 func (s *Storage) ReadLastIP() (string, error) {
+	if last, ok := s.pendingLastIP(); ok {
+		return last.ip, nil
+	}
 	data, err := os.ReadFile(s.lastIPFile)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -52,11 +187,31 @@ func (s *Storage) ReadLastIP() (string, error) {
 		}
 		return "", fmt.Errorf("failed to read last IP file: %w", err)
 	}
-	return strings.TrimSpace(string(data)), nil
+	// Older versions stored the address as the service formatted it
+	return NormalizeIP(string(data)), nil
+}
+
+// LastIPTime returns when the last known IP was saved, or the zero time if unknown
+func (s *Storage) LastIPTime() time.Time {
+	if last, ok := s.pendingLastIP(); ok {
+		return last.savedAt
+	}
+	info, err := os.Stat(s.lastIPFile)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
 }
 
 // SaveLastIP saves the current IP to file
 func (s *Storage) SaveLastIP(ip string) error {
+	if s.deferred != nil {
+		s.deferred.mu.Lock()
+		defer s.deferred.mu.Unlock()
+		s.deferred.lastIPs[s.lastIPFile] = deferredIP{ip: ip, savedAt: time.Now()}
+		return nil
+	}
+
 	if err := s.Initialize(); err != nil {
 		return err
 	}
@@ -74,12 +229,17 @@ func (s *Storage) SaveRecord(ip string) error {
 	}
 
 	record := Record{
+		Version:   RecordVersion,
 		IP:        ip,
+		Family:    s.family,
 		Timestamp: time.Now(),
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Read existing records
-	records, err := s.GetHistory()
+	records, err := s.readRecords()
 	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to read existing records: %w", err)
 	}
@@ -88,37 +248,130 @@ func (s *Storage) SaveRecord(ip string) error {
 	records = append(records, record)
 
 	// Save updated records
-	data, err := json.MarshalIndent(records, "", "    ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal records: %w", err)
-	}
-
-	if err := os.WriteFile(s.recordsFile, data, DataFilePerm); err != nil {
+	if err := s.writeRecords(records); err != nil {
 		return fmt.Errorf("failed to save IP record: %w", err)
 	}
 
 	return nil
 }
 
+// AnnotateRecord updates the latest record of this view's family with
+// annotate, if it is of the given IP, e.g. to add details looked up after
+// the change was saved
+func (s *Storage) AnnotateRecord(ip string, annotate func(record *Record)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.readRecords()
+	if err != nil {
+		return fmt.Errorf("failed to read records: %w", err)
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].undecodable() || records[i].Family != s.family {
+			continue
+		}
+		if records[i].IP != ip {
+			return nil
+		}
+		annotate(&records[i])
+		if err := s.writeRecords(records); err != nil {
+			return fmt.Errorf("failed to save IP record: %w", err)
+		}
+		return nil
+	}
+	return nil
+}
+
 // GetHistory returns the history of IP changes
 func (s *Storage) GetHistory() ([]Record, error) {
-	var records []Record
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.readRecords()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(records, Record.undecodable), nil
+}
 
+// Recent returns the latest n records of this view's family, newest first
+func (s *Storage) Recent(n int) ([]Record, error) {
+	records, err := s.GetHistory()
+	if err != nil {
+		return nil, err
+	}
+	var recent []Record
+	for i := len(records) - 1; i >= 0 && len(recent) < n; i-- {
+		if records[i].Family == s.family {
+			recent = append(recent, records[i])
+		}
+	}
+	return recent, nil
+}
+
+// readRecords reads the records file, including the records this version
+// cannot decode; callers must hold the lock
+func (s *Storage) readRecords() ([]Record, error) {
 	data, err := os.ReadFile(s.recordsFile)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return records, nil // File doesn't exist, return empty slice
+			return nil, nil // File doesn't exist, return empty slice
 		}
 		return nil, fmt.Errorf("failed to read records file: %w", err)
 	}
 
-	if err := json.Unmarshal(data, &records); err != nil {
+	records, err := decodeRecords(data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal records: %w", err)
 	}
-
 	return records, nil
 }
 
+// writeRecords replaces the records file; callers must hold the lock
+func (s *Storage) writeRecords(records []Record) error {
+	data, err := json.MarshalIndent(records, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal records: %w", err)
+	}
+	return os.WriteFile(s.recordsFile, data, DataFilePerm)
+}
+
+// PruneHistory removes the records older than before, except the latest
+// one of each family, which holds the current IP. It returns how many were
+// removed.
+func (s *Storage) PruneHistory(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.readRecords()
+	if err != nil {
+		return 0, err
+	}
+	latest := make(map[Family]int)
+	for i, record := range records {
+		if !record.undecodable() {
+			latest[record.Family] = i
+		}
+	}
+	kept := make([]Record, 0, len(records))
+	for i, record := range records {
+		// Records this version cannot read are left to the version that can
+		if !record.undecodable() && record.Timestamp.Before(before) && latest[record.Family] != i {
+			continue
+		}
+		kept = append(kept, record)
+	}
+	removed := len(records) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	if err := s.writeRecords(kept); err != nil {
+		return 0, fmt.Errorf("failed to prune history: %w", err)
+	}
+	return removed, nil
+}
+
 // GetHistoryCount returns the number of IP change records
 func (s *Storage) GetHistoryCount() (int, error) {
 	records, err := s.GetHistory()
@@ -130,6 +383,9 @@ func (s *Storage) GetHistoryCount() (int, error) {
 
 // ClearHistory removes all IP change records (useful for testing or cleanup)
 func (s *Storage) ClearHistory() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if err := os.Remove(s.recordsFile); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to clear history: %w", err)
 	}
@@ -0,0 +1,44 @@
+package ip
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestStorageConcurrentFamiliesKeepRecords saves from the IPv4 and IPv6
+// views of a WAN, each derived from its own ForWAN call the way the
+// monitors get them, and checks that no record is lost to concurrent
+// rewrites of the shared records file
+func TestStorageConcurrentFamiliesKeepRecords(t *testing.T) {
+	dataDir := t.TempDir()
+	const saves = 50
+
+	var wg sync.WaitGroup
+	for _, family := range []Family{FamilyIPv4, FamilyIPv6} {
+		storage := NewStorage(dataDir, "ip_records.json", "last_ip.txt").ForWAN("lte").ForFamily(family)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range saves {
+				ip := fmt.Sprintf("203.0.113.%d", i)
+				if family == FamilyIPv6 {
+					ip = fmt.Sprintf("2001:db8::%x", i)
+				}
+				if err := storage.SaveRecord(ip); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	count, err := NewStorage(dataDir, "ip_records.json", "last_ip.txt").ForWAN("lte").GetHistoryCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2*saves {
+		t.Errorf("%d records, want %d", count, 2*saves)
+	}
+}
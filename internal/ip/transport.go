@@ -0,0 +1,284 @@
+package ip
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"public-ip-monitor/internal/debughttp"
+	"public-ip-monitor/internal/proxy"
+)
+
+// acceptEncoding lists the response encodings decoded by readBody
+const acceptEncoding = "gzip, deflate"
+
+// maxResponseSize bounds how much of a service response is read; an IP
+// address is a few dozen bytes even with surrounding markup
+const maxResponseSize = 64 << 10
+
+// transportKey identifies fetchers that can share connections
+type transportKey struct {
+	family Family
+	iface  string
+	proxy  string // Of the source; empty for the installed one
+}
+
+// transports are shared by all fetchers that dial the same way, so that
+// connections to the services are reused across checks and services
+// instead of being opened for every request
+var (
+	transportsMu sync.Mutex
+	transports   = make(map[transportKey]*http.Transport)
+	tlsConfig    *tls.Config // Of the transports created from now on; nil for the defaults
+)
+
+// resolver looks up the hostnames of the services and of DNS records, when
+// set instead of the system resolver
+var resolver atomic.Pointer[net.Resolver]
+
+// SetResolver makes the fetchers and DNS record checks look hostnames up
+// with r, e.g. through DNS over HTTPS
+func SetResolver(r *net.Resolver) {
+	resolver.Store(r)
+}
+
+// Resolver returns the resolver of the fetchers: the one set with
+// SetResolver, or the system resolver
+func Resolver() *net.Resolver {
+	if r := resolver.Load(); r != nil {
+		return r
+	}
+	return net.DefaultResolver
+}
+
+// SetTLSConfig makes the fetchers created afterwards verify services with
+// the given settings, e.g. a private CA, and present its client certificate
+func SetTLSConfig(config *tls.Config) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	tlsConfig = config
+}
+
+// LoadTLSConfig returns the TLS settings trusting the CAs in caFile in
+// addition to the system roots, and presenting the client certificate in
+// certFile and keyFile to services that ask for one; either may be empty
+func LoadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		config.RootCAs = roots
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// newHTTPClient creates an HTTP client using the shared transport for the
+// given family and interface
+func newHTTPClient(family Family, iface string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: debughttp.Wrap(sharedTransport(transportKey{family: family, iface: iface}, nil)),
+	}
+}
+
+// proxiedHTTPClient creates an HTTP client like newHTTPClient that connects
+// through the given proxy, or directly with "direct", instead of the
+// installed one
+func proxiedHTTPClient(env SourceEnv, proxyURL string) (*http.Client, error) {
+	pick, err := proxy.New(proxyURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	key := transportKey{family: env.Family, iface: env.Interface, proxy: proxyURL}
+	return &http.Client{
+		Timeout:   env.Timeout,
+		Transport: debughttp.Wrap(sharedTransport(key, pick)),
+	}, nil
+}
+
+// sharedTransport returns the transport for the given key, creating it on
+// first use. Without pick, it uses the proxy installed when it is created.
+func sharedTransport(key transportKey, pick proxy.Func) *http.Transport {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+
+	if transport, ok := transports[key]; ok {
+		return transport
+	}
+
+	transport := debughttp.BaseTransport().Clone()
+	transport.ForceAttemptHTTP2 = true
+	transport.MaxIdleConns = 32
+	transport.MaxIdleConnsPerHost = 2
+	transport.IdleConnTimeout = 2 * time.Minute // Outlive short check intervals
+	transport.TLSHandshakeTimeout = 10 * time.Second
+	transport.ResponseHeaderTimeout = 15 * time.Second
+
+	// Responses are decompressed by readBody, which also handles deflate
+	transport.DisableCompression = true
+
+	transport.DialContext = dialContext(key.family, key.iface)
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig.Clone()
+	}
+	if pick != nil {
+		transport.Proxy = pick
+	}
+
+	transports[key] = transport
+	return transport
+}
+
+// localAddrKey is the context key of the localAddr recording the local
+// address of a fetch
+type localAddrKey struct{}
+
+// localAddr records the local address of the last connection a fetch used
+type localAddr struct {
+	mu   sync.Mutex
+	addr net.Addr
+}
+
+// withLocalAddr returns a context in which connections record their local
+// address: new ones when they are dialed, and pooled HTTP connections when
+// they are reused
+func withLocalAddr(ctx context.Context) (context.Context, *localAddr) {
+	recorder := &localAddr{}
+	ctx = context.WithValue(ctx, localAddrKey{}, recorder)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			recorder.set(info.Conn.LocalAddr())
+		},
+	})
+	return ctx, recorder
+}
+
+func (l *localAddr) set(addr net.Addr) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.addr = addr
+}
+
+// IP returns the recorded address without the port, or "" without one
+func (l *localAddr) IP() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch addr := l.addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP.String()
+	case *net.UDPAddr:
+		return addr.IP.String()
+	}
+	return ""
+}
+
+// recordLocalAddr records the local address of a dialed connection in the
+// context's localAddr, if any
+func recordLocalAddr(ctx context.Context, conn net.Conn) {
+	if recorder, ok := ctx.Value(localAddrKey{}).(*localAddr); ok {
+		recorder.set(conn.LocalAddr())
+	}
+}
+
+// dialContext returns a dial function pinned to the given family and, if
+// set, originating from the given interface
+func dialContext(family Family, iface string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if family != FamilyAny {
+			network = family.network(strings.TrimRight(network, "46"))
+		}
+		bound := *dialer
+		bound.Resolver = resolver.Load()
+		if iface != "" {
+			// Look the address up on every dial: WAN links often get new
+			// addresses from DHCP or PPPoE
+			local, err := interfaceAddr(iface, family)
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(network, "udp") {
+				bound.LocalAddr = &net.UDPAddr{IP: local}
+			} else {
+				bound.LocalAddr = &net.TCPAddr{IP: local}
+			}
+		}
+
+		conn, err := bound.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		recordLocalAddr(ctx, conn)
+		return conn, nil
+	}
+}
+
+// readBody reads a response body, decoding gzip and deflate content
+func readBody(resp *http.Response) ([]byte, error) {
+	var body io.Reader = resp.Body
+
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip response: %w", err)
+		}
+		defer reader.Close()
+		body = reader
+	case "deflate":
+		// "deflate" is zlib-wrapped per the spec, but some servers send a raw
+		// deflate stream; zlib streams start with a 0x78 header byte
+		buffered := bufio.NewReader(body)
+		header, err := buffered.Peek(1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deflate response: %w", err)
+		}
+		if header[0] == 0x78 {
+			reader, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, fmt.Errorf("invalid deflate response: %w", err)
+			}
+			defer reader.Close()
+			body = reader
+		} else {
+			reader := flate.NewReader(buffered)
+			defer reader.Close()
+			body = reader
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	return io.ReadAll(io.LimitReader(body, maxResponseSize))
+}
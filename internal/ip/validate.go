@@ -0,0 +1,54 @@
+package ip
+
+import (
+	"net"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxQuoted is how much of an unexpected answer is quoted in errors, so an
+// error page does not flood the log
+const maxQuoted = 64
+
+// cgnatBlock is the shared address space of carrier-grade NAT (RFC 6598)
+var cgnatBlock = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicAddr reports whether the address is globally routable
+func isPublicAddr(addr net.IP) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !cgnatBlock.Contains(addr)
+}
+
+// ParseAddr parses an address as answered by a service or stored in a file:
+// surrounding white space is ignored and a zone (fe80::1%eth0) is dropped.
+// It returns nil for anything else, e.g. an HTML page.
+func ParseAddr(text string) net.IP {
+	text = strings.TrimSpace(text)
+	if host, _, found := strings.Cut(text, "%"); found && strings.Contains(host, ":") {
+		text = host
+	}
+	return net.ParseIP(text)
+}
+
+// NormalizeIP returns an address in canonical form, e.g. IPv6 in lower
+// case with zeros compressed, so that differently formatted answers for
+// the same address compare equal. Text that is no address is returned
+// trimmed.
+func NormalizeIP(text string) string {
+	if addr := ParseAddr(text); addr != nil {
+		return addr.String()
+	}
+	return strings.TrimSpace(text)
+}
+
+// quoteAnswer quotes an unexpected answer for an error message, shortened
+// to maxQuoted bytes
+func quoteAnswer(text string) string {
+	if len(text) <= maxQuoted {
+		return `"` + text + `"`
+	}
+	cut := maxQuoted
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return `"` + text[:cut] + `..."`
+}
@@ -15,6 +15,7 @@ type Logger struct {
 	format     string
 	identifier string // New field for log identifier
 	logger     *log.Logger
+	redact     func(string) string
 }
 
 // New creates a new logger with timezone configuration
@@ -32,24 +33,39 @@ func New(cfg config.LoggingConfig) (*Logger, error) {
 	}, nil
 }
 
+// SetRedact sets a function applied to every message before it is written,
+// e.g. to hide IP addresses
+func (l *Logger) SetRedact(redact func(string) string) {
+	l.redact = redact
+}
+
+// Location returns the timezone log timestamps are written in
+func (l *Logger) Location() *time.Location {
+	return l.timezone
+}
+
 func (l *Logger) Info(message string) {
-	timestamp := time.Now().In(l.timezone).Format(l.format + " MST")
-	l.logger.Printf("[%s] [INFO] %s - %s", l.identifier, timestamp, message)
+	l.write("INFO", message)
 }
 
 func (l *Logger) Error(message string) {
-	timestamp := time.Now().In(l.timezone).Format(l.format + " MST")
-	l.logger.Printf("[%s] [ERROR] %s - %s", l.identifier, timestamp, message)
+	l.write("ERROR", message)
 }
 
 func (l *Logger) Warn(message string) {
-	timestamp := time.Now().In(l.timezone).Format(l.format + " MST")
-	l.logger.Printf("[%s] [WARN] %s - %s", l.identifier, timestamp, message)
+	l.write("WARN", message)
 }
 
 func (l *Logger) Debug(message string) {
+	l.write("DEBUG", message)
+}
+
+func (l *Logger) write(level, message string) {
+	if l.redact != nil {
+		message = l.redact(message)
+	}
 	timestamp := time.Now().In(l.timezone).Format(l.format + " MST")
-	l.logger.Printf("[%s] [DEBUG] %s - %s", l.identifier, timestamp, message)
+	l.logger.Printf("[%s] [%s] %s - %s", l.identifier, level, timestamp, message)
 }
 
 // Infof logs a formatted info message
@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Ack records that someone took care of an event, which stops its
+// escalation
+type Ack struct {
+	EventID string    `json:"event_id"`
+	AckedAt time.Time `json:"acked_at"`
+	By      string    `json:"by,omitempty"` // Who took care of it, e.g. a user name or API client
+}
+
+// Acks keeps acknowledgments in a JSON lines file, shared by the monitor
+// and the ack command
+type Acks struct {
+	path string
+	mu   sync.Mutex
+}
+
+// OpenAcks opens the acknowledgments at path, creating the file on the
+// first Add
+func OpenAcks(path string) *Acks {
+	return &Acks{path: path}
+}
+
+// Add records an acknowledgment
+func (a *Acks) Add(ack Ack) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	data, err := json.Marshal(ack)
+	if err != nil {
+		return fmt.Errorf("failed to marshal acknowledgment: %w", err)
+	}
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, spoolFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open acknowledgments: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write acknowledgments: %w", err)
+	}
+	return nil
+}
+
+// List returns the acknowledgments, oldest first
+func (a *Acks) List() ([]Ack, error) {
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read acknowledgments: %w", err)
+	}
+
+	var acks []Ack
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var ack Ack
+		// A line cut short by a crash is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &ack); err != nil {
+			continue
+		}
+		acks = append(acks, ack)
+	}
+	return acks, nil
+}
+
+// Prune removes the acknowledgments keep rejects and returns how many
+func (a *Acks) Prune(keep func(ack Ack) bool) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	acks, err := a.List()
+	if err != nil {
+		return 0, err
+	}
+	var buf bytes.Buffer
+	removed := 0
+	for _, ack := range acks {
+		if !keep(ack) {
+			removed++
+			continue
+		}
+		data, err := json.Marshal(ack)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal acknowledgment: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	if err := replaceFile(a.path, buf.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to prune acknowledgments: %w", err)
+	}
+	return removed, nil
+}
+
+// Find returns the first acknowledgment of the event
+func (a *Acks) Find(eventID string) (Ack, bool, error) {
+	acks, err := a.List()
+	if err != nil {
+		return Ack{}, false, err
+	}
+	for _, ack := range acks {
+		if ack.EventID == eventID {
+			return ack, true, nil
+		}
+	}
+	return Ack{}, false, nil
+}
+
+// Acked reports whether the event was acknowledged
+func (a *Acks) Acked(eventID string) (bool, error) {
+	_, found, err := a.Find(eventID)
+	return found, err
+}
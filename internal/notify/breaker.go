@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of notifying through a channel that
+// failed repeatedly, until its cooldown is over
+var ErrCircuitOpen = errors.New("skipped after repeated failures")
+
+// breakerNotifier skips a channel that keeps failing, e.g. because of an
+// expired token, instead of retrying it on every event. After the cooldown
+// a single call probes the channel: success closes the circuit, failure
+// opens it for another cooldown.
+type breakerNotifier struct {
+	Notifier
+	threshold int
+	cooldown  time.Duration
+	logf      func(format string, args ...interface{}) // Reports a channel working again
+	warnf     func(format string, args ...interface{}) // Reports a channel being skipped
+
+	mu        sync.Mutex
+	failures  int       // Consecutive failed calls
+	openUntil time.Time // Zero while the circuit is closed
+	probing   bool
+}
+
+// Guard wraps a notifier in a circuit breaker opening after threshold
+// consecutive failed calls
+func Guard(notifier Notifier, threshold int, cooldown time.Duration, logf, warnf func(format string, args ...interface{})) Notifier {
+	return &breakerNotifier{Notifier: notifier, threshold: threshold, cooldown: cooldown, logf: logf, warnf: warnf}
+}
+
+// Notify calls the wrapped notifier unless the circuit is open
+func (n *breakerNotifier) Notify(ctx context.Context, event Event) error {
+	n.mu.Lock()
+	if !n.openUntil.IsZero() {
+		if wait := time.Until(n.openUntil); wait > 0 || n.probing {
+			n.mu.Unlock()
+			return fmt.Errorf("%w, next try in %v", ErrCircuitOpen, max(wait, 0).Round(time.Second))
+		}
+		n.probing = true
+	}
+	n.mu.Unlock()
+
+	err := n.Notifier.Notify(ctx, event)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	wasOpen := !n.openUntil.IsZero()
+	n.probing = false
+	if err == nil {
+		if wasOpen {
+			n.logf("%s works again, no longer skipping it", n.Name())
+		}
+		n.failures = 0
+		n.openUntil = time.Time{}
+		return nil
+	}
+
+	n.failures++
+	if wasOpen || n.failures >= n.threshold {
+		n.openUntil = time.Now().Add(n.cooldown)
+		n.warnf("%s failed %d times in a row, skipping it for %v: %v", n.Name(), n.failures, n.cooldown, err)
+	}
+	return err
+}
+
+// Accepts keeps the event filtering of the wrapped notifier
+func (n *breakerNotifier) Accepts(event Event) bool {
+	return Accepts(n.Notifier, event)
+}
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DeadLetter is an event a channel failed to deliver after all attempts
+type DeadLetter struct {
+	ID       int64     `json:"id"`
+	Channel  string    `json:"channel"`
+	Event    Event     `json:"event"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+	Attempts int       `json:"attempts"` // Deliveries attempted, including resends
+}
+
+// DeadLetters keeps failed deliveries in a JSON lines file until they are
+// resent
+type DeadLetters struct {
+	path string
+
+	mu     sync.Mutex
+	nextID int64
+}
+
+// OpenDeadLetters opens the dead letters at path, creating the file on the
+// first Add
+func OpenDeadLetters(path string) (*DeadLetters, error) {
+	d := &DeadLetters{path: path, nextID: 1}
+	letters, err := d.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, letter := range letters {
+		d.nextID = max(d.nextID, letter.ID+1)
+	}
+	return d, nil
+}
+
+// Add records a failed delivery, assigning its ID
+func (d *DeadLetters) Add(letter DeadLetter) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	letter.ID = d.nextID
+	d.nextID++
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+	file, err := os.OpenFile(d.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, spoolFilePerm)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open dead letters: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return 0, fmt.Errorf("failed to write dead letters: %w", err)
+	}
+	return letter.ID, nil
+}
+
+// List returns the failed deliveries, oldest first
+func (d *DeadLetters) List() ([]DeadLetter, error) {
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+
+	var letters []DeadLetter
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var letter DeadLetter
+		// A line cut short by a crash is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			continue
+		}
+		letters = append(letters, letter)
+	}
+	return letters, nil
+}
+
+// Update rewrites the file with the changed letters replaced and without
+// the removed ones, e.g. after a resend. Letters added meanwhile are kept.
+func (d *DeadLetters) Update(changed []DeadLetter, removed []int64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current, err := d.List()
+	if err != nil {
+		return err
+	}
+	var letters []DeadLetter
+	for _, letter := range current {
+		if slices.Contains(removed, letter.ID) {
+			continue
+		}
+		if i := slices.IndexFunc(changed, func(c DeadLetter) bool { return c.ID == letter.ID }); i >= 0 {
+			letter = changed[i]
+		}
+		letters = append(letters, letter)
+	}
+
+	if len(letters) == 0 {
+		if err := os.Remove(d.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to update dead letters: %w", err)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, letter := range letters {
+		data, err := json.Marshal(letter)
+		if err != nil {
+			return fmt.Errorf("failed to marshal dead letter: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	if err := replaceFile(d.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to update dead letters: %w", err)
+	}
+	return nil
+}
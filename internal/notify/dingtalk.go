@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/dingtalk"
+)
+
+// DingTalkNotifier renders events as DingTalk robot messages
+type DingTalkNotifier struct {
+	client dingtalk.Client
+}
+
+// NewDingTalkNotifier creates a DingTalk notifier
+func NewDingTalkNotifier(client dingtalk.Client) *DingTalkNotifier {
+	return &DingTalkNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *DingTalkNotifier) Name() string {
+	return "DingTalk"
+}
+
+// Notify posts the event to the DingTalk group
+func (n *DingTalkNotifier) Notify(ctx context.Context, event Event) error {
+	title, text := config.BuildDingTalkMessage(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		title, text = config.BuildHookFailureDingTalkMessage(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		title, text = config.BuildCatchUpDingTalkMessage(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		title, text = config.BuildLifecycleDingTalkMessage(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		title, text = config.BuildFetchFailureDingTalkMessage(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	}
+	if event.Digest != nil {
+		title, text = config.BuildDigestTitle(), config.BuildDigestText(n.Name(), *event.Digest)
+	}
+
+	return n.client.Send(ctx, dingtalk.Message{
+		Title: config.ChannelText(n.Name(), title),
+		Text:  config.ChannelText(n.Name(), withReference(text, event)),
+	})
+}
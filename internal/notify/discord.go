@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/discord"
+)
+
+// DiscordNotifier renders events as Discord embeds
+type DiscordNotifier struct {
+	client discord.Client
+}
+
+// NewDiscordNotifier creates a Discord notifier
+func NewDiscordNotifier(client discord.Client) *DiscordNotifier {
+	return &DiscordNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *DiscordNotifier) Name() string {
+	return "Discord"
+}
+
+// Notify sends the event as a Discord embed
+func (n *DiscordNotifier) Notify(ctx context.Context, event Event) error {
+	card := config.BuildDiscordCard(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		card = config.BuildHookFailureDiscordCard(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		card = config.BuildCatchUpDiscordCard(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		card = config.BuildLifecycleDiscordCard(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		card = config.BuildFetchFailureDiscordCard(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	}
+	if event.Digest != nil {
+		card = config.BuildDigestCard(n.Name(), *event.Digest)
+	}
+	if ref := event.Reference(); ref != "" {
+		card.Footer += " · " + ref
+	}
+	card = config.ChannelCard(n.Name(), card)
+
+	embed := discord.Embed{
+		Title:     card.Title,
+		Color:     card.Color,
+		Footer:    card.Footer,
+		Timestamp: event.Timestamp,
+	}
+	for _, field := range card.Fields {
+		embed.Fields = append(embed.Fields, discord.EmbedField(field))
+	}
+
+	return n.client.Send(ctx, discord.Message{Embeds: []discord.Embed{embed}})
+}
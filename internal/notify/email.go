@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/email"
+	"public-ip-monitor/pkg/openpgp"
+)
+
+// EmailNotifier renders events as plain-text emails
+type EmailNotifier struct {
+	client email.Client
+	to     string
+	key    *openpgp.PublicKey // Bodies are encrypted to it when set
+}
+
+// NewEmailNotifier creates an email notifier sending to the given recipient.
+// With a key, bodies are sent as inline OpenPGP messages; the subject stays
+// readable and carries no IPs.
+func NewEmailNotifier(client email.Client, to string, key *openpgp.PublicKey) *EmailNotifier {
+	return &EmailNotifier{client: client, to: to, key: key}
+}
+
+// Name returns the channel name
+func (n *EmailNotifier) Name() string {
+	return "Email"
+}
+
+// Notify sends the event as an email
+func (n *EmailNotifier) Notify(ctx context.Context, event Event) error {
+	subject := config.BuildEmailSubject()
+	body := config.BuildCombinedEmailBody(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		subject = config.BuildHookFailureEmailSubject()
+		body = config.BuildHookFailureEmailBody(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		subject = config.BuildCatchUpEmailSubject()
+		body = config.BuildCatchUpEmailBody(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		subject = config.BuildLifecycleEmailSubject(*event.Lifecycle)
+		body = config.BuildLifecycleEmailBody(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		subject = config.BuildFetchFailureEmailSubject(event.Type == TypeFetchRecovered)
+		body = config.BuildFetchFailureEmailBody(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	case TypeFailover:
+		subject = config.BuildFailoverEmailSubject()
+	case TypeHostedExit:
+		subject = config.BuildHostedExitEmailSubject()
+	case TypeNetworkChanged:
+		subject = config.BuildNetworkChangedEmailSubject()
+	case TypeUnusualActivity:
+		subject = config.BuildUnusualActivityEmailSubject()
+	case TypeUnexpectedIP:
+		subject = config.BuildUnexpectedIPEmailSubject()
+	case TypeOutsideGeofence:
+		subject = config.BuildGeofenceEmailSubject()
+		body = config.BuildGeofenceEmailBody(event.Changes, event.Timestamp, event.Gateway)
+	default:
+		if change, ok := event.Single(); ok {
+			body = config.BuildEmailBody(change.OldIP, change.NewIP, event.Timestamp, event.Gateway)
+		}
+	}
+	if event.Digest != nil {
+		subject = config.BuildDigestEmailSubject()
+		body = config.BuildDigestEmailBody(n.Name(), *event.Digest)
+	}
+
+	body = config.ChannelText(n.Name(), withReference(body, event))
+	if n.key != nil {
+		encrypted, err := openpgp.Encrypt(n.key, []byte(body))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt email: %w", err)
+		}
+		body = encrypted
+	}
+
+	return n.client.Send(ctx, email.Message{
+		To:      n.to,
+		Subject: config.ChannelText(n.Name(), subject),
+		Body:    body,
+	})
+}
@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Escalation is an event waiting to be acknowledged before it reaches the
+// secondary channels
+type Escalation struct {
+	Event Event     `json:"event"`
+	Due   time.Time `json:"due"`
+}
+
+// escalationState is the content of the escalations file
+type escalationState struct {
+	Pending          []Escalation `json:"pending"`
+	FailureEscalated bool         `json:"failure_escalated"` // A check failure reached the secondary channels, its recovery not yet
+}
+
+// Escalations keeps the events waiting to be acknowledged in a JSON file,
+// so that they are escalated after a restart as well
+type Escalations struct {
+	path string
+	acks *Acks
+
+	mu    sync.Mutex
+	state escalationState
+}
+
+// OpenEscalations loads the escalations pending at path. Events
+// acknowledged in acks are not escalated.
+func OpenEscalations(path string, acks *Acks) (*Escalations, error) {
+	e := &Escalations{path: path, acks: acks}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return e, nil
+		}
+		return nil, fmt.Errorf("failed to read escalations: %w", err)
+	}
+	if err := json.Unmarshal(data, &e.state); err != nil {
+		return nil, fmt.Errorf("failed to parse escalations: %w", err)
+	}
+	return e, nil
+}
+
+// Add escalates the event at due unless it is acknowledged before
+func (e *Escalations) Add(event Event, due time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.state.Pending = append(e.state.Pending, Escalation{Event: event, Due: due})
+	return e.save()
+}
+
+// Pending returns the escalations not due yet
+func (e *Escalations) Pending() []Escalation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Escalation(nil), e.state.Pending...)
+}
+
+// Due removes the escalations due at now and returns those to send and
+// those acknowledged meanwhile
+func (e *Escalations) Due(now time.Time) (escalate, acked []Escalation, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var pending []Escalation
+	for _, escalation := range e.state.Pending {
+		if now.Before(escalation.Due) {
+			pending = append(pending, escalation)
+			continue
+		}
+		done, err := e.acks.Acked(escalation.Event.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if done {
+			acked = append(acked, escalation)
+			continue
+		}
+		escalate = append(escalate, escalation)
+		if escalation.Event.Type == TypeFetchFailed {
+			e.state.FailureEscalated = true
+		}
+	}
+	if len(escalate) == 0 && len(acked) == 0 {
+		return nil, nil, nil
+	}
+	e.state.Pending = pending
+	return escalate, acked, e.save()
+}
+
+// Recovered drops the pending escalations of check failures, which
+// resolved themselves, and reports whether a failure was escalated
+// already, in which case the recovery has to be escalated as well
+func (e *Escalations) Recovered() (dropped int, escalated bool, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var pending []Escalation
+	for _, escalation := range e.state.Pending {
+		if escalation.Event.Type == TypeFetchFailed {
+			dropped++
+			continue
+		}
+		pending = append(pending, escalation)
+	}
+	escalated = e.state.FailureEscalated
+	if dropped == 0 && !escalated {
+		return 0, false, nil
+	}
+	e.state.Pending = pending
+	e.state.FailureEscalated = false
+	return dropped, escalated, e.save()
+}
+
+// save writes the escalations, removing the file when none are left
+func (e *Escalations) save() error {
+	if len(e.state.Pending) == 0 && !e.state.FailureEscalated {
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to update escalations: %w", err)
+		}
+		return nil
+	}
+	data, err := json.Marshal(e.state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal escalations: %w", err)
+	}
+	if err := replaceFile(e.path, data); err != nil {
+		return fmt.Errorf("failed to update escalations: %w", err)
+	}
+	return nil
+}
+
+// secondaryNotifier only takes escalated events
+type secondaryNotifier struct {
+	Notifier
+}
+
+// Secondary makes a notifier an escalation channel, which only gets the
+// events not acknowledged in time
+func Secondary(notifier Notifier) Notifier {
+	return &secondaryNotifier{Notifier: notifier}
+}
+
+// Accepts takes escalated events the wrapped notifier accepts
+func (n *secondaryNotifier) Accepts(event Event) bool {
+	return event.Escalated && Accepts(n.Notifier, event)
+}
@@ -0,0 +1,299 @@
+package notify
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"public-ip-monitor/internal/config"
+)
+
+// Type identifies what an event reports
+type Type string
+
+const (
+	TypeIPChanged       Type = "ip_changed"       // The public IP changed
+	TypeFailover        Type = "failover"         // The IP changed because traffic moved to a backup WAN
+	TypeHostedExit      Type = "hosted_exit"      // The new IP belongs to a hosting or VPN provider rather than the ISP
+	TypeNetworkChanged  Type = "network_changed"  // The new IP is announced by another network (ASN) than the old one
+	TypeUnusualActivity Type = "unusual_activity" // The IP changes far more often than usual
+	TypeOutsideGeofence Type = "outside_geofence" // The new IP is located outside the expected countries
+	TypeUnexpectedIP    Type = "unexpected_ip"    // The new IP is outside the expected ranges, and nothing else explains it
+	TypeCatchUp         Type = "catch_up"         // Changes found on startup that happened while not running
+	TypeHookFailed      Type = "hook_failed"      // On-change hook commands failed
+
+	TypeFetchFailed    Type = "fetch_failed"    // Checks kept failing, e.g. all IP services unreachable
+	TypeFetchRecovered Type = "fetch_recovered" // Checks succeed again after TypeFetchFailed
+
+	TypeStarted   Type = config.LifecycleStarted   // The monitor started
+	TypeStopped   Type = config.LifecycleStopped   // The monitor is shutting down
+	TypeHeartbeat Type = config.LifecycleHeartbeat // The monitor is alive, sent on a schedule
+)
+
+// Severity indicates how urgently an event needs attention
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Escalated returns the next higher severity; critical stays critical
+func (s Severity) Escalated() Severity {
+	switch s {
+	case SeverityInfo:
+		return SeverityWarning
+	default:
+		return SeverityCritical
+	}
+}
+
+// Event is the single source of truth passed to every notifier, which
+// renders it in whatever form suits the channel
+type Event struct {
+	ID           string // Correlation ID, the same in every channel's message and log line for the event
+	Type         Type
+	Severity     Severity
+	Changes      []config.IPChange      // One entry per address family / WAN
+	HookFailures []config.HookFailure   // Set for TypeHookFailed
+	Failures     []config.CheckFailure  // Set for TypeFetchFailed and TypeFetchRecovered, and TypeCatchUp after an outage
+	Gateway      *config.GatewayContext // Default gateway at the time of the event, if detected
+	Site         string                 // Name of the monitored location, e.g. the hostname
+	Timestamp    time.Time
+	Enrichment   map[string]string       // Additional details about the new IP, by name
+	Escalated    bool                    // Sent to the escalation channels after nobody acknowledged it
+	Replay       bool                    // Sent again by "events replay", e.g. to backfill a new consumer
+	Lifecycle    *config.LifecycleStatus // Set for TypeStarted, TypeStopped and TypeHeartbeat
+	Digest       *config.Digest          // Every change summarized by a change event, for channels rendering digests
+}
+
+// NewChangeEvent creates an event for IP changes seen while monitoring
+func NewChangeEvent(changes []config.IPChange, gateway *config.GatewayContext, timestamp time.Time) Event {
+	event := Event{
+		ID:        newEventID(),
+		Type:      TypeIPChanged,
+		Severity:  SeverityInfo,
+		Changes:   changes,
+		Gateway:   gateway,
+		Timestamp: timestamp,
+	}
+	switch {
+	case hasOutsideGeofence(changes):
+		event.Type = TypeOutsideGeofence
+		event.Severity = SeverityCritical
+	case hasFailover(changes):
+		event.Type = TypeFailover
+		event.Severity = SeverityWarning
+	case hasHostedExit(changes):
+		event.Type = TypeHostedExit
+		event.Severity = SeverityWarning
+	case hasNetworkChange(changes):
+		event.Type = TypeNetworkChanged
+		event.Severity = SeverityWarning
+	case hasUnusualActivity(changes):
+		event.Type = TypeUnusualActivity
+		event.Severity = SeverityWarning
+	case hasUnexpectedIP(changes):
+		event.Type = TypeUnexpectedIP
+	}
+	if hasUnexpectedIP(changes) {
+		event.Severity = event.Severity.Escalated()
+	}
+	return event
+}
+
+// NewCatchUpEvent creates an event for what happened while the monitor was not running
+func NewCatchUpEvent(changes []config.IPChange, gateway *config.GatewayContext, timestamp time.Time) Event {
+	event := Event{
+		ID:        newEventID(),
+		Type:      TypeCatchUp,
+		Severity:  SeverityInfo,
+		Changes:   changes,
+		Gateway:   gateway,
+		Timestamp: timestamp,
+	}
+	switch {
+	case hasOutsideGeofence(changes):
+		event.Severity = SeverityCritical
+	case hasFailover(changes) || hasHostedExit(changes) || hasNetworkChange(changes) || hasUnusualActivity(changes):
+		event.Severity = SeverityWarning
+	}
+	if hasUnexpectedIP(changes) {
+		event.Severity = event.Severity.Escalated()
+	}
+	// The outage a catch-up waited out is reported with it, not separately
+	for _, change := range changes {
+		if change.Outage != nil {
+			event.Failures = append(event.Failures, *change.Outage)
+		}
+	}
+	return event
+}
+
+// NewHookFailureEvent creates an event for failed on-change hooks
+func NewHookFailureEvent(failures []config.HookFailure, timestamp time.Time) Event {
+	return Event{
+		ID:           newEventID(),
+		Type:         TypeHookFailed,
+		Severity:     SeverityWarning,
+		HookFailures: failures,
+		Timestamp:    timestamp,
+	}
+}
+
+// Single returns the only change of an event that carries nothing beyond
+// the old and new IP, for channels with a dedicated single-change template
+func (e Event) Single() (config.IPChange, bool) {
+	if len(e.Changes) != 1 || !e.Changes[0].Plain() {
+		return config.IPChange{}, false
+	}
+	return e.Changes[0], true
+}
+
+// hasFailover reports whether any of the changes is a failover to a backup WAN
+func hasFailover(changes []config.IPChange) bool {
+	for _, change := range changes {
+		if change.FailoverTo != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHostedExit reports whether any of the changes leaves through a hosting
+// or VPN provider
+func hasHostedExit(changes []config.IPChange) bool {
+	for _, change := range changes {
+		if change.HostedExit != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNetworkChange reports whether any of the changes moved to another
+// network
+func hasNetworkChange(changes []config.IPChange) bool {
+	for _, change := range changes {
+		if change.PreviousASN != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasUnusualActivity reports whether any of the changes is part of
+// unusually frequent changes
+func hasUnusualActivity(changes []config.IPChange) bool {
+	for _, change := range changes {
+		if change.UnusualActivity != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOutsideGeofence reports whether any of the new IPs is located outside
+// the expected countries
+func hasOutsideGeofence(changes []config.IPChange) bool {
+	for _, change := range changes {
+		if change.OutsideCountry != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasUnexpectedIP reports whether any of the new IPs is outside the
+// expected ranges
+func hasUnexpectedIP(changes []config.IPChange) bool {
+	for _, change := range changes {
+		if change.UnexpectedIP != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// NewFetchFailureEvent creates an event for checks that keep failing
+func NewFetchFailureEvent(failures []config.CheckFailure, timestamp time.Time) Event {
+	return Event{
+		ID:        newEventID(),
+		Type:      TypeFetchFailed,
+		Severity:  SeverityCritical,
+		Failures:  failures,
+		Timestamp: timestamp,
+	}
+}
+
+// NewFetchRecoveredEvent creates an event for checks working again after failing
+func NewFetchRecoveredEvent(failures []config.CheckFailure, timestamp time.Time) Event {
+	return Event{
+		ID:        newEventID(),
+		Type:      TypeFetchRecovered,
+		Severity:  SeverityInfo,
+		Failures:  failures,
+		Timestamp: timestamp,
+	}
+}
+
+// NewLifecycleEvent creates an event for the monitor starting, stopping or
+// sending its heartbeat
+func NewLifecycleEvent(status config.LifecycleStatus, timestamp time.Time) Event {
+	event := Event{
+		ID:        newEventID(),
+		Type:      Type(status.Kind),
+		Severity:  SeverityInfo,
+		Site:      status.Site,
+		Timestamp: timestamp,
+		Lifecycle: &status,
+	}
+	if status.Reason != "" {
+		event.Severity = SeverityCritical
+	}
+	return event
+}
+
+// IsLifecycle reports whether the event is about the monitor itself
+// rather than the network
+func (e Event) IsLifecycle() bool {
+	return e.Lifecycle != nil
+}
+
+// IsChange reports whether the event reports IP changes, which are merged
+// across address families
+func (e Event) IsChange() bool {
+	return e.Type == TypeIPChanged || e.Type == TypeFailover || e.Type == TypeHostedExit || e.Type == TypeNetworkChanged || e.Type == TypeUnusualActivity || e.Type == TypeOutsideGeofence || e.Type == TypeUnexpectedIP || e.Type == TypeCatchUp
+}
+
+// newEventID returns a random correlation ID, e.g. "3f9a1c07b2e4"
+func newEventID() string {
+	id := make([]byte, 6)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Reference returns the line identifying the event in messages, e.g.
+// "Ref: 3f9a1c07b2e4", or "" when disabled or for events spooled by older
+// versions
+func (e Event) Reference() string {
+	if e.ID == "" || !config.ReferenceEnabled() {
+		return ""
+	}
+	switch {
+	case e.Escalated:
+		return "Ref: " + e.ID + " (escalated, not acknowledged)"
+	case e.Replay:
+		return "Ref: " + e.ID + " (replay)"
+	}
+	return "Ref: " + e.ID
+}
+
+// withReference appends the event reference to a text message
+func withReference(text string, event Event) string {
+	if ref := event.Reference(); ref != "" {
+		return text + "\n\n" + ref
+	}
+	return text
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/file"
+)
+
+// FileNotifier writes events as single lines to a file or named pipe
+type FileNotifier struct {
+	client file.Client
+}
+
+// NewFileNotifier creates a file notifier
+func NewFileNotifier(client file.Client) *FileNotifier {
+	return &FileNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *FileNotifier) Name() string {
+	return "File"
+}
+
+// Notify writes the event as a single line
+func (n *FileNotifier) Notify(ctx context.Context, event Event) error {
+	text := buildLine(event)
+	if ref := event.Reference(); ref != "" {
+		text += " [" + ref + "]"
+	}
+	return n.client.Send(ctx, file.Message{Text: text})
+}
+
+// buildLine renders the event as a single line of plain text
+func buildLine(event Event) string {
+	switch event.Type {
+	case TypeHookFailed:
+		return config.BuildHookFailureFileMessage(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		return config.BuildCatchUpFileMessage(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		return config.BuildLifecycleFileMessage(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		return config.BuildFetchFailureFileMessage(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	default:
+		return config.BuildFileMessage(event.Changes, event.Timestamp, event.Gateway)
+	}
+}
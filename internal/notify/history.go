@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// EventHistory keeps the most recent events notified, so that they can be
+// looked up by ID when acknowledged. Events are appended to a JSON lines
+// file, which is rewritten without the dropped ones once it has grown a
+// quarter beyond the cap.
+type EventHistory struct {
+	path       string
+	maxEntries int
+
+	mu     sync.Mutex
+	events []Event // Oldest first
+	lines  int     // Events in the file, including dropped ones
+}
+
+// OpenEventHistory loads the event history from path, creating it on the
+// first Add
+func OpenEventHistory(path string, maxEntries int) (*EventHistory, error) {
+	h := &EventHistory{path: path, maxEntries: maxEntries}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read event history: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var event Event
+		// A line cut short by a crash is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		h.events = append(h.events, event)
+		h.lines++
+	}
+	h.trim()
+
+	return h, nil
+}
+
+// Add records a notified event
+func (h *EventHistory) Add(event Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.events = append(h.events, event)
+	h.trim()
+
+	if h.lines+1 > h.maxEntries+h.maxEntries/4 {
+		return h.rewrite()
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, spoolFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open event history: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write event history: %w", err)
+	}
+	h.lines++
+	return nil
+}
+
+// Find returns the event with the given ID
+func (h *EventHistory) Find(id string) (Event, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := len(h.events) - 1; i >= 0; i-- {
+		if h.events[i].ID == id {
+			return h.events[i], true
+		}
+	}
+	return Event{}, false
+}
+
+// List returns the events, oldest first
+func (h *EventHistory) List() []Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Event(nil), h.events...)
+}
+
+// trim drops the events beyond the cap
+func (h *EventHistory) trim() {
+	if h.maxEntries > 0 && len(h.events) > h.maxEntries {
+		h.events = append(h.events[:0], h.events[len(h.events)-h.maxEntries:]...)
+	}
+}
+
+// rewrite replaces the file with the kept events
+func (h *EventHistory) rewrite() error {
+	var buf bytes.Buffer
+	for _, event := range h.events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if err := replaceFile(h.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to rewrite event history: %w", err)
+	}
+	h.lines = len(h.events)
+	return nil
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/line"
+)
+
+// LineNotifier renders events as LINE text messages
+type LineNotifier struct {
+	client line.Client
+	recent int // Earlier addresses listed in change messages
+}
+
+// NewLineNotifier creates a LINE notifier. Change messages list up to
+// recent earlier addresses of the history.
+func NewLineNotifier(client line.Client, recent int) *LineNotifier {
+	return &LineNotifier{client: client, recent: recent}
+}
+
+// Name returns the channel name
+func (n *LineNotifier) Name() string {
+	return "LINE"
+}
+
+// Notify pushes the event to the LINE chat
+func (n *LineNotifier) Notify(ctx context.Context, event Event) error {
+	text := config.BuildLineMessage(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		text = config.BuildHookFailureLineMessage(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		text = config.BuildCatchUpLineMessage(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		text = config.BuildLifecycleLineMessage(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		text = config.BuildFetchFailureLineMessage(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	default:
+		text = config.AddRecentChanges(text, event.Changes, n.recent)
+	}
+	if event.Digest != nil {
+		text = config.BuildDigestMessage(n.Name(), *event.Digest)
+	}
+
+	return n.client.Send(ctx, line.Message{Text: config.ChannelText(n.Name(), withReference(text, event))})
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/matrix"
+)
+
+// MatrixNotifier renders events as Matrix room messages
+type MatrixNotifier struct {
+	client matrix.Client
+}
+
+// NewMatrixNotifier creates a Matrix notifier
+func NewMatrixNotifier(client matrix.Client) *MatrixNotifier {
+	return &MatrixNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *MatrixNotifier) Name() string {
+	return "Matrix"
+}
+
+// Notify posts the event to the Matrix room
+func (n *MatrixNotifier) Notify(ctx context.Context, event Event) error {
+	text, formatted := config.BuildMatrixMessage(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		text, formatted = config.BuildHookFailureMatrixMessage(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		text, formatted = config.BuildCatchUpMatrixMessage(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		text, formatted = config.BuildLifecycleMatrixMessage(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		text, formatted = config.BuildFetchFailureMatrixMessage(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	}
+	if event.Digest != nil {
+		text = config.BuildDigestMessage(n.Name(), *event.Digest)
+		formatted = "<p><strong>" + html.EscapeString(config.BuildDigestTitle()) + "</strong></p><p>" +
+			strings.ReplaceAll(html.EscapeString(config.BuildDigestText(n.Name(), *event.Digest)), "\n", "<br>") + "</p>"
+	}
+
+	if ref := event.Reference(); ref != "" {
+		formatted += "<p><small>" + html.EscapeString(ref) + "</small></p>"
+	}
+	txnID := fmt.Sprintf("public-ip-monitor-%s-%d", event.Type, event.Timestamp.UnixNano())
+	if event.ID != "" {
+		txnID = "public-ip-monitor-" + event.ID
+	}
+	// The homeserver drops a message reusing the transaction ID of one it
+	// already got, so an escalation or replay of the event needs its own
+	switch {
+	case event.Escalated:
+		txnID += "-escalated"
+	case event.Replay:
+		txnID += "-replay"
+	}
+
+	return n.client.Send(ctx, matrix.Message{
+		Text: config.ChannelText(n.Name(), withReference(text, event)),
+		HTML: config.ChannelHTML(n.Name(), formatted),
+		// Derived from the event so that retries are deduplicated by the homeserver
+		TxnID: txnID,
+	})
+}
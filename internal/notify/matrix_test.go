@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/matrix"
+)
+
+// recordingMatrix keeps the messages sent through it
+type recordingMatrix struct {
+	messages []matrix.Message
+}
+
+func (c *recordingMatrix) Send(ctx context.Context, message matrix.Message) error {
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+func (c *recordingMatrix) Close() error {
+	return nil
+}
+
+// TestMatrixTxnIDs checks that a retry reuses the transaction ID, which the
+// homeserver deduplicates, while an escalation or replay of the same event
+// gets its own so that it is not dropped
+func TestMatrixTxnIDs(t *testing.T) {
+	client := &recordingMatrix{}
+	notifier := NewMatrixNotifier(client)
+	event := NewChangeEvent([]config.IPChange{{Family: "IPv4", OldIP: "203.0.113.1", NewIP: "198.51.100.7"}}, nil, time.Now())
+	event.ID = "3f9a1c07b2e4"
+
+	escalated, replay := event, event
+	escalated.Escalated = true
+	replay.Replay = true
+	for _, e := range []Event{event, event, escalated, replay} {
+		if err := notifier.Notify(context.Background(), e); err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+
+	want := []string{
+		"public-ip-monitor-3f9a1c07b2e4",
+		"public-ip-monitor-3f9a1c07b2e4",
+		"public-ip-monitor-3f9a1c07b2e4-escalated",
+		"public-ip-monitor-3f9a1c07b2e4-replay",
+	}
+	for i, message := range client.messages {
+		if message.TxnID != want[i] {
+			t.Errorf("message %d TxnID = %q, want %q", i+1, message.TxnID, want[i])
+		}
+	}
+}
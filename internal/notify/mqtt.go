@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/mqtt"
+)
+
+// MQTTNotifier publishes the current IP as retained state and events as JSON
+type MQTTNotifier struct {
+	client     mqtt.Client
+	topic      string
+	eventTopic string
+}
+
+// NewMQTTNotifier creates an MQTT notifier. The current IP is published to
+// topic, events to eventTopic unless it is empty.
+func NewMQTTNotifier(client mqtt.Client, topic, eventTopic string) *MQTTNotifier {
+	return &MQTTNotifier{client: client, topic: topic, eventTopic: eventTopic}
+}
+
+// Name returns the channel name
+func (n *MQTTNotifier) Name() string {
+	return "MQTT"
+}
+
+// mqttEvent is the JSON payload published to the event topic
+type mqttEvent struct {
+	ID        string       `json:"id,omitempty"`
+	Event     string       `json:"event"`
+	Severity  string       `json:"severity"`
+	Site      string       `json:"site"`
+	Timestamp time.Time    `json:"timestamp"`
+	Changes   []mqttChange `json:"changes"`
+	Text      string       `json:"text"`
+	Replay    bool         `json:"replay,omitempty"`
+}
+
+type mqttChange struct {
+	Family string `json:"family"`
+	WAN    string `json:"wan,omitempty"`
+	OldIP  string `json:"old_ip"`
+	NewIP  string `json:"new_ip"`
+	Topic  string `json:"topic"`
+}
+
+// Notify publishes the new IPs and the event
+func (n *MQTTNotifier) Notify(ctx context.Context, event Event) error {
+	var messages []mqtt.Message
+
+	payload := mqttEvent{
+		ID:        event.ID,
+		Event:     string(event.Type),
+		Severity:  string(event.Severity),
+		Site:      event.Site,
+		Timestamp: event.Timestamp,
+		Changes:   make([]mqttChange, 0, len(event.Changes)),
+		Text:      buildLine(event),
+		Replay:    event.Replay,
+	}
+	for _, change := range event.Changes {
+		topic := stateTopic(n.topic, change)
+		// A replay must not put a past IP back into the retained state
+		if !event.Replay {
+			messages = append(messages, mqtt.Message{Topic: topic, Payload: []byte(change.NewIP), Retain: true})
+		}
+		payload.Changes = append(payload.Changes, mqttChange{
+			Family: change.Family,
+			WAN:    change.WAN,
+			OldIP:  change.OldIP,
+			NewIP:  change.NewIP,
+			Topic:  topic,
+		})
+	}
+
+	if n.eventTopic != "" {
+		var data bytes.Buffer
+		encoder := json.NewEncoder(&data)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(payload); err != nil {
+			return fmt.Errorf("failed to marshal MQTT event: %w", err)
+		}
+		messages = append(messages, mqtt.Message{Topic: n.eventTopic, Payload: bytes.TrimSpace(data.Bytes())})
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+	return n.client.Send(ctx, messages...)
+}
+
+// stateTopic returns the topic holding the current IP of a family and WAN,
+// e.g. "public-ip-monitor/ip", ".../ip/ipv6" or ".../ip/backup/ipv4"
+func stateTopic(base string, change config.IPChange) string {
+	topic := base
+	if change.WAN != "" {
+		topic += "/" + change.WAN
+	}
+	if change.Family != "" && change.Family != "IP" {
+		topic += "/" + strings.ToLower(change.Family)
+	}
+	return topic
+}
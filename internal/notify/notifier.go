@@ -0,0 +1,24 @@
+package notify
+
+import "context"
+
+// Notifier delivers events through a single channel
+type Notifier interface {
+	// Name identifies the channel in logs, e.g. "Email"
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+// Filter is implemented by notifiers that only handle some events
+type Filter interface {
+	Accepts(event Event) bool
+}
+
+// Accepts reports whether the notifier handles the event
+func Accepts(notifier Notifier, event Event) bool {
+	filter, ok := notifier.(Filter)
+	if !ok {
+		return true
+	}
+	return filter.Accepts(event)
+}
@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/ntfy"
+)
+
+// NtfyNotifier renders events as ntfy push notifications
+type NtfyNotifier struct {
+	client ntfy.Client
+}
+
+// NewNtfyNotifier creates an ntfy notifier
+func NewNtfyNotifier(client ntfy.Client) *NtfyNotifier {
+	return &NtfyNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *NtfyNotifier) Name() string {
+	return "ntfy"
+}
+
+// Notify publishes the event to the ntfy topic
+func (n *NtfyNotifier) Notify(ctx context.Context, event Event) error {
+	title, text := config.BuildNtfyMessage(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		title, text = config.BuildHookFailureNtfyMessage(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		title, text = config.BuildCatchUpNtfyMessage(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		title, text = config.BuildLifecycleNtfyMessage(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		title, text = config.BuildFetchFailureNtfyMessage(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	}
+	if event.Digest != nil {
+		title, text = config.BuildDigestTitle(), config.BuildDigestText(n.Name(), *event.Digest)
+	}
+
+	// Tags are shown as emoji by the ntfy apps
+	var tags []string
+	if config.EmojiEnabled(n.Name()) {
+		tags = []string{"rotating_light"}
+		if event.Severity != SeverityInfo {
+			tags = []string{"warning"}
+		}
+	}
+
+	return n.client.Send(ctx, ntfy.Message{Title: title, Text: config.ChannelText(n.Name(), withReference(text, event)), Tags: tags})
+}
@@ -0,0 +1,247 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// outboxRecord is a line of the outbox file
+type outboxRecord struct {
+	Seq      int64     `json:"seq"`
+	QueuedAt time.Time `json:"queued_at"`
+	Event    Event     `json:"event"`
+}
+
+// Outbox keeps the events of one endpoint until it confirmed them, for
+// at-least-once delivery in order. Events are appended to a JSON lines
+// file; a cursor file next to it holds the sequence number of the last
+// event delivered or discarded, so a restart resumes after it.
+type Outbox struct {
+	path       string
+	cursorPath string
+	maxAge     time.Duration // Older events are discarded rather than delivered late; 0 keeps them
+
+	mu      sync.Mutex
+	pending []outboxRecord // Oldest first
+	cursor  int64
+	nextSeq int64
+	lines   int // Records in the file, including delivered ones
+}
+
+// OpenOutbox loads the events left in the outbox at path
+func OpenOutbox(path string, maxAge time.Duration) (*Outbox, error) {
+	o := &Outbox{path: path, cursorPath: path + ".cursor", maxAge: maxAge}
+
+	data, err := os.ReadFile(o.cursorPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read outbox cursor: %w", err)
+	}
+	if text := strings.TrimSpace(string(data)); text != "" {
+		if o.cursor, err = strconv.ParseInt(text, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid outbox cursor %q", text)
+		}
+	}
+	o.nextSeq = o.cursor + 1
+
+	data, err = os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var record outboxRecord
+		// A line cut short by a crash is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		o.lines++
+		o.nextSeq = max(o.nextSeq, record.Seq+1)
+		if record.Seq > o.cursor {
+			o.pending = append(o.pending, record)
+		}
+	}
+	return o, nil
+}
+
+// Push appends an event, unless it is pending already (e.g. resent)
+func (o *Outbox) Push(event Event) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, record := range o.pending {
+		if event.ID != "" && record.Event.ID == event.ID {
+			return nil
+		}
+	}
+	record := outboxRecord{Seq: o.nextSeq, QueuedAt: time.Now(), Event: event}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event: %w", err)
+	}
+	file, err := os.OpenFile(o.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, spoolFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	o.pending = append(o.pending, record)
+	o.nextSeq++
+	o.lines++
+	return nil
+}
+
+// Len returns the number of events not delivered yet
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// Flush sends the pending events in order, stopping at the first failure
+// so that none overtakes another. Events older than the maximum age are
+// discarded instead. It returns how many were delivered and discarded.
+func (o *Outbox) Flush(ctx context.Context, send func(ctx context.Context, event Event) error) (delivered, discarded int, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for len(o.pending) > 0 {
+		record := o.pending[0]
+		if o.maxAge > 0 && time.Since(record.QueuedAt) > o.maxAge {
+			discarded++
+		} else if err = send(ctx, record.Event); err != nil {
+			break
+		} else {
+			delivered++
+		}
+		o.pending = o.pending[1:]
+		o.cursor = record.Seq
+		if saveErr := o.saveCursor(); saveErr != nil {
+			return delivered, discarded, saveErr
+		}
+	}
+	if compactErr := o.compact(); compactErr != nil && err == nil {
+		err = compactErr
+	}
+	return delivered, discarded, err
+}
+
+// saveCursor records the last event delivered or discarded
+func (o *Outbox) saveCursor() error {
+	if err := replaceFile(o.cursorPath, []byte(strconv.FormatInt(o.cursor, 10)+"\n")); err != nil {
+		return fmt.Errorf("failed to update outbox cursor: %w", err)
+	}
+	return nil
+}
+
+// compact drops the delivered events from the file once they make up most
+// of it
+func (o *Outbox) compact() error {
+	if o.lines <= 2*len(o.pending)+16 {
+		return nil
+	}
+	if len(o.pending) == 0 {
+		if err := os.Remove(o.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to compact outbox: %w", err)
+		}
+		o.lines = 0
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, record := range o.pending {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal outbox event: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if err := replaceFile(o.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to compact outbox: %w", err)
+	}
+	o.lines = len(o.pending)
+	return nil
+}
+
+// OutboxNotifier delivers the events of a notifier through an outbox: an
+// event is done for the notification queue once it is in the outbox, and
+// the outbox keeps sending it, after earlier ones, until the endpoint
+// confirms it or it is too old
+type OutboxNotifier struct {
+	Notifier
+	outbox *Outbox
+	label  string // Endpoint in log lines, e.g. "Webhook example.com"
+	logf   func(format string, args ...interface{})
+	warnf  func(format string, args ...interface{})
+
+	mu          sync.Mutex
+	unreachable bool // The last flush failed
+	reported    bool // Replays failing meanwhile were logged
+}
+
+// NewOutboxNotifier wraps a notifier in an outbox
+func NewOutboxNotifier(notifier Notifier, outbox *Outbox, label string, logf, warnf func(format string, args ...interface{})) *OutboxNotifier {
+	return &OutboxNotifier{Notifier: notifier, outbox: outbox, label: label, logf: logf, warnf: warnf}
+}
+
+// Notify queues the event in the outbox and sends what is pending. It only
+// fails when the event could not be stored.
+func (n *OutboxNotifier) Notify(ctx context.Context, event Event) error {
+	if err := n.outbox.Push(event); err != nil {
+		return err
+	}
+	if err := n.flush(ctx); err != nil {
+		n.warnf("%s unreachable, event %s queued for replay (%d pending): %v", n.label, event.ID, n.outbox.Len(), err)
+	}
+	return nil
+}
+
+// Replay sends the events left in the outbox, e.g. after the endpoint was
+// down or the monitor restarted
+func (n *OutboxNotifier) Replay(ctx context.Context) {
+	if err := n.flush(ctx); err != nil {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if !n.reported {
+			n.warnf("%s still unreachable, %d events queued for replay: %v", n.label, n.outbox.Len(), err)
+			n.reported = true
+		}
+	}
+}
+
+// flush sends the pending events, reporting discarded ones and the
+// endpoint working again
+func (n *OutboxNotifier) flush(ctx context.Context) error {
+	delivered, discarded, err := n.outbox.Flush(ctx, n.Notifier.Notify)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if discarded > 0 {
+		n.warnf("%s: discarded %d events older than the maximum age", n.label, discarded)
+	}
+	if err != nil {
+		n.unreachable = true
+		return err
+	}
+	if n.unreachable {
+		n.logf("%s reachable again, replayed %d events", n.label, delivered)
+	}
+	n.unreachable = false
+	n.reported = false
+	return nil
+}
+
+// Accepts keeps the event filtering of the wrapped notifier
+func (n *OutboxNotifier) Accepts(event Event) bool {
+	return Accepts(n.Notifier, event)
+}
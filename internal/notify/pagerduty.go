@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/pagerduty"
+)
+
+// PagerDutyNotifier triggers PagerDuty incidents for events
+type PagerDutyNotifier struct {
+	client      pagerduty.Client
+	severities  map[string]string
+	autoResolve bool
+}
+
+// NewPagerDutyNotifier creates a PagerDuty notifier. Severities maps event
+// severities to PagerDuty severities; with autoResolve, fetch failure
+// incidents are resolved on recovery and change incidents right away.
+func NewPagerDutyNotifier(client pagerduty.Client, severities map[string]string, autoResolve bool) *PagerDutyNotifier {
+	return &PagerDutyNotifier{client: client, severities: severities, autoResolve: autoResolve}
+}
+
+// Name returns the channel name
+func (n *PagerDutyNotifier) Name() string {
+	return "PagerDuty"
+}
+
+// Accepts reports whether the event is sent; recoveries only resolve
+// incidents when auto-resolve is enabled, and notices about the monitor
+// itself would only open incidents nobody needs to act on
+func (n *PagerDutyNotifier) Accepts(event Event) bool {
+	if event.IsLifecycle() {
+		return false
+	}
+	return event.Type != TypeFetchRecovered || n.autoResolve
+}
+
+// Notify triggers or resolves an incident for the event
+func (n *PagerDutyNotifier) Notify(ctx context.Context, event Event) error {
+	// Failures of the same site share an incident until they recover
+	failureKey := fmt.Sprintf("public-ip-monitor/%s/%s", event.Site, TypeFetchFailed)
+
+	message := pagerduty.Message{
+		Action:        pagerduty.ActionTrigger,
+		DedupKey:      fmt.Sprintf("public-ip-monitor/%s/%s/%d", event.Site, event.Type, event.Timestamp.UnixNano()),
+		Source:        event.Site,
+		Severity:      n.severity(event.Severity),
+		Component:     "public-ip-monitor",
+		Class:         string(event.Type),
+		Timestamp:     event.Timestamp,
+		CustomDetails: config.BuildPagerDutyDetails(event.Changes, event.Failures, event.Timestamp, event.Gateway),
+	}
+	if event.ID != "" {
+		// Resends of the same event update its incident instead of opening another
+		message.DedupKey = fmt.Sprintf("public-ip-monitor/%s/%s", event.Site, event.ID)
+		message.CustomDetails["event_id"] = event.ID
+	}
+	if event.Replay {
+		message.CustomDetails["replay"] = "true"
+	}
+
+	switch event.Type {
+	case TypeFetchRecovered:
+		return n.client.Send(ctx, pagerduty.Message{Action: pagerduty.ActionResolve, DedupKey: failureKey})
+	case TypeFetchFailed:
+		message.DedupKey = failureKey
+		message.Summary = config.ChannelText(n.Name(), config.BuildFetchFailurePagerDutySummary(event.Failures, event.Site))
+		return n.client.Send(ctx, message)
+	case TypeHookFailed:
+		message.Summary = config.ChannelText(n.Name(), config.BuildHookFailurePagerDutySummary(event.HookFailures, event.Site))
+		return n.client.Send(ctx, message)
+	case TypeCatchUp:
+		message.Summary = config.ChannelText(n.Name(), config.BuildCatchUpPagerDutySummary(event.Changes, event.Site))
+	default:
+		message.Summary = config.ChannelText(n.Name(), config.BuildPagerDutySummary(event.Changes, event.Site))
+	}
+
+	if err := n.client.Send(ctx, message); err != nil {
+		return err
+	}
+	if !n.autoResolve {
+		return nil
+	}
+	if err := n.client.Send(ctx, pagerduty.Message{Action: pagerduty.ActionResolve, DedupKey: message.DedupKey}); err != nil {
+		return err
+	}
+	// A catch-up after an outage stands in for the recovery event
+	if event.Type == TypeCatchUp && len(event.Failures) > 0 {
+		return n.client.Send(ctx, pagerduty.Message{Action: pagerduty.ActionResolve, DedupKey: failureKey})
+	}
+	return nil
+}
+
+// severity maps an event severity to a PagerDuty severity
+func (n *PagerDutyNotifier) severity(severity Severity) string {
+	if mapped, ok := n.severities[string(severity)]; ok {
+		return mapped
+	}
+	return string(severity)
+}
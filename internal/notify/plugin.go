@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"public-ip-monitor/internal/hooks"
+)
+
+// PluginNotifier passes events as JSON on standard input to an external
+// command, so that users can add channels without changing the monitor. A
+// non-zero exit fails the notification, which is then retried.
+type PluginNotifier struct {
+	runner  *hooks.Runner
+	command hooks.Command
+}
+
+// NewPluginNotifier creates a plugin notifier running command for every event
+func NewPluginNotifier(runner *hooks.Runner, command hooks.Command) *PluginNotifier {
+	return &PluginNotifier{runner: runner, command: command}
+}
+
+// Name returns the channel name
+func (n *PluginNotifier) Name() string {
+	return "Plugin " + n.command.Name
+}
+
+// Accepts reports whether the plugin handles the event. Plugins receive
+// every event and decide themselves from its type.
+func (n *PluginNotifier) Accepts(event Event) bool {
+	return true
+}
+
+// pluginEvent is the JSON document written to the plugin's standard input.
+// The top-level IPs are those of the first change.
+type pluginEvent struct {
+	ID         string            `json:"id,omitempty"`
+	Event      string            `json:"event"`
+	Severity   string            `json:"severity"`
+	Site       string            `json:"site"`
+	Timestamp  time.Time         `json:"timestamp"`
+	OldIP      string            `json:"old_ip,omitempty"`
+	NewIP      string            `json:"new_ip,omitempty"`
+	Family     string            `json:"family,omitempty"`
+	Changes    []pluginChange    `json:"changes"`
+	Failures   []pluginFailure   `json:"failures,omitempty"`
+	Text       string            `json:"text"`
+	Enrichment map[string]string `json:"enrichment,omitempty"`
+	Escalated  bool              `json:"escalated,omitempty"` // Sent to an escalation channel, nobody acknowledged it
+	Replay     bool              `json:"replay,omitempty"`    // Sent again by "events replay"
+}
+
+type pluginChange struct {
+	Family string `json:"family"`
+	WAN    string `json:"wan,omitempty"`
+	OldIP  string `json:"old_ip"`
+	NewIP  string `json:"new_ip"`
+}
+
+type pluginFailure struct {
+	Family string    `json:"family"`
+	WAN    string    `json:"wan,omitempty"`
+	Count  int       `json:"count"`
+	Since  time.Time `json:"since"`
+	Error  string    `json:"error"`
+}
+
+// Notify runs the plugin with the event on its standard input
+func (n *PluginNotifier) Notify(ctx context.Context, event Event) error {
+	payload := pluginEvent{
+		ID:         event.ID,
+		Event:      string(event.Type),
+		Severity:   string(event.Severity),
+		Site:       event.Site,
+		Timestamp:  event.Timestamp,
+		Changes:    make([]pluginChange, 0, len(event.Changes)),
+		Text:       buildLine(event),
+		Enrichment: event.Enrichment,
+		Escalated:  event.Escalated,
+		Replay:     event.Replay,
+	}
+	for _, change := range event.Changes {
+		payload.Changes = append(payload.Changes, pluginChange{
+			Family: change.Family,
+			WAN:    change.WAN,
+			OldIP:  change.OldIP,
+			NewIP:  change.NewIP,
+		})
+	}
+	if len(event.Changes) > 0 {
+		payload.OldIP = event.Changes[0].OldIP
+		payload.NewIP = event.Changes[0].NewIP
+		payload.Family = event.Changes[0].Family
+	}
+	for _, failure := range event.Failures {
+		payload.Failures = append(payload.Failures, pluginFailure{
+			Family: failure.Family,
+			WAN:    failure.WAN,
+			Count:  failure.Count,
+			Since:  failure.Since,
+			Error:  failure.Error,
+		})
+	}
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(payload); err != nil {
+		return fmt.Errorf("failed to marshal plugin event: %w", err)
+	}
+
+	command := n.command
+	command.Stdin = data.Bytes()
+	command.Env = []string{"EVENT=" + string(event.Type), "EVENT_ID=" + event.ID}
+
+	result := n.runner.Run(ctx, command)
+	if result.Failed() {
+		if output := result.Output(); output != "" {
+			return fmt.Errorf("plugin %s %v\n%s", result.Name, result.Err, output)
+		}
+		return fmt.Errorf("plugin %s %v", result.Name, result.Err)
+	}
+	return nil
+}
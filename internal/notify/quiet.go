@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"public-ip-monitor/internal/config"
+)
+
+// QuietHours is a daily window during which notifications are held back
+type QuietHours struct {
+	start, end int // Minutes after midnight; the window wraps midnight when end < start
+	location   *time.Location
+	urgent     []string
+}
+
+// NewQuietHours creates a quiet window from "HH:MM" times in the given
+// location. Urgent channels, by name, are never held back.
+func NewQuietHours(start, end string, location *time.Location, urgent []string) (*QuietHours, error) {
+	startMinutes, err := parseClock(start)
+	if err != nil {
+		return nil, err
+	}
+	endMinutes, err := parseClock(end)
+	if err != nil {
+		return nil, err
+	}
+	return &QuietHours{start: startMinutes, end: endMinutes, location: location, urgent: urgent}, nil
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Active reports whether t falls within the quiet window
+func (q *QuietHours) Active(t time.Time) bool {
+	t = t.In(q.location)
+	minutes := t.Hour()*60 + t.Minute()
+	if q.start <= q.end {
+		return minutes >= q.start && minutes < q.end
+	}
+	return minutes >= q.start || minutes < q.end
+}
+
+// Holds reports whether an event for the named channel is held back at t
+func (q *QuietHours) Holds(channel string, t time.Time) bool {
+	for _, name := range q.urgent {
+		if strings.EqualFold(name, channel) {
+			return false
+		}
+	}
+	return q.Active(t)
+}
+
+// Summarize combines held back events into as few as possible: one for all
+// IP changes, from the first old to the last new IP of each address, one
+// for all hook failures, the last check failure or recovery and the last
+// startup, shutdown or heartbeat notice. Addresses
+// that changed and changed back are left out. When there was more than one
+// change, the change event carries a digest of all of them, as of now.
+func Summarize(events []Event, now time.Time) []Event {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var (
+		changes      []config.IPChange
+		catchUp      bool
+		gw           *config.GatewayContext
+		enrichment   map[string]string
+		changedAt    time.Time
+		hookFailures []config.HookFailure
+		hookAt       time.Time
+		fetch        *Event
+		lifecycle    *Event
+		digest       []config.DigestChange
+	)
+	index := make(map[string]int)
+
+	for _, event := range events {
+		switch {
+		case event.IsChange():
+			catchUp = catchUp || event.Type == TypeCatchUp
+			if event.Gateway != nil {
+				gw = event.Gateway
+			}
+			if event.Enrichment != nil {
+				enrichment = event.Enrichment
+			}
+			for _, change := range event.Changes {
+				if change.OldIP != change.NewIP {
+					digest = append(digest, config.DigestChange{IPChange: change, ChangedAt: event.Timestamp})
+				}
+				if i, ok := index[change.Label()]; ok {
+					oldIP := changes[i].OldIP
+					changes[i] = change
+					changes[i].OldIP = oldIP
+					continue
+				}
+				index[change.Label()] = len(changes)
+				changes = append(changes, change)
+			}
+			changedAt = event.Timestamp
+		case event.Type == TypeHookFailed:
+			hookFailures = append(hookFailures, event.HookFailures...)
+			hookAt = event.Timestamp
+		case event.Type == TypeFetchFailed || event.Type == TypeFetchRecovered:
+			fetch = &event
+		case event.IsLifecycle():
+			lifecycle = &event
+		}
+	}
+
+	var summary []Event
+	var net []config.IPChange
+	for _, change := range changes {
+		if change.OldIP != change.NewIP {
+			net = append(net, change)
+		}
+	}
+	if len(net) > 0 {
+		event := NewChangeEvent(net, gw, changedAt)
+		if catchUp {
+			event = NewCatchUpEvent(net, gw, changedAt)
+		}
+		event.Enrichment = enrichment
+		if len(digest) > 1 {
+			event.Digest = buildDigest(digest, events[0].Site, now)
+		}
+		summary = append(summary, event)
+	}
+	if len(hookFailures) > 0 {
+		summary = append(summary, NewHookFailureEvent(hookFailures, hookAt))
+	}
+	if fetch != nil {
+		summary = append(summary, *fetch)
+	}
+	if lifecycle != nil {
+		summary = append(summary, *lifecycle)
+	}
+
+	for i := range summary {
+		summary[i].Site = events[0].Site
+	}
+	return summary
+}
+
+// buildDigest lists the changes in order with how long each new IP was
+// kept: until the next change of the same address, or now
+func buildDigest(changes []config.DigestChange, site string, now time.Time) *config.Digest {
+	for i := range changes {
+		changes[i].Held = now.Sub(changes[i].ChangedAt)
+		changes[i].Current = true
+		for _, next := range changes[i+1:] {
+			if next.Label() == changes[i].Label() {
+				changes[i].Held = next.ChangedAt.Sub(changes[i].ChangedAt)
+				changes[i].Current = false
+				break
+			}
+		}
+	}
+	return &config.Digest{Site: site, Since: changes[0].ChangedAt, Until: now, Changes: changes}
+}
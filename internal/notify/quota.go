@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"public-ip-monitor/internal/config"
+)
+
+// QuotaState tells how much of its daily quota a channel has left
+type QuotaState int
+
+const (
+	QuotaOK        QuotaState = iota
+	QuotaLow                  // Within the reserve: only important events are sent
+	QuotaExhausted            // Nothing is sent until the quota resets
+)
+
+// quotaFile is the JSON form of the counts of the current day
+type quotaFile struct {
+	Day    string         `json:"day"`    // e.g. "2025-06-08"
+	Counts map[string]int `json:"counts"` // By channel name in lower case
+}
+
+// Quotas counts the sends of channels with a daily quota, such as the
+// conversations a WhatsApp Business tier allows or an SMTP provider's daily
+// cap. The counts are kept in a file so that restarts do not reset them;
+// days start at midnight in the given location.
+type Quotas struct {
+	path     string
+	limits   map[string]int    // By channel name in lower case
+	names    map[string]string // Configured channel names, by lower case name
+	reserve  int               // Percent of each quota kept for important events
+	location *time.Location
+
+	mu     sync.Mutex
+	day    string
+	counts map[string]int
+}
+
+// OpenQuotas loads the counts of today from path. Limits are sends per day
+// by channel name; the reserve is the percentage of each limit kept for
+// important events.
+func OpenQuotas(path string, limits map[string]int, reservePercent int, location *time.Location) (*Quotas, error) {
+	q := &Quotas{
+		path:     path,
+		limits:   make(map[string]int),
+		names:    make(map[string]string),
+		reserve:  max(reservePercent, 0),
+		location: location,
+		counts:   make(map[string]int),
+	}
+	for name, limit := range limits {
+		q.limits[strings.ToLower(name)] = limit
+		q.names[strings.ToLower(name)] = name
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification quotas: %w", err)
+	}
+	var saved quotaFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse notification quotas %s: %w", path, err)
+	}
+	q.day = saved.Day
+	for name, count := range saved.Counts {
+		q.counts[name] = count
+	}
+	return q, nil
+}
+
+// Limited reports whether the channel has a quota
+func (q *Quotas) Limited(channel string) bool {
+	if q == nil {
+		return false
+	}
+	_, ok := q.limits[strings.ToLower(channel)]
+	return ok
+}
+
+// rollOver starts a new day's counts once the day changed
+func (q *Quotas) rollOver(now time.Time) {
+	if day := now.In(q.location).Format(time.DateOnly); day != q.day {
+		q.day = day
+		clear(q.counts)
+	}
+}
+
+// State returns how much of its quota the channel has left at now
+func (q *Quotas) State(channel string, now time.Time) QuotaState {
+	if q == nil {
+		return QuotaOK
+	}
+	key := strings.ToLower(channel)
+	limit, ok := q.limits[key]
+	if !ok {
+		return QuotaOK
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollOver(now)
+	used := q.counts[key]
+	switch {
+	case used >= limit:
+		return QuotaExhausted
+	case used >= limit-limit*q.reserve/100:
+		return QuotaLow
+	}
+	return QuotaOK
+}
+
+// Allows reports whether the channel may send the event at now. Within the
+// reserve, only IP changes and warnings are sent, so that heartbeats and
+// notices cannot use up the quota changes need.
+func (q *Quotas) Allows(channel string, event Event, now time.Time) bool {
+	switch q.State(channel, now) {
+	case QuotaExhausted:
+		return false
+	case QuotaLow:
+		return event.IsChange() || event.Severity != SeverityInfo
+	}
+	return true
+}
+
+// Record counts a send of the channel and saves the counts
+func (q *Quotas) Record(channel string, now time.Time) error {
+	key := strings.ToLower(channel)
+	if !q.Limited(key) {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollOver(now)
+	q.counts[key]++
+	data, err := json.Marshal(quotaFile{Day: q.day, Counts: q.counts})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification quotas: %w", err)
+	}
+	if err := replaceFile(q.path, data); err != nil {
+		return fmt.Errorf("failed to save notification quotas: %w", err)
+	}
+	return nil
+}
+
+// Usage returns the sends of today of every channel with a quota, by name
+func (q *Quotas) Usage(now time.Time) []config.QuotaUsage {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollOver(now)
+
+	usage := make([]config.QuotaUsage, 0, len(q.limits))
+	for key, limit := range q.limits {
+		usage = append(usage, config.QuotaUsage{Channel: q.names[key], Used: q.counts[key], Limit: limit})
+	}
+	slices.SortFunc(usage, func(a, b config.QuotaUsage) int {
+		return strings.Compare(a.Channel, b.Channel)
+	})
+	return usage
+}
+
+// ResetsAt returns when the quotas start over after now
+func (q *Quotas) ResetsAt(now time.Time) time.Time {
+	local := now.In(q.location)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, q.location)
+}
+
+// meterNotifier counts the sends of a channel against its quota
+type meterNotifier struct {
+	Notifier
+	quotas *Quotas
+	warnf  func(format string, args ...interface{})
+}
+
+// Meter wraps a notifier so that every event it delivers counts against
+// its quota, whichever path it is sent on
+func Meter(notifier Notifier, quotas *Quotas, warnf func(format string, args ...interface{})) Notifier {
+	if !quotas.Limited(notifier.Name()) {
+		return notifier
+	}
+	return &meterNotifier{Notifier: notifier, quotas: quotas, warnf: warnf}
+}
+
+// Notify calls the wrapped notifier and counts the send if it succeeded
+func (n *meterNotifier) Notify(ctx context.Context, event Event) error {
+	if err := n.Notifier.Notify(ctx, event); err != nil {
+		return err
+	}
+	if err := n.quotas.Record(n.Name(), time.Now()); err != nil {
+		n.warnf("%v", err)
+	}
+	return nil
+}
+
+// Accepts keeps the event filtering of the wrapped notifier
+func (n *meterNotifier) Accepts(event Event) bool {
+	return Accepts(n.Notifier, event)
+}
@@ -0,0 +1,29 @@
+package notify
+
+import "slices"
+
+// routedNotifier limits a notifier to some event types
+type routedNotifier struct {
+	Notifier
+	events []Type
+}
+
+// Route limits a notifier to the given event types, out of those it
+// accepts. Without any types the notifier is returned unchanged.
+func Route(notifier Notifier, events []string) Notifier {
+	if len(events) == 0 {
+		return notifier
+	}
+
+	routed := &routedNotifier{Notifier: notifier}
+	for _, event := range events {
+		routed.events = append(routed.events, Type(event))
+	}
+	return routed
+}
+
+// Accepts reports whether the event is one of the routed types and
+// accepted by the wrapped notifier
+func (n *routedNotifier) Accepts(event Event) bool {
+	return slices.Contains(n.events, event.Type) && Accepts(n.Notifier, event)
+}
@@ -0,0 +1,40 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/sheets"
+)
+
+// SheetsNotifier appends IP changes to a spreadsheet as rows
+type SheetsNotifier struct {
+	client sheets.Client
+}
+
+// NewSheetsNotifier creates a Google Sheets notifier
+func NewSheetsNotifier(client sheets.Client) *SheetsNotifier {
+	return &SheetsNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *SheetsNotifier) Name() string {
+	return "Google Sheets"
+}
+
+// Accepts reports whether the event produces any rows
+func (n *SheetsNotifier) Accepts(event Event) bool {
+	return len(config.BuildSheetsRows(event.Changes, event.Type == TypeCatchUp, event.Timestamp)) > 0
+}
+
+// Notify appends the event's changes to the spreadsheet
+func (n *SheetsNotifier) Notify(ctx context.Context, event Event) error {
+	rows := config.BuildSheetsRows(event.Changes, event.Type == TypeCatchUp, event.Timestamp)
+	if ref := event.Reference(); ref != "" {
+		for _, row := range rows {
+			// The note column
+			row[len(row)-1] += " [" + ref + "]"
+		}
+	}
+	return n.client.Send(ctx, sheets.Message{Rows: rows})
+}
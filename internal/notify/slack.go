@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/slack"
+)
+
+// SlackNotifier renders events as Slack mrkdwn messages
+type SlackNotifier struct {
+	client slack.Client
+}
+
+// NewSlackNotifier creates a Slack notifier
+func NewSlackNotifier(client slack.Client) *SlackNotifier {
+	return &SlackNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *SlackNotifier) Name() string {
+	return "Slack"
+}
+
+// Notify sends the event as a Slack message
+func (n *SlackNotifier) Notify(ctx context.Context, event Event) error {
+	text := config.BuildSlackMessage(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		text = config.BuildHookFailureSlackMessage(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		text = config.BuildCatchUpSlackMessage(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		text = config.BuildLifecycleSlackMessage(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		text = config.BuildFetchFailureSlackMessage(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	}
+	if event.Digest != nil {
+		text = config.BuildDigestMessage(n.Name(), *event.Digest)
+	}
+
+	return n.client.Send(ctx, slack.Message{Text: config.ChannelText(n.Name(), withReference(text, event))})
+}
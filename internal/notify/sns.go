@@ -0,0 +1,124 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/sns"
+)
+
+// SNSNotifier publishes events to an AWS SNS topic, which fans them out to
+// its SMS, email, Lambda and queue subscribers
+type SNSNotifier struct {
+	client sns.Client
+}
+
+// NewSNSNotifier creates an SNS notifier
+func NewSNSNotifier(client sns.Client) *SNSNotifier {
+	return &SNSNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *SNSNotifier) Name() string {
+	return "SNS"
+}
+
+// snsEvent is the JSON payload delivered to Lambda, SQS and HTTP(S) subscribers
+type snsEvent struct {
+	ID         string            `json:"id,omitempty"`
+	Event      string            `json:"event"`
+	Severity   string            `json:"severity"`
+	Site       string            `json:"site"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Changes    []snsChange       `json:"changes"`
+	Text       string            `json:"text"`
+	Enrichment map[string]string `json:"enrichment,omitempty"`
+	Replay     bool              `json:"replay,omitempty"`
+}
+
+type snsChange struct {
+	Family string `json:"family"`
+	WAN    string `json:"wan,omitempty"`
+	OldIP  string `json:"old_ip"`
+	NewIP  string `json:"new_ip"`
+}
+
+// Notify publishes the event to the topic
+func (n *SNSNotifier) Notify(ctx context.Context, event Event) error {
+	message := config.BuildSNSMessage(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		message = config.BuildHookFailureSNSMessage(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		message = config.BuildCatchUpSNSMessage(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		message = config.BuildLifecycleSNSMessage(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		message = config.BuildFetchFailureSNSMessage(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	case TypeFailover:
+		message.Subject = config.BuildFailoverEmailSubject()
+	case TypeHostedExit:
+		message.Subject = config.BuildHostedExitEmailSubject()
+	case TypeNetworkChanged:
+		message.Subject = config.BuildNetworkChangedEmailSubject()
+	case TypeUnusualActivity:
+		message.Subject = config.BuildUnusualActivityEmailSubject()
+	case TypeUnexpectedIP:
+		message.Subject = config.BuildUnexpectedIPEmailSubject()
+	case TypeOutsideGeofence:
+		message.Subject = config.BuildGeofenceEmailSubject()
+		message.Email = config.BuildGeofenceEmailBody(event.Changes, event.Timestamp, event.Gateway)
+	}
+	if event.Digest != nil {
+		// SMS keeps the single line of the summarized change
+		message.Subject = config.BuildDigestEmailSubject()
+		message.Default = config.BuildDigestMessage(n.Name(), *event.Digest)
+		message.Email = config.BuildDigestEmailBody(n.Name(), *event.Digest)
+	}
+
+	payload := snsEvent{
+		ID:         event.ID,
+		Event:      string(event.Type),
+		Severity:   string(event.Severity),
+		Site:       event.Site,
+		Timestamp:  event.Timestamp,
+		Changes:    make([]snsChange, 0, len(event.Changes)),
+		Text:       buildLine(event),
+		Enrichment: event.Enrichment,
+		Replay:     event.Replay,
+	}
+	for _, change := range event.Changes {
+		payload.Changes = append(payload.Changes, snsChange{
+			Family: change.Family,
+			WAN:    change.WAN,
+			OldIP:  change.OldIP,
+			NewIP:  change.NewIP,
+		})
+	}
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(payload); err != nil {
+		return fmt.Errorf("failed to marshal SNS event: %w", err)
+	}
+
+	return n.client.Send(ctx, sns.Message{
+		Subject: message.Subject,
+		Default: config.ChannelText(n.Name(), withReference(message.Default, event)),
+		Email:   config.ChannelText(n.Name(), withReference(message.Email, event)),
+		// SMS gateways often mangle emoji, so SMS texts never contain them
+		SMS:  config.ChannelText("SMS", message.SMS),
+		JSON: string(bytes.TrimSpace(data.Bytes())),
+		Attributes: map[string]string{
+			"event":    string(event.Type),
+			"severity": string(event.Severity),
+			"site":     event.Site,
+			"event_id": event.ID,
+		},
+	})
+}
@@ -0,0 +1,312 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// spoolFilePerm is the mode of the spool file
+const spoolFilePerm = 0644
+
+// SpoolEntry is a queued event with the channels it still has to reach
+type SpoolEntry struct {
+	ID       int64
+	Event    Event
+	Channels []string // Names of the channels left; every channel the event is routed to when empty
+}
+
+// spoolRecord is a line of the spool file. The first record of an entry
+// carries the event, later ones the channels left or that it is done.
+type spoolRecord struct {
+	ID       int64    `json:"id"`
+	Event    *Event   `json:"event,omitempty"`
+	Channels []string `json:"channels,omitempty"`
+	Done     bool     `json:"done,omitempty"`
+}
+
+// Spool is a notification queue kept in a JSON lines file, so that events
+// pending at shutdown or not delivered because channels were unreachable
+// are sent on the next start. Entries are handed out in order on Entries
+// and stay in the file until settled.
+type Spool struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[int64]*SpoolEntry // Not settled yet
+	queue   []SpoolEntry          // Not handed out yet
+	nextID  int64
+	lines   int // Records in the file
+
+	wake   chan struct{}
+	out    chan SpoolEntry
+	closed chan struct{}
+	once   sync.Once
+}
+
+// OpenSpool loads the entries left in the spool at path, which are handed
+// out first, and starts handing out entries
+func OpenSpool(path string) (*Spool, error) {
+	s := &Spool{
+		path:    path,
+		entries: make(map[int64]*SpoolEntry),
+		nextID:  1,
+		wake:    make(chan struct{}, 1),
+		out:     make(chan SpoolEntry),
+		closed:  make(chan struct{}),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read notification spool: %w", err)
+	}
+
+	var order []int64
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var record spoolRecord
+		// A line cut short by a crash is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		s.nextID = max(s.nextID, record.ID+1)
+		if record.Event != nil {
+			s.entries[record.ID] = &SpoolEntry{ID: record.ID, Event: *record.Event, Channels: record.Channels}
+			order = append(order, record.ID)
+			continue
+		}
+		entry, ok := s.entries[record.ID]
+		if !ok {
+			continue
+		}
+		if record.Done {
+			delete(s.entries, record.ID)
+		} else {
+			entry.Channels = record.Channels
+		}
+	}
+
+	for _, id := range order {
+		if entry, ok := s.entries[id]; ok {
+			s.queue = append(s.queue, *entry)
+		}
+	}
+	if err := s.rewrite(); err != nil {
+		return nil, err
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// Entries returns the channel entries are handed out on, in order. It is
+// closed by Close.
+func (s *Spool) Entries() <-chan SpoolEntry {
+	return s.out
+}
+
+// Len returns the number of entries not settled yet
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Push queues an event for every channel it is routed to. The event is
+// queued even if writing it to the file fails.
+func (s *Spool) Push(event Event) error {
+	return s.PushTo(event, nil)
+}
+
+// PushTo queues an event for the named channels only, e.g. an escalation
+func (s *Spool) PushTo(event Event, channels []string) error {
+	s.mu.Lock()
+	entry := SpoolEntry{ID: s.nextID, Event: event, Channels: channels}
+	s.nextID++
+	s.entries[entry.ID] = &entry
+	s.queue = append(s.queue, entry)
+	err := s.append(spoolRecord{ID: entry.ID, Event: &event, Channels: channels})
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return err
+}
+
+// Replace settles the entries with the given IDs and records event in
+// their place, e.g. changes of several address families merged into one.
+// The new entry is returned rather than handed out.
+func (s *Spool) Replace(ids []int64, event Event) (SpoolEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := SpoolEntry{ID: s.nextID, Event: event}
+	s.nextID++
+	s.entries[entry.ID] = &entry
+	// The new entry is written first so that a crash in between repeats
+	// the events rather than losing them
+	if err := s.append(spoolRecord{ID: entry.ID, Event: &event}); err != nil {
+		return entry, err
+	}
+	for _, id := range ids {
+		if err := s.settle(id, nil); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// Settle records the channels an entry still has to reach, e.g. those
+// that failed or hold it for quiet hours. The entry is done without any.
+func (s *Spool) Settle(id int64, channels []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.settle(id, channels)
+}
+
+// Delivered records that an entry reached the channel
+func (s *Spool) Delivered(id int64, channel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil
+	}
+	channels := slices.DeleteFunc(slices.Clone(entry.Channels), func(name string) bool { return name == channel })
+	return s.settle(id, channels)
+}
+
+// Close stops handing out entries and closes the Entries channel. Entries
+// not settled yet stay in the file for the next start.
+func (s *Spool) Close() {
+	s.once.Do(func() { close(s.closed) })
+}
+
+// run hands out queued entries until the spool is closed
+func (s *Spool) run() {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-s.closed:
+				return
+			}
+		}
+		entry := s.queue[0]
+		s.mu.Unlock()
+
+		select {
+		case s.out <- entry:
+			s.mu.Lock()
+			s.queue = s.queue[1:]
+			s.mu.Unlock()
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+// settle records the channels left of an entry, or that it is done
+func (s *Spool) settle(id int64, channels []string) error {
+	entry, ok := s.entries[id]
+	if !ok {
+		return nil
+	}
+	if len(channels) == 0 {
+		delete(s.entries, id)
+		// The file is rewritten once most of its records are settled
+		if s.lines > 64 && s.lines > 4*len(s.entries) {
+			return s.rewrite()
+		}
+		return s.append(spoolRecord{ID: id, Done: true})
+	}
+	entry.Channels = channels
+	return s.append(spoolRecord{ID: id, Channels: channels})
+}
+
+// append writes a record to the file
+func (s *Spool) append(record spoolRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, spoolFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open notification spool: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write notification spool: %w", err)
+	}
+	s.lines++
+	return nil
+}
+
+// rewrite replaces the file with the entries not settled yet, or removes
+// it when there are none
+func (s *Spool) rewrite() error {
+	if len(s.entries) == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rewrite notification spool: %w", err)
+		}
+		s.lines = 0
+		return nil
+	}
+
+	ids := make([]int64, 0, len(s.entries))
+	for id := range s.entries {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	var buf bytes.Buffer
+	for _, id := range ids {
+		entry := s.entries[id]
+		data, err := json.Marshal(spoolRecord{ID: id, Event: &entry.Event, Channels: entry.Channels})
+		if err != nil {
+			return fmt.Errorf("failed to marshal notification: %w", err)
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	if err := replaceFile(s.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to rewrite notification spool: %w", err)
+	}
+	s.lines = len(ids)
+	return nil
+}
+
+// replaceFile atomically replaces the file at path with data, so that a
+// crash leaves either the old or the new content
+func replaceFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), spoolFilePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
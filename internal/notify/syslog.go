@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/syslog"
+)
+
+// sdEnterprise is the enterprise number of the structured data IDs, the
+// example number of RFC 5612 as the project has none of its own
+const sdEnterprise = "@32473"
+
+// SyslogNotifier forwards change and failure events to a syslog collector,
+// e.g. for audit trails
+type SyslogNotifier struct {
+	client   syslog.Client
+	hostname string
+}
+
+// NewSyslogNotifier creates a syslog notifier. Messages carry the hostname,
+// or the site of the event when it is empty.
+func NewSyslogNotifier(client syslog.Client, hostname string) *SyslogNotifier {
+	return &SyslogNotifier{client: client, hostname: hostname}
+}
+
+// Name returns the channel name
+func (n *SyslogNotifier) Name() string {
+	return "Syslog"
+}
+
+// Accepts reports whether the event is sent; notices about the monitor
+// itself are not part of the audit trail
+func (n *SyslogNotifier) Accepts(event Event) bool {
+	return !event.IsLifecycle()
+}
+
+// Notify sends a message per change, so that every change is a record of
+// its own at the collector, or a single message for other events
+func (n *SyslogNotifier) Notify(ctx context.Context, event Event) error {
+	if len(event.Changes) == 0 {
+		return n.client.Send(ctx, n.message(event, nil))
+	}
+
+	messages := make([]syslog.Message, 0, len(event.Changes))
+	for _, change := range event.Changes {
+		single := event
+		single.Changes = []config.IPChange{change}
+
+		params := []syslog.Param{
+			{Name: "family", Value: change.Family},
+			{Name: "old_ip", Value: change.OldIP},
+			{Name: "new_ip", Value: change.NewIP},
+		}
+		if change.WAN != "" {
+			params = append(params, syslog.Param{Name: "wan", Value: change.WAN})
+		}
+		if change.ASN != "" {
+			params = append(params, syslog.Param{Name: "asn", Value: change.ASN})
+		}
+		messages = append(messages, n.message(single, &syslog.Element{ID: "ipchange" + sdEnterprise, Params: params}))
+	}
+	return n.client.Send(ctx, messages...)
+}
+
+// message renders the event with its identification and the given details
+func (n *SyslogNotifier) message(event Event, details *syslog.Element) syslog.Message {
+	hostname := n.hostname
+	if hostname == "" {
+		hostname = event.Site
+	}
+
+	origin := syslog.Element{ID: "event" + sdEnterprise, Params: []syslog.Param{
+		{Name: "type", Value: string(event.Type)},
+		{Name: "severity", Value: string(event.Severity)},
+		{Name: "site", Value: event.Site},
+	}}
+	if event.ID != "" {
+		origin.Params = append(origin.Params, syslog.Param{Name: "id", Value: event.ID})
+	}
+	if event.Replay {
+		origin.Params = append(origin.Params, syslog.Param{Name: "replay", Value: "true"})
+	}
+	elements := []syslog.Element{origin}
+	if details != nil {
+		elements = append(elements, *details)
+	}
+
+	return syslog.Message{
+		Severity:       syslogSeverity(event),
+		Timestamp:      event.Timestamp,
+		Hostname:       hostname,
+		MsgID:          string(event.Type),
+		StructuredData: elements,
+		Text:           buildLine(event),
+	}
+}
+
+// syslogSeverity maps the event severity; changes are notices rather than
+// mere information
+func syslogSeverity(event Event) int {
+	switch {
+	case event.Severity == SeverityCritical:
+		return syslog.SeverityCritical
+	case event.Severity == SeverityWarning:
+		return syslog.SeverityWarning
+	case event.IsChange():
+		return syslog.SeverityNotice
+	}
+	return syslog.SeverityInformational
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/teams"
+)
+
+// TeamsNotifier renders events as Microsoft Teams Adaptive Cards
+type TeamsNotifier struct {
+	client teams.Client
+}
+
+// NewTeamsNotifier creates a Teams notifier
+func NewTeamsNotifier(client teams.Client) *TeamsNotifier {
+	return &TeamsNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *TeamsNotifier) Name() string {
+	return "Teams"
+}
+
+// Notify sends the event as an Adaptive Card
+func (n *TeamsNotifier) Notify(ctx context.Context, event Event) error {
+	card := config.BuildTeamsCard(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		card = config.BuildHookFailureTeamsCard(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		card = config.BuildCatchUpTeamsCard(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		card = config.BuildLifecycleTeamsCard(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		card = config.BuildFetchFailureTeamsCard(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	}
+	if event.Digest != nil {
+		card = config.BuildDigestCard(n.Name(), *event.Digest)
+	}
+	if ref := event.Reference(); ref != "" {
+		card.Footer += " · " + ref
+	}
+	card = config.ChannelCard(n.Name(), card)
+
+	message := teams.Message{
+		Title:  card.Title,
+		Style:  teamsStyle(card.Color),
+		Footer: card.Footer,
+	}
+	for _, field := range card.Fields {
+		message.Facts = append(message.Facts, teams.Fact{Title: field.Name, Value: field.Value})
+	}
+
+	return n.client.Send(ctx, message)
+}
+
+// teamsStyle maps a card accent color to the closest Adaptive Card text color
+func teamsStyle(color int) string {
+	switch color {
+	case config.CardColorChange:
+		return "attention"
+	case config.CardColorCatchUp, config.CardColorWarning:
+		return "warning"
+	case config.CardColorHealthy:
+		return "good"
+	}
+	return ""
+}
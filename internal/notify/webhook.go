@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"context"
+	"os"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/webhook"
+)
+
+// WebhookNotifier passes events to a templated generic webhook
+type WebhookNotifier struct {
+	client webhook.Client
+}
+
+// NewWebhookNotifier creates a webhook notifier
+func NewWebhookNotifier(client webhook.Client) *WebhookNotifier {
+	return &WebhookNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *WebhookNotifier) Name() string {
+	return "Webhook"
+}
+
+// Notify sends the event's fields to the webhook template
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	hostname, _ := os.Hostname()
+	message := webhook.Message{
+		ID:         event.ID,
+		Event:      string(event.Type),
+		Severity:   string(event.Severity),
+		Changes:    make([]webhook.Change, 0, len(event.Changes)),
+		Timestamp:  event.Timestamp,
+		Hostname:   hostname,
+		Site:       event.Site,
+		Text:       config.ChannelText(n.Name(), buildLine(event)),
+		Enrichment: event.Enrichment,
+		Replay:     event.Replay,
+	}
+
+	for _, change := range event.Changes {
+		message.Changes = append(message.Changes, webhook.Change{
+			Family: change.Family,
+			OldIP:  change.OldIP,
+			NewIP:  change.NewIP,
+			WAN:    change.WAN,
+		})
+	}
+	if message.Enrichment == nil {
+		message.Enrichment = map[string]string{}
+	}
+	if len(message.Changes) > 0 {
+		message.Family = message.Changes[0].Family
+		message.OldIP = message.Changes[0].OldIP
+		message.NewIP = message.Changes[0].NewIP
+	}
+
+	return n.client.Send(ctx, message)
+}
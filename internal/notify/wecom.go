@@ -0,0 +1,43 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/wecom"
+)
+
+// WeComNotifier renders events as WeChat Work group bot messages
+type WeComNotifier struct {
+	client wecom.Client
+}
+
+// NewWeComNotifier creates a WeCom notifier
+func NewWeComNotifier(client wecom.Client) *WeComNotifier {
+	return &WeComNotifier{client: client}
+}
+
+// Name returns the channel name
+func (n *WeComNotifier) Name() string {
+	return "WeCom"
+}
+
+// Notify posts the event to the WeCom group
+func (n *WeComNotifier) Notify(ctx context.Context, event Event) error {
+	content := config.BuildWeComMessage(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		content = config.BuildHookFailureWeComMessage(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		content = config.BuildCatchUpWeComMessage(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		content = config.BuildLifecycleWeComMessage(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		content = config.BuildFetchFailureWeComMessage(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	}
+	if event.Digest != nil {
+		content = config.BuildDigestMessage(n.Name(), *event.Digest)
+	}
+
+	return n.client.Send(ctx, wecom.Message{Content: config.ChannelText(n.Name(), withReference(content, event))})
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+
+	"public-ip-monitor/internal/config"
+	"public-ip-monitor/pkg/whatsapp"
+)
+
+// WhatsAppNotifier renders events as WhatsApp text messages
+type WhatsAppNotifier struct {
+	client whatsapp.Client
+	to     string
+	recent int // Earlier addresses listed in change messages
+}
+
+// NewWhatsAppNotifier creates a WhatsApp notifier sending to the given
+// number. Change messages list up to recent earlier addresses of the history.
+func NewWhatsAppNotifier(client whatsapp.Client, to string, recent int) *WhatsAppNotifier {
+	return &WhatsAppNotifier{client: client, to: to, recent: recent}
+}
+
+// Name returns the channel name
+func (n *WhatsAppNotifier) Name() string {
+	return "WhatsApp"
+}
+
+// Notify sends the event as a WhatsApp message
+func (n *WhatsAppNotifier) Notify(ctx context.Context, event Event) error {
+	text := config.BuildCombinedWhatsAppMessage(event.Changes, event.Timestamp, event.Gateway)
+	switch event.Type {
+	case TypeHookFailed:
+		text = config.BuildHookFailureWhatsAppMessage(event.HookFailures, event.Timestamp)
+	case TypeCatchUp:
+		text = config.BuildCatchUpWhatsAppMessage(event.Changes, event.Timestamp, event.Gateway)
+	case TypeStarted, TypeStopped, TypeHeartbeat:
+		text = config.BuildLifecycleWhatsAppMessage(*event.Lifecycle, event.Timestamp)
+	case TypeFetchFailed, TypeFetchRecovered:
+		text = config.BuildFetchFailureWhatsAppMessage(event.Failures, event.Type == TypeFetchRecovered, event.Timestamp)
+	default:
+		if change, ok := event.Single(); ok {
+			text = config.BuildWhatsAppMessage(change.OldIP, change.NewIP, event.Timestamp, event.Gateway)
+		}
+		text = config.AddRecentChanges(text, event.Changes, n.recent)
+	}
+	if event.Digest != nil {
+		text = config.BuildDigestMessage(n.Name(), *event.Digest)
+	}
+
+	return n.client.Send(ctx, whatsapp.Message{
+		To:   n.to,
+		Text: config.ChannelText(n.Name(), withReference(text, event)),
+	})
+}
@@ -0,0 +1,73 @@
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// Modes lists the supported privacy modes; an empty mode keeps IPs as they are
+var Modes = []string{"mask", "hash"}
+
+// candidate matches text that may be an IPv4 or IPv6 address; matches are
+// confirmed by parsing them
+var candidate = regexp.MustCompile(`[0-9A-Fa-f]*:[0-9A-Fa-f:.]*[0-9A-Fa-f]|\b\d{1,3}(?:\.\d{1,3}){3}\b`)
+
+// Redactor hides public IP addresses in logs and shared outputs
+type Redactor struct {
+	mode string
+	salt []byte
+}
+
+// New creates a redactor for the given mode. A nil redactor, returned for
+// an empty mode, leaves IPs untouched.
+func New(mode, salt string) (*Redactor, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case "mask", "hash":
+		return &Redactor{mode: mode, salt: []byte(salt)}, nil
+	default:
+		return nil, fmt.Errorf("unknown privacy mode %q (available: %s)", mode, strings.Join(Modes, ", "))
+	}
+}
+
+// IP returns the redacted form of an IP address. Private, loopback and
+// other non-public addresses, and anything that is not an IP, are returned
+// unchanged.
+func (r *Redactor) IP(value string) string {
+	if r == nil {
+		return value
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil || !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return value
+	}
+
+	if r.mode == "hash" {
+		mac := hmac.New(sha256.New, r.salt)
+		mac.Write([]byte(addr.Unmap().String()))
+		return "ip-" + hex.EncodeToString(mac.Sum(nil)[:6])
+	}
+
+	// Keep the network part, enough to tell the provider or region
+	if addr.Is4() || addr.Is4In6() {
+		b := addr.Unmap().As4()
+		return fmt.Sprintf("%d.%d.%d.x", b[0], b[1], b[2])
+	}
+	prefix := netip.PrefixFrom(addr, 48).Masked()
+	return strings.TrimSuffix(prefix.Addr().String(), "::") + "::x"
+}
+
+// Text redacts every public IP address in a free-form text such as a log
+// message
+func (r *Redactor) Text(text string) string {
+	if r == nil {
+		return text
+	}
+	return candidate.ReplaceAllStringFunc(text, r.IP)
+}
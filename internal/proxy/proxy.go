@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"public-ip-monitor/internal/debughttp"
+)
+
+// Direct as a proxy URL connects without a proxy, even when HTTP_PROXY is set
+const Direct = "direct"
+
+// Func picks the proxy of a request, like http.Transport.Proxy; a nil URL
+// connects directly
+type Func func(*http.Request) (*url.URL, error)
+
+// Parse checks a proxy URL: http://, https://, socks5:// or socks5h://
+// (names resolved by the proxy), optionally with user:password@
+func Parse(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %s: scheme must be http, https, socks5 or socks5h", u.Redacted())
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %s: host is required", u.Redacted())
+	}
+	return u, nil
+}
+
+// New returns how requests find their proxy. Without a URL, the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables decide, as for
+// any Go program; "direct" uses none. Hosts matching noProxy, e.g.
+// "192.168.1.1", ".lan" or "10.0.0.0/8", are always reached directly.
+func New(raw string, noProxy []string) (Func, error) {
+	var pick Func
+	switch raw {
+	case "":
+		pick = http.ProxyFromEnvironment
+	case Direct:
+		return func(*http.Request) (*url.URL, error) { return nil, nil }, nil
+	default:
+		u, err := Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		pick = http.ProxyURL(u)
+	}
+
+	bypass, err := parseNoProxy(noProxy)
+	if err != nil {
+		return nil, err
+	}
+	if bypass == nil {
+		return pick, nil
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if bypass.match(req.URL.Hostname()) {
+			return nil, nil
+		}
+		return pick(req)
+	}, nil
+}
+
+// Install makes every HTTP client without its own transport, such as the
+// notification clients, and the transports of the IP fetcher created
+// afterwards connect through the proxy
+func Install(pick Func) {
+	debughttp.BaseTransport().Proxy = pick
+}
+
+// bypassList holds the hosts reached without a proxy
+type bypassList struct {
+	hosts    []string // Exact names or addresses
+	suffixes []string // Domains with their leading dot, matching subdomains
+	networks []*net.IPNet
+	all      bool
+}
+
+func parseNoProxy(entries []string) (*bypassList, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	list := &bypassList{}
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case entry == "*":
+			list.all = true
+		case strings.Contains(entry, "/"):
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid no_proxy entry %q: %w", entry, err)
+			}
+			list.networks = append(list.networks, network)
+		case strings.HasPrefix(entry, "."):
+			list.suffixes = append(list.suffixes, entry)
+		default:
+			// "example.com" also covers its subdomains, as in NO_PROXY
+			list.hosts = append(list.hosts, entry)
+			if net.ParseIP(entry) == nil {
+				list.suffixes = append(list.suffixes, "."+entry)
+			}
+		}
+	}
+	return list, nil
+}
+
+func (l *bypassList) match(host string) bool {
+	if l == nil {
+		return false
+	}
+	if l.all {
+		return true
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, h := range l.hosts {
+		if host == h {
+			return true
+		}
+	}
+	for _, suffix := range l.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	if addr := net.ParseIP(host); addr != nil {
+		for _, network := range l.networks {
+			if network.Contains(addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+//go:build linux
+
+package resources
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// cpuQuota returns the CPU quota of the process's cgroup in CPUs
+func cpuQuota() (float64, bool) {
+	// cgroup v2: "<quota> <period>" or "max <period>"
+	if data, ok := readCgroupFile("", "cpu.max"); ok {
+		fields := strings.Fields(data)
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0, false
+		}
+		return ratio(fields[0], fields[1])
+	}
+
+	// cgroup v1: quota is -1 when unlimited
+	quota, ok := readCgroupFile("cpu", "cpu.cfs_quota_us")
+	if !ok {
+		return 0, false
+	}
+	period, ok := readCgroupFile("cpu", "cpu.cfs_period_us")
+	if !ok {
+		return 0, false
+	}
+	return ratio(quota, period)
+}
+
+// memoryLimit returns the memory limit of the process's cgroup in bytes
+func memoryLimit() (int64, bool) {
+	data, ok := readCgroupFile("", "memory.max")
+	if !ok {
+		data, ok = readCgroupFile("memory", "memory.limit_in_bytes")
+	}
+	if !ok || data == "max" {
+		return 0, false
+	}
+
+	limit, err := strconv.ParseInt(data, 10, 64)
+	// cgroup v1 reports "unlimited" as a huge page-aligned number
+	if err != nil || limit <= 0 || limit >= 1<<62 {
+		return 0, false
+	}
+	return limit, true
+}
+
+// ratio divides two integers given as strings, requiring a positive result
+func ratio(numerator, denominator string) (float64, bool) {
+	n, err := strconv.ParseFloat(numerator, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	d, err := strconv.ParseFloat(denominator, 64)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return n / d, true
+}
+
+// readCgroupFile reads a file of the process's cgroup for the given v1
+// controller, or of the unified (v2) hierarchy when controller is empty.
+// Inside containers the cgroup path is usually not visible and the file is
+// read from the root of the mount instead.
+func readCgroupFile(controller, name string) (string, bool) {
+	mount := filepath.Join(cgroupRoot, controller)
+
+	dirs := []string{mount}
+	if path, ok := cgroupPath(controller); ok && path != "/" {
+		dirs = []string{filepath.Join(mount, path), mount}
+	}
+
+	for _, dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			return strings.TrimSpace(string(data)), true
+		}
+	}
+	return "", false
+}
+
+// cgroupPath returns the process's cgroup path for a v1 controller, or for
+// the unified hierarchy when controller is empty, from /proc/self/cgroup
+func cgroupPath(controller string) (string, bool) {
+	file, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Lines look like "4:memory:/docker/abc" or "0::/user.slice"
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if controller == "" && parts[0] == "0" && parts[1] == "" {
+			return parts[2], true
+		}
+		for _, c := range strings.Split(parts[1], ",") {
+			if controller != "" && c == controller {
+				return parts[2], true
+			}
+		}
+	}
+	return "", false
+}
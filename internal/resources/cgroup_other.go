@@ -0,0 +1,13 @@
+//go:build !linux
+
+package resources
+
+// cpuQuota is only available on Linux
+func cpuQuota() (float64, bool) {
+	return 0, false
+}
+
+// memoryLimit is only available on Linux
+func memoryLimit() (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,100 @@
+package resources
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// memoryLimitShare is the share of the container memory limit the Go
+// runtime aims to stay under when no explicit limit is configured
+const memoryLimitShare = 0.9
+
+// Settings describes the resource settings to apply
+type Settings struct {
+	GOMAXPROCS       int   // 0 derives it from the container CPU quota
+	MemoryLimitBytes int64 // 0 derives it from the container memory limit
+	BallastBytes     int   // Heap ballast reducing GC frequency on tiny heaps
+}
+
+// Applied describes the resource settings in effect
+type Applied struct {
+	GOMAXPROCS       int
+	CPUQuota         float64 // Container CPU quota in CPUs, 0 if unlimited
+	MemoryLimitBytes int64   // Go runtime soft memory limit, 0 if unlimited
+	ContainerMemory  int64   // Container memory limit, 0 if unlimited
+	BallastBytes     int
+}
+
+// ballast is never read; it only makes the GC target a larger heap
+var ballast []byte
+
+// Apply sets GOMAXPROCS, the Go memory limit and the heap ballast. An
+// explicit GOMAXPROCS or GOMEMLIMIT environment variable takes precedence
+// over derived values.
+func Apply(settings Settings) Applied {
+	var applied Applied
+
+	quota, hasQuota := cpuQuota()
+	if hasQuota {
+		applied.CPUQuota = quota
+	}
+
+	switch {
+	case settings.GOMAXPROCS > 0:
+		runtime.GOMAXPROCS(settings.GOMAXPROCS)
+	case os.Getenv("GOMAXPROCS") == "" && hasQuota:
+		// Round up so that a quota of 1.5 CPUs can use both, but never
+		// exceed the CPUs actually present
+		procs := int(math.Ceil(quota))
+		procs = max(1, min(procs, runtime.NumCPU()))
+		runtime.GOMAXPROCS(procs)
+	}
+	applied.GOMAXPROCS = runtime.GOMAXPROCS(0)
+
+	limit, hasLimit := memoryLimit()
+	if hasLimit {
+		applied.ContainerMemory = limit
+	}
+
+	switch {
+	case settings.MemoryLimitBytes > 0:
+		debug.SetMemoryLimit(settings.MemoryLimitBytes)
+	case os.Getenv("GOMEMLIMIT") == "" && hasLimit:
+		debug.SetMemoryLimit(int64(float64(limit) * memoryLimitShare))
+	}
+	if current := debug.SetMemoryLimit(-1); current != math.MaxInt64 {
+		applied.MemoryLimitBytes = current
+	}
+
+	if settings.BallastBytes > 0 {
+		ballast = make([]byte, settings.BallastBytes)
+		applied.BallastBytes = len(ballast)
+	}
+
+	return applied
+}
+
+// Usage is a snapshot of the process's resource usage
+type Usage struct {
+	Goroutines     int
+	HeapAllocBytes uint64
+	HeapSysBytes   uint64
+	SysBytes       uint64
+	NumGC          uint32
+}
+
+// ReadUsage returns the current resource usage
+func ReadUsage() Usage {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	return Usage{
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: stats.HeapAlloc,
+		HeapSysBytes:   stats.HeapSys,
+		SysBytes:       stats.Sys,
+		NumGC:          stats.NumGC,
+	}
+}
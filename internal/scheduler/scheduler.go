@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"public-ip-monitor/pkg/clock"
+)
+
+// Job is the work performed by a scheduled task
+type Job func(ctx context.Context)
+
+// Entry describes a registered task and its run state
+type Entry struct {
+	Name         string
+	Spec         string
+	Next         time.Time
+	LastRun      time.Time
+	LastDuration time.Duration
+	Running      bool
+}
+
+// task is a registered job and its schedule
+type task struct {
+	Entry
+	schedule Schedule
+	job      Job
+}
+
+// Scheduler runs registered jobs on their schedules. A job that is still
+// running when it is due again is skipped rather than run concurrently.
+type Scheduler struct {
+	mu       sync.Mutex
+	tasks    map[string]*task
+	location *time.Location
+	clock    clock.Clock
+	wake     chan struct{}
+}
+
+// New creates a scheduler evaluating cron expressions in the given location
+func New(location *time.Location) *Scheduler {
+	if location == nil {
+		location = time.Local
+	}
+
+	return &Scheduler{
+		tasks:    make(map[string]*task),
+		location: location,
+		clock:    clock.System,
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// SetClock replaces the system clock, e.g. with a fake one to fast-forward
+// schedules. It must be called before tasks are added.
+func (s *Scheduler) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = clock.OrSystem(c)
+}
+
+// Add registers a job under a unique name
+func (s *Scheduler) Add(name, spec string, job Job) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tasks[name]; exists {
+		return fmt.Errorf("task %s is already scheduled", name)
+	}
+
+	s.tasks[name] = &task{
+		Entry: Entry{
+			Name: name,
+			Spec: spec,
+			Next: schedule.Next(s.clock.Now().In(s.location)),
+		},
+		schedule: schedule,
+		job:      job,
+	}
+
+	// Let a running scheduler pick up the new task
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Entries returns all registered tasks, ordered by name
+func (s *Scheduler) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		entries = append(entries, t.Entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries
+}
+
+// Run runs due jobs until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		timer := s.currentClock().NewTimer(s.untilNext())
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wake:
+			timer.Stop()
+			continue
+		case <-timer.C():
+		}
+
+		for _, t := range s.due() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.runTask(ctx, t)
+			}()
+		}
+	}
+}
+
+// untilNext returns how long to wait for the next due task
+func (s *Scheduler) untilNext() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := time.Hour
+	now := s.clock.Now()
+	for _, t := range s.tasks {
+		if t.Next.IsZero() {
+			continue
+		}
+		if until := t.Next.Sub(now); until < wait {
+			wait = until
+		}
+	}
+
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// due marks due tasks as running and schedules their next run
+func (s *Scheduler) due() []*task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now().In(s.location)
+
+	var due []*task
+	for _, t := range s.tasks {
+		if t.Next.IsZero() || t.Next.After(now) {
+			continue
+		}
+
+		t.Next = t.schedule.Next(now)
+		if t.Running {
+			continue // Still busy with the previous run
+		}
+
+		t.Running = true
+		due = append(due, t)
+	}
+
+	return due
+}
+
+// runTask runs a task's job and records how long it took
+func (s *Scheduler) runTask(ctx context.Context, t *task) {
+	c := s.currentClock()
+	start := c.Now()
+	t.job(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t.Running = false
+	t.LastRun = start
+	t.LastDuration = clock.Since(c, start)
+}
+
+// currentClock returns the clock, read under the lock
+func (s *Scheduler) currentClock() clock.Clock {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clock
+}
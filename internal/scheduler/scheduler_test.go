@@ -0,0 +1,127 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"public-ip-monitor/pkg/clock"
+)
+
+// runOnFakeClock runs the scheduler on a fake clock advanced by step until
+// end, and returns when the job ran, as seen on the clock. The job hands
+// its time over before returning, and the clock only moves once the
+// scheduler waits again, so the result is the same on every run.
+func runOnFakeClock(t *testing.T, spec string, start, end time.Time, step time.Duration) []time.Time {
+	t.Helper()
+	fake := clock.NewFake(start)
+	s := New(time.UTC)
+	s.SetClock(fake)
+
+	runs := make(chan time.Time)
+	if err := s.Add("job", spec, func(context.Context) { runs <- fake.Now() }); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	var got []time.Time
+	for fake.Now().Before(end) {
+		fake.BlockUntil(1)
+		fake.Advance(step)
+		// Due jobs are marked running before the scheduler waits again
+		fake.BlockUntil(1)
+		if !s.Entries()[0].Running {
+			continue
+		}
+		got = append(got, <-runs)
+		for s.Entries()[0].Running {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return got
+}
+
+func TestSchedulerRunsOnFakeClock(t *testing.T) {
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC) // A Monday
+	tests := []struct {
+		name string
+		spec string
+		step time.Duration
+		want []string
+	}{
+		{
+			name: "every",
+			spec: "@every 6h",
+			step: time.Hour,
+			want: []string{"2026-01-05T06:00:00Z", "2026-01-05T12:00:00Z", "2026-01-05T18:00:00Z", "2026-01-06T00:00:00Z"},
+		},
+		{
+			name: "cron",
+			spec: "30 2 * * 1,3",
+			step: 30 * time.Minute,
+			want: []string{"2026-01-05T02:30:00Z", "2026-01-07T02:30:00Z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runOnFakeClock(t, tt.spec, start, start.Add(tt.step*time.Duration(len(tt.want)*60)), tt.step)
+			if len(got) < len(tt.want) {
+				t.Fatalf("ran %d times (%v), want at least %v", len(got), got, tt.want)
+			}
+			for i, want := range tt.want {
+				if at := got[i].Format(time.RFC3339); at != want {
+					t.Errorf("run %d at %s, want %s", i+1, at, want)
+				}
+			}
+		})
+	}
+}
+
+// TestSchedulerSkipsBusyJob checks that a job still running when it is due
+// again is skipped rather than run twice at once
+func TestSchedulerSkipsBusyJob(t *testing.T) {
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	s := New(time.UTC)
+	s.SetClock(fake)
+
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	if err := s.Add("job", "@every 1m", func(context.Context) {
+		started <- struct{}{}
+		<-release
+	}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	for range 3 {
+		fake.BlockUntil(1)
+		fake.Advance(time.Minute)
+	}
+	fake.BlockUntil(1)
+	<-started
+	close(release)
+	cancel()
+	<-done
+
+	if n := len(started); n != 0 {
+		t.Errorf("job started %d more times while busy, want 0", n)
+	}
+}
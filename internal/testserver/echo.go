@@ -0,0 +1,67 @@
+package testserver
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// IPEcho answers like ipify: the current IP as text, or as {"ip": "..."}
+// with ?format=json. Unlike ipify the IP is not the caller's but whatever
+// was last set, so tests decide when it changes.
+type IPEcho struct {
+	mu sync.Mutex
+	ip string
+}
+
+// NewIPEcho creates an echo answering with ip
+func NewIPEcho(ip string) *IPEcho {
+	return &IPEcho{ip: ip}
+}
+
+// IP returns the IP the echo answers with
+func (e *IPEcho) IP() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.ip
+}
+
+// SetIP changes the IP the echo answers with
+func (e *IPEcho) SetIP(ip string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ip = ip
+}
+
+// ServeHTTP answers GET with the IP and sets it from the body of POST
+func (e *IPEcho) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{"ip": e.IP()})
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, e.IP())
+	case http.MethodPost, http.MethodPut:
+		body, err := io.ReadAll(io.LimitReader(r.Body, 256))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ip := strings.TrimSpace(string(body))
+		if net.ParseIP(ip) == nil {
+			http.Error(w, "invalid IP "+ip, http.StatusBadRequest)
+			return
+		}
+		e.SetIP(ip)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
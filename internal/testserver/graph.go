@@ -0,0 +1,102 @@
+package testserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WhatsAppMessage is a message sent through the Graph API stub
+type WhatsAppMessage struct {
+	ID       string    `json:"id"`
+	Version  string    `json:"version"` // API version of the request path, e.g. "v17.0"
+	PhoneID  string    `json:"phone_id"`
+	To       string    `json:"to"`
+	Text     string    `json:"text"`
+	Received time.Time `json:"received"`
+}
+
+// GraphAPI stubs the WhatsApp messages endpoint of Meta's Graph API,
+// POST /{version}/{phone-id}/messages, keeping the messages in memory
+type GraphAPI struct {
+	mu       sync.Mutex
+	messages []WhatsAppMessage
+
+	// OnMessage is called with every message accepted, optional
+	OnMessage func(message WhatsAppMessage)
+}
+
+// Messages returns the messages received so far
+func (g *GraphAPI) Messages() []WhatsAppMessage {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]WhatsAppMessage{}, g.messages...)
+}
+
+// ServeHTTP accepts text messages the way the Graph API does, including its
+// error format
+func (g *GraphAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	version, phoneID := r.PathValue("version"), r.PathValue("phone")
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		graphError(w, http.StatusUnauthorized, 190, "Invalid OAuth access token")
+		return
+	}
+
+	var request struct {
+		MessagingProduct string `json:"messaging_product"`
+		To               string `json:"to"`
+		Type             string `json:"type"`
+		Text             struct {
+			Body string `json:"body"`
+		} `json:"text"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil {
+		graphError(w, http.StatusBadRequest, 100, "Invalid request body: "+err.Error())
+		return
+	}
+	switch {
+	case request.MessagingProduct != "whatsapp":
+		graphError(w, http.StatusBadRequest, 100, "The parameter messaging_product is required")
+		return
+	case request.To == "":
+		graphError(w, http.StatusBadRequest, 100, "The parameter to is required")
+		return
+	case request.Type != "text" || request.Text.Body == "":
+		graphError(w, http.StatusBadRequest, 100, "Only text messages with a body are supported")
+		return
+	}
+
+	g.mu.Lock()
+	message := WhatsAppMessage{
+		ID:       fmt.Sprintf("wamid.simulated.%d", len(g.messages)+1),
+		Version:  version,
+		PhoneID:  phoneID,
+		To:       request.To,
+		Text:     request.Text.Body,
+		Received: time.Now(),
+	}
+	g.messages = append(g.messages, message)
+	g.mu.Unlock()
+	if g.OnMessage != nil {
+		g.OnMessage(message)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"messaging_product": "whatsapp",
+		"contacts":          []map[string]string{{"input": request.To, "wa_id": request.To}},
+		"messages":          []map[string]string{{"id": message.ID}},
+	})
+}
+
+// graphError writes an error the way the Graph API reports them
+func graphError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": message, "type": "OAuthException", "code": code},
+	})
+}
@@ -0,0 +1,309 @@
+package testserver
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// smtpIdleTimeout is how long the sink waits for the next command
+const smtpIdleTimeout = time.Minute
+
+// Email is a message delivered to the SMTP sink
+type Email struct {
+	Username string    `json:"username"` // Who logged in to send it
+	From     string    `json:"from"`     // Envelope sender
+	To       []string  `json:"to"`       // Envelope recipients
+	Subject  string    `json:"subject"`  // Decoded subject header
+	Body     string    `json:"body"`
+	Received time.Time `json:"received"`
+}
+
+// SMTPSink is an SMTP server keeping the messages it is sent in memory.
+// Like the relays the monitor is meant for, it requires STARTTLS, with a
+// self-signed certificate clients trust through CertPool, and a login,
+// which it accepts whatever the password.
+type SMTPSink struct {
+	listener net.Listener
+	tls      *tls.Config
+	pool     *x509.CertPool
+
+	mu     sync.Mutex
+	emails []Email
+
+	// OnEmail is called with every message delivered, optional
+	OnEmail func(email Email)
+}
+
+// NewSMTPSink listens on addr, e.g. "127.0.0.1:0", and serves until closed
+func NewSMTPSink(addr string) (*SMTPSink, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for SMTP: %w", err)
+	}
+	host, _, _ := net.SplitHostPort(listener.Addr().String())
+	cert, err := selfSignedCert(host)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert.Leaf)
+	s := &SMTPSink{
+		listener: listener,
+		tls:      &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		pool:     pool,
+	}
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the host and port the sink listens on
+func (s *SMTPSink) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// CertPool returns the pool holding the sink's certificate
+func (s *SMTPSink) CertPool() *x509.CertPool {
+	return s.pool
+}
+
+// Emails returns the messages delivered so far
+func (s *SMTPSink) Emails() []Email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Email{}, s.emails...)
+}
+
+// Close stops listening; sessions in progress are cut off by their timeout
+func (s *SMTPSink) Close() error {
+	return s.listener.Close()
+}
+
+func (s *SMTPSink) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.session(conn)
+	}
+}
+
+// smtpSession is the state of a connection
+type smtpSession struct {
+	text     *textproto.Conn
+	secure   bool
+	username string
+	from     string
+	to       []string
+}
+
+// session speaks SMTP on a connection until QUIT or an error
+func (s *SMTPSink) session(conn net.Conn) {
+	session := &smtpSession{text: textproto.NewConn(conn)}
+	defer func() { session.text.Close() }()
+
+	session.reply(220, "simulation ESMTP ready")
+	for {
+		conn.SetDeadline(time.Now().Add(smtpIdleTimeout))
+		line, err := session.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			session.from, session.to = "", nil
+			extensions := []string{"simulation", "8BITMIME"}
+			if session.secure {
+				extensions = append(extensions, "AUTH PLAIN")
+			} else {
+				extensions = append(extensions, "STARTTLS")
+			}
+			session.reply(250, extensions...)
+		case "STARTTLS":
+			if session.secure {
+				session.reply(503, "already running TLS")
+				continue
+			}
+			session.reply(220, "ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			session.text, session.secure = textproto.NewConn(tlsConn), true
+		case "AUTH":
+			s.auth(session, arg)
+		case "MAIL":
+			switch {
+			case !session.secure:
+				session.reply(530, "must issue a STARTTLS command first")
+			case session.username == "":
+				session.reply(530, "authentication required")
+			default:
+				session.from, session.to = parseAddress(arg, "FROM:"), nil
+				session.reply(250, "sender ok")
+			}
+		case "RCPT":
+			if session.from == "" {
+				session.reply(503, "need MAIL before RCPT")
+				continue
+			}
+			session.to = append(session.to, parseAddress(arg, "TO:"))
+			session.reply(250, "recipient ok")
+		case "DATA":
+			if len(session.to) == 0 {
+				session.reply(503, "need RCPT before DATA")
+				continue
+			}
+			session.reply(354, "end data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(session.text.DotReader())
+			if err != nil {
+				return
+			}
+			s.deliver(session, data)
+			session.from, session.to = "", nil
+			session.reply(250, "message accepted")
+		case "RSET":
+			session.from, session.to = "", nil
+			session.reply(250, "ok")
+		case "NOOP":
+			session.reply(250, "ok")
+		case "QUIT":
+			session.reply(221, "bye")
+			return
+		default:
+			session.reply(502, "command not implemented")
+		}
+	}
+}
+
+// auth handles "AUTH PLAIN", with the credentials inline or on the next line
+func (s *SMTPSink) auth(session *smtpSession, arg string) {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	switch {
+	case !session.secure:
+		session.reply(530, "must issue a STARTTLS command first")
+		return
+	case !strings.EqualFold(mechanism, "PLAIN"):
+		session.reply(504, "unrecognized authentication type")
+		return
+	}
+	if initial == "" {
+		session.reply(334, "")
+		line, err := session.text.ReadLine()
+		if err != nil {
+			return
+		}
+		initial = line
+	}
+
+	// authorization identity \0 username \0 password
+	decoded, err := base64.StdEncoding.DecodeString(initial)
+	fields := bytes.Split(decoded, []byte{0})
+	if err != nil || len(fields) != 3 || len(fields[1]) == 0 {
+		session.reply(535, "authentication credentials invalid")
+		return
+	}
+	session.username = string(fields[1])
+	session.reply(235, "authentication successful")
+}
+
+// deliver stores a message
+func (s *SMTPSink) deliver(session *smtpSession, data []byte) {
+	email := Email{Username: session.username, From: session.from, To: session.to, Received: time.Now()}
+	if message, err := mail.ReadMessage(bytes.NewReader(data)); err == nil {
+		subject := message.Header.Get("Subject")
+		if decoded, err := new(mime.WordDecoder).DecodeHeader(subject); err == nil {
+			subject = decoded
+		}
+		body, _ := io.ReadAll(message.Body)
+		email.Subject, email.Body = subject, strings.ReplaceAll(string(body), "\r\n", "\n")
+	} else {
+		email.Body = string(data)
+	}
+
+	s.mu.Lock()
+	s.emails = append(s.emails, email)
+	s.mu.Unlock()
+	if s.OnEmail != nil {
+		s.OnEmail(email)
+	}
+}
+
+// reply writes a reply, one line per text
+func (session *smtpSession) reply(code int, texts ...string) {
+	if len(texts) == 0 {
+		texts = []string{""}
+	}
+	for i, text := range texts {
+		separator := "-"
+		if i == len(texts)-1 {
+			separator = " "
+		}
+		session.text.PrintfLine("%d%s%s", code, separator, text)
+	}
+}
+
+// parseAddress extracts the address of "FROM:<a@b> SIZE=10"
+func parseAddress(arg, prefix string) string {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return ""
+	}
+	address, _, _ := strings.Cut(strings.TrimSpace(arg[len(prefix):]), " ")
+	return strings.Trim(address, "<>")
+}
+
+// selfSignedCert creates a certificate for host, localhost and the
+// loopback addresses
+func selfSignedCert(host string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create SMTP key: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "public-ip-monitor simulation"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(host); ip != nil && !ip.IsLoopback() && !ip.IsUnspecified() {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create SMTP certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, errors.New("failed to parse SMTP certificate")
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
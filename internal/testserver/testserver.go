@@ -0,0 +1,105 @@
+// Package testserver stands in for the services the monitor talks to, so
+// the whole pipeline can run end to end without reaching the internet: an
+// ipify-style IP echo whose answer tests change at will, a stub of the
+// Graph API WhatsApp messages are sent through and an SMTP sink. Everything
+// sent to them is kept in memory.
+package testserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// InitialIP is the IP the echo answers with until it is changed
+const InitialIP = "203.0.113.10"
+
+// Server runs the IP echo and the Graph API stub on one HTTP port and the
+// SMTP sink on another:
+//
+//	GET  /              the IP as text, or JSON with ?format=json
+//	GET  /ip            the same
+//	POST /ip            changes the IP to the request body
+//	POST /{version}/{phone-id}/messages   the Graph API stub
+//	GET  /messages      what the stubs received, as JSON
+type Server struct {
+	Echo  *IPEcho
+	Graph *GraphAPI
+	SMTP  *SMTPSink
+
+	listener net.Listener
+	http     *http.Server
+}
+
+// Start listens on addr, e.g. "127.0.0.1:0" for a free port, and serves
+// until closed. logf, if not nil, is called for every message received.
+func Start(addr string, logf func(format string, args ...any)) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	host, _, _ := net.SplitHostPort(listener.Addr().String())
+	sink, err := NewSMTPSink(net.JoinHostPort(host, "0"))
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	s := &Server{
+		Echo:     NewIPEcho(InitialIP),
+		Graph:    &GraphAPI{},
+		SMTP:     sink,
+		listener: listener,
+	}
+	if logf != nil {
+		s.Graph.OnMessage = func(message WhatsAppMessage) {
+			logf("WhatsApp message to %s received: %s", message.To, firstLine(message.Text))
+		}
+		s.SMTP.OnEmail = func(email Email) {
+			logf("Email to %s received: %s", strings.Join(email.To, ", "), email.Subject)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /{$}", s.Echo)
+	mux.Handle("/ip", s.Echo)
+	mux.Handle("POST /{version}/{phone}/messages", s.Graph)
+	mux.HandleFunc("GET /messages", s.serveMessages)
+	s.http = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go s.http.Serve(listener)
+
+	return s, nil
+}
+
+// URL returns the base URL of the HTTP services
+func (s *Server) URL() string {
+	return "http://" + s.listener.Addr().String()
+}
+
+// Close stops the servers
+func (s *Server) Close() error {
+	return errors.Join(s.http.Close(), s.SMTP.Close())
+}
+
+// serveMessages lists what the stubs received
+func (s *Server) serveMessages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		WhatsApp []WhatsAppMessage `json:"whatsapp"`
+		Email    []Email           `json:"email"`
+	}{s.Graph.Messages(), s.SMTP.Emails()})
+}
+
+// firstLine returns the first non-empty line of a message, for logs
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
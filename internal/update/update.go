@@ -0,0 +1,265 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBinarySize caps downloads, so that a broken server cannot fill the disk
+const maxBinarySize = 256 << 20
+
+// Release is a published version, as listed in the signed release manifest
+type Release struct {
+	Version   string            `json:"version"` // e.g. "1.5.0" or "v1.5.0"
+	Published time.Time         `json:"published"`
+	Binaries  map[string]Binary `json:"binaries"` // By platform, e.g. "linux/arm64"
+}
+
+// Binary is the release binary of a platform
+type Binary struct {
+	URL    string `json:"url"`    // Absolute, or relative to the manifest URL
+	SHA256 string `json:"sha256"` // Hex digest, covered by the manifest signature
+}
+
+// signedManifest is the envelope served at the manifest URL. The signature
+// is an Ed25519 signature over the base64-decoded payload, which holds a
+// Release.
+type signedManifest struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// Updater finds new releases in a signed manifest and installs them in
+// place of the running binary
+type Updater struct {
+	url        string
+	publicKey  ed25519.PublicKey
+	httpClient *http.Client
+}
+
+// New creates an updater for the manifest at manifestURL, signed by the
+// base64-encoded Ed25519 publicKey
+func New(manifestURL, publicKey string, timeoutSeconds int) (*Updater, error) {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid update public key: %w", err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid update public key: expected %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+
+	return &Updater{
+		url:        manifestURL,
+		publicKey:  ed25519.PublicKey(key),
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Platform returns the key of the running platform in the manifest, e.g.
+// "linux/arm64"
+func Platform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// Latest downloads and verifies the release manifest
+func (u *Updater) Latest(ctx context.Context) (*Release, error) {
+	data, err := u.get(ctx, u.url, 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release manifest: %w", err)
+	}
+
+	var envelope signedManifest
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse release manifest: %w", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode release manifest payload: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(envelope.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode release manifest signature: %w", err)
+	}
+	if !ed25519.Verify(u.publicKey, payload, signature) {
+		return nil, fmt.Errorf("release manifest signature verification failed")
+	}
+
+	var release Release
+	if err := json.Unmarshal(payload, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse release manifest payload: %w", err)
+	}
+	if _, ok := parseVersion(release.Version); !ok {
+		return nil, fmt.Errorf("release manifest has an invalid version %q", release.Version)
+	}
+	return &release, nil
+}
+
+// Newer reports whether the release is newer than the running version;
+// builds without a release version, e.g. "dev", are never older
+func (r *Release) Newer(current string) bool {
+	have, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	want, _ := parseVersion(r.Version)
+	for i := range want {
+		if want[i] != have[i] {
+			return want[i] > have[i]
+		}
+	}
+	return false
+}
+
+// Install downloads the release binary of the running platform, checks
+// its digest and replaces the executable with it. The replaced executable
+// is kept with an ".old" suffix, to go back by hand.
+func (u *Updater) Install(ctx context.Context, release *Release, executable string) error {
+	binary, ok := release.Binaries[Platform()]
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s", release.Version, Platform())
+	}
+	want, err := hex.DecodeString(binary.SHA256)
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("release %s has an invalid sha256 for %s", release.Version, Platform())
+	}
+	link, err := resolve(u.url, binary.URL)
+	if err != nil {
+		return err
+	}
+
+	// The new binary is written next to the executable, so that it can be
+	// renamed into place
+	dir := filepath.Dir(executable)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(executable)+".update-*")
+	if err != nil {
+		return fmt.Errorf("failed to create update file in %s: %w", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	if err := u.download(ctx, link, io.MultiWriter(tmp, hash)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write update file: %w", err)
+	}
+	if got := hash.Sum(nil); !bytes.Equal(got, want) {
+		return fmt.Errorf("downloaded binary does not match the signed sha256 (got %x)", got)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return fmt.Errorf("failed to make update executable: %w", err)
+	}
+
+	// Renaming a running executable works on every platform, unlike
+	// overwriting it
+	old := executable + ".old"
+	if err := os.Rename(executable, old); err != nil {
+		return fmt.Errorf("failed to move %s aside: %w", executable, err)
+	}
+	if err := os.Rename(tmp.Name(), executable); err != nil {
+		if restoreErr := os.Rename(old, executable); restoreErr != nil {
+			return fmt.Errorf("failed to install update: %w (and to restore %s: %v)", err, executable, restoreErr)
+		}
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+	return nil
+}
+
+// download writes the binary at link to w
+func (u *Updater) download(ctx context.Context, link string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request for %s: %w", link, err)
+	}
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download release binary: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("release binary %s returned status %d", link, resp.StatusCode)
+	}
+
+	n, err := io.Copy(w, io.LimitReader(resp.Body, maxBinarySize+1))
+	if err != nil {
+		return fmt.Errorf("failed to download release binary: %w", err)
+	}
+	if n > maxBinarySize {
+		return fmt.Errorf("release binary exceeds %d MiB", maxBinarySize>>20)
+	}
+	return nil
+}
+
+// get returns the body of a page, up to limit bytes
+func (u *Updater) get(ctx context.Context, link string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", link, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", link, err)
+	}
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", link, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// resolve returns the binary URL, relative ones resolved against the
+// manifest URL
+func resolve(manifestURL, binaryURL string) (string, error) {
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid release manifest URL: %w", err)
+	}
+	ref, err := url.Parse(binaryURL)
+	if err != nil || binaryURL == "" {
+		return "", fmt.Errorf("invalid release binary URL %q", binaryURL)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// parseVersion returns the major, minor and patch numbers of a version
+// such as "1.5.0", "v1.5" or "1.5.0-rc1" (the suffix is ignored)
+func parseVersion(version string) ([3]int, bool) {
+	var parts [3]int
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	fields := strings.Split(version, ".")
+	if len(fields) > 3 || fields[0] == "" {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
@@ -0,0 +1,207 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a clock that only moves when advanced. Timers and tickers fire
+// in order of their due time as Advance passes it, so a test or simulation
+// can run hours of schedules in an instant and get the same result every
+// time.
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond // Broadcast when waiters are added or removed
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// NewFake creates a fake clock showing start
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer creates a timer firing once d has been advanced past
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	w := &fakeWaiter{clock: f, c: make(chan time.Time, 1)}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedule(w, d)
+	return fakeTimer{w}
+}
+
+// NewTicker creates a ticker firing every d of advanced time. It panics
+// for a non-positive interval, like time.NewTicker.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	w := &fakeWaiter{clock: f, c: make(chan time.Time, 1), period: d}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedule(w, d)
+	return fakeTicker{w}
+}
+
+// Advance moves the clock forward by d, firing every timer and tick due
+// on the way at its own time. A negative d is ignored.
+func (f *Fake) Advance(d time.Duration) {
+	if d < 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	target := f.now.Add(d)
+	for {
+		next := f.nextDue(target)
+		if next == nil {
+			break
+		}
+		f.now = next.when
+		next.fire()
+	}
+	f.now = target
+}
+
+// Set moves the clock to t, firing what is due until then like Advance. A
+// time before the current one is ignored.
+func (f *Fake) Set(t time.Time) {
+	f.Advance(t.Sub(f.Now()))
+}
+
+// Waiters returns how many timers and tickers are pending
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil waits until at least n timers and tickers are pending, so a
+// goroutine is known to wait on the clock before it is advanced
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.changed.Wait()
+	}
+}
+
+// schedule makes w due d from now, firing it right away when d is not
+// positive
+func (f *Fake) schedule(w *fakeWaiter, d time.Duration) {
+	f.remove(w)
+	w.when = f.now.Add(d)
+	if d <= 0 && w.period == 0 {
+		w.fire()
+		return
+	}
+	f.waiters = append(f.waiters, w)
+	f.changed.Broadcast()
+}
+
+// remove drops w from the pending waiters and reports whether it was one
+func (f *Fake) remove(w *fakeWaiter) bool {
+	for i, pending := range f.waiters {
+		if pending == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// nextDue returns the earliest waiter due by target, timers created first
+// going first on ties
+func (f *Fake) nextDue(target time.Time) *fakeWaiter {
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].when.Before(f.waiters[j].when)
+	})
+	if len(f.waiters) == 0 || f.waiters[0].when.After(target) {
+		return nil
+	}
+	return f.waiters[0]
+}
+
+// fakeWaiter is a pending timer or ticker of a Fake
+type fakeWaiter struct {
+	clock  *Fake
+	c      chan time.Time
+	when   time.Time
+	period time.Duration // Zero for timers
+}
+
+// fire delivers the current time and reschedules tickers. Like the time
+// package, a tick is dropped when the previous one was not received.
+func (w *fakeWaiter) fire() {
+	select {
+	case w.c <- w.clock.now:
+	default:
+	}
+	if w.period > 0 {
+		w.when = w.clock.now.Add(w.period)
+		return
+	}
+	w.clock.remove(w)
+}
+
+// drain drops a value sent before a Stop or Reset, which the time package
+// does not deliver either
+func (w *fakeWaiter) drain() {
+	select {
+	case <-w.c:
+	default:
+	}
+}
+
+type fakeTimer struct{ *fakeWaiter }
+
+func (t fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.drain()
+	return t.clock.remove(t.fakeWaiter)
+}
+
+func (t fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.drain()
+	active := t.clock.remove(t.fakeWaiter)
+	t.clock.schedule(t.fakeWaiter, d)
+	return active
+}
+
+type fakeTicker struct{ *fakeWaiter }
+
+func (t fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.clock.remove(t.fakeWaiter)
+}
+
+func (t fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.drain()
+	t.period = d
+	t.clock.schedule(t.fakeWaiter, d)
+}
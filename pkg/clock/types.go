@@ -0,0 +1,28 @@
+package clock
+
+import "time"
+
+// Clock tells the time and waits for it. Code reading the time through a
+// Clock runs on the system clock in production and on a Fake in tests and
+// simulations, which moves only when advanced. The monitor and scheduler
+// of this module are internal packages, so their SetClock is only there
+// for the module's own tests; other programs can use a Clock in their code.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is a single event, like time.Timer
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// Ticker delivers ticks at an interval, like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+	Reset(d time.Duration)
+}
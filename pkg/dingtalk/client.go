@@ -0,0 +1,132 @@
+package dingtalk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// WebhookClient implements the DingTalk client using custom robot webhooks
+type WebhookClient struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// WebhookFactory creates DingTalk webhook clients
+type WebhookFactory struct{}
+
+// NewWebhookFactory creates a new DingTalk factory
+func NewWebhookFactory() *WebhookFactory {
+	return &WebhookFactory{}
+}
+
+// NewClient creates a new DingTalk webhook client
+func (f *WebhookFactory) NewClient(config Config) (Client, error) {
+	if config.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+	if _, err := url.Parse(config.WebhookURL); err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &WebhookClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// webhookPayload is the body of a markdown robot message
+type webhookPayload struct {
+	MsgType  string          `json:"msgtype"`
+	Markdown markdownPayload `json:"markdown"`
+}
+
+type markdownPayload struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// webhookResponse is returned by the robot API, with status 200 also on errors
+type webhookResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// Send posts a message to the DingTalk robot
+func (c *WebhookClient) Send(ctx context.Context, message Message) error {
+	jsonData, err := json.Marshal(webhookPayload{
+		MsgType:  "markdown",
+		Markdown: markdownPayload{Title: message.Title, Text: message.Text},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.signedURL(time.Now()), bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DingTalk webhook error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result webhookResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode DingTalk response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("DingTalk webhook error (errcode %d): %s", result.ErrCode, result.ErrMsg)
+	}
+
+	return nil
+}
+
+// signedURL adds the timestamp and signature robots with a signing secret
+// require: base64 HMAC-SHA256 of "<timestamp ms>\n<secret>" keyed with the secret
+func (c *WebhookClient) signedURL(now time.Time) string {
+	if c.config.Secret == "" {
+		return c.config.WebhookURL
+	}
+
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(c.config.Secret))
+	mac.Write([]byte(timestamp + "\n" + c.config.Secret))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	u, _ := url.Parse(c.config.WebhookURL)
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", signature)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Close closes the DingTalk client
+func (c *WebhookClient) Close() error {
+	return nil
+}
@@ -0,0 +1,27 @@
+package dingtalk
+
+import "context"
+
+// Message represents a DingTalk markdown message
+type Message struct {
+	Title string // Shown in the conversation list and notifications
+	Text  string // Markdown
+}
+
+// Config represents DingTalk configuration
+type Config struct {
+	WebhookURL     string // Custom robot URL including its access_token
+	Secret         string // Signing secret ("SEC..."), when the robot uses signatures
+	TimeoutSeconds int
+}
+
+// Client defines the DingTalk client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates DingTalk clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -0,0 +1,126 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookClient implements the Discord client using webhooks
+type WebhookClient struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// WebhookFactory creates Discord webhook clients
+type WebhookFactory struct{}
+
+// NewWebhookFactory creates a new Discord factory
+func NewWebhookFactory() *WebhookFactory {
+	return &WebhookFactory{}
+}
+
+// NewClient creates a new Discord webhook client
+func (f *WebhookFactory) NewClient(config Config) (Client, error) {
+	if config.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &WebhookClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// webhookPayload is the JSON body accepted by Discord webhooks
+type webhookPayload struct {
+	Username string         `json:"username,omitempty"`
+	Content  string         `json:"content,omitempty"`
+	Embeds   []embedPayload `json:"embeds,omitempty"`
+}
+
+type embedPayload struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Fields      []fieldPayload `json:"fields,omitempty"`
+	Footer      *footerPayload `json:"footer,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+}
+
+type fieldPayload struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type footerPayload struct {
+	Text string `json:"text"`
+}
+
+// Send posts a message to the Discord webhook
+func (c *WebhookClient) Send(ctx context.Context, message Message) error {
+	payload := webhookPayload{
+		Username: c.config.Username,
+		Content:  message.Content,
+	}
+
+	for _, embed := range message.Embeds {
+		e := embedPayload{
+			Title:       embed.Title,
+			Description: embed.Description,
+			Color:       embed.Color,
+		}
+		for _, field := range embed.Fields {
+			e.Fields = append(e.Fields, fieldPayload(field))
+		}
+		if embed.Footer != "" {
+			e.Footer = &footerPayload{Text: embed.Footer}
+		}
+		if !embed.Timestamp.IsZero() {
+			e.Timestamp = embed.Timestamp.UTC().Format(time.RFC3339)
+		}
+		payload.Embeds = append(payload.Embeds, e)
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.WebhookURL, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Discord answers 204 No Content, or 200 when ?wait=true is used
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Discord webhook error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// Close closes the Discord client
+func (c *WebhookClient) Close() error {
+	return nil
+}
@@ -0,0 +1,47 @@
+package discord
+
+import (
+	"context"
+	"time"
+)
+
+// Message represents a Discord webhook message
+type Message struct {
+	Content string
+	Embeds  []Embed
+}
+
+// Embed represents a rich embed attached to a message
+type Embed struct {
+	Title       string
+	Description string
+	Color       int // RGB, e.g. 0xE74C3C
+	Fields      []EmbedField
+	Footer      string
+	Timestamp   time.Time
+}
+
+// EmbedField represents a name/value pair shown in an embed
+type EmbedField struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+// Config represents Discord configuration
+type Config struct {
+	WebhookURL     string
+	Username       string // Overrides the webhook's default name when set
+	TimeoutSeconds int
+}
+
+// Client defines the Discord client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates Discord clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -3,8 +3,13 @@ package email
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"mime"
+	"net"
+	"net/mail"
 	"net/smtp"
+	"strings"
 	"time"
 )
 
@@ -28,33 +33,81 @@ func (f *SMTPFactory) NewClient(config Config) (Client, error) {
 	}, nil
 }
 
-// Send sends an email using SMTP
+// errDelivery marks failures after the message was handed to a relay, when
+// trying another relay could deliver it twice
+var errDelivery = errors.New("delivery failed")
+
+// Send sends an email using SMTP, failing over to the backup relays in order
+// when a relay fails before accepting the message
 func (c *SMTPClient) Send(ctx context.Context, message Message) error {
-	// Create context with timeout
-	if c.config.Timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, time.Duration(c.config.Timeout)*time.Second)
-		defer cancel()
+	msg := buildMessage(c.config, message)
+
+	relays := append([]Relay{{Host: c.config.SMTPHost, Port: c.config.SMTPPort}}, c.config.Relays...)
+	var failures []string
+	for i, relay := range relays {
+		if relay.Username == "" {
+			relay.Username = c.config.From
+		}
+		if relay.Password == "" {
+			relay.Password = c.config.Password
+		}
+		addr := net.JoinHostPort(relay.Host, relay.Port)
+
+		err := c.sendVia(ctx, relay, message.To, msg)
+		if err == nil {
+			if i > 0 && c.config.Logf != nil {
+				c.config.Logf("Email sent through backup relay %s after: %s", addr, strings.Join(failures, "; "))
+			}
+			return nil
+		}
+		if len(relays) == 1 || errors.Is(err, errDelivery) || ctx.Err() != nil {
+			return err
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", addr, err))
 	}
 
-	// Set up authentication
-	auth := smtp.PlainAuth("", c.config.From, c.config.Password, c.config.SMTPHost)
+	return fmt.Errorf("all SMTP relays failed: %s", strings.Join(failures, "; "))
+}
 
-	// Prepare email message
-	msg := []byte(fmt.Sprintf(
-		"To: %s\r\n"+
+// buildMessage formats the message for sending; the sender name and the
+// subject are encoded if they are not ASCII
+func buildMessage(config Config, message Message) []byte {
+	from := (&mail.Address{Name: config.FromName, Address: config.From}).String()
+	return []byte(fmt.Sprintf(
+		"From: %s\r\n"+
+			"To: %s\r\n"+
 			"Subject: %s\r\n"+
+			"MIME-Version: 1.0\r\n"+
 			"Content-Type: text/plain; charset=UTF-8\r\n"+
 			"\r\n"+
 			"%s\r\n",
-		message.To, message.Subject, message.Body))
+		from, message.To, mime.QEncoding.Encode("UTF-8", message.Subject), message.Body))
+}
+
+// sendVia sends the message through a single relay
+func (c *SMTPClient) sendVia(ctx context.Context, relay Relay, to string, msg []byte) error {
+	// Create context with timeout, for each relay
+	timeout := relayTimeout
+	if c.config.Timeout > 0 {
+		timeout = time.Duration(c.config.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
 	// SMTP server address
-	addr := c.config.SMTPHost + ":" + c.config.SMTPPort
+	addr := net.JoinHostPort(relay.Host, relay.Port)
 
 	// Connect to SMTP server
-	conn, err := smtp.Dial(addr)
+	netConn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+	conn, err := smtp.NewClient(netConn, relay.Host)
 	if err != nil {
+		netConn.Close()
 		return fmt.Errorf("failed to connect to SMTP server: %w", err)
 	}
 	defer conn.Quit()
@@ -62,7 +115,8 @@ func (c *SMTPClient) Send(ctx context.Context, message Message) error {
 	// Start TLS
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: false,
-		ServerName:         c.config.SMTPHost,
+		ServerName:         relay.Host,
+		RootCAs:            c.config.RootCAs,
 	}
 
 	if err = conn.StartTLS(tlsConfig); err != nil {
@@ -70,6 +124,7 @@ func (c *SMTPClient) Send(ctx context.Context, message Message) error {
 	}
 
 	// Authenticate
+	auth := smtp.PlainAuth("", relay.Username, relay.Password, relay.Host)
 	if err = conn.Auth(auth); err != nil {
 		return fmt.Errorf("SMTP authentication failed: %w", err)
 	}
@@ -80,7 +135,7 @@ func (c *SMTPClient) Send(ctx context.Context, message Message) error {
 	}
 
 	// Set recipient
-	if err = conn.Rcpt(message.To); err != nil {
+	if err = conn.Rcpt(to); err != nil {
 		return fmt.Errorf("failed to set recipient: %w", err)
 	}
 
@@ -92,12 +147,12 @@ func (c *SMTPClient) Send(ctx context.Context, message Message) error {
 
 	_, err = w.Write(msg)
 	if err != nil {
-		return fmt.Errorf("failed to write email message: %w", err)
+		return fmt.Errorf("%w: failed to write email message: %w", errDelivery, err)
 	}
 
 	err = w.Close()
 	if err != nil {
-		return fmt.Errorf("failed to close email writer: %w", err)
+		return fmt.Errorf("%w: failed to close email writer: %w", errDelivery, err)
 	}
 
 	return nil
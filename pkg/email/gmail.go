@@ -0,0 +1,159 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gmailSendURL   = "https://gmail.googleapis.com/gmail/v1/users/me/messages/send"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+// GmailClient implements the email client using the Gmail API, which needs
+// no app password and works with Advanced Protection enabled
+type GmailClient struct {
+	config     Config
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// GmailFactory creates Gmail API email clients
+type GmailFactory struct{}
+
+// NewGmailFactory creates a new Gmail API factory
+func NewGmailFactory() *GmailFactory {
+	return &GmailFactory{}
+}
+
+// NewClient creates a new Gmail API email client
+func (f *GmailFactory) NewClient(config Config) (Client, error) {
+	if config.Gmail.ClientID == "" || config.Gmail.ClientSecret == "" || config.Gmail.RefreshToken == "" {
+		return nil, fmt.Errorf("Gmail client ID, client secret and refresh token are required")
+	}
+	if config.Gmail.TokenURL == "" {
+		config.Gmail.TokenURL = googleTokenURL
+	}
+
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = time.Duration(config.Timeout) * time.Second
+	}
+
+	return &GmailClient{
+		config:     config,
+		httpClient: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Send sends an email through the Gmail API as the account the refresh
+// token belongs to
+func (c *GmailClient) Send(ctx context.Context, message Message) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := json.Marshal(map[string]string{
+		"raw": base64.URLEncoding.EncodeToString(buildMessage(c.config, message)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", gmailSendURL, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusUnauthorized {
+			c.forgetToken()
+		}
+		return fmt.Errorf("Gmail API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// accessToken returns a cached access token, redeeming the refresh token
+// for a new one when it is about to expire
+func (c *GmailClient) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Before(c.expires.Add(-1*time.Minute)) {
+		return c.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {c.config.Gmail.ClientID},
+		"client_secret": {c.config.Gmail.ClientSecret},
+		"refresh_token": {c.config.Gmail.RefreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.Gmail.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error"`
+	}
+	json.Unmarshal(body, &result)
+	if resp.StatusCode != http.StatusOK || result.AccessToken == "" {
+		// Refresh tokens of apps in testing mode expire after seven days
+		if result.Error == "invalid_grant" {
+			return "", fmt.Errorf("Google token error: the refresh token was revoked or expired, authorize again (status %d): %s", resp.StatusCode, string(body))
+		}
+		return "", fmt.Errorf("Google token error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	c.token = result.AccessToken
+	c.expires = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+// forgetToken drops the cached access token after it was rejected, e.g.
+// because the account revoked it early
+func (c *GmailClient) forgetToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+}
+
+// Close closes the Gmail API client
+func (c *GmailClient) Close() error {
+	return nil
+}
@@ -1,6 +1,10 @@
 package email
 
-import "context"
+import (
+	"context"
+	"crypto/x509"
+	"time"
+)
 
 // Message represents an email message
 type Message struct {
@@ -12,12 +16,48 @@ type Message struct {
 // Config represents email configuration
 type Config struct {
 	From     string
+	FromName string // Display name of the sender, optional
 	Password string
 	SMTPHost string
 	SMTPPort string
 	Timeout  int
+
+	// Certificates the SMTP servers are verified against instead of the
+	// system's, e.g. of a test server; optional
+	RootCAs *x509.CertPool
+
+	// Relays tried in order when SMTPHost fails before accepting the
+	// message, e.g. it cannot be reached or rejects the login; optional
+	Relays []Relay
+
+	// Logf reports which relay a message was sent through after a failover,
+	// optional
+	Logf func(format string, args ...any)
+
+	// Gmail holds the OAuth2 credentials GmailFactory clients send with;
+	// SMTP clients ignore it
+	Gmail GmailAuth
 }
 
+// GmailAuth holds the OAuth2 client and refresh token of a Gmail account
+type GmailAuth struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string // Granted for the gmail.send scope
+	TokenURL     string // Defaults to Google's token endpoint
+}
+
+// Relay is a backup SMTP server
+type Relay struct {
+	Host     string
+	Port     string
+	Username string // Defaults to Config.From
+	Password string // Defaults to Config.Password
+}
+
+// relayTimeout bounds each relay attempt when Config.Timeout is not set
+const relayTimeout = 30 * time.Second
+
 // Client defines the email client interface
 type Client interface {
 	Send(ctx context.Context, message Message) error
@@ -0,0 +1,96 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// FileClient writes messages to a regular file or a named pipe
+type FileClient struct {
+	config Config
+	mu     sync.Mutex
+}
+
+// LocalFactory creates file clients
+type LocalFactory struct{}
+
+// NewLocalFactory creates a new file factory
+func NewLocalFactory() *LocalFactory {
+	return &LocalFactory{}
+}
+
+// NewClient creates a new file client
+func (f *LocalFactory) NewClient(config Config) (Client, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("file path is required")
+	}
+
+	return &FileClient{
+		config: config,
+	}, nil
+}
+
+// Send appends the message to the file, or writes it to the named pipe
+func (c *FileClient) Send(ctx context.Context, message Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	text := message.Text
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	info, err := os.Stat(c.config.Path)
+	if err == nil && info.Mode()&os.ModeNamedPipe != 0 {
+		return c.writePipe(ctx, text)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.config.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", c.config.Path, err)
+	}
+
+	f, err := os.OpenFile(c.config.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", c.config.Path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(text); err != nil {
+		return fmt.Errorf("failed to write to %s: %w", c.config.Path, err)
+	}
+
+	return nil
+}
+
+// writePipe writes to a named pipe without blocking when nobody is reading
+func (c *FileClient) writePipe(ctx context.Context, text string) error {
+	f, err := os.OpenFile(c.config.Path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		if errors.Is(err, syscall.ENXIO) {
+			return fmt.Errorf("no reader on named pipe %s", c.config.Path)
+		}
+		return fmt.Errorf("failed to open named pipe %s: %w", c.config.Path, err)
+	}
+	defer f.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = f.SetWriteDeadline(deadline)
+	}
+
+	if _, err := f.WriteString(text); err != nil {
+		return fmt.Errorf("failed to write to named pipe %s: %w", c.config.Path, err)
+	}
+
+	return nil
+}
+
+// Close closes the file client (no-op, the file is opened per message)
+func (c *FileClient) Close() error {
+	return nil
+}
@@ -0,0 +1,24 @@
+package file
+
+import "context"
+
+// Message represents a line of text written to the file
+type Message struct {
+	Text string
+}
+
+// Config represents file output configuration
+type Config struct {
+	Path string // Regular file (appended to) or named pipe
+}
+
+// Client defines the file client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates file clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -0,0 +1,112 @@
+package line
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultPushURL is the Messaging API endpoint pushing messages to a chat
+const DefaultPushURL = "https://api.line.me/v2/bot/message/push"
+
+// maxTextLength is the longest text message LINE accepts, in characters
+const maxTextLength = 5000
+
+// MessagingClient implements the LINE client using the Messaging API. LINE
+// Notify, the former token-only API, was shut down in 2025; a bot's channel
+// access token and a chat ID take its place.
+type MessagingClient struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// MessagingFactory creates LINE Messaging API clients
+type MessagingFactory struct{}
+
+// NewMessagingFactory creates a new LINE factory
+func NewMessagingFactory() *MessagingFactory {
+	return &MessagingFactory{}
+}
+
+// NewClient creates a new LINE Messaging API client
+func (f *MessagingFactory) NewClient(config Config) (Client, error) {
+	if config.Token == "" {
+		return nil, fmt.Errorf("channel access token is required")
+	}
+	if config.To == "" {
+		return nil, fmt.Errorf("recipient ID is required")
+	}
+	if config.APIURL == "" {
+		config.APIURL = DefaultPushURL
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &MessagingClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// pushPayload is the body of a push message request
+type pushPayload struct {
+	To       string        `json:"to"`
+	Messages []textMessage `json:"messages"`
+}
+
+type textMessage struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Send pushes a text message to the configured chat
+func (c *MessagingClient) Send(ctx context.Context, message Message) error {
+	text := message.Text
+	if utf8.RuneCountInString(text) > maxTextLength {
+		text = string([]rune(text)[:maxTextLength-1]) + "…"
+	}
+
+	body, err := json.Marshal(pushPayload{
+		To:       c.config.To,
+		Messages: []textMessage{{Type: "text", Text: text}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal LINE payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.APIURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.config.Token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("LINE API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// Close closes the LINE client
+func (c *MessagingClient) Close() error {
+	return nil
+}
@@ -0,0 +1,27 @@
+package line
+
+import "context"
+
+// Message represents a LINE text message
+type Message struct {
+	Text string
+}
+
+// Config represents LINE configuration
+type Config struct {
+	Token          string // Channel access token of a Messaging API channel
+	To             string // User, group or room ID the bot pushes to
+	APIURL         string // Push endpoint; defaults to the LINE Messaging API
+	TimeoutSeconds int
+}
+
+// Client defines the LINE client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates LINE clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
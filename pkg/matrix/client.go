@@ -0,0 +1,121 @@
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// APIClient implements the Matrix client using the client-server API
+type APIClient struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// APIFactory creates Matrix clients
+type APIFactory struct{}
+
+// NewAPIFactory creates a new Matrix factory
+func NewAPIFactory() *APIFactory {
+	return &APIFactory{}
+}
+
+// NewClient creates a new Matrix client
+func (f *APIFactory) NewClient(config Config) (Client, error) {
+	if config.HomeserverURL == "" {
+		return nil, fmt.Errorf("homeserver URL is required")
+	}
+	if config.AccessToken == "" {
+		return nil, fmt.Errorf("access token is required")
+	}
+	if config.RoomID == "" {
+		return nil, fmt.Errorf("room ID is required")
+	}
+	config.HomeserverURL = strings.TrimRight(config.HomeserverURL, "/")
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &APIClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// messageContent is the content of an m.room.message event
+type messageContent struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+// Send posts a message to the configured room
+func (c *APIClient) Send(ctx context.Context, message Message) error {
+	content := messageContent{
+		MsgType: "m.text",
+		Body:    message.Text,
+	}
+	if message.HTML != "" {
+		content.Format = "org.matrix.custom.html"
+		content.FormattedBody = message.HTML
+	}
+
+	body, err := json.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Matrix message: %w", err)
+	}
+
+	txnID := message.TxnID
+	if txnID == "" {
+		txnID = newTxnID()
+	}
+
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		c.config.HomeserverURL, url.PathEscape(c.config.RoomID), url.PathEscape(txnID))
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("Matrix API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// newTxnID creates a random transaction ID
+func newTxnID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Close closes the Matrix client
+func (c *APIClient) Close() error {
+	return nil
+}
@@ -0,0 +1,29 @@
+package matrix
+
+import "context"
+
+// Message represents a Matrix room message
+type Message struct {
+	Text  string // Plain-text body, shown by clients without HTML support
+	HTML  string // Optional formatted body
+	TxnID string // Transaction ID; resending with the same ID does not duplicate the message
+}
+
+// Config represents Matrix configuration
+type Config struct {
+	HomeserverURL  string // e.g., "https://matrix.example.org"
+	AccessToken    string
+	RoomID         string // e.g., "!abcdef:example.org"
+	TimeoutSeconds int
+}
+
+// Client defines the Matrix client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates Matrix clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
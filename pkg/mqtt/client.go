@@ -0,0 +1,193 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+// keepAlive is announced to the broker; sessions last a single Send
+const keepAlive = 60
+
+// BrokerClient implements the MQTT client, publishing over a short-lived
+// MQTT 3.1.1 session per Send
+type BrokerClient struct {
+	config    Config
+	address   string
+	tlsConfig *tls.Config // Nil for plain TCP
+	timeout   time.Duration
+}
+
+// BrokerFactory creates MQTT clients
+type BrokerFactory struct{}
+
+// NewBrokerFactory creates a new MQTT factory
+func NewBrokerFactory() *BrokerFactory {
+	return &BrokerFactory{}
+}
+
+// NewClient creates a new MQTT client
+func (f *BrokerFactory) NewClient(config Config) (Client, error) {
+	if config.Broker == "" {
+		return nil, fmt.Errorf("broker is required")
+	}
+	if config.QoS < 0 || config.QoS > 2 {
+		return nil, fmt.Errorf("invalid QoS %d (expected 0, 1 or 2)", config.QoS)
+	}
+	if config.ClientID == "" {
+		config.ClientID = "public-ip-monitor"
+	}
+
+	broker, err := url.Parse(config.Broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker URL: %w", err)
+	}
+
+	client := &BrokerClient{config: config}
+
+	port := "1883"
+	switch broker.Scheme {
+	case "mqtt", "tcp":
+	case "mqtts", "ssl", "tls":
+		port = "8883"
+		client.tlsConfig = &tls.Config{
+			ServerName:         broker.Hostname(),
+			InsecureSkipVerify: config.InsecureSkipVerify,
+		}
+		if config.CAFile != "" {
+			pem, err := os.ReadFile(config.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA file: %w", err)
+			}
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", config.CAFile)
+			}
+			client.tlsConfig.RootCAs = roots
+		}
+	default:
+		return nil, fmt.Errorf("unsupported broker scheme %q (expected mqtt or mqtts)", broker.Scheme)
+	}
+	if broker.Port() != "" {
+		port = broker.Port()
+	}
+	client.address = net.JoinHostPort(broker.Hostname(), port)
+
+	client.timeout = time.Duration(config.TimeoutSeconds) * time.Second
+	if client.timeout <= 0 {
+		client.timeout = 30 * time.Second
+	}
+
+	return client, nil
+}
+
+// Send connects to the broker, publishes the messages and disconnects
+func (c *BrokerClient) Send(ctx context.Context, messages ...Message) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if c.tlsConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: c.tlsConfig}).DialContext(ctx, "tcp", c.address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to broker: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	reader := bufio.NewReader(conn)
+
+	connect, err := connectPacket(c.config, keepAlive)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(connect); err != nil {
+		return fmt.Errorf("failed to send CONNECT: %w", err)
+	}
+
+	ack, err := readPacket(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read CONNACK: %w", err)
+	}
+	if ack.kind != packetConnAck || len(ack.body) != 2 {
+		return fmt.Errorf("MQTT broker error: expected CONNACK, got packet type %d", ack.kind)
+	}
+	if code := ack.body[1]; code != 0 {
+		reason, ok := connackErrors[code]
+		if !ok {
+			reason = "unknown error"
+		}
+		return fmt.Errorf("MQTT broker error (code %d): %s", code, reason)
+	}
+
+	for i, message := range messages {
+		// Packet identifiers are per session and must be non-zero
+		if err := c.publish(conn, reader, message, uint16(i+1)); err != nil {
+			return fmt.Errorf("failed to publish to %s: %w", message.Topic, err)
+		}
+	}
+
+	// A clean disconnect tells the broker not to publish any will message
+	disconnect, _ := encodePacket(packetDisconnect<<4, nil)
+	conn.Write(disconnect)
+
+	return nil
+}
+
+// publish sends a message and completes the acknowledgement flow of its QoS
+func (c *BrokerClient) publish(conn net.Conn, reader *bufio.Reader, message Message, id uint16) error {
+	publish, err := publishPacket(message, c.config.QoS, id)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Write(publish); err != nil {
+		return err
+	}
+
+	switch c.config.QoS {
+	case 1:
+		return expectAck(reader, packetPubAck, id)
+	case 2:
+		if err := expectAck(reader, packetPubRec, id); err != nil {
+			return err
+		}
+		if _, err := conn.Write(ackPacket(packetPubRel, id)); err != nil {
+			return err
+		}
+		return expectAck(reader, packetPubComp, id)
+	}
+	return nil
+}
+
+// expectAck waits for an acknowledgement of the given packet identifier
+func expectAck(reader *bufio.Reader, kind byte, id uint16) error {
+	for {
+		p, err := readPacket(reader)
+		if err != nil {
+			return fmt.Errorf("failed to read acknowledgement: %w", err)
+		}
+		if p.kind == kind && len(p.body) >= 2 && binary.BigEndian.Uint16(p.body) == id {
+			return nil
+		}
+		// Anything else (e.g., a PINGRESP) is not for us
+	}
+}
+
+// Close closes the MQTT client
+func (c *BrokerClient) Close() error {
+	return nil
+}
@@ -0,0 +1,146 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Control packet types of MQTT 3.1.1
+const (
+	packetConnect    = 1
+	packetConnAck    = 2
+	packetPublish    = 3
+	packetPubAck     = 4
+	packetPubRec     = 5
+	packetPubRel     = 6
+	packetPubComp    = 7
+	packetDisconnect = 14
+)
+
+// maxRemainingLength is the largest remaining length the protocol can encode
+const maxRemainingLength = 268435455
+
+// connackErrors describes CONNACK return codes
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// packet is a decoded control packet
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+// encodePacket builds a control packet from its header byte and body
+func encodePacket(header byte, body []byte) ([]byte, error) {
+	if len(body) > maxRemainingLength {
+		return nil, fmt.Errorf("packet of %d bytes is too large", len(body))
+	}
+
+	out := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		out = append(out, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(out, body...), nil
+}
+
+// readPacket reads a control packet
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, fmt.Errorf("malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(digit&0x7F) * multiplier
+		multiplier *= 128
+		if digit&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: header >> 4, flags: header & 0x0F, body: body}, nil
+}
+
+// appendString appends a length-prefixed UTF-8 string
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// connectPacket builds a CONNECT packet
+func connectPacket(config Config, keepAlive uint16) ([]byte, error) {
+	flags := byte(0x02) // Clean session
+	if config.Username != "" {
+		flags |= 0x80
+	}
+	if config.Password != "" {
+		flags |= 0x40
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // Protocol level 4 is MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, keepAlive)
+	body = appendString(body, config.ClientID)
+	if config.Username != "" {
+		body = appendString(body, config.Username)
+	}
+	if config.Password != "" {
+		body = appendString(body, config.Password)
+	}
+
+	return encodePacket(packetConnect<<4, body)
+}
+
+// publishPacket builds a PUBLISH packet
+func publishPacket(message Message, qos int, id uint16) ([]byte, error) {
+	header := byte(packetPublish<<4) | byte(qos)<<1
+	if message.Retain {
+		header |= 0x01
+	}
+
+	body := appendString(nil, message.Topic)
+	if qos > 0 {
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	body = append(body, message.Payload...)
+
+	return encodePacket(header, body)
+}
+
+// ackPacket builds a PUBACK, PUBREC, PUBREL or PUBCOMP packet
+func ackPacket(kind byte, id uint16) []byte {
+	header := kind << 4
+	if kind == packetPubRel {
+		header |= 0x02 // Reserved flags required by the spec
+	}
+	return []byte{header, 2, byte(id >> 8), byte(id)}
+}
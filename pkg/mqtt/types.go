@@ -0,0 +1,34 @@
+package mqtt
+
+import "context"
+
+// Message represents an MQTT publication
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool // Broker keeps the message and hands it to new subscribers
+}
+
+// Config represents MQTT configuration
+type Config struct {
+	Broker             string // e.g., "mqtt://broker:1883" or "mqtts://broker:8883" for TLS
+	ClientID           string
+	Username           string
+	Password           string
+	QoS                int    // 0 (at most once), 1 (at least once) or 2 (exactly once)
+	CAFile             string // PEM bundle verifying the broker instead of the system roots
+	InsecureSkipVerify bool   // Accept any broker certificate (self-signed test brokers only)
+	TimeoutSeconds     int
+}
+
+// Client defines the MQTT client interface
+type Client interface {
+	// Send publishes the messages in order over a single broker session
+	Send(ctx context.Context, messages ...Message) error
+	Close() error
+}
+
+// Factory creates MQTT clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -0,0 +1,133 @@
+package ntfy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultServer is the public ntfy server
+const DefaultServer = "https://ntfy.sh"
+
+// HTTPClient implements the ntfy client using the JSON publish API
+type HTTPClient struct {
+	config     Config
+	priority   int
+	httpClient *http.Client
+}
+
+// HTTPFactory creates ntfy clients
+type HTTPFactory struct{}
+
+// NewHTTPFactory creates a new ntfy factory
+func NewHTTPFactory() *HTTPFactory {
+	return &HTTPFactory{}
+}
+
+// NewClient creates a new ntfy client
+func (f *HTTPFactory) NewClient(config Config) (Client, error) {
+	if config.Topic == "" {
+		return nil, fmt.Errorf("topic is required")
+	}
+	if config.Server == "" {
+		config.Server = DefaultServer
+	}
+	config.Server = strings.TrimRight(config.Server, "/")
+
+	priority, err := ParsePriority(config.Priority)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &HTTPClient{
+		config:   config,
+		priority: priority,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// ParsePriority converts a priority name or number to ntfy's 1-5 scale
+func ParsePriority(priority string) (int, error) {
+	switch strings.ToLower(priority) {
+	case "", "default":
+		return 3, nil
+	case "min":
+		return 1, nil
+	case "low":
+		return 2, nil
+	case "high":
+		return 4, nil
+	case "max", "urgent":
+		return 5, nil
+	}
+
+	value, err := strconv.Atoi(priority)
+	if err != nil || value < 1 || value > 5 {
+		return 0, fmt.Errorf("invalid priority %q (expected min, low, default, high, max or 1-5)", priority)
+	}
+	return value, nil
+}
+
+// publishPayload is the JSON body accepted by the ntfy publish API
+type publishPayload struct {
+	Topic    string   `json:"topic"`
+	Title    string   `json:"title,omitempty"`
+	Message  string   `json:"message"`
+	Priority int      `json:"priority,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// Send publishes a message to the configured topic
+func (c *HTTPClient) Send(ctx context.Context, message Message) error {
+	body, err := json.Marshal(publishPayload{
+		Topic:    c.config.Topic,
+		Title:    message.Title,
+		Message:  message.Text,
+		Priority: c.priority,
+		Tags:     message.Tags,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal ntfy payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.Server, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("ntfy returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// Close closes the ntfy client
+func (c *HTTPClient) Close() error {
+	return nil
+}
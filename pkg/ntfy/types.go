@@ -0,0 +1,30 @@
+package ntfy
+
+import "context"
+
+// Message represents an ntfy notification
+type Message struct {
+	Title string
+	Text  string
+	Tags  []string // Emoji shortcodes or plain tags, e.g. "rotating_light"
+}
+
+// Config represents ntfy configuration
+type Config struct {
+	Server         string // e.g., "https://ntfy.sh" or a self-hosted server
+	Topic          string
+	Priority       string // "min", "low", "default", "high", "max"/"urgent" or 1-5
+	Token          string // Access token for protected topics
+	TimeoutSeconds int
+}
+
+// Client defines the ntfy client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates ntfy clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -0,0 +1,93 @@
+package openpgp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// armor encodes data as an ASCII-armored block of the given type, e.g.
+// "PGP MESSAGE"
+func armor(blockType string, data []byte) string {
+	var out strings.Builder
+	out.WriteString("-----BEGIN " + blockType + "-----\n\n")
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 64 {
+		out.WriteString(encoded[:64] + "\n")
+		encoded = encoded[64:]
+	}
+	out.WriteString(encoded + "\n")
+
+	crc := crc24(data)
+	out.WriteString("=" + base64.StdEncoding.EncodeToString([]byte{byte(crc >> 16), byte(crc >> 8), byte(crc)}) + "\n")
+	out.WriteString("-----END " + blockType + "-----\n")
+	return out.String()
+}
+
+// dearmor decodes the first ASCII-armored block of the given type
+func dearmor(blockType string, text []byte) ([]byte, error) {
+	begin := "-----BEGIN " + blockType + "-----"
+	end := "-----END " + blockType + "-----"
+
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	state := 0 // 0: before the block, 1: headers, 2: data
+	var body strings.Builder
+	var checksum string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case state == 0:
+			if line == begin {
+				state = 1
+			}
+		case state == 1:
+			// Armor headers such as "Comment: ..." end with an empty line
+			if line == "" {
+				state = 2
+			} else if !strings.Contains(line, ": ") {
+				state = 2
+				body.WriteString(line)
+			}
+		case line == end:
+			data, err := base64.StdEncoding.DecodeString(body.String())
+			if err != nil {
+				return nil, fmt.Errorf("invalid armor: %w", err)
+			}
+			if checksum != "" {
+				sum, err := base64.StdEncoding.DecodeString(checksum)
+				crc := crc24(data)
+				if err != nil || len(sum) != 3 || sum[0] != byte(crc>>16) || sum[1] != byte(crc>>8) || sum[2] != byte(crc) {
+					return nil, fmt.Errorf("invalid armor checksum")
+				}
+			}
+			return data, nil
+		case strings.HasPrefix(line, "="):
+			checksum = line[1:]
+		default:
+			body.WriteString(line)
+		}
+	}
+
+	if state == 0 {
+		return nil, fmt.Errorf("no %s found", blockType)
+	}
+	return nil, fmt.Errorf("%s is not terminated", blockType)
+}
+
+// crc24 computes the armor checksum (RFC 4880, section 6.1)
+func crc24(data []byte) uint32 {
+	crc := uint32(0xB704CE)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864CFB
+			}
+		}
+	}
+	return crc & 0xFFFFFF
+}
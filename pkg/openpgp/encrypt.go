@@ -0,0 +1,205 @@
+package openpgp
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	_ "crypto/sha256" // Key derivation hashes of ECDH keys
+	_ "crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Symmetric algorithm IDs of the OpenPGP format
+const (
+	cipherAES128 = 7
+	cipherAES192 = 8
+	cipherAES256 = 9
+)
+
+// Hash algorithm IDs of the OpenPGP format
+var kdfHashes = map[byte]crypto.Hash{
+	8:  crypto.SHA256,
+	9:  crypto.SHA384,
+	10: crypto.SHA512,
+}
+
+// Encrypt encrypts text to the key, returning an ASCII-armored OpenPGP
+// message that any OpenPGP client (gpg, Thunderbird, OpenKeychain) can
+// decrypt. The message is encrypted with AES-256 and integrity protected.
+func Encrypt(key *PublicKey, text []byte) (string, error) {
+	sessionKey := make([]byte, 32)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return "", err
+	}
+
+	pkesk, err := encryptSessionKey(key, sessionKey)
+	if err != nil {
+		return "", err
+	}
+
+	// Literal data packet: UTF-8 text, no file name
+	literal := []byte{'u', 0}
+	literal = binary.BigEndian.AppendUint32(literal, uint32(time.Now().Unix()))
+	literal = append(literal, text...)
+	plaintext := appendPacket(nil, tagLiteral, literal)
+
+	seipd, err := encryptData(sessionKey, plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	message := appendPacket(nil, tagPKESK, pkesk)
+	message = appendPacket(message, tagSEIPD, seipd)
+	return armor("PGP MESSAGE", message), nil
+}
+
+// encryptSessionKey builds the body of a public-key encrypted session key
+// packet (version 3)
+func encryptSessionKey(key *PublicKey, sessionKey []byte) ([]byte, error) {
+	body := []byte{3}
+	body = binary.BigEndian.AppendUint64(body, key.KeyID)
+	body = append(body, key.algorithm)
+
+	// Algorithm, key and checksum
+	var sum uint16
+	for _, b := range sessionKey {
+		sum += uint16(b)
+	}
+	m := append([]byte{cipherAES256}, sessionKey...)
+	m = binary.BigEndian.AppendUint16(m, sum)
+
+	switch key.algorithm {
+	case algoRSA, algoRSAEncrypt:
+		encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, key.rsa, m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt session key: %w", err)
+		}
+		return appendMPI(body, encrypted), nil
+	case algoECDH:
+		return appendECDH(body, key, m)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %d", key.algorithm)
+	}
+}
+
+// appendECDH appends the session key encrypted to a Curve25519 key (RFC 6637)
+func appendECDH(body []byte, key *PublicKey, m []byte) ([]byte, error) {
+	hash, ok := kdfHashes[key.kdfHash]
+	if !ok {
+		return nil, fmt.Errorf("unsupported ECDH hash algorithm %d", key.kdfHash)
+	}
+	var kekSize int
+	switch key.kdfCipher {
+	case cipherAES128:
+		kekSize = 16
+	case cipherAES192:
+		kekSize = 24
+	case cipherAES256:
+		kekSize = 32
+	default:
+		return nil, fmt.Errorf("unsupported ECDH cipher %d", key.kdfCipher)
+	}
+
+	recipient, err := ecdh.X25519().NewPublicKey(key.point)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Curve25519 key: %w", err)
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %w", err)
+	}
+
+	// Key derivation parameters bind the key to the recipient
+	param := append([]byte{byte(len(oidCurve25519))}, oidCurve25519...)
+	param = append(param, algoECDH, 3, 1, key.kdfHash, key.kdfCipher)
+	param = append(param, "Anonymous Sender    "...)
+	param = append(param, key.Fingerprint[:]...)
+
+	h := hash.New()
+	h.Write([]byte{0, 0, 0, 1})
+	h.Write(shared)
+	h.Write(param)
+	kek := h.Sum(nil)[:kekSize]
+
+	// PKCS#5 padding to a multiple of 8 bytes for the key wrap
+	padding := 8 - len(m)%8
+	for i := 0; i < padding; i++ {
+		m = append(m, byte(padding))
+	}
+	wrapped, err := wrapKey(kek, m)
+	if err != nil {
+		return nil, err
+	}
+
+	body = appendMPI(body, append([]byte{0x40}, ephemeral.PublicKey().Bytes()...))
+	body = append(body, byte(len(wrapped)))
+	return append(body, wrapped...), nil
+}
+
+// wrapKey wraps data with the AES key wrap algorithm (RFC 3394)
+func wrapKey(kek, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(data) / 8
+	a := []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+	r := append([]byte(nil), data...)
+	b := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 0; i < n; i++ {
+			copy(b, a)
+			copy(b[8:], r[i*8:i*8+8])
+			block.Encrypt(b, b)
+			t := uint64(n*j + i + 1)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(b[:8])^t)
+			copy(r[i*8:], b[8:])
+		}
+	}
+	return append(a, r...), nil
+}
+
+// encryptData builds the body of a symmetrically encrypted integrity
+// protected data packet (version 1): a random prefix, the plaintext and a
+// modification detection code, encrypted with AES in CFB mode
+func encryptData(sessionKey, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, aes.BlockSize, aes.BlockSize+2)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	prefix = append(prefix, prefix[aes.BlockSize-2:]...)
+
+	data := append(prefix, plaintext...)
+	data = append(data, 0xD3, 0x14)
+	mdc := sha1.Sum(data)
+	data = append(data, mdc[:]...)
+
+	// CFB with an all-zero IV
+	feedback := make([]byte, aes.BlockSize)
+	stream := make([]byte, aes.BlockSize)
+	for i := 0; i < len(data); i += aes.BlockSize {
+		block.Encrypt(stream, feedback)
+		end := min(i+aes.BlockSize, len(data))
+		for j := i; j < end; j++ {
+			data[j] ^= stream[j-i]
+		}
+		copy(feedback, data[i:end])
+	}
+
+	return append([]byte{1}, data...), nil
+}
@@ -0,0 +1,147 @@
+package openpgp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Fixture keys were generated with gpg 2.2 and have no passphrase; they
+// protect nothing but these tests
+var fixtureKeys = []string{"rsa", "cv25519"}
+
+// TestWrapKeyRFC3394 checks the key wrap against the test vectors of RFC
+// 3394, sections 4.1 and 4.6
+func TestWrapKeyRFC3394(t *testing.T) {
+	tests := []struct {
+		kek, data, want string
+	}{
+		{
+			kek:  "000102030405060708090A0B0C0D0E0F",
+			data: "00112233445566778899AABBCCDDEEFF",
+			want: "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5",
+		},
+		{
+			kek:  "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F",
+			data: "00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F",
+			want: "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21",
+		},
+	}
+	for _, tt := range tests {
+		got, err := wrapKey(mustHex(t, tt.kek), mustHex(t, tt.data))
+		if err != nil {
+			t.Fatalf("wrapKey: %v", err)
+		}
+		if !bytes.Equal(got, mustHex(t, tt.want)) {
+			t.Errorf("wrapKey(%s) = %X, want %s", tt.kek, got, tt.want)
+		}
+	}
+}
+
+// TestEncryptData decrypts the data packet with the standard library's CFB
+// mode and checks the prefix and the modification detection code
+func TestEncryptData(t *testing.T) {
+	sessionKey := bytes.Repeat([]byte{0x42}, 32)
+	plaintext := []byte("IP changed from 203.0.113.1 to 198.51.100.7, and a tail longer than a block")
+
+	body, err := encryptData(sessionKey, plaintext)
+	if err != nil {
+		t.Fatalf("encryptData: %v", err)
+	}
+	if body[0] != 1 {
+		t.Fatalf("version = %d, want 1", body[0])
+	}
+
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, len(body)-1)
+	cipher.NewCFBDecrypter(block, make([]byte, aes.BlockSize)).XORKeyStream(data, body[1:])
+
+	prefix := data[:aes.BlockSize+2]
+	if !bytes.Equal(prefix[aes.BlockSize-2:aes.BlockSize], prefix[aes.BlockSize:]) {
+		t.Errorf("prefix does not repeat its last two bytes: %X", prefix)
+	}
+	mdcStart := len(data) - sha1.Size - 2
+	if got := data[len(prefix):mdcStart]; !bytes.Equal(got, plaintext) {
+		t.Errorf("plaintext = %q, want %q", got, plaintext)
+	}
+	if data[mdcStart] != 0xD3 || data[mdcStart+1] != 0x14 {
+		t.Errorf("MDC packet header = %X, want D314", data[mdcStart:mdcStart+2])
+	}
+	if sum := sha1.Sum(data[:mdcStart+2]); !bytes.Equal(sum[:], data[mdcStart+2:]) {
+		t.Error("MDC does not match the decrypted data")
+	}
+}
+
+// TestEncryptDecryptsWithGPG encrypts to the fixture keys and decrypts the
+// messages with gpg, the client most recipients use
+func TestEncryptDecryptsWithGPG(t *testing.T) {
+	gpg, err := exec.LookPath("gpg")
+	if err != nil {
+		t.Skip("gpg not installed")
+	}
+	home := t.TempDir()
+	t.Cleanup(func() {
+		// The agent gpg starts would outlive the test
+		exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run()
+	})
+	text := "Your public IP address has changed: 203.0.113.1 → 198.51.100.7\n"
+
+	for _, name := range fixtureKeys {
+		t.Run(name, func(t *testing.T) {
+			armored, err := os.ReadFile(filepath.Join("testdata", name+".pub.asc"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := ReadPublicKey(armored)
+			if err != nil {
+				t.Fatalf("ReadPublicKey: %v", err)
+			}
+			runGPG(t, gpg, home, nil, "--import", filepath.Join("testdata", name+".sec.asc"))
+
+			message, err := Encrypt(key, []byte(text))
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			got := runGPG(t, gpg, home, strings.NewReader(message), "--decrypt")
+			if got != text {
+				t.Errorf("gpg decrypted %q, want %q", got, text)
+			}
+		})
+	}
+}
+
+// runGPG runs gpg non-interactively with its own home directory, failing
+// the test when it fails, e.g. on a message it cannot decrypt or whose
+// integrity check fails
+func runGPG(t *testing.T, gpg, home string, stdin *strings.Reader, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(gpg, append([]string{"--homedir", home, "--batch", "--quiet", "--pinentry-mode", "loopback", "--passphrase", ""}, args...)...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("gpg %s: %v\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	return stdout.String()
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
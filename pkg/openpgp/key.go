@@ -0,0 +1,292 @@
+package openpgp
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Packet tags of the OpenPGP format
+const (
+	tagPKESK     = 1
+	tagSignature = 2
+	tagPublicKey = 6
+	tagLiteral   = 11
+	tagSubkey    = 14
+	tagSEIPD     = 18
+)
+
+// Key flags allowing encryption (RFC 4880, section 5.2.3.21)
+const flagsEncrypt = 0x04 | 0x08
+
+// packet is an OpenPGP packet with its body
+type packet struct {
+	tag  byte
+	body []byte
+}
+
+// candidate is a key of the certificate with what its self-signature says
+type candidate struct {
+	key   *PublicKey
+	flags int // Key flags of the self-signature; -1 when there are none
+}
+
+// ReadPublicKey reads the encryption key of an ASCII-armored OpenPGP
+// certificate ("gpg --armor --export"). The first subkey flagged for
+// encryption is used, or the primary key if it can encrypt itself.
+func ReadPublicKey(armored []byte) (*PublicKey, error) {
+	data, err := dearmor("PGP PUBLIC KEY BLOCK", armored)
+	if err != nil {
+		return nil, err
+	}
+	packets, err := readPackets(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(packets) == 0 || packets[0].tag != tagPublicKey {
+		return nil, fmt.Errorf("not an OpenPGP certificate")
+	}
+
+	var primary *candidate
+	var subkeys []*candidate
+	var current *candidate
+	for _, p := range packets {
+		switch p.tag {
+		case tagPublicKey, tagSubkey:
+			key, err := parsePublicKey(p.body)
+			if err != nil && !errors.Is(err, errUnsupported) {
+				return nil, err
+			}
+			current = &candidate{key: key, flags: -1}
+			if p.tag == tagPublicKey {
+				primary = current
+			} else {
+				subkeys = append(subkeys, current)
+			}
+		case tagSignature:
+			if current != nil && current.flags < 0 {
+				current.flags = keyFlags(p.body)
+			}
+		}
+	}
+
+	for _, sub := range subkeys {
+		if sub.key != nil && (sub.flags < 0 || sub.flags&flagsEncrypt != 0) {
+			return sub.key, nil
+		}
+	}
+	if primary.key != nil && primary.key.algorithm != algoECDH && (primary.flags < 0 || primary.flags&flagsEncrypt != 0) {
+		return primary.key, nil
+	}
+	return nil, fmt.Errorf("certificate has no supported encryption key (RSA or cv25519)")
+}
+
+// errUnsupported marks keys of algorithms or versions that cannot be used
+var errUnsupported = errors.New("unsupported key")
+
+// parsePublicKey parses the body of a version 4 public key or subkey packet
+func parsePublicKey(body []byte) (*PublicKey, error) {
+	if len(body) < 6 {
+		return nil, fmt.Errorf("truncated public key packet")
+	}
+	if body[0] != 4 {
+		return nil, errUnsupported
+	}
+
+	key := &PublicKey{algorithm: body[5]}
+	fields := body[6:]
+	switch key.algorithm {
+	case algoRSA, algoRSAEncrypt:
+		n, rest, err := readMPI(fields)
+		if err != nil {
+			return nil, err
+		}
+		e, _, err := readMPI(rest)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31 {
+			return nil, fmt.Errorf("unsupported RSA exponent")
+		}
+		key.rsa = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	case algoECDH:
+		if len(fields) < 1 || len(fields) < 1+int(fields[0]) {
+			return nil, fmt.Errorf("truncated ECDH key")
+		}
+		oid := fields[1 : 1+fields[0]]
+		if !bytes.Equal(oid, oidCurve25519) {
+			return nil, errUnsupported
+		}
+		point, rest, err := readMPI(fields[1+fields[0]:])
+		if err != nil {
+			return nil, err
+		}
+		if len(point) != 33 || point[0] != 0x40 {
+			return nil, fmt.Errorf("invalid Curve25519 point")
+		}
+		if len(rest) < 4 || rest[0] != 3 || rest[1] != 1 {
+			return nil, fmt.Errorf("invalid ECDH key derivation parameters")
+		}
+		key.point = point[1:]
+		key.kdfHash = rest[2]
+		key.kdfCipher = rest[3]
+	default:
+		return nil, errUnsupported
+	}
+
+	// V4 fingerprint: SHA-1 over the packet with an old-format header
+	h := sha1.New()
+	h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+	h.Write(body)
+	copy(key.Fingerprint[:], h.Sum(nil))
+	key.KeyID = binary.BigEndian.Uint64(key.Fingerprint[12:])
+
+	return key, nil
+}
+
+// keyFlags returns the key flags of a version 4 signature, or -1 if it has
+// none
+func keyFlags(body []byte) int {
+	if len(body) < 6 || body[0] != 4 {
+		return -1
+	}
+	length := int(binary.BigEndian.Uint16(body[4:6]))
+	if len(body) < 6+length {
+		return -1
+	}
+	subpackets := body[6 : 6+length]
+
+	for len(subpackets) > 0 {
+		var size, header int
+		switch first := int(subpackets[0]); {
+		case first < 192:
+			size, header = first, 1
+		case first < 255 && len(subpackets) >= 2:
+			size, header = (first-192)<<8+int(subpackets[1])+192, 2
+		case first == 255 && len(subpackets) >= 5:
+			size, header = int(binary.BigEndian.Uint32(subpackets[1:5])), 5
+		default:
+			return -1
+		}
+		if size == 0 || len(subpackets) < header+size {
+			return -1
+		}
+		data := subpackets[header : header+size]
+		if data[0]&0x7F == 27 && len(data) > 1 {
+			return int(data[1])
+		}
+		subpackets = subpackets[header+size:]
+	}
+	return -1
+}
+
+// readPackets splits data into packets
+func readPackets(data []byte) ([]packet, error) {
+	var packets []packet
+	for len(data) > 0 {
+		header := data[0]
+		if header&0x80 == 0 {
+			return nil, fmt.Errorf("invalid packet header")
+		}
+
+		var tag byte
+		var length, offset int
+		if header&0x40 != 0 {
+			// New format
+			tag = header & 0x3F
+			if len(data) < 2 {
+				return nil, fmt.Errorf("truncated packet header")
+			}
+			switch first := int(data[1]); {
+			case first < 192:
+				length, offset = first, 2
+			case first < 224 && len(data) >= 3:
+				length, offset = (first-192)<<8+int(data[2])+192, 3
+			case first == 255 && len(data) >= 6:
+				length, offset = int(binary.BigEndian.Uint32(data[2:6])), 6
+			default:
+				return nil, fmt.Errorf("unsupported packet length")
+			}
+		} else {
+			// Old format
+			tag = (header >> 2) & 0x0F
+			switch header & 0x03 {
+			case 0:
+				if len(data) >= 2 {
+					length, offset = int(data[1]), 2
+				}
+			case 1:
+				if len(data) >= 3 {
+					length, offset = int(binary.BigEndian.Uint16(data[1:3])), 3
+				}
+			case 2:
+				if len(data) >= 5 {
+					length, offset = int(binary.BigEndian.Uint32(data[1:5])), 5
+				}
+			}
+			if offset == 0 {
+				return nil, fmt.Errorf("unsupported packet length")
+			}
+		}
+
+		if length < 0 || len(data) < offset+length {
+			return nil, fmt.Errorf("truncated packet")
+		}
+		packets = append(packets, packet{tag: tag, body: data[offset : offset+length]})
+		data = data[offset+length:]
+	}
+	return packets, nil
+}
+
+// readMPI reads a multiprecision integer, returning its bytes and the rest
+func readMPI(data []byte) ([]byte, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, fmt.Errorf("truncated MPI")
+	}
+	bits := int(binary.BigEndian.Uint16(data))
+	size := (bits + 7) / 8
+	if len(data) < 2+size {
+		return nil, nil, fmt.Errorf("truncated MPI")
+	}
+	return data[2 : 2+size], data[2+size:], nil
+}
+
+// appendMPI appends value as a multiprecision integer
+func appendMPI(out, value []byte) []byte {
+	value = bytes.TrimLeft(value, "\x00")
+	bits := 0
+	if len(value) > 0 {
+		bits = (len(value)-1)*8 + bitLength(value[0])
+	}
+	out = binary.BigEndian.AppendUint16(out, uint16(bits))
+	return append(out, value...)
+}
+
+func bitLength(b byte) int {
+	n := 0
+	for ; b != 0; b >>= 1 {
+		n++
+	}
+	return n
+}
+
+// appendPacket appends a packet with a new-format header
+func appendPacket(out []byte, tag byte, body []byte) []byte {
+	out = append(out, 0xC0|tag)
+	switch n := len(body); {
+	case n < 192:
+		out = append(out, byte(n))
+	case n < 8384:
+		n -= 192
+		out = append(out, byte(n>>8)+192, byte(n))
+	default:
+		out = append(out, 0xFF)
+		out = binary.BigEndian.AppendUint32(out, uint32(n))
+	}
+	return append(out, body...)
+}
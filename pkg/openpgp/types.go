@@ -0,0 +1,36 @@
+package openpgp
+
+import (
+	"crypto/rsa"
+	"fmt"
+)
+
+// Public-key algorithm IDs of the OpenPGP format
+const (
+	algoRSA        = 1
+	algoRSAEncrypt = 2
+	algoECDH       = 18
+)
+
+// oidCurve25519 identifies Curve25519 in ECDH keys
+var oidCurve25519 = []byte{0x2B, 0x06, 0x01, 0x04, 0x01, 0x97, 0x55, 0x01, 0x05, 0x01}
+
+// PublicKey is the key of an OpenPGP certificate that messages are
+// encrypted to: an RSA or Curve25519 (cv25519) encryption key
+type PublicKey struct {
+	KeyID       uint64
+	Fingerprint [20]byte
+
+	algorithm byte
+	rsa       *rsa.PublicKey
+
+	// ECDH parameters
+	point     []byte // Curve25519 public key, without the 0x40 prefix
+	kdfHash   byte   // Hash algorithm ID of the key derivation
+	kdfCipher byte   // Symmetric algorithm ID of the key wrapping
+}
+
+// String returns the key ID as shown by gpg
+func (k *PublicKey) String() string {
+	return fmt.Sprintf("%016X", k.KeyID)
+}
@@ -0,0 +1,126 @@
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultEventsURL is the Events API v2 endpoint
+const DefaultEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// EventsClient implements the PagerDuty client using the Events API v2
+type EventsClient struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// EventsFactory creates PagerDuty clients
+type EventsFactory struct{}
+
+// NewEventsFactory creates a new PagerDuty factory
+func NewEventsFactory() *EventsFactory {
+	return &EventsFactory{}
+}
+
+// NewClient creates a new PagerDuty client
+func (f *EventsFactory) NewClient(config Config) (Client, error) {
+	if config.RoutingKey == "" {
+		return nil, fmt.Errorf("routing key is required")
+	}
+	if config.EventsURL == "" {
+		config.EventsURL = DefaultEventsURL
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &EventsClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// eventPayload is the JSON body of the Events API v2
+type eventPayload struct {
+	RoutingKey  string          `json:"routing_key"`
+	EventAction string          `json:"event_action"`
+	DedupKey    string          `json:"dedup_key,omitempty"`
+	Payload     *detailsPayload `json:"payload,omitempty"`
+}
+
+type detailsPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// Send enqueues an event
+func (c *EventsClient) Send(ctx context.Context, message Message) error {
+	payload := eventPayload{
+		RoutingKey:  c.config.RoutingKey,
+		EventAction: message.Action,
+		DedupKey:    message.DedupKey,
+	}
+
+	// Resolve events only need the dedup key
+	if message.Action != ActionResolve {
+		details := &detailsPayload{
+			Summary:       message.Summary,
+			Source:        message.Source,
+			Severity:      message.Severity,
+			Component:     message.Component,
+			Class:         message.Class,
+			CustomDetails: message.CustomDetails,
+		}
+		if !message.Timestamp.IsZero() {
+			details.Timestamp = message.Timestamp.UTC().Format(time.RFC3339)
+		}
+		// Summaries are limited to 1024 characters
+		if len(details.Summary) > 1024 {
+			details.Summary = details.Summary[:1021] + "..."
+		}
+		payload.Payload = details
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.EventsURL, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("PagerDuty API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// Close closes the PagerDuty client
+func (c *EventsClient) Close() error {
+	return nil
+}
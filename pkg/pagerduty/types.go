@@ -0,0 +1,43 @@
+package pagerduty
+
+import (
+	"context"
+	"time"
+)
+
+// Event actions of the Events API v2
+const (
+	ActionTrigger = "trigger"
+	ActionResolve = "resolve"
+)
+
+// Message represents a PagerDuty event
+type Message struct {
+	Action        string // ActionTrigger or ActionResolve
+	DedupKey      string // Identifies the incident across trigger and resolve
+	Summary       string
+	Source        string // Affected system, e.g. the hostname
+	Severity      string // "critical", "error", "warning" or "info"
+	Component     string
+	Class         string
+	Timestamp     time.Time
+	CustomDetails map[string]string
+}
+
+// Config represents PagerDuty configuration
+type Config struct {
+	RoutingKey     string // Integration key of an Events API v2 integration
+	EventsURL      string // Defaults to DefaultEventsURL
+	TimeoutSeconds int
+}
+
+// Client defines the PagerDuty client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates PagerDuty clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -0,0 +1,106 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Type IDs of the Thrift compact protocol
+const (
+	thriftTrue   = 1
+	thriftFalse  = 2
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the Parquet metadata structures with the Thrift
+// compact protocol
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // ID of the last field written, per open struct
+}
+
+// beginStruct starts a struct; the top-level struct and list elements are
+// started directly, nested struct fields through structField
+func (w *thriftWriter) beginStruct() {
+	w.last = append(w.last, 0)
+}
+
+// endStruct writes the stop marker of the open struct
+func (w *thriftWriter) endStruct() {
+	w.buf.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) i32Field(id int16, value int32) {
+	w.fieldHeader(id, thriftI32)
+	w.varint(zigzag(int64(value)))
+}
+
+func (w *thriftWriter) i64Field(id int16, value int64) {
+	w.fieldHeader(id, thriftI64)
+	w.varint(zigzag(value))
+}
+
+func (w *thriftWriter) boolField(id int16, value bool) {
+	if value {
+		w.fieldHeader(id, thriftTrue)
+	} else {
+		w.fieldHeader(id, thriftFalse)
+	}
+}
+
+func (w *thriftWriter) stringField(id int16, value string) {
+	w.fieldHeader(id, thriftBinary)
+	w.binary(value)
+}
+
+// structField starts a nested struct, closed with endStruct
+func (w *thriftWriter) structField(id int16) {
+	w.fieldHeader(id, thriftStruct)
+	w.beginStruct()
+}
+
+// listField starts a list of size elements, which are written right after
+func (w *thriftWriter) listField(id int16, elementType byte, size int) {
+	w.fieldHeader(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elementType)
+	} else {
+		w.buf.WriteByte(0xF0 | elementType)
+		w.varint(uint64(size))
+	}
+}
+
+// i32 writes an i32 list element
+func (w *thriftWriter) i32(value int32) {
+	w.varint(zigzag(int64(value)))
+}
+
+// binary writes a string list element or field value
+func (w *thriftWriter) binary(value string) {
+	w.varint(uint64(len(value)))
+	w.buf.WriteString(value)
+}
+
+func (w *thriftWriter) varint(value uint64) {
+	w.buf.Write(binary.AppendUvarint(nil, value))
+}
+
+func zigzag(value int64) uint64 {
+	return uint64(value<<1) ^ uint64(value>>63)
+}
@@ -0,0 +1,17 @@
+package parquet
+
+// Type is the type of the values of a column
+type Type int
+
+const (
+	String    Type = iota // UTF-8 string
+	Int64                 // 64-bit signed integer
+	Timestamp             // time.Time, stored in UTC with microsecond precision
+)
+
+// Column describes a column of the file
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool // Rows may have no value (nil) for the column
+}
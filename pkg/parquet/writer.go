@@ -0,0 +1,238 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// magic starts and ends every Parquet file
+const magic = "PAR1"
+
+// Values of the enums of the Parquet format used by the writer
+const (
+	physicalInt64     = 2
+	physicalByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+	pageTypeData      = 0
+)
+
+// chunk is the location of a written column chunk
+type chunk struct {
+	offset int64
+	size   int64
+}
+
+// Write writes the rows as an uncompressed Parquet file with a single row
+// group. Each row has one value per column: a string, int64 or time.Time
+// depending on the column type, or nil in optional columns.
+func Write(w io.Writer, columns []Column, rows [][]any) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return fmt.Errorf("row %d has %d values for %d columns", i+1, len(row), len(columns))
+		}
+	}
+
+	var file bytes.Buffer
+	file.WriteString(magic)
+
+	chunks := make([]chunk, len(columns))
+	if len(rows) > 0 {
+		for i, column := range columns {
+			page, err := encodePage(column, i, rows)
+			if err != nil {
+				return err
+			}
+			chunks[i] = chunk{offset: int64(file.Len()), size: int64(len(page))}
+			file.Write(page)
+		}
+	}
+
+	footer := encodeFileMetaData(columns, chunks, len(rows))
+	file.Write(footer)
+	file.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	file.WriteString(magic)
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// encodePage encodes the values of a column as a data page with its header
+func encodePage(column Column, index int, rows [][]any) ([]byte, error) {
+	var values bytes.Buffer
+	levels := make([]byte, 0, len(rows))
+
+	for i, row := range rows {
+		value := row[index]
+		if value == nil {
+			if !column.Optional {
+				return nil, fmt.Errorf("row %d has no value for required column %s", i+1, column.Name)
+			}
+			levels = append(levels, 0)
+			continue
+		}
+		levels = append(levels, 1)
+
+		switch v := value.(type) {
+		case string:
+			if column.Type != String {
+				return nil, fmt.Errorf("row %d has a string for column %s", i+1, column.Name)
+			}
+			values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+			values.WriteString(v)
+		case int64:
+			if column.Type != Int64 {
+				return nil, fmt.Errorf("row %d has an integer for column %s", i+1, column.Name)
+			}
+			values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+		case time.Time:
+			if column.Type != Timestamp {
+				return nil, fmt.Errorf("row %d has a time for column %s", i+1, column.Name)
+			}
+			values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v.UnixMicro())))
+		default:
+			return nil, fmt.Errorf("row %d has an unsupported %T for column %s", i+1, value, column.Name)
+		}
+	}
+
+	var data bytes.Buffer
+	if column.Optional {
+		encoded := encodeLevels(levels)
+		data.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(encoded))))
+		data.Write(encoded)
+	}
+	data.Write(values.Bytes())
+
+	header := &thriftWriter{}
+	header.beginStruct()
+	header.i32Field(1, pageTypeData)
+	header.i32Field(2, int32(data.Len()))
+	header.i32Field(3, int32(data.Len()))
+	header.structField(5)
+	header.i32Field(1, int32(len(rows)))
+	header.i32Field(2, encodingPlain)
+	header.i32Field(3, encodingRLE)
+	header.i32Field(4, encodingRLE)
+	header.endStruct()
+	header.endStruct()
+
+	return append(header.buf.Bytes(), data.Bytes()...), nil
+}
+
+// encodeLevels encodes definition levels of bit width 1 as RLE runs
+func encodeLevels(levels []byte) []byte {
+	var out []byte
+	for start := 0; start < len(levels); {
+		end := start + 1
+		for end < len(levels) && levels[end] == levels[start] {
+			end++
+		}
+		out = binary.AppendUvarint(out, uint64(end-start)<<1)
+		out = append(out, levels[start])
+		start = end
+	}
+	return out
+}
+
+// encodeFileMetaData encodes the footer describing the schema and where the
+// column chunks are
+func encodeFileMetaData(columns []Column, chunks []chunk, numRows int) []byte {
+	w := &thriftWriter{}
+	w.beginStruct()
+	w.i32Field(1, 1)
+
+	w.listField(2, thriftStruct, len(columns)+1)
+	w.beginStruct()
+	w.stringField(4, "schema")
+	w.i32Field(5, int32(len(columns)))
+	w.endStruct()
+	for _, column := range columns {
+		w.beginStruct()
+		w.i32Field(1, physicalType(column.Type))
+		if column.Optional {
+			w.i32Field(3, repetitionOptional)
+		} else {
+			w.i32Field(3, repetitionRequired)
+		}
+		w.stringField(4, column.Name)
+		switch column.Type {
+		case String:
+			w.i32Field(6, convertedUTF8)
+			w.structField(10)
+			w.structField(1) // STRING
+			w.endStruct()
+			w.endStruct()
+		case Timestamp:
+			w.i32Field(6, convertedTimestampMicros)
+			w.structField(10)
+			w.structField(8) // TIMESTAMP
+			w.boolField(1, true)
+			w.structField(2)
+			w.structField(2) // MICROS
+			w.endStruct()
+			w.endStruct()
+			w.endStruct()
+			w.endStruct()
+		}
+		w.endStruct()
+	}
+
+	w.i64Field(3, int64(numRows))
+
+	if numRows == 0 {
+		w.listField(4, thriftStruct, 0)
+	} else {
+		w.listField(4, thriftStruct, 1)
+		w.beginStruct()
+		var totalSize int64
+		w.listField(1, thriftStruct, len(columns))
+		for i, column := range columns {
+			totalSize += chunks[i].size
+			w.beginStruct()
+			w.i64Field(2, chunks[i].offset)
+			w.structField(3)
+			w.i32Field(1, physicalType(column.Type))
+			w.listField(2, thriftI32, 2)
+			w.i32(encodingPlain)
+			w.i32(encodingRLE)
+			w.listField(3, thriftBinary, 1)
+			w.binary(column.Name)
+			w.i32Field(4, codecUncompressed)
+			w.i64Field(5, int64(numRows))
+			w.i64Field(6, chunks[i].size)
+			w.i64Field(7, chunks[i].size)
+			w.i64Field(9, chunks[i].offset)
+			w.endStruct()
+			w.endStruct()
+		}
+		w.i64Field(2, totalSize)
+		w.i64Field(3, int64(numRows))
+		w.endStruct()
+	}
+
+	w.stringField(6, "public-ip-monitor")
+	w.endStruct()
+	return w.buf.Bytes()
+}
+
+func physicalType(t Type) int32 {
+	if t == String {
+		return physicalByteArray
+	}
+	return physicalInt64
+}
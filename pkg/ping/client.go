@@ -0,0 +1,91 @@
+package ping
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxMessageBytes caps the failure message sent, below the request body
+// limits of the services
+const maxMessageBytes = 10 * 1024
+
+// HTTPClient implements the ping client over HTTP
+type HTTPClient struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// NewClient creates a new ping client
+func NewClient(config Config) (Client, error) {
+	if err := validURL(config.URL); err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if config.FailURL == "" {
+		config.FailURL = strings.TrimRight(config.URL, "/") + "/fail"
+	} else if err := validURL(config.FailURL); err != nil {
+		return nil, fmt.Errorf("invalid fail URL: %w", err)
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &HTTPClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// validURL checks that raw is an absolute http(s) URL
+func validURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", raw)
+	}
+	return nil
+}
+
+// Success reports a successful run
+func (c *HTTPClient) Success(ctx context.Context) error {
+	return c.send(ctx, c.config.URL, "")
+}
+
+// Fail reports a failed run. The message is sent as the request body,
+// which healthchecks.io shows in the check's log.
+func (c *HTTPClient) Fail(ctx context.Context, message string) error {
+	if len(message) > maxMessageBytes {
+		message = message[:maxMessageBytes]
+	}
+	return c.send(ctx, c.config.FailURL, message)
+}
+
+func (c *HTTPClient) send(ctx context.Context, target, body string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send ping: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("ping returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package ping
+
+import "context"
+
+// Config represents a check of a dead man's switch service, such as
+// healthchecks.io or Dead Man's Snitch, which alerts when the pings stop
+type Config struct {
+	URL            string // Requested after every successful run, e.g. "https://hc-ping.com/<uuid>"
+	FailURL        string // Requested after a failed run; URL + "/fail" by default, as healthchecks.io expects
+	TimeoutSeconds int
+}
+
+// Client reports runs to the service
+type Client interface {
+	Success(ctx context.Context) error
+	Fail(ctx context.Context, message string) error
+}
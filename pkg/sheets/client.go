@@ -0,0 +1,104 @@
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const sheetsAPIURL = "https://sheets.googleapis.com/v4/spreadsheets"
+
+// APIClient implements the Google Sheets client using a service account
+type APIClient struct {
+	config     Config
+	tokens     *tokenSource
+	httpClient *http.Client
+}
+
+// APIFactory creates Google Sheets clients
+type APIFactory struct{}
+
+// NewAPIFactory creates a new Google Sheets factory
+func NewAPIFactory() *APIFactory {
+	return &APIFactory{}
+}
+
+// NewClient creates a new Google Sheets client
+func (f *APIFactory) NewClient(config Config) (Client, error) {
+	if config.SpreadsheetID == "" {
+		return nil, fmt.Errorf("spreadsheet ID is required")
+	}
+	if config.SheetName == "" {
+		config.SheetName = "Sheet1"
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	httpClient := &http.Client{Timeout: timeout}
+
+	tokens, err := newTokenSource(config.CredentialsFile, httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return &APIClient{
+		config:     config,
+		tokens:     tokens,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Send appends the message rows after the last row of the sheet
+func (c *APIClient) Send(ctx context.Context, message Message) error {
+	if len(message.Rows) == 0 {
+		return nil
+	}
+
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/values/%s:append?valueInputOption=USER_ENTERED&insertDataOption=INSERT_ROWS",
+		sheetsAPIURL, url.PathEscape(c.config.SpreadsheetID), url.PathEscape(c.config.SheetName+"!A1"))
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"majorDimension": "ROWS",
+		"values":         message.Rows,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Google Sheets API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// Close closes the Google Sheets client
+func (c *APIClient) Close() error {
+	return nil
+}
@@ -0,0 +1,27 @@
+package sheets
+
+import "context"
+
+// Message represents rows appended to the sheet
+type Message struct {
+	Rows [][]string
+}
+
+// Config represents Google Sheets configuration
+type Config struct {
+	CredentialsFile string // Service account key file (JSON)
+	SpreadsheetID   string
+	SheetName       string // Tab the rows are appended to
+	TimeoutSeconds  int
+}
+
+// Client defines the Google Sheets client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates Google Sheets clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
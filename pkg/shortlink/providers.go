@@ -0,0 +1,123 @@
+package shortlink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterProvider("shlink", newShlinkClient)
+	RegisterProvider("kutt", newKuttClient)
+	RegisterProvider("http", newHTTPClient)
+}
+
+// httpClient sends one request per update
+type httpClient struct {
+	config     Config
+	httpClient *http.Client
+	request    func(target string) (method, url string, body []byte, headers map[string]string)
+}
+
+// newShlinkClient edits the long URL of a Shlink short URL
+// (PATCH /rest/v3/short-urls/{shortCode})
+func newShlinkClient(config Config) (Client, error) {
+	if config.ID == "" {
+		return nil, fmt.Errorf("shlink: short code is required")
+	}
+	endpoint := strings.TrimRight(config.APIURL, "/") + "/rest/v3/short-urls/" + url.PathEscape(config.ID)
+	return newClient(config, func(target string) (string, string, []byte, map[string]string) {
+		body, _ := json.Marshal(map[string]string{"longUrl": target})
+		return http.MethodPatch, endpoint, body, map[string]string{"X-Api-Key": config.APIKey}
+	}), nil
+}
+
+// newKuttClient edits the target of a Kutt link (PATCH /api/v2/links/{id})
+func newKuttClient(config Config) (Client, error) {
+	if config.ID == "" {
+		return nil, fmt.Errorf("kutt: link ID is required")
+	}
+	endpoint := strings.TrimRight(config.APIURL, "/") + "/api/v2/links/" + url.PathEscape(config.ID)
+	return newClient(config, func(target string) (string, string, []byte, map[string]string) {
+		body, _ := json.Marshal(map[string]string{"target": target})
+		return http.MethodPatch, endpoint, body, map[string]string{"X-API-KEY": config.APIKey}
+	}), nil
+}
+
+// newHTTPClient sends a configurable request, for self-hosted redirect
+// services. {url}, {ip} and {id} are replaced in the URL and body.
+func newHTTPClient(config Config) (Client, error) {
+	method := config.Method
+	if method == "" {
+		method = http.MethodPut
+	}
+	body := config.Body
+	if body == "" {
+		body = `{"url": "{url}"}`
+	}
+	return newClient(config, func(target string) (string, string, []byte, map[string]string) {
+		replacer := strings.NewReplacer("{url}", target, "{id}", config.ID, "{ip}", hostOf(target))
+		headers := map[string]string{}
+		if config.APIKey != "" {
+			headers["Authorization"] = "Bearer " + config.APIKey
+		}
+		for key, value := range config.Headers {
+			headers[key] = value
+		}
+		return method, replacer.Replace(config.APIURL), []byte(replacer.Replace(body)), headers
+	}), nil
+}
+
+func newClient(config Config, request func(target string) (string, string, []byte, map[string]string)) *httpClient {
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &httpClient{
+		config:     config,
+		httpClient: &http.Client{Timeout: timeout},
+		request:    request,
+	}
+}
+
+// Update points the link at the target URL
+func (c *httpClient) Update(ctx context.Context, target string) error {
+	method, endpoint, body, headers := c.request(target)
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update short link %s: %w", c.config.ID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s returned status %d: %s", c.config.Provider, resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// hostOf returns the host of a URL without brackets
+func hostOf(target string) string {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
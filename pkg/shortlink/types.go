@@ -0,0 +1,73 @@
+package shortlink
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Config represents the short link to keep pointed at the current IP
+type Config struct {
+	Provider       string            // Registered provider, e.g. "shlink", "kutt" or "http"
+	APIURL         string            // Base URL of the service, or the request URL for "http"
+	APIKey         string            // Sent as the provider expects it
+	ID             string            // Short code or ID of the link, e.g. "home"
+	Method         string            // http: request method; PUT by default
+	Headers        map[string]string // http: additional request headers
+	Body           string            // http: request body; {url}, {ip} and {id} are replaced
+	TimeoutSeconds int
+}
+
+// Client points a short link somewhere else
+type Client interface {
+	Update(ctx context.Context, url string) error
+}
+
+// ProviderFactory creates a client for a short link service
+type ProviderFactory func(config Config) (Client, error)
+
+// providers holds the registered providers
+var providers = make(map[string]ProviderFactory)
+
+// RegisterProvider makes a short link service available by name. It is
+// meant to be called from init functions.
+func RegisterProvider(name string, factory ProviderFactory) {
+	if _, exists := providers[name]; exists {
+		panic("shortlink: provider " + name + " registered twice")
+	}
+	providers[name] = factory
+}
+
+// Providers returns the registered providers
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewClient creates a client of a registered provider
+func NewClient(config Config) (Client, error) {
+	factory, ok := providers[config.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown short link provider %q (expected one of %v)", config.Provider, Providers())
+	}
+	if config.APIURL == "" {
+		return nil, fmt.Errorf("short link API URL is required")
+	}
+	return factory(config)
+}
+
+// TargetURL replaces {ip} in pattern, e.g. "http://{ip}:8080/", with the
+// address, in brackets for IPv6
+func TargetURL(pattern, ip string) string {
+	host := ip
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		host = "[" + ip + "]"
+	}
+	return strings.ReplaceAll(pattern, "{ip}", host)
+}
@@ -0,0 +1,89 @@
+package shortlink
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Updater points a short link at the latest target, at most once per
+// interval to stay within the service's rate limits. Targets set in
+// between are coalesced into one update, and failed updates are retried
+// after the interval until a newer target replaces them.
+type Updater struct {
+	client   Client
+	interval time.Duration
+	logf     func(format string, args ...interface{}) // Reports updates
+	errorf   func(format string, args ...interface{}) // Reports failed updates
+
+	mu      sync.Mutex
+	latest  string    // Target last set
+	pending string    // Target waiting for the next update
+	last    time.Time // Start of the last update
+	timer   *time.Timer
+	running bool
+}
+
+// NewUpdater creates an updater
+func NewUpdater(client Client, interval time.Duration, logf, errorf func(format string, args ...interface{})) *Updater {
+	return &Updater{client: client, interval: interval, logf: logf, errorf: errorf}
+}
+
+// Set points the link at target, now or once the interval has passed
+func (u *Updater) Set(target string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if target == u.latest {
+		return
+	}
+	u.latest = target
+	u.pending = target
+	u.schedule()
+}
+
+// schedule starts the next update when due, unless one is running or
+// scheduled already
+func (u *Updater) schedule() {
+	if u.running || u.timer != nil || u.pending == "" {
+		return
+	}
+	wait := time.Until(u.last.Add(u.interval))
+	if wait <= 0 {
+		u.start()
+		return
+	}
+	u.timer = time.AfterFunc(wait, func() {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		u.timer = nil
+		u.schedule()
+	})
+}
+
+// start updates the link in the background
+func (u *Updater) start() {
+	target := u.pending
+	u.pending = ""
+	u.running = true
+	u.last = time.Now()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := u.client.Update(ctx, target)
+		cancel()
+
+		u.mu.Lock()
+		defer u.mu.Unlock()
+		u.running = false
+		if err != nil {
+			u.errorf("Failed to point short link at %s, retrying in %v: %v", target, u.interval, err)
+			if u.pending == "" {
+				u.pending = target
+			}
+		} else {
+			u.logf("Short link now points at %s", target)
+		}
+		u.schedule()
+	}()
+}
@@ -0,0 +1,148 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const postMessageURL = "https://slack.com/api/chat.postMessage"
+
+// APIClient implements the Slack client using incoming webhooks or the Web API
+type APIClient struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// APIFactory creates Slack clients
+type APIFactory struct{}
+
+// NewAPIFactory creates a new Slack factory
+func NewAPIFactory() *APIFactory {
+	return &APIFactory{}
+}
+
+// NewClient creates a new Slack client
+func (f *APIFactory) NewClient(config Config) (Client, error) {
+	if config.WebhookURL == "" && config.Token == "" {
+		return nil, fmt.Errorf("either a webhook URL or an API token is required")
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &APIClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// Send posts a message to Slack
+func (c *APIClient) Send(ctx context.Context, message Message) error {
+	if c.config.Token != "" {
+		return c.postMessage(ctx, message)
+	}
+	return c.postWebhook(ctx, message)
+}
+
+// postWebhook sends the message through an incoming webhook
+func (c *APIClient) postWebhook(ctx context.Context, message Message) error {
+	payload := map[string]string{
+		"text": message.Text,
+	}
+
+	resp, err := c.post(ctx, c.config.WebhookURL, payload, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Slack webhook error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// postMessage sends the message through the chat.postMessage Web API
+func (c *APIClient) postMessage(ctx context.Context, message Message) error {
+	channel := message.Channel
+	if channel == "" {
+		channel = c.config.Channel
+	}
+	if channel == "" {
+		return fmt.Errorf("a channel is required when using the Slack Web API")
+	}
+
+	payload := map[string]string{
+		"channel": channel,
+		"text":    message.Text,
+	}
+	headers := map[string]string{
+		"Authorization": "Bearer " + c.config.Token,
+	}
+
+	resp, err := c.post(ctx, postMessageURL, payload, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Slack API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	// The Web API reports failures in the body with a 200 status
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to parse Slack API response: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("Slack API error: %s", result.Error)
+	}
+
+	return nil
+}
+
+// post sends a JSON payload to url
+func (c *APIClient) post(ctx context.Context, url string, payload interface{}, headers map[string]string) (*http.Response, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	return resp, nil
+}
+
+// Close closes the Slack client
+func (c *APIClient) Close() error {
+	return nil
+}
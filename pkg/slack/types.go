@@ -0,0 +1,29 @@
+package slack
+
+import "context"
+
+// Message represents a Slack message
+type Message struct {
+	Text    string
+	Channel string // Overrides Config.Channel when using the Web API
+}
+
+// Config represents Slack configuration. Either WebhookURL or Token and
+// Channel must be set; the Web API (chat.postMessage) is used when Token is set.
+type Config struct {
+	WebhookURL     string
+	Token          string
+	Channel        string
+	TimeoutSeconds int
+}
+
+// Client defines the Slack client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates Slack clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
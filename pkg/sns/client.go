@@ -0,0 +1,218 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSubjectLength is the longest subject SNS accepts, in characters
+const maxSubjectLength = 99
+
+// PublishClient implements the SNS client using the Publish action of the
+// SNS query API, signed with AWS Signature Version 4
+type PublishClient struct {
+	config      Config
+	httpClient  *http.Client
+	credentials *credentialSource
+}
+
+// PublishFactory creates SNS clients
+type PublishFactory struct{}
+
+// NewPublishFactory creates a new SNS factory
+func NewPublishFactory() *PublishFactory {
+	return &PublishFactory{}
+}
+
+// NewClient creates a new SNS client
+func (f *PublishFactory) NewClient(config Config) (Client, error) {
+	if config.TopicARN == "" {
+		return nil, fmt.Errorf("topic ARN is required")
+	}
+	if config.Region == "" {
+		config.Region = regionFromARN(config.TopicARN)
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("region is required when the topic ARN has none")
+	}
+	if config.AccessKeyID != "" && config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("secret access key is required with an access key ID")
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = "https://sns." + config.Region + ".amazonaws.com/"
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	httpClient := &http.Client{
+		Timeout: timeout,
+	}
+
+	return &PublishClient{
+		config:     config,
+		httpClient: httpClient,
+		credentials: &credentialSource{
+			config:     config,
+			region:     config.Region,
+			httpClient: httpClient,
+		},
+	}, nil
+}
+
+// regionFromARN returns the region of an ARN such as
+// "arn:aws:sns:eu-west-1:123456789012:ip-changes"
+func regionFromARN(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 6 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[3]
+}
+
+// Send publishes the message to the configured topic
+func (c *PublishClient) Send(ctx context.Context, message Message) error {
+	creds, err := c.credentials.get(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+
+	form, err := c.publishForm(message)
+	if err != nil {
+		return err
+	}
+	body := []byte(form.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signRequest(req, body, creds, c.config.Region, "sns", time.Now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("SNS API error (status %d): %s", resp.StatusCode, errorMessage(respBody))
+	}
+
+	return nil
+}
+
+// publishForm builds the parameters of the Publish action. When texts for
+// specific protocols are given, the message is sent as a JSON structure
+// with one text per protocol.
+func (c *PublishClient) publishForm(message Message) (url.Values, error) {
+	form := url.Values{}
+	form.Set("Action", "Publish")
+	form.Set("Version", "2010-03-31")
+	form.Set("TopicArn", c.config.TopicARN)
+
+	if subject := asciiSubject(message.Subject); subject != "" {
+		form.Set("Subject", subject)
+	}
+
+	texts := map[string]string{}
+	if message.Email != "" {
+		texts["email"] = message.Email
+	}
+	if message.SMS != "" {
+		texts["sms"] = message.SMS
+	}
+	if message.JSON != "" {
+		for _, protocol := range []string{"lambda", "sqs", "http", "https", "firehose"} {
+			texts[protocol] = message.JSON
+		}
+	}
+
+	if len(texts) == 0 {
+		form.Set("Message", message.Default)
+	} else {
+		texts["default"] = message.Default
+		structure, err := json.Marshal(texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal SNS message: %w", err)
+		}
+		form.Set("Message", string(structure))
+		form.Set("MessageStructure", "json")
+	}
+
+	names := make([]string, 0, len(message.Attributes))
+	for name, value := range message.Attributes {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		prefix := "MessageAttributes.entry." + strconv.Itoa(i+1)
+		form.Set(prefix+".Name", name)
+		form.Set(prefix+".Value.DataType", "String")
+		form.Set(prefix+".Value.StringValue", message.Attributes[name])
+	}
+
+	// FIFO topics require a group, and a deduplication ID unless content-based
+	// deduplication is enabled on the topic
+	if strings.HasSuffix(c.config.TopicARN, ".fifo") {
+		sum := sha256.Sum256([]byte(form.Get("Message")))
+		form.Set("MessageGroupId", "public-ip-monitor")
+		form.Set("MessageDeduplicationId", hex.EncodeToString(sum[:16]))
+	}
+
+	return form, nil
+}
+
+// asciiSubject reduces a subject to what SNS accepts: printable ASCII on a
+// single line, starting with a letter, digit or punctuation, under 100
+// characters
+func asciiSubject(subject string) string {
+	var out strings.Builder
+	for _, r := range subject {
+		if r >= 0x20 && r < 0x7F {
+			out.WriteRune(r)
+		}
+	}
+	result := strings.Join(strings.Fields(out.String()), " ")
+	if len(result) > maxSubjectLength {
+		result = strings.TrimSpace(result[:maxSubjectLength])
+	}
+	return result
+}
+
+// errorResponse is the error document of the AWS query APIs
+type errorResponse struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// errorMessage extracts the error from an AWS error response
+func errorMessage(body []byte) string {
+	var response errorResponse
+	if err := xml.Unmarshal(body, &response); err != nil || response.Code == "" {
+		return strings.TrimSpace(string(body))
+	}
+	return response.Code + ": " + response.Message
+}
+
+// Close closes the SNS client
+func (c *PublishClient) Close() error {
+	return nil
+}
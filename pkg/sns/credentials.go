@@ -0,0 +1,268 @@
+package sns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// credentials are the AWS credentials requests are signed with
+type credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time // Zero for credentials that do not expire
+}
+
+// expiring reports whether the credentials must be refreshed before use
+func (c credentials) expiring(now time.Time) bool {
+	return !c.Expires.IsZero() && now.Add(5*time.Minute).After(c.Expires)
+}
+
+// credentialSource resolves and caches credentials, in the order static
+// config, environment, ECS task role, EC2 instance role, then assumes the
+// configured role with them
+type credentialSource struct {
+	config     Config
+	region     string
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	base   credentials
+	cached credentials
+}
+
+// get returns valid credentials, refreshing them when they expire
+func (s *credentialSource) get(ctx context.Context) (credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.cached.AccessKeyID != "" && !s.cached.expiring(now) {
+		return s.cached, nil
+	}
+
+	if s.base.AccessKeyID == "" || s.base.expiring(now) {
+		base, err := s.resolveBase(ctx)
+		if err != nil {
+			return credentials{}, err
+		}
+		s.base = base
+	}
+
+	if s.config.RoleARN == "" {
+		s.cached = s.base
+		return s.cached, nil
+	}
+
+	assumed, err := s.assumeRole(ctx, s.base)
+	if err != nil {
+		return credentials{}, err
+	}
+	s.cached = assumed
+	return s.cached, nil
+}
+
+// resolveBase finds the credentials used directly or to assume the role
+func (s *credentialSource) resolveBase(ctx context.Context) (credentials, error) {
+	if s.config.AccessKeyID != "" {
+		return credentials{
+			AccessKeyID:     s.config.AccessKeyID,
+			SecretAccessKey: s.config.SecretAccessKey,
+			SessionToken:    s.config.SessionToken,
+		}, nil
+	}
+
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return credentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		return s.fetchContainerCredentials(ctx, "http://169.254.170.2"+relative, "")
+	}
+	if full := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"); full != "" {
+		return s.fetchContainerCredentials(ctx, full, os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"))
+	}
+
+	creds, err := s.fetchInstanceCredentials(ctx)
+	if err != nil {
+		return credentials{}, fmt.Errorf("no AWS credentials configured and instance role unavailable: %w", err)
+	}
+	return creds, nil
+}
+
+// roleCredentials is the JSON document of the ECS and EC2 credential endpoints
+type roleCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+func (r roleCredentials) credentials() credentials {
+	return credentials{
+		AccessKeyID:     r.AccessKeyID,
+		SecretAccessKey: r.SecretAccessKey,
+		SessionToken:    r.Token,
+		Expires:         r.Expiration,
+	}
+}
+
+// fetchContainerCredentials reads the credentials of an ECS task role
+func (s *credentialSource) fetchContainerCredentials(ctx context.Context, endpoint, token string) (credentials, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return credentials{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	var role roleCredentials
+	if err := s.getJSON(req, &role); err != nil {
+		return credentials{}, fmt.Errorf("failed to get container credentials: %w", err)
+	}
+	return role.credentials(), nil
+}
+
+// imdsURL is the EC2 instance metadata service
+const imdsURL = "http://169.254.169.254/latest"
+
+// fetchInstanceCredentials reads the credentials of the EC2 instance role
+// through IMDSv2
+func (s *credentialSource) fetchInstanceCredentials(ctx context.Context) (credentials, error) {
+	req, err := http.NewRequestWithContext(ctx, "PUT", imdsURL+"/api/token", nil)
+	if err != nil {
+		return credentials{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	token, err := s.getText(req)
+	if err != nil {
+		return credentials{}, fmt.Errorf("failed to get metadata token: %w", err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "GET", imdsURL+"/meta-data/iam/security-credentials/", nil)
+	if err != nil {
+		return credentials{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	roles, err := s.getText(req)
+	if err != nil {
+		return credentials{}, fmt.Errorf("failed to get instance role: %w", err)
+	}
+	roleName, _, _ := strings.Cut(strings.TrimSpace(roles), "\n")
+	if roleName == "" {
+		return credentials{}, fmt.Errorf("no instance role attached")
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "GET", imdsURL+"/meta-data/iam/security-credentials/"+url.PathEscape(roleName), nil)
+	if err != nil {
+		return credentials{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+
+	var role roleCredentials
+	if err := s.getJSON(req, &role); err != nil {
+		return credentials{}, fmt.Errorf("failed to get credentials of role %s: %w", roleName, err)
+	}
+	return role.credentials(), nil
+}
+
+// assumeRoleResponse is the relevant part of the STS AssumeRole response
+type assumeRoleResponse struct {
+	Credentials struct {
+		AccessKeyID     string    `xml:"AccessKeyId"`
+		SecretAccessKey string    `xml:"SecretAccessKey"`
+		SessionToken    string    `xml:"SessionToken"`
+		Expiration      time.Time `xml:"Expiration"`
+	} `xml:"AssumeRoleResult>Credentials"`
+}
+
+// assumeRole exchanges base credentials for temporary credentials of the
+// configured role
+func (s *credentialSource) assumeRole(ctx context.Context, base credentials) (credentials, error) {
+	form := url.Values{}
+	form.Set("Action", "AssumeRole")
+	form.Set("Version", "2011-06-15")
+	form.Set("RoleArn", s.config.RoleARN)
+	form.Set("RoleSessionName", "public-ip-monitor")
+	form.Set("DurationSeconds", "3600")
+	if s.config.ExternalID != "" {
+		form.Set("ExternalId", s.config.ExternalID)
+	}
+	body := []byte(form.Encode())
+
+	endpoint := s.config.STSEndpoint
+	if endpoint == "" {
+		endpoint = "https://sts." + s.region + ".amazonaws.com/"
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return credentials{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signRequest(req, body, base, s.region, "sts", time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return credentials{}, fmt.Errorf("failed to assume role: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		return credentials{}, fmt.Errorf("STS API error (status %d): %s", resp.StatusCode, errorMessage(respBody))
+	}
+
+	var result assumeRoleResponse
+	if err := xml.Unmarshal(respBody, &result); err != nil {
+		return credentials{}, fmt.Errorf("failed to parse AssumeRole response: %w", err)
+	}
+	if result.Credentials.AccessKeyID == "" {
+		return credentials{}, fmt.Errorf("AssumeRole response has no credentials")
+	}
+
+	return credentials{
+		AccessKeyID:     result.Credentials.AccessKeyID,
+		SecretAccessKey: result.Credentials.SecretAccessKey,
+		SessionToken:    result.Credentials.SessionToken,
+		Expires:         result.Credentials.Expiration,
+	}, nil
+}
+
+// getText performs req and returns the response body
+func (s *credentialSource) getText(req *http.Request) (string, error) {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+// getJSON performs req and decodes the JSON response into v
+func (s *credentialSource) getJSON(req *http.Request, v any) error {
+	body, err := s.getText(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(body), v)
+}
@@ -0,0 +1,44 @@
+package sns
+
+import "context"
+
+// Message represents a notification published to an SNS topic. Subscribers
+// receive the text matching their protocol, or Default.
+type Message struct {
+	Subject    string            // Email subject; reduced to the ASCII SNS accepts
+	Default    string            // Text for protocols without a specific text
+	Email      string            // Text for email subscribers, optional
+	SMS        string            // Short text for SMS subscribers, optional
+	JSON       string            // Event payload for Lambda, SQS and HTTP(S) subscribers, optional
+	Attributes map[string]string // Message attributes, usable in subscription filter policies
+}
+
+// Config represents SNS configuration
+type Config struct {
+	TopicARN string
+	Region   string // Defaults to the region of the topic ARN
+
+	// Static credentials; when empty, the AWS_* environment variables, the
+	// ECS task role or the EC2 instance role are used
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	RoleARN     string // Role assumed with the credentials above, optional
+	ExternalID  string // External ID required by the role's trust policy, optional
+	Endpoint    string // SNS endpoint; defaults to the regional endpoint
+	STSEndpoint string // STS endpoint used to assume RoleARN; defaults to the regional endpoint
+
+	TimeoutSeconds int
+}
+
+// Client defines the SNS client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates SNS clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -0,0 +1,61 @@
+package syslog
+
+import (
+	"context"
+	"time"
+)
+
+// Severities of RFC 5424 used by the notifier
+const (
+	SeverityCritical      = 2
+	SeverityWarning       = 4
+	SeverityNotice        = 5
+	SeverityInformational = 6
+)
+
+// Message represents a single syslog message
+type Message struct {
+	Severity       int // 0 (emergency) to 7 (debug)
+	Timestamp      time.Time
+	Hostname       string
+	MsgID          string    // Type of the message, e.g. "ip_changed"
+	StructuredData []Element // Machine-readable details, in order
+	Text           string
+}
+
+// Element is an SD-ELEMENT: an ID such as "ipchange@32473" and its
+// parameters, in order
+type Element struct {
+	ID     string
+	Params []Param
+}
+
+// Param is a parameter of a structured data element
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Config represents syslog configuration
+type Config struct {
+	Address            string // Collector host:port; port 6514 when omitted
+	Facility           string // e.g. "local0" (default), "user" or "daemon"
+	AppName            string
+	CAFile             string // PEM bundle verifying the collector instead of the system roots
+	CertFile           string // Client certificate, for collectors requiring mutual TLS
+	KeyFile            string
+	InsecureSkipVerify bool // Accept any collector certificate (self-signed test collectors only)
+	TimeoutSeconds     int
+}
+
+// Client defines the syslog client interface
+type Client interface {
+	// Send delivers the messages in order over a single connection
+	Send(ctx context.Context, messages ...Message) error
+	Close() error
+}
+
+// Factory creates syslog clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -0,0 +1,148 @@
+package teams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WebhookClient implements the Teams client using incoming webhooks
+type WebhookClient struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// WebhookFactory creates Teams webhook clients
+type WebhookFactory struct{}
+
+// NewWebhookFactory creates a new Teams factory
+func NewWebhookFactory() *WebhookFactory {
+	return &WebhookFactory{}
+}
+
+// NewClient creates a new Teams webhook client
+func (f *WebhookFactory) NewClient(config Config) (Client, error) {
+	if config.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &WebhookClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// webhookPayload is the message envelope accepted by Teams webhooks
+type webhookPayload struct {
+	Type        string              `json:"type"`
+	Attachments []attachmentPayload `json:"attachments"`
+}
+
+type attachmentPayload struct {
+	ContentType string      `json:"contentType"`
+	Content     cardPayload `json:"content"`
+}
+
+type cardPayload struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"`
+	Version string        `json:"version"`
+	Body    []interface{} `json:"body"`
+	MSTeams msTeamsWidth  `json:"msteams"`
+}
+
+type msTeamsWidth struct {
+	Width string `json:"width"`
+}
+
+type textBlock struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Weight   string `json:"weight,omitempty"`
+	Size     string `json:"size,omitempty"`
+	Color    string `json:"color,omitempty"`
+	IsSubtle bool   `json:"isSubtle,omitempty"`
+	Wrap     bool   `json:"wrap"`
+}
+
+type factSet struct {
+	Type  string        `json:"type"`
+	Facts []factPayload `json:"facts"`
+}
+
+type factPayload struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// Send posts a message to the Teams webhook
+func (c *WebhookClient) Send(ctx context.Context, message Message) error {
+	body := []interface{}{
+		textBlock{Type: "TextBlock", Text: message.Title, Weight: "bolder", Size: "medium", Color: message.Style, Wrap: true},
+	}
+	if len(message.Facts) > 0 {
+		facts := factSet{Type: "FactSet"}
+		for _, fact := range message.Facts {
+			facts.Facts = append(facts.Facts, factPayload(fact))
+		}
+		body = append(body, facts)
+	}
+	if message.Footer != "" {
+		body = append(body, textBlock{Type: "TextBlock", Text: message.Footer, Size: "small", IsSubtle: true, Wrap: true})
+	}
+
+	payload := webhookPayload{
+		Type: "message",
+		Attachments: []attachmentPayload{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: cardPayload{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+				MSTeams: msTeamsWidth{Width: "Full"},
+			},
+		}},
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.WebhookURL, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Office 365 connectors answer 200, Workflows webhooks 202 Accepted
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Teams webhook error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// Close closes the Teams client
+func (c *WebhookClient) Close() error {
+	return nil
+}
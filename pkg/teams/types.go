@@ -0,0 +1,34 @@
+package teams
+
+import "context"
+
+// Message represents a Teams message rendered as an Adaptive Card
+type Message struct {
+	Title  string
+	Style  string // Title color: "attention", "warning", "good" or "" for the default
+	Facts  []Fact
+	Footer string
+}
+
+// Fact represents a name/value pair shown in the card's fact set
+type Fact struct {
+	Title string
+	Value string
+}
+
+// Config represents Teams configuration
+type Config struct {
+	WebhookURL     string
+	TimeoutSeconds int
+}
+
+// Client defines the Teams client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates Teams clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTemplate renders the message as a JSON object
+const DefaultTemplate = `{
+  "id": {{json .ID}},
+  "event": {{json .Event}},
+  "severity": {{json .Severity}},
+  "family": {{json .Family}},
+  "old_ip": {{json .OldIP}},
+  "new_ip": {{json .NewIP}},
+  "changes": {{json .Changes}},
+  "timestamp": {{json .Timestamp}},
+  "hostname": {{json .Hostname}},
+  "site": {{json .Site}},
+  "text": {{json .Text}},
+  "replay": {{json .Replay}},
+  "enrichment": {{json .Enrichment}}
+}`
+
+// HTTPClient implements the webhook client over HTTP
+type HTTPClient struct {
+	config     Config
+	template   *template.Template
+	httpClient *http.Client
+}
+
+// HTTPFactory creates webhook clients
+type HTTPFactory struct{}
+
+// NewHTTPFactory creates a new webhook factory
+func NewHTTPFactory() *HTTPFactory {
+	return &HTTPFactory{}
+}
+
+// NewClient creates a new webhook client, parsing the payload template
+func (f *HTTPFactory) NewClient(config Config) (Client, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+	if config.Method == "" {
+		config.Method = "POST"
+	}
+	if config.Template == "" {
+		config.Template = DefaultTemplate
+	}
+
+	tmpl, err := ParseTemplate(config.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &HTTPClient{
+		config:   config,
+		template: tmpl,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// ParseTemplate parses a payload template. Besides the standard functions,
+// templates can use "json" to encode any value as JSON.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("payload").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			if err := encoder.Encode(v); err != nil {
+				return "", err
+			}
+			return strings.TrimSuffix(buf.String(), "\n"), nil
+		},
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Send renders the payload and sends it to the webhook URL
+func (c *HTTPClient) Send(ctx context.Context, message Message) error {
+	var body bytes.Buffer
+	if err := c.template.Execute(&body, message); err != nil {
+		return fmt.Errorf("failed to render webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.config.Method, c.config.URL, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range c.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request to %s: %w", c.config.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("webhook %s returned status %d: %s", c.config.URL, resp.StatusCode, string(respBody))
+	}
+
+	return nil
+}
+
+// Close closes the webhook client
+func (c *HTTPClient) Close() error {
+	return nil
+}
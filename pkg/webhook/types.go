@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"context"
+	"time"
+)
+
+// Message holds the data available to the payload template
+type Message struct {
+	ID        string // Correlation ID, the same for every channel notified of the event
+	Event     string // e.g., "ip_changed", "failover"
+	Severity  string // "info", "warning" or "critical"
+	Family    string // Family of the first change
+	OldIP     string // Old IP of the first change
+	NewIP     string // New IP of the first change
+	Changes   []Change
+	Timestamp time.Time
+	Hostname  string
+	Site      string // Name of the monitored location
+	Text      string // Plain-text description of the event
+	Replay    bool   // Sent again by "events replay" rather than as it happened
+
+	// Additional details about the new IP, by name
+	Enrichment map[string]string
+}
+
+// Change describes an address change for a single IP family
+type Change struct {
+	Family string `json:"family"`
+	OldIP  string `json:"old_ip"`
+	NewIP  string `json:"new_ip"`
+	WAN    string `json:"wan,omitempty"`
+}
+
+// Config represents webhook configuration
+type Config struct {
+	URL            string
+	Method         string            // Defaults to POST
+	Headers        map[string]string // Sent with every request
+	Template       string            // Go text/template rendering the request body
+	TimeoutSeconds int
+}
+
+// Client defines the webhook client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates webhook clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -0,0 +1,119 @@
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// maxContentBytes is the longest markdown content group bots accept
+const maxContentBytes = 4096
+
+// WebhookClient implements the WeCom client using group bot webhooks
+type WebhookClient struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// WebhookFactory creates WeCom webhook clients
+type WebhookFactory struct{}
+
+// NewWebhookFactory creates a new WeCom factory
+func NewWebhookFactory() *WebhookFactory {
+	return &WebhookFactory{}
+}
+
+// NewClient creates a new WeCom webhook client
+func (f *WebhookFactory) NewClient(config Config) (Client, error) {
+	if config.WebhookURL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &WebhookClient{
+		config: config,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}, nil
+}
+
+// webhookPayload is the body of a markdown group bot message
+type webhookPayload struct {
+	MsgType  string          `json:"msgtype"`
+	Markdown markdownPayload `json:"markdown"`
+}
+
+type markdownPayload struct {
+	Content string `json:"content"`
+}
+
+// webhookResponse is returned by the bot API, with status 200 also on errors
+type webhookResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// Send posts a message to the WeCom group bot
+func (c *WebhookClient) Send(ctx context.Context, message Message) error {
+	jsonData, err := json.Marshal(webhookPayload{
+		MsgType:  "markdown",
+		Markdown: markdownPayload{Content: truncateBytes(message.Content, maxContentBytes)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.config.WebhookURL, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("WeCom webhook error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result webhookResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode WeCom response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("WeCom webhook error (errcode %d): %s", result.ErrCode, result.ErrMsg)
+	}
+
+	return nil
+}
+
+// truncateBytes shortens text to at most limit bytes without splitting characters
+func truncateBytes(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := limit - len("…")
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
+
+// Close closes the WeCom client
+func (c *WebhookClient) Close() error {
+	return nil
+}
@@ -0,0 +1,25 @@
+package wecom
+
+import "context"
+
+// Message represents a WeChat Work (WeCom) markdown message
+type Message struct {
+	Content string // Markdown, at most 4096 bytes
+}
+
+// Config represents WeCom configuration
+type Config struct {
+	WebhookURL     string // Group bot URL including its key
+	TimeoutSeconds int
+}
+
+// Client defines the WeCom client interface
+type Client interface {
+	Send(ctx context.Context, message Message) error
+	Close() error
+}
+
+// Factory creates WeCom clients
+type Factory interface {
+	NewClient(config Config) (Client, error)
+}
@@ -41,8 +41,12 @@ func (f *MetaFactory) NewClient(config Config) (Client, error) {
 
 // Send sends a WhatsApp message using Meta Business API
 func (c *MetaClient) Send(ctx context.Context, message Message) error {
-	url := fmt.Sprintf("https://graph.facebook.com/%s/%s/messages",
-		c.config.APIVersion, c.config.PhoneID)
+	baseURL := c.config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://graph.facebook.com"
+	}
+	url := fmt.Sprintf("%s/%s/%s/messages",
+		strings.TrimSuffix(baseURL, "/"), c.config.APIVersion, c.config.PhoneID)
 
 	payload := map[string]interface{}{
 		"messaging_product": "whatsapp",
@@ -14,6 +14,7 @@ type Config struct {
 	PhoneID        string
 	APIVersion     string
 	TimeoutSeconds int
+	BaseURL        string // Graph API base URL; defaults to https://graph.facebook.com
 }
 
 // Client defines the WhatsApp client interface